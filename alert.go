@@ -60,6 +60,8 @@ type AlertNode struct {
 	messageTmpl *text.Template
 	detailsTmpl *html.Template
 
+	// statesMu guards the states, it is held while updating a state
+	// so the states are consistent in snapshots.
 	statesMu sync.RWMutex
 
 	alertsTriggered *expvar.Int
@@ -73,13 +75,27 @@ type AlertNode struct {
 
 	levelResets  []stateful.Expression
 	lrScopePools []stateful.ScopePool
+
+	// levelsMu guards the level expressions of the groups, it is held while evaluating them
+	// so their state is consistent in snapshots.
+	levelsMu    sync.Mutex
+	groupLevels map[models.GroupID]*alertLevels
+	// State of the level expressions restored from a snapshot for groups not seen since.
+	restoredLevels map[models.GroupID]alertLevelsSnapshot
+}
+
+// alertLevels are the level and reset expressions of a group, indexed by level.
+type alertLevels struct {
+	levels []stateful.Expression
+	resets []stateful.Expression
 }
 
 // Create a new  AlertNode which caches the most recent item and exposes it over the HTTP API.
 func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, l *log.Logger) (an *AlertNode, err error) {
 	an = &AlertNode{
-		node: node{Node: n, et: et, logger: l},
-		a:    n,
+		node:        node{Node: n, et: et, logger: l},
+		a:           n,
+		groupLevels: make(map[models.GroupID]*alertLevels),
 	}
 	an.node.runF = an.runAlert
 
@@ -385,7 +401,7 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, l *log.Logger) (an *
 	an.lrScopePools = make([]stateful.ScopePool, alert.Critical+1)
 
 	if n.Info != nil {
-		statefulExpression, expressionCompileError := stateful.NewExpression(n.Info.Expression)
+		statefulExpression, expressionCompileError := an.newExpression(n.Info.Expression)
 		if expressionCompileError != nil {
 			return nil, fmt.Errorf("Failed to compile stateful expression for info: %s", expressionCompileError)
 		}
@@ -393,7 +409,7 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, l *log.Logger) (an *
 		an.levels[alert.Info] = statefulExpression
 		an.scopePools[alert.Info] = stateful.NewScopePool(ast.FindReferenceVariables(n.Info.Expression))
 		if n.InfoReset != nil {
-			lstatefulExpression, lexpressionCompileError := stateful.NewExpression(n.InfoReset.Expression)
+			lstatefulExpression, lexpressionCompileError := an.newExpression(n.InfoReset.Expression)
 			if lexpressionCompileError != nil {
 				return nil, fmt.Errorf("Failed to compile stateful expression for infoReset: %s", lexpressionCompileError)
 			}
@@ -403,14 +419,14 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, l *log.Logger) (an *
 	}
 
 	if n.Warn != nil {
-		statefulExpression, expressionCompileError := stateful.NewExpression(n.Warn.Expression)
+		statefulExpression, expressionCompileError := an.newExpression(n.Warn.Expression)
 		if expressionCompileError != nil {
 			return nil, fmt.Errorf("Failed to compile stateful expression for warn: %s", expressionCompileError)
 		}
 		an.levels[alert.Warning] = statefulExpression
 		an.scopePools[alert.Warning] = stateful.NewScopePool(ast.FindReferenceVariables(n.Warn.Expression))
 		if n.WarnReset != nil {
-			lstatefulExpression, lexpressionCompileError := stateful.NewExpression(n.WarnReset.Expression)
+			lstatefulExpression, lexpressionCompileError := an.newExpression(n.WarnReset.Expression)
 			if lexpressionCompileError != nil {
 				return nil, fmt.Errorf("Failed to compile stateful expression for warnReset: %s", lexpressionCompileError)
 			}
@@ -420,14 +436,14 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, l *log.Logger) (an *
 	}
 
 	if n.Crit != nil {
-		statefulExpression, expressionCompileError := stateful.NewExpression(n.Crit.Expression)
+		statefulExpression, expressionCompileError := an.newExpression(n.Crit.Expression)
 		if expressionCompileError != nil {
 			return nil, fmt.Errorf("Failed to compile stateful expression for crit: %s", expressionCompileError)
 		}
 		an.levels[alert.Critical] = statefulExpression
 		an.scopePools[alert.Critical] = stateful.NewScopePool(ast.FindReferenceVariables(n.Crit.Expression))
 		if n.CritReset != nil {
-			lstatefulExpression, lexpressionCompileError := stateful.NewExpression(n.CritReset.Expression)
+			lstatefulExpression, lexpressionCompileError := an.newExpression(n.CritReset.Expression)
 			if lexpressionCompileError != nil {
 				return nil, fmt.Errorf("Failed to compile stateful expression for critReset: %s", lexpressionCompileError)
 			}
@@ -452,7 +468,12 @@ func newAlertNode(et *ExecutingTask, n *pipeline.AlertNode, l *log.Logger) (an *
 	return
 }
 
-func (a *AlertNode) runAlert([]byte) error {
+func (a *AlertNode) runAlert(snapshot []byte) error {
+	if len(snapshot) > 0 {
+		if err := a.restore(snapshot); err != nil {
+			return err
+		}
+	}
 	valueF := func() int64 {
 		a.statesMu.RLock()
 		l := len(a.states)
@@ -510,10 +531,10 @@ func (a *AlertNode) runAlert([]byte) error {
 				if currentLevel != alert.OK {
 					// Update the state with the restored state
 					state = a.updateState(p.Time, currentLevel, p.Group)
-					state.triggered(triggered)
+					a.triggered(state, triggered)
 				}
 			}
-			l := a.determineLevel(p.Time, p.Fields, p.Tags, currentLevel)
+			l := a.determineLevel(p.Group, p.Time, p.Fields, p.Tags, currentLevel)
 			state := a.updateState(p.Time, l, p.Group)
 			if (a.a.UseFlapping && state.flapping) || (a.a.IsStateChangesOnly && !state.changed && !state.expired) {
 				a.timer.Stop()
@@ -528,7 +549,7 @@ func (a *AlertNode) runAlert([]byte) error {
 					Tags:   p.Tags,
 					Points: []models.BatchPoint{models.BatchPointFromPoint(p)},
 				}
-				state.triggered(p.Time)
+				a.triggered(state, p.Time)
 				// Suppress the recovery event.
 				if a.a.NoRecoveriesFlag && l == alert.OK {
 					a.timer.Stop()
@@ -602,11 +623,11 @@ func (a *AlertNode) runAlert([]byte) error {
 				if currentLevel != alert.OK {
 					// Update the state with the restored state
 					state = a.updateState(b.TMax, currentLevel, b.Group)
-					state.triggered(triggered)
+					a.triggered(state, triggered)
 				}
 			}
 			for i, p := range b.Points {
-				l := a.determineLevel(p.Time, p.Fields, p.Tags, currentLevel)
+				l := a.determineLevel(b.Group, p.Time, p.Fields, p.Tags, currentLevel)
 				if l < lowestLevel {
 					lowestLevel = l
 				}
@@ -638,7 +659,7 @@ func (a *AlertNode) runAlert([]byte) error {
 				(l != alert.OK &&
 					!((a.a.UseFlapping && state.flapping) ||
 						(a.a.IsStateChangesOnly && !state.changed && !state.expired))) {
-				state.triggered(t)
+				a.triggered(state, t)
 				// Suppress the recovery event.
 				if a.a.NoRecoveriesFlag && l == alert.OK {
 					a.timer.Stop()
@@ -810,42 +831,234 @@ func (a *AlertNode) handleEvent(event alert.Event) {
 	}
 }
 
-func (a *AlertNode) determineLevel(now time.Time, fields models.Fields, tags map[string]string, currentLevel alert.Level) alert.Level {
-	if higherLevel, found := a.findFirstMatchLevel(alert.Critical, currentLevel-1, now, fields, tags); found {
-		return higherLevel
+// determineLevel returns the level of the point given the current level of its group.
+// The expressions of all levels are evaluated for every point, so stateful functions see every point of the group.
+func (a *AlertNode) determineLevel(group models.GroupID, now time.Time, fields models.Fields, tags map[string]string, currentLevel alert.Level) alert.Level {
+	a.levelsMu.Lock()
+	defer a.levelsMu.Unlock()
+	exprs := a.levelExpressions(group)
+	var matches, resets [alert.Critical + 1]bool
+	for l := alert.Info; l <= alert.Critical; l++ {
+		if se := exprs.levels[l]; se != nil {
+			if pass, err := EvalPredicate(se, a.scopePools[l], now, fields, tags); err != nil {
+				a.incrementErrorCount()
+				a.logger.Printf("E! error evaluating expression for level %v: %s", l, err)
+			} else {
+				matches[l] = pass
+			}
+		}
+		if rse := exprs.resets[l]; rse != nil {
+			if pass, err := EvalPredicate(rse, a.lrScopePools[l], now, fields, tags); err != nil {
+				a.incrementErrorCount()
+				a.logger.Printf("E! error evaluating reset expression for level %v: %s", l, err)
+			} else {
+				resets[l] = pass
+			}
+		}
 	}
-	if rse := a.levelResets[currentLevel]; rse != nil {
-		if pass, err := EvalPredicate(rse, a.lrScopePools[currentLevel], now, fields, tags); err != nil {
-			a.incrementErrorCount()
-			a.logger.Printf("E! error evaluating reset expression for current level %v: %s", currentLevel, err)
-		} else if !pass {
-			return currentLevel
+	// A level at least as high as the current level is kept or escalated to.
+	for l := alert.Critical; l > alert.OK && l >= currentLevel; l-- {
+		if matches[l] {
+			return l
 		}
 	}
-	if newLevel, found := a.findFirstMatchLevel(currentLevel, alert.OK, now, fields, tags); found {
-		return newLevel
+	// The current level is only left once its reset expression passes.
+	if exprs.resets[currentLevel] != nil && !resets[currentLevel] {
+		return currentLevel
+	}
+	for l := currentLevel; l > alert.OK; l-- {
+		if matches[l] {
+			return l
+		}
 	}
 	return alert.OK
 }
 
-func (a *AlertNode) findFirstMatchLevel(start alert.Level, stop alert.Level, now time.Time, fields models.Fields, tags map[string]string) (alert.Level, bool) {
-	if stop < alert.OK {
-		stop = alert.OK
+// levelExpressions returns the level expressions of the group, creating them the first time the group is seen.
+// The caller must hold levelsMu.
+func (a *AlertNode) levelExpressions(group models.GroupID) *alertLevels {
+	if exprs, ok := a.groupLevels[group]; ok {
+		return exprs
+	}
+	exprs := &alertLevels{
+		levels: copyLevels(a.levels),
+		resets: copyLevels(a.levelResets),
+	}
+	if state, ok := a.restoredLevels[group]; ok {
+		if err := restoreLevels(exprs.levels, state.Levels); err != nil {
+			a.logger.Printf("E! failed to restore state of group %s: %v", group, err)
+		}
+		if err := restoreLevels(exprs.resets, state.LevelResets); err != nil {
+			a.logger.Printf("E! failed to restore state of group %s: %v", group, err)
+		}
+		delete(a.restoredLevels, group)
+	}
+	a.groupLevels[group] = exprs
+	return exprs
+}
+
+// copyLevels returns copies of the expressions with a reset state.
+func copyLevels(exprs []stateful.Expression) []stateful.Expression {
+	copies := make([]stateful.Expression, len(exprs))
+	for l, se := range exprs {
+		if se != nil {
+			copies[l] = se.CopyReset()
+		}
+	}
+	return copies
+}
+
+// alertSnapshot is the state of the level expressions and the alert state of each group.
+type alertSnapshot struct {
+	Levels map[models.GroupID]alertLevelsSnapshot `json:"levels,omitempty"`
+	States map[models.GroupID]alertStateSnapshot  `json:"states,omitempty"`
+}
+
+// alertLevelsSnapshot is the state of the level expressions of a group, indexed by level.
+type alertLevelsSnapshot struct {
+	Levels      [][]byte `json:"levels,omitempty"`
+	LevelResets [][]byte `json:"level-resets,omitempty"`
+}
+
+type alertStateSnapshot struct {
+	History        []alert.Level `json:"history"`
+	Index          int           `json:"index"`
+	Flapping       bool          `json:"flapping"`
+	Changed        bool          `json:"changed"`
+	FirstTriggered time.Time     `json:"first-triggered"`
+	LastTriggered  time.Time     `json:"last-triggered"`
+	Expired        bool          `json:"expired"`
+}
+
+func (a *AlertNode) snapshot() ([]byte, error) {
+	levels, err := a.snapshotGroupLevels()
+	if err != nil {
+		return nil, err
+	}
+	states := a.snapshotStates()
+	if levels == nil && states == nil {
+		// Nothing to snapshot
+		return nil, nil
+	}
+	return json.Marshal(alertSnapshot{
+		Levels: levels,
+		States: states,
+	})
+}
+
+// snapshotGroupLevels returns the state of the level expressions of each group, or nil if none have state.
+// The state of restored groups that have not been seen since is kept as is.
+func (a *AlertNode) snapshotGroupLevels() (map[models.GroupID]alertLevelsSnapshot, error) {
+	a.levelsMu.Lock()
+	defer a.levelsMu.Unlock()
+	snapshots := make(map[models.GroupID]alertLevelsSnapshot, len(a.groupLevels)+len(a.restoredLevels))
+	for group, state := range a.restoredLevels {
+		snapshots[group] = state
+	}
+	for group, exprs := range a.groupLevels {
+		levels, err := snapshotLevels(exprs.levels)
+		if err != nil {
+			return nil, err
+		}
+		resets, err := snapshotLevels(exprs.resets)
+		if err != nil {
+			return nil, err
+		}
+		if levels != nil || resets != nil {
+			snapshots[group] = alertLevelsSnapshot{
+				Levels:      levels,
+				LevelResets: resets,
+			}
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+	return snapshots, nil
+}
+
+// snapshotStates returns the alert state of each group, or nil if there are none.
+func (a *AlertNode) snapshotStates() map[models.GroupID]alertStateSnapshot {
+	a.statesMu.RLock()
+	defer a.statesMu.RUnlock()
+	if len(a.states) == 0 {
+		return nil
+	}
+	states := make(map[models.GroupID]alertStateSnapshot, len(a.states))
+	for group, state := range a.states {
+		states[group] = alertStateSnapshot{
+			History:        append([]alert.Level(nil), state.history...),
+			Index:          state.idx,
+			Flapping:       state.flapping,
+			Changed:        state.changed,
+			FirstTriggered: state.firstTriggered,
+			LastTriggered:  state.lastTriggered,
+			Expired:        state.expired,
+		}
+	}
+	return states
+}
+
+func (a *AlertNode) restore(data []byte) error {
+	var snapshot alertSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to restore alert state: %v", err)
 	}
-	for l := start; l > stop; l-- {
-		se := a.levels[l]
+	a.statesMu.Lock()
+	for group, state := range snapshot.States {
+		if len(state.History) != int(a.a.History) || state.Index < 0 || state.Index >= len(state.History) {
+			// The history is only restored with the same length.
+			continue
+		}
+		a.states[group] = &alertState{
+			history:        state.History,
+			idx:            state.Index,
+			flapping:       state.Flapping,
+			changed:        state.Changed,
+			firstTriggered: state.FirstTriggered,
+			lastTriggered:  state.LastTriggered,
+			expired:        state.Expired,
+		}
+	}
+	a.statesMu.Unlock()
+	a.levelsMu.Lock()
+	a.restoredLevels = snapshot.Levels
+	a.levelsMu.Unlock()
+	return nil
+}
+
+// snapshotLevels returns the state of the expressions indexed by level,
+// or nil if none of the expressions have state.
+func snapshotLevels(exprs []stateful.Expression) ([][]byte, error) {
+	states := make([][]byte, len(exprs))
+	hasState := false
+	for l, se := range exprs {
 		if se == nil {
 			continue
 		}
-		if pass, err := EvalPredicate(se, a.scopePools[l], now, fields, tags); err != nil {
-			a.incrementErrorCount()
-			a.logger.Printf("E! error evaluating expression for level %v: %s", alert.Level(l), err)
+		state, err := se.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		states[l] = state
+		hasState = hasState || state != nil
+	}
+	if !hasState {
+		return nil, nil
+	}
+	return states, nil
+}
+
+func restoreLevels(exprs []stateful.Expression, states [][]byte) error {
+	for l, state := range states {
+		if l >= len(exprs) || exprs[l] == nil || state == nil {
 			continue
-		} else if pass {
-			return alert.Level(l), true
+		}
+		if err := exprs[l].Restore(state); err != nil {
+			return fmt.Errorf("failed to restore expression state for level %v: %v", alert.Level(l), err)
 		}
 	}
-	return alert.OK, false
+	return nil
 }
 
 func (a *AlertNode) event(
@@ -954,14 +1167,14 @@ func (a *alertState) percentChange() float64 {
 }
 
 func (a *AlertNode) updateState(t time.Time, level alert.Level, group models.GroupID) *alertState {
-	state, ok := a.getAlertState(group)
+	a.statesMu.Lock()
+	defer a.statesMu.Unlock()
+	state, ok := a.states[group]
 	if !ok {
 		state = &alertState{
 			history: make([]alert.Level, a.a.History),
 		}
-		a.statesMu.Lock()
 		a.states[group] = state
-		a.statesMu.Unlock()
 	}
 	state.addEvent(level)
 
@@ -1105,6 +1318,13 @@ func (a *AlertNode) renderMessageAndDetails(id, name string, t time.Time, group
 	return msg, details, nil
 }
 
+// triggered records that the alert of the state was triggered at time t.
+func (a *AlertNode) triggered(state *alertState, t time.Time) {
+	a.statesMu.Lock()
+	state.triggered(t)
+	a.statesMu.Unlock()
+}
+
 func (a *AlertNode) getAlertState(id models.GroupID) (state *alertState, ok bool) {
 	a.statesMu.RLock()
 	state, ok = a.states[id]
@@ -0,0 +1,190 @@
+package kapacitor
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/pkg/errors"
+)
+
+const (
+	anomalyExpectedField = "expected"
+	anomalyUpperField    = "upper"
+	anomalyLowerField    = "lower"
+	anomalyScoreField    = "anomalyScore"
+)
+
+type AnomalyNode struct {
+	node
+	a *pipeline.AnomalyNode
+
+	mu        sync.RWMutex
+	baselines map[models.GroupID]map[int]*anomalyBaseline
+}
+
+// anomalyBaseline is an exponentially weighted moving mean and variance.
+type anomalyBaseline struct {
+	Count    int64   `json:"count"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+}
+
+func (b *anomalyBaseline) update(value, alpha float64) {
+	if b.Count == 0 {
+		b.Mean = value
+		b.Variance = 0
+	} else {
+		diff := value - b.Mean
+		incr := alpha * diff
+		b.Mean += incr
+		b.Variance = (1 - alpha) * (b.Variance + diff*incr)
+	}
+	b.Count++
+}
+
+// Create a new anomaly node.
+func newAnomalyNode(et *ExecutingTask, n *pipeline.AnomalyNode, l *log.Logger) (*AnomalyNode, error) {
+	an := &AnomalyNode{
+		node:      node{Node: n, et: et, logger: l},
+		a:         n,
+		baselines: make(map[models.GroupID]map[int]*anomalyBaseline),
+	}
+	an.node.runF = an.runAnomaly
+	return an, nil
+}
+
+func (a *AnomalyNode) runAnomaly(snapshot []byte) error {
+	if snapshot != nil {
+		if err := a.restore(snapshot); err != nil {
+			return err
+		}
+	}
+	valueF := func() int64 {
+		a.mu.RLock()
+		l := len(a.baselines)
+		a.mu.RUnlock()
+		return int64(l)
+	}
+	a.statMap.Set(statCardinalityGauge, expvar.NewIntFuncGauge(valueF))
+
+	switch a.Provides() {
+	case pipeline.StreamEdge:
+		for p, ok := a.ins[0].NextPoint(); ok; p, ok = a.ins[0].NextPoint() {
+			a.timer.Start()
+			fields, emit := a.detect(p.Group, p.Time, p.Fields)
+			if emit {
+				p.Fields = fields
+				a.timer.Pause()
+				for _, child := range a.outs {
+					err := child.CollectPoint(p)
+					if err != nil {
+						return err
+					}
+				}
+				a.timer.Resume()
+			}
+			a.timer.Stop()
+		}
+	case pipeline.BatchEdge:
+		for b, ok := a.ins[0].NextBatch(); ok; b, ok = a.ins[0].NextBatch() {
+			a.timer.Start()
+			points := make([]models.BatchPoint, 0, len(b.Points))
+			for _, p := range b.Points {
+				fields, emit := a.detect(b.Group, p.Time, p.Fields)
+				if emit {
+					p.Fields = fields
+					points = append(points, p)
+				}
+			}
+			b.Points = points
+			a.timer.Stop()
+			for _, child := range a.outs {
+				err := child.CollectBatch(b)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// detect compares the value of the field to its baseline and then updates the baseline.
+// Returns the new fields and whether the point should be emitted.
+func (a *AnomalyNode) detect(group models.GroupID, t time.Time, fields models.Fields) (models.Fields, bool) {
+	value, ok := numToFloat(fields[a.a.Field])
+	if !ok {
+		a.incrementErrorCount()
+		a.logger.Printf("E! cannot detect anomalies of field %s with type %T", a.a.Field, fields[a.a.Field])
+		return nil, false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	buckets := a.baselines[group]
+	if buckets == nil {
+		buckets = make(map[int]*anomalyBaseline)
+		a.baselines[group] = buckets
+	}
+	bucket := a.bucket(t)
+	baseline := buckets[bucket]
+	if baseline == nil {
+		baseline = new(anomalyBaseline)
+		buckets[bucket] = baseline
+	}
+
+	emit := baseline.Count >= a.a.Warmup
+	var newFields models.Fields
+	if emit {
+		stddev := math.Sqrt(baseline.Variance)
+		score := 0.0
+		if stddev > 0 {
+			score = math.Abs(value-baseline.Mean) / stddev
+		}
+		newFields = fields.Copy()
+		newFields[anomalyExpectedField] = baseline.Mean
+		newFields[anomalyUpperField] = baseline.Mean + a.a.Sigmas*stddev
+		newFields[anomalyLowerField] = baseline.Mean - a.a.Sigmas*stddev
+		newFields[anomalyScoreField] = score
+	}
+	baseline.update(value, a.a.Alpha)
+	return newFields, emit
+}
+
+// bucket returns the season bucket of the time.
+func (a *AnomalyNode) bucket(t time.Time) int {
+	t = t.UTC()
+	switch a.a.Season {
+	case pipeline.AnomalySeasonHour:
+		return t.Hour()
+	case pipeline.AnomalySeasonWeekday:
+		return int(t.Weekday())
+	case pipeline.AnomalySeasonHourOfWeek:
+		return int(t.Weekday())*24 + t.Hour()
+	default:
+		return 0
+	}
+}
+
+func (a *AnomalyNode) snapshot() ([]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return json.Marshal(a.baselines)
+}
+
+func (a *AnomalyNode) restore(data []byte) error {
+	baselines := make(map[models.GroupID]map[int]*anomalyBaseline)
+	if err := json.Unmarshal(data, &baselines); err != nil {
+		return errors.Wrap(err, "failed to restore anomaly baselines")
+	}
+	a.mu.Lock()
+	a.baselines = baselines
+	a.mu.Unlock()
+	return nil
+}
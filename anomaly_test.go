@@ -0,0 +1,80 @@
+package kapacitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+func newTestAnomalyNode() *AnomalyNode {
+	return &AnomalyNode{
+		a: &pipeline.AnomalyNode{
+			Field:  "value",
+			Season: pipeline.AnomalySeasonHour,
+			Alpha:  0.5,
+			Sigmas: 2,
+			Warmup: 2,
+		},
+		baselines: make(map[models.GroupID]map[int]*anomalyBaseline),
+	}
+}
+
+func TestAnomalyNode_SnapshotRestore(t *testing.T) {
+	start := time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC)
+	training := []struct {
+		group models.GroupID
+		t     time.Time
+		value float64
+	}{
+		{group: "a", t: start, value: 10},
+		{group: "a", t: start.Add(10 * time.Minute), value: 14},
+		{group: "a", t: start.Add(time.Hour), value: 100},
+		{group: "b", t: start, value: 1},
+	}
+
+	an := newTestAnomalyNode()
+	for _, tc := range training {
+		if _, emit := an.detect(tc.group, tc.t, models.Fields{"value": tc.value}); emit {
+			t.Fatalf("unexpected emit during warmup for group %s at %v", tc.group, tc.t)
+		}
+	}
+
+	data, err := an.snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestAnomalyNode()
+	if err := restored.restore(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(an.baselines, restored.baselines) {
+		t.Fatalf("unexpected restored baselines:\ngot\n%v\nexp\n%v\n", restored.baselines, an.baselines)
+	}
+
+	next := start.Add(24 * time.Hour)
+	fields, emit := restored.detect("a", next, models.Fields{"value": 16.0})
+	if !emit {
+		t.Fatal("expected restored node to emit after warmup")
+	}
+	exp := models.Fields{
+		"value":        16.0,
+		"expected":     12.0,
+		"upper":        16.0,
+		"lower":        8.0,
+		"anomalyScore": 2.0,
+	}
+	if !reflect.DeepEqual(fields, exp) {
+		t.Errorf("unexpected fields:\ngot\n%v\nexp\n%v\n", fields, exp)
+	}
+
+	// Buckets that have not completed warmup are not emitted.
+	if _, emit := restored.detect("a", next.Add(time.Hour), models.Fields{"value": 100.0}); emit {
+		t.Error("unexpected emit for hour 1 of group a before warmup")
+	}
+	if _, emit := restored.detect("b", next, models.Fields{"value": 1.0}); emit {
+		t.Error("unexpected emit for group b before warmup")
+	}
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"fmt"
 	"path"
+	"sort"
 	"strings"
 )
 
@@ -11,10 +12,17 @@ type Interface interface {
 	Authenticate(username, password string) (User, error)
 	User(username string) (User, error)
 	SubscriptionUser(token string) (User, error)
+	// TokenUser returns the user of an API token, the token has the APITokenPrefix.
+	TokenUser(token string) (User, error)
 	GrantSubscriptionAccess(token, db, rp string) error
 	ListSubscriptionTokens() ([]string, error)
 	RevokeSubscriptionAccess(token string) error
 }
+
+// APITokenPrefix is the prefix of API tokens,
+// it distinguishes them from JWT bearer tokens.
+const APITokenPrefix = "kapacitor_"
+
 type Privilege uint
 
 const (
@@ -54,6 +62,40 @@ func (p Privilege) String() string {
 	}
 }
 
+// ParsePrivilege returns the privilege with the given name.
+func ParsePrivilege(s string) (Privilege, error) {
+	for _, p := range PrivilegeList {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return NoPrivileges, fmt.Errorf("unknown privilege %q", s)
+}
+
+// ParsePrivileges parses a map of resource to the names of its privileges,
+// i.e. "/api/tasks/ci-*": ["read", "write"]. The resources may be glob patterns.
+func ParsePrivileges(names map[string][]string) (map[string][]Privilege, error) {
+	privileges := make(map[string][]Privilege, len(names))
+	for resource, ps := range names {
+		if !path.IsAbs(resource) {
+			return nil, fmt.Errorf("invalid resource %q, must be an absolute path", resource)
+		}
+		if IsResourcePattern(resource) {
+			if _, err := path.Match(resource, ""); err != nil {
+				return nil, fmt.Errorf("invalid resource pattern %q: %s", resource, err)
+			}
+		}
+		for _, name := range ps {
+			p, err := ParsePrivilege(name)
+			if err != nil {
+				return nil, err
+			}
+			privileges[resource] = append(privileges[resource], p)
+		}
+	}
+	return privileges, nil
+}
+
 type Action struct {
 	Resource  string
 	Privilege Privilege
@@ -68,11 +110,14 @@ type User struct {
 	hash  []byte
 	// Map of resource -> Bitmask of Privileges
 	privileges map[string]Privilege
+	// Resources that are glob patterns, i.e. /api/tasks/ci-*
+	patterns []string
 }
 
 // Create a user with the given privileges.
 func NewUser(name string, hash []byte, admin bool, privileges map[string][]Privilege) User {
 	ps := make(map[string]Privilege, len(privileges))
+	var patterns []string
 	// Clean resources and convert to bitmask
 	for resource, privileges := range privileges {
 		clean := path.Clean(resource)
@@ -81,7 +126,11 @@ func NewUser(name string, hash []byte, admin bool, privileges map[string][]Privi
 			mask |= p
 		}
 		ps[clean] = mask
+		if IsResourcePattern(clean) {
+			patterns = append(patterns, clean)
+		}
 	}
+	sort.Strings(patterns)
 	// Make our own copy of the hash
 	h := make([]byte, len(hash))
 	copy(h, hash)
@@ -90,9 +139,16 @@ func NewUser(name string, hash []byte, admin bool, privileges map[string][]Privi
 		admin:      admin,
 		hash:       h,
 		privileges: ps,
+		patterns:   patterns,
 	}
 }
 
+// IsResourcePattern reports whether the resource is a glob pattern,
+// see https://golang.org/pkg/path/#Match for the syntax.
+func IsResourcePattern(resource string) bool {
+	return strings.ContainsAny(resource, `*?[\`)
+}
+
 // This user has all privileges for all resources.
 var AdminUser = NewUser("ADMIN_USER", nil, true, nil)
 
@@ -142,7 +198,7 @@ func (u User) AuthorizeAction(action Action) error {
 		// Clean path to prevent path traversal like /a/b/../d when user has access to /a/b
 		resource := path.Clean(action.Resource)
 		for {
-			if p, ok := u.privileges[resource]; ok {
+			if p, ok := u.lookup(resource); ok {
 				// Found matching resource
 				authorized := p&action.Privilege != 0 || p == AllPrivileges
 				if authorized {
@@ -164,6 +220,20 @@ func (u User) AuthorizeAction(action Action) error {
 	}
 }
 
+// lookup returns the privileges of the resource,
+// either directly or through the first resource pattern that matches it.
+func (u User) lookup(resource string) (Privilege, bool) {
+	if p, ok := u.privileges[resource]; ok {
+		return p, true
+	}
+	for _, pattern := range u.patterns {
+		if matched, _ := path.Match(pattern, resource); matched {
+			return u.privileges[pattern], true
+		}
+	}
+	return 0, false
+}
+
 // All auth errors are of this type.
 type authError struct {
 	username string
@@ -2,6 +2,7 @@ package auth_test
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/influxdata/kapacitor/auth"
@@ -45,6 +46,45 @@ func Test_Privilege_String(t *testing.T) {
 	}
 }
 
+func Test_ParsePrivilege(t *testing.T) {
+	for _, p := range auth.PrivilegeList {
+		got, err := auth.ParsePrivilege(p.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != p {
+			t.Errorf("unexpected privilege: got %v exp %v", got, p)
+		}
+	}
+	if _, err := auth.ParsePrivilege("execute"); err == nil {
+		t.Error("expected error parsing unknown privilege")
+	} else if exp, got := `unknown privilege "execute"`, err.Error(); got != exp {
+		t.Errorf("unexpected error message: got %q exp %q", got, exp)
+	}
+}
+
+func Test_ParsePrivileges(t *testing.T) {
+	got, err := auth.ParsePrivileges(map[string][]string{
+		"/api/tasks/ci-*": {"read", "write"},
+		"/database/db":    {"all"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string][]auth.Privilege{
+		"/api/tasks/ci-*": {auth.ReadPrivilege, auth.WritePrivilege},
+		"/database/db":    {auth.AllPrivileges},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected privileges: got %v exp %v", got, exp)
+	}
+	for _, resource := range []string{"api/tasks", "/api/tasks/["} {
+		if _, err := auth.ParsePrivileges(map[string][]string{resource: {"read"}}); err == nil {
+			t.Errorf("expected error parsing invalid resource %q", resource)
+		}
+	}
+}
+
 func Test_NewUser(t *testing.T) {
 	privs := map[string][]auth.Privilege{
 		"/simple/path/":               []auth.Privilege{auth.ReadPrivilege, auth.WritePrivilege},
@@ -354,6 +394,43 @@ func Test_User_AuthorizeAction(t *testing.T) {
 			authorized: false,
 			err:        errors.New(`user hackerjim does not have "write" privilege for resource "/a/b/c/../../../../d/e/f"`),
 		},
+		{
+			username: "patty",
+			privileges: map[string][]auth.Privilege{
+				"/a/b/c-*": []auth.Privilege{auth.WritePrivilege},
+			},
+			action: auth.Action{
+				Resource:  "/a/b/c-d/e",
+				Privilege: auth.WritePrivilege,
+			},
+			authorized: true,
+			err:        nil,
+		},
+		{
+			username: "pete",
+			privileges: map[string][]auth.Privilege{
+				"/a/b/c-*": []auth.Privilege{auth.WritePrivilege},
+			},
+			action: auth.Action{
+				Resource:  "/a/b/d",
+				Privilege: auth.WritePrivilege,
+			},
+			authorized: false,
+			err:        errors.New(`user pete does not have "write" privilege for resource "/a/b/d"`),
+		},
+		{
+			username: "penny",
+			privileges: map[string][]auth.Privilege{
+				"/a/*/c": []auth.Privilege{auth.ReadPrivilege},
+				"/a":     []auth.Privilege{auth.WritePrivilege},
+			},
+			action: auth.Action{
+				Resource:  "/a/b/c",
+				Privilege: auth.WritePrivilege,
+			},
+			authorized: false,
+			err:        errors.New(`user penny does not have "write" privilege for resource "/a/b/c"`),
+		},
 		{
 			username: "admin",
 			admin:    true,
@@ -81,7 +81,8 @@ fpm_common_args = "-f -s dir --log error \
 targets = {
     'kapacitor' : './cmd/kapacitor',
     'kapacitord' : './cmd/kapacitord',
-    'tickfmt' : './tick/cmd/tickfmt'
+    'tickfmt' : './tick/cmd/tickfmt',
+    'tickls' : './tick/cmd/tickls'
 }
 
 supported_builds = {
@@ -31,9 +31,16 @@ const (
 	basePreviewPath   = "/kapacitor/v1preview"
 	pingPath          = basePath + "/ping"
 	logLevelPath      = basePath + "/loglevel"
+	auditPath         = basePath + "/audit"
+	applyPath         = basePath + "/apply"
 	debugVarsPath     = basePath + "/debug/vars"
 	tasksPath         = basePath + "/tasks"
+	lintTaskPath      = basePath + "/tasks/lint"
+	evaluateTaskPath  = basePath + "/tasks/evaluate"
+	taskGraphPath     = basePath + "/tasks/graph"
+	taskGroupsPath    = basePath + "/task-groups"
 	templatesPath     = basePath + "/templates"
+	modulesPath       = basePath + "/modules"
 	recordingsPath    = basePath + "/recordings"
 	recordStreamPath  = basePath + "/recordings/stream"
 	recordBatchPath   = basePath + "/recordings/batch"
@@ -50,6 +57,8 @@ const (
 	storagePath       = basePath + "/storage"
 	storesPath        = storagePath + "/stores"
 	backupPath        = storagePath + "/backup"
+	usersPath         = basePath + "/users"
+	apiTokensPath     = basePath + "/tokens"
 )
 
 // HTTP configuration for connecting to Kapacitor
@@ -73,6 +82,10 @@ type Config struct {
 
 	// Optional credentials for authenticating with the server.
 	Credentials *Credentials
+
+	// Transport performs the HTTP requests if set.
+	// If set, this option overrides InsecureSkipVerify and TLSConfig.
+	Transport http.RoundTripper
 }
 
 // AuthenticationMethod defines the type of authentication used.
@@ -157,12 +170,16 @@ func New(conf Config) (*Client, error) {
 	if conf.TLSConfig != nil {
 		tr.TLSClientConfig = conf.TLSConfig
 	}
+	var transport http.RoundTripper = tr
+	if conf.Transport != nil {
+		transport = conf.Transport
+	}
 	return &Client{
 		url:       u,
 		userAgent: conf.UserAgent,
 		httpClient: &http.Client{
 			Timeout:   conf.Timeout,
-			Transport: tr,
+			Transport: transport,
 		},
 		credentials: conf.Credentials,
 	}, nil
@@ -242,6 +259,9 @@ type TaskStatus int
 const (
 	Disabled TaskStatus = 1
 	Enabled  TaskStatus = 2
+	// The task exceeded one of its limits and was stopped.
+	// Enable the task to start it again.
+	Quarantined TaskStatus = 3
 )
 
 func (ts TaskStatus) MarshalText() ([]byte, error) {
@@ -250,6 +270,8 @@ func (ts TaskStatus) MarshalText() ([]byte, error) {
 		return []byte("disabled"), nil
 	case Enabled:
 		return []byte("enabled"), nil
+	case Quarantined:
+		return []byte("quarantined"), nil
 	default:
 		return nil, fmt.Errorf("unknown TaskStatus %d", ts)
 	}
@@ -261,6 +283,8 @@ func (ts *TaskStatus) UnmarshalText(text []byte) error {
 		*ts = Enabled
 	case "disabled":
 		*ts = Disabled
+	case "quarantined":
+		*ts = Quarantined
 	default:
 		return fmt.Errorf("unknown TaskStatus %s", s)
 	}
@@ -275,6 +299,86 @@ func (ts TaskStatus) String() string {
 	return string(s)
 }
 
+// The action taken when a task exceeds one of its limits.
+type LimitAction int
+
+const (
+	// Use the action configured on the server.
+	DefaultLimitAction LimitAction = 0
+	// Discard the oldest data of the limited queue or buffer,
+	// limits that cannot be enforced by discarding data pause the task instead.
+	LimitDropOldest LimitAction = 1
+	// Drop the incoming data of the task until it is back under its limits.
+	LimitPause LimitAction = 2
+	// Stop the task and set its status to quarantined.
+	LimitStop LimitAction = 3
+)
+
+func (a LimitAction) MarshalText() ([]byte, error) {
+	switch a {
+	case DefaultLimitAction:
+		return []byte(""), nil
+	case LimitDropOldest:
+		return []byte("drop-oldest"), nil
+	case LimitPause:
+		return []byte("pause"), nil
+	case LimitStop:
+		return []byte("stop"), nil
+	default:
+		return nil, fmt.Errorf("unknown LimitAction %d", a)
+	}
+}
+
+func (a *LimitAction) UnmarshalText(text []byte) error {
+	switch s := string(text); s {
+	case "":
+		*a = DefaultLimitAction
+	case "drop-oldest":
+		*a = LimitDropOldest
+	case "pause":
+		*a = LimitPause
+	case "stop":
+		*a = LimitStop
+	default:
+		return fmt.Errorf("unknown LimitAction %s", s)
+	}
+	return nil
+}
+
+func (a LimitAction) String() string {
+	s, err := a.MarshalText()
+	if err != nil {
+		return err.Error()
+	}
+	if len(s) == 0 {
+		return "default"
+	}
+	return string(s)
+}
+
+// The limits of the resources used by a task.
+// Zero values use the limits configured on the server.
+type TaskLimits struct {
+	// Maximum number of groups of any node of the task.
+	// The groups are never removed, so a task exceeding it is always quarantined whatever the action.
+	MaxGroups int64 `json:"max-groups,omitempty"`
+	// Maximum number of points, or batches for batch tasks, buffered by any window or join node of the task.
+	MaxBufferedPoints int64 `json:"max-buffered-points,omitempty"`
+	// Maximum number of points or batches queued on an edge between two nodes of the task.
+	MaxEdgeQueue int64 `json:"max-edge-queue,omitempty"`
+	// Maximum number of CPUs the nodes of the task keep busy, i.e. 0.5 is half of one CPU.
+	MaxCPU float64 `json:"max-cpu,omitempty"`
+	// How often the groups, buffered points and CPU time of the task are sampled.
+	SampleInterval Duration `json:"sample-interval,omitempty"`
+	// The action taken when a limit is exceeded.
+	Action LimitAction `json:"action,omitempty"`
+}
+
+// Whether no limit is set.
+func (l TaskLimits) IsZero() bool {
+	return l == TaskLimits{}
+}
+
 type Status int
 
 const (
@@ -520,36 +624,73 @@ type Var struct {
 
 // A Task plus its read-only attributes.
 type Task struct {
-	Link           Link           `json:"link"`
-	ID             string         `json:"id"`
-	TemplateID     string         `json:"template-id"`
-	Type           TaskType       `json:"type"`
-	DBRPs          []DBRP         `json:"dbrps"`
-	TICKscript     string         `json:"script"`
-	Vars           Vars           `json:"vars"`
-	Dot            string         `json:"dot"`
-	Status         TaskStatus     `json:"status"`
-	Executing      bool           `json:"executing"`
-	Error          string         `json:"error"`
-	ExecutionStats ExecutionStats `json:"stats"`
-	Created        time.Time      `json:"created"`
-	Modified       time.Time      `json:"modified"`
-	LastEnabled    time.Time      `json:"last-enabled,omitempty"`
+	Link           Link             `json:"link"`
+	ID             string           `json:"id"`
+	TemplateID     string           `json:"template-id"`
+	Type           TaskType         `json:"type"`
+	DBRPs          []DBRP           `json:"dbrps"`
+	TICKscript     string           `json:"script"`
+	Vars           Vars             `json:"vars"`
+	Modules        map[string]int64 `json:"modules,omitempty"`
+	Dot            string           `json:"dot"`
+	Status         TaskStatus       `json:"status"`
+	Limits         TaskLimits       `json:"limits"`
+	Executing      bool             `json:"executing"`
+	Paused         bool             `json:"paused,omitempty"`
+	Error          string           `json:"error"`
+	ExecutionStats ExecutionStats   `json:"stats"`
+	Created        time.Time        `json:"created"`
+	Modified       time.Time        `json:"modified"`
+	LastEnabled    time.Time        `json:"last-enabled,omitempty"`
 }
 
 // A Template plus its read-only attributes.
 type Template struct {
+	Link       Link             `json:"link"`
+	ID         string           `json:"id"`
+	Type       TaskType         `json:"type"`
+	TICKscript string           `json:"script"`
+	Vars       Vars             `json:"vars"`
+	Dot        string           `json:"dot"`
+	Error      string           `json:"error"`
+	Created    time.Time        `json:"created"`
+	Modified   time.Time        `json:"modified"`
+	Modules    map[string]int64 `json:"modules,omitempty"`
+}
+
+// A Module is a TICKscript that can be imported by tasks and templates.
+type Module struct {
 	Link       Link      `json:"link"`
 	ID         string    `json:"id"`
-	Type       TaskType  `json:"type"`
 	TICKscript string    `json:"script"`
-	Vars       Vars      `json:"vars"`
-	Dot        string    `json:"dot"`
-	Error      string    `json:"error"`
+	Version    int64     `json:"version"`
 	Created    time.Time `json:"created"`
 	Modified   time.Time `json:"modified"`
 }
 
+// A User of the local auth service.
+type User struct {
+	Link  Link   `json:"link"`
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+	// Map of resource to the names of its privileges, i.e. "/api/tasks": ["read", "write"].
+	Privileges map[string][]string `json:"privileges"`
+}
+
+// An APIToken of the local auth service.
+type APIToken struct {
+	Link Link   `json:"link"`
+	Name string `json:"name"`
+	// The token to use as a bearer token, it is only returned when the token is created.
+	Token string `json:"token,omitempty"`
+	// Map of resource or resource pattern to the names of its privileges, i.e. "/api/tasks/ci-*": ["read", "write"].
+	Privileges map[string][]string `json:"privileges"`
+	Created    time.Time           `json:"created"`
+	// Expires is the zero time if the token does not expire.
+	Expires  time.Time `json:"expires"`
+	LastUsed time.Time `json:"last-used"`
+}
+
 // Information about a recording.
 type Recording struct {
 	Link     Link      `json:"link"`
@@ -683,10 +824,26 @@ func (c *Client) TaskLink(id string) Link {
 	return Link{Relation: Self, Href: path.Join(tasksPath, id)}
 }
 
+func (c *Client) TaskGroupLink(id string) Link {
+	return Link{Relation: Self, Href: path.Join(taskGroupsPath, id)}
+}
+
 func (c *Client) TemplateLink(id string) Link {
 	return Link{Relation: Self, Href: path.Join(templatesPath, id)}
 }
 
+func (c *Client) ModuleLink(id string) Link {
+	return Link{Relation: Self, Href: path.Join(modulesPath, id)}
+}
+
+func (c *Client) UserLink(name string) Link {
+	return Link{Relation: Self, Href: path.Join(usersPath, name)}
+}
+
+func (c *Client) APITokenLink(name string) Link {
+	return Link{Relation: Self, Href: path.Join(apiTokensPath, name)}
+}
+
 func (c *Client) ConfigSectionLink(section string) Link {
 	return Link{Relation: Self, Href: path.Join(configPath, section)}
 }
@@ -725,13 +882,14 @@ func (c *Client) StorageLink(name string) Link {
 }
 
 type CreateTaskOptions struct {
-	ID         string     `json:"id,omitempty"`
-	TemplateID string     `json:"template-id,omitempty"`
-	Type       TaskType   `json:"type,omitempty"`
-	DBRPs      []DBRP     `json:"dbrps,omitempty"`
-	TICKscript string     `json:"script,omitempty"`
-	Status     TaskStatus `json:"status,omitempty"`
-	Vars       Vars       `json:"vars,omitempty"`
+	ID         string      `json:"id,omitempty"`
+	TemplateID string      `json:"template-id,omitempty"`
+	Type       TaskType    `json:"type,omitempty"`
+	DBRPs      []DBRP      `json:"dbrps,omitempty"`
+	TICKscript string      `json:"script,omitempty"`
+	Status     TaskStatus  `json:"status,omitempty"`
+	Vars       Vars        `json:"vars,omitempty"`
+	Limits     *TaskLimits `json:"limits,omitempty"`
 }
 
 // Create a new task.
@@ -758,19 +916,176 @@ func (c *Client) CreateTask(opt CreateTaskOptions) (Task, error) {
 	return t, err
 }
 
+type LintTaskOptions struct {
+	Type       TaskType    `json:"type,omitempty"`
+	DBRPs      []DBRP      `json:"dbrps,omitempty"`
+	TICKscript string      `json:"script,omitempty"`
+	Vars       Vars        `json:"vars,omitempty"`
+	Schema     *LintSchema `json:"schema,omitempty"`
+	// Name of the InfluxDB cluster to sample the schema from when no schema is provided.
+	Cluster string `json:"cluster,omitempty"`
+}
+
+// LintSchema describes the fields and tags of the measurements a task reads.
+type LintSchema struct {
+	Measurements map[string]LintMeasurement `json:"measurements"`
+}
+
+// LintMeasurement describes the fields, mapped to their InfluxDB type, and tags of a measurement.
+type LintMeasurement struct {
+	Fields map[string]string `json:"fields"`
+	Tags   []string          `json:"tags"`
+}
+
+type LintProblem struct {
+	Line    int    `json:"line"`
+	Char    int    `json:"char"`
+	Message string `json:"message"`
+}
+
+func (p LintProblem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d char %d: %s", p.Line, p.Char, p.Message)
+}
+
+type LintResult struct {
+	Problems []LintProblem `json:"problems"`
+}
+
+// Lint a TICKscript without creating a task.
+// Errors if the TICKscript is invalid, otherwise the problems found in the TICKscript are returned.
+func (c *Client) LintTask(opt LintTaskOptions) (LintResult, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return LintResult{}, err
+	}
+
+	u := *c.url
+	u.Path = lintTaskPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return LintResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r := LintResult{}
+	_, err = c.Do(req, &r, http.StatusOK)
+	return r, err
+}
+
+type EvaluateTaskOptions struct {
+	Type       TaskType `json:"type,omitempty"`
+	DBRPs      []DBRP   `json:"dbrps,omitempty"`
+	TICKscript string   `json:"script,omitempty"`
+	// Lambda expression to evaluate against each point instead of a TICKscript.
+	Lambda string `json:"lambda,omitempty"`
+	Vars   Vars   `json:"vars,omitempty"`
+	// Points in line protocol, they are evaluated in order.
+	Points string `json:"points"`
+	// Precision of the timestamps of the points, defaults to nanoseconds.
+	Precision string `json:"precision,omitempty"`
+}
+
+// EvaluatePoint is a point emitted while evaluating a task.
+type EvaluatePoint struct {
+	Database        string                 `json:"database,omitempty"`
+	RetentionPolicy string                 `json:"retention-policy,omitempty"`
+	Name            string                 `json:"name"`
+	Tags            map[string]string      `json:"tags,omitempty"`
+	Fields          map[string]interface{} `json:"fields"`
+	Time            time.Time              `json:"time"`
+}
+
+// EvaluateAlert is an alert event triggered while evaluating a task.
+// Alert events are never sent to the alert handlers.
+type EvaluateAlert struct {
+	// Topic of the event, empty if the alert node has no topic.
+	Topic string     `json:"topic,omitempty"`
+	ID    string     `json:"id"`
+	State EventState `json:"state"`
+}
+
+// EvaluateTrace is the data collected by a node from its parent while evaluating a task.
+type EvaluateTrace struct {
+	Parent string          `json:"parent"`
+	Child  string          `json:"child"`
+	Points []EvaluatePoint `json:"points"`
+}
+
+// EvaluateValue is the result of evaluating a lambda expression against a point.
+type EvaluateValue struct {
+	Value interface{} `json:"value"`
+	Error string      `json:"error,omitempty"`
+}
+
+type EvaluateResult struct {
+	// Values of the lambda expression for each point.
+	Values []EvaluateValue `json:"values,omitempty"`
+	// Points written by the InfluxDBOut nodes of the task.
+	Points []EvaluatePoint `json:"points,omitempty"`
+	Alerts []EvaluateAlert `json:"alerts,omitempty"`
+	Traces []EvaluateTrace `json:"traces,omitempty"`
+	// Error returned by the task, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Evaluate a TICKscript or a lambda expression against sample points without creating a task.
+// The task does not read live data and nothing is written to InfluxDB or sent to alert handlers,
+// instead the points written, the alerts triggered and the data collected by each node are returned.
+// TICKscripts with httpPost, k8sAutoscale, httpOut or UDF nodes cannot be evaluated.
+func (c *Client) EvaluateTask(opt EvaluateTaskOptions) (EvaluateResult, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return EvaluateResult{}, err
+	}
+
+	u := *c.url
+	u.Path = evaluateTaskPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return EvaluateResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r := EvaluateResult{}
+	_, err = c.Do(req, &r, http.StatusOK)
+	return r, err
+}
+
 type UpdateTaskOptions struct {
-	ID         string     `json:"id,omitempty"`
-	TemplateID string     `json:"template-id,omitempty"`
-	Type       TaskType   `json:"type,omitempty"`
-	DBRPs      []DBRP     `json:"dbrps,omitempty"`
-	TICKscript string     `json:"script,omitempty"`
-	Status     TaskStatus `json:"status,omitempty"`
-	Vars       Vars       `json:"vars,omitempty"`
+	ID         string      `json:"id,omitempty"`
+	TemplateID string      `json:"template-id,omitempty"`
+	Type       TaskType    `json:"type,omitempty"`
+	DBRPs      []DBRP      `json:"dbrps,omitempty"`
+	TICKscript string      `json:"script,omitempty"`
+	Status     TaskStatus  `json:"status,omitempty"`
+	Vars       Vars        `json:"vars,omitempty"`
+	Limits     *TaskLimits `json:"limits,omitempty"`
 }
 
 // Update an existing task.
 // Only fields that are not their default value will be updated.
 func (c *Client) UpdateTask(link Link, opt UpdateTaskOptions) (Task, error) {
+	return c.updateTask(link, opt, nil)
+}
+
+// Redefine an existing task.
+// The template ID and vars replace those of the task even when they are empty,
+// so the task is detached from its template if no template ID is set.
+// The other fields are only updated if they are not their default value, the same as UpdateTask.
+func (c *Client) RedefineTask(link Link, opt UpdateTaskOptions) (Task, error) {
+	return c.updateTask(link, opt, url.Values{"replace": []string{"true"}})
+}
+
+func (c *Client) updateTask(link Link, opt UpdateTaskOptions, query url.Values) (Task, error) {
 	t := Task{}
 	if link.Href == "" {
 		return t, fmt.Errorf("invalid link %v", link)
@@ -785,6 +1100,7 @@ func (c *Client) UpdateTask(link Link, opt UpdateTaskOptions) (Task, error) {
 
 	u := *c.url
 	u.Path = link.Href
+	u.RawQuery = query.Encode()
 
 	req, err := http.NewRequest("PATCH", u.String(), &buf)
 	if err != nil {
@@ -799,41 +1115,917 @@ func (c *Client) UpdateTask(link Link, opt UpdateTaskOptions) (Task, error) {
 	return t, nil
 }
 
-type TaskOptions struct {
-	DotView      string
+type TaskOptions struct {
+	DotView      string
+	ScriptFormat string
+	ReplayID     string
+}
+
+func (o *TaskOptions) Default() {
+	if o.DotView == "" {
+		o.DotView = "attributes"
+	}
+	if o.ScriptFormat == "" {
+		o.ScriptFormat = "formatted"
+	}
+}
+
+func (o *TaskOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("dot-view", o.DotView)
+	v.Set("script-format", o.ScriptFormat)
+	v.Set("replay-id", o.ReplayID)
+	return v
+}
+
+// Get information about a task.
+// Options can be nil and the default options will be used.
+// By default the DOT content will use attributes for stats. Use DotView="labels" to generate a purley labels based DOT content, which can accurately be rendered but is less readable.
+// By default the TICKscript contents are formatted, use ScriptFormat="raw" to return the TICKscript unmodified.
+func (c *Client) Task(link Link, opt *TaskOptions) (Task, error) {
+	task := Task{}
+	if link.Href == "" {
+		return task, fmt.Errorf("invalid link %v", link)
+	}
+
+	if opt == nil {
+		opt = new(TaskOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = link.Href
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return task, err
+	}
+
+	_, err = c.Do(req, &task, http.StatusOK)
+	if err != nil {
+		return task, err
+	}
+	return task, nil
+}
+
+// Delete a task.
+func (c *Client) DeleteTask(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+type ListTasksOptions struct {
+	TaskOptions
+	Pattern string
+	Fields  []string
+	Offset  int
+	Limit   int
+}
+
+func (o *ListTasksOptions) Default() {
+	o.TaskOptions.Default()
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *ListTasksOptions) Values() *url.Values {
+	v := o.TaskOptions.Values()
+	v.Set("pattern", o.Pattern)
+	for _, field := range o.Fields {
+		v.Add("fields", field)
+	}
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+// Get tasks.
+func (c *Client) ListTasks(opt *ListTasksOptions) ([]Task, error) {
+	if opt == nil {
+		opt = new(ListTasksOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = tasksPath
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response type
+	type response struct {
+		Tasks []Task `json:"tasks"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Tasks, nil
+}
+
+// A TaskRevision is the definition of a task at the time it was changed.
+type TaskRevision struct {
+	Link       Link      `json:"link"`
+	TaskID     string    `json:"task-id"`
+	Revision   int64     `json:"revision"`
+	TemplateID string    `json:"template-id"`
+	Type       TaskType  `json:"type"`
+	DBRPs      []DBRP    `json:"dbrps"`
+	TICKscript string    `json:"script"`
+	Vars       Vars      `json:"vars"`
+	Author     string    `json:"author"`
+	Created    time.Time `json:"created"`
+}
+
+// TaskRevisionDiff describes the changes between two revisions of a task.
+type TaskRevisionDiff struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+	// Changes of the type, template, dbrps and vars of the task.
+	Changes []string `json:"changes"`
+	// Unified diff of the TICKscripts, empty if they are the same.
+	ScriptDiff string `json:"script-diff"`
+}
+
+type ListTaskRevisionsOptions struct {
+	Offset int
+	Limit  int
+}
+
+func (o *ListTaskRevisionsOptions) Default() {
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *ListTaskRevisionsOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+// Get the revisions of a task, the most recent revision first.
+func (c *Client) ListTaskRevisions(link Link, opt *ListTaskRevisionsOptions) ([]TaskRevision, error) {
+	if link.Href == "" {
+		return nil, fmt.Errorf("invalid link %v", link)
+	}
+	if opt == nil {
+		opt = new(ListTaskRevisionsOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "revisions")
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response type
+	type response struct {
+		Revisions []TaskRevision `json:"revisions"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Revisions, nil
+}
+
+// Get a revision of a task.
+func (c *Client) TaskRevision(link Link) (TaskRevision, error) {
+	r := TaskRevision{}
+	if link.Href == "" {
+		return r, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return r, err
+	}
+
+	_, err = c.Do(req, &r, http.StatusOK)
+	return r, err
+}
+
+type DiffTaskRevisionsOptions struct {
+	// From is the older revision, by default the revision before To.
+	From int64
+	// To is the newer revision, by default the latest revision.
+	To int64
+}
+
+func (o *DiffTaskRevisionsOptions) Values() *url.Values {
+	v := &url.Values{}
+	if o.From != 0 {
+		v.Set("from", strconv.FormatInt(o.From, 10))
+	}
+	if o.To != 0 {
+		v.Set("to", strconv.FormatInt(o.To, 10))
+	}
+	return v
+}
+
+// Compare two revisions of a task.
+// Options can be nil and the latest revision is compared to the revision before it.
+func (c *Client) DiffTaskRevisions(link Link, opt *DiffTaskRevisionsOptions) (TaskRevisionDiff, error) {
+	diff := TaskRevisionDiff{}
+	if link.Href == "" {
+		return diff, fmt.Errorf("invalid link %v", link)
+	}
+	if opt == nil {
+		opt = new(DiffTaskRevisionsOptions)
+	}
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "diff")
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return diff, err
+	}
+
+	_, err = c.Do(req, &diff, http.StatusOK)
+	return diff, err
+}
+
+// Rollback a task to one of its revisions.
+// The rollback is recorded as a new revision of the task.
+func (c *Client) RollbackTask(link Link, revision int64) (Task, error) {
+	t := Task{}
+	if link.Href == "" {
+		return t, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "rollback")
+	u.RawQuery = url.Values{"revision": []string{strconv.FormatInt(revision, 10)}}.Encode()
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return t, err
+	}
+
+	_, err = c.Do(req, &t, http.StatusOK)
+	return t, err
+}
+
+func (c *Client) TaskOutput(link Link, name string) (*influxql.Result, error) {
+	u := *c.url
+	u.Path = path.Join(link.Href, name)
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	r := &influxql.Result{}
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// A TaskGroup is a set of tasks that are enabled and disabled together,
+// in the order of their dependencies.
+type TaskGroup struct {
+	Link  Link     `json:"link"`
+	ID    string   `json:"id"`
+	Tasks []string `json:"tasks"`
+	// Map of task ID to the IDs of the tasks it depends on.
+	// A task is enabled after and disabled before the tasks it depends on.
+	Dependencies map[string][]string `json:"dependencies"`
+	// Order is the order the tasks are enabled in,
+	// it honors both the declared dependencies and the dependencies inferred from KapacitorLoopback nodes.
+	Order []string `json:"order"`
+	// Error is set if the tasks cannot be ordered, i.e. their dependencies have a cycle.
+	Error    string    `json:"error"`
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+}
+
+type CreateTaskGroupOptions struct {
+	ID           string              `json:"id,omitempty"`
+	Tasks        []string            `json:"tasks,omitempty"`
+	Dependencies map[string][]string `json:"dependencies,omitempty"`
+}
+
+// Create a new task group.
+// Errors if the task group already exists.
+func (c *Client) CreateTaskGroup(opt CreateTaskGroupOptions) (TaskGroup, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return TaskGroup{}, err
+	}
+
+	u := *c.url
+	u.Path = taskGroupsPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return TaskGroup{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	g := TaskGroup{}
+	_, err = c.Do(req, &g, http.StatusOK)
+	return g, err
+}
+
+type UpdateTaskGroupOptions struct {
+	ID           string              `json:"id,omitempty"`
+	Tasks        []string            `json:"tasks,omitempty"`
+	Dependencies map[string][]string `json:"dependencies,omitempty"`
+}
+
+// Update an existing task group.
+// Only fields that are not their default value will be updated,
+// the tasks and dependencies are replaced as a whole.
+func (c *Client) UpdateTaskGroup(link Link, opt UpdateTaskGroupOptions) (TaskGroup, error) {
+	g := TaskGroup{}
+	if link.Href == "" {
+		return g, fmt.Errorf("invalid link %v", link)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return g, err
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
+	if err != nil {
+		return g, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &g, http.StatusOK)
+	return g, err
+}
+
+// Get information about a task group.
+func (c *Client) TaskGroup(link Link) (TaskGroup, error) {
+	g := TaskGroup{}
+	if link.Href == "" {
+		return g, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return g, err
+	}
+
+	_, err = c.Do(req, &g, http.StatusOK)
+	return g, err
+}
+
+// Delete a task group.
+// The tasks of the group are not changed.
+func (c *Client) DeleteTaskGroup(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+type ListTaskGroupsOptions struct {
+	Pattern string
+	Offset  int
+	Limit   int
+}
+
+func (o *ListTaskGroupsOptions) Default() {
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *ListTaskGroupsOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("pattern", o.Pattern)
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+// Get task groups.
+func (c *Client) ListTaskGroups(opt *ListTaskGroupsOptions) ([]TaskGroup, error) {
+	if opt == nil {
+		opt = new(ListTaskGroupsOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = taskGroupsPath
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response type
+	type response struct {
+		TaskGroups []TaskGroup `json:"task-groups"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.TaskGroups, nil
+}
+
+// Enable the tasks of a task group, in the order of their dependencies.
+func (c *Client) EnableTaskGroup(link Link) (TaskGroup, error) {
+	return c.taskGroupAction(link, "enable")
+}
+
+// Disable the tasks of a task group, in the reverse order of their dependencies.
+func (c *Client) DisableTaskGroup(link Link) (TaskGroup, error) {
+	return c.taskGroupAction(link, "disable")
+}
+
+// Reload the tasks of a task group.
+// The tasks are disabled in the reverse order of their dependencies and then enabled in order.
+func (c *Client) ReloadTaskGroup(link Link) (TaskGroup, error) {
+	return c.taskGroupAction(link, "reload")
+}
+
+func (c *Client) taskGroupAction(link Link, action string) (TaskGroup, error) {
+	g := TaskGroup{}
+	if link.Href == "" {
+		return g, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = path.Join(link.Href, action)
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return g, err
+	}
+
+	_, err = c.Do(req, &g, http.StatusOK)
+	return g, err
+}
+
+// A TaskGraphEdge is a dependency between two tasks.
+// The From task is enabled after and disabled before the To task.
+type TaskGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Inferred is true if the From task writes data the To task reads via a KapacitorLoopback node,
+	// otherwise the dependency was declared in a task group.
+	Inferred bool `json:"inferred"`
+	// Label is the database, retention policy and measurement written, if the edge is inferred.
+	Label string `json:"label"`
+}
+
+// A TaskGraph is the graph of the dependencies between all tasks.
+type TaskGraph struct {
+	Link Link `json:"link"`
+	// Dot is the graph in DOT format.
+	Dot   string          `json:"dot"`
+	Edges []TaskGraphEdge `json:"edges"`
+}
+
+// Get the graph of the dependencies between tasks.
+func (c *Client) TaskGraph() (TaskGraph, error) {
+	g := TaskGraph{}
+
+	u := *c.url
+	u.Path = taskGraphPath
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return g, err
+	}
+
+	_, err = c.Do(req, &g, http.StatusOK)
+	return g, err
+}
+
+type CreateTemplateOptions struct {
+	ID         string   `json:"id,omitempty"`
+	Type       TaskType `json:"type,omitempty"`
+	TICKscript string   `json:"script,omitempty"`
+}
+
+// Create a new template.
+// Errors if the template already exists.
+func (c *Client) CreateTemplate(opt CreateTemplateOptions) (Template, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return Template{}, err
+	}
+
+	u := *c.url
+	u.Path = templatesPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return Template{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	t := Template{}
+	_, err = c.Do(req, &t, http.StatusOK)
+	return t, err
+}
+
+type UpdateTemplateOptions struct {
+	ID         string   `json:"id,omitempty"`
+	Type       TaskType `json:"type,omitempty"`
+	TICKscript string   `json:"script,omitempty"`
+}
+
+// Update an existing template.
+// Only fields that are not their default value will be updated.
+func (c *Client) UpdateTemplate(link Link, opt UpdateTemplateOptions) (Template, error) {
+	t := Template{}
+	if link.Href == "" {
+		return t, fmt.Errorf("invalid link %v", link)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return t, err
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
+	if err != nil {
+		return t, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &t, http.StatusOK)
+	if err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+type TemplateOptions struct {
+	ScriptFormat string
+}
+
+func (o *TemplateOptions) Default() {
+	if o.ScriptFormat == "" {
+		o.ScriptFormat = "formatted"
+	}
+}
+
+func (o *TemplateOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("script-format", o.ScriptFormat)
+	return v
+}
+
+// Get information about a template.
+// Options can be nil and the default options will be used.
+// By default the TICKscript contents are formatted, use ScriptFormat="raw" to return the TICKscript unmodified.
+func (c *Client) Template(link Link, opt *TemplateOptions) (Template, error) {
+	template := Template{}
+	if link.Href == "" {
+		return template, fmt.Errorf("invalid link %v", link)
+	}
+
+	if opt == nil {
+		opt = new(TemplateOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = link.Href
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return template, err
+	}
+
+	_, err = c.Do(req, &template, http.StatusOK)
+	if err != nil {
+		return template, err
+	}
+	return template, nil
+}
+
+// Delete a template.
+func (c *Client) DeleteTemplate(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
+}
+
+type ListTemplatesOptions struct {
+	TemplateOptions
+	Pattern string
+	Fields  []string
+	Offset  int
+	Limit   int
+}
+
+func (o *ListTemplatesOptions) Default() {
+	o.TemplateOptions.Default()
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *ListTemplatesOptions) Values() *url.Values {
+	v := o.TemplateOptions.Values()
+	v.Set("pattern", o.Pattern)
+	for _, field := range o.Fields {
+		v.Add("fields", field)
+	}
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+// Get templates.
+func (c *Client) ListTemplates(opt *ListTemplatesOptions) ([]Template, error) {
+	if opt == nil {
+		opt = new(ListTemplatesOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = templatesPath
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response type
+	type response struct {
+		Templates []Template `json:"templates"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Templates, nil
+}
+
+// A TemplateTask is a task created from a template.
+type TemplateTask struct {
+	Link      Link       `json:"link"`
+	ID        string     `json:"id"`
+	Status    TaskStatus `json:"status"`
+	Executing bool       `json:"executing"`
+	DBRPs     []DBRP     `json:"dbrps"`
+	Vars      Vars       `json:"vars"`
+	Error     string     `json:"error"`
+	Modified  time.Time  `json:"modified"`
+}
+
+// Get the tasks created from a template and their vars.
+func (c *Client) ListTemplateTasks(link Link) ([]TemplateTask, error) {
+	if link.Href == "" {
+		return nil, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = path.Join(link.Href, "tasks")
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response type
+	type response struct {
+		Tasks []TemplateTask `json:"tasks"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Tasks, nil
+}
+
+// TemplateTaskPreview describes the change an update of a template would make to a task created from it.
+type TemplateTaskPreview struct {
+	Link   Link       `json:"link"`
+	ID     string     `json:"id"`
+	Status TaskStatus `json:"status"`
+	// Changed is true if the pipeline of the task would change.
+	Changed bool `json:"changed"`
+	// Unified diff of the TICKscript of the task with its vars applied, empty if the pipeline is unchanged.
+	Diff string `json:"diff"`
+	// Error is set if the task would be invalid with the updated template.
+	Error string `json:"error"`
+}
+
+// TemplateUpdatePreview describes the changes an update of a template would make.
+type TemplateUpdatePreview struct {
+	// The template as it would be after the update.
+	Template Template `json:"template"`
+	// Unified diff of the TICKscripts of the template, empty if they are the same.
+	ScriptDiff string                `json:"script-diff"`
+	Tasks      []TemplateTaskPreview `json:"tasks"`
+}
+
+// Preview the update of a template without changing it or the tasks created from it.
+func (c *Client) PreviewUpdateTemplate(link Link, opt UpdateTemplateOptions) (TemplateUpdatePreview, error) {
+	p := TemplateUpdatePreview{}
+	if link.Href == "" {
+		return p, fmt.Errorf("invalid link %v", link)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return p, err
+	}
+
+	u := *c.url
+	u.Path = link.Href
+	u.RawQuery = url.Values{"dry-run": []string{"true"}}.Encode()
+
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
+	if err != nil {
+		return p, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &p, http.StatusOK)
+	return p, err
+}
+
+type CreateModuleOptions struct {
+	ID         string `json:"id,omitempty"`
+	TICKscript string `json:"script,omitempty"`
+}
+
+// Create a new module.
+// Errors if the module already exists.
+func (c *Client) CreateModule(opt CreateModuleOptions) (Module, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return Module{}, err
+	}
+
+	u := *c.url
+	u.Path = modulesPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return Module{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	m := Module{}
+	_, err = c.Do(req, &m, http.StatusOK)
+	return m, err
+}
+
+type UpdateModuleOptions struct {
+	TICKscript string `json:"script,omitempty"`
+}
+
+// Update an existing module.
+// All tasks importing the module are validated and reloaded,
+// the update fails if any of them becomes invalid.
+func (c *Client) UpdateModule(link Link, opt UpdateModuleOptions) (Module, error) {
+	m := Module{}
+	if link.Href == "" {
+		return m, fmt.Errorf("invalid link %v", link)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return m, err
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("PATCH", u.String(), &buf)
+	if err != nil {
+		return m, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &m, http.StatusOK)
+	if err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+type ModuleOptions struct {
 	ScriptFormat string
-	ReplayID     string
 }
 
-func (o *TaskOptions) Default() {
-	if o.DotView == "" {
-		o.DotView = "attributes"
-	}
+func (o *ModuleOptions) Default() {
 	if o.ScriptFormat == "" {
 		o.ScriptFormat = "formatted"
 	}
 }
 
-func (o *TaskOptions) Values() *url.Values {
+func (o *ModuleOptions) Values() *url.Values {
 	v := &url.Values{}
-	v.Set("dot-view", o.DotView)
 	v.Set("script-format", o.ScriptFormat)
-	v.Set("replay-id", o.ReplayID)
 	return v
 }
 
-// Get information about a task.
+// Get information about a module.
 // Options can be nil and the default options will be used.
-// By default the DOT content will use attributes for stats. Use DotView="labels" to generate a purley labels based DOT content, which can accurately be rendered but is less readable.
 // By default the TICKscript contents are formatted, use ScriptFormat="raw" to return the TICKscript unmodified.
-func (c *Client) Task(link Link, opt *TaskOptions) (Task, error) {
-	task := Task{}
+func (c *Client) Module(link Link, opt *ModuleOptions) (Module, error) {
+	module := Module{}
 	if link.Href == "" {
-		return task, fmt.Errorf("invalid link %v", link)
+		return module, fmt.Errorf("invalid link %v", link)
 	}
 
 	if opt == nil {
-		opt = new(TaskOptions)
+		opt = new(ModuleOptions)
 	}
 	opt.Default()
 
@@ -843,18 +2035,19 @@ func (c *Client) Task(link Link, opt *TaskOptions) (Task, error) {
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return task, err
+		return module, err
 	}
 
-	_, err = c.Do(req, &task, http.StatusOK)
+	_, err = c.Do(req, &module, http.StatusOK)
 	if err != nil {
-		return task, err
+		return module, err
 	}
-	return task, nil
+	return module, nil
 }
 
-// Delete a task.
-func (c *Client) DeleteTask(link Link) error {
+// Delete a module.
+// Errors if the module is imported by any task.
+func (c *Client) DeleteModule(link Link) error {
 	if link.Href == "" {
 		return fmt.Errorf("invalid link %v", link)
 	}
@@ -871,41 +2064,37 @@ func (c *Client) DeleteTask(link Link) error {
 	return err
 }
 
-type ListTasksOptions struct {
-	TaskOptions
+type ListModulesOptions struct {
+	ModuleOptions
 	Pattern string
-	Fields  []string
 	Offset  int
 	Limit   int
 }
 
-func (o *ListTasksOptions) Default() {
-	o.TaskOptions.Default()
+func (o *ListModulesOptions) Default() {
+	o.ModuleOptions.Default()
 	if o.Limit == 0 {
 		o.Limit = 100
 	}
 }
 
-func (o *ListTasksOptions) Values() *url.Values {
-	v := o.TaskOptions.Values()
+func (o *ListModulesOptions) Values() *url.Values {
+	v := o.ModuleOptions.Values()
 	v.Set("pattern", o.Pattern)
-	for _, field := range o.Fields {
-		v.Add("fields", field)
-	}
 	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
 	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
 	return v
 }
 
-// Get tasks.
-func (c *Client) ListTasks(opt *ListTasksOptions) ([]Task, error) {
+// Get modules.
+func (c *Client) ListModules(opt *ListModulesOptions) ([]Module, error) {
 	if opt == nil {
-		opt = new(ListTasksOptions)
+		opt = new(ListModulesOptions)
 	}
 	opt.Default()
 
 	u := *c.url
-	u.Path = tasksPath
+	u.Path = modulesPath
 	u.RawQuery = opt.Values().Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
@@ -915,7 +2104,7 @@ func (c *Client) ListTasks(opt *ListTasksOptions) ([]Task, error) {
 
 	// Response type
 	type response struct {
-		Tasks []Task `json:"tasks"`
+		Modules []Module `json:"modules"`
 	}
 
 	r := &response{}
@@ -924,74 +2113,60 @@ func (c *Client) ListTasks(opt *ListTasksOptions) ([]Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	return r.Tasks, nil
-}
-
-func (c *Client) TaskOutput(link Link, name string) (*influxql.Result, error) {
-	u := *c.url
-	u.Path = path.Join(link.Href, name)
-
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	r := &influxql.Result{}
-	_, err = c.Do(req, r, http.StatusOK)
-	if err != nil {
-		return nil, err
-	}
-	return r, nil
+	return r.Modules, nil
 }
 
-type CreateTemplateOptions struct {
-	ID         string   `json:"id,omitempty"`
-	Type       TaskType `json:"type,omitempty"`
-	TICKscript string   `json:"script,omitempty"`
+type CreateUserOptions struct {
+	Name       string              `json:"name"`
+	Password   string              `json:"password"`
+	Admin      bool                `json:"admin,omitempty"`
+	Privileges map[string][]string `json:"privileges,omitempty"`
 }
 
-// Create a new template.
-// Errors if the template already exists.
-func (c *Client) CreateTemplate(opt CreateTemplateOptions) (Template, error) {
+// Create a new user.
+// Errors if the user already exists.
+func (c *Client) CreateUser(opt CreateUserOptions) (User, error) {
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	err := enc.Encode(opt)
 	if err != nil {
-		return Template{}, err
+		return User{}, err
 	}
 
 	u := *c.url
-	u.Path = templatesPath
+	u.Path = usersPath
 
 	req, err := http.NewRequest("POST", u.String(), &buf)
 	if err != nil {
-		return Template{}, err
+		return User{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	t := Template{}
-	_, err = c.Do(req, &t, http.StatusOK)
-	return t, err
+	user := User{}
+	_, err = c.Do(req, &user, http.StatusOK)
+	return user, err
 }
 
-type UpdateTemplateOptions struct {
-	ID         string   `json:"id,omitempty"`
-	Type       TaskType `json:"type,omitempty"`
-	TICKscript string   `json:"script,omitempty"`
+type UpdateUserOptions struct {
+	Password string `json:"password,omitempty"`
+	Admin    *bool  `json:"admin,omitempty"`
+	// Privileges replace all existing privileges of the user if not nil.
+	Privileges map[string][]string `json:"privileges,omitempty"`
 }
 
-// Update an existing template.
+// Update an existing user.
 // Only fields that are not their default value will be updated.
-func (c *Client) UpdateTemplate(link Link, opt UpdateTemplateOptions) (Template, error) {
-	t := Template{}
+func (c *Client) UpdateUser(link Link, opt UpdateUserOptions) (User, error) {
+	user := User{}
 	if link.Href == "" {
-		return t, fmt.Errorf("invalid link %v", link)
+		return user, fmt.Errorf("invalid link %v", link)
 	}
 
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	err := enc.Encode(opt)
 	if err != nil {
-		return t, err
+		return user, err
 	}
 
 	u := *c.url
@@ -999,65 +2174,154 @@ func (c *Client) UpdateTemplate(link Link, opt UpdateTemplateOptions) (Template,
 
 	req, err := http.NewRequest("PATCH", u.String(), &buf)
 	if err != nil {
-		return t, err
+		return user, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	_, err = c.Do(req, &t, http.StatusOK)
+	_, err = c.Do(req, &user, http.StatusOK)
+	return user, err
+}
+
+// Get information about a user.
+func (c *Client) User(link Link) (User, error) {
+	user := User{}
+	if link.Href == "" {
+		return user, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return t, err
+		return user, err
 	}
-	return t, nil
+
+	_, err = c.Do(req, &user, http.StatusOK)
+	return user, err
 }
 
-type TemplateOptions struct {
-	ScriptFormat string
+// Delete a user.
+func (c *Client) DeleteUser(link Link) error {
+	if link.Href == "" {
+		return fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("DELETE", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Do(req, nil, http.StatusNoContent)
+	return err
 }
 
-func (o *TemplateOptions) Default() {
-	if o.ScriptFormat == "" {
-		o.ScriptFormat = "formatted"
+type ListUsersOptions struct {
+	Pattern string
+	Offset  int
+	Limit   int
+}
+
+func (o *ListUsersOptions) Default() {
+	if o.Limit == 0 {
+		o.Limit = 100
 	}
 }
 
-func (o *TemplateOptions) Values() *url.Values {
+func (o *ListUsersOptions) Values() *url.Values {
 	v := &url.Values{}
-	v.Set("script-format", o.ScriptFormat)
+	v.Set("pattern", o.Pattern)
+	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
 	return v
 }
 
-// Get information about a template.
-// Options can be nil and the default options will be used.
-// By default the TICKscript contents are formatted, use ScriptFormat="raw" to return the TICKscript unmodified.
-func (c *Client) Template(link Link, opt *TemplateOptions) (Template, error) {
-	template := Template{}
-	if link.Href == "" {
-		return template, fmt.Errorf("invalid link %v", link)
-	}
-
+// Get users.
+func (c *Client) ListUsers(opt *ListUsersOptions) ([]User, error) {
 	if opt == nil {
-		opt = new(TemplateOptions)
+		opt = new(ListUsersOptions)
 	}
 	opt.Default()
 
 	u := *c.url
-	u.Path = link.Href
+	u.Path = usersPath
 	u.RawQuery = opt.Values().Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return template, err
+		return nil, err
 	}
 
-	_, err = c.Do(req, &template, http.StatusOK)
+	// Response type
+	type response struct {
+		Users []User `json:"users"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
 	if err != nil {
-		return template, err
+		return nil, err
 	}
-	return template, nil
+	return r.Users, nil
 }
 
-// Delete a template.
-func (c *Client) DeleteTemplate(link Link) error {
+type CreateAPITokenOptions struct {
+	Name       string              `json:"name"`
+	Privileges map[string][]string `json:"privileges"`
+	// Expires is the time the token expires, the token never expires if it is the zero time.
+	Expires time.Time `json:"expires"`
+}
+
+// Create a new API token.
+// The returned token contains the secret Token which cannot be retrieved later.
+// Errors if a token with the same name already exists.
+func (c *Client) CreateAPIToken(opt CreateAPITokenOptions) (APIToken, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	u := *c.url
+	u.Path = apiTokensPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return APIToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	token := APIToken{}
+	_, err = c.Do(req, &token, http.StatusOK)
+	return token, err
+}
+
+// Get information about an API token.
+func (c *Client) APIToken(link Link) (APIToken, error) {
+	token := APIToken{}
+	if link.Href == "" {
+		return token, fmt.Errorf("invalid link %v", link)
+	}
+
+	u := *c.url
+	u.Path = link.Href
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return token, err
+	}
+
+	_, err = c.Do(req, &token, http.StatusOK)
+	return token, err
+}
+
+// Revoke an API token.
+func (c *Client) DeleteAPIToken(link Link) error {
 	if link.Href == "" {
 		return fmt.Errorf("invalid link %v", link)
 	}
@@ -1074,41 +2338,35 @@ func (c *Client) DeleteTemplate(link Link) error {
 	return err
 }
 
-type ListTemplatesOptions struct {
-	TemplateOptions
+type ListAPITokensOptions struct {
 	Pattern string
-	Fields  []string
 	Offset  int
 	Limit   int
 }
 
-func (o *ListTemplatesOptions) Default() {
-	o.TemplateOptions.Default()
+func (o *ListAPITokensOptions) Default() {
 	if o.Limit == 0 {
 		o.Limit = 100
 	}
 }
 
-func (o *ListTemplatesOptions) Values() *url.Values {
-	v := o.TemplateOptions.Values()
+func (o *ListAPITokensOptions) Values() *url.Values {
+	v := &url.Values{}
 	v.Set("pattern", o.Pattern)
-	for _, field := range o.Fields {
-		v.Add("fields", field)
-	}
 	v.Set("offset", strconv.FormatInt(int64(o.Offset), 10))
 	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
 	return v
 }
 
-// Get templates.
-func (c *Client) ListTemplates(opt *ListTemplatesOptions) ([]Template, error) {
+// Get API tokens.
+func (c *Client) ListAPITokens(opt *ListAPITokensOptions) ([]APIToken, error) {
 	if opt == nil {
-		opt = new(ListTemplatesOptions)
+		opt = new(ListAPITokensOptions)
 	}
 	opt.Default()
 
 	u := *c.url
-	u.Path = templatesPath
+	u.Path = apiTokensPath
 	u.RawQuery = opt.Values().Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
@@ -1118,7 +2376,7 @@ func (c *Client) ListTemplates(opt *ListTemplatesOptions) ([]Template, error) {
 
 	// Response type
 	type response struct {
-		Templates []Template `json:"templates"`
+		Tokens []APIToken `json:"tokens"`
 	}
 
 	r := &response{}
@@ -1127,7 +2385,7 @@ func (c *Client) ListTemplates(opt *ListTemplatesOptions) ([]Template, error) {
 	if err != nil {
 		return nil, err
 	}
-	return r.Templates, nil
+	return r.Tokens, nil
 }
 
 // Get information about a recording.
@@ -2235,6 +3493,139 @@ func (c *Client) LogLevel(level string) error {
 	return err
 }
 
+// AuditEntry records a mutating API call.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Method   string    `json:"method"`
+	Path     string    `json:"path"`
+	Resource string    `json:"resource"`
+	// Names of the fields of the request or a summary of the changes.
+	Changes   []string `json:"changes"`
+	Status    int      `json:"status"`
+	RequestID string   `json:"request-id"`
+	// Whether the request was a dry run that did not change any state.
+	DryRun bool `json:"dry-run"`
+}
+
+type AuditOptions struct {
+	User string
+	// Resource is a prefix or glob pattern of the resources, i.e. /tasks/mytask.
+	Resource string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+func (o *AuditOptions) Default() {
+	if o.Limit == 0 {
+		o.Limit = 100
+	}
+}
+
+func (o *AuditOptions) Values() *url.Values {
+	v := &url.Values{}
+	v.Set("user", o.User)
+	v.Set("resource", o.Resource)
+	if !o.Since.IsZero() {
+		v.Set("since", o.Since.Format(time.RFC3339Nano))
+	}
+	if !o.Until.IsZero() {
+		v.Set("until", o.Until.Format(time.RFC3339Nano))
+	}
+	v.Set("limit", strconv.FormatInt(int64(o.Limit), 10))
+	return v
+}
+
+// Get the most recent entries of the audit log, oldest first.
+func (c *Client) Audit(opt *AuditOptions) ([]AuditEntry, error) {
+	if opt == nil {
+		opt = new(AuditOptions)
+	}
+	opt.Default()
+
+	u := *c.url
+	u.Path = auditPath
+	u.RawQuery = opt.Values().Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Response type
+	type response struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+
+	r := &response{}
+
+	_, err = c.Do(req, r, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return r.Entries, nil
+}
+
+// A topic handler and the topic it belongs to.
+type ApplyTopicHandler struct {
+	Topic string `json:"topic" yaml:"topic"`
+	TopicHandlerOptions
+}
+
+type ApplyOptions struct {
+	Templates     []CreateTemplateOptions `json:"templates"`
+	Tasks         []CreateTaskOptions     `json:"tasks"`
+	TopicHandlers []ApplyTopicHandler     `json:"topic-handlers"`
+	// Prune deletes the tasks, templates and topic handlers that are not defined.
+	Prune bool `json:"prune"`
+	// DryRun only reports the changes without making them.
+	DryRun bool `json:"dry-run"`
+}
+
+// An ApplyChange describes a change of a task, template or topic handler.
+type ApplyChange struct {
+	// Action is one of create, update or delete.
+	Action string `json:"action"`
+	// Kind is one of task, template or topic-handler.
+	Kind string `json:"kind"`
+	// ID of the object, topic handlers are identified by <topic>/<handler>.
+	ID string `json:"id"`
+	// Changes of the definition other than the TICKscript.
+	Changes []string `json:"changes"`
+	// Unified diff of the TICKscript.
+	ScriptDiff string `json:"script-diff"`
+}
+
+type ApplyResult struct {
+	Changes []ApplyChange `json:"changes"`
+	DryRun  bool          `json:"dry-run"`
+}
+
+// Apply reconciles the tasks, templates and topic handlers of the server with the desired definitions.
+// Either all changes are made or none, the changes already made are reverted if a change fails.
+func (c *Client) Apply(opt ApplyOptions) (ApplyResult, error) {
+	r := ApplyResult{}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := enc.Encode(opt)
+	if err != nil {
+		return r, err
+	}
+
+	u := *c.url
+	u.Path = applyPath
+
+	req, err := http.NewRequest("POST", u.String(), &buf)
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = c.Do(req, &r, http.StatusOK)
+	return r, err
+}
+
 type DebugVars struct {
 	ClusterID        string                 `json:"cluster_id"`
 	ServerID         string                 `json:"server_id"`
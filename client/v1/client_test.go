@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -613,6 +614,122 @@ func Test_CreateTask(t *testing.T) {
 	}
 }
 
+func Test_LintTask(t *testing.T) {
+	tickScript := "stream|from().measurement('cpu')|where(lambda: \"usage_idel\" < 10)"
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opt client.LintTaskOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r.URL.Path == "/kapacitor/v1/tasks/lint" && r.Method == "POST" {
+			exp := client.LintTaskOptions{
+				Type:       client.StreamTask,
+				DBRPs:      []client.DBRP{{Database: "dbname", RetentionPolicy: "rpname"}},
+				TICKscript: tickScript,
+				Schema: &client.LintSchema{
+					Measurements: map[string]client.LintMeasurement{
+						"cpu": {
+							Fields: map[string]string{"usage_idle": "float"},
+							Tags:   []string{"host"},
+						},
+					},
+				},
+			}
+			if !reflect.DeepEqual(exp, opt) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "unexpected LintTask body: got:\n%v\nexp:\n%v\n", opt, exp)
+			} else {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"problems":[{"line":1,"char":51,"message":"unknown field or tag \"usage_idel\", did you mean \"usage_idle\"?"}]}`)
+			}
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	result, err := c.LintTask(client.LintTaskOptions{
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "dbname", RetentionPolicy: "rpname"}},
+		TICKscript: tickScript,
+		Schema: &client.LintSchema{
+			Measurements: map[string]client.LintMeasurement{
+				"cpu": {
+					Fields: map[string]string{"usage_idle": "float"},
+					Tags:   []string{"host"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.LintResult{
+		Problems: []client.LintProblem{{
+			Line:    1,
+			Char:    51,
+			Message: `unknown field or tag "usage_idel", did you mean "usage_idle"?`,
+		}},
+	}
+	if !reflect.DeepEqual(exp, result) {
+		t.Errorf("unexpected lint result:\ngot\n%v\nexp\n%v", result, exp)
+	}
+}
+
+func Test_EvaluateTask(t *testing.T) {
+	points := "cpu,host=serverA usage_idle=5 1490000000000000000\n"
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opt client.EvaluateTaskOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r.URL.Path == "/kapacitor/v1/tasks/evaluate" && r.Method == "POST" {
+			exp := client.EvaluateTaskOptions{
+				Lambda: `"usage_idle" < 10.0`,
+				Points: points,
+			}
+			if !reflect.DeepEqual(exp, opt) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "unexpected EvaluateTask body: got:\n%v\nexp:\n%v\n", opt, exp)
+			} else {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"values":[{"value":true}]}`)
+			}
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	result, err := c.EvaluateTask(client.EvaluateTaskOptions{
+		Lambda: `"usage_idle" < 10.0`,
+		Points: points,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.EvaluateResult{
+		Values: []client.EvaluateValue{{Value: true}},
+	}
+	if !reflect.DeepEqual(exp, result) {
+		t.Errorf("unexpected evaluate result:\ngot\n%v\nexp\n%v", result, exp)
+	}
+}
+
 func Test_UpdateTask(t *testing.T) {
 	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var task client.UpdateTaskOptions
@@ -679,6 +796,53 @@ func Test_UpdateTask(t *testing.T) {
 	}
 }
 
+func Test_UpdateTask_Limits(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/kapacitor/v1/tasks/taskname" && r.Method == "PATCH" {
+			exp := `{"limits":{"max-groups":100,"max-edge-queue":10,"sample-interval":"5s","action":"drop-oldest"}}`
+			if got := strings.TrimSpace(string(body)); got != exp {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "unexpected UpdateTask body: got:\n%s\nexp:\n%s\n", got, exp)
+			} else {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"link": {"rel":"self", "href":"/kapacitor/v1/tasks/taskname"}, "id":"taskname", "status": "quarantined",
+"limits":{"max-groups":100,"max-edge-queue":10,"sample-interval":"5s","action":"drop-oldest"},
+"error": "task quarantined: node window2 has 101 groups, the limit is 100"}`)
+			}
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	limits := client.TaskLimits{
+		MaxGroups:      100,
+		MaxEdgeQueue:   10,
+		SampleInterval: client.Duration(5 * time.Second),
+		Action:         client.LimitDropOldest,
+	}
+	task, err := c.UpdateTask(
+		c.TaskLink("taskname"),
+		client.UpdateTaskOptions{
+			Limits: &limits,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := task.Status, client.Quarantined; got != exp {
+		t.Errorf("unexpected task status got %v exp %v", got, exp)
+	}
+	if !reflect.DeepEqual(task.Limits, limits) {
+		t.Errorf("unexpected task limits got %+v exp %+v", task.Limits, limits)
+	}
+}
+
 func Test_UpdateTask_Enable(t *testing.T) {
 	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var task client.UpdateTaskOptions
@@ -787,6 +951,311 @@ func Test_DeleteTask(t *testing.T) {
 	}
 }
 
+func Test_ListTaskRevisions(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/tasks/taskname/revisions" && r.Method == "GET" &&
+			r.URL.Query().Get("offset") == "0" &&
+			r.URL.Query().Get("limit") == "100" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{
+	"revisions":[
+		{
+			"link": {"rel":"self", "href":"/kapacitor/v1/tasks/taskname/revisions/2"},
+			"task-id": "taskname",
+			"revision": 2,
+			"type": "stream",
+			"dbrps": [{"db":"db","rp":"rp"}],
+			"script": "stream|from().measurement('cpu')",
+			"vars": {},
+			"author": "bob",
+			"created": "2017-01-01T00:00:00Z"
+		}
+	]
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	revisions, err := c.ListTaskRevisions(c.TaskLink("taskname"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []client.TaskRevision{{
+		Link:       client.Link{Relation: client.Self, Href: "/kapacitor/v1/tasks/taskname/revisions/2"},
+		TaskID:     "taskname",
+		Revision:   2,
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		TICKscript: "stream|from().measurement('cpu')",
+		Vars:       client.Vars{},
+		Author:     "bob",
+		Created:    time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	if !reflect.DeepEqual(exp, revisions) {
+		t.Errorf("unexpected task revisions:\ngot:\n%v\nexp:\n%v", revisions, exp)
+	}
+}
+
+func Test_DiffTaskRevisions(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/tasks/taskname/diff" && r.Method == "GET" &&
+			r.URL.Query().Get("from") == "1" &&
+			r.URL.Query().Get("to") == "" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"from":1,"to":3,"changes":["var crit: 80 -> 90"],"script-diff":""}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	diff, err := c.DiffTaskRevisions(c.TaskLink("taskname"), &client.DiffTaskRevisionsOptions{From: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.TaskRevisionDiff{
+		From:    1,
+		To:      3,
+		Changes: []string{"var crit: 80 -> 90"},
+	}
+	if !reflect.DeepEqual(exp, diff) {
+		t.Errorf("unexpected task revision diff:\ngot:\n%v\nexp:\n%v", diff, exp)
+	}
+}
+
+func Test_RollbackTask(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/tasks/taskname/rollback" && r.Method == "POST" &&
+			r.URL.Query().Get("revision") == "2" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"link":{"rel":"self", "href":"/kapacitor/v1/tasks/taskname"}, "id":"taskname"}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	task, err := c.RollbackTask(c.TaskLink("taskname"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := task.ID, "taskname"; got != exp {
+		t.Errorf("unexpected task ID got %s exp %s", got, exp)
+	}
+}
+
+func Test_CreateTaskGroup(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opt client.CreateTaskGroupOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opt)
+
+		if r.URL.Path == "/kapacitor/v1/task-groups" && r.Method == "POST" {
+			exp := client.CreateTaskGroupOptions{
+				ID:           "groupname",
+				Tasks:        []string{"producer", "consumer"},
+				Dependencies: map[string][]string{"producer": {"consumer"}},
+			}
+			if !reflect.DeepEqual(exp, opt) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "unexpected CreateTaskGroup body: got:\n%v\nexp:\n%v\n", opt, exp)
+			} else {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"link": {"rel":"self", "href":"/kapacitor/v1/task-groups/groupname"}, "id":"groupname", "order":["consumer","producer"]}`)
+			}
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	group, err := c.CreateTaskGroup(client.CreateTaskGroupOptions{
+		ID:           "groupname",
+		Tasks:        []string{"producer", "consumer"},
+		Dependencies: map[string][]string{"producer": {"consumer"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := group.Link.Href, "/kapacitor/v1/task-groups/groupname"; got != exp {
+		t.Errorf("unexpected task group link got %s exp %s", got, exp)
+	}
+	if got, exp := group.Order, []string{"consumer", "producer"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected task group order got %v exp %v", got, exp)
+	}
+}
+
+func Test_UpdateTaskGroup(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opt client.UpdateTaskGroupOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opt)
+
+		if r.URL.Path == "/kapacitor/v1/task-groups/groupname" && r.Method == "PATCH" {
+			exp := client.UpdateTaskGroupOptions{
+				Tasks: []string{"producer"},
+			}
+			if !reflect.DeepEqual(exp, opt) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "unexpected UpdateTaskGroup body: got:\n%v\nexp:\n%v\n", opt, exp)
+			} else {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"link": {"rel":"self", "href":"/kapacitor/v1/task-groups/groupname"}, "id":"groupname", "tasks":["producer"]}`)
+			}
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	group, err := c.UpdateTaskGroup(c.TaskGroupLink("groupname"), client.UpdateTaskGroupOptions{
+		Tasks: []string{"producer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := group.Tasks, []string{"producer"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected task group tasks got %v exp %v", got, exp)
+	}
+}
+
+func Test_DeleteTaskGroup(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/task-groups/groupname" && r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = c.DeleteTaskGroup(c.TaskGroupLink("groupname"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ListTaskGroups(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/task-groups" && r.Method == "GET" &&
+			r.URL.Query().Get("pattern") == "group*" &&
+			r.URL.Query().Get("offset") == "0" &&
+			r.URL.Query().Get("limit") == "100" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"task-groups":[{"link": {"rel":"self", "href":"/kapacitor/v1/task-groups/group1"}, "id":"group1"}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	groups, err := c.ListTaskGroups(&client.ListTaskGroupsOptions{Pattern: "group*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(groups), 1; got != exp {
+		t.Fatalf("unexpected number of task groups got %d exp %d", got, exp)
+	}
+	if got, exp := groups[0].ID, "group1"; got != exp {
+		t.Errorf("unexpected task group ID got %s exp %s", got, exp)
+	}
+}
+
+func Test_TaskGroupActions(t *testing.T) {
+	testCases := []struct {
+		action string
+		f      func(c *client.Client, l client.Link) (client.TaskGroup, error)
+	}{
+		{action: "enable", f: (*client.Client).EnableTaskGroup},
+		{action: "disable", f: (*client.Client).DisableTaskGroup},
+		{action: "reload", f: (*client.Client).ReloadTaskGroup},
+	}
+	for _, tc := range testCases {
+		s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/kapacitor/v1/task-groups/groupname/"+tc.action && r.Method == "POST" {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"link": {"rel":"self", "href":"/kapacitor/v1/task-groups/groupname"}, "id":"groupname"}`)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "request: %v", r)
+			}
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, err := tc.f(c, c.TaskGroupLink("groupname"))
+		s.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.action, err)
+		}
+		if got, exp := group.ID, "groupname"; got != exp {
+			t.Errorf("%s: unexpected task group ID got %s exp %s", tc.action, got, exp)
+		}
+	}
+}
+
+func Test_TaskGraph(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/tasks/graph" && r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{
+	"link": {"rel":"self", "href":"/kapacitor/v1/tasks/graph"},
+	"dot": "digraph tasks {\n\"producer\";\n\"consumer\";\n\"producer\" -> \"consumer\" [label=\"db.rp.m\"];\n}",
+	"edges": [{"from":"producer","to":"consumer","inferred":true,"label":"db.rp.m"}]
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	graph, err := c.TaskGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []client.TaskGraphEdge{{From: "producer", To: "consumer", Inferred: true, Label: "db.rp.m"}}
+	if !reflect.DeepEqual(graph.Edges, exp) {
+		t.Errorf("unexpected task graph edges got %v exp %v", graph.Edges, exp)
+	}
+	if !strings.Contains(graph.Dot, `"producer" -> "consumer"`) {
+		t.Errorf("unexpected task graph dot %s", graph.Dot)
+	}
+}
+
 func Test_ListTasks(t *testing.T) {
 	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/kapacitor/v1/tasks" && r.Method == "GET" &&
@@ -1205,6 +1674,102 @@ func Test_DeleteTemplate(t *testing.T) {
 	}
 }
 
+func Test_ListTemplateTasks(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/templates/templatename/tasks" && r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"tasks":[{
+	"link": {"rel":"self", "href":"/kapacitor/v1/tasks/t1"},
+	"id": "t1",
+	"status": "enabled",
+	"executing": true,
+	"dbrps": [{"db":"db","rp":"rp"}],
+	"vars": {"m": {"type":"string", "value":"cpu"}}
+}]}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tasks, err := c.ListTemplateTasks(c.TemplateLink("templatename"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []client.TemplateTask{{
+		Link:      client.Link{Relation: client.Self, Href: "/kapacitor/v1/tasks/t1"},
+		ID:        "t1",
+		Status:    client.Enabled,
+		Executing: true,
+		DBRPs:     []client.DBRP{{Database: "db", RetentionPolicy: "rp"}},
+		Vars:      client.Vars{"m": {Type: client.VarString, Value: "cpu"}},
+	}}
+	if !reflect.DeepEqual(exp, tasks) {
+		t.Errorf("unexpected template tasks got:\n%v\nexp:\n%v", tasks, exp)
+	}
+}
+
+func Test_PreviewUpdateTemplate(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opt client.UpdateTemplateOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opt)
+
+		if r.URL.Path == "/kapacitor/v1/templates/templatename" && r.Method == "PATCH" &&
+			r.URL.Query().Get("dry-run") == "true" &&
+			opt.TICKscript == "stream|from().measurement('mem')" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{
+	"template": {"link": {"rel":"self", "href":"/kapacitor/v1/templates/templatename"}, "id":"templatename"},
+	"script-diff": "diff",
+	"tasks": [
+		{"link": {"rel":"self", "href":"/kapacitor/v1/tasks/t1"}, "id":"t1", "status":"enabled", "changed":true, "diff":"task diff"},
+		{"link": {"rel":"self", "href":"/kapacitor/v1/tasks/t2"}, "id":"t2", "status":"disabled", "error":"missing value for var"}
+	]
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	preview, err := c.PreviewUpdateTemplate(c.TemplateLink("templatename"), client.UpdateTemplateOptions{
+		TICKscript: "stream|from().measurement('mem')",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := preview.Template.ID, "templatename"; got != exp {
+		t.Errorf("unexpected template ID got %s exp %s", got, exp)
+	}
+	exp := []client.TemplateTaskPreview{
+		{
+			Link:    client.Link{Relation: client.Self, Href: "/kapacitor/v1/tasks/t1"},
+			ID:      "t1",
+			Status:  client.Enabled,
+			Changed: true,
+			Diff:    "task diff",
+		},
+		{
+			Link:   client.Link{Relation: client.Self, Href: "/kapacitor/v1/tasks/t2"},
+			ID:     "t2",
+			Status: client.Disabled,
+			Error:  "missing value for var",
+		},
+	}
+	if !reflect.DeepEqual(exp, preview.Tasks) {
+		t.Errorf("unexpected task previews got:\n%v\nexp:\n%v", preview.Tasks, exp)
+	}
+}
+
 func Test_ListTemplates(t *testing.T) {
 	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/kapacitor/v1/templates" && r.Method == "GET" &&
@@ -3006,6 +3571,272 @@ func Test_DeleteTopicHandler(t *testing.T) {
 	}
 }
 
+func Test_CreateUser(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		options := client.CreateUserOptions{}
+		json.NewDecoder(r.Body).Decode(&options)
+		expOptions := client.CreateUserOptions{
+			Name:     "bob",
+			Password: "secret",
+			Privileges: map[string][]string{
+				"/api/tasks": {"read", "write"},
+			},
+		}
+		if r.URL.String() == "/kapacitor/v1/users" &&
+			r.Method == "POST" &&
+			reflect.DeepEqual(expOptions, options) {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{
+	"link":{"rel":"self","href":"/kapacitor/v1/users/bob"},
+	"name":"bob",
+	"admin":false,
+	"privileges":{"/api/tasks":["read","write"]}
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	u, err := c.CreateUser(client.CreateUserOptions{
+		Name:     "bob",
+		Password: "secret",
+		Privileges: map[string][]string{
+			"/api/tasks": {"read", "write"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.User{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/users/bob"},
+		Name: "bob",
+		Privileges: map[string][]string{
+			"/api/tasks": {"read", "write"},
+		},
+	}
+	if !reflect.DeepEqual(exp, u) {
+		t.Errorf("unexpected create user result:\ngot:\n%v\nexp:\n%v", u, exp)
+	}
+}
+
+func Test_ListUsers(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/kapacitor/v1/users" &&
+			r.Method == "GET" &&
+			r.URL.Query().Get("pattern") == "b*" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{
+	"users":[
+		{"link":{"rel":"self","href":"/kapacitor/v1/users/bob"},"name":"bob","admin":true,"privileges":{}}
+	]
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	users, err := c.ListUsers(&client.ListUsersOptions{
+		Pattern: "b*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []client.User{{
+		Link:       client.Link{Relation: client.Self, Href: "/kapacitor/v1/users/bob"},
+		Name:       "bob",
+		Admin:      true,
+		Privileges: map[string][]string{},
+	}}
+	if !reflect.DeepEqual(exp, users) {
+		t.Errorf("unexpected list users result:\ngot:\n%v\nexp:\n%v", users, exp)
+	}
+}
+
+func Test_CreateAPIToken(t *testing.T) {
+	expires := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		options := client.CreateAPITokenOptions{}
+		json.NewDecoder(r.Body).Decode(&options)
+		expOptions := client.CreateAPITokenOptions{
+			Name: "ci",
+			Privileges: map[string][]string{
+				"/api/tasks/ci-*": {"all"},
+			},
+			Expires: expires,
+		}
+		if r.URL.String() == "/kapacitor/v1/tokens" &&
+			r.Method == "POST" &&
+			reflect.DeepEqual(expOptions, options) {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{
+	"link":{"rel":"self","href":"/kapacitor/v1/tokens/ci"},
+	"name":"ci",
+	"token":"kapacitor_ci_0123",
+	"privileges":{"/api/tasks/ci-*":["all"]},
+	"created":"2016-12-01T00:00:00Z",
+	"expires":"2017-01-01T00:00:00Z",
+	"last-used":"0001-01-01T00:00:00Z"
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	token, err := c.CreateAPIToken(client.CreateAPITokenOptions{
+		Name: "ci",
+		Privileges: map[string][]string{
+			"/api/tasks/ci-*": {"all"},
+		},
+		Expires: expires,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.APIToken{
+		Link:  client.Link{Relation: client.Self, Href: "/kapacitor/v1/tokens/ci"},
+		Name:  "ci",
+		Token: "kapacitor_ci_0123",
+		Privileges: map[string][]string{
+			"/api/tasks/ci-*": {"all"},
+		},
+		Created: time.Date(2016, 12, 1, 0, 0, 0, 0, time.UTC),
+		Expires: expires,
+	}
+	if !reflect.DeepEqual(exp, token) {
+		t.Errorf("unexpected create API token result:\ngot:\n%v\nexp:\n%v", token, exp)
+	}
+}
+
+func Test_Audit(t *testing.T) {
+	since := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path == "/kapacitor/v1/audit" &&
+			r.Method == "GET" &&
+			q.Get("user") == "bob" &&
+			q.Get("resource") == "/tasks/" &&
+			q.Get("since") == "2017-01-01T00:00:00Z" &&
+			q.Get("limit") == "100" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{
+	"entries":[
+		{"time":"2017-01-01T00:01:00Z","user":"bob","method":"PATCH","path":"/kapacitor/v1/tasks/cpu","resource":"/tasks/cpu","changes":["status"],"status":200}
+	]
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	entries, err := c.Audit(&client.AuditOptions{
+		User:     "bob",
+		Resource: "/tasks/",
+		Since:    since,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []client.AuditEntry{{
+		Time:     since.Add(time.Minute),
+		User:     "bob",
+		Method:   "PATCH",
+		Path:     "/kapacitor/v1/tasks/cpu",
+		Resource: "/tasks/cpu",
+		Changes:  []string{"status"},
+		Status:   http.StatusOK,
+	}}
+	if !reflect.DeepEqual(exp, entries) {
+		t.Errorf("unexpected audit result:\ngot:\n%v\nexp:\n%v", entries, exp)
+	}
+}
+
+func Test_Apply(t *testing.T) {
+	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opt client.ApplyOptions
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &opt)
+		if r.URL.Path == "/kapacitor/v1/apply" && r.Method == "POST" &&
+			opt.DryRun && !opt.Prune &&
+			len(opt.Tasks) == 1 && opt.Tasks[0].ID == "cpu" &&
+			len(opt.TopicHandlers) == 1 && opt.TopicHandlers[0].Topic == "cpu" && opt.TopicHandlers[0].ID == "slack" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{
+	"changes":[
+		{"action":"update","kind":"task","id":"cpu","changes":["status: disabled -> enabled"],"script-diff":""},
+		{"action":"create","kind":"topic-handler","id":"cpu/slack","changes":[],"script-diff":""}
+	],
+	"dry-run":true
+}`)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "request: %v", r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	result, err := c.Apply(client.ApplyOptions{
+		Tasks: []client.CreateTaskOptions{{
+			ID:         "cpu",
+			TICKscript: "stream|from()",
+			Status:     client.Enabled,
+		}},
+		TopicHandlers: []client.ApplyTopicHandler{{
+			Topic: "cpu",
+			TopicHandlerOptions: client.TopicHandlerOptions{
+				ID:   "slack",
+				Kind: "slack",
+			},
+		}},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.ApplyResult{
+		Changes: []client.ApplyChange{
+			{
+				Action:  "update",
+				Kind:    "task",
+				ID:      "cpu",
+				Changes: []string{"status: disabled -> enabled"},
+			},
+			{
+				Action:  "create",
+				Kind:    "topic-handler",
+				ID:      "cpu/slack",
+				Changes: []string{},
+			},
+		},
+		DryRun: true,
+	}
+	if !reflect.DeepEqual(exp, result) {
+		t.Errorf("unexpected apply result:\ngot:\n%v\nexp:\n%v", result, exp)
+	}
+}
+
 func Test_LogLevel(t *testing.T) {
 	s, c, err := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var opts client.LogLevelOptions
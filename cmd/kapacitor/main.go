@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"flag"
@@ -49,20 +50,28 @@ Commands:
 	record                Record the result of a query or a snapshot of the current stream data.
 	define                Create/update a task.
 	define-template       Create/update a template.
+	define-task-group     Create/update a group of tasks that are enabled and disabled in the order of their dependencies.
 	define-topic-handler  Create/update an alert handler for a topic.
+	apply                 Create/update/delete tasks, templates and topic handlers from the definitions of a directory.
+	lint                  Check a TICKscript for mistakes without defining a task.
+	eval                  Evaluate a TICKscript or lambda expression against sample points.
+	user                  Create, update, list, show or delete users of the local auth service.
+	token                 Create, list, show or revoke API tokens of the local auth service.
 	replay                Replay a recording to a task.
 	replay-live           Replay data against a task without recording it.
-	enable                Enable and start running a task with live data.
-	disable               Stop running a task.
-	reload                Reload a running task with an updated task definition.
+	enable                Enable and start running a task or task group with live data.
+	disable               Stop running a task or task group.
+	reload                Reload a running task or task group with an updated task definition.
+	rollback              Rollback a task to a previous revision of its definition.
 	push                  Publish a task definition to another Kapacitor instance. Not implemented yet.
-	delete                Delete tasks, templates, recordings, replays, topics or topic-handlers.
-	list                  List information about tasks, templates, recordings, replays, topics, topic-handlers or service-tests.
+	delete                Delete tasks, templates, task-groups, recordings, replays, topics or topic-handlers.
+	list                  List information about tasks, templates, task-groups, recordings, replays, topics, topic-handlers or service-tests.
 	show                  Display detailed information about a task.
 	show-template         Display detailed information about a template.
 	show-topic-handler    Display detailed information about an alert handler for a topic.
 	show-topic            Display detailed information about an alert topic.
 	backup                Backup the Kapacitor database.
+	audit                 Display the audit log of changes made through the API.
 	level                 Sets the logging level on the kapacitord server.
 	stats                 Display various stats about Kapacitor.
 	version               Displays the Kapacitor version info.
@@ -105,7 +114,7 @@ func main() {
 	}
 
 	var err error
-	cli, err = connect(url, skipSSL)
+	cli, err = connect(url, skipSSL, os.Getenv("KAPACITOR_TOKEN"))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(4)
@@ -139,9 +148,30 @@ func main() {
 	case "define-template":
 		commandArgs = args
 		commandF = doDefineTemplate
+	case "define-task-group":
+		commandArgs = args
+		commandF = doDefineTaskGroup
 	case "define-topic-handler":
 		commandArgs = args
 		commandF = doDefineTopicHandler
+	case "apply":
+		applyFlags.Parse(args)
+		commandArgs = applyFlags.Args()
+		commandF = doApply
+	case "lint":
+		lintFlags.Parse(args)
+		commandArgs = lintFlags.Args()
+		commandF = doLint
+	case "eval":
+		evalFlags.Parse(args)
+		commandArgs = evalFlags.Args()
+		commandF = doEval
+	case "user":
+		commandArgs = args
+		commandF = doUser
+	case "token":
+		commandArgs = args
+		commandF = doToken
 	case "replay":
 		replayFlags.Parse(args)
 		commandArgs = replayFlags.Args()
@@ -154,14 +184,21 @@ func main() {
 		commandArgs = args
 		commandF = doReplayLive
 	case "enable":
-		commandArgs = args
+		enableFlags.Parse(args)
+		commandArgs = enableFlags.Args()
 		commandF = doEnable
 	case "disable":
-		commandArgs = args
+		disableFlags.Parse(args)
+		commandArgs = disableFlags.Args()
 		commandF = doDisable
 	case "reload":
-		commandArgs = args
+		reloadFlags.Parse(args)
+		commandArgs = reloadFlags.Args()
 		commandF = doReload
+	case "rollback":
+		rollbackFlags.Parse(args)
+		commandArgs = rollbackFlags.Args()
+		commandF = doRollback
 	case "delete":
 		commandArgs = args
 		commandF = doDelete
@@ -173,7 +210,8 @@ func main() {
 		commandArgs = showFlags.Args()
 		commandF = doShow
 	case "show-template":
-		commandArgs = args
+		showTemplateFlags.Parse(args)
+		commandArgs = showTemplateFlags.Args()
 		commandF = doShowTemplate
 	case "show-topic-handler":
 		commandArgs = args
@@ -184,6 +222,10 @@ func main() {
 	case "backup":
 		commandArgs = args
 		commandF = doBackup
+	case "audit":
+		auditFlags.Parse(args)
+		commandArgs = auditFlags.Args()
+		commandF = doAudit
 	case "level":
 		commandArgs = args
 		commandF = doLevel
@@ -216,7 +258,15 @@ func init() {
 	replayFlags.Usage = replayUsage
 	defineFlags.Usage = defineUsage
 	defineTemplateFlags.Usage = defineTemplateUsage
+	defineTaskGroupFlags.Usage = defineTaskGroupUsage
+	lintFlags.Usage = lintUsage
+	evalFlags.Usage = evalUsage
 	showFlags.Usage = showUsage
+	showTemplateFlags.Usage = showTemplateUsage
+	rollbackFlags.Usage = rollbackUsage
+	enableFlags.Usage = enableUsage
+	disableFlags.Usage = disableUsage
+	reloadFlags.Usage = reloadUsage
 
 	recordStreamFlags.Usage = recordStreamUsage
 	recordBatchFlags.Usage = recordBatchUsage
@@ -236,10 +286,18 @@ func (e responseError) Error() string {
 	return e.Err
 }
 
-func connect(url string, skipSSL bool) (*client.Client, error) {
+func connect(url string, skipSSL bool, token string) (*client.Client, error) {
+	var credentials *client.Credentials
+	if token != "" {
+		credentials = &client.Credentials{
+			Method: client.BearerAuthentication,
+			Token:  token,
+		}
+	}
 	return client.New(client.Config{
 		URL:                url,
 		InsecureSkipVerify: skipSSL,
+		Credentials:        credentials,
 	})
 }
 
@@ -260,8 +318,20 @@ func doHelp(args []string) error {
 			defineFlags.Usage()
 		case "define-template":
 			defineTemplateFlags.Usage()
+		case "define-task-group":
+			defineTaskGroupFlags.Usage()
 		case "define-topic-handler":
 			defineTopicHandlerUsage()
+		case "apply":
+			applyUsage()
+		case "lint":
+			lintFlags.Usage()
+		case "eval":
+			evalFlags.Usage()
+		case "user":
+			userUsage()
+		case "token":
+			tokenUsage()
 		case "replay":
 			replayFlags.Usage()
 		case "enable":
@@ -270,6 +340,8 @@ func doHelp(args []string) error {
 			disableUsage()
 		case "reload":
 			reloadUsage()
+		case "rollback":
+			rollbackUsage()
 		case "delete":
 			deleteUsage()
 		case "list":
@@ -284,6 +356,8 @@ func doHelp(args []string) error {
 			showTopicUsage()
 		case "backup":
 			backupUsage()
+		case "audit":
+			auditUsage()
 		case "level":
 			levelUsage()
 		case "help":
@@ -542,6 +616,7 @@ var (
 	dtype       = defineFlags.String("type", "", "The task type (stream|batch)")
 	dtemplate   = defineFlags.String("template", "", "Optional template ID")
 	dvars       = defineFlags.String("vars", "", "Optional path to a JSON vars file")
+	dlimits     = defineFlags.String("limits", "", "Optional path to a JSON file of the resource limits of the task")
 	dnoReload   = defineFlags.Bool("no-reload", false, "Do not reload the task even if it is enabled")
 	ddbrp       = make(dbrps, 0)
 )
@@ -618,6 +693,9 @@ func defineUsage() {
 	If an option is absent it will be left unmodified.
 
 	If the task is enabled then it will be reloaded unless -no-reload is specified.
+	The state of the nodes whose definition is unchanged is kept when the task is reloaded.
+	Nodes are matched by their name, i.e. window2, which is numbered in the order the nodes are defined,
+	so adding or removing a node drops the state of the nodes defined after it.
 
 For example:
 
@@ -636,6 +714,38 @@ For example:
 
 	NOTE: you must specify all 'dbrp' flags you desire if you wish to modify them.
 
+	The type and dbrp flags may be omitted if the TICKscript declares them.
+	The type is inferred from the root node, stream or batch, of the TICKscript
+	and the DBRPs are read from dbrp statements:
+
+		dbrp "mydb"."myrp"
+
+		stream
+		    |from()
+		        .measurement('cpu')
+
+		$ kapacitor define my_task -tick path/to/TICKscript
+
+	The resources used by the task can be limited with a JSON limits file,
+	limits that are not set use the limits configured on the server:
+
+		{
+		    "max-groups": 1000,
+		    "max-buffered-points": 100000,
+		    "max-edge-queue": 500,
+		    "max-cpu": 0.5,
+		    "sample-interval": "10s",
+		    "action": "stop"
+		}
+
+		$ kapacitor define my_task -limits path/to/limits.json
+
+	The action taken when a limit is exceeded is one of:
+
+	    drop-oldest   Discard the oldest queued or buffered data.
+	    pause         Drop the incoming data of the task until it is back under its limits.
+	    stop          Stop the task and set its status to quarantined, enable the task to start it again.
+
 Options:
 
 `
@@ -687,6 +797,20 @@ func doDefine(args []string) error {
 		}
 	}
 
+	var limits *client.TaskLimits
+	if *dlimits != "" {
+		f, err := os.Open(*dlimits)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open file %s", *dlimits)
+		}
+		defer f.Close()
+		limits = new(client.TaskLimits)
+		dec := json.NewDecoder(f)
+		if err := dec.Decode(limits); err != nil {
+			return errors.Wrapf(err, "invalid JSON in file %s", *dlimits)
+		}
+	}
+
 	l := cli.TaskLink(id)
 	task, _ := cli.Task(l, nil)
 	var err error
@@ -699,6 +823,7 @@ func doDefine(args []string) error {
 			TICKscript: script,
 			Vars:       vars,
 			Status:     client.Disabled,
+			Limits:     limits,
 		})
 	} else {
 		_, err = cli.UpdateTask(
@@ -709,6 +834,7 @@ func doDefine(args []string) error {
 				DBRPs:      ddbrp,
 				TICKscript: script,
 				Vars:       vars,
+				Limits:     limits,
 			},
 		)
 	}
@@ -729,157 +855,1251 @@ func doDefine(args []string) error {
 	return nil
 }
 
-// DefineTemplate
+// Lint
 var (
-	defineTemplateFlags = flag.NewFlagSet("define-template", flag.ExitOnError)
-	dtTick              = defineTemplateFlags.String("tick", "", "Path to the TICKscript")
-	dtType              = defineTemplateFlags.String("type", "", "The template type (stream|batch)")
+	lintFlags = flag.NewFlagSet("lint", flag.ExitOnError)
+	ltick     = lintFlags.String("tick", "", "Path to the TICKscript")
+	ltype     = lintFlags.String("type", "", "The task type (stream|batch)")
+	lvars     = lintFlags.String("vars", "", "Optional path to a JSON vars file")
+	lschema   = lintFlags.String("schema", "", "Optional path to a JSON schema file, by default the schema is sampled from InfluxDB")
+	lcluster  = lintFlags.String("cluster", "", "Optional name of the InfluxDB cluster to sample the schema from")
+	ldbrp     = make(dbrps, 0)
 )
 
-func defineTemplateUsage() {
-	var u = `Usage: kapacitor define-template <template ID> [options]
+func init() {
+	lintFlags.Var(&ldbrp, "dbrp", `A database and retention policy pair of the form "db"."rp" the quotes are optional. The flag can be specified multiple times.`)
+}
 
-	Create or update a template.
+func lintUsage() {
+	var u = `Usage: kapacitor lint [options]
 
-	A template is defined via a TICKscript that defines the data processing pipeline of the template.
+	Check a TICKscript for mistakes without defining a task.
 
-	If an option is absent it will be left unmodified.
+	The fields and tags referenced by the TICKscript are checked against a schema.
+	The schema is sampled from the databases of the task using SHOW FIELD KEYS and
+	SHOW TAG KEYS unless a schema file is provided.
 
-	NOTE: Updating a template will reload all associated tasks.
+	The problems reported are:
+
+	    * Unknown fields and tags.
+	    * Expressions with mismatched types.
+	    * Vars and funcs that are never used.
+	    * Nodes that are unreachable.
+
+	The exit code is non zero if any problems are found.
 
 For example:
 
-	You must define a template for the first time with all the flags.
+	Check a TICKscript against the schema of the mydb database:
 
-		$ kapacitor define-template my_template -tick path/to/TICKscript -type stream
+		$ kapacitor lint -tick path/to/TICKscript -type stream -dbrp mydb.myrp
 
-	Later you can change a single property of the template by referencing its name
-	and only providing the single option you wish to modify.
+	Check a TICKscript against a schema file:
 
-		$ kapacitor define-template my_template -tick path/to/TICKscript
+		$ kapacitor lint -tick path/to/TICKscript -schema path/to/schema.json
 
-	or
+	where the schema file maps each measurement to its fields, with their InfluxDB types, and tags:
 
-		$ kapacitor define-template my_template -type batch
+		{
+		    "measurements": {
+		        "cpu": {
+		            "fields": {"usage_idle": "float", "usage_user": "float"},
+		            "tags": ["host", "cpu"]
+		        }
+		    }
+		}
 
 Options:
 
 `
 	fmt.Fprintln(os.Stderr, u)
-	defineTemplateFlags.PrintDefaults()
+	lintFlags.PrintDefaults()
 }
 
-func doDefineTemplate(args []string) error {
-	if len(args) < 1 {
-		fmt.Fprintln(os.Stderr, "Must provide a template ID.")
-		defineTemplateFlags.Usage()
+func doLint(args []string) error {
+	if *ltick == "" {
+		fmt.Fprintln(os.Stderr, "Must provide a TICKscript.")
+		lintFlags.Usage()
 		os.Exit(2)
 	}
-	defineTemplateFlags.Parse(args[1:])
-	id := args[0]
+	data, err := ioutil.ReadFile(*ltick)
+	if err != nil {
+		return err
+	}
 
-	var script string
-	if *dtTick != "" {
-		file, err := os.Open(*dtTick)
+	var ttype client.TaskType
+	switch *ltype {
+	case "stream":
+		ttype = client.StreamTask
+	case "batch":
+		ttype = client.BatchTask
+	}
+
+	vars := make(client.Vars)
+	if *lvars != "" {
+		f, err := os.Open(*lvars)
 		if err != nil {
-			return err
+			return errors.Wrapf(err, "faild to open file %s", *lvars)
 		}
-		defer file.Close()
-		data, err := ioutil.ReadAll(file)
+		defer f.Close()
+		dec := json.NewDecoder(f)
+		if err := dec.Decode(&vars); err != nil {
+			return errors.Wrapf(err, "invalid JSON in file %s", *lvars)
+		}
+	}
+
+	var schema *client.LintSchema
+	if *lschema != "" {
+		f, err := os.Open(*lschema)
+		if err != nil {
+			return errors.Wrapf(err, "faild to open file %s", *lschema)
+		}
+		defer f.Close()
+		schema = new(client.LintSchema)
+		dec := json.NewDecoder(f)
+		if err := dec.Decode(schema); err != nil {
+			return errors.Wrapf(err, "invalid JSON in file %s", *lschema)
+		}
+	}
+
+	result, err := cli.LintTask(client.LintTaskOptions{
+		Type:       ttype,
+		DBRPs:      ldbrp,
+		TICKscript: string(data),
+		Vars:       vars,
+		Schema:     schema,
+		Cluster:    *lcluster,
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range result.Problems {
+		fmt.Printf("%s: %s\n", *ltick, p)
+	}
+	if l := len(result.Problems); l > 0 {
+		return fmt.Errorf("found %d problem(s)", l)
+	}
+	return nil
+}
+
+// Eval
+var (
+	evalFlags  = flag.NewFlagSet("eval", flag.ExitOnError)
+	etick      = evalFlags.String("tick", "", "Path to the TICKscript")
+	elambda    = evalFlags.String("lambda", "", "A lambda expression to evaluate instead of a TICKscript")
+	etype      = evalFlags.String("type", "", "The task type (stream|batch)")
+	evars      = evalFlags.String("vars", "", "Optional path to a JSON vars file")
+	epoints    = evalFlags.String("points", "", "Path to a file of points in line protocol")
+	eprecision = evalFlags.String("precision", "", "Precision of the timestamps of the points (n|u|ms|s|m|h)")
+	edbrp      = make(dbrps, 0)
+)
+
+func init() {
+	evalFlags.Var(&edbrp, "dbrp", `A database and retention policy pair of the form "db"."rp" the quotes are optional. The flag can be specified multiple times.`)
+}
+
+func evalUsage() {
+	var u = `Usage: kapacitor eval [options]
+
+	Evaluate a TICKscript or a lambda expression against sample points without defining a task.
+
+	The points are run through the TICKscript in a temporary task that does not receive live data.
+	Nothing is written to InfluxDB and no alerts are sent to handlers, instead the points
+	collected by each node, the alerts triggered and the points written are printed.
+
+	A lambda expression is evaluated against each point in order and its values are printed.
+
+For example:
+
+	Evaluate a TICKscript:
+
+		$ kapacitor eval -tick path/to/TICKscript -points path/to/points.txt
+
+	Evaluate a lambda expression:
+
+		$ kapacitor eval -lambda '"usage_idle" < 10.0' -points path/to/points.txt
+
+	where the points file contains points in line protocol:
+
+		cpu,host=serverA usage_idle=12.5 1490000000000000000
+		cpu,host=serverA usage_idle=7.5 1490000010000000000
+
+Options:
+
+`
+	fmt.Fprintln(os.Stderr, u)
+	evalFlags.PrintDefaults()
+}
+
+func doEval(args []string) error {
+	if (*etick == "") == (*elambda == "") {
+		fmt.Fprintln(os.Stderr, "Must provide either a TICKscript or a lambda expression.")
+		evalFlags.Usage()
+		os.Exit(2)
+	}
+	if *epoints == "" {
+		fmt.Fprintln(os.Stderr, "Must provide a points file.")
+		evalFlags.Usage()
+		os.Exit(2)
+	}
+	points, err := ioutil.ReadFile(*epoints)
+	if err != nil {
+		return err
+	}
+	opt := client.EvaluateTaskOptions{
+		DBRPs:     edbrp,
+		Lambda:    *elambda,
+		Points:    string(points),
+		Precision: *eprecision,
+	}
+	if *etick != "" {
+		data, err := ioutil.ReadFile(*etick)
 		if err != nil {
 			return err
 		}
-		script = string(data)
+		opt.TICKscript = string(data)
 	}
 
-	var ttype client.TaskType
-	switch *dtType {
+	switch *etype {
 	case "stream":
-		ttype = client.StreamTask
+		opt.Type = client.StreamTask
 	case "batch":
-		ttype = client.BatchTask
+		opt.Type = client.BatchTask
 	}
 
-	l := cli.TemplateLink(id)
-	template, _ := cli.Template(l, nil)
-	var err error
-	if template.ID == "" {
-		_, err = cli.CreateTemplate(client.CreateTemplateOptions{
-			ID:         id,
-			Type:       ttype,
-			TICKscript: script,
-		})
-	} else {
-		_, err = cli.UpdateTemplate(
-			l,
-			client.UpdateTemplateOptions{
-				Type:       ttype,
-				TICKscript: script,
-			},
-		)
+	if *evars != "" {
+		f, err := os.Open(*evars)
+		if err != nil {
+			return errors.Wrapf(err, "faild to open file %s", *evars)
+		}
+		defer f.Close()
+		opt.Vars = make(client.Vars)
+		dec := json.NewDecoder(f)
+		if err := dec.Decode(&opt.Vars); err != nil {
+			return errors.Wrapf(err, "invalid JSON in file %s", *evars)
+		}
 	}
-	return err
+
+	result, err := cli.EvaluateTask(opt)
+	if err != nil {
+		return err
+	}
+	if opt.Lambda != "" {
+		for i, v := range result.Values {
+			if v.Error != "" {
+				fmt.Printf("%d: error: %s\n", i+1, v.Error)
+			} else {
+				fmt.Printf("%d: %v\n", i+1, v.Value)
+			}
+		}
+		return nil
+	}
+	for _, t := range result.Traces {
+		fmt.Printf("%s -> %s:\n", t.Parent, t.Child)
+		for _, p := range t.Points {
+			fmt.Println("    " + formatEvaluatePoint(p))
+		}
+	}
+	if len(result.Alerts) > 0 {
+		fmt.Println("Alerts:")
+		for _, a := range result.Alerts {
+			fmt.Printf("    %-8s %s %s %s\n", a.State.Level, a.State.Time.Format(time.RFC3339Nano), a.ID, a.State.Message)
+		}
+	}
+	if len(result.Points) > 0 {
+		fmt.Println("Points written:")
+		for _, p := range result.Points {
+			fmt.Printf("    %s.%s %s\n", p.Database, p.RetentionPolicy, formatEvaluatePoint(p))
+		}
+	}
+	if result.Error != "" {
+		return errors.New(result.Error)
+	}
+	return nil
 }
 
-func defineTopicHandlerUsage() {
-	var u = `Usage: kapacitor define-topic-handler <topic id> <handler id> <path to handler spec file>
+// formatEvaluatePoint formats a point like line protocol, with sorted tags and fields.
+func formatEvaluatePoint(p client.EvaluatePoint) string {
+	var buf bytes.Buffer
+	buf.WriteString(p.Name)
+	tags := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		tags = append(tags, k)
+	}
+	sort.Strings(tags)
+	for _, k := range tags {
+		fmt.Fprintf(&buf, ",%s=%s", k, p.Tags[k])
+	}
+	fields := make([]string, 0, len(p.Fields))
+	for k := range p.Fields {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	for i, k := range fields {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, "%s=%v", k, p.Fields[k])
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(p.Time.Format(time.RFC3339Nano))
+	return buf.String()
+}
 
-	Create or update a handler.
+// User
+var (
+	userCreateFlags = flag.NewFlagSet("user create", flag.ExitOnError)
+	ucPassword      = userCreateFlags.String("password", "", "The password of the user, read from stdin if not set")
+	ucAdmin         = userCreateFlags.Bool("admin", false, "Whether the user is an admin with all privileges")
+	ucPrivileges    = make(privileges)
+	userUpdateFlags = flag.NewFlagSet("user update", flag.ExitOnError)
+	uuPassword      = userUpdateFlags.String("password", "", "The new password of the user")
+	uuAdmin         = userUpdateFlags.String("admin", "", "Whether the user is an admin with all privileges (true|false)")
+	uuPrivileges    = make(privileges)
+)
 
-	A handler is defined via a JSON or YAML file.
+func init() {
+	userCreateFlags.Var(&ucPrivileges, "privilege", `A privilege of the form resource=privilege[,privilege...] i.e. /api/tasks=read,write. The flag can be specified multiple times.`)
+	userUpdateFlags.Var(&uuPrivileges, "privilege", `A privilege of the form resource=privilege[,privilege...] i.e. /api/tasks=read,write. The flag can be specified multiple times. Replaces all existing privileges of the user.`)
+	userCreateFlags.Usage = userUsage
+	userUpdateFlags.Usage = userUsage
+}
+
+// privileges maps resources to the names of their privileges.
+type privileges map[string][]string
+
+func (p *privileges) String() string {
+	return fmt.Sprint(*p)
+}
+
+// Parse string of the form resource=privilege[,privilege...]
+func (p *privileges) Set(value string) error {
+	i := strings.IndexRune(value, '=')
+	if i <= 0 || i == len(value)-1 {
+		return errors.New("privilege must be of the form resource=privilege[,privilege...]")
+	}
+	resource := value[:i]
+	(*p)[resource] = append((*p)[resource], strings.Split(value[i+1:], ",")...)
+	return nil
+}
+
+func userUsage() {
+	var u = `Usage: kapacitor user (create|update|list|show|delete) [options] [args...]
+
+	Manage the users of the local auth service.
+
+	Only admin users can manage users. Privileges are granted on resources,
+	API endpoints are resources of the form /api/<path> and databases of the form
+	/database/<name>_clean. The privileges are read, write, delete and all.
+
+	Create a user:
+
+		$ kapacitor user create [-admin] [-password password] [-privilege resource=privilege...] <name>
+
+	Update a user, only the given options are changed:
+
+		$ kapacitor user update [-admin true|false] [-password password] [-privilege resource=privilege...] <name>
+
+	List users matching the optional patterns:
+
+		$ kapacitor user list [pattern...]
+
+	Show a user:
+
+		$ kapacitor user show <name>
+
+	Delete users:
+
+		$ kapacitor user delete <name>...
 
 For example:
 
-	Define a handler using the slack.yaml file:
+	Create a user that can read and write tasks and read all other API endpoints:
 
-		$ kapacitor define-handler system my_handler slack.yaml
+		$ kapacitor user create -privilege /api/tasks=read,write -privilege /api=read bob
 
 Options:
+
 `
 	fmt.Fprintln(os.Stderr, u)
+	fmt.Fprintln(os.Stderr, "create:")
+	userCreateFlags.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "update:")
+	userUpdateFlags.PrintDefaults()
 }
 
-func doDefineTopicHandler(args []string) error {
-	if len(args) != 3 {
-		fmt.Fprintln(os.Stderr, "Must provide a topic ID, a handler ID and a path to a handler file.")
-		defineTopicHandlerUsage()
+func doUser(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Must specify 'create', 'update', 'list', 'show' or 'delete'")
+		userUsage()
 		os.Exit(2)
 	}
-	topic := args[0]
-	handlerID := args[1]
-	p := args[2]
-	f, err := os.Open(p)
-	if err != nil {
-		return errors.Wrapf(err, "failed to open handler spec file %q", p)
+	switch action := args[0]; action {
+	case "create":
+		userCreateFlags.Parse(args[1:])
+		return doUserCreate(userCreateFlags.Args())
+	case "update":
+		userUpdateFlags.Parse(args[1:])
+		return doUserUpdate(userUpdateFlags.Args())
+	case "list":
+		return doUserList(args[1:])
+	case "show":
+		return doUserShow(args[1:])
+	case "delete":
+		return doUserDelete(args[1:])
+	default:
+		return fmt.Errorf("unknown user action %q", action)
 	}
+}
 
-	// Decode file into HandlerOptions
-	var ho client.TopicHandlerOptions
-	ext := path.Ext(p)
-	switch ext {
-	case ".yaml", ".yml":
-		data, err := ioutil.ReadAll(f)
+func doUserCreate(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Must provide exactly one user name.")
+		userUsage()
+		os.Exit(2)
+	}
+	password := *ucPassword
+	if password == "" {
+		var err error
+		password, err = readPassword()
 		if err != nil {
-			return errors.Wrapf(err, "failed to read handler file %q", p)
+			return err
 		}
-		if err := yaml.Unmarshal(data, &ho); err != nil {
-			return errors.Wrapf(err, "failed to unmarshal yaml handler file %q", p)
+	}
+	_, err := cli.CreateUser(client.CreateUserOptions{
+		Name:       args[0],
+		Password:   password,
+		Admin:      *ucAdmin,
+		Privileges: ucPrivileges,
+	})
+	return err
+}
+
+// readPassword reads a password from the first line of stdin.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	in := bufio.NewScanner(os.Stdin)
+	if !in.Scan() {
+		if err := in.Err(); err != nil {
+			return "", err
 		}
-	case ".json":
-		if err := json.NewDecoder(f).Decode(&ho); err != nil {
-			return errors.Wrapf(err, "failed to unmarshal json handler file %q", p)
+		return "", errors.New("no password provided")
+	}
+	password := strings.TrimRight(in.Text(), "\r")
+	if password == "" {
+		return "", errors.New("password cannot be empty")
+	}
+	return password, nil
+}
+
+func doUserUpdate(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Must provide exactly one user name.")
+		userUsage()
+		os.Exit(2)
+	}
+	opt := client.UpdateUserOptions{
+		Password: *uuPassword,
+	}
+	if *uuAdmin != "" {
+		admin, err := strconv.ParseBool(*uuAdmin)
+		if err != nil {
+			return errors.Wrapf(err, "invalid admin value %q", *uuAdmin)
 		}
+		opt.Admin = &admin
 	}
-	ho.ID = handlerID
+	if len(uuPrivileges) > 0 {
+		opt.Privileges = uuPrivileges
+	}
+	_, err := cli.UpdateUser(cli.UserLink(args[0]), opt)
+	return err
+}
 
-	l := cli.TopicHandlerLink(topic, ho.ID)
+func doUserList(patterns []string) error {
+	if len(patterns) == 0 {
+		patterns = []string{""}
+	}
+	limit := 100
+	var users []client.User
+	for _, pattern := range patterns {
+		offset := 0
+		for {
+			list, err := cli.ListUsers(&client.ListUsersOptions{
+				Pattern: pattern,
+				Offset:  offset,
+				Limit:   limit,
+			})
+			if err != nil {
+				return err
+			}
+			users = append(users, list...)
+			if len(list) != limit {
+				break
+			}
+			offset += limit
+		}
+	}
+	maxName := 4 // len("Name")
+	for _, u := range users {
+		if l := len(u.Name); l > maxName {
+			maxName = l
+		}
+	}
+	outFmt := fmt.Sprintf("%%-%ds%%-7v\n", maxName+1)
+	fmt.Fprintf(os.Stdout, outFmt, "Name", "Admin")
+	for _, u := range users {
+		fmt.Fprintf(os.Stdout, outFmt, u.Name, u.Admin)
+	}
+	return nil
+}
+
+func doUserShow(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Must provide exactly one user name.")
+		userUsage()
+		os.Exit(2)
+	}
+	u, err := cli.User(cli.UserLink(args[0]))
+	if err != nil {
+		return err
+	}
+	fmt.Println("Name:", u.Name)
+	fmt.Println("Admin:", u.Admin)
+	fmt.Println("Privileges:")
+	resources := make([]string, 0, len(u.Privileges))
+	for r := range u.Privileges {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+	for _, r := range resources {
+		fmt.Printf("    %s: %s\n", r, strings.Join(u.Privileges[r], ", "))
+	}
+	return nil
+}
+
+func doUserDelete(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Must provide at least one user name.")
+		userUsage()
+		os.Exit(2)
+	}
+	for _, name := range args {
+		if err := cli.DeleteUser(cli.UserLink(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Token
+var (
+	tokenCreateFlags = flag.NewFlagSet("token create", flag.ExitOnError)
+	tcExpires        = tokenCreateFlags.Duration("expires", 0, "Duration after which the token expires, i.e. 720h. The token never expires if not set.")
+	tcPrivileges     = make(privileges)
+)
+
+func init() {
+	tokenCreateFlags.Var(&tcPrivileges, "privilege", `A privilege of the form resource=privilege[,privilege...] i.e. /api/tasks/ci-*=read,write. The resource may be a glob pattern. The flag can be specified multiple times.`)
+	tokenCreateFlags.Usage = tokenUsage
+}
+
+func tokenUsage() {
+	var u = `Usage: kapacitor token (create|list|show|revoke) [options] [args...]
+
+	Manage the API tokens of the local auth service.
+
+	API tokens are used as bearer tokens in place of a user and password.
+	They only have the privileges they were created with, the resources of the
+	privileges may be glob patterns, i.e. /api/tasks/ci-* matches all tasks
+	whose ID starts with ci-. Creating a task is authorized against the
+	resource of the new task, so such a token can also create tasks whose ID
+	starts with ci-.
+
+	Set the KAPACITOR_TOKEN environment variable to use a token with this command.
+
+	Only admin users can manage API tokens.
+
+	Create a token, the token is printed once and cannot be retrieved later:
+
+		$ kapacitor token create [-expires duration] [-privilege resource=privilege...] <name>
+
+	List tokens matching the optional patterns:
+
+		$ kapacitor token list [pattern...]
+
+	Show a token:
+
+		$ kapacitor token show <name>
+
+	Revoke tokens:
+
+		$ kapacitor token revoke <name>...
+
+For example:
+
+	Create a token for a CI pipeline that can only manage tasks starting with ci-:
+
+		$ kapacitor token create -expires 720h -privilege '/api/tasks/ci-*=all' ci
+
+Options:
+
+`
+	fmt.Fprintln(os.Stderr, u)
+	tokenCreateFlags.PrintDefaults()
+}
+
+func doToken(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Must specify 'create', 'list', 'show' or 'revoke'")
+		tokenUsage()
+		os.Exit(2)
+	}
+	switch action := args[0]; action {
+	case "create":
+		tokenCreateFlags.Parse(args[1:])
+		return doTokenCreate(tokenCreateFlags.Args())
+	case "list":
+		return doTokenList(args[1:])
+	case "show":
+		return doTokenShow(args[1:])
+	case "revoke":
+		return doTokenRevoke(args[1:])
+	default:
+		return fmt.Errorf("unknown token action %q", action)
+	}
+}
+
+func doTokenCreate(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Must provide exactly one token name.")
+		tokenUsage()
+		os.Exit(2)
+	}
+	opt := client.CreateAPITokenOptions{
+		Name:       args[0],
+		Privileges: tcPrivileges,
+	}
+	if *tcExpires > 0 {
+		opt.Expires = time.Now().Add(*tcExpires)
+	}
+	t, err := cli.CreateAPIToken(opt)
+	if err != nil {
+		return err
+	}
+	fmt.Println(t.Token)
+	return nil
+}
+
+func formatTokenTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format(time.RFC822)
+}
+
+func doTokenList(patterns []string) error {
+	if len(patterns) == 0 {
+		patterns = []string{""}
+	}
+	limit := 100
+	var tokens []client.APIToken
+	for _, pattern := range patterns {
+		offset := 0
+		for {
+			list, err := cli.ListAPITokens(&client.ListAPITokensOptions{
+				Pattern: pattern,
+				Offset:  offset,
+				Limit:   limit,
+			})
+			if err != nil {
+				return err
+			}
+			tokens = append(tokens, list...)
+			if len(list) != limit {
+				break
+			}
+			offset += limit
+		}
+	}
+	maxName := 4 // len("Name")
+	for _, t := range tokens {
+		if l := len(t.Name); l > maxName {
+			maxName = l
+		}
+	}
+	outFmt := fmt.Sprintf("%%-%ds%%-20s%%-20s\n", maxName+1)
+	fmt.Fprintf(os.Stdout, outFmt, "Name", "Expires", "Last Used")
+	for _, t := range tokens {
+		fmt.Fprintf(os.Stdout, outFmt, t.Name, formatTokenTime(t.Expires), formatTokenTime(t.LastUsed))
+	}
+	return nil
+}
+
+func doTokenShow(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Must provide exactly one token name.")
+		tokenUsage()
+		os.Exit(2)
+	}
+	t, err := cli.APIToken(cli.APITokenLink(args[0]))
+	if err != nil {
+		return err
+	}
+	fmt.Println("Name:", t.Name)
+	fmt.Println("Created:", formatTokenTime(t.Created))
+	fmt.Println("Expires:", formatTokenTime(t.Expires))
+	fmt.Println("Last Used:", formatTokenTime(t.LastUsed))
+	fmt.Println("Privileges:")
+	resources := make([]string, 0, len(t.Privileges))
+	for r := range t.Privileges {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+	for _, r := range resources {
+		fmt.Printf("    %s: %s\n", r, strings.Join(t.Privileges[r], ", "))
+	}
+	return nil
+}
+
+func doTokenRevoke(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Must provide at least one token name.")
+		tokenUsage()
+		os.Exit(2)
+	}
+	for _, name := range args {
+		if err := cli.DeleteAPIToken(cli.APITokenLink(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefineTemplate
+var (
+	defineTemplateFlags = flag.NewFlagSet("define-template", flag.ExitOnError)
+	dtTick              = defineTemplateFlags.String("tick", "", "Path to the TICKscript")
+	dtType              = defineTemplateFlags.String("type", "", "The template type (stream|batch)")
+)
+
+func defineTemplateUsage() {
+	var u = `Usage: kapacitor define-template <template ID> [options]
+
+	Create or update a template.
+
+	A template is defined via a TICKscript that defines the data processing pipeline of the template.
+
+	If an option is absent it will be left unmodified.
+
+	NOTE: Updating a template will reload all associated tasks.
+
+For example:
+
+	You must define a template for the first time with all the flags.
+
+		$ kapacitor define-template my_template -tick path/to/TICKscript -type stream
+
+	Later you can change a single property of the template by referencing its name
+	and only providing the single option you wish to modify.
+
+		$ kapacitor define-template my_template -tick path/to/TICKscript
+
+	or
+
+		$ kapacitor define-template my_template -type batch
+
+Options:
+
+`
+	fmt.Fprintln(os.Stderr, u)
+	defineTemplateFlags.PrintDefaults()
+}
+
+func doDefineTemplate(args []string) error {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Must provide a template ID.")
+		defineTemplateFlags.Usage()
+		os.Exit(2)
+	}
+	defineTemplateFlags.Parse(args[1:])
+	id := args[0]
+
+	var script string
+	if *dtTick != "" {
+		file, err := os.Open(*dtTick)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		script = string(data)
+	}
+
+	var ttype client.TaskType
+	switch *dtType {
+	case "stream":
+		ttype = client.StreamTask
+	case "batch":
+		ttype = client.BatchTask
+	}
+
+	l := cli.TemplateLink(id)
+	template, _ := cli.Template(l, nil)
+	var err error
+	if template.ID == "" {
+		_, err = cli.CreateTemplate(client.CreateTemplateOptions{
+			ID:         id,
+			Type:       ttype,
+			TICKscript: script,
+		})
+	} else {
+		_, err = cli.UpdateTemplate(
+			l,
+			client.UpdateTemplateOptions{
+				Type:       ttype,
+				TICKscript: script,
+			},
+		)
+	}
+	return err
+}
+
+// DefineTaskGroup
+var (
+	defineTaskGroupFlags = flag.NewFlagSet("define-task-group", flag.ExitOnError)
+	dgTasks              = make(taskIDs, 0)
+	dgDepends            = make(dependencies)
+)
+
+func init() {
+	defineTaskGroupFlags.Var(&dgTasks, "task", "The ID of a task of the group. The flag can be specified multiple times. Replaces all existing tasks of the group.")
+	defineTaskGroupFlags.Var(&dgDepends, "depends", `A dependency of the form task=dependency[,dependency...] i.e. downsample=alert. The task is enabled after and disabled before its dependencies. The flag can be specified multiple times. Replaces all existing dependencies of the group.`)
+}
+
+type taskIDs []string
+
+func (t *taskIDs) String() string {
+	return fmt.Sprint(*t)
+}
+
+func (t *taskIDs) Set(value string) error {
+	if value == "" {
+		return errors.New("task ID cannot be empty")
+	}
+	*t = append(*t, value)
+	return nil
+}
+
+// dependencies maps task IDs to the IDs of the tasks they depend on.
+type dependencies map[string][]string
+
+func (d *dependencies) String() string {
+	return fmt.Sprint(*d)
+}
+
+// Parse string of the form task=dependency[,dependency...]
+func (d *dependencies) Set(value string) error {
+	i := strings.IndexRune(value, '=')
+	if i <= 0 || i == len(value)-1 {
+		return errors.New("dependency must be of the form task=dependency[,dependency...]")
+	}
+	task := value[:i]
+	(*d)[task] = append((*d)[task], strings.Split(value[i+1:], ",")...)
+	return nil
+}
+
+func defineTaskGroupUsage() {
+	var u = `Usage: kapacitor define-task-group <task group ID> [options]
+
+	Create or update a task group.
+
+	The tasks of a group are enabled, disabled and reloaded together with the -group option
+	of the enable, disable and reload commands.
+	A task is enabled after and disabled before the tasks it depends on.
+
+	Dependencies between tasks that write data via a KapacitorLoopback node and the tasks
+	that read that data are inferred, the writing task depends on the reading task
+	so that no data is lost. They do not need to be declared.
+
+	If an option is absent it will be left unmodified.
+
+For example:
+
+	Define a group of two tasks, where the alert task must be running before the downsample task:
+
+		$ kapacitor define-task-group downsampling -task downsample -task alert -depends downsample=alert
+
+Options:
+
+`
+	fmt.Fprintln(os.Stderr, u)
+	defineTaskGroupFlags.PrintDefaults()
+}
+
+func doDefineTaskGroup(args []string) error {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Must provide a task group ID.")
+		defineTaskGroupFlags.Usage()
+		os.Exit(2)
+	}
+	defineTaskGroupFlags.Parse(args[1:])
+	id := args[0]
+
+	var deps map[string][]string
+	if len(dgDepends) > 0 {
+		deps = dgDepends
+	}
+
+	l := cli.TaskGroupLink(id)
+	group, _ := cli.TaskGroup(l)
+	var err error
+	if group.ID == "" {
+		group, err = cli.CreateTaskGroup(client.CreateTaskGroupOptions{
+			ID:           id,
+			Tasks:        dgTasks,
+			Dependencies: deps,
+		})
+	} else {
+		group, err = cli.UpdateTaskGroup(
+			l,
+			client.UpdateTaskGroupOptions{
+				Tasks:        dgTasks,
+				Dependencies: deps,
+			},
+		)
+	}
+	if err != nil {
+		return err
+	}
+	if group.Error != "" {
+		fmt.Fprintln(os.Stderr, "Warning:", group.Error)
+	}
+	return nil
+}
+
+func defineTopicHandlerUsage() {
+	var u = `Usage: kapacitor define-topic-handler <topic id> <handler id> <path to handler spec file>
+
+	Create or update a handler.
+
+	A handler is defined via a JSON or YAML file.
+
+For example:
+
+	Define a handler using the slack.yaml file:
+
+		$ kapacitor define-handler system my_handler slack.yaml
+
+Options:
+`
+	fmt.Fprintln(os.Stderr, u)
+}
+
+func doDefineTopicHandler(args []string) error {
+	if len(args) != 3 {
+		fmt.Fprintln(os.Stderr, "Must provide a topic ID, a handler ID and a path to a handler file.")
+		defineTopicHandlerUsage()
+		os.Exit(2)
+	}
+	topic := args[0]
+	handlerID := args[1]
+	p := args[2]
+	f, err := os.Open(p)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open handler spec file %q", p)
+	}
+
+	// Decode file into HandlerOptions
+	var ho client.TopicHandlerOptions
+	ext := path.Ext(p)
+	switch ext {
+	case ".yaml", ".yml":
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read handler file %q", p)
+		}
+		if err := yaml.Unmarshal(data, &ho); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal yaml handler file %q", p)
+		}
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&ho); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal json handler file %q", p)
+		}
+	}
+	ho.ID = handlerID
+
+	l := cli.TopicHandlerLink(topic, ho.ID)
 	handler, _ := cli.TopicHandler(l)
 	if handler.ID == "" {
 		_, err = cli.CreateTopicHandler(cli.TopicHandlersLink(topic), ho)
 	} else {
 		_, err = cli.ReplaceTopicHandler(l, ho)
 	}
-	return err
+	return err
+}
+
+// Apply
+var (
+	applyFlags = flag.NewFlagSet("apply", flag.ExitOnError)
+	apDir      = applyFlags.String("dir", "", "Path to the directory of definitions.")
+	apDryRun   = applyFlags.Bool("dry-run", false, "Only display the changes without making them.")
+	apPrune    = applyFlags.Bool("prune", false, "Delete the tasks, templates and topic handlers that are not defined in the directory.")
+)
+
+func init() {
+	applyFlags.Usage = applyUsage
+}
+
+func applyUsage() {
+	var u = `Usage: kapacitor apply -dir <path to directory> [options]
+
+	Create, update and optionally delete tasks, templates and topic handlers
+	so that they match the definitions of a directory.
+
+	Either all changes are made or none, the changes already made are reverted if a change fails.
+	Enabled tasks whose definition changed are reloaded.
+
+	The directory contains:
+
+	    templates/<template ID>.tick            The TICKscript of a template.
+	    templates/<template ID>.(yaml|json)     Optional spec of the template: type, defaults to stream.
+	    tasks/<task ID>.tick                    The TICKscript of a task.
+	    tasks/<task ID>.(yaml|json)             Optional spec of the task: type, dbrps, status and vars,
+	                                            the status defaults to enabled.
+	                                            A spec with a template-id defines a task from a template
+	                                            and does not need a TICKscript.
+	    topic-handlers/<topic ID>/<handler ID>.(yaml|json)
+	                                            A topic handler, the same as for define-topic-handler.
+
+	A task spec has the form:
+
+		template-id: my_template
+		dbrps:
+		  - db: telegraf
+		    rp: autogen
+		status: enabled
+		vars:
+		  warn:
+		    type: float
+		    value: 80
+
+For example:
+
+	Display the changes the definitions make:
+
+		$ kapacitor apply -dir ./kapacitor -dry-run
+
+	Apply the definitions and delete everything else:
+
+		$ kapacitor apply -dir ./kapacitor -prune
+
+Options:
+
+`
+	fmt.Fprintln(os.Stderr, u)
+	applyFlags.PrintDefaults()
+}
+
+func doApply(args []string) error {
+	if *apDir == "" || len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Must provide a directory.")
+		applyUsage()
+		os.Exit(2)
+	}
+	opt, err := loadApplyDir(*apDir)
+	if err != nil {
+		return err
+	}
+	opt.Prune = *apPrune
+	opt.DryRun = *apDryRun
+
+	result, err := cli.Apply(opt)
+	if err != nil {
+		return err
+	}
+	if len(result.Changes) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+	if result.DryRun {
+		fmt.Println("Changes to apply:")
+	} else {
+		fmt.Println("Changes applied:")
+	}
+	for _, c := range result.Changes {
+		fmt.Println(c.Action, c.Kind, c.ID)
+		for _, change := range c.Changes {
+			fmt.Println("    " + change)
+		}
+		if c.ScriptDiff != "" {
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(c.ScriptDiff, "\n"), "\n") {
+				fmt.Print("    " + line)
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// applySpec is the spec of a template or task of the apply directory.
+type applySpec struct {
+	TemplateID string            `json:"template-id"`
+	Type       client.TaskType   `json:"type"`
+	DBRPs      []client.DBRP     `json:"dbrps"`
+	Status     client.TaskStatus `json:"status"`
+	Vars       client.Vars       `json:"vars"`
+}
+
+// loadApplyDir reads the definitions of the directory.
+func loadApplyDir(dir string) (client.ApplyOptions, error) {
+	opt := client.ApplyOptions{}
+
+	templates, err := readApplyDefinitions(path.Join(dir, "templates"))
+	if err != nil {
+		return opt, err
+	}
+	for _, id := range sortedDefinitionIDs(templates) {
+		d := templates[id]
+		if d.script == "" {
+			return opt, fmt.Errorf("missing TICKscript of template %s", id)
+		}
+		if d.spec.Type == 0 {
+			d.spec.Type = client.StreamTask
+		}
+		opt.Templates = append(opt.Templates, client.CreateTemplateOptions{
+			ID:         id,
+			Type:       d.spec.Type,
+			TICKscript: d.script,
+		})
+	}
+
+	tasks, err := readApplyDefinitions(path.Join(dir, "tasks"))
+	if err != nil {
+		return opt, err
+	}
+	for _, id := range sortedDefinitionIDs(tasks) {
+		d := tasks[id]
+		if d.script == "" && d.spec.TemplateID == "" {
+			return opt, fmt.Errorf("missing TICKscript or template-id of task %s", id)
+		}
+		if d.spec.Status == 0 {
+			d.spec.Status = client.Enabled
+		}
+		opt.Tasks = append(opt.Tasks, client.CreateTaskOptions{
+			ID:         id,
+			TemplateID: d.spec.TemplateID,
+			Type:       d.spec.Type,
+			DBRPs:      d.spec.DBRPs,
+			TICKscript: d.script,
+			Status:     d.spec.Status,
+			Vars:       d.spec.Vars,
+		})
+	}
+
+	handlersDir := path.Join(dir, "topic-handlers")
+	topics, err := ioutil.ReadDir(handlersDir)
+	if err != nil && !os.IsNotExist(err) {
+		return opt, err
+	}
+	for _, topic := range topics {
+		if !topic.IsDir() {
+			continue
+		}
+		files, err := ioutil.ReadDir(path.Join(handlersDir, topic.Name()))
+		if err != nil {
+			return opt, err
+		}
+		for _, f := range files {
+			ext := path.Ext(f.Name())
+			if f.IsDir() || !isApplySpec(ext) {
+				continue
+			}
+			h := client.ApplyTopicHandler{Topic: topic.Name()}
+			if err := decodeApplyFile(path.Join(handlersDir, topic.Name(), f.Name()), &h.TopicHandlerOptions); err != nil {
+				return opt, err
+			}
+			h.ID = strings.TrimSuffix(f.Name(), ext)
+			opt.TopicHandlers = append(opt.TopicHandlers, h)
+		}
+	}
+	return opt, nil
+}
+
+type applyDefinition struct {
+	script string
+	spec   applySpec
+}
+
+// readApplyDefinitions reads the TICKscripts and specs of a directory by ID.
+// A missing directory has no definitions.
+func readApplyDefinitions(dir string) (map[string]*applyDefinition, error) {
+	definitions := make(map[string]*applyDefinition)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return definitions, nil
+		}
+		return nil, err
+	}
+	for _, f := range files {
+		ext := path.Ext(f.Name())
+		if f.IsDir() || (ext != ".tick" && !isApplySpec(ext)) {
+			continue
+		}
+		id := strings.TrimSuffix(f.Name(), ext)
+		d, ok := definitions[id]
+		if !ok {
+			d = new(applyDefinition)
+			definitions[id] = d
+		}
+		p := path.Join(dir, f.Name())
+		if ext == ".tick" {
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return nil, err
+			}
+			d.script = string(data)
+		} else if err := decodeApplyFile(p, &d.spec); err != nil {
+			return nil, err
+		}
+	}
+	return definitions, nil
+}
+
+func isApplySpec(ext string) bool {
+	return ext == ".yaml" || ext == ".yml" || ext == ".json"
+}
+
+// decodeApplyFile decodes a JSON or YAML file into v.
+func decodeApplyFile(p string, v interface{}) error {
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read file %q", p)
+	}
+	if path.Ext(p) == ".json" {
+		if err := json.Unmarshal(data, v); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal json file %q", p)
+		}
+		return nil
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal yaml file %q", p)
+	}
+	return nil
+}
+
+func sortedDefinitionIDs(definitions map[string]*applyDefinition) []string {
+	keys := make([]string, 0, len(definitions))
+	for k := range definitions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // Replay
@@ -1141,8 +2361,13 @@ func doReplayLive(args []string) error {
 }
 
 // Enable
+var (
+	enableFlags = flag.NewFlagSet("enable", flag.ExitOnError)
+	enGroup     = enableFlags.String("group", "", "Enable the tasks of a task group in the order of their dependencies.")
+)
+
 func enableUsage() {
-	var u = `Usage: kapacitor enable [task ID...]
+	var u = `Usage: kapacitor enable [-group <task group ID>] [task ID...]
 
 	Enable and start a task running from the live data.
 
@@ -1155,19 +2380,41 @@ For example:
 	Or, you can enable by glob:
 
 		$ kapacitor enable *_alert
+
+	Or, you can enable all tasks of a task group,
+	each task is enabled after the tasks it depends on.
+
+		$ kapacitor enable -group downsampling
+
+Options:
+
 `
 	fmt.Fprintln(os.Stderr, u)
+	enableFlags.PrintDefaults()
 }
 
 func doEnable(args []string) error {
-	if len(args) < 1 {
-		fmt.Fprintln(os.Stderr, "Must pass at least one task ID")
+	if len(args) < 1 && *enGroup == "" {
+		fmt.Fprintln(os.Stderr, "Must pass at least one task ID or a task group")
 		enableUsage()
 		os.Exit(2)
 	}
+	if *enGroup != "" {
+		if _, err := cli.EnableTaskGroup(cli.TaskGroupLink(*enGroup)); err != nil {
+			return errors.Wrapf(err, "enabling task group %s", *enGroup)
+		}
+	}
+	return setTasksStatus(args, client.Enabled)
+}
 
+// setTasksStatus enables or disables the tasks matching the patterns.
+func setTasksStatus(patterns []string, status client.TaskStatus) error {
+	action := "enabling"
+	if status == client.Disabled {
+		action = "disabling"
+	}
 	limit := 100
-	for _, pattern := range args {
+	for _, pattern := range patterns {
 		offset := 0
 		for {
 			tasks, err := cli.ListTasks(&client.ListTasksOptions{
@@ -1182,10 +2429,10 @@ func doEnable(args []string) error {
 			for _, task := range tasks {
 				_, err := cli.UpdateTask(
 					task.Link,
-					client.UpdateTaskOptions{Status: client.Enabled},
+					client.UpdateTaskOptions{Status: status},
 				)
 				if err != nil {
-					return errors.Wrapf(err, "enabling task %s", task.ID)
+					return errors.Wrapf(err, "%s task %s", action, task.ID)
 				}
 			}
 			if len(tasks) != limit {
@@ -1198,9 +2445,13 @@ func doEnable(args []string) error {
 }
 
 // Disable
+var (
+	disableFlags = flag.NewFlagSet("disable", flag.ExitOnError)
+	disGroup     = disableFlags.String("group", "", "Disable the tasks of a task group in the reverse order of their dependencies.")
+)
 
 func disableUsage() {
-	var u = `Usage: kapacitor disable [task ID...]
+	var u = `Usage: kapacitor disable [-group <task group ID>] [task ID...]
 
 	Disable and stop a task running.
 
@@ -1213,55 +2464,49 @@ For example:
 	Or, you can disable by glob:
 
 		$ kapacitor disable *_alert
+
+	Or, you can disable all tasks of a task group,
+	each task is disabled before the tasks it depends on.
+
+		$ kapacitor disable -group downsampling
+
+Options:
+
 `
 	fmt.Fprintln(os.Stderr, u)
+	disableFlags.PrintDefaults()
 }
 
 func doDisable(args []string) error {
-	if len(args) < 1 {
-		fmt.Fprintln(os.Stderr, "Must pass at least one task ID")
+	if len(args) < 1 && *disGroup == "" {
+		fmt.Fprintln(os.Stderr, "Must pass at least one task ID or a task group")
 		disableUsage()
 		os.Exit(2)
 	}
-
-	limit := 100
-	for _, pattern := range args {
-		offset := 0
-		for {
-			tasks, err := cli.ListTasks(&client.ListTasksOptions{
-				Pattern: pattern,
-				Fields:  []string{"link"},
-				Offset:  offset,
-				Limit:   limit,
-			})
-			if err != nil {
-				return errors.Wrap(err, "listing tasks")
-			}
-			for _, task := range tasks {
-				_, err := cli.UpdateTask(
-					task.Link,
-					client.UpdateTaskOptions{Status: client.Disabled},
-				)
-				if err != nil {
-					return errors.Wrapf(err, "disabling task %s", task.ID)
-				}
-			}
-			if len(tasks) != limit {
-				break
-			}
-			offset += limit
+	if *disGroup != "" {
+		if _, err := cli.DisableTaskGroup(cli.TaskGroupLink(*disGroup)); err != nil {
+			return errors.Wrapf(err, "disabling task group %s", *disGroup)
 		}
 	}
-	return nil
+	return setTasksStatus(args, client.Disabled)
 }
 
 // Reload
+var (
+	reloadFlags = flag.NewFlagSet("reload", flag.ExitOnError)
+	relGroup    = reloadFlags.String("group", "", "Reload the tasks of a task group, they are disabled in reverse order and then enabled in the order of their dependencies.")
+)
 
 func reloadUsage() {
-	var u = `Usage: kapacitor reload [task ID...]
+	var u = `Usage: kapacitor reload [-group <task group ID>] [task ID...]
 
 	Disable then enable a running task.
 
+	The state of the nodes of the task, such as the buffered points of windows and joins and the alert states,
+	is restored for the nodes whose definition is unchanged.
+	Nodes are matched by their name, i.e. window2, which is numbered in the order the nodes are defined,
+	so adding or removing a node drops the state of the nodes defined after it.
+
 For example:
 
 	You can reload by specific task ID.
@@ -1271,35 +2516,109 @@ For example:
 	Or, you can reload by glob:
 
 		$ kapacitor reload *_alert
+
+	Or, you can reload all tasks of a task group.
+
+		$ kapacitor reload -group downsampling
+
+Options:
+
 `
 	fmt.Fprintln(os.Stderr, u)
+	reloadFlags.PrintDefaults()
 }
 
 func doReload(args []string) error {
-	if len(args) < 1 {
-		fmt.Fprintln(os.Stderr, "Must pass at least one task ID")
+	if len(args) < 1 && *relGroup == "" {
+		fmt.Fprintln(os.Stderr, "Must pass at least one task ID or a task group")
 		reloadUsage()
 		os.Exit(2)
 	}
-	err := doDisable(args)
+	if *relGroup != "" {
+		if _, err := cli.ReloadTaskGroup(cli.TaskGroupLink(*relGroup)); err != nil {
+			return errors.Wrapf(err, "reloading task group %s", *relGroup)
+		}
+	}
+	err := setTasksStatus(args, client.Disabled)
 	if err != nil {
 		return err
 	}
 
-	return doEnable(args)
+	return setTasksStatus(args, client.Enabled)
+}
+
+// Rollback
+var (
+	rollbackFlags     = flag.NewFlagSet("rollback", flag.ExitOnError)
+	rollbackRevisionF = rollbackFlags.Int64("revision", 0, "The revision to restore, defaults to the revision before the latest revision.")
+)
+
+func rollbackUsage() {
+	var u = `Usage: kapacitor rollback [-revision N] [task ID]
+
+	Restore the definition of a task from one of its revisions.
+
+	Every change of the TICKscript, vars, dbrps or type of a task is recorded as a revision,
+	use 'kapacitor show -revisions' to list them. The rollback is recorded as a new revision.
+	The task keeps its status and is reloaded if it is enabled.
+
+For example:
+
+	Undo the last change of a task:
+
+		$ kapacitor rollback cpu_alert
+
+	Restore the first revision of a task:
+
+		$ kapacitor rollback -revision 1 cpu_alert
+
+Options:
+`
+	fmt.Fprintln(os.Stderr, u)
+	rollbackFlags.PrintDefaults()
+}
+
+func doRollback(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Must specify one task ID")
+		rollbackUsage()
+		os.Exit(2)
+	}
+	link := cli.TaskLink(args[0])
+	revision := *rollbackRevisionF
+	if revision == 0 {
+		revisions, err := cli.ListTaskRevisions(link, &client.ListTaskRevisionsOptions{Limit: 2})
+		if err != nil {
+			return err
+		}
+		if len(revisions) < 2 {
+			return fmt.Errorf("task %s has no previous revision", args[0])
+		}
+		revision = revisions[1].Revision
+	}
+	if _, err := cli.RollbackTask(link, revision); err != nil {
+		return err
+	}
+	fmt.Printf("Task %s rolled back to revision %d\n", args[0], revision)
+	return nil
 }
 
 // Show
 var (
-	showFlags = flag.NewFlagSet("show", flag.ExitOnError)
-	sReplayId = showFlags.String("replay", "", "Optional replay ID. If set the task information is in the context of the running replay.")
+	showFlags  = flag.NewFlagSet("show", flag.ExitOnError)
+	sReplayId  = showFlags.String("replay", "", "Optional replay ID. If set the task information is in the context of the running replay.")
+	sRevisions = showFlags.Bool("revisions", false, "List the revisions of the task instead of its details.")
+	sDiff      = showFlags.Int64("diff", 0, "Show the changes of the task since the revision instead of its details.")
 )
 
 func showUsage() {
-	var u = `Usage: kapacitor show [-replay] [task ID]
+	var u = `Usage: kapacitor show [-replay] [-revisions] [-diff N] [task ID]
 
 	Show details about a specific task.
 
+	Use -revisions to list the revisions of the task definition,
+	or -diff N to show the changes of the task since revision N.
+
 Options:
 `
 	fmt.Fprintln(os.Stderr, u)
@@ -1312,6 +2631,12 @@ func doShow(args []string) error {
 		showUsage()
 		os.Exit(2)
 	}
+	if *sRevisions {
+		return doShowRevisions(args[0])
+	}
+	if *sDiff != 0 {
+		return doShowDiff(args[0], *sDiff)
+	}
 
 	t, err := cli.Task(
 		cli.TaskLink(args[0]),
@@ -1326,7 +2651,13 @@ func doShow(args []string) error {
 	fmt.Println("Template:", t.TemplateID)
 	fmt.Println("Type:", t.Type)
 	fmt.Println("Status:", t.Status)
+	if !t.Limits.IsZero() {
+		fmt.Println("Limits:", limitsToStr(t.Limits))
+	}
 	fmt.Println("Executing:", t.Executing)
+	if t.Paused {
+		fmt.Println("Paused:", t.Paused)
+	}
 	fmt.Println("Created:", t.Created.Format(time.RFC822))
 	fmt.Println("Modified:", t.Modified.Format(time.RFC822))
 	fmt.Println("LastEnabled:", t.LastEnabled.Format(time.RFC822))
@@ -1359,6 +2690,78 @@ func doShow(args []string) error {
 	return nil
 }
 
+// limitsToStr formats the limits that are set, i.e. "max-groups=1000 action=stop".
+func limitsToStr(l client.TaskLimits) string {
+	var limits []string
+	if l.MaxGroups > 0 {
+		limits = append(limits, fmt.Sprintf("max-groups=%d", l.MaxGroups))
+	}
+	if l.MaxBufferedPoints > 0 {
+		limits = append(limits, fmt.Sprintf("max-buffered-points=%d", l.MaxBufferedPoints))
+	}
+	if l.MaxEdgeQueue > 0 {
+		limits = append(limits, fmt.Sprintf("max-edge-queue=%d", l.MaxEdgeQueue))
+	}
+	if l.MaxCPU > 0 {
+		limits = append(limits, fmt.Sprintf("max-cpu=%v", l.MaxCPU))
+	}
+	if l.SampleInterval > 0 {
+		limits = append(limits, fmt.Sprintf("sample-interval=%v", time.Duration(l.SampleInterval)))
+	}
+	if l.Action != client.DefaultLimitAction {
+		limits = append(limits, fmt.Sprintf("action=%v", l.Action))
+	}
+	return strings.Join(limits, " ")
+}
+
+func doShowRevisions(id string) error {
+	link := cli.TaskLink(id)
+	limit := 100
+	var revisions []client.TaskRevision
+	for offset := 0; ; offset += limit {
+		list, err := cli.ListTaskRevisions(link, &client.ListTaskRevisionsOptions{
+			Offset: offset,
+			Limit:  limit,
+		})
+		if err != nil {
+			return err
+		}
+		revisions = append(revisions, list...)
+		if len(list) != limit {
+			break
+		}
+	}
+	maxAuthor := 6 // len("Author")
+	for _, r := range revisions {
+		if l := len(r.Author); l > maxAuthor {
+			maxAuthor = l
+		}
+	}
+	outFmt := fmt.Sprintf("%%-10v%%-%ds%%s\n", maxAuthor+1)
+	fmt.Fprintf(os.Stdout, outFmt, "Revision", "Author", "Created")
+	for _, r := range revisions {
+		author := r.Author
+		if author == "" {
+			author = "-"
+		}
+		fmt.Fprintf(os.Stdout, outFmt, r.Revision, author, r.Created.Local().Format(time.RFC822))
+	}
+	return nil
+}
+
+func doShowDiff(id string, from int64) error {
+	diff, err := cli.DiffTaskRevisions(cli.TaskLink(id), &client.DiffTaskRevisionsOptions{From: from})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Changes from revision %d to revision %d:\n", diff.From, diff.To)
+	for _, c := range diff.Changes {
+		fmt.Println(c)
+	}
+	fmt.Print(diff.ScriptDiff)
+	return nil
+}
+
 func varListToStr(list []client.Var) (string, error) {
 	values := make([]string, len(list))
 	for i := range list {
@@ -1382,12 +2785,38 @@ func varListToStr(list []client.Var) (string, error) {
 
 // Show Template
 
+var (
+	showTemplateFlags = flag.NewFlagSet("show-template", flag.ExitOnError)
+	stTasks           = showTemplateFlags.Bool("tasks", false, "List the tasks created from the template and their vars instead of its details.")
+	stTick            = showTemplateFlags.String("tick", "", "Path to a TICKscript, preview the changes updating the template with it would make to the tasks created from it instead of showing its details.")
+	stType            = showTemplateFlags.String("type", "", "The template type (stream|batch) of the preview.")
+)
+
 func showTemplateUsage() {
-	var u = `Usage: kapacitor show-template [template ID]
+	var u = `Usage: kapacitor show-template [options] [template ID]
 
 	Show details about a specific template.
+
+For example:
+
+	Show the details of a template:
+
+		$ kapacitor show-template my_template
+
+	List the tasks created from a template and their vars:
+
+		$ kapacitor show-template -tasks my_template
+
+	Preview the changes an updated TICKscript would make to the pipelines of the tasks created from a template,
+	nothing is changed:
+
+		$ kapacitor show-template -tick path/to/TICKscript my_template
+
+Options:
+
 `
 	fmt.Fprintln(os.Stderr, u)
+	showTemplateFlags.PrintDefaults()
 }
 
 func doShowTemplate(args []string) error {
@@ -1396,6 +2825,12 @@ func doShowTemplate(args []string) error {
 		showTemplateUsage()
 		os.Exit(2)
 	}
+	if *stTasks {
+		return doShowTemplateTasks(args[0])
+	}
+	if *stTick != "" || *stType != "" {
+		return doPreviewTemplate(args[0])
+	}
 
 	t, err := cli.Template(cli.TemplateLink(args[0]), nil)
 	if err != nil {
@@ -1435,6 +2870,92 @@ func doShowTemplate(args []string) error {
 	return nil
 }
 
+func doShowTemplateTasks(id string) error {
+	tasks, err := cli.ListTemplateTasks(cli.TemplateLink(id))
+	if err != nil {
+		return err
+	}
+	maxID := 2 // len("ID")
+	for _, t := range tasks {
+		if l := len(t.ID); l > maxID {
+			maxID = l
+		}
+	}
+	outFmt := fmt.Sprintf("%%-%ds%%-10v%%-10v%%s\n", maxID+1)
+	fmt.Fprintf(os.Stdout, outFmt, "ID", "Status", "Executing", "Vars")
+	for _, t := range tasks {
+		vars := make([]string, 0, len(t.Vars))
+		for name := range t.Vars {
+			vars = append(vars, name)
+		}
+		sort.Strings(vars)
+		for i, name := range vars {
+			value := t.Vars[name].Value
+			if list, ok := value.([]client.Var); ok {
+				value, err = varListToStr(list)
+				if err != nil {
+					return errors.Wrapf(err, "invalid var %s of task %s", name, t.ID)
+				}
+			}
+			vars[i] = fmt.Sprintf("%s=%v", name, value)
+		}
+		fmt.Fprintf(os.Stdout, outFmt, t.ID, t.Status, t.Executing, strings.Join(vars, ", "))
+	}
+	return nil
+}
+
+func doPreviewTemplate(id string) error {
+	var script string
+	if *stTick != "" {
+		data, err := ioutil.ReadFile(*stTick)
+		if err != nil {
+			return err
+		}
+		script = string(data)
+	}
+
+	var ttype client.TaskType
+	switch *stType {
+	case "stream":
+		ttype = client.StreamTask
+	case "batch":
+		ttype = client.BatchTask
+	}
+
+	preview, err := cli.PreviewUpdateTemplate(cli.TemplateLink(id), client.UpdateTemplateOptions{
+		Type:       ttype,
+		TICKscript: script,
+	})
+	if err != nil {
+		return err
+	}
+	if preview.ScriptDiff == "" {
+		fmt.Println("TICKscript: unchanged")
+	} else {
+		fmt.Print("TICKscript:\n" + preview.ScriptDiff)
+	}
+	if len(preview.Tasks) == 0 {
+		fmt.Println("No tasks are created from the template.")
+		return nil
+	}
+	fmt.Println("Tasks:")
+	for _, t := range preview.Tasks {
+		switch {
+		case t.Error != "":
+			fmt.Printf("  %s (%v): invalid: %s\n", t.ID, t.Status, t.Error)
+		case t.Changed:
+			fmt.Printf("  %s (%v): changed\n", t.ID, t.Status)
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(t.Diff, "\n"), "\n") {
+				fmt.Print("    " + line)
+			}
+			fmt.Println()
+		default:
+			fmt.Printf("  %s (%v): unchanged\n", t.ID, t.Status)
+		}
+	}
+	return nil
+}
+
 // Show Handler
 
 func showTopicHandlerUsage() {
@@ -1540,9 +3061,9 @@ func doShowTopic(args []string) error {
 // List
 
 func listUsage() {
-	var u = `Usage: kapacitor list (tasks|templates|recordings|replays|topics|topic-handlers|service-tests) [ID or pattern]...
+	var u = `Usage: kapacitor list (tasks|templates|task-groups|recordings|replays|topics|topic-handlers|service-tests) [ID or pattern]...
 
-	List tasks, templates, recordings, replays, topics or handlers and their current state.
+	List tasks, templates, task groups, recordings, replays, topics or handlers and their current state.
 
 	If no ID or pattern is given then all items will be listed.
 
@@ -1576,7 +3097,7 @@ func (t TemplateList) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
 
 func doList(args []string) error {
 	if len(args) == 0 {
-		fmt.Fprintln(os.Stderr, "Must specify 'tasks', 'recordings', 'replays', 'topics', or 'topic-handlers'")
+		fmt.Fprintln(os.Stderr, "Must specify 'tasks', 'templates', 'task-groups', 'recordings', 'replays', 'topics', or 'topic-handlers'")
 		listUsage()
 		os.Exit(2)
 	}
@@ -1618,7 +3139,7 @@ func doList(args []string) error {
 				offset += limit
 			}
 		}
-		outFmt := fmt.Sprintf("%%-%ds%%-10v%%-10v%%-10v%%s\n", maxID+1)
+		outFmt := fmt.Sprintf("%%-%ds%%-10v%%-13v%%-10v%%s\n", maxID+1)
 		fmt.Fprintf(os.Stdout, outFmt, "ID", "Type", "Status", "Executing", "Databases and Retention Policies")
 		sort.Sort(allTasks)
 		for _, t := range allTasks {
@@ -1663,6 +3184,42 @@ func doList(args []string) error {
 			sort.Strings(vars)
 			fmt.Fprintf(os.Stdout, outFmt, t.ID, t.Type, strings.Join(vars, ","))
 		}
+	case "task-groups":
+		maxID := 2 // len("ID")
+		// The task groups are returned in sorted order already, no need to sort them here.
+		var allGroups []client.TaskGroup
+		for _, pattern := range patterns {
+			offset := 0
+			for {
+				groups, err := cli.ListTaskGroups(&client.ListTaskGroupsOptions{
+					Pattern: pattern,
+					Offset:  offset,
+					Limit:   limit,
+				})
+				if err != nil {
+					return err
+				}
+				allGroups = append(allGroups, groups...)
+				for _, g := range groups {
+					if l := len(g.ID); l > maxID {
+						maxID = l
+					}
+				}
+				if len(groups) != limit {
+					break
+				}
+				offset += limit
+			}
+		}
+		outFmt := fmt.Sprintf("%%-%ds%%s\n", maxID+1)
+		fmt.Fprintf(os.Stdout, outFmt, "ID", "Order")
+		for _, g := range allGroups {
+			order := strings.Join(g.Order, ",")
+			if g.Error != "" {
+				order = "error: " + g.Error
+			}
+			fmt.Fprintf(os.Stdout, outFmt, g.ID, order)
+		}
 	case "recordings":
 		maxID := 2 // len("ID")
 		// The recordings are returned in sorted order already, no need to sort them here.
@@ -1845,9 +3402,11 @@ func doList(args []string) error {
 
 // Delete
 func deleteUsage() {
-	var u = `Usage: kapacitor delete (tasks|templates|recordings|replays|topics|topic-handlers) [ID or pattern]...
+	var u = `Usage: kapacitor delete (tasks|templates|task-groups|recordings|replays|topics|topic-handlers) [ID or pattern]...
+
+	Delete a tasks, templates, task groups, recordings, replays, topics or handlers.
 
-	Delete a tasks, templates, recordings, replays, topics or handlers.
+	Deleting a task group does not change its tasks.
 
 	If a task is enabled it will be disabled and then deleted.
 
@@ -1930,6 +3489,27 @@ func doDelete(args []string) error {
 				}
 			}
 		}
+	case "task-groups":
+		for _, pattern := range args[1:] {
+			for {
+				groups, err := cli.ListTaskGroups(&client.ListTaskGroupsOptions{
+					Pattern: pattern,
+					Limit:   limit,
+				})
+				if err != nil {
+					return err
+				}
+				for _, group := range groups {
+					err := cli.DeleteTaskGroup(group.Link)
+					if err != nil {
+						return err
+					}
+				}
+				if len(groups) != limit {
+					break
+				}
+			}
+		}
 	case "recordings":
 		for _, pattern := range args[1:] {
 			for {
@@ -2011,6 +3591,73 @@ func doDelete(args []string) error {
 	return nil
 }
 
+// Audit
+var (
+	auditFlags = flag.NewFlagSet("audit", flag.ExitOnError)
+	aUser      = auditFlags.String("user", "", "Only show changes made by the user.")
+	aResource  = auditFlags.String("resource", "", "Only show changes of resources with the prefix or matching the glob pattern, i.e. /tasks/cpu_alert.")
+	aSince     = auditFlags.Duration("since", 0, "Only show changes made within the duration, i.e. 24h.")
+	aLimit     = auditFlags.Int("limit", 100, "Maximum number of changes to show, the most recent changes are shown.")
+)
+
+func init() {
+	auditFlags.Usage = auditUsage
+}
+
+func auditUsage() {
+	var u = `Usage: kapacitor audit [options]
+
+	Display the audit log of changes made through the API, oldest first.
+
+	The audit log must be enabled on the kapacitord server with the audit-enabled option of the [http] section.
+	Reading the audit log requires the read privilege on /api/audit.
+
+	Show the changes made to a task within the last day:
+
+		$ kapacitor audit -resource /tasks/cpu_alert -since 24h
+
+Options:
+`
+	fmt.Fprintln(os.Stderr, u)
+	auditFlags.PrintDefaults()
+}
+
+func doAudit(args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Unexpected arguments:", args)
+		auditUsage()
+		os.Exit(2)
+	}
+	opt := &client.AuditOptions{
+		User:     *aUser,
+		Resource: *aResource,
+		Limit:    *aLimit,
+	}
+	if *aSince > 0 {
+		opt.Since = time.Now().Add(-*aSince)
+	}
+	entries, err := cli.Audit(opt)
+	if err != nil {
+		return err
+	}
+	maxUser := 4 // len("User")
+	for _, e := range entries {
+		if l := len(e.User); l > maxUser {
+			maxUser = l
+		}
+	}
+	outFmt := fmt.Sprintf("%%-26s%%-%ds%%-7s%%-7s%%s\n", maxUser+1)
+	fmt.Fprintf(os.Stdout, outFmt, "Time", "User", "Method", "Status", "Resource")
+	for _, e := range entries {
+		resource := e.Resource
+		if len(e.Changes) > 0 {
+			resource += " (" + strings.Join(e.Changes, ", ") + ")"
+		}
+		fmt.Fprintf(os.Stdout, outFmt, e.Time.Local().Format(time.RFC3339), e.User, e.Method, strconv.Itoa(e.Status), resource)
+	}
+	return nil
+}
+
 // Level
 func levelUsage() {
 	var u = `Usage: kapacitor level (debug|info|warn|error)
@@ -1,6 +1,9 @@
 package kapacitor
 
 import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -13,43 +16,51 @@ import (
 type DerivativeNode struct {
 	node
 	d *pipeline.DerivativeNode
+
+	mu sync.RWMutex
+	// The previous point of each group of a stream.
+	previous map[models.GroupID]models.Point
 }
 
 // Create a new derivative node.
 func newDerivativeNode(et *ExecutingTask, n *pipeline.DerivativeNode, l *log.Logger) (*DerivativeNode, error) {
 	dn := &DerivativeNode{
-		node: node{Node: n, et: et, logger: l},
-		d:    n,
+		node:     node{Node: n, et: et, logger: l},
+		d:        n,
+		previous: make(map[models.GroupID]models.Point),
 	}
 	// Create stateful expressions
 	dn.node.runF = dn.runDerivative
 	return dn, nil
 }
 
-func (d *DerivativeNode) runDerivative([]byte) error {
+func (d *DerivativeNode) runDerivative(snapshot []byte) error {
 	switch d.Provides() {
 	case pipeline.StreamEdge:
-		var mu sync.RWMutex
-		previous := make(map[models.GroupID]models.Point)
+		if len(snapshot) > 0 {
+			if err := d.restore(snapshot); err != nil {
+				return err
+			}
+		}
 		valueF := func() int64 {
-			mu.RLock()
-			l := len(previous)
-			mu.RUnlock()
+			d.mu.RLock()
+			l := len(d.previous)
+			d.mu.RUnlock()
 			return int64(l)
 		}
 		d.statMap.Set(statCardinalityGauge, expvar.NewIntFuncGauge(valueF))
 
 		for p, ok := d.ins[0].NextPoint(); ok; p, ok = d.ins[0].NextPoint() {
 			d.timer.Start()
-			mu.RLock()
-			pr := previous[p.Group]
-			mu.RUnlock()
+			d.mu.RLock()
+			pr := d.previous[p.Group]
+			d.mu.RUnlock()
 
 			value, store, emit := d.derivative(pr.Fields, p.Fields, pr.Time, p.Time)
 			if store {
-				mu.Lock()
-				previous[p.Group] = p
-				mu.Unlock()
+				d.mu.Lock()
+				d.previous[p.Group] = p
+				d.mu.Unlock()
 			}
 			if emit {
 				fields := p.Fields.Copy()
@@ -98,6 +109,32 @@ func (d *DerivativeNode) runDerivative([]byte) error {
 	return nil
 }
 
+func (d *DerivativeNode) snapshot() ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if len(d.previous) == 0 {
+		// Nothing to snapshot
+		return nil, nil
+	}
+	// Encode with gob, which keeps the types of the field values unlike JSON.
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(d.previous); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *DerivativeNode) restore(data []byte) error {
+	previous := make(map[models.GroupID]models.Point)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&previous); err != nil {
+		return fmt.Errorf("failed to restore derivative state: %v", err)
+	}
+	d.mu.Lock()
+	d.previous = previous
+	d.mu.Unlock()
+	return nil
+}
+
 // derivative calculates the derivative between prev and cur.
 // Return is the resulting derivative, whether the current point should be
 // stored as previous, and whether the point result should be emitted.
@@ -31,13 +31,21 @@ type BatchCollector interface {
 	Close()
 }
 
+// EdgeTracer is notified of each point or batch collected by an edge between two nodes of a task.
+// It must be safe for concurrent use as the nodes of a task run concurrently.
+type EdgeTracer interface {
+	Trace(task, parent, child string, p models.PointInterface)
+}
+
 type Edge struct {
-	mu     sync.Mutex
-	closed bool
+	mu        sync.Mutex
+	closed    bool
+	abortOnce sync.Once
 
 	stream chan models.Point
 	batch  chan models.Batch
 
+	name       string
 	logger     *log.Logger
 	aborted    chan struct{}
 	statsKey   string
@@ -46,6 +54,15 @@ type Edge struct {
 	statMap    *expvar.Map
 	groupMu    sync.RWMutex
 	groupStats map[models.GroupID]*edgeStat
+
+	// trace is called with each collected point or batch if set.
+	trace func(p models.PointInterface)
+
+	// limiter enforces the limits of the task on the edge, nil if the task has no limits.
+	limiter *taskLimiter
+	// input is set for the edges the task receives its data from.
+	input   bool
+	dropped *expvar.Int
 }
 
 func newEdge(taskName, parentName, childName string, t pipeline.EdgeType, size int, logService LogService) *Edge {
@@ -68,8 +85,8 @@ func newEdge(taskName, parentName, childName string, t pipeline.EdgeType, size i
 		aborted:    make(chan struct{}),
 		groupStats: make(map[models.GroupID]*edgeStat),
 	}
-	name := fmt.Sprintf("%s|%s->%s", taskName, parentName, childName)
-	e.logger = logService.NewLogger(fmt.Sprintf("[edge:%s] ", name), log.LstdFlags)
+	e.name = fmt.Sprintf("%s|%s->%s", taskName, parentName, childName)
+	e.logger = logService.NewLogger(fmt.Sprintf("[edge:%s] ", e.name), log.LstdFlags)
 	switch t {
 	case pipeline.StreamEdge:
 		e.stream = make(chan models.Point, size)
@@ -136,13 +153,77 @@ func (e *Edge) Close() {
 
 // Abort all next and collect calls.
 // Items in flight may or may not be processed.
+// Can be called multiple times.
 func (e *Edge) Abort() {
-	close(e.aborted)
-	e.logger.Printf(
-		"I! aborting c: %d e: %d\n",
-		e.collected.IntValue(),
-		e.emitted.IntValue(),
-	)
+	e.abortOnce.Do(func() {
+		close(e.aborted)
+		e.logger.Printf(
+			"I! aborting c: %d e: %d\n",
+			e.collected.IntValue(),
+			e.emitted.IntValue(),
+		)
+	})
+}
+
+// limit enforces the limits of the task on the edge.
+// The incoming data of the task is dropped on its input edges while the task is paused,
+// the other edges enforce the MaxEdgeQueue limit.
+func (e *Edge) limit(l *taskLimiter, input bool) {
+	e.limiter = l
+	e.input = input
+	e.dropped = &expvar.Int{}
+	e.statMap.Set(statDropped, e.dropped)
+}
+
+// admit enforces the limits of the task before a point or batch is queued on the edge.
+// It returns false if the point or batch must be dropped.
+func (e *Edge) admit() bool {
+	if e.input {
+		if e.limiter.paused() {
+			e.dropped.Add(1)
+			return false
+		}
+		return true
+	}
+	if e.limiter.limits.MaxEdgeQueue <= 0 || e.queued() < e.limiter.limits.MaxEdgeQueue {
+		return true
+	}
+	switch e.limiter.limits.Action {
+	case LimitDropOldest:
+		// Make room for the new item, there is a single parent so the queue cannot fill up again.
+		if e.dropOldest() {
+			e.dropped.Add(1)
+		}
+	case LimitStop:
+		e.limiter.quarantine(fmt.Sprintf("queue of edge %s reached %d", e.name, e.limiter.limits.MaxEdgeQueue))
+	}
+	// Otherwise the collect call blocks until the child makes room in the queue, pausing the parent node.
+	return true
+}
+
+func (e *Edge) queued() int {
+	if e.stream != nil {
+		return len(e.stream)
+	}
+	return len(e.batch)
+}
+
+// dropOldest discards the oldest queued point or batch, it returns false if the queue was already empty.
+func (e *Edge) dropOldest() bool {
+	if e.stream != nil {
+		select {
+		case _, ok := <-e.stream:
+			return ok
+		default:
+			return false
+		}
+	}
+	select {
+	case _, ok := <-e.batch:
+		return ok
+	default:
+		return false
+	}
 }
 
 func (e *Edge) Next() (p models.PointInterface, ok bool) {
@@ -179,6 +260,12 @@ func (e *Edge) NextBatch() (b models.Batch, ok bool) {
 func (e *Edge) CollectPoint(p models.Point) error {
 	e.collected.Add(1)
 	e.incCollected(p.Group, p.Tags, p.Dimensions, 1)
+	if e.trace != nil {
+		e.trace(p)
+	}
+	if e.limiter != nil && !e.admit() {
+		return nil
+	}
 	select {
 	case <-e.aborted:
 		return ErrAborted
@@ -190,6 +277,12 @@ func (e *Edge) CollectPoint(p models.Point) error {
 func (e *Edge) CollectBatch(b models.Batch) error {
 	e.collected.Add(1)
 	e.incCollected(b.Group, b.Tags, b.PointDimensions(), int64(len(b.Points)))
+	if e.trace != nil {
+		e.trace(b)
+	}
+	if e.limiter != nil && !e.admit() {
+		return nil
+	}
 	select {
 	case <-e.aborted:
 		return ErrAborted
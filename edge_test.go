@@ -3,7 +3,9 @@ package kapacitor
 import (
 	"log"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
@@ -41,6 +43,49 @@ func BenchmarkCollectPoint(b *testing.B) {
 	})
 }
 
+func TestEdge_MaxEdgeQueueDropOldest(t *testing.T) {
+	e := newEdge("TMaxEdgeQueue", "parent", "child", pipeline.StreamEdge, defaultEdgeBufferSize, &logService{})
+	e.limit(&taskLimiter{limits: TaskLimits{MaxEdgeQueue: 3, Action: LimitDropOldest}}, false)
+	for i := 0; i < 5; i++ {
+		if err := e.CollectPoint(models.Point{Name: "point", Time: time.Unix(int64(i), 0)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, exp := e.dropped.IntValue(), int64(2); got != exp {
+		t.Errorf("unexpected dropped count: got %d exp %d", got, exp)
+	}
+	e.Close()
+	var got []int64
+	for p, ok := e.NextPoint(); ok; p, ok = e.NextPoint() {
+		got = append(got, p.Time.Unix())
+	}
+	if exp := []int64{2, 3, 4}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected points: got %v exp %v", got, exp)
+	}
+}
+
+func TestEdge_PausedInput(t *testing.T) {
+	e := newEdge("TPausedInput", "stream", "stream0", pipeline.StreamEdge, defaultEdgeBufferSize, &logService{})
+	l := &taskLimiter{limits: TaskLimits{MaxGroups: 1, Action: LimitPause}, pausedFlag: 1}
+	e.limit(l, true)
+	if err := e.CollectPoint(models.Point{Name: "point"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := e.queued(), 0; got != exp {
+		t.Errorf("unexpected queue length while paused: got %d exp %d", got, exp)
+	}
+	l.pausedFlag = 0
+	if err := e.CollectPoint(models.Point{Name: "point"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := e.queued(), 1; got != exp {
+		t.Errorf("unexpected queue length: got %d exp %d", got, exp)
+	}
+	if got, exp := e.dropped.IntValue(), int64(1); got != exp {
+		t.Errorf("unexpected dropped count: got %d exp %d", got, exp)
+	}
+}
+
 type logService struct{}
 
 func (l *logService) NewLogger(prefix string, flag int) *log.Logger {
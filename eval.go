@@ -1,9 +1,11 @@
 package kapacitor
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
@@ -23,7 +25,11 @@ type EvalNode struct {
 	scopePool          stateful.ScopePool
 	tags               map[string]bool
 
+	// expressionsByGroupMu guards the expressions, it is held for reading while evaluating them
+	// so their state is consistent in snapshots.
 	expressionsByGroupMu sync.RWMutex
+	// State of the expressions restored from a snapshot for groups not seen since.
+	restored map[models.GroupID][][]byte
 
 	evalErrors *expvar.Int
 }
@@ -45,7 +51,7 @@ func newEvalNode(et *ExecutingTask, n *pipeline.EvalNode, l *log.Logger) (*EvalN
 	expressions := make([]ast.Node, len(n.Lambdas))
 	for i, lambda := range n.Lambdas {
 		expressions[i] = lambda.Expression
-		statefulExpr, err := stateful.NewExpression(lambda.Expression)
+		statefulExpr, err := en.newExpression(lambda.Expression)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to compile %v expression: %v", i, err)
 		}
@@ -69,6 +75,11 @@ func newEvalNode(et *ExecutingTask, n *pipeline.EvalNode, l *log.Logger) (*EvalN
 }
 
 func (e *EvalNode) runEval(snapshot []byte) error {
+	if len(snapshot) > 0 {
+		if err := e.restore(snapshot); err != nil {
+			return err
+		}
+	}
 	valueF := func() int64 {
 		e.expressionsByGroupMu.RLock()
 		l := len(e.expressionsByGroup)
@@ -143,9 +154,21 @@ func (e *EvalNode) eval(now time.Time, group models.GroupID, fields models.Field
 			expressions[i] = exp.CopyReset()
 		}
 		e.expressionsByGroupMu.Lock()
+		if states, ok := e.restored[group]; ok {
+			for i, state := range states {
+				if i < len(expressions) && state != nil {
+					if err := expressions[i].Restore(state); err != nil {
+						e.logger.Printf("E! failed to restore state of group %s: %v", group, err)
+					}
+				}
+			}
+			delete(e.restored, group)
+		}
 		e.expressionsByGroup[group] = expressions
 		e.expressionsByGroupMu.Unlock()
 	}
+	e.expressionsByGroupMu.RLock()
+	defer e.expressionsByGroupMu.RUnlock()
 	for i, expr := range expressions {
 		err := fillScope(vars, e.refVarList[i], now, fields, tags)
 		if err != nil {
@@ -184,7 +207,7 @@ func (e *EvalNode) eval(now time.Time, group models.GroupID, fields models.Field
 					if err != nil {
 						return nil, nil, err
 					}
-					newFields[f] = v
+					setField(newFields, f, v)
 				} else if v, ok := fields[f]; ok {
 					// Try the raw fields next, since it may not have been a referenced var.
 					newFields[f] = v
@@ -202,7 +225,7 @@ func (e *EvalNode) eval(now time.Time, group models.GroupID, fields models.Field
 				if err != nil {
 					return nil, nil, err
 				}
-				newFields[f] = v
+				setField(newFields, f, v)
 			}
 		}
 	} else {
@@ -215,8 +238,79 @@ func (e *EvalNode) eval(now time.Time, group models.GroupID, fields models.Field
 			if err != nil {
 				return nil, nil, err
 			}
-			newFields[f] = v
+			setField(newFields, f, v)
 		}
 	}
 	return newFields, newTags, nil
 }
+
+// setField sets the result of an expression as a field.
+// Maps and lists are flattened into fields prefixed with the name of the result,
+// i.e. the result {"a": {"b": 1}} as "msg" is the field "msg.a.b"
+// and the result ["a", "b"] as "parts" are the fields "parts.0" and "parts.1".
+func setField(fields models.Fields, name string, v interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, e := range value {
+			setNestedField(fields, name+"."+k, e)
+		}
+	case []interface{}:
+		setNestedField(fields, name, value)
+	default:
+		fields[name] = v
+	}
+}
+
+// setNestedField sets a value nested within a map result.
+// List elements are named by their index and missing values are dropped.
+func setNestedField(fields models.Fields, name string, v interface{}) {
+	switch value := v.(type) {
+	case []interface{}:
+		for i, e := range value {
+			setNestedField(fields, name+"."+strconv.Itoa(i), e)
+		}
+	case *ast.Missing:
+	default:
+		setField(fields, name, v)
+	}
+}
+
+func (e *EvalNode) snapshot() ([]byte, error) {
+	e.expressionsByGroupMu.Lock()
+	defer e.expressionsByGroupMu.Unlock()
+	states := make(map[models.GroupID][][]byte, len(e.expressionsByGroup)+len(e.restored))
+	for group, state := range e.restored {
+		states[group] = state
+	}
+	for group, expressions := range e.expressionsByGroup {
+		exprStates := make([][]byte, len(expressions))
+		hasState := false
+		for i, expr := range expressions {
+			state, err := expr.Snapshot()
+			if err != nil {
+				return nil, err
+			}
+			exprStates[i] = state
+			hasState = hasState || state != nil
+		}
+		if hasState {
+			states[group] = exprStates
+		}
+	}
+	if len(states) == 0 {
+		// Nothing to snapshot
+		return nil, nil
+	}
+	return json.Marshal(states)
+}
+
+func (e *EvalNode) restore(data []byte) error {
+	restored := make(map[models.GroupID][][]byte)
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return fmt.Errorf("failed to restore expression state: %v", err)
+	}
+	e.expressionsByGroupMu.Lock()
+	e.restored = restored
+	e.expressionsByGroupMu.Unlock()
+	return nil
+}
@@ -1,6 +1,7 @@
 package kapacitor
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -60,3 +61,36 @@ func fillScope(vars *stateful.Scope, referenceVariables []string, now time.Time,
 
 	return nil
 }
+
+// snapshotExpressions encodes the state of the expressions of each group.
+// The state of restored groups that have not been seen since is kept as is.
+func snapshotExpressions(expressions map[models.GroupID]stateful.Expression, restored map[models.GroupID][]byte) ([]byte, error) {
+	states := make(map[models.GroupID][]byte, len(expressions)+len(restored))
+	for group, state := range restored {
+		states[group] = state
+	}
+	for group, expr := range expressions {
+		state, err := expr.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		if state != nil {
+			states[group] = state
+		}
+	}
+	if len(states) == 0 {
+		// Nothing to snapshot
+		return nil, nil
+	}
+	return json.Marshal(states)
+}
+
+// restoreExpressions decodes the state of the expressions of each group.
+// The state is restored once an expression for the group is created.
+func restoreExpressions(snapshot []byte) (map[models.GroupID][]byte, error) {
+	states := make(map[models.GroupID][]byte)
+	if err := json.Unmarshal(snapshot, &states); err != nil {
+		return nil, fmt.Errorf("failed to restore expression state: %v", err)
+	}
+	return states, nil
+}
@@ -0,0 +1,168 @@
+package kapacitor
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+type FillNode struct {
+	node
+	f *pipeline.FillNode
+}
+
+// fillGroup is the resampling state of a single group.
+type fillGroup struct {
+	hasPrevious bool
+	previous    models.BatchPoint
+	// The next grid time to emit.
+	next time.Time
+}
+
+// Create a new fill node.
+func newFillNode(et *ExecutingTask, n *pipeline.FillNode, l *log.Logger) (*FillNode, error) {
+	fn := &FillNode{
+		node: node{Node: n, et: et, logger: l},
+		f:    n,
+	}
+	fn.node.runF = fn.runFill
+	return fn, nil
+}
+
+func (n *FillNode) runFill([]byte) error {
+	switch n.Wants() {
+	case pipeline.StreamEdge:
+		var mu sync.RWMutex
+		groups := make(map[models.GroupID]*fillGroup)
+		valueF := func() int64 {
+			mu.RLock()
+			l := len(groups)
+			mu.RUnlock()
+			return int64(l)
+		}
+		n.statMap.Set(statCardinalityGauge, expvar.NewIntFuncGauge(valueF))
+
+		for p, ok := n.ins[0].NextPoint(); ok; p, ok = n.ins[0].NextPoint() {
+			n.timer.Start()
+			mu.RLock()
+			g := groups[p.Group]
+			mu.RUnlock()
+			if g == nil {
+				g = new(fillGroup)
+				mu.Lock()
+				groups[p.Group] = g
+				mu.Unlock()
+			}
+			points := n.resample(g, models.BatchPointFromPoint(p))
+			n.timer.Pause()
+			for _, bp := range points {
+				fp := p
+				fp.Time = bp.Time
+				fp.Fields = bp.Fields
+				fp.Tags = bp.Tags
+				for _, child := range n.outs {
+					err := child.CollectPoint(fp)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			n.timer.Resume()
+			n.timer.Stop()
+		}
+	case pipeline.BatchEdge:
+		for b, ok := n.ins[0].NextBatch(); ok; b, ok = n.ins[0].NextBatch() {
+			n.timer.Start()
+			g := new(fillGroup)
+			points := make([]models.BatchPoint, 0, len(b.Points))
+			for _, p := range b.Points {
+				points = append(points, n.resample(g, p)...)
+			}
+			b.Points = points
+			n.timer.Stop()
+			for _, child := range n.outs {
+				err := child.CollectBatch(b)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resample returns the grid points up to and including the time of p.
+func (n *FillNode) resample(g *fillGroup, p models.BatchPoint) []models.BatchPoint {
+	every := n.f.Every
+	if !g.hasPrevious {
+		g.next = p.Time.Truncate(every)
+		if g.next.Before(p.Time) {
+			g.next = g.next.Add(every)
+		}
+	} else if !p.Time.After(g.previous.Time) {
+		n.incrementErrorCount()
+		n.logger.Printf("E! point at %v is not after the previous point at %v, skipping point", p.Time, g.previous.Time)
+		return nil
+	}
+	if gap := int64((p.Time.Sub(g.next) + every - 1) / every); gap > n.f.Limit {
+		n.incrementErrorCount()
+		n.logger.Printf("E! gap between %v and %v needs %d points, more than the limit of %d, not filling gap", g.previous.Time, p.Time, gap, n.f.Limit)
+		// Continue the grid at the first grid time not before p.
+		g.next = p.Time.Truncate(every)
+		if g.next.Before(p.Time) {
+			g.next = g.next.Add(every)
+		}
+	}
+	var points []models.BatchPoint
+	for ; g.next.Before(p.Time); g.next = g.next.Add(every) {
+		points = append(points, n.synthetic(g.previous, p, g.next))
+	}
+	if g.next.Equal(p.Time) {
+		points = append(points, p)
+		g.next = g.next.Add(every)
+	}
+	g.previous = p
+	g.hasPrevious = true
+	return points
+}
+
+// synthetic creates a point at time t between prev and next.
+func (n *FillNode) synthetic(prev, next models.BatchPoint, t time.Time) models.BatchPoint {
+	fields := make(models.Fields, len(prev.Fields))
+	for k, v := range prev.Fields {
+		switch n.f.FillMethod {
+		case pipeline.FillLinear:
+			fields[k] = v
+			f0, ok := numToFloat(v)
+			if !ok {
+				continue
+			}
+			f1, ok := numToFloat(next.Fields[k])
+			if !ok {
+				continue
+			}
+			ratio := float64(t.Sub(prev.Time)) / float64(next.Time.Sub(prev.Time))
+			fields[k] = f0 + (f1-f0)*ratio
+		case pipeline.FillPrevious:
+			fields[k] = v
+		case pipeline.FillNull:
+			fields[k] = nil
+		case pipeline.FillValue:
+			fields[k] = n.f.FillValue
+		}
+	}
+	tags := prev.Tags
+	if n.f.Tag != "" {
+		tags = prev.Tags.Copy()
+		tags[n.f.Tag] = "true"
+	}
+	return models.BatchPoint{
+		Time:   t,
+		Fields: fields,
+		Tags:   tags,
+	}
+}
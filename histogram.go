@@ -0,0 +1,97 @@
+package kapacitor
+
+import (
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+type HistogramNode struct {
+	node
+	h *pipeline.HistogramNode
+
+	// Precomputed field names for each bucket
+	bucketFields []string
+	infField     string
+}
+
+// Create a new histogram node.
+func newHistogramNode(et *ExecutingTask, n *pipeline.HistogramNode, l *log.Logger) (*HistogramNode, error) {
+	hn := &HistogramNode{
+		node:         node{Node: n, et: et, logger: l},
+		h:            n,
+		bucketFields: make([]string, len(n.Bounds)),
+		infField:     n.Prefix + "inf",
+	}
+	for i, b := range n.Bounds {
+		hn.bucketFields[i] = n.Prefix + strconv.FormatFloat(b, 'f', -1, 64)
+	}
+	hn.node.runF = hn.runHistogram
+	return hn, nil
+}
+
+func (n *HistogramNode) runHistogram([]byte) error {
+	for b, ok := n.ins[0].NextBatch(); ok; b, ok = n.ins[0].NextBatch() {
+		n.timer.Start()
+		p := models.Point{
+			Name:       b.Name,
+			Time:       b.TMax,
+			Group:      b.Group,
+			Dimensions: b.PointDimensions(),
+			Tags:       b.Tags,
+			Fields:     n.histogram(b.Points),
+		}
+		n.timer.Pause()
+		for _, child := range n.outs {
+			err := child.CollectPoint(p)
+			if err != nil {
+				return err
+			}
+		}
+		n.timer.Resume()
+		n.timer.Stop()
+	}
+	return nil
+}
+
+// histogram computes the cumulative bucket counts, sum and count of the field values of the points.
+// Points without a numeric value for the field are skipped.
+func (n *HistogramNode) histogram(points []models.BatchPoint) models.Fields {
+	counts := make([]int64, len(n.h.Bounds))
+	var sum float64
+	var count int64
+	for _, p := range points {
+		v, ok := p.Fields[n.h.Field]
+		if !ok {
+			continue
+		}
+		f, ok := numToFloat(v)
+		if !ok || math.IsNaN(f) {
+			n.incrementErrorCount()
+			n.logger.Printf("E! cannot compute histogram of type %T", v)
+			continue
+		}
+		// Bounds are sorted, find the first bucket containing the value.
+		for i, bound := range n.h.Bounds {
+			if f <= bound {
+				counts[i]++
+				break
+			}
+		}
+		sum += f
+		count++
+	}
+	fields := make(models.Fields, len(counts)+3)
+	var cumulative int64
+	for i, c := range counts {
+		cumulative += c
+		fields[n.bucketFields[i]] = cumulative
+	}
+	fields[n.infField] = count
+	fields["sum"] = sum
+	fields["count"] = count
+	return fields
+}
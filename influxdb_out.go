@@ -130,8 +130,13 @@ func (i *InfluxDBOutNode) write(db, rp string, batch models.Batch) error {
 		name = batch.Name
 	}
 
-	points := make([]influxdb.Point, len(batch.Points))
-	for j, p := range batch.Points {
+	points := make([]influxdb.Point, 0, len(batch.Points))
+	for _, p := range batch.Points {
+		// Null fields, i.e. from fill('null'), cannot be written to InfluxDB.
+		fields := nonNullFields(p.Fields)
+		if len(fields) == 0 {
+			continue
+		}
 		var tags map[string]string
 		if len(i.i.Tags) > 0 {
 			tags = make(map[string]string, len(p.Tags)+len(i.i.Tags))
@@ -144,12 +149,15 @@ func (i *InfluxDBOutNode) write(db, rp string, batch models.Batch) error {
 		} else {
 			tags = p.Tags
 		}
-		points[j] = influxdb.Point{
+		points = append(points, influxdb.Point{
 			Name:   name,
 			Tags:   tags,
-			Fields: p.Fields,
+			Fields: fields,
 			Time:   p.Time,
-		}
+		})
+	}
+	if len(points) == 0 {
+		return nil
 	}
 	bpc := influxdb.BatchPointsConfig{
 		Database:         db,
@@ -161,6 +169,24 @@ func (i *InfluxDBOutNode) write(db, rp string, batch models.Batch) error {
 	return nil
 }
 
+// nonNullFields returns the fields without the null fields.
+// The fields are returned as is if none are null.
+func nonNullFields(fields models.Fields) models.Fields {
+	for _, v := range fields {
+		if v != nil {
+			continue
+		}
+		nonNull := make(models.Fields, len(fields))
+		for k, v := range fields {
+			if v != nil {
+				nonNull[k] = v
+			}
+		}
+		return nonNull
+	}
+	return fields
+}
+
 type writeBuffer struct {
 	size          int
 	flushInterval time.Duration
@@ -0,0 +1,33 @@
+package kapacitor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/kapacitor/models"
+)
+
+func TestNonNullFields(t *testing.T) {
+	testCases := []struct {
+		fields models.Fields
+		exp    models.Fields
+	}{
+		{
+			fields: models.Fields{"a": 1.0, "b": "x"},
+			exp:    models.Fields{"a": 1.0, "b": "x"},
+		},
+		{
+			fields: models.Fields{"a": 1.0, "b": nil},
+			exp:    models.Fields{"a": 1.0},
+		},
+		{
+			fields: models.Fields{"a": nil, "b": nil},
+			exp:    models.Fields{},
+		},
+	}
+	for _, tc := range testCases {
+		if got := nonNullFields(tc.fields); !reflect.DeepEqual(got, tc.exp) {
+			t.Errorf("unexpected fields for %v got: %v exp: %v", tc.fields, got, tc.exp)
+		}
+	}
+}
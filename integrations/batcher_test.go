@@ -478,6 +478,115 @@ batch
 	testBatcherWithOutput(t, "TestBatch_CumulativeSum", script, 31*time.Second, er, false)
 }
 
+func TestBatch_Histogram(t *testing.T) {
+
+	var script = `
+batch
+	|query('''
+		SELECT latency
+		FROM "telegraf"."default".requests
+''')
+		.period(10s)
+		.every(10s)
+		.groupBy('host')
+	|histogram('latency')
+		.exponentialBuckets(0.5, 2.0, 4)
+	|httpOut('TestBatch_Histogram')
+`
+
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "requests",
+				Tags:    map[string]string{"host": "serverA"},
+				Columns: []string{"time", "count", "le_0.5", "le_1", "le_2", "le_4", "le_inf", "sum"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 8, 0, time.UTC),
+						5.0,
+						2.0,
+						3.0,
+						3.0,
+						4.0,
+						5.0,
+						16.05,
+					},
+				},
+			},
+			{
+				Name:    "requests",
+				Tags:    map[string]string{"host": "serverB"},
+				Columns: []string{"time", "count", "le_0.5", "le_1", "le_2", "le_4", "le_inf", "sum"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 8, 0, time.UTC),
+						5.0,
+						1.0,
+						2.0,
+						3.0,
+						4.0,
+						5.0,
+						8.7,
+					},
+				},
+			},
+		},
+	}
+
+	testBatcherWithOutput(t, "TestBatch_Histogram", script, 21*time.Second, er, true)
+}
+
+func TestBatch_FillValue(t *testing.T) {
+
+	var script = `
+batch
+	|query('''
+		SELECT value
+		FROM "telegraf"."default".cpu
+''')
+		.period(10s)
+		.every(10s)
+	|fill()
+		.every(2s)
+		.method('value', 0.0)
+	|httpOut('TestBatch_FillValue')
+`
+
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    nil,
+				Columns: []string{"time", "value"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC),
+						1.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 2, 0, time.UTC),
+						0.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 4, 0, time.UTC),
+						3.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 6, 0, time.UTC),
+						0.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 8, 0, time.UTC),
+						5.0,
+					},
+				},
+			},
+		},
+	}
+
+	testBatcherWithOutput(t, "TestBatch_FillValue", script, 21*time.Second, er, false)
+}
+
 func TestBatch_SimpleMR(t *testing.T) {
 
 	var script = `
@@ -340,6 +340,100 @@ stream
 	testStreamerWithOutput(t, "TestStream_DerivativeNN", script, 15*time.Second, er, false, nil)
 }
 
+func TestStream_RateCounter(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('net')
+	|window()
+		.period(10s)
+		.every(10s)
+	|rate('bytes')
+		.counter()
+	|httpOut('TestStream_RateCounter')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "net",
+				Tags:    nil,
+				Columns: []string{"time", "increase", "rate"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 10, 0, time.UTC),
+						85.0,
+						9.444444444444445,
+					},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_RateCounter", script, 13*time.Second, er, false, nil)
+}
+
+func TestStream_RateWrap(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('net')
+	|rate('bytes')
+		.wrap(256)
+		.unit(1m)
+		.as('per_minute')
+	|httpOut('TestStream_RateWrap')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "net",
+				Tags:    nil,
+				Columns: []string{"time", "bytes", "increase", "per_minute"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 6, 0, time.UTC),
+						10.0,
+						6.0,
+						180.0,
+					},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_RateWrap", script, 13*time.Second, er, false, nil)
+}
+
+func TestStream_RateWrapReset(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('net')
+	|rate('bytes')
+		.wrap(4294967296)
+	|httpOut('TestStream_RateWrapReset')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "net",
+				Tags:    nil,
+				Columns: []string{"time", "bytes", "increase", "rate"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 2, 0, time.UTC),
+						10.0,
+						10.0,
+						5.0,
+					},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_RateWrapReset", script, 13*time.Second, er, false, nil)
+}
+
 func TestStream_HoltWinters(t *testing.T) {
 	var script = `
 stream
@@ -653,6 +747,169 @@ stream
 	testStreamerWithOutput(t, "TestStream_CumulativeSum", script, 13*time.Second, er, false, nil)
 }
 
+func TestStream_Histogram(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('requests')
+	|window()
+		.period(10s)
+		.every(10s)
+	|histogram('latency')
+		.buckets(0.1, 0.5, 1, 5)
+	|httpOut('TestStream_Histogram')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "requests",
+				Tags:    nil,
+				Columns: []string{"time", "count", "le_0.1", "le_0.5", "le_1", "le_5", "le_inf", "sum"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 10, 0, time.UTC),
+						10.0,
+						2.0,
+						4.0,
+						6.0,
+						9.0,
+						10.0,
+						15.73,
+					},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_Histogram", script, 13*time.Second, er, false, nil)
+}
+
+func TestStream_Anomaly(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('requests')
+	|anomaly('count')
+		.season('hour')
+	|httpOut('TestStream_Anomaly')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "requests",
+				Tags:    nil,
+				Columns: []string{"time", "anomalyScore", "count", "expected", "lower", "upper"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 2, 1, 0, 0, 0, time.UTC),
+						108.45429144728823,
+						300.0,
+						101.2,
+						95.700909166053,
+						106.69909083394701,
+					},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_Anomaly", script, 25*time.Hour, er, false, nil)
+}
+
+func TestStream_FillLinear(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('cpu')
+	|fill()
+		.every(10s)
+		.method('linear')
+		.tag('synthetic')
+	|window()
+		.period(60s)
+		.every(60s)
+	|httpOut('TestStream_FillLinear')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    nil,
+				Columns: []string{"time", "value"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC),
+						0.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 10, 0, time.UTC),
+						10.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 20, 0, time.UTC),
+						20.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 30, 0, time.UTC),
+						30.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 40, 0, time.UTC),
+						40.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 50, 0, time.UTC),
+						50.0,
+					},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_FillLinear", script, 65*time.Second, er, false, nil)
+}
+
+func TestStream_FillLimit(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('cpu')
+	|fill()
+		.every(10s)
+		.method('linear')
+		.limit(2)
+	|window()
+		.period(60s)
+		.every(60s)
+	|httpOut('TestStream_FillLimit')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    nil,
+				Columns: []string{"time", "value"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC),
+						0.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 40, 0, time.UTC),
+						40.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 50, 0, time.UTC),
+						50.0,
+					},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_FillLimit", script, 65*time.Second, er, false, nil)
+}
+
 func TestStream_WindowMissing(t *testing.T) {
 
 	var script = `
@@ -2087,6 +2344,36 @@ stream
 	testStreamerWithOutput(t, "TestStream_Eval_Missing", script, 2*time.Hour, er, false, nil)
 }
 
+func TestStream_Eval_JSON(t *testing.T) {
+	var script = `
+stream
+	|from()
+		.measurement('logs')
+	|eval(lambda: jsonParse("msg"), lambda: mapGet("payload", 'level'))
+		.as('payload', 'level')
+	|httpOut('TestStream_Eval_JSON')
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "logs",
+				Tags:    nil,
+				Columns: []string{"time", "level", "payload.level", "payload.request.codes.0", "payload.request.codes.1", "payload.request.latency"},
+				Values: [][]interface{}{[]interface{}{
+					time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC),
+					"error",
+					"error",
+					200.0,
+					500.0,
+					1.5,
+				}},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_Eval_JSON", script, 2*time.Second, er, false, nil)
+}
+
 func TestStream_Default(t *testing.T) {
 	var script = `
 stream
@@ -4434,6 +4721,76 @@ stream
 	testStreamerWithOutput(t, "TestStream_JoinOn_Fill", script, 13*time.Second, er, true, nil)
 }
 
+func TestStream_Switch(t *testing.T) {
+	var script = `
+var sw = stream
+	|from()
+		.measurement('cpu')
+	|switch()
+		.case('critical', lambda: "usage_idle" < 10)
+		.case('warning', lambda: "usage_idle" < 30)
+		.default('ok')
+
+sw.route('critical')
+	|window()
+		.period(10s)
+		.every(10s)
+	|httpOut('TestStream_Switch')
+
+sw.route('ok')
+	|log()
+`
+	er := models.Result{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    nil,
+				Columns: []string{"time", "usage_idle"},
+				Values: [][]interface{}{
+					{
+						time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC),
+						5.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 3, 0, time.UTC),
+						8.0,
+					},
+					{
+						time.Date(1971, 1, 1, 0, 0, 6, 0, time.UTC),
+						3.0,
+					},
+				},
+			},
+		},
+	}
+
+	testStreamerWithOutput(t, "TestStream_Switch", script, 13*time.Second, er, false, nil)
+
+	clock, et, replayErr, tm := testStreamer(t, "TestStream_Switch", script, nil)
+	defer tm.Close()
+	if err := fastForwardTask(clock, et, replayErr, tm, 13*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := et.ExecutionStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	switchStats := stats.NodeStats["switch2"]
+	expRoutes := map[string]int64{
+		"route_critical": 4,
+		"route_warning":  3,
+		"route_ok":       4,
+	}
+	for k, exp := range expRoutes {
+		if got := switchStats[k]; got != exp {
+			t.Errorf("unexpected %s count: got %v exp %d", k, got, exp)
+		}
+	}
+	if got, exp := switchStats["emitted"], int64(8); got != exp {
+		t.Errorf("unexpected emitted count: got %v exp %d", got, exp)
+	}
+}
+
 func TestStream_Union(t *testing.T) {
 
 	var script = `
@@ -1,9 +1,12 @@
 package kapacitor
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb/influxql"
@@ -30,6 +33,11 @@ type JoinNode struct {
 	lowMarks map[srcGroup]time.Time
 
 	groupsMu sync.RWMutex
+	// Groups in the order they were created, the oldest group is dropped first.
+	groupOrder []models.GroupID
+
+	// Number of points buffered by the caches and the sets of all groups.
+	buffered int64
 
 	reported    map[int]bool
 	allReported bool
@@ -44,6 +52,7 @@ func newJoinNode(et *ExecutingTask, n *pipeline.JoinNode, l *log.Logger) (*JoinN
 		specificGroupsBuffer: make(map[models.GroupID][]srcPoint),
 		lowMarks:             make(map[srcGroup]time.Time),
 		reported:             make(map[int]bool),
+		groups:               make(map[models.GroupID]*group),
 	}
 	// Set fill
 	switch fill := n.Fill.(type) {
@@ -66,8 +75,12 @@ func newJoinNode(et *ExecutingTask, n *pipeline.JoinNode, l *log.Logger) (*JoinN
 	return jn, nil
 }
 
-func (j *JoinNode) runJoin([]byte) error {
-	j.groups = make(map[models.GroupID]*group)
+func (j *JoinNode) runJoin(snapshot []byte) error {
+	if len(snapshot) > 0 {
+		if err := j.restore(snapshot); err != nil {
+			return err
+		}
+	}
 	valueF := func() int64 {
 		j.groupsMu.RLock()
 		l := len(j.groups)
@@ -79,11 +92,19 @@ func (j *JoinNode) runJoin([]byte) error {
 	groupErrs := make(chan error, 1)
 	done := make(chan struct{}, len(j.ins))
 
+	// Start the groups restored from the snapshot.
+	j.groupsMu.RLock()
+	for _, group := range j.groups {
+		j.runningGroups.Add(1)
+		j.startGroup(group, groupErrs)
+	}
+	j.groupsMu.RUnlock()
+
 	for i := range j.ins {
 		// Start gorouting per parent so we do not deadlock.
 		// This way independent of the order that parents receive data
 		// we can handle it.
-		t := j.et.tm.TimingService.NewTimer(j.statMap.Get(statAverageExecTime).(timer.Setter))
+		t := j.et.limiter.timer(j.et.tm.TimingService.NewTimer(j.statMap.Get(statAverageExecTime).(timer.Setter)))
 		go func(i int, t timer.Timer) {
 			defer func() {
 				done <- struct{}{}
@@ -126,8 +147,11 @@ func (j *JoinNode) runJoin([]byte) error {
 	j.runningGroups.Wait()
 	j.groupsMu.RLock()
 	for _, group := range j.groups {
+		group.mu.Lock()
 		err := group.emitAll()
+		group.mu.Unlock()
 		if err != nil {
+			j.groupsMu.RUnlock()
 			return err
 		}
 	}
@@ -160,6 +184,12 @@ func (j *JoinNode) matchPoints(p srcPoint, groupErrs chan<- error) {
 			TagNames: j.j.Dimensions,
 		},
 	)
+	cached := len(j.matchGroupsBuffer[groupId]) + len(j.specificGroupsBuffer[groupId])
+	defer func() {
+		atomic.AddInt64(&j.buffered, int64(len(j.matchGroupsBuffer[groupId])+len(j.specificGroupsBuffer[groupId])-cached))
+		j.limitCache(groupId)
+	}()
+
 	// Update current srcGroup lowMark
 	srcG := srcGroup{src: p.src, groupId: groupId}
 	j.lowMarks[srcG] = t
@@ -257,6 +287,29 @@ func (j *JoinNode) matchPoints(p srcPoint, groupErrs chan<- error) {
 	}
 }
 
+// limitCache drops the oldest cached points of the group while the node exceeds the MaxBufferedPoints limit
+// of the task, if the limited buffers of the task drop their oldest data.
+func (j *JoinNode) limitCache(groupId models.GroupID) {
+	max := j.et.Task.Limits.MaxBufferedPoints
+	if max <= 0 || !j.et.limiter.dropOldest() {
+		return
+	}
+	for atomic.LoadInt64(&j.buffered) > max {
+		matches := j.matchGroupsBuffer[groupId]
+		specifics := j.specificGroupsBuffer[groupId]
+		switch {
+		case len(matches) > 0 && (len(specifics) == 0 || !specifics[0].p.PointTime().Before(matches[0].p.PointTime())):
+			j.matchGroupsBuffer[groupId] = matches[1:]
+		case len(specifics) > 0:
+			j.specificGroupsBuffer[groupId] = specifics[1:]
+		default:
+			// The points are buffered by the sets of the groups.
+			return
+		}
+		atomic.AddInt64(&j.buffered, -1)
+	}
+}
+
 // Add the specific tags from the specific point to the matched point
 // and then send both on to the group.
 func (j *JoinNode) sendMatchPoint(specific, matched srcPoint, groupErrs chan<- error) {
@@ -288,24 +341,198 @@ func (j *JoinNode) getGroup(p models.PointInterface, groupErrs chan<- error) *gr
 	if group == nil {
 		group = newGroup(len(j.ins), j)
 		j.groupsMu.Lock()
+		if max := j.et.Task.Limits.MaxGroups; max > 0 && j.et.limiter.dropOldest() && int64(len(j.groups)) >= max {
+			// Make room for the new group.
+			// The callers hold the lock of the node, no point is being sent to the dropped group.
+			oldest := j.groups[j.groupOrder[0]]
+			delete(j.groups, j.groupOrder[0])
+			j.groupOrder = j.groupOrder[1:]
+			oldest.dropped = true
+			close(oldest.points)
+		}
 		j.groups[p.PointGroup()] = group
+		j.groupOrder = append(j.groupOrder, p.PointGroup())
 		j.runningGroups.Add(1)
 		j.groupsMu.Unlock()
-		go func() {
-			err := group.run()
-			if err != nil {
-				j.incrementErrorCount()
-				j.logger.Println("E! join group error:", err)
-				select {
-				case groupErrs <- err:
-				default:
-				}
-			}
-		}()
+		j.startGroup(group, groupErrs)
 	}
 	return group
 }
 
+// startGroup runs the group until its points channel is closed.
+// The caller must have added the group to the running groups.
+func (j *JoinNode) startGroup(group *group, groupErrs chan<- error) {
+	go func() {
+		err := group.run()
+		if err != nil {
+			j.incrementErrorCount()
+			j.logger.Println("E! join group error:", err)
+			select {
+			case groupErrs <- err:
+			default:
+			}
+		}
+	}()
+}
+
+func (j *JoinNode) bufferedCount() int64 {
+	return atomic.LoadInt64(&j.buffered)
+}
+
+// joinSnapshot is the state of a join node.
+type joinSnapshot struct {
+	Groups         []joinGroupSnapshot
+	MatchPoints    map[models.GroupID][]joinPointSnapshot
+	SpecificPoints map[models.GroupID][]joinPointSnapshot
+	LowMarks       []joinLowMarkSnapshot
+	Reported       []int
+}
+
+// joinGroupSnapshot is the state of a group, the sets of points waiting to be joined.
+type joinGroupSnapshot struct {
+	Group models.GroupID
+	Head  []time.Time
+	Sets  []joinSetSnapshot
+}
+
+type joinSetSnapshot struct {
+	Time   time.Time
+	Values []joinPointSnapshot
+}
+
+// joinPointSnapshot is a point or a batch and the index of the parent it came from.
+type joinPointSnapshot struct {
+	Src   int
+	Point *models.Point
+	Batch *models.Batch
+}
+
+type joinLowMarkSnapshot struct {
+	Src   int
+	Group models.GroupID
+	Time  time.Time
+}
+
+func newJoinPointSnapshot(src int, p models.PointInterface) joinPointSnapshot {
+	s := joinPointSnapshot{Src: src}
+	switch v := p.(type) {
+	case models.Point:
+		s.Point = &v
+	case models.Batch:
+		s.Batch = &v
+	}
+	return s
+}
+
+func (s joinPointSnapshot) point() models.PointInterface {
+	if s.Batch != nil {
+		return *s.Batch
+	}
+	if s.Point != nil {
+		return *s.Point
+	}
+	return nil
+}
+
+func snapshotSrcPoints(points []srcPoint) []joinPointSnapshot {
+	snapshots := make([]joinPointSnapshot, len(points))
+	for i, p := range points {
+		snapshots[i] = newJoinPointSnapshot(p.src, p.p)
+	}
+	return snapshots
+}
+
+// restoreSrcPoints returns the points of the snapshots, dropping the points of parents the node does not have.
+func (j *JoinNode) restoreSrcPoints(snapshots []joinPointSnapshot) []srcPoint {
+	points := make([]srcPoint, 0, len(snapshots))
+	for _, s := range snapshots {
+		if p := s.point(); p != nil && s.Src < len(j.ins) {
+			points = append(points, srcPoint{src: s.Src, p: p})
+		}
+	}
+	return points
+}
+
+func (j *JoinNode) snapshot() ([]byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.groupsMu.RLock()
+	defer j.groupsMu.RUnlock()
+
+	s := joinSnapshot{
+		MatchPoints:    make(map[models.GroupID][]joinPointSnapshot),
+		SpecificPoints: make(map[models.GroupID][]joinPointSnapshot),
+	}
+	for _, id := range j.groupOrder {
+		s.Groups = append(s.Groups, j.groups[id].snapshot(id))
+	}
+	for id, points := range j.matchGroupsBuffer {
+		if len(points) > 0 {
+			s.MatchPoints[id] = snapshotSrcPoints(points)
+		}
+	}
+	for id, points := range j.specificGroupsBuffer {
+		if len(points) > 0 {
+			s.SpecificPoints[id] = snapshotSrcPoints(points)
+		}
+	}
+	for sg, t := range j.lowMarks {
+		s.LowMarks = append(s.LowMarks, joinLowMarkSnapshot{Src: sg.src, Group: sg.groupId, Time: t})
+	}
+	for src := range j.reported {
+		s.Reported = append(s.Reported, src)
+	}
+	if len(s.Groups) == 0 && len(s.MatchPoints) == 0 && len(s.SpecificPoints) == 0 && len(s.LowMarks) == 0 {
+		// Nothing to snapshot
+		return nil, nil
+	}
+	// Encode with gob, which keeps the types of the field values unlike JSON.
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// restore the state of the node, the restored groups are started when the node runs.
+func (j *JoinNode) restore(data []byte) error {
+	var s joinSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return fmt.Errorf("failed to restore join state: %v", err)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.groupsMu.Lock()
+	defer j.groupsMu.Unlock()
+
+	var buffered int64
+	for _, gs := range s.Groups {
+		g := newGroup(len(j.ins), j)
+		buffered += g.restore(gs)
+		j.groups[gs.Group] = g
+		j.groupOrder = append(j.groupOrder, gs.Group)
+	}
+	for id, points := range s.MatchPoints {
+		j.matchGroupsBuffer[id] = j.restoreSrcPoints(points)
+		buffered += int64(len(j.matchGroupsBuffer[id]))
+	}
+	for id, points := range s.SpecificPoints {
+		j.specificGroupsBuffer[id] = j.restoreSrcPoints(points)
+		buffered += int64(len(j.specificGroupsBuffer[id]))
+	}
+	for _, lm := range s.LowMarks {
+		j.lowMarks[srcGroup{src: lm.Src, groupId: lm.Group}] = lm.Time
+	}
+	for _, src := range s.Reported {
+		if src < len(j.ins) {
+			j.reported[src] = true
+		}
+	}
+	j.allReported = len(j.reported) == len(j.ins)
+	atomic.AddInt64(&j.buffered, buffered)
+	return nil
+}
+
 // A groupId and its parent
 type srcGroup struct {
 	src     int
@@ -320,11 +547,15 @@ type srcPoint struct {
 
 // handles emitting joined sets once enough data has arrived from parents.
 type group struct {
+	// mu guards the sets, it is held while collecting points so the sets are consistent in snapshots.
+	mu         sync.Mutex
 	sets       map[time.Time][]*joinset
 	head       []time.Time
 	oldestTime time.Time
 	j          *JoinNode
 	points     chan srcPoint
+	// Set when the group is dropped to make room for a new group.
+	dropped bool
 }
 
 func newGroup(i int, j *JoinNode) *group {
@@ -340,11 +571,19 @@ func newGroup(i int, j *JoinNode) *group {
 func (g *group) run() error {
 	defer g.j.runningGroups.Done()
 	for sp := range g.points {
+		g.mu.Lock()
 		err := g.collect(sp.src, sp.p)
+		g.mu.Unlock()
 		if err != nil {
 			return err
 		}
 	}
+	if g.dropped {
+		// Discard the sets of the group
+		for len(g.sets) > 0 {
+			g.removeOldestSets(len(g.sets[g.oldestTime]))
+		}
+	}
 	return nil
 }
 
@@ -359,17 +598,7 @@ func (g *group) collect(i int, p models.PointInterface) error {
 	var set *joinset
 	sets := g.sets[t]
 	if len(sets) == 0 {
-		set = newJoinset(
-			g.j,
-			g.j.j.StreamName,
-			g.j.fill,
-			g.j.fillValue,
-			g.j.j.Names,
-			g.j.j.Delimiter,
-			g.j.j.Tolerance,
-			t,
-			g.j.logger,
-		)
+		set = g.newSet(t)
 		sets = append(sets, set)
 		g.sets[t] = sets
 	}
@@ -380,21 +609,13 @@ func (g *group) collect(i int, p models.PointInterface) error {
 		}
 	}
 	if set == nil {
-		set = newJoinset(
-			g.j,
-			g.j.j.StreamName,
-			g.j.fill,
-			g.j.fillValue,
-			g.j.j.Names,
-			g.j.j.Delimiter,
-			g.j.j.Tolerance,
-			t,
-			g.j.logger,
-		)
+		set = g.newSet(t)
 		sets = append(sets, set)
 		g.sets[t] = sets
 	}
 	set.Set(i, p)
+	atomic.AddInt64(&g.j.buffered, 1)
+	g.limit()
 
 	// Update head
 	g.head[i] = t
@@ -413,6 +634,66 @@ func (g *group) collect(i int, p models.PointInterface) error {
 	return nil
 }
 
+func (g *group) newSet(t time.Time) *joinset {
+	return newJoinset(
+		g.j,
+		g.j.j.StreamName,
+		g.j.fill,
+		g.j.fillValue,
+		g.j.j.Names,
+		g.j.j.Delimiter,
+		g.j.j.Tolerance,
+		t,
+		g.j.logger,
+	)
+}
+
+func (g *group) snapshot(id models.GroupID) joinGroupSnapshot {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := joinGroupSnapshot{
+		Group: id,
+		Head:  append([]time.Time(nil), g.head...),
+	}
+	for t, sets := range g.sets {
+		for _, set := range sets {
+			ss := joinSetSnapshot{Time: t}
+			for i, v := range set.values {
+				if v != nil {
+					ss.Values = append(ss.Values, newJoinPointSnapshot(i, v))
+				}
+			}
+			s.Sets = append(s.Sets, ss)
+		}
+	}
+	return s
+}
+
+// restore the sets of the group, returns the number of restored points.
+func (g *group) restore(s joinGroupSnapshot) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	copy(g.head, s.Head)
+	var restored int64
+	for _, ss := range s.Sets {
+		set := g.newSet(ss.Time)
+		for _, v := range ss.Values {
+			if p := v.point(); p != nil && v.Src < len(g.head) {
+				set.Set(v.Src, p)
+			}
+		}
+		if set.size == 0 {
+			continue
+		}
+		g.sets[ss.Time] = append(g.sets[ss.Time], set)
+		restored += int64(set.size)
+		if g.oldestTime.IsZero() || ss.Time.Before(g.oldestTime) {
+			g.oldestTime = ss.Time
+		}
+	}
+	return restored
+}
+
 // emit a set and update the oldestTime.
 func (g *group) emit(onlyReadySets bool) error {
 	sets := g.sets[g.oldestTime]
@@ -427,10 +708,20 @@ func (g *group) emit(onlyReadySets bool) error {
 			break
 		}
 	}
-	if i == len(sets) {
+	g.removeOldestSets(i)
+	return nil
+}
+
+// removeOldestSets removes the first n sets of the oldest time and updates the oldest time.
+func (g *group) removeOldestSets(n int) {
+	sets := g.sets[g.oldestTime]
+	for _, set := range sets[:n] {
+		atomic.AddInt64(&g.j.buffered, -int64(set.size))
+	}
+	if n == len(sets) {
 		delete(g.sets, g.oldestTime)
 	} else {
-		g.sets[g.oldestTime] = sets[i:]
+		g.sets[g.oldestTime] = sets[n:]
 	}
 
 	g.oldestTime = time.Time{}
@@ -439,7 +730,18 @@ func (g *group) emit(onlyReadySets bool) error {
 			g.oldestTime = t
 		}
 	}
-	return nil
+}
+
+// limit drops the oldest sets of the group while the node exceeds the MaxBufferedPoints limit of the task,
+// if the limited buffers of the task drop their oldest data.
+func (g *group) limit() {
+	max := g.j.et.Task.Limits.MaxBufferedPoints
+	if max <= 0 || !g.j.et.limiter.dropOldest() {
+		return
+	}
+	for atomic.LoadInt64(&g.j.buffered) > max && len(g.sets) > 0 {
+		g.removeOldestSets(1)
+	}
 }
 
 // emit sets until we have none left.
@@ -319,7 +319,7 @@ func (k *K8sAutoscaleNode) evalExpr(
 	expr, ok := expressionsMap[group]
 	if !ok {
 		var err error
-		expr, err = stateful.NewExpression(lambda.Expression)
+		expr, err = k.newExpression(lambda.Expression)
 		if err != nil {
 			return 0, err
 		}
@@ -0,0 +1,707 @@
+// Package lint finds mistakes in TICKscripts that would otherwise only
+// be noticed once a task is running.
+//
+// The pipeline of a task is walked from its sources while keeping track of
+// the fields and tags each node receives. The fields and tags read from a
+// measurement are provided by a Schema, either written by hand or sampled
+// from InfluxDB using QuerySchema.
+//
+// The following problems are reported:
+//
+//   - References to fields or tags that do not exist.
+//   - Lambda expressions that are not of the type the node expects, or cannot be evaluated with the types of the fields.
+//   - Vars and funcs that are declared but never used.
+//   - Nodes that are unreachable since a where expression before them is always false.
+package lint
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+// Schema describes the fields and tags of the measurements a task reads.
+type Schema struct {
+	Measurements map[string]Measurement `json:"measurements"`
+}
+
+// Measurement describes the fields and tags of a measurement.
+// Fields map the name of the field to its InfluxDB type,
+// one of float, integer, string or boolean.
+type Measurement struct {
+	Fields map[string]string `json:"fields"`
+	Tags   []string          `json:"tags"`
+}
+
+// Problem is a mistake found in a TICKscript.
+type Problem struct {
+	// Line and Char are zero if the position of the problem is not known.
+	Line    int
+	Char    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d char %d: %s", p.Line, p.Char, p.Message)
+}
+
+// Lint checks the TICKscript and the pipeline that was created from it.
+// References to fields and tags are only checked if a schema is provided.
+// The problems are ordered by their position in the TICKscript.
+func Lint(script string, p *pipeline.Pipeline, schema *Schema) ([]Problem, error) {
+	root, err := ast.Parse(script)
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{
+		schema:      schema,
+		outputs:     make(map[pipeline.ID]*fieldSet),
+		unreachable: make(map[pipeline.ID]bool),
+	}
+	if err := l.declarations(root); err != nil {
+		return nil, err
+	}
+	if err := p.Walk(l.node); err != nil {
+		return nil, err
+	}
+	sort.Stable(byPosition(l.problems))
+	return l.problems, nil
+}
+
+type byPosition []Problem
+
+func (p byPosition) Len() int      { return len(p) }
+func (p byPosition) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byPosition) Less(i, j int) bool {
+	if p[i].Line != p[j].Line {
+		return p[i].Line < p[j].Line
+	}
+	return p[i].Char < p[j].Char
+}
+
+// fieldSet is the fields and tags of the data a node emits.
+type fieldSet struct {
+	// known is false if the names of the fields and tags cannot be determined,
+	// in which case references are not checked.
+	known bool
+	// fields maps the name of each field to its type, ast.InvalidType if the type is not known.
+	fields map[string]ast.ValueType
+	tags   map[string]bool
+}
+
+func unknownFieldSet() *fieldSet {
+	return &fieldSet{}
+}
+
+func newFieldSet() *fieldSet {
+	return &fieldSet{
+		known:  true,
+		fields: make(map[string]ast.ValueType),
+		tags:   make(map[string]bool),
+	}
+}
+
+func (s *fieldSet) copy() *fieldSet {
+	if !s.known {
+		return unknownFieldSet()
+	}
+	c := newFieldSet()
+	for f, t := range s.fields {
+		c.fields[f] = t
+	}
+	for t := range s.tags {
+		c.tags[t] = true
+	}
+	return c
+}
+
+// has reports whether the name is a field or tag of the set.
+func (s *fieldSet) has(name string) bool {
+	if name == "time" {
+		return true
+	}
+	_, ok := s.fields[name]
+	return ok || s.tags[name]
+}
+
+// typeOf returns the type of a field or tag.
+func (s *fieldSet) typeOf(name string) ast.ValueType {
+	if name == "time" {
+		return ast.TTime
+	}
+	if t, ok := s.fields[name]; ok {
+		return t
+	}
+	if s.tags[name] {
+		return ast.TString
+	}
+	return ast.InvalidType
+}
+
+// names returns the sorted names of the fields and tags of the set.
+func (s *fieldSet) names() []string {
+	names := make([]string, 0, len(s.fields)+len(s.tags))
+	for f := range s.fields {
+		names = append(names, f)
+	}
+	for t := range s.tags {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type linter struct {
+	schema      *Schema
+	outputs     map[pipeline.ID]*fieldSet
+	unreachable map[pipeline.ID]bool
+	problems    []Problem
+}
+
+func (l *linter) problemf(p ast.Position, format string, args ...interface{}) {
+	problem := Problem{
+		Message: fmt.Sprintf(format, args...),
+	}
+	if p != nil {
+		problem.Line = p.Line()
+		problem.Char = p.Char()
+	}
+	l.problems = append(l.problems, problem)
+}
+
+// declarations reports the vars and funcs of the program that are never used.
+func (l *linter) declarations(root ast.Node) error {
+	program, ok := root.(*ast.ProgramNode)
+	if !ok {
+		return nil
+	}
+	used := make(map[string]bool)
+	use := func(n ast.Node) (ast.Node, error) {
+		switch node := n.(type) {
+		case *ast.IdentifierNode:
+			used[node.Ident] = true
+		case *ast.FunctionNode:
+			if node.Type == ast.GlobalFunc {
+				used[node.Func] = true
+			}
+		}
+		return n, nil
+	}
+	for _, n := range program.Nodes {
+		var err error
+		switch node := n.(type) {
+		case *ast.DeclarationNode:
+			_, err = ast.Walk(node.Right, use)
+		case *ast.FuncDeclarationNode:
+			_, err = ast.Walk(node.Body, use)
+		case *ast.TypeDeclarationNode, *ast.DBRPNode, *ast.ImportNode:
+		default:
+			_, err = ast.Walk(node, use)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, n := range program.Nodes {
+		switch node := n.(type) {
+		case *ast.DeclarationNode:
+			if !used[node.Left.Ident] {
+				l.problemf(node, "var %s is declared but never used", node.Left.Ident)
+			}
+		case *ast.FuncDeclarationNode:
+			if !used[node.Name.Ident] {
+				l.problemf(node, "func %s is declared but never used", node.Name.Ident)
+			}
+		}
+	}
+	return nil
+}
+
+// node checks a node of the pipeline, its parents are always checked before it.
+func (l *linter) node(n pipeline.Node) error {
+	parents := n.Parents()
+	if len(parents) > 0 {
+		unreachable := true
+		for _, p := range parents {
+			unreachable = unreachable && l.unreachable[p.ID()]
+		}
+		if unreachable {
+			l.unreachable[n.ID()] = true
+			return nil
+		}
+	}
+	in := l.input(parents)
+	out := l.output(n, in)
+	l.outputs[n.ID()] = out
+
+	for _, lambda := range l.predicates(n) {
+		if alwaysFalse(lambda) {
+			for _, c := range n.Children() {
+				l.problemf(c.Position(), "node %s is unreachable, the expression %s at line %d char %d is always false", c.Name(), ast.Format(lambda), lambda.Line(), lambda.Char())
+			}
+			l.unreachable[n.ID()] = true
+		}
+	}
+	return nil
+}
+
+// input returns the fields and tags received from the parents of a node.
+func (l *linter) input(parents []pipeline.Node) *fieldSet {
+	if len(parents) == 0 {
+		return unknownFieldSet()
+	}
+	in := newFieldSet()
+	for _, p := range parents {
+		if l.unreachable[p.ID()] {
+			continue
+		}
+		out := l.outputs[p.ID()]
+		if out == nil || !out.known {
+			return unknownFieldSet()
+		}
+		for f, t := range out.fields {
+			if prev, ok := in.fields[f]; ok && prev != t {
+				t = ast.InvalidType
+			}
+			in.fields[f] = t
+		}
+		for t := range out.tags {
+			in.tags[t] = true
+		}
+	}
+	return in
+}
+
+// output checks the properties of the node against its input
+// and returns the fields and tags the node emits.
+func (l *linter) output(n pipeline.Node, in *fieldSet) *fieldSet {
+	switch node := n.(type) {
+	case *pipeline.FromNode:
+		out := l.measurement(node)
+		l.dimensions(node, node.Dimensions, out)
+		l.predicate(node.Lambda, out)
+		return out
+	case *pipeline.WhereNode:
+		l.predicate(node.Lambda, in)
+		return in
+	case *pipeline.GroupByNode:
+		l.dimensions(node, node.Dimensions, in)
+		return in
+	case *pipeline.WindowNode, *pipeline.SampleNode, *pipeline.ShiftNode,
+		*pipeline.LogNode, *pipeline.NoOpNode, *pipeline.HTTPOutNode,
+		*pipeline.InfluxDBOutNode, *pipeline.UnionNode, *pipeline.SwitchRouteNode:
+		return in
+	case *pipeline.SwitchNode:
+		for _, c := range node.Cases {
+			l.predicate(c.Lambda, in)
+		}
+		return in
+	case *pipeline.AlertNode:
+		for _, lambda := range []*ast.LambdaNode{node.Info, node.Warn, node.Crit, node.InfoReset, node.WarnReset, node.CritReset} {
+			l.predicate(lambda, in)
+		}
+		return unknownFieldSet()
+	case *pipeline.EvalNode:
+		return l.eval(node, in)
+	case *pipeline.DefaultNode:
+		out := in.copy()
+		if out.known {
+			for f, v := range node.Fields {
+				if _, ok := out.fields[f]; !ok {
+					out.fields[f] = ast.TypeOf(v)
+				}
+			}
+			for t := range node.Tags {
+				out.tags[t] = true
+			}
+		}
+		return out
+	case *pipeline.DeleteNode:
+		out := in.copy()
+		if out.known {
+			for _, f := range node.Fields {
+				if _, ok := out.fields[f]; !ok {
+					l.problemf(node.Position(), "cannot delete unknown field %q%s", f, suggest(f, in))
+				}
+				delete(out.fields, f)
+			}
+			for _, t := range node.Tags {
+				if !out.tags[t] {
+					l.problemf(node.Position(), "cannot delete unknown tag %q%s", t, suggest(t, in))
+				}
+				delete(out.tags, t)
+			}
+		}
+		return out
+	case *pipeline.InfluxQLNode:
+		l.field(node, node.Field, in)
+		if !in.known {
+			return in
+		}
+		out := newFieldSet()
+		out.fields[node.As] = ast.InvalidType
+		for t := range in.tags {
+			out.tags[t] = true
+		}
+		return out
+	case *pipeline.DerivativeNode:
+		l.field(node, node.Field, in)
+		return with(in, node.As, ast.TFloat)
+	case *pipeline.RateNode:
+		l.field(node, node.Field, in)
+		return with(with(in, node.As, ast.TFloat), node.IncreaseAs, ast.TFloat)
+	case *pipeline.StateDurationNode:
+		l.predicate(node.Lambda, in)
+		return with(in, node.As, ast.TFloat)
+	case *pipeline.StateCountNode:
+		l.predicate(node.Lambda, in)
+		return with(in, node.As, ast.TInt)
+	default:
+		// The fields emitted by the node cannot be determined,
+		// still check its lambda expressions as far as possible.
+		for _, lambda := range lambdas(n) {
+			l.lambdaType(lambda, in)
+		}
+		return unknownFieldSet()
+	}
+}
+
+// with returns a copy of the set with the field added.
+func with(s *fieldSet, field string, t ast.ValueType) *fieldSet {
+	out := s.copy()
+	if out.known && field != "" {
+		out.fields[field] = t
+	}
+	return out
+}
+
+// measurement returns the fields and tags of the measurement of the from node.
+func (l *linter) measurement(n *pipeline.FromNode) *fieldSet {
+	if l.schema == nil || n.Measurement == "" {
+		return unknownFieldSet()
+	}
+	m, ok := l.schema.Measurements[n.Measurement]
+	if !ok {
+		l.problemf(n.Position(), "unknown measurement %q", n.Measurement)
+		return unknownFieldSet()
+	}
+	out := newFieldSet()
+	for f, t := range m.Fields {
+		out.fields[f] = influxType(t)
+	}
+	for _, t := range m.Tags {
+		out.tags[t] = true
+	}
+	return out
+}
+
+// influxType returns the type of the values of an InfluxDB field type.
+func influxType(t string) ast.ValueType {
+	switch t {
+	case "float":
+		return ast.TFloat
+	case "integer":
+		return ast.TInt
+	case "string":
+		return ast.TString
+	case "boolean":
+		return ast.TBool
+	default:
+		return ast.InvalidType
+	}
+}
+
+// dimensions checks that the group by dimensions are known tags.
+func (l *linter) dimensions(n pipeline.Node, dimensions []interface{}, in *fieldSet) {
+	if !in.known {
+		return
+	}
+	for _, d := range dimensions {
+		if tag, ok := d.(string); ok && !in.tags[tag] {
+			l.problemf(n.Position(), "cannot group by unknown tag %q%s", tag, suggest(tag, in))
+		}
+	}
+}
+
+// field checks that the field read by a node is known.
+func (l *linter) field(n pipeline.Node, field string, in *fieldSet) {
+	if !in.known || field == "" {
+		return
+	}
+	if _, ok := in.fields[field]; !ok {
+		l.problemf(n.Position(), "unknown field %q%s", field, suggest(field, in))
+	}
+}
+
+// eval checks the lambda expressions of the eval node in order,
+// each expression can reference the results of the previous expressions.
+func (l *linter) eval(n *pipeline.EvalNode, in *fieldSet) *fieldSet {
+	scope := in.copy()
+	results := make(map[string]ast.ValueType, len(n.AsList))
+	for i, lambda := range n.Lambdas {
+		t := l.lambdaType(lambda, scope)
+		if i >= len(n.AsList) {
+			continue
+		}
+		name := n.AsList[i]
+		results[name] = t
+		if scope.known {
+			scope.fields[name] = t
+		}
+	}
+	for _, tag := range n.TagsList {
+		if t, ok := results[tag]; ok && t != ast.InvalidType && t != ast.TString {
+			l.problemf(n.Position(), "tag %s must be a string, the expression is of type %s", tag, t)
+		}
+	}
+
+	// Map results are flattened into fields whose names are not known.
+	for _, t := range results {
+		if t == ast.InvalidType || t == ast.TMap {
+			return unknownFieldSet()
+		}
+	}
+	if !in.known {
+		return in
+	}
+	out := newFieldSet()
+	for t := range in.tags {
+		out.tags[t] = true
+	}
+	for _, tag := range n.TagsList {
+		out.tags[tag] = true
+	}
+	isTag := make(map[string]bool, len(n.TagsList))
+	for _, tag := range n.TagsList {
+		isTag[tag] = true
+	}
+	if !n.KeepFlag {
+		for name, t := range results {
+			if !isTag[name] {
+				out.fields[name] = t
+			}
+		}
+		return out
+	}
+	if len(n.KeepList) == 0 {
+		for f, t := range in.fields {
+			out.fields[f] = t
+		}
+		for name, t := range results {
+			out.fields[name] = t
+		}
+		return out
+	}
+	for _, f := range n.KeepList {
+		if t, ok := results[f]; ok {
+			out.fields[f] = t
+		} else if t, ok := in.fields[f]; ok {
+			out.fields[f] = t
+		} else {
+			l.problemf(n.Position(), "cannot keep unknown field %q%s", f, suggest(f, scope))
+		}
+	}
+	return out
+}
+
+// predicate checks that the lambda expression is a bool expression.
+func (l *linter) predicate(lambda *ast.LambdaNode, in *fieldSet) {
+	if lambda == nil {
+		return
+	}
+	if t := l.lambdaType(lambda, in); t != ast.InvalidType && t != ast.TBool {
+		l.problemf(lambda, "expression must be of type bool, got %s", t)
+	}
+}
+
+// lambdaType checks the references of the lambda expression and returns its type.
+// The returned type is ast.InvalidType if the type cannot be determined.
+func (l *linter) lambdaType(lambda *ast.LambdaNode, in *fieldSet) ast.ValueType {
+	if lambda == nil {
+		return ast.InvalidType
+	}
+	refs := references(lambda)
+	if !in.known {
+		if len(refs) > 0 {
+			return ast.InvalidType
+		}
+		in = newFieldSet()
+	}
+	typed := true
+	for _, ref := range refs {
+		if !in.has(ref.Reference) {
+			l.problemf(ref, "unknown field or tag %q%s", ref.Reference, suggest(ref.Reference, in))
+			typed = false
+		} else if in.typeOf(ref.Reference) == ast.InvalidType {
+			typed = false
+		}
+	}
+	if !typed {
+		return ast.InvalidType
+	}
+
+	expr, err := stateful.NewExpression(lambda.Expression)
+	if err != nil {
+		l.problemf(lambda, "invalid expression: %v", err)
+		return ast.InvalidType
+	}
+	scope := stateful.NewScope()
+	for _, ref := range refs {
+		scope.Set(ref.Reference, zeroValue(in.typeOf(ref.Reference)))
+	}
+	t, err := expr.Type(scope)
+	if err == nil {
+		err = stateful.CheckOperandTypes(lambda.Expression, scope)
+	}
+	if err != nil {
+		l.problemf(lambda, "invalid expression: %v", err)
+		return ast.InvalidType
+	}
+	return t
+}
+
+func zeroValue(t ast.ValueType) interface{} {
+	if t == ast.TTime {
+		return time.Time{}
+	}
+	return ast.ZeroValue(t)
+}
+
+// references returns the references of the lambda expression.
+func references(lambda *ast.LambdaNode) []*ast.ReferenceNode {
+	var refs []*ast.ReferenceNode
+	ast.Walk(lambda, func(n ast.Node) (ast.Node, error) {
+		if ref, ok := n.(*ast.ReferenceNode); ok {
+			refs = append(refs, ref)
+		}
+		return n, nil
+	})
+	return refs
+}
+
+// alwaysFalse reports whether the lambda expression is false regardless of the data.
+func alwaysFalse(lambda *ast.LambdaNode) bool {
+	constant := true
+	ast.Walk(lambda, func(n ast.Node) (ast.Node, error) {
+		switch n.(type) {
+		case *ast.ReferenceNode, *ast.FunctionNode:
+			constant = false
+		}
+		return n, nil
+	})
+	if !constant {
+		return false
+	}
+	expr, err := stateful.NewExpression(lambda.Expression)
+	if err != nil {
+		return false
+	}
+	b, err := expr.EvalBool(stateful.NewScope())
+	return err == nil && !b
+}
+
+// predicates returns the lambda expressions that filter the data of the node.
+func (l *linter) predicates(n pipeline.Node) []*ast.LambdaNode {
+	switch node := n.(type) {
+	case *pipeline.FromNode:
+		if node.Lambda != nil {
+			return []*ast.LambdaNode{node.Lambda}
+		}
+	case *pipeline.WhereNode:
+		return []*ast.LambdaNode{node.Lambda}
+	}
+	return nil
+}
+
+// lambdas returns the lambda expressions of the properties of a node.
+func lambdas(n pipeline.Node) []*ast.LambdaNode {
+	return collectLambdas(reflect.ValueOf(n), nil)
+}
+
+var (
+	lambdaType     = reflect.TypeOf((*ast.LambdaNode)(nil))
+	lambdaListType = reflect.TypeOf([]*ast.LambdaNode(nil))
+	nodeType       = reflect.TypeOf((*pipeline.Node)(nil)).Elem()
+)
+
+func collectLambdas(v reflect.Value, list []*ast.LambdaNode) []*ast.LambdaNode {
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return list
+	}
+	s := v.Elem()
+	for i := 0; i < s.NumField(); i++ {
+		f := s.Field(i)
+		if s.Type().Field(i).PkgPath != "" {
+			// Unexported field
+			continue
+		}
+		switch {
+		case f.Type() == lambdaType:
+			if !f.IsNil() {
+				list = append(list, f.Interface().(*ast.LambdaNode))
+			}
+		case f.Type() == lambdaListType:
+			list = append(list, f.Interface().([]*ast.LambdaNode)...)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Ptr && !f.Type().Elem().Implements(nodeType):
+			for j := 0; j < f.Len(); j++ {
+				list = collectLambdas(f.Index(j), list)
+			}
+		}
+	}
+	return list
+}
+
+// suggest returns a hint naming the field or tag closest to the unknown name.
+func suggest(name string, in *fieldSet) string {
+	best := ""
+	bestDist := len(name)/2 + 1
+	for _, n := range in.names() {
+		if strings.EqualFold(n, name) {
+			return fmt.Sprintf(", did you mean %q?", n)
+		}
+		if d := distance(name, n); d < bestDist {
+			best = n
+			bestDist = d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1
+			if d := curr[j-1] + 1; d < curr[j] {
+				curr[j] = d
+			}
+			if d := prev[j-1] + cost; d < curr[j] {
+				curr[j] = d
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,220 @@
+package lint_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	imodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/influxdb"
+	"github.com/influxdata/kapacitor/lint"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+type deadman struct{}
+
+func (deadman) Interval() time.Duration { return 0 }
+func (deadman) Threshold() float64      { return 0 }
+func (deadman) Id() string              { return "" }
+func (deadman) Message() string         { return "" }
+func (deadman) Global() bool            { return false }
+
+var testSchema = &lint.Schema{
+	Measurements: map[string]lint.Measurement{
+		"cpu": {
+			Fields: map[string]string{
+				"usage_idle": "float",
+				"usage_user": "float",
+				"count":      "integer",
+				"state":      "string",
+			},
+			Tags: []string{"host", "cpu"},
+		},
+	},
+}
+
+func TestLint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		script   string
+		schema   *lint.Schema
+		problems []lint.Problem
+	}{
+		{
+			name: "valid",
+			script: `stream
+    |from()
+        .measurement('cpu')
+        .groupBy('host')
+    |where(lambda: "cpu" == 'cpu-total')
+    |eval(lambda: 100.0 - "usage_idle")
+        .as('used')
+        .keep('used', 'count')
+    |alert()
+        .crit(lambda: "used" > 90.0 AND "count" > 1)
+`,
+			schema: testSchema,
+		},
+		{
+			name: "unknown fields and tags",
+			script: `stream
+    |from()
+        .measurement('cpu')
+        .groupBy('hots')
+    |where(lambda: "usage_idel" < 10.0)
+    |delete()
+        .field('count')
+    |alert()
+        .crit(lambda: "count" > 1)
+`,
+			schema: testSchema,
+			problems: []lint.Problem{
+				{Line: 2, Char: 6, Message: `cannot group by unknown tag "hots", did you mean "host"?`},
+				{Line: 5, Char: 20, Message: `unknown field or tag "usage_idel", did you mean "usage_idle"?`},
+				{Line: 9, Char: 23, Message: `unknown field or tag "count"`},
+			},
+		},
+		{
+			name: "unknown measurement",
+			script: `stream
+    |from()
+        .measurement('mem')
+    |where(lambda: "used" > 10)
+`,
+			schema: testSchema,
+			problems: []lint.Problem{
+				{Line: 2, Char: 6, Message: `unknown measurement "mem"`},
+			},
+		},
+		{
+			name: "type mismatch",
+			script: `stream
+    |from()
+        .measurement('cpu')
+    |where(lambda: "state" > 10)
+    |eval(lambda: "usage_user" + "usage_idle")
+        .as('total')
+    |alert()
+        .crit(lambda: "total")
+`,
+			schema: testSchema,
+			problems: []lint.Problem{
+				{Line: 4, Char: 12, Message: `invalid expression: mismatched type to binary operator. got string > int. see bool(), int(), float(), string(), duration()`},
+				{Line: 8, Char: 15, Message: `expression must be of type bool, got float`},
+			},
+		},
+		{
+			name: "unused declarations",
+			script: `var threshold = 10.0
+var unused = 5
+func double(x) = x * 2
+func half(x) = x / 2
+
+stream
+    |from()
+    |where(lambda: double("value") > threshold)
+`,
+			problems: []lint.Problem{
+				{Line: 2, Char: 1, Message: `var unused is declared but never used`},
+				{Line: 4, Char: 1, Message: `func half is declared but never used`},
+			},
+		},
+		{
+			name: "unreachable",
+			script: `var data = stream
+    |from()
+        .measurement('cpu')
+    |where(lambda: FALSE)
+
+data
+    |log()
+    |httpOut('cpu')
+`,
+			problems: []lint.Problem{
+				{Line: 7, Char: 6, Message: `node log3 is unreachable, the expression lambda: FALSE at line 4 char 12 is always false`},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		p, err := pipeline.CreatePipeline(tc.script, pipeline.StreamEdge, stateful.NewScope(), deadman{}, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		problems, err := lint.Lint(tc.script, p, tc.schema)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(problems, tc.problems) {
+			t.Errorf("%s: unexpected problems:\ngot\n%v\nexp\n%v", tc.name, problems, tc.problems)
+		}
+	}
+}
+
+type queryClient struct {
+	influxdb.Client
+	responses map[string]*influxdb.Response
+}
+
+func (c queryClient) Query(q influxdb.Query) (*influxdb.Response, error) {
+	return c.responses[q.Database+" "+q.Command], nil
+}
+
+func TestQuerySchema(t *testing.T) {
+	c := queryClient{
+		responses: map[string]*influxdb.Response{
+			"telegraf SHOW FIELD KEYS": {
+				Results: []influxdb.Result{{
+					Series: []imodels.Row{
+						{
+							Name:    "cpu",
+							Columns: []string{"fieldKey", "fieldType"},
+							Values: [][]interface{}{
+								{"usage_idle", "float"},
+								{"count", "integer"},
+							},
+						},
+					},
+				}},
+			},
+			"telegraf SHOW TAG KEYS": {
+				Results: []influxdb.Result{{
+					Series: []imodels.Row{
+						{
+							Name:    "cpu",
+							Columns: []string{"tagKey"},
+							Values:  [][]interface{}{{"host"}, {"cpu"}},
+						},
+						{
+							Name:    "mem",
+							Columns: []string{"tagKey"},
+							Values:  [][]interface{}{{"host"}},
+						},
+					},
+				}},
+			},
+		},
+	}
+	schema, err := lint.QuerySchema(c, "telegraf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := &lint.Schema{
+		Measurements: map[string]lint.Measurement{
+			"cpu": {
+				Fields: map[string]string{
+					"usage_idle": "float",
+					"count":      "integer",
+				},
+				Tags: []string{"host", "cpu"},
+			},
+			"mem": {
+				Fields: map[string]string{},
+				Tags:   []string{"host"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(schema, exp) {
+		t.Errorf("unexpected schema:\ngot\n%v\nexp\n%v", schema, exp)
+	}
+}
@@ -0,0 +1,96 @@
+package lint
+
+import (
+	"fmt"
+
+	imodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor/influxdb"
+)
+
+// QuerySchema samples the schema of the databases from InfluxDB
+// using the SHOW FIELD KEYS and SHOW TAG KEYS statements.
+// Measurements of the same name in different databases are merged.
+func QuerySchema(c influxdb.Client, databases ...string) (*Schema, error) {
+	s := &Schema{
+		Measurements: make(map[string]Measurement),
+	}
+	for _, db := range databases {
+		fields, err := query(c, "SHOW FIELD KEYS", db)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range fields {
+			m := s.measurement(row.Name)
+			for _, v := range row.Values {
+				if len(v) < 2 {
+					continue
+				}
+				name, _ := v[0].(string)
+				typ, _ := v[1].(string)
+				if name == "" {
+					continue
+				}
+				m.Fields[name] = typ
+			}
+			s.Measurements[row.Name] = m
+		}
+
+		tags, err := query(c, "SHOW TAG KEYS", db)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range tags {
+			m := s.measurement(row.Name)
+			for _, v := range row.Values {
+				if len(v) < 1 {
+					continue
+				}
+				name, _ := v[0].(string)
+				if name == "" || m.hasTag(name) {
+					continue
+				}
+				m.Tags = append(m.Tags, name)
+			}
+			s.Measurements[row.Name] = m
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) measurement(name string) Measurement {
+	m, ok := s.Measurements[name]
+	if !ok {
+		m = Measurement{}
+	}
+	if m.Fields == nil {
+		m.Fields = make(map[string]string)
+	}
+	return m
+}
+
+func (m Measurement) hasTag(name string) bool {
+	for _, t := range m.Tags {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func query(c influxdb.Client, command, database string) ([]imodels.Row, error) {
+	resp, err := c.Query(influxdb.Query{
+		Command:  command,
+		Database: database,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema of database %q: %v", database, err)
+	}
+	if err := resp.Error(); err != nil {
+		return nil, fmt.Errorf("failed to query schema of database %q: %v", database, err)
+	}
+	var rows []imodels.Row
+	for _, res := range resp.Results {
+		rows = append(rows, res.Series...)
+	}
+	return rows, nil
+}
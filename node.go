@@ -14,6 +14,8 @@ import (
 	"github.com/influxdata/kapacitor/models"
 	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/influxdata/kapacitor/tick/stateful"
 	"github.com/influxdata/kapacitor/timer"
 	"github.com/pkg/errors"
 )
@@ -64,6 +66,9 @@ type Node interface {
 	incrementErrorCount()
 
 	stats() map[string]interface{}
+
+	// number of groups of the node
+	cardinality() int64
 }
 
 //implementation of Node
@@ -111,7 +116,7 @@ func (n *node) init() {
 	n.nodeErrors = &kexpvar.Int{}
 	n.statMap.Set(statErrorCount, n.nodeErrors)
 	n.statMap.Set(statCardinalityGauge, kexpvar.NewIntFuncGauge(nil))
-	n.timer = n.et.tm.TimingService.NewTimer(avgExecVar)
+	n.timer = n.et.limiter.timer(n.et.tm.TimingService.NewTimer(avgExecVar))
 	n.errCh = make(chan error, 1)
 }
 
@@ -173,10 +178,24 @@ func (n *node) addChild(c Node) (*Edge, error) {
 	}
 	n.children = append(n.children, c)
 
-	edge := newEdge(n.et.Task.ID, n.Name(), c.Name(), n.Provides(), defaultEdgeBufferSize, n.et.tm.LogService)
+	size := defaultEdgeBufferSize
+	if q := n.et.Task.Limits.MaxEdgeQueue; q > size {
+		// Leave room for the queue to reach its limit.
+		size = q
+	}
+	edge := newEdge(n.et.Task.ID, n.Name(), c.Name(), n.Provides(), size, n.et.tm.LogService)
 	if edge == nil {
 		return nil, fmt.Errorf("unknown edge type %s", n.Provides())
 	}
+	if n.et.limiter.limited() {
+		edge.limit(n.et.limiter, false)
+	}
+	if tracer := n.et.tm.EdgeTracer; tracer != nil {
+		task, parent, child := n.et.Task.ID, n.Name(), c.Name()
+		edge.trace = func(p models.PointInterface) {
+			tracer.Trace(task, parent, child, p)
+		}
+	}
 	c.addParentEdge(edge)
 	return edge, nil
 }
@@ -297,6 +316,11 @@ func (n *node) emittedCount() (count int64) {
 }
 
 // node increment error count increments a nodes error_count stat
+// newExpression compiles the lambda expression with the calendars of the task master.
+func (n *node) newExpression(expr ast.Node) (stateful.Expression, error) {
+	return stateful.NewExpressionWithCalendars(expr, n.et.tm.CalendarService)
+}
+
 func (n *node) incrementErrorCount() {
 	n.nodeErrors.Add(1)
 }
@@ -318,6 +342,13 @@ func (n *node) stats() map[string]interface{} {
 	return stats
 }
 
+func (n *node) cardinality() int64 {
+	if v, ok := n.statMap.Get(statCardinalityGauge).(kexpvar.IntVar); ok {
+		return v.IntValue()
+	}
+	return 0
+}
+
 // Statistics for a node
 type nodeStats struct {
 	Fields     models.Fields
@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Seasons supported by the AnomalyNode.
+const (
+	// A single baseline for all points.
+	AnomalySeasonNone = "none"
+	// A baseline for each hour of the day.
+	AnomalySeasonHour = "hour"
+	// A baseline for each day of the week.
+	AnomalySeasonWeekday = "weekday"
+	// A baseline for each hour of each day of the week.
+	AnomalySeasonHourOfWeek = "hourOfWeek"
+)
+
+// An AnomalyNode detects anomalies in a field by comparing each value
+// to a learned seasonal baseline.
+//
+// A baseline is maintained per group and per season bucket, i.e. when using
+// the 'hour' season each group has 24 independent baselines, one for each hour of the day.
+// Each baseline is an exponentially weighted moving mean and variance of the values
+// that fell into the bucket.
+//
+// For each point the following fields are added:
+//
+//    * expected     -- The mean of the baseline.
+//    * upper        -- The upper band, expected + sigmas * stddev.
+//    * lower        -- The lower band, expected - sigmas * stddev.
+//    * anomalyScore -- The number of standard deviations the value is away from expected.
+//
+// Points that fall into a bucket which has not yet seen `warmup` values are used
+// to train the baseline and are not emitted.
+//
+// The learned baselines are included in task snapshots so restarting
+// the task does not lose them. See the `snapshot-interval` option of the task store.
+//
+// Example:
+//    stream
+//        |from()
+//            .measurement('requests')
+//        |groupBy('host')
+//        |anomaly('count')
+//            .season('hourOfWeek')
+//            .sigmas(3.0)
+//        |alert()
+//            .crit(lambda: "anomalyScore" > 3.0)
+//
+// Times are bucketed using UTC.
+type AnomalyNode struct {
+	chainnode
+
+	// The field to use when detecting anomalies.
+	// tick:ignore
+	Field string
+
+	// The season of the baselines.
+	// One of 'none', 'hour', 'weekday' or 'hourOfWeek'.
+	// Default: 'hour'
+	Season string
+
+	// The smoothing factor of the moving mean and variance, between 0 and 1.
+	// Larger values give more weight to recent values.
+	// Default: 0.3
+	Alpha float64
+
+	// The number of standard deviations of the upper and lower bands.
+	// Default: 3.0
+	Sigmas float64
+
+	// The number of values a bucket must see before anomalies are reported for it.
+	// Default: 2
+	Warmup int64
+}
+
+func newAnomalyNode(wants EdgeType, field string) *AnomalyNode {
+	return &AnomalyNode{
+		chainnode: newBasicChainNode("anomaly", wants, wants),
+		Field:     field,
+		Season:    AnomalySeasonHour,
+		Alpha:     0.3,
+		Sigmas:    3.0,
+		Warmup:    2,
+	}
+}
+
+func (n *AnomalyNode) validate() error {
+	switch n.Season {
+	case AnomalySeasonNone, AnomalySeasonHour, AnomalySeasonWeekday, AnomalySeasonHourOfWeek:
+	default:
+		return fmt.Errorf("invalid season %q, must be one of 'none', 'hour', 'weekday' or 'hourOfWeek'", n.Season)
+	}
+	if n.Alpha <= 0 || n.Alpha > 1 {
+		return errors.New("alpha must be greater than 0 and less than or equal to 1")
+	}
+	if n.Sigmas <= 0 {
+		return errors.New("sigmas must be greater than 0")
+	}
+	if n.Warmup < 1 {
+		return errors.New("warmup must be at least 1")
+	}
+	return nil
+}
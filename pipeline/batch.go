@@ -151,6 +151,7 @@ func newQueryNode() *QueryNode {
 func (n *QueryNode) ChainMethods() map[string]reflect.Value {
 	return map[string]reflect.Value{
 		"GroupBy": reflect.ValueOf(n.chainnode.GroupBy),
+		"Fill":    reflect.ValueOf(n.chainnode.Fill),
 	}
 }
 
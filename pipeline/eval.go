@@ -20,6 +20,17 @@ import (
 // data point with the result of `error_count / total_count` where
 // `error_count` and `total_count` are existing fields on the data point.
 //
+// An expression that results in a map or list, i.e. `jsonParse("message")`, is flattened into
+// fields prefixed with its name, with nested keys and list indexes separated by a `.`.
+//
+// Example:
+//    stream
+//        |eval(lambda: jsonParse("message"))
+//          .as('msg')
+//
+// The above example will add the fields `msg.level` and `msg.request.latency`
+// for the message `{"level": "error", "request": {"latency": 1.5}}`.
+//
 // Available Statistics:
 //
 //    * eval_errors -- number of errors evaluating any expressions.
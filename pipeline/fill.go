@@ -0,0 +1,139 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Fill methods supported by the FillNode.
+const (
+	// Linearly interpolate between the previous and next point.
+	FillLinear = "linear"
+	// Carry forward the fields of the previous point.
+	FillPrevious = "previous"
+	// Set the fields to null.
+	FillNull = "null"
+	// Set the fields to a given value.
+	FillValue = "value"
+)
+
+// A FillNode resamples the data of each group onto a regular time grid.
+//
+// Points whose time is on the grid are passed through unchanged.
+// For each grid time without a point a synthetic point is created from
+// the surrounding points using the fill method.
+// Points whose time is not on the grid are only used to compute synthetic points.
+// The grid is aligned with the zero time, i.e. when every is 10s grid times are
+// 00:00:00, 00:00:10, 00:00:20 etc.
+//
+// The available fill methods are:
+//
+//    * linear   -- Linearly interpolate numeric fields between the previous and next point.
+//                  Non numeric fields are carried forward from the previous point.
+//    * previous -- Carry forward the fields of the previous point.
+//    * null     -- Set all fields to null. Null fields are not written by influxDBOut.
+//    * value    -- Set all fields to the given value.
+//
+// Example:
+//    var fast = stream
+//        |from()
+//            .measurement('cpu')
+//            .where(lambda: "interval" == '10s')
+//    var slow = stream
+//        |from()
+//            .measurement('cpu')
+//            .where(lambda: "interval" == '60s')
+//        |fill()
+//            .every(10s)
+//            .method('linear')
+//            .tag('synthetic')
+//    fast
+//        |join(slow)
+//            .as('fast', 'slow')
+//
+// Synthetic points are tagged with synthetic=true.
+//
+// On a batch edge each batch is resampled independently, starting at the first
+// grid time of the batch.
+//
+// Gaps that would need more than limit synthetic points are not filled,
+// the grid continues at the next point after the gap.
+//
+// NOTE: On a stream edge synthetic points can only be created once the
+// next point of the group has arrived.
+type FillNode struct {
+	chainnode
+
+	// The interval of the time grid.
+	Every time.Duration
+
+	// The fill method, one of 'linear', 'previous', 'null' or 'value'.
+	// Default: 'linear'
+	// tick:ignore
+	FillMethod string `tick:"Method"`
+
+	// The value used by the 'value' fill method.
+	// tick:ignore
+	FillValue interface{}
+
+	// The name of a tag that is set to 'true' on synthetic points.
+	// If empty synthetic points are not tagged.
+	Tag string
+
+	// The maximum number of synthetic points created for a single gap.
+	// Larger gaps are not filled.
+	// Default: 1000
+	Limit int64
+}
+
+func newFillNode(wants EdgeType) *FillNode {
+	return &FillNode{
+		chainnode:  newBasicChainNode("fill", wants, wants),
+		FillMethod: FillLinear,
+		Limit:      1000,
+	}
+}
+
+// Set the fill method.
+// The 'value' method requires the fill value as its second argument.
+//
+// Example:
+//    |fill()
+//        .every(10s)
+//        .method('value', 0.0)
+//
+// tick:property
+func (n *FillNode) Method(method string, value ...interface{}) *FillNode {
+	n.FillMethod = method
+	n.FillValue = nil
+	if len(value) > 0 {
+		n.FillValue = value[0]
+	}
+	if len(value) > 1 {
+		panic(fmt.Sprintf("method accepts at most one fill value, got %d", len(value)))
+	}
+	return n
+}
+
+func (n *FillNode) validate() error {
+	if n.Every <= 0 {
+		return errors.New("fill every must be greater than zero")
+	}
+	if n.Limit <= 0 {
+		return errors.New("fill limit must be greater than zero")
+	}
+	switch n.FillMethod {
+	case FillLinear, FillPrevious, FillNull:
+		if n.FillValue != nil {
+			return fmt.Errorf("fill method %q does not accept a value", n.FillMethod)
+		}
+	case FillValue:
+		if n.FillValue == nil {
+			return errors.New("fill method 'value' requires a value")
+		}
+	default:
+		return fmt.Errorf("invalid fill method %q, must be one of 'linear', 'previous', 'null' or 'value'", n.FillMethod)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+var (
+	nodeType     = reflect.TypeOf((*Node)(nil)).Elem()
+	astNodeType  = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// Fingerprint returns a digest of the properties of the node.
+// Nodes of the same kind with the same properties have the same fingerprint,
+// the parents and children of the node and the position of its definition do not change it.
+func Fingerprint(n Node) string {
+	var buf bytes.Buffer
+	buf.WriteString(n.Desc())
+	writeFingerprint(&buf, reflect.ValueOf(n), true)
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFingerprint writes the exported properties of v to buf.
+// Other nodes referenced by v are only written by name, which breaks the cycles of the pipeline.
+func writeFingerprint(buf *bytes.Buffer, v reflect.Value, root bool) {
+	if !v.IsValid() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		buf.WriteString("nil;")
+		return
+	}
+	if v.CanInterface() {
+		t := v.Type()
+		switch {
+		case !root && t.Implements(nodeType):
+			fmt.Fprintf(buf, "%s;", v.Interface().(Node).Name())
+			return
+		case t.Implements(astNodeType):
+			n := v.Interface().(ast.Node)
+			if l, ok := n.(*ast.LambdaNode); ok {
+				// Comments do not change the lambda.
+				n = l.Expression
+			}
+			fmt.Fprintf(buf, "%s;", ast.Format(n))
+			return
+		case !root && t.Implements(stringerType):
+			fmt.Fprintf(buf, "%s;", v.Interface().(fmt.Stringer).String())
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		writeFingerprint(buf, v.Elem(), root)
+	case reflect.Struct:
+		t := v.Type()
+		buf.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				// Unexported fields, including the embedded node, are not properties.
+				continue
+			}
+			buf.WriteString(f.Name)
+			buf.WriteString(":")
+			writeFingerprint(buf, v.Field(i), false)
+		}
+		buf.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		buf.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			writeFingerprint(buf, v.Index(i), false)
+		}
+		buf.WriteString("]")
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value, v.Len())
+		for _, k := range v.MapKeys() {
+			key := fmt.Sprint(k.Interface())
+			keys = append(keys, key)
+			values[key] = v.MapIndex(k)
+		}
+		sort.Strings(keys)
+		buf.WriteString("{")
+		for _, k := range keys {
+			fmt.Fprintf(buf, "%q:", k)
+			writeFingerprint(buf, values[k], false)
+		}
+		buf.WriteString("}")
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		// Not a property.
+		buf.WriteString("-;")
+	default:
+		fmt.Fprintf(buf, "%#v;", v.Interface())
+	}
+}
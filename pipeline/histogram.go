@@ -0,0 +1,155 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// A HistogramNode computes the distribution of a field's values over a set of buckets.
+// The histogram is computed over each batch, producing a single point per group.
+//
+// Each bucket is identified by its upper bound and counts the number of values
+// less than or equal to that bound, i.e. the buckets are cumulative.
+// An additional `le_inf` bucket counts all values.
+// The `sum` and `count` fields contain the sum and number of values respectively.
+//
+// Example:
+//    stream
+//        |from()
+//            .measurement('requests')
+//        |window()
+//            .period(1m)
+//            .every(1m)
+//        |histogram('latency')
+//            .buckets(0.1, 0.5, 1, 5)
+//        |influxDBOut()
+//            .database('mydb')
+//            .measurement('latency_histogram')
+//
+// Each emitted point has the fields `le_0.1`, `le_0.5`, `le_1`, `le_5`, `le_inf`, `sum` and `count`.
+//
+// Buckets can also be defined as an exponential series using the exponentialBuckets property.
+//
+// Example:
+//    batch
+//        |query('SELECT latency FROM "mydb"."autogen"."requests"')
+//            .period(1m)
+//            .every(1m)
+//        |histogram('latency')
+//            .exponentialBuckets(0.01, 2.0, 10)
+//
+// NOTE: Histogram can only be applied to batch edges, use a window node on stream edges.
+type HistogramNode struct {
+	chainnode
+
+	// The field to use when calculating the histogram
+	// tick:ignore
+	Field string
+
+	// The upper bounds of the buckets, sorted in increasing order.
+	// tick:ignore
+	Bounds []float64 `tick:"Buckets"`
+
+	// The parameters used to generate exponential buckets, if any.
+	// tick:ignore
+	Exponential *ExponentialBuckets `tick:"ExponentialBuckets"`
+
+	// Prefix of the bucket field names.
+	// Default: le_
+	Prefix string
+}
+
+func newHistogramNode(field string) *HistogramNode {
+	return &HistogramNode{
+		chainnode: newBasicChainNode("histogram", BatchEdge, StreamEdge),
+		Field:     field,
+		Prefix:    "le_",
+	}
+}
+
+// Set the upper bounds of the buckets.
+// Bounds can be any mix of float and integer values.
+// tick:property
+func (n *HistogramNode) Buckets(bounds ...interface{}) *HistogramNode {
+	n.Exponential = nil
+	n.Bounds = make([]float64, len(bounds))
+	for i, b := range bounds {
+		switch v := b.(type) {
+		case float64:
+			n.Bounds[i] = v
+		case int64:
+			n.Bounds[i] = float64(v)
+		default:
+			panic(fmt.Sprintf("bucket bounds must be float or int values, got %T", b))
+		}
+	}
+	sort.Float64s(n.Bounds)
+	return n
+}
+
+// Set count bucket bounds where the first bound is start
+// and each following bound is the previous bound multiplied by factor.
+// tick:property
+func (n *HistogramNode) ExponentialBuckets(start, factor interface{}, count int64) *HistogramNode {
+	s, ok := histogramFloat(start)
+	if !ok {
+		panic(fmt.Sprintf("start must be a float or int value, got %T", start))
+	}
+	f, ok := histogramFloat(factor)
+	if !ok {
+		panic(fmt.Sprintf("factor must be a float or int value, got %T", factor))
+	}
+	if count < 1 {
+		panic("count must be greater than zero")
+	}
+	n.Exponential = &ExponentialBuckets{
+		Start:  s,
+		Factor: f,
+		Count:  count,
+	}
+	n.Bounds = make([]float64, count)
+	for i := range n.Bounds {
+		n.Bounds[i] = s
+		s *= f
+	}
+	return n
+}
+
+// ExponentialBuckets describes a series of bucket bounds
+// where each bound is the previous bound multiplied by Factor.
+type ExponentialBuckets struct {
+	Start  float64
+	Factor float64
+	Count  int64
+}
+
+func histogramFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func (n *HistogramNode) validate() error {
+	if len(n.Bounds) == 0 {
+		return errors.New("must specify buckets or exponentialBuckets for histogram")
+	}
+	if n.Exponential != nil && (n.Exponential.Start <= 0 || n.Exponential.Factor <= 1) {
+		return errors.New("exponential buckets must have a start greater than zero and a factor greater than one")
+	}
+	for i, b := range n.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return fmt.Errorf("invalid bucket bound %v", b)
+		}
+		if i > 0 && b <= n.Bounds[i-1] {
+			return fmt.Errorf("bucket bounds must be strictly increasing, got %v after %v", b, n.Bounds[i-1])
+		}
+	}
+	return nil
+}
@@ -54,6 +54,9 @@ type Node interface {
 	Name() string
 	SetName(string)
 
+	// Position in the TICKscript where the node was created
+	Position() ast.Position
+
 	// Unique id for the node
 	ID() ID
 	setID(ID)
@@ -89,6 +92,7 @@ type node struct {
 	provides EdgeType
 	tm       bool
 	pm       bool
+	pos      ast.Position
 }
 
 // tick:ignore
@@ -118,6 +122,18 @@ func (n *node) SetName(name string) {
 	n.name = name
 }
 
+// The position in the TICKscript where the node was created.
+// It is nil if the node was not created by a TICKscript.
+// tick:ignore
+func (n *node) Position() ast.Position {
+	return n.pos
+}
+
+// tick:ignore
+func (n *node) SetPosition(p ast.Position) {
+	n.pos = p
+}
+
 // tick:ignore
 func (n *node) Parents() []Node {
 	return n.parents
@@ -315,6 +331,13 @@ func (n *chainnode) Where(expression *ast.LambdaNode) *WhereNode {
 	return w
 }
 
+// Create a new node that routes each point to one of several named routes.
+func (n *chainnode) Switch() *SwitchNode {
+	s := newSwitchNode(n.provides)
+	n.linkChild(s)
+	return s
+}
+
 // Create an HTTP output node that caches the most recent data it has received.
 // The cached data are available at the given endpoint.
 // The endpoint is the relative path from the API endpoint of the running task.
@@ -417,6 +440,32 @@ func (n *chainnode) Window() *WindowNode {
 	return w
 }
 
+// Create a new node that detects anomalies of a field against seasonal baselines.
+func (n *chainnode) Anomaly(field string) *AnomalyNode {
+	a := newAnomalyNode(n.Provides(), field)
+	n.linkChild(a)
+	return a
+}
+
+// Create a new node that computes a histogram of a field's values for each batch.
+//
+// NOTE: Histogram can only be applied to batch edges.
+func (n *chainnode) Histogram(field string) *HistogramNode {
+	if n.Provides() != BatchEdge {
+		panic("cannot compute histogram of stream edge, use window first")
+	}
+	h := newHistogramNode(field)
+	n.linkChild(h)
+	return h
+}
+
+// Create a new node that resamples the data onto a regular time grid, filling gaps.
+func (n *chainnode) Fill() *FillNode {
+	f := newFillNode(n.Provides())
+	n.linkChild(f)
+	return f
+}
+
 // Create a new node that samples the incoming points or batches.
 //
 // One point will be emitted every count or duration specified.
@@ -433,6 +482,13 @@ func (n *chainnode) Derivative(field string) *DerivativeNode {
 	return s
 }
 
+// Create a new node that computes the rate and increase of a field.
+func (n *chainnode) Rate(field string) *RateNode {
+	r := newRateNode(n.Provides(), field)
+	n.linkChild(r)
+	return r
+}
+
 // Create a new node that shifts the incoming points or batches in time.
 func (n *chainnode) Shift(shift time.Duration) *ShiftNode {
 	s := newShiftNode(n.Provides(), shift)
@@ -25,8 +25,9 @@ func CreateTemplatePipeline(
 	sourceEdge EdgeType,
 	scope *stateful.Scope,
 	deadman DeadmanService,
+	importer tick.Importer,
 ) (*TemplatePipeline, error) {
-	p, vars, err := createPipelineAndVars(script, sourceEdge, scope, deadman, nil, true)
+	p, vars, err := createPipelineAndVars(script, sourceEdge, scope, deadman, nil, true, importer)
 	if err != nil {
 		return nil, err
 	}
@@ -45,8 +46,9 @@ func CreatePipeline(
 	scope *stateful.Scope,
 	deadman DeadmanService,
 	predefinedVars map[string]tick.Var,
+	importer tick.Importer,
 ) (*Pipeline, error) {
-	p, _, err := createPipelineAndVars(script, sourceEdge, scope, deadman, predefinedVars, false)
+	p, _, err := createPipelineAndVars(script, sourceEdge, scope, deadman, predefinedVars, false, importer)
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +62,7 @@ func createPipelineAndVars(
 	deadman DeadmanService,
 	predefinedVars map[string]tick.Var,
 	ignoreMissingVars bool,
+	importer tick.Importer,
 ) (*Pipeline, map[string]tick.Var, error) {
 	p := &Pipeline{
 		deadman: deadman,
@@ -77,7 +80,7 @@ func createPipelineAndVars(
 	}
 	p.addSource(src)
 
-	vars, err := tick.Evaluate(script, scope, predefinedVars, ignoreMissingVars)
+	vars, err := tick.EvaluateWithImporter(script, scope, predefinedVars, ignoreMissingVars, importer)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -206,6 +209,12 @@ func (t *TemplatePipeline) Vars() map[string]tick.Var {
 	return t.vars
 }
 
+// Return the pipeline of the template, vars without a default value are not set.
+// tick:ignore
+func (t *TemplatePipeline) Pipeline() *Pipeline {
+	return t.p
+}
+
 // Return a graphviz .dot formatted byte array.
 // tick:ignore
 func (t *TemplatePipeline) Dot(name string) []byte {
@@ -35,7 +35,7 @@ w.every(1s)
 	d := deadman{}
 
 	scope := stateful.NewScope()
-	p, err := CreatePipeline(tickScript, StreamEdge, scope, d, nil)
+	p, err := CreatePipeline(tickScript, StreamEdge, scope, d, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -96,3 +96,67 @@ func TestPipelineSort(t *testing.T) {
 
 	assert.Equal(sorted, p.sorted)
 }
+
+func TestFingerprint(t *testing.T) {
+	fingerprints := func(script string) map[string]string {
+		p, err := CreatePipeline(script, StreamEdge, stateful.NewScope(), deadman{}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fps := make(map[string]string)
+		p.Walk(func(n Node) error {
+			fps[n.Name()] = Fingerprint(n)
+			return nil
+		})
+		return fps
+	}
+	orig := fingerprints(`stream
+	|from()
+		.measurement('cpu')
+	|window()
+		.period(10s)
+		.every(10s)
+	|where(lambda: "value" > 10)
+	|alert()
+		.crit(lambda: "value" > 90)
+		.log('/tmp/alert.log')
+`)
+	// Same properties, defined with different formatting and comments.
+	same := fingerprints(`
+// comment
+stream
+	|from().measurement('cpu')
+	|window().period(10s).every(10s)
+	|where(
+		// comment
+		lambda: "value" > 10
+	)
+	|alert().crit(lambda: "value" > 90).log('/tmp/alert.log')
+`)
+	changed := fingerprints(`stream
+	|from()
+		.measurement('cpu')
+	|window()
+		.period(20s)
+		.every(10s)
+	|where(lambda: "value" > 10)
+	|alert()
+		.crit(lambda: "value" > 80)
+		.log('/tmp/alert.log')
+`)
+	for name, fp := range orig {
+		if got := same[name]; got != fp {
+			t.Errorf("unexpected fingerprint change of node %s", name)
+		}
+	}
+	for _, name := range []string{"stream0", "from1", "where3"} {
+		if orig[name] != changed[name] {
+			t.Errorf("unexpected fingerprint change of node %s", name)
+		}
+	}
+	for _, name := range []string{"window2", "alert4"} {
+		if orig[name] == changed[name] {
+			t.Errorf("expected fingerprint change of node %s", name)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Compute the rate and increase of a field.
+//
+// On a stream edge the rate and increase are computed between each point and
+// the previous point of the same group, and the first point is dropped.
+// On a batch edge the rate and increase are computed over all points of the batch
+// and a single point is emitted per batch.
+//
+// When the counter property is set the field is treated as a monotonic counter.
+// A decrease in value is considered a counter reset and the value after the
+// reset is counted as the increase, similar to the Prometheus rate and increase functions.
+// If a wrap value is set, a decrease from a value above half the wrap value is
+// instead considered an overflow of the counter at that value.
+// Decreases from lower values are still considered counter resets.
+//
+// Example:
+//     stream
+//         |from()
+//             .measurement('net')
+//         |window()
+//             .period(1m)
+//             .every(1m)
+//         |rate('bytes_recv')
+//             .counter()
+//             .unit(1s)
+//         |influxDBOut()
+//             .database('mydb')
+//             .measurement('net_rate')
+//
+// Computes the per second rate of bytes received over each minute,
+// along with the total number of bytes received.
+//
+// The rate is computed via:
+//    increase / ( time_difference / unit)
+type RateNode struct {
+	chainnode
+
+	// The field to use when calculating the rate
+	// tick:ignore
+	Field string
+
+	// The name of the rate field.
+	// Default: 'rate'
+	As string
+
+	// The name of the increase field.
+	// Default: 'increase'
+	IncreaseAs string
+
+	// The time unit of the resulting rate value.
+	// Default: 1s
+	Unit time.Duration
+
+	// Whether the field is a monotonic counter.
+	// tick:ignore
+	CounterFlag bool `tick:"Counter"`
+
+	// The value at which the counter wraps around to zero.
+	// tick:ignore
+	WrapValue float64 `tick:"Wrap"`
+}
+
+func newRateNode(wants EdgeType, field string) *RateNode {
+	return &RateNode{
+		chainnode:  newBasicChainNode("rate", wants, StreamEdge),
+		Field:      field,
+		As:         "rate",
+		IncreaseAs: "increase",
+		Unit:       time.Second,
+	}
+}
+
+// If called the field is treated as a monotonic counter and
+// decreases in value are handled as counter resets.
+// tick:property
+func (n *RateNode) Counter() *RateNode {
+	n.CounterFlag = true
+	return n
+}
+
+// Set the value at which the counter overflows and wraps around to zero.
+// For example an unsigned 32 bit counter wraps at 4294967296.
+// Only a decrease from a value above half the wrap value is considered an overflow,
+// other decreases are considered counter resets.
+// Implies counter.
+// tick:property
+func (n *RateNode) Wrap(max interface{}) *RateNode {
+	switch v := max.(type) {
+	case float64:
+		n.WrapValue = v
+	case int64:
+		n.WrapValue = float64(v)
+	default:
+		panic(fmt.Sprintf("wrap value must be a float or int value, got %T", max))
+	}
+	n.CounterFlag = true
+	return n
+}
+
+func (n *RateNode) validate() error {
+	if n.Unit <= 0 {
+		return errors.New("unit must be greater than zero")
+	}
+	if n.WrapValue < 0 {
+		return errors.New("wrap value must not be negative")
+	}
+	if n.As == n.IncreaseAs {
+		return fmt.Errorf("rate and increase fields must have different names, got %q", n.As)
+	}
+	return nil
+}
@@ -33,6 +33,10 @@ import (
 //
 // Note that as the first point in the given state has no previous point, its
 // state duration will be 0.
+//
+// Stateful functions in the expression, i.e. `previous` or `delta`, keep their state per group
+// regardless of the state. For batches the state duration and the state of the
+// stateful functions are reset at the start of each batch.
 type StateDurationNode struct {
 	chainnode
 
@@ -80,6 +84,10 @@ func newStateDurationNode(wants EdgeType, predicate *ast.LambdaNode) *StateDurat
 //             .warn(lambda: "state_count" >= 1)
 //             // Critical after 5 points
 //             .crit(lambda: "state_count" >= 5)
+//
+// Stateful functions in the expression, i.e. `previous` or `delta`, keep their state per group
+// regardless of the state. For batches the state count and the state of the
+// stateful functions are reset at the start of each batch.
 type StateCountNode struct {
 	chainnode
 
@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// A SwitchNode routes each point to exactly one of several named routes.
+//
+// Cases are evaluated in the order they are defined and each point is forwarded
+// to the route of the first case whose expression evaluates to true.
+// Points that match no case are forwarded to the default route, or dropped
+// if no default route is defined.
+// Points for which the expression of a case fails to evaluate are dropped.
+//
+// Children are attached to a route using the route property of the switch node.
+//
+// Example:
+//    var sw = stream
+//        |from()
+//            .measurement('cpu')
+//        |switch()
+//            .case('critical', lambda: "usage_idle" < 10)
+//            .case('warning', lambda: "usage_idle" < 30)
+//            .default('ok')
+//
+//    sw.route('critical')
+//        |alert()
+//            .crit(lambda: TRUE)
+//
+//    sw.route('warning')
+//        |influxDBOut()
+//            .database('mydb')
+//            .measurement('cpu_warnings')
+//
+// Points matching neither case are forwarded to the 'ok' route, which has no children and
+// so they are dropped.
+//
+// On a batch edge the points of each batch are split into one batch per route.
+// Routes that receive no points of a batch are not sent an empty batch.
+//
+// The number of points sent to each route is available in the node stats
+// as `route_<name>`.
+type SwitchNode struct {
+	chainnode
+
+	// The ordered cases of the switch.
+	// tick:ignore
+	Cases []*SwitchCase `tick:"Case"`
+
+	// The name of the route for points matching no case.
+	// tick:ignore
+	DefaultRoute string `tick:"Default"`
+
+	// The routes attached to the switch.
+	// tick:ignore
+	Routes []*SwitchRouteNode `tick:"Route"`
+}
+
+// A named case of a SwitchNode.
+type SwitchCase struct {
+	Name   string
+	Lambda *ast.LambdaNode
+}
+
+func newSwitchNode(wants EdgeType) *SwitchNode {
+	return &SwitchNode{
+		chainnode: newBasicChainNode("switch", wants, wants),
+	}
+}
+
+// Add a named case to the switch.
+// Points for which the expression evaluates to true
+// and that matched no previous case are forwarded to the route of the same name.
+// tick:property
+func (n *SwitchNode) Case(name string, expression *ast.LambdaNode) *SwitchNode {
+	n.Cases = append(n.Cases, &SwitchCase{
+		Name:   name,
+		Lambda: expression,
+	})
+	return n
+}
+
+// Set the name of the route for points that match no case.
+// tick:property
+func (n *SwitchNode) Default(name string) *SwitchNode {
+	n.DefaultRoute = name
+	return n
+}
+
+// Create a child of the switch that receives the points of the named route.
+// tick:property
+func (n *SwitchNode) Route(name string) *SwitchRouteNode {
+	r := newSwitchRouteNode(n.provides, name)
+	n.linkChild(r)
+	n.Routes = append(n.Routes, r)
+	return r
+}
+
+func (n *SwitchNode) validate() error {
+	if len(n.Cases) == 0 {
+		return errors.New("switch must have at least one case")
+	}
+	names := make(map[string]bool, len(n.Cases)+1)
+	for _, c := range n.Cases {
+		if c.Name == "" {
+			return errors.New("switch case name must not be empty")
+		}
+		if c.Lambda == nil {
+			return fmt.Errorf("switch case %q must have an expression", c.Name)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("duplicate switch case %q", c.Name)
+		}
+		names[c.Name] = true
+	}
+	if n.DefaultRoute != "" {
+		if names[n.DefaultRoute] {
+			return fmt.Errorf("default route %q has the same name as a case", n.DefaultRoute)
+		}
+		names[n.DefaultRoute] = true
+	}
+	for _, c := range n.Children() {
+		r, ok := c.(*SwitchRouteNode)
+		if !ok {
+			return fmt.Errorf("children of a switch must be attached using route, got %s", c.Name())
+		}
+		if !names[r.Case] {
+			return fmt.Errorf("unknown switch route %q", r.Case)
+		}
+	}
+	return nil
+}
+
+// A SwitchRouteNode passes on the points of a single route of a SwitchNode.
+// Use the SwitchNode.Route property to create a SwitchRouteNode.
+type SwitchRouteNode struct {
+	chainnode
+
+	// The name of the case or default route.
+	// tick:ignore
+	Case string
+}
+
+func newSwitchRouteNode(wants EdgeType, name string) *SwitchRouteNode {
+	return &SwitchRouteNode{
+		chainnode: newBasicChainNode("route", wants, wants),
+		Case:      name,
+	}
+}
@@ -0,0 +1,159 @@
+package kapacitor
+
+import (
+	"log"
+	"sync"
+
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+)
+
+type RateNode struct {
+	node
+	r *pipeline.RateNode
+}
+
+// Create a new rate node.
+func newRateNode(et *ExecutingTask, n *pipeline.RateNode, l *log.Logger) (*RateNode, error) {
+	rn := &RateNode{
+		node: node{Node: n, et: et, logger: l},
+		r:    n,
+	}
+	rn.node.runF = rn.runRate
+	return rn, nil
+}
+
+func (r *RateNode) runRate([]byte) error {
+	switch r.Wants() {
+	case pipeline.StreamEdge:
+		var mu sync.RWMutex
+		previous := make(map[models.GroupID]models.Point)
+		valueF := func() int64 {
+			mu.RLock()
+			l := len(previous)
+			mu.RUnlock()
+			return int64(l)
+		}
+		r.statMap.Set(statCardinalityGauge, expvar.NewIntFuncGauge(valueF))
+
+		for p, ok := r.ins[0].NextPoint(); ok; p, ok = r.ins[0].NextPoint() {
+			r.timer.Start()
+			curr, ok := r.value(p.Fields)
+			if !ok {
+				r.timer.Stop()
+				continue
+			}
+			mu.RLock()
+			pr, hasPrevious := previous[p.Group]
+			mu.RUnlock()
+			mu.Lock()
+			previous[p.Group] = p
+			mu.Unlock()
+			if !hasPrevious {
+				r.timer.Stop()
+				continue
+			}
+			prev, _ := r.value(pr.Fields)
+			elapsed := p.Time.Sub(pr.Time)
+			if elapsed <= 0 {
+				r.incrementErrorCount()
+				r.logger.Printf("E! cannot compute rate, elapsed time was %v", elapsed)
+				r.timer.Stop()
+				continue
+			}
+			increase := r.increase(prev, curr)
+			fields := p.Fields.Copy()
+			fields[r.r.As] = increase / (float64(elapsed) / float64(r.r.Unit))
+			fields[r.r.IncreaseAs] = increase
+			p.Fields = fields
+			r.timer.Pause()
+			for _, child := range r.outs {
+				err := child.CollectPoint(p)
+				if err != nil {
+					return err
+				}
+			}
+			r.timer.Resume()
+			r.timer.Stop()
+		}
+	case pipeline.BatchEdge:
+		for b, ok := r.ins[0].NextBatch(); ok; b, ok = r.ins[0].NextBatch() {
+			r.timer.Start()
+			var first, last models.BatchPoint
+			var prev, increase float64
+			count := 0
+			for _, p := range b.Points {
+				curr, ok := r.value(p.Fields)
+				if !ok {
+					continue
+				}
+				if count == 0 {
+					first = p
+				} else {
+					increase += r.increase(prev, curr)
+				}
+				prev = curr
+				last = p
+				count++
+			}
+			elapsed := last.Time.Sub(first.Time)
+			if count < 2 || elapsed <= 0 {
+				// Not enough points to compute a rate
+				r.timer.Stop()
+				continue
+			}
+			p := models.Point{
+				Name:       b.Name,
+				Time:       b.TMax,
+				Group:      b.Group,
+				Dimensions: b.PointDimensions(),
+				Tags:       b.Tags,
+				Fields: models.Fields{
+					r.r.As:         increase / (float64(elapsed) / float64(r.r.Unit)),
+					r.r.IncreaseAs: increase,
+				},
+			}
+			r.timer.Stop()
+			for _, child := range r.outs {
+				err := child.CollectPoint(p)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// value returns the value of the field as a float.
+func (r *RateNode) value(fields models.Fields) (float64, bool) {
+	v, ok := fields[r.r.Field]
+	if !ok {
+		r.incrementErrorCount()
+		r.logger.Printf("E! field %s missing from point, skipping point", r.r.Field)
+		return 0, false
+	}
+	f, ok := numToFloat(v)
+	if !ok {
+		r.incrementErrorCount()
+		r.logger.Printf("E! cannot apply rate to type %T", v)
+		return 0, false
+	}
+	return f, true
+}
+
+// increase computes the increase from prev to curr,
+// accounting for counter resets and wrap arounds.
+func (r *RateNode) increase(prev, curr float64) float64 {
+	if !r.r.CounterFlag || curr >= prev {
+		return curr - prev
+	}
+	if r.r.WrapValue > 0 && prev <= r.r.WrapValue && prev > r.r.WrapValue/2 {
+		// The counter was close to the wrap value,
+		// so it overflowed and wrapped around to zero.
+		return r.r.WrapValue - prev + curr
+	}
+	// The counter was reset, everything since the reset is the increase.
+	return curr
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/influxdata/kapacitor/command"
 	"github.com/influxdata/kapacitor/services/alerta"
 	"github.com/influxdata/kapacitor/services/azure"
+	"github.com/influxdata/kapacitor/services/calendar"
 	"github.com/influxdata/kapacitor/services/config"
 	"github.com/influxdata/kapacitor/services/consul"
 	"github.com/influxdata/kapacitor/services/deadman"
@@ -26,9 +27,11 @@ import (
 	"github.com/influxdata/kapacitor/services/httppost"
 	"github.com/influxdata/kapacitor/services/influxdb"
 	"github.com/influxdata/kapacitor/services/k8s"
+	"github.com/influxdata/kapacitor/services/localauth"
 	"github.com/influxdata/kapacitor/services/logging"
 	"github.com/influxdata/kapacitor/services/marathon"
 	"github.com/influxdata/kapacitor/services/nerve"
+	"github.com/influxdata/kapacitor/services/oidc"
 	"github.com/influxdata/kapacitor/services/opsgenie"
 	"github.com/influxdata/kapacitor/services/pagerduty"
 	"github.com/influxdata/kapacitor/services/pushover"
@@ -59,6 +62,8 @@ import (
 // Config represents the configuration format for the kapacitord binary.
 type Config struct {
 	HTTP           httpd.Config      `toml:"http"`
+	LocalAuth      localauth.Config  `toml:"localauth"`
+	OIDC           oidc.Config       `toml:"oidc"`
 	Replay         replay.Config     `toml:"replay"`
 	Storage        storage.Config    `toml:"storage"`
 	Task           task_store.Config `toml:"task"`
@@ -108,6 +113,7 @@ type Config struct {
 	Stats     stats.Config     `toml:"stats"`
 	UDF       udf.Config       `toml:"udf"`
 	Deadman   deadman.Config   `toml:"deadman"`
+	Calendar  calendar.Config  `toml:"calendar"`
 
 	Hostname               string `toml:"hostname"`
 	DataDir                string `toml:"data_dir"`
@@ -125,6 +131,8 @@ func NewConfig() *Config {
 	}
 
 	c.HTTP = httpd.NewConfig()
+	c.LocalAuth = localauth.NewConfig()
+	c.OIDC = oidc.NewConfig()
 	c.Storage = storage.NewConfig()
 	c.Replay = replay.NewConfig()
 	c.Task = task_store.NewConfig()
@@ -153,6 +161,7 @@ func NewConfig() *Config {
 	c.Stats = stats.NewConfig()
 	c.UDF = udf.NewConfig()
 	c.Deadman = deadman.NewConfig()
+	c.Calendar = calendar.NewConfig()
 
 	return c
 }
@@ -176,6 +185,7 @@ func NewDemoConfig() (*Config, error) {
 	c.Task.Dir = filepath.Join(homeDir, ".kapacitor", c.Task.Dir)
 	c.Storage.BoltDBPath = filepath.Join(homeDir, ".kapacitor", c.Storage.BoltDBPath)
 	c.DataDir = filepath.Join(homeDir, ".kapacitor", c.DataDir)
+	c.HTTP.AuditFile = filepath.Join(homeDir, ".kapacitor", c.HTTP.AuditFile)
 
 	return c, nil
 }
@@ -197,6 +207,12 @@ func (c *Config) Validate() error {
 	if err := c.HTTP.Validate(); err != nil {
 		return err
 	}
+	if err := c.LocalAuth.Validate(); err != nil {
+		return err
+	}
+	if err := c.OIDC.Validate(); err != nil {
+		return fmt.Errorf("invalid oidc config: %v", err)
+	}
 	if err := c.Task.Validate(); err != nil {
 		return err
 	}
@@ -281,6 +297,9 @@ func (c *Config) Validate() error {
 	if err := c.UDF.Validate(); err != nil {
 		return err
 	}
+	if err := c.Calendar.Validate(); err != nil {
+		return err
+	}
 
 	// Validate scrapers
 	for i := range c.Scraper {
@@ -23,7 +23,9 @@ import (
 	"github.com/influxdata/kapacitor/server/vars"
 	"github.com/influxdata/kapacitor/services/alert"
 	"github.com/influxdata/kapacitor/services/alerta"
+	"github.com/influxdata/kapacitor/services/apply"
 	"github.com/influxdata/kapacitor/services/azure"
+	"github.com/influxdata/kapacitor/services/calendar"
 	"github.com/influxdata/kapacitor/services/config"
 	"github.com/influxdata/kapacitor/services/consul"
 	"github.com/influxdata/kapacitor/services/deadman"
@@ -36,10 +38,12 @@ import (
 	"github.com/influxdata/kapacitor/services/httppost"
 	"github.com/influxdata/kapacitor/services/influxdb"
 	"github.com/influxdata/kapacitor/services/k8s"
+	"github.com/influxdata/kapacitor/services/localauth"
 	"github.com/influxdata/kapacitor/services/logging"
 	"github.com/influxdata/kapacitor/services/marathon"
 	"github.com/influxdata/kapacitor/services/nerve"
 	"github.com/influxdata/kapacitor/services/noauth"
+	"github.com/influxdata/kapacitor/services/oidc"
 	"github.com/influxdata/kapacitor/services/opsgenie"
 	"github.com/influxdata/kapacitor/services/pagerduty"
 	"github.com/influxdata/kapacitor/services/pushover"
@@ -188,6 +192,7 @@ func New(c *Config, buildInfo BuildInfo, logService logging.Interface) (*Server,
 	s.initHTTPDService()
 	s.appendStorageService()
 	s.appendAuthService()
+	s.appendOIDCService()
 	s.appendConfigOverrideService()
 	s.appendTesterService()
 
@@ -197,6 +202,7 @@ func New(c *Config, buildInfo BuildInfo, logService logging.Interface) (*Server,
 	// Append all dynamic services after the config override and tester services.
 	s.appendUDFService()
 	s.appendDeadmanService()
+	s.appendCalendarService()
 
 	if err := s.appendInfluxDBService(); err != nil {
 		return nil, errors.Wrap(err, "influxdb service")
@@ -225,6 +231,7 @@ func New(c *Config, buildInfo BuildInfo, logService logging.Interface) (*Server,
 	// Append these after InfluxDB because they depend on it
 	s.appendTaskStoreService()
 	s.appendReplayService()
+	s.appendApplyService()
 
 	// Append third-party integrations
 	// Append extra input services
@@ -396,9 +403,11 @@ func (s *Server) appendTaskStoreService() {
 	srv.StorageService = s.StorageService
 	srv.HTTPDService = s.HTTPDService
 	srv.TaskMasterLookup = s.TaskMasterLookup
+	srv.InfluxDBService = s.InfluxDBService
 
 	s.TaskStore = srv
 	s.TaskMaster.TaskStore = srv
+	s.TaskMaster.ModuleImporter = srv
 	s.AppendService("task_store", srv)
 }
 
@@ -416,6 +425,15 @@ func (s *Server) appendReplayService() {
 	s.AppendService("replay", srv)
 }
 
+func (s *Server) appendApplyService() {
+	l := s.LogService.NewLogger("[apply] ", log.LstdFlags)
+	srv := apply.NewService(apply.NewConfig(), l)
+	srv.HTTPDService = s.HTTPDService
+	srv.APIHandler = s.HTTPDService.Handler
+
+	s.AppendService("apply", srv)
+}
+
 func (s *Server) appendK8sService() error {
 	c := s.config.Kubernetes
 	l := s.LogService.NewLogger("[kubernetes] ", log.LstdFlags)
@@ -438,6 +456,17 @@ func (s *Server) appendDeadmanService() {
 	s.AppendService("deadman", srv)
 }
 
+func (s *Server) appendCalendarService() {
+	c := s.config.Calendar
+	if c.Enabled {
+		l := s.LogService.NewLogger("[calendar] ", log.LstdFlags)
+		srv := calendar.NewService(c, l)
+
+		s.TaskMaster.CalendarService = srv
+		s.AppendService("calendar", srv)
+	}
+}
+
 func (s *Server) appendUDFService() {
 	l := s.LogService.NewLogger("[udf] ", log.LstdFlags)
 	srv := udf.NewService(s.config.UDF, l)
@@ -447,6 +476,17 @@ func (s *Server) appendUDFService() {
 }
 
 func (s *Server) appendAuthService() {
+	if s.config.LocalAuth.Enabled {
+		l := s.LogService.NewLogger("[localauth] ", log.LstdFlags)
+		srv := localauth.NewService(s.config.LocalAuth, l)
+		srv.HTTPDService = s.HTTPDService
+		srv.StorageService = s.StorageService
+
+		s.AuthService = srv
+		s.HTTPDService.Handler.AuthService = srv
+		s.AppendService("auth", srv)
+		return
+	}
 	l := s.LogService.NewLogger("[noauth] ", log.LstdFlags)
 	srv := noauth.NewService(l)
 
@@ -455,6 +495,18 @@ func (s *Server) appendAuthService() {
 	s.AppendService("auth", srv)
 }
 
+func (s *Server) appendOIDCService() {
+	c := s.config.OIDC
+	if !c.Enabled {
+		return
+	}
+	l := s.LogService.NewLogger("[oidc] ", log.LstdFlags)
+	srv := oidc.NewService(c, l)
+
+	s.HTTPDService.Handler.OIDCService = srv
+	s.AppendService("oidc", srv)
+}
+
 func (s *Server) appendOpsGenieService() {
 	c := s.config.OpsGenie
 	l := s.LogService.NewLogger("[opsgenie] ", log.LstdFlags)
@@ -53,6 +53,7 @@ import (
 	"github.com/influxdata/kapacitor/services/victorops/victoropstest"
 	"github.com/k-sone/snmpgo"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var udfDir string
@@ -167,6 +168,181 @@ func TestServer_Authenticate_User(t *testing.T) {
 	}
 }
 
+func TestServer_LocalAuth(t *testing.T) {
+	conf := NewConfig()
+	conf.HTTP.AuthEnabled = true
+	conf.LocalAuth.Enabled = true
+	conf.LocalAuth.AdminUsername = "admin"
+	conf.LocalAuth.AdminPassword = "admin's secure password"
+	conf.LocalAuth.BcryptCost = bcrypt.MinCost
+	s := OpenServer(conf)
+	defer s.Close()
+	newClient := func(username, password string) *client.Client {
+		cli, err := client.New(client.Config{
+			URL: s.URL(),
+			Credentials: &client.Credentials{
+				Method:   client.UserAuthentication,
+				Username: username,
+				Password: password,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cli
+	}
+	admin := newClient("admin", "admin's secure password")
+	u, err := admin.CreateUser(client.CreateUserOptions{
+		Name:     "bob",
+		Password: "bob's secure password",
+		Privileges: map[string][]string{
+			"/api": {"read"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.User{
+		Link:  client.Link{Relation: client.Self, Href: "/kapacitor/v1/users/bob"},
+		Name:  "bob",
+		Admin: false,
+		Privileges: map[string][]string{
+			"/api": {"read"},
+		},
+	}
+	if !reflect.DeepEqual(u, exp) {
+		t.Errorf("unexpected user:\ngot\n%#v\nexp\n%#v\n", u, exp)
+	}
+
+	bob := newClient("bob", "bob's secure password")
+	if _, _, err := bob.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.ListUsers(nil); err == nil {
+		t.Error("expected non-admin user to not be allowed to list users")
+	}
+	if _, err := bob.CreateTask(client.CreateTaskOptions{
+		ID:         "testTaskID",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: "stream|from()",
+	}); err == nil {
+		t.Error("expected user with only read privileges to not be allowed to create a task")
+	}
+	if _, _, err := newClient("bob", "wrong password").Ping(); err == nil {
+		t.Error("expected authentication to fail with the wrong password")
+	}
+
+	// Revoke all privileges of the user
+	if _, err := admin.UpdateUser(admin.UserLink("bob"), client.UpdateUserOptions{
+		Privileges: map[string][]string{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := bob.Ping(); err == nil {
+		t.Error("expected user without privileges to not be allowed to ping")
+	}
+
+	if err := admin.DeleteUser(admin.UserLink("bob")); err != nil {
+		t.Fatal(err)
+	}
+	users, err := admin.ListUsers(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(users), 1; got != exp {
+		t.Fatalf("unexpected number of users got %d exp %d", got, exp)
+	}
+	if got, exp := users[0].Name, "admin"; got != exp {
+		t.Errorf("unexpected user got %s exp %s", got, exp)
+	}
+}
+
+func TestServer_Authenticate_APIToken(t *testing.T) {
+	conf := NewConfig()
+	conf.HTTP.AuthEnabled = true
+	conf.LocalAuth.Enabled = true
+	conf.LocalAuth.AdminUsername = "admin"
+	conf.LocalAuth.AdminPassword = "admin's secure password"
+	conf.LocalAuth.BcryptCost = bcrypt.MinCost
+	s := OpenServer(conf)
+	defer s.Close()
+	admin, err := client.New(client.Config{
+		URL: s.URL(),
+		Credentials: &client.Credentials{
+			Method:   client.UserAuthentication,
+			Username: "admin",
+			Password: "admin's secure password",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := admin.CreateAPIToken(client.CreateAPITokenOptions{
+		Name: "ci",
+		Privileges: map[string][]string{
+			"/api/tasks/ci-*": {"all"},
+			"/api/ping":       {"read"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := client.New(client.Config{
+		URL: s.URL(),
+		Credentials: &client.Credentials{
+			Method: client.BearerAuthentication,
+			Token:  token.Token,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cli.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.UpdateTask(cli.TaskLink("ci-task"), client.UpdateTaskOptions{
+		Status: client.Enabled,
+	}); err == nil || strings.Contains(err.Error(), "privilege") {
+		t.Errorf("expected task not found error, got %v", err)
+	}
+	if _, err := cli.Task(cli.TaskLink("other-task"), nil); err == nil || !strings.Contains(err.Error(), "privilege") {
+		t.Errorf("expected missing privilege error, got %v", err)
+	}
+
+	// Creating a task is authorized against the ID of the new task.
+	tick := `stream
+    |from()
+        .measurement('test')
+`
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "ci-x",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+	}); err != nil {
+		t.Errorf("expected task matching the token privileges to be created, got %v", err)
+	}
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "prod-x",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: tick,
+	}); err == nil || !strings.Contains(err.Error(), "privilege") {
+		t.Errorf("expected missing privilege error, got %v", err)
+	}
+	if _, err := admin.Task(admin.TaskLink("prod-x"), nil); err == nil {
+		t.Error("expected task without privileges not to be created")
+	}
+
+	if err := admin.DeleteAPIToken(admin.APITokenLink("ci")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cli.Ping(); err == nil {
+		t.Error("expected revoked token to fail authentication")
+	}
+}
+
 func TestServer_Authenticate_Bearer_Fail(t *testing.T) {
 	secret := "secret"
 	// Create a new token object, specifying signing method and the claims
@@ -343,6 +519,424 @@ func TestServer_CreateTask(t *testing.T) {
 	}
 }
 
+func TestServer_CreateTask_TypeAndDBRPsFromTICKscript(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testTaskID"
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+		{
+			Database:        "otherdb",
+			RetentionPolicy: "default",
+		},
+	}
+	tick := `dbrp "mydb"."myrp"
+dbrp "otherdb"."default"
+
+batch
+    |query('SELECT * FROM "mydb"."myrp"."test"')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		TICKscript: tick,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti, err := cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ti.Error != "" {
+		t.Fatal(ti.Error)
+	}
+	if ti.Type != client.BatchTask {
+		t.Fatalf("unexpected type got %v exp %v", ti.Type, client.BatchTask)
+	}
+	if !reflect.DeepEqual(ti.DBRPs, dbrps) {
+		t.Fatalf("unexpected dbrps got %s exp %s", ti.DBRPs, dbrps)
+	}
+	if ti.TICKscript != tick {
+		t.Fatalf("unexpected TICKscript got %s exp %s", ti.TICKscript, tick)
+	}
+}
+
+func TestServer_LintTask(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	tick := `dbrp "mydb"."myrp"
+
+var unused = 10
+
+stream
+    |from()
+        .measurement('cpu')
+    |where(lambda: "usage_idel" < 10.0)
+`
+	result, err := cli.LintTask(client.LintTaskOptions{
+		TICKscript: tick,
+		Schema: &client.LintSchema{
+			Measurements: map[string]client.LintMeasurement{
+				"cpu": {
+					Fields: map[string]string{"usage_idle": "float"},
+					Tags:   []string{"host"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.LintResult{
+		Problems: []client.LintProblem{
+			{Line: 3, Char: 1, Message: "var unused is declared but never used"},
+			{Line: 8, Char: 12, Message: `unknown field or tag "usage_idel", did you mean "usage_idle"?`},
+		},
+	}
+	if !reflect.DeepEqual(result, exp) {
+		t.Errorf("unexpected lint result:\ngot\n%v\nexp\n%v", result, exp)
+	}
+
+	// Linting does not create a task
+	tasks, err := cli.ListTasks(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("unexpected tasks after lint: %v", tasks)
+	}
+}
+
+func TestServer_EvaluateTask(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	tick := `dbrp "mydb"."myrp"
+
+stream
+    |from()
+        .measurement('cpu')
+    |where(lambda: "usage_idle" < 20.0)
+    |alert()
+        .id('{{ index .Tags "host" }}')
+        .crit(lambda: "usage_idle" < 10.0)
+    |influxDBOut()
+        .database('out')
+        .retentionPolicy('autogen')
+`
+	points := `cpu,host=serverA usage_idle=50 1490000000000000000
+cpu,host=serverA usage_idle=15 1490000010000000000
+cpu,host=serverB usage_idle=5 1490000020000000000
+`
+	result, err := cli.EvaluateTask(client.EvaluateTaskOptions{
+		TICKscript: tick,
+		Points:     points,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	t2 := time.Unix(0, 1490000020000000000).UTC()
+	expPoints := []client.EvaluatePoint{{
+		Database:        "out",
+		RetentionPolicy: "autogen",
+		Name:            "cpu",
+		Tags:            map[string]string{"host": "serverB"},
+		Fields:          map[string]interface{}{"usage_idle": 5.0},
+		Time:            t2,
+	}}
+	if !reflect.DeepEqual(result.Points, expPoints) {
+		t.Errorf("unexpected points:\ngot\n%v\nexp\n%v", result.Points, expPoints)
+	}
+	if got, exp := len(result.Alerts), 1; got != exp {
+		t.Fatalf("unexpected number of alerts got %d exp %d", got, exp)
+	}
+	if got, exp := result.Alerts[0].ID, "serverB"; got != exp {
+		t.Errorf("unexpected alert ID got %s exp %s", got, exp)
+	}
+	if got, exp := result.Alerts[0].State.Level, "CRITICAL"; got != exp {
+		t.Errorf("unexpected alert level got %s exp %s", got, exp)
+	}
+	expTraces := []struct {
+		parent, child string
+		points        int
+	}{
+		{parent: "stream0", child: "from1", points: 3},
+		{parent: "from1", child: "where2", points: 3},
+		{parent: "where2", child: "alert3", points: 2},
+		{parent: "alert3", child: "influxdb_out4", points: 1},
+	}
+	if got, exp := len(result.Traces), len(expTraces); got != exp {
+		t.Fatalf("unexpected number of traces got %d exp %d", got, exp)
+	}
+	for i, exp := range expTraces {
+		got := result.Traces[i]
+		if got.Parent != exp.parent || got.Child != exp.child || len(got.Points) != exp.points {
+			t.Errorf("unexpected trace %d got %s -> %s with %d points exp %s -> %s with %d points",
+				i, got.Parent, got.Child, len(got.Points), exp.parent, exp.child, exp.points)
+		}
+	}
+
+	// Lambda expressions are evaluated against each point in order.
+	result, err = cli.EvaluateTask(client.EvaluateTaskOptions{
+		Lambda: `delta("usage_idle")`,
+		Points: points,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expValues := []client.EvaluateValue{
+		{Value: 0.0},
+		{Value: -35.0},
+		{Value: -10.0},
+	}
+	if !reflect.DeepEqual(result.Values, expValues) {
+		t.Errorf("unexpected values:\ngot\n%v\nexp\n%v", result.Values, expValues)
+	}
+
+	// Evaluating does not create a task
+	tasks, err := cli.ListTasks(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("unexpected tasks after evaluate: %v", tasks)
+	}
+}
+
+func TestServer_CreateTask_ConflictsWithTICKscript(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	tick := `dbrp "mydb"."myrp"
+
+stream
+    |from()
+        .measurement('test')
+`
+	testCases := []struct {
+		ttype client.TaskType
+		dbrps []client.DBRP
+		exp   string
+	}{
+		{
+			ttype: client.BatchTask,
+			exp:   "task type batch conflicts with type stream of the TICKscript",
+		},
+		{
+			dbrps: []client.DBRP{{Database: "otherdb", RetentionPolicy: "default"}},
+			exp:   "dbrps conflict with the dbrp statements of the TICKscript",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         "testTaskID",
+			Type:       tc.ttype,
+			DBRPs:      tc.dbrps,
+			TICKscript: tick,
+		})
+		if err == nil {
+			t.Fatalf("expected error %q", tc.exp)
+		}
+		if got := err.Error(); got != tc.exp {
+			t.Errorf("unexpected error got %q exp %q", got, tc.exp)
+		}
+	}
+}
+
+func TestServer_Modules(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	module, err := cli.CreateModule(client.CreateModuleOptions{
+		ID: "shared/out.tick",
+		TICKscript: `var measurement = 'test'
+
+var out = fragment
+    |httpOut('out')
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.Version != 1 {
+		t.Fatalf("unexpected module version got %d exp 1", module.Version)
+	}
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:   "testTaskID",
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `import 'shared/out.tick'
+
+stream
+    |from()
+        .measurement(measurement)
+    @out()
+`,
+		Status: client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := map[string]int64{"shared/out.tick": 1}, task.Modules; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected task modules got %v exp %v", got, exp)
+	}
+	dot := "digraph testTaskID {\nstream0 -> from1;\nfrom1 -> http_out2;\n}"
+	if task.Dot != dot {
+		t.Fatalf("unexpected dot\ngot\n%s\nexp\n%s\n", task.Dot, dot)
+	}
+
+	// Updating the module records the new version on the task
+	module, err = cli.UpdateModule(module.Link, client.UpdateModuleOptions{
+		TICKscript: `var measurement = 'other'
+
+var out = fragment
+    |httpOut('out')
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.Version != 2 {
+		t.Fatalf("unexpected module version got %d exp 2", module.Version)
+	}
+	task, err = cli.Task(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := map[string]int64{"shared/out.tick": 2}, task.Modules; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected task modules got %v exp %v", got, exp)
+	}
+
+	// Updates that break a dependent task are rejected
+	_, err = cli.UpdateModule(module.Link, client.UpdateModuleOptions{
+		TICKscript: `var out = fragment
+    |httpOut('out')
+`,
+	})
+	if err == nil {
+		t.Fatal("expected error updating module that breaks a dependent task")
+	}
+	module, err = cli.Module(module.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.Version != 2 {
+		t.Fatalf("unexpected module version after failed update got %d exp 2", module.Version)
+	}
+
+	// Modules imported by tasks cannot be deleted
+	if err := cli.DeleteModule(module.Link); err == nil {
+		t.Fatal("expected error deleting module imported by a task")
+	}
+	if err := cli.DeleteTask(task.Link); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.DeleteModule(module.Link); err != nil {
+		t.Fatal(err)
+	}
+	modules, err := cli.ListModules(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 0 {
+		t.Fatalf("unexpected modules after delete: %v", modules)
+	}
+}
+
+func TestServer_ModulesTemplates(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	// Modules that cannot be evaluated are rejected
+	if _, err := cli.CreateModule(client.CreateModuleOptions{
+		ID:         "shared/bad.tick",
+		TICKscript: `var out = stream|unknownNode()`,
+	}); err == nil {
+		t.Fatal("expected error creating module that cannot be evaluated")
+	}
+
+	module, err := cli.CreateModule(client.CreateModuleOptions{
+		ID: "shared/out.tick",
+		TICKscript: `var out = fragment
+    |httpOut('out')
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:   "testTemplateID",
+		Type: client.StreamTask,
+		TICKscript: `import 'shared/out.tick'
+
+var measurement string
+
+stream
+    |from()
+        .measurement(measurement)
+    @out()
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := map[string]int64{"shared/out.tick": 1}, template.Modules; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected template modules got %v exp %v", got, exp)
+	}
+
+	// Updating the module records the new version on the template
+	if _, err := cli.UpdateModule(module.Link, client.UpdateModuleOptions{
+		TICKscript: `var out = fragment
+    |log()
+    |httpOut('out')
+`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	template, err = cli.Template(template.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := map[string]int64{"shared/out.tick": 2}, template.Modules; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected template modules got %v exp %v", got, exp)
+	}
+
+	// Updates that break a dependent template are rejected
+	if _, err := cli.UpdateModule(module.Link, client.UpdateModuleOptions{
+		TICKscript: `var other = fragment
+    |httpOut('out')
+`,
+	}); err == nil {
+		t.Fatal("expected error updating module that breaks a dependent template")
+	}
+
+	// Modules imported by templates cannot be deleted
+	if err := cli.DeleteModule(module.Link); err == nil {
+		t.Fatal("expected error deleting module imported by a template")
+	}
+	if err := cli.DeleteTemplate(template.Link); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.DeleteModule(module.Link); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestServer_EnableTask(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
@@ -1272,6 +1866,108 @@ func TestServer_DeleteTemplate(t *testing.T) {
 	}
 }
 
+func TestServer_TemplateTasks(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	tick := `var measurement string
+var period = 5m
+
+stream
+    |from()
+        .measurement(measurement)
+    |window()
+        .period(period)
+        .every(period)
+`
+	template, err := cli.CreateTemplate(client.CreateTemplateOptions{
+		ID:         "testTemplateID",
+		Type:       client.StreamTask,
+		TICKscript: tick,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbrps := []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}}
+	for id, vars := range map[string]client.Vars{
+		"cpu": {"measurement": {Type: client.VarString, Value: "cpu"}},
+		"mem": {
+			"measurement": {Type: client.VarString, Value: "mem"},
+			"period":      {Type: client.VarDuration, Value: 10 * time.Minute},
+		},
+	} {
+		if _, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         id,
+			TemplateID: template.ID,
+			DBRPs:      dbrps,
+			Vars:       vars,
+			Status:     client.Disabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks, err := cli.ListTemplateTasks(template.Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(tasks), 2; got != exp {
+		t.Fatalf("unexpected number of template tasks got %d exp %d", got, exp)
+	}
+	if got, exp := tasks[0].ID, "cpu"; got != exp {
+		t.Errorf("unexpected task ID got %s exp %s", got, exp)
+	}
+	if got, exp := tasks[1].Vars["measurement"].Value, "mem"; got != exp {
+		t.Errorf("unexpected measurement var got %v exp %v", got, exp)
+	}
+
+	// Changing the default period changes only the task that does not set it,
+	// a new required var makes both tasks invalid.
+	for _, tc := range []struct {
+		tick    string
+		changed []bool
+		invalid []bool
+	}{
+		{
+			tick:    strings.Replace(tick, "5m", "1m", 1),
+			changed: []bool{true, false},
+			invalid: []bool{false, false},
+		},
+		{
+			tick:    "var field string\n" + tick,
+			changed: []bool{false, false},
+			invalid: []bool{true, true},
+		},
+	} {
+		preview, err := cli.PreviewUpdateTemplate(template.Link, client.UpdateTemplateOptions{
+			TICKscript: tc.tick,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if preview.ScriptDiff == "" {
+			t.Error("expected template script diff")
+		}
+		for i, p := range preview.Tasks {
+			if p.Changed != tc.changed[i] {
+				t.Errorf("unexpected changed of task %s got %v exp %v: %s", p.ID, p.Changed, tc.changed[i], p.Diff)
+			}
+			if got := p.Error != ""; got != tc.invalid[i] {
+				t.Errorf("unexpected invalid of task %s got %v exp %v: %s", p.ID, got, tc.invalid[i], p.Error)
+			}
+		}
+	}
+
+	// The preview does not change the template.
+	ti, err := cli.Template(template.Link, &client.TemplateOptions{ScriptFormat: "raw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti.TICKscript != tick {
+		t.Errorf("unexpected template TICKscript after preview got %s exp %s", ti.TICKscript, tick)
+	}
+}
+
 func TestServer_CreateTaskFromTemplate(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
@@ -1429,35 +2125,197 @@ func TestServer_StreamTask(t *testing.T) {
 	nullResponse := `{"series":null}`
 	err = s.HTTPGetRetry(endpoint, nullResponse, 100, time.Millisecond*5)
 	if err != nil {
-		t.Error(err)
+		t.Error(err)
+	}
+
+	points := `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000003
+test value=1 0000000004
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+test value=1 0000000011
+`
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", points, v)
+
+	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
+	err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTask_RecreateDoesNotRestoreState(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testStreamTask"
+	createOpts := client.CreateTaskOptions{
+		ID:   id,
+		Type: client.StreamTask,
+		DBRPs: []client.DBRP{{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		}},
+		TICKscript: `stream
+    |from()
+        .measurement('test')
+    |window()
+        .period(10s)
+        .every(10s)
+        .align()
+    |count('value')
+    |httpOut('count')
+`,
+		Status: client.Enabled,
+	}
+	task, err := cli.CreateTask(createOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := url.Values{}
+	v.Add("precision", "s")
+	s.MustWrite("mydb", "myrp", `test value=1 0000000000
+test value=1 0000000001
+test value=1 0000000002
+test value=1 0000000003
+test value=1 0000000004
+`, v)
+
+	// Disabling the task saves a snapshot with the buffered points of the window.
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.DeleteTask(task.Link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.CreateTask(createOpts); err != nil {
+		t.Fatal(err)
+	}
+
+	s.MustWrite("mydb", "myrp", `test value=1 0000000005
+test value=1 0000000006
+test value=1 0000000007
+test value=1 0000000008
+test value=1 0000000009
+test value=1 0000000010
+`, v)
+
+	// Only the points written after the task was recreated are counted.
+	endpoint := fmt.Sprintf("%s/tasks/%s/count", s.URL(), id)
+	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",5]]}]}`
+	if err := s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_StreamTask_Limits(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testStreamTask"
+	ttype := client.StreamTask
+	dbrps := []client.DBRP{{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}}
+	tick := `stream
+    |from()
+        .measurement('test')
+        .groupBy('host')
+    |window()
+        .period(10s)
+        .every(10s)
+    |count('value')
+`
+	limits := client.TaskLimits{
+		MaxGroups:      2,
+		SampleInterval: client.Duration(10 * time.Millisecond),
+		Action:         client.LimitStop,
+	}
+
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       ttype,
+		DBRPs:      dbrps,
+		TICKscript: tick,
+		Status:     client.Disabled,
+		Limits:     &limits,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(task.Limits, limits) {
+		t.Fatalf("unexpected task limits got %+v exp %+v", task.Limits, limits)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	points := `test value=1 0000000000
-test value=1 0000000001
-test value=1 0000000001
-test value=1 0000000002
-test value=1 0000000002
-test value=1 0000000003
-test value=1 0000000003
-test value=1 0000000004
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000005
-test value=1 0000000006
-test value=1 0000000007
-test value=1 0000000008
-test value=1 0000000009
-test value=1 0000000010
-test value=1 0000000011
+	points := `test,host=serverA value=1 0000000000
+test,host=serverB value=1 0000000001
+test,host=serverC value=1 0000000002
 `
 	v := url.Values{}
 	v.Add("precision", "s")
 	s.MustWrite("mydb", "myrp", points, v)
 
-	exp := `{"series":[{"name":"test","columns":["time","count"],"values":[["1970-01-01T00:00:10Z",15]]}]}`
-	err = s.HTTPGetRetry(endpoint, exp, 100, time.Millisecond*5)
+	quarantined := false
+	for i := 0; i < 100 && !quarantined; i++ {
+		time.Sleep(10 * time.Millisecond)
+		task, err = cli.Task(task.Link, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		quarantined = task.Status == client.Quarantined
+	}
+	if !quarantined {
+		t.Fatalf("expected task to be quarantined, got status %v", task.Status)
+	}
+	if task.Executing {
+		t.Error("expected quarantined task not to be executing")
+	}
+	if !strings.Contains(task.Error, "task quarantined") {
+		t.Errorf("unexpected task error: %q", task.Error)
+	}
+
+	_, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Quarantined,
+	})
+	if err == nil {
+		t.Error("expected error quarantining a task")
+	}
+
+	task, err = cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if got, exp := task.Status, client.Enabled; got != exp {
+		t.Errorf("unexpected task status got %v exp %v", got, exp)
+	}
+	if !task.Executing {
+		t.Error("expected re-enabled task to be executing")
 	}
 }
 
@@ -2442,6 +3300,389 @@ func TestServer_UpdateTaskID_Enabled(t *testing.T) {
 	}
 }
 
+func TestServer_TaskRevisions(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	id := "testTaskID"
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+	}
+	tick1 := `stream
+    |from()
+        .measurement('test')
+`
+	tick2 := `stream
+    |from()
+        .measurement('test2')
+`
+	task, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       client.StreamTask,
+		DBRPs:      dbrps,
+		TICKscript: tick1,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changing the status does not create a revision.
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		Status: client.Enabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.UpdateTask(task.Link, client.UpdateTaskOptions{
+		TICKscript: tick2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := cli.ListTaskRevisions(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(revisions), 2; got != exp {
+		t.Fatalf("unexpected number of revisions got %d exp %d", got, exp)
+	}
+	for i, exp := range []struct {
+		revision int64
+		tick     string
+	}{{2, tick2}, {1, tick1}} {
+		r := revisions[i]
+		if r.Revision != exp.revision {
+			t.Errorf("unexpected revision got %d exp %d", r.Revision, exp.revision)
+		}
+		if r.TICKscript != exp.tick {
+			t.Errorf("unexpected TICKscript of revision %d got %s exp %s", r.Revision, r.TICKscript, exp.tick)
+		}
+		if r.Author != "ADMIN_USER" {
+			t.Errorf("unexpected author of revision %d got %s exp ADMIN_USER", r.Revision, r.Author)
+		}
+		if !reflect.DeepEqual(r.DBRPs, dbrps) {
+			t.Errorf("unexpected dbrps of revision %d got %s exp %s", r.Revision, r.DBRPs, dbrps)
+		}
+		if got, exp := r.Link.Href, fmt.Sprintf("/kapacitor/v1/tasks/testTaskID/revisions/%d", exp.revision); got != exp {
+			t.Errorf("unexpected link got %s exp %s", got, exp)
+		}
+	}
+
+	r, err := cli.TaskRevision(revisions[1].Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, revisions[1]) {
+		t.Errorf("unexpected revision got %v exp %v", r, revisions[1])
+	}
+
+	diff, err := cli.DiffTaskRevisions(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.From != 1 || diff.To != 2 {
+		t.Errorf("unexpected diff revisions got %d..%d exp 1..2", diff.From, diff.To)
+	}
+	if len(diff.Changes) != 0 {
+		t.Errorf("unexpected changes %v", diff.Changes)
+	}
+	expDiff := `--- revision 1
++++ revision 2
+@@ -1,3 +1,3 @@
+ stream
+     |from()
+-        .measurement('test')
++        .measurement('test2')
+`
+	if diff.ScriptDiff != expDiff {
+		t.Errorf("unexpected script diff\ngot\n%s\nexp\n%s", diff.ScriptDiff, expDiff)
+	}
+
+	ti, err := cli.RollbackTask(task.Link, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti.TICKscript != tick1 {
+		t.Errorf("unexpected TICKscript after rollback got %s exp %s", ti.TICKscript, tick1)
+	}
+	if ti.Status != client.Enabled {
+		t.Errorf("unexpected status after rollback got %v exp %v", ti.Status, client.Enabled)
+	}
+	revisions, err = cli.ListTaskRevisions(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(revisions), 3; got != exp {
+		t.Fatalf("unexpected number of revisions after rollback got %d exp %d", got, exp)
+	}
+	if revisions[0].Revision != 3 || revisions[0].TICKscript != tick1 {
+		t.Errorf("unexpected latest revision after rollback got %d %s", revisions[0].Revision, revisions[0].TICKscript)
+	}
+
+	if _, err := cli.RollbackTask(task.Link, 10); err == nil {
+		t.Error("expected error rolling back to an unknown revision")
+	}
+
+	// The revisions are deleted with the task.
+	if err := cli.DeleteTask(task.Link); err != nil {
+		t.Fatal(err)
+	}
+	task, err = cli.CreateTask(client.CreateTaskOptions{
+		ID:         id,
+		Type:       client.StreamTask,
+		DBRPs:      dbrps,
+		TICKscript: tick2,
+		Status:     client.Disabled,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err = cli.ListTaskRevisions(task.Link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Revision != 1 || revisions[0].TICKscript != tick2 {
+		t.Errorf("unexpected revisions of recreated task %v", revisions)
+	}
+}
+
+func TestServer_TaskGroups(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	dbrps := []client.DBRP{
+		{
+			Database:        "mydb",
+			RetentionPolicy: "myrp",
+		},
+	}
+	tasks := map[string]string{
+		"producer": `stream
+    |from()
+        .measurement('test')
+    |kapacitorLoopback()
+        .database('mydb')
+        .retentionPolicy('myrp')
+        .measurement('derived')
+`,
+		"consumer": `stream
+    |from()
+        .measurement('derived')
+`,
+		"alert": `stream
+    |from()
+        .measurement('other')
+`,
+	}
+	for id, tick := range tasks {
+		if _, err := cli.CreateTask(client.CreateTaskOptions{
+			ID:         id,
+			Type:       client.StreamTask,
+			DBRPs:      dbrps,
+			TICKscript: tick,
+			Status:     client.Disabled,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The consumer of the loopback data must be running before the producer,
+	// the declared dependency orders the alert task before the consumer.
+	group, err := cli.CreateTaskGroup(client.CreateTaskGroupOptions{
+		ID:           "testGroup",
+		Tasks:        []string{"producer", "consumer", "alert"},
+		Dependencies: map[string][]string{"consumer": {"alert"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := group.Order, []string{"alert", "consumer", "producer"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected order got %v exp %v", got, exp)
+	}
+
+	// A dependency cycle is rejected.
+	if _, err := cli.UpdateTaskGroup(group.Link, client.UpdateTaskGroupOptions{
+		Dependencies: map[string][]string{"consumer": {"producer"}},
+	}); err == nil {
+		t.Error("expected error for dependency cycle")
+	}
+
+	if _, err := cli.EnableTaskGroup(group.Link); err != nil {
+		t.Fatal(err)
+	}
+	for id := range tasks {
+		task, err := cli.Task(cli.TaskLink(id), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Status != client.Enabled {
+			t.Errorf("unexpected status of task %s got %v exp %v", id, task.Status, client.Enabled)
+		}
+	}
+	if _, err := cli.DisableTaskGroup(group.Link); err != nil {
+		t.Fatal(err)
+	}
+	for id := range tasks {
+		task, err := cli.Task(cli.TaskLink(id), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Status != client.Disabled {
+			t.Errorf("unexpected status of task %s got %v exp %v", id, task.Status, client.Disabled)
+		}
+	}
+
+	graph, err := cli.TaskGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expEdges := []client.TaskGraphEdge{
+		{From: "producer", To: "consumer", Inferred: true, Label: "mydb.myrp.derived"},
+		{From: "consumer", To: "alert"},
+	}
+	if !reflect.DeepEqual(graph.Edges, expEdges) {
+		t.Errorf("unexpected graph edges got %v exp %v", graph.Edges, expEdges)
+	}
+
+	// Deleting a task removes it from the group.
+	if err := cli.DeleteTask(cli.TaskLink("alert")); err != nil {
+		t.Fatal(err)
+	}
+	group, err = cli.TaskGroup(group.Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := group.Tasks, []string{"producer", "consumer"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected tasks got %v exp %v", got, exp)
+	}
+
+	if err := cli.DeleteTaskGroup(group.Link); err != nil {
+		t.Fatal(err)
+	}
+	groups, err := cli.ListTaskGroups(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(groups), 0; got != exp {
+		t.Errorf("unexpected number of task groups got %d exp %d", got, exp)
+	}
+}
+
+func TestServer_Apply(t *testing.T) {
+	s, cli := OpenDefaultServer()
+	defer s.Close()
+
+	// Defined outside of apply, pruned below.
+	if _, err := cli.CreateTask(client.CreateTaskOptions{
+		ID:         "unmanaged",
+		Type:       client.StreamTask,
+		DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+		TICKscript: "stream|from()\n",
+		Status:     client.Disabled,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	templateTick := `var measurement string
+stream
+    |from()
+        .measurement(measurement)
+`
+	opt := client.ApplyOptions{
+		Templates: []client.CreateTemplateOptions{{
+			ID:         "base",
+			Type:       client.StreamTask,
+			TICKscript: templateTick,
+		}},
+		Tasks: []client.CreateTaskOptions{
+			{
+				ID:         "cpu",
+				TemplateID: "base",
+				DBRPs:      []client.DBRP{{Database: "mydb", RetentionPolicy: "myrp"}},
+				Vars: client.Vars{
+					"measurement": {Type: client.VarString, Value: "cpu"},
+				},
+				Status: client.Enabled,
+			},
+		},
+		TopicHandlers: []client.ApplyTopicHandler{{
+			Topic: "cpu",
+			TopicHandlerOptions: client.TopicHandlerOptions{
+				ID:      "log",
+				Kind:    "log",
+				Options: map[string]interface{}{"path": "/tmp/cpu.log"},
+			},
+		}},
+		DryRun: true,
+	}
+
+	result, err := cli.Apply(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []string
+	for _, c := range result.Changes {
+		changes = append(changes, c.Action+" "+c.Kind+" "+c.ID)
+	}
+	if exp := []string{"create template base", "create task cpu", "create topic-handler cpu/log"}; !reflect.DeepEqual(changes, exp) {
+		t.Fatalf("unexpected changes got %v exp %v", changes, exp)
+	}
+	if _, err := cli.Task(cli.TaskLink("cpu"), nil); err == nil {
+		t.Fatal("expected dry run to not create the task")
+	}
+
+	opt.DryRun = false
+	opt.Prune = true
+	result, err = cli.Apply(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(result.Changes), 4; got != exp {
+		t.Fatalf("unexpected number of changes got %d exp %d: %v", got, exp, result.Changes)
+	}
+	task, err := cli.Task(cli.TaskLink("cpu"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.TemplateID != "base" || task.Status != client.Enabled || !task.Executing {
+		t.Errorf("unexpected task %v", task)
+	}
+	if _, err := cli.Task(cli.TaskLink("unmanaged"), nil); err == nil {
+		t.Error("expected the unmanaged task to be pruned")
+	}
+	if _, err := cli.TopicHandler(cli.TopicHandlerLink("cpu", "log")); err != nil {
+		t.Error(err)
+	}
+
+	// Applying the same definitions again changes nothing.
+	result, err = cli.Apply(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 0 {
+		t.Errorf("unexpected changes %v", result.Changes)
+	}
+
+	// A failed change reverts the changes already made.
+	opt.Tasks[0].Vars["measurement"] = client.Var{Type: client.VarString, Value: "mem"}
+	opt.Tasks = append(opt.Tasks, client.CreateTaskOptions{
+		ID:         "invalid",
+		TICKscript: "stream|from(",
+	})
+	if _, err := cli.Apply(opt); err == nil {
+		t.Fatal("expected error applying an invalid task")
+	}
+	task, err = cli.Task(cli.TaskLink("cpu"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := task.Vars["measurement"].Value, "cpu"; got != exp {
+		t.Errorf("unexpected var after failed apply got %v exp %v", got, exp)
+	}
+}
+
 func TestServer_StreamTask_AllMeasurements(t *testing.T) {
 	s, cli := OpenDefaultServer()
 	defer s.Close()
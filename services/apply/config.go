@@ -0,0 +1,8 @@
+package apply
+
+type Config struct {
+}
+
+func NewConfig() Config {
+	return Config{}
+}
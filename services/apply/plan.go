@@ -0,0 +1,643 @@
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	createAction = "create"
+	updateAction = "update"
+	deleteAction = "delete"
+
+	taskKind         = "task"
+	templateKind     = "template"
+	topicHandlerKind = "topic-handler"
+
+	// listLimit is the page size used to list the current definitions.
+	listLimit = 100
+)
+
+// A step makes a single change.
+// The returned undo function reverts the change, it is returned even when a later part of the step failed.
+// The commit function, if any, completes the change once all steps have been applied,
+// it is used for changes that cannot be reverted.
+type step struct {
+	change client.ApplyChange
+	apply  func() (undo func() error, err error)
+	commit func() error
+}
+
+// A plan is the list of steps that make the current definitions match the desired ones.
+type plan struct {
+	cli   *client.Client
+	steps []step
+}
+
+// revertError is returned when a step failed and the changes already made could not all be reverted.
+type revertError struct {
+	err        error
+	revertErrs []error
+}
+
+func (e revertError) Error() string {
+	msgs := make([]string, len(e.revertErrs))
+	for i, err := range e.revertErrs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%s, failed to revert the changes made: %s", e.err, strings.Join(msgs, ", "))
+}
+
+// commitError is returned when all steps were applied but some of them could not be completed.
+type commitError struct {
+	errs []error
+}
+
+func (e commitError) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// newPlan compares the current definitions with the desired ones.
+// Templates are changed first, so that tasks can use them, and objects are pruned last.
+func newPlan(cli *client.Client, opt client.ApplyOptions) (*plan, error) {
+	p := &plan{cli: cli}
+	templates, err := p.listTemplates()
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := p.listTasks()
+	if err != nil {
+		return nil, err
+	}
+	topics := make([]string, 0, len(opt.TopicHandlers))
+	for _, h := range opt.TopicHandlers {
+		topics = append(topics, h.Topic)
+	}
+	if opt.Prune {
+		all, err := cli.ListTopics(nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range all.Topics {
+			topics = append(topics, t.ID)
+		}
+	}
+	handlers, err := p.listTopicHandlers(topics)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range opt.Templates {
+		old, ok := templates[t.ID]
+		p.planTemplate(old, ok, t)
+		delete(templates, t.ID)
+	}
+	for _, t := range opt.Tasks {
+		old, ok := tasks[t.ID]
+		p.planTask(old, ok, t)
+		delete(tasks, t.ID)
+	}
+	for _, h := range opt.TopicHandlers {
+		id := handlerID(h.Topic, h.ID)
+		old, ok := handlers[id]
+		p.planTopicHandler(old, ok, h)
+		delete(handlers, id)
+	}
+	if !opt.Prune {
+		return p, nil
+	}
+
+	ids := make([]string, 0, len(tasks))
+	for id := range tasks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		p.pruneTask(tasks[id])
+	}
+	ids = ids[:0]
+	for id := range templates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		p.pruneTemplate(templates[id])
+	}
+	ids = ids[:0]
+	for id := range handlers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		p.pruneTopicHandler(handlers[id])
+	}
+	return p, nil
+}
+
+func (p *plan) changes() []client.ApplyChange {
+	changes := make([]client.ApplyChange, len(p.steps))
+	for i, s := range p.steps {
+		changes[i] = s.change
+	}
+	return changes
+}
+
+// execute applies all steps in order and then commits them.
+// If a step fails, the changes already made are reverted in reverse order.
+func (p *plan) execute() error {
+	var undos []func() error
+	for _, s := range p.steps {
+		undo, err := s.apply()
+		if undo != nil {
+			undos = append(undos, undo)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to %s %s %s: %s", s.change.Action, s.change.Kind, s.change.ID, err)
+			var revertErrs []error
+			for i := len(undos) - 1; i >= 0; i-- {
+				if uerr := undos[i](); uerr != nil {
+					revertErrs = append(revertErrs, uerr)
+				}
+			}
+			if len(revertErrs) > 0 {
+				return revertError{err: err, revertErrs: revertErrs}
+			}
+			return err
+		}
+	}
+	var commitErrs []error
+	for _, s := range p.steps {
+		if s.commit == nil {
+			continue
+		}
+		if err := s.commit(); err != nil {
+			commitErrs = append(commitErrs, fmt.Errorf("failed to %s %s %s: %s", s.change.Action, s.change.Kind, s.change.ID, err))
+		}
+	}
+	if len(commitErrs) > 0 {
+		return commitError{errs: commitErrs}
+	}
+	return nil
+}
+
+func (p *plan) add(action, kind, id string, changes []string, scriptDiff string, apply func() (func() error, error)) {
+	p.steps = append(p.steps, newStep(action, kind, id, changes, scriptDiff, apply))
+}
+
+func newStep(action, kind, id string, changes []string, scriptDiff string, apply func() (func() error, error)) step {
+	if changes == nil {
+		changes = []string{}
+	}
+	return step{
+		change: client.ApplyChange{
+			Action:     action,
+			Kind:       kind,
+			ID:         id,
+			Changes:    changes,
+			ScriptDiff: scriptDiff,
+		},
+		apply: apply,
+	}
+}
+
+// Templates
+
+func (p *plan) listTemplates() (map[string]client.Template, error) {
+	templates := make(map[string]client.Template)
+	for offset := 0; ; offset += listLimit {
+		list, err := p.cli.ListTemplates(&client.ListTemplatesOptions{
+			TemplateOptions: client.TemplateOptions{ScriptFormat: "raw"},
+			Offset:          offset,
+			Limit:           listLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range list {
+			templates[t.ID] = t
+		}
+		if len(list) < listLimit {
+			return templates, nil
+		}
+	}
+}
+
+func (p *plan) planTemplate(old client.Template, exists bool, t client.CreateTemplateOptions) {
+	cli := p.cli
+	link := cli.TemplateLink(t.ID)
+	if !exists {
+		p.add(createAction, templateKind, t.ID, nil, scriptDiff("", t.TICKscript), func() (func() error, error) {
+			if _, err := cli.CreateTemplate(t); err != nil {
+				return nil, err
+			}
+			return func() error {
+				return cli.DeleteTemplate(link)
+			}, nil
+		})
+		return
+	}
+	var changes []string
+	if t.Type != 0 && t.Type != old.Type {
+		changes = append(changes, fmt.Sprintf("type: %v -> %v", old.Type, t.Type))
+	}
+	if len(changes) == 0 && t.TICKscript == old.TICKscript {
+		return
+	}
+	p.add(updateAction, templateKind, t.ID, changes, scriptDiff(old.TICKscript, t.TICKscript), func() (func() error, error) {
+		if _, err := cli.UpdateTemplate(link, client.UpdateTemplateOptions{
+			Type:       t.Type,
+			TICKscript: t.TICKscript,
+		}); err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := cli.UpdateTemplate(link, client.UpdateTemplateOptions{
+				Type:       old.Type,
+				TICKscript: old.TICKscript,
+			})
+			return err
+		}, nil
+	})
+}
+
+func (p *plan) pruneTemplate(old client.Template) {
+	cli := p.cli
+	p.add(deleteAction, templateKind, old.ID, nil, scriptDiff(old.TICKscript, ""), func() (func() error, error) {
+		if err := cli.DeleteTemplate(old.Link); err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := cli.CreateTemplate(client.CreateTemplateOptions{
+				ID:         old.ID,
+				Type:       old.Type,
+				TICKscript: old.TICKscript,
+			})
+			return err
+		}, nil
+	})
+}
+
+// Tasks
+
+func (p *plan) listTasks() (map[string]client.Task, error) {
+	tasks := make(map[string]client.Task)
+	for offset := 0; ; offset += listLimit {
+		list, err := p.cli.ListTasks(&client.ListTasksOptions{
+			TaskOptions: client.TaskOptions{ScriptFormat: "raw"},
+			Offset:      offset,
+			Limit:       listLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range list {
+			tasks[t.ID] = t
+		}
+		if len(list) < listLimit {
+			return tasks, nil
+		}
+	}
+}
+
+func (p *plan) planTask(old client.Task, exists bool, t client.CreateTaskOptions) {
+	cli := p.cli
+	link := cli.TaskLink(t.ID)
+	if !exists {
+		diff := ""
+		if t.TemplateID == "" {
+			diff = scriptDiff("", t.TICKscript)
+		}
+		p.add(createAction, taskKind, t.ID, nil, diff, func() (func() error, error) {
+			if _, err := cli.CreateTask(t); err != nil {
+				return nil, err
+			}
+			return func() error {
+				return cli.DeleteTask(link)
+			}, nil
+		})
+		return
+	}
+	if t.Status == 0 {
+		t.Status = old.Status
+	}
+	changes := taskChanges(old, t)
+	diff := ""
+	if t.TemplateID == "" && t.TICKscript != old.TICKscript {
+		diff = scriptDiff(old.TICKscript, t.TICKscript)
+	}
+	definitionChanged := len(changes) > 0 || diff != ""
+	if t.Status != old.Status {
+		changes = append([]string{fmt.Sprintf("status: %v -> %v", old.Status, t.Status)}, changes...)
+	} else if !definitionChanged {
+		return
+	}
+
+	// The task is redefined in place so that it keeps its revisions and state,
+	// its template is unset and vars are removed if they are not defined.
+	p.add(updateAction, taskKind, t.ID, changes, diff, func() (func() error, error) {
+		opt := client.UpdateTaskOptions{
+			TemplateID: t.TemplateID,
+			Type:       t.Type,
+			DBRPs:      t.DBRPs,
+			Vars:       t.Vars,
+			Status:     t.Status,
+		}
+		if t.TemplateID == "" {
+			opt.TICKscript = t.TICKscript
+		}
+		if _, err := cli.RedefineTask(link, opt); err != nil {
+			return nil, err
+		}
+		restoreStatus := func() error {
+			if t.Status == old.Status {
+				return nil
+			}
+			_, err := cli.UpdateTask(link, client.UpdateTaskOptions{Status: old.Status})
+			return err
+		}
+		if !definitionChanged {
+			return restoreStatus, nil
+		}
+		// The previous revision is the definition before the update.
+		revisions, err := cli.ListTaskRevisions(link, &client.ListTaskRevisionsOptions{Limit: 2})
+		if err != nil {
+			return restoreStatus, err
+		}
+		if len(revisions) < 2 {
+			return restoreStatus, errors.New("missing revision of the previous definition")
+		}
+		previous := revisions[1].Revision
+		undo := func() error {
+			if _, err := cli.RollbackTask(link, previous); err != nil {
+				return err
+			}
+			return restoreStatus()
+		}
+		// Reload the task if it keeps running, the same as kapacitor define does.
+		if old.Status == client.Enabled && t.Status == client.Enabled {
+			if _, err := cli.UpdateTask(link, client.UpdateTaskOptions{Status: client.Disabled}); err != nil {
+				return undo, err
+			}
+			if _, err := cli.UpdateTask(link, client.UpdateTaskOptions{Status: client.Enabled}); err != nil {
+				return undo, err
+			}
+		}
+		return undo, nil
+	})
+}
+
+func (p *plan) pruneTask(old client.Task) {
+	cli := p.cli
+	diff := ""
+	if old.TemplateID == "" {
+		diff = scriptDiff(old.TICKscript, "")
+	}
+	// Deleting the task loses its revisions and state, so it cannot be reverted.
+	// The task is only disabled until all steps have been applied and is deleted once they are committed.
+	s := newStep(deleteAction, taskKind, old.ID, nil, diff, func() (func() error, error) {
+		if old.Status != client.Enabled {
+			return nil, nil
+		}
+		if _, err := cli.UpdateTask(old.Link, client.UpdateTaskOptions{Status: client.Disabled}); err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := cli.UpdateTask(old.Link, client.UpdateTaskOptions{Status: client.Enabled})
+			return err
+		}, nil
+	})
+	s.commit = func() error {
+		return cli.DeleteTask(old.Link)
+	}
+	p.steps = append(p.steps, s)
+}
+
+// taskChanges describes the changes of the task definition other than the TICKscript.
+// The type and dbrps are only compared if they are defined.
+func taskChanges(old client.Task, t client.CreateTaskOptions) []string {
+	var changes []string
+	if t.Type != 0 && t.Type != old.Type {
+		changes = append(changes, fmt.Sprintf("type: %v -> %v", old.Type, t.Type))
+	}
+	if t.TemplateID != old.TemplateID {
+		changes = append(changes, fmt.Sprintf("template-id: %q -> %q", old.TemplateID, t.TemplateID))
+	}
+	if len(t.DBRPs) > 0 && !dbrpsEqual(old.DBRPs, t.DBRPs) {
+		changes = append(changes, fmt.Sprintf("dbrps: %s -> %s", formatDBRPs(old.DBRPs), formatDBRPs(t.DBRPs)))
+	}
+	names := make([]string, 0, len(old.Vars)+len(t.Vars))
+	for name := range old.Vars {
+		names = append(names, name)
+	}
+	for name := range t.Vars {
+		if _, ok := old.Vars[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o, inOld := old.Vars[name]
+		n, inNew := t.Vars[name]
+		switch {
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("var %s: added %s", name, formatVar(n)))
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("var %s: removed", name))
+		case o.Type != n.Type || formatVar(o) != formatVar(n):
+			changes = append(changes, fmt.Sprintf("var %s: %s -> %s", name, formatVar(o), formatVar(n)))
+		}
+	}
+	return changes
+}
+
+func dbrpsEqual(a, b []client.DBRP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func formatDBRPs(dbrps []client.DBRP) string {
+	s := make([]string, len(dbrps))
+	for i, dbrp := range dbrps {
+		s[i] = fmt.Sprintf("%q.%q", dbrp.Database, dbrp.RetentionPolicy)
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+func formatVar(v client.Var) string {
+	switch value := v.Value.(type) {
+	case []client.Var:
+		values := make([]string, len(value))
+		for i, l := range value {
+			values[i] = formatVar(l)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case time.Duration:
+		return value.String()
+	case nil:
+		return v.Type.String()
+	}
+	switch v.Type {
+	case client.VarRegex:
+		return fmt.Sprintf("/%v/", v.Value)
+	case client.VarLambda:
+		return fmt.Sprintf("lambda: %v", v.Value)
+	case client.VarString:
+		return fmt.Sprintf("%q", v.Value)
+	}
+	return fmt.Sprintf("%v", v.Value)
+}
+
+// Topic handlers
+
+func handlerID(topic, id string) string {
+	return topic + "/" + id
+}
+
+// A topicHandler is a handler and the topic it belongs to.
+type topicHandler struct {
+	client.TopicHandler
+	topic string
+}
+
+func (p *plan) listTopicHandlers(topics []string) (map[string]topicHandler, error) {
+	handlers := make(map[string]topicHandler)
+	listed := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		if listed[topic] {
+			continue
+		}
+		listed[topic] = true
+		list, err := p.cli.ListTopicHandlers(p.cli.TopicHandlersLink(topic), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range list.Handlers {
+			handlers[handlerID(topic, h.ID)] = topicHandler{TopicHandler: h, topic: topic}
+		}
+	}
+	return handlers, nil
+}
+
+func (p *plan) planTopicHandler(old topicHandler, exists bool, h client.ApplyTopicHandler) {
+	cli := p.cli
+	id := handlerID(h.Topic, h.ID)
+	link := cli.TopicHandlerLink(h.Topic, h.ID)
+	if !exists {
+		p.add(createAction, topicHandlerKind, id, nil, "", func() (func() error, error) {
+			if _, err := cli.CreateTopicHandler(cli.TopicHandlersLink(h.Topic), h.TopicHandlerOptions); err != nil {
+				return nil, err
+			}
+			return func() error {
+				return cli.DeleteTopicHandler(link)
+			}, nil
+		})
+		return
+	}
+	var changes []string
+	if h.Kind != old.Kind {
+		changes = append(changes, fmt.Sprintf("kind: %s -> %s", old.Kind, h.Kind))
+	}
+	if h.Match != old.Match {
+		changes = append(changes, fmt.Sprintf("match: %q -> %q", old.Match, h.Match))
+	}
+	if o, n := formatOptions(old.Options), formatOptions(h.Options); o != n {
+		changes = append(changes, fmt.Sprintf("options: %s -> %s", o, n))
+	}
+	if len(changes) == 0 {
+		return
+	}
+	p.add(updateAction, topicHandlerKind, id, changes, "", func() (func() error, error) {
+		if _, err := cli.ReplaceTopicHandler(link, h.TopicHandlerOptions); err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := cli.ReplaceTopicHandler(link, handlerDefinition(old.TopicHandler))
+			return err
+		}, nil
+	})
+}
+
+func (p *plan) pruneTopicHandler(old topicHandler) {
+	cli := p.cli
+	p.add(deleteAction, topicHandlerKind, handlerID(old.topic, old.ID), nil, "", func() (func() error, error) {
+		if err := cli.DeleteTopicHandler(old.Link); err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := cli.CreateTopicHandler(cli.TopicHandlersLink(old.topic), handlerDefinition(old.TopicHandler))
+			return err
+		}, nil
+	})
+}
+
+// handlerDefinition returns the options that create the handler.
+func handlerDefinition(h client.TopicHandler) client.TopicHandlerOptions {
+	return client.TopicHandlerOptions{
+		ID:      h.ID,
+		Kind:    h.Kind,
+		Options: h.Options,
+		Match:   h.Match,
+	}
+}
+
+// formatOptions returns the options as JSON with sorted keys, so that equal options format the same.
+func formatOptions(options map[string]interface{}) string {
+	if len(options) == 0 {
+		return "{}"
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Sprintf("%v", options)
+	}
+	return string(data)
+}
+
+// TICKscripts
+
+// scriptDiff returns the unified diff of the TICKscripts, it is empty if they are equal.
+func scriptDiff(old, new string) string {
+	if old == new {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := difflib.WriteUnifiedDiff(&buf, difflib.UnifiedDiff{
+		A:        scriptLines(old),
+		B:        scriptLines(new),
+		FromFile: "current",
+		ToFile:   "desired",
+		Context:  3,
+	}); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+// scriptLines splits the script into lines that end with a newline.
+func scriptLines(script string) []string {
+	lines := strings.SplitAfter(script, "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n"
+	}
+	return lines
+}
@@ -0,0 +1,245 @@
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pkg/errors"
+)
+
+const (
+	applyPath = "/apply"
+)
+
+// Service reconciles the tasks, templates and topic handlers with a desired set of definitions.
+// The changes are made through the API so that they are authorized and audited as the changes of the caller.
+type Service struct {
+	// mu serializes applies, so that a plan is not made stale by a concurrent apply.
+	mu     sync.Mutex
+	routes []httpd.Route
+
+	// APIHandler serves the API requests made to read and change the definitions.
+	APIHandler http.Handler
+
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+
+	logger *log.Logger
+}
+
+func NewService(c Config, l *log.Logger) *Service {
+	return &Service{
+		logger: l,
+	}
+}
+
+func (s *Service) Open() error {
+	// Define API routes
+	s.routes = []httpd.Route{
+		{
+			Method:      "POST",
+			Pattern:     applyPath,
+			HandlerFunc: s.handleApply,
+		},
+	}
+
+	err := s.HTTPDService.AddRoutes(s.routes)
+	return errors.Wrap(err, "failed to add API routes")
+}
+
+func (s *Service) Close() error {
+	s.HTTPDService.DelRoutes(s.routes)
+	return nil
+}
+
+func (s *Service) handleApply(w http.ResponseWriter, r *http.Request) {
+	opt := client.ApplyOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	if err := validate(opt); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	cli, err := s.newClient(r)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := newPlan(cli, opt)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to read the current definitions: %s", err), true, http.StatusBadRequest)
+		return
+	}
+	if !opt.DryRun {
+		if err := p.execute(); err != nil {
+			code := http.StatusBadRequest
+			switch err.(type) {
+			case revertError, commitError:
+				code = http.StatusInternalServerError
+			}
+			s.logger.Println("E! failed to apply definitions:", err)
+			httpd.HttpError(w, err.Error(), true, code)
+			return
+		}
+	}
+	result := client.ApplyResult{
+		Changes: p.changes(),
+		DryRun:  opt.DryRun,
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(result, true))
+}
+
+// validate checks that the definitions have IDs and are defined only once.
+func validate(opt client.ApplyOptions) error {
+	templates := make(map[string]bool, len(opt.Templates))
+	for _, t := range opt.Templates {
+		if t.ID == "" {
+			return errors.New("template ID must not be empty")
+		}
+		if templates[t.ID] {
+			return fmt.Errorf("template %s is defined more than once", t.ID)
+		}
+		templates[t.ID] = true
+	}
+	tasks := make(map[string]bool, len(opt.Tasks))
+	for _, t := range opt.Tasks {
+		if t.ID == "" {
+			return errors.New("task ID must not be empty")
+		}
+		if tasks[t.ID] {
+			return fmt.Errorf("task %s is defined more than once", t.ID)
+		}
+		tasks[t.ID] = true
+	}
+	handlers := make(map[string]bool, len(opt.TopicHandlers))
+	for _, h := range opt.TopicHandlers {
+		if h.Topic == "" || h.ID == "" {
+			return errors.New("topic and ID of topic handlers must not be empty")
+		}
+		id := handlerID(h.Topic, h.ID)
+		if handlers[id] {
+			return fmt.Errorf("topic handler %s is defined more than once", id)
+		}
+		handlers[id] = true
+	}
+	return nil
+}
+
+// newClient returns a client that makes its requests with the credentials of r.
+func (s *Service) newClient(r *http.Request) (*client.Client, error) {
+	t := &handlerTransport{
+		handler:       s.APIHandler,
+		authorization: r.Header.Get("Authorization"),
+		remoteAddr:    r.RemoteAddr,
+	}
+	if u, p := r.URL.Query().Get("u"), r.URL.Query().Get("p"); u != "" && p != "" {
+		t.credentials = url.Values{
+			"u": []string{u},
+			"p": []string{p},
+		}
+	}
+	return client.New(client.Config{
+		URL:       "http://localhost",
+		UserAgent: "KapacitorApply",
+		Transport: t,
+	})
+}
+
+// handlerTransport serves the requests of a client with the API handler directly,
+// instead of sending them over the network.
+type handlerTransport struct {
+	handler       http.Handler
+	authorization string
+	credentials   url.Values
+	remoteAddr    string
+}
+
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	if t.authorization != "" {
+		r.Header.Set("Authorization", t.authorization)
+	}
+	if len(t.credentials) > 0 {
+		u := *req.URL
+		q := u.Query()
+		for k, v := range t.credentials {
+			q[k] = v
+		}
+		u.RawQuery = q.Encode()
+		r.URL = &u
+	}
+	if r.Body == nil {
+		r.Body = ioutil.NopCloser(strings.NewReader(""))
+	}
+	r.RequestURI = r.URL.RequestURI()
+	r.RemoteAddr = t.remoteAddr
+
+	w := &responseRecorder{header: make(http.Header)}
+	t.handler.ServeHTTP(w, r)
+	return w.response(req), nil
+}
+
+// responseRecorder records the response of the API handler.
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *responseRecorder) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// response returns the recorded response to the request.
+func (w *responseRecorder) response(req *http.Request) *http.Response {
+	w.WriteHeader(http.StatusOK)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.code, http.StatusText(w.code)),
+		StatusCode:    w.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          ioutil.NopCloser(bytes.NewReader(w.body.Bytes())),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}
+}
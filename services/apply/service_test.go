@@ -0,0 +1,500 @@
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	client "github.com/influxdata/kapacitor/client/v1"
+)
+
+// fakeAPI is an in memory implementation of the task, template and topic handler API.
+type fakeAPI struct {
+	templates map[string]client.Template
+	tasks     map[string]client.Task
+	revisions map[string][]client.Task
+	handlers  map[string]map[string]client.TopicHandler
+	// failTask fails the creation of the task with the ID.
+	failTask string
+	// authorization of the requests.
+	authorization []string
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		templates: make(map[string]client.Template),
+		tasks:     make(map[string]client.Task),
+		revisions: make(map[string][]client.Task),
+		handlers:  make(map[string]map[string]client.TopicHandler),
+	}
+}
+
+const (
+	tasksPrefix    = "/kapacitor/v1/tasks"
+	templatePrefix = "/kapacitor/v1/templates"
+	topicsPrefix   = "/kapacitor/v1preview/alerts/topics"
+)
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.authorization = append(a.authorization, r.Header.Get("Authorization"))
+	var err error
+	switch p := r.URL.Path; {
+	case strings.HasPrefix(p, tasksPrefix):
+		err = a.serveTasks(w, r, strings.Trim(strings.TrimPrefix(p, tasksPrefix), "/"))
+	case strings.HasPrefix(p, templatePrefix):
+		err = a.serveTemplates(w, r, strings.Trim(strings.TrimPrefix(p, templatePrefix), "/"))
+	case strings.HasPrefix(p, topicsPrefix):
+		err = a.serveTopics(w, r, strings.Trim(strings.TrimPrefix(p, topicsPrefix), "/"))
+	default:
+		err = fmt.Errorf("unexpected request %s %s", r.Method, p)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+}
+
+func (a *fakeAPI) serveTasks(w http.ResponseWriter, r *http.Request, id string) error {
+	switch {
+	case r.Method == "GET" && id == "":
+		list := []client.Task{}
+		if r.URL.Query().Get("offset") == "0" {
+			for _, t := range a.tasks {
+				list = append(list, t)
+			}
+		}
+		return json.NewEncoder(w).Encode(map[string][]client.Task{"tasks": list})
+	case r.Method == "GET" && strings.HasSuffix(id, "/revisions"):
+		revisions := a.revisions[strings.TrimSuffix(id, "/revisions")]
+		list := []client.TaskRevision{}
+		for i := len(revisions) - 1; i >= 0; i-- {
+			list = append(list, client.TaskRevision{Revision: int64(i + 1), Type: revisions[i].Type})
+		}
+		return json.NewEncoder(w).Encode(map[string][]client.TaskRevision{"revisions": list})
+	case r.Method == "POST" && strings.HasSuffix(id, "/rollback"):
+		id = strings.TrimSuffix(id, "/rollback")
+		var rev int
+		fmt.Sscan(r.URL.Query().Get("revision"), &rev)
+		t := a.revisions[id][rev-1]
+		t.Status = a.tasks[id].Status
+		a.setTask(t)
+		return json.NewEncoder(w).Encode(t)
+	case r.Method == "POST":
+		var opt client.CreateTaskOptions
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+			return err
+		}
+		if _, ok := a.tasks[opt.ID]; ok {
+			return fmt.Errorf("task %s already exists", opt.ID)
+		}
+		if opt.ID == a.failTask {
+			return fmt.Errorf("invalid task %s", opt.ID)
+		}
+		if opt.Status == 0 {
+			opt.Status = client.Disabled
+		}
+		if opt.TemplateID != "" {
+			opt.Type = a.templates[opt.TemplateID].Type
+		}
+		t := client.Task{
+			Link:       client.Link{Relation: client.Self, Href: tasksPrefix + "/" + opt.ID},
+			ID:         opt.ID,
+			TemplateID: opt.TemplateID,
+			Type:       opt.Type,
+			DBRPs:      opt.DBRPs,
+			TICKscript: opt.TICKscript,
+			Vars:       opt.Vars,
+			Status:     opt.Status,
+		}
+		a.setTask(t)
+		return json.NewEncoder(w).Encode(t)
+	case r.Method == "PATCH":
+		t, ok := a.tasks[id]
+		if !ok {
+			return fmt.Errorf("unknown task %s", id)
+		}
+		var opt client.UpdateTaskOptions
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+			return err
+		}
+		replace := r.URL.Query().Get("replace") == "true"
+		if opt.TemplateID != "" || replace {
+			t.TemplateID = opt.TemplateID
+		}
+		if opt.TICKscript != "" {
+			t.TICKscript = opt.TICKscript
+		}
+		if opt.Status != 0 {
+			t.Status = opt.Status
+		}
+		if len(opt.Vars) > 0 || replace {
+			t.Vars = opt.Vars
+		}
+		a.setTask(t)
+		return json.NewEncoder(w).Encode(t)
+	case r.Method == "DELETE":
+		delete(a.tasks, id)
+		delete(a.revisions, id)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return fmt.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+}
+
+// setTask saves the task and a revision if its definition changed.
+func (a *fakeAPI) setTask(t client.Task) {
+	revisions := a.revisions[t.ID]
+	if n := len(revisions); n == 0 || revisions[n-1].TICKscript != t.TICKscript || !reflect.DeepEqual(revisions[n-1].Vars, t.Vars) {
+		a.revisions[t.ID] = append(revisions, t)
+	}
+	a.tasks[t.ID] = t
+}
+
+func (a *fakeAPI) serveTemplates(w http.ResponseWriter, r *http.Request, id string) error {
+	switch r.Method {
+	case "GET":
+		list := []client.Template{}
+		if r.URL.Query().Get("offset") == "0" {
+			for _, t := range a.templates {
+				list = append(list, t)
+			}
+		}
+		return json.NewEncoder(w).Encode(map[string][]client.Template{"templates": list})
+	case "POST":
+		var opt client.CreateTemplateOptions
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+			return err
+		}
+		t := client.Template{
+			Link:       client.Link{Relation: client.Self, Href: templatePrefix + "/" + opt.ID},
+			ID:         opt.ID,
+			Type:       opt.Type,
+			TICKscript: opt.TICKscript,
+		}
+		a.templates[opt.ID] = t
+		return json.NewEncoder(w).Encode(t)
+	case "PATCH":
+		var opt client.UpdateTemplateOptions
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+			return err
+		}
+		t := a.templates[id]
+		t.TICKscript = opt.TICKscript
+		a.templates[id] = t
+		return json.NewEncoder(w).Encode(t)
+	case "DELETE":
+		delete(a.templates, id)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return fmt.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+}
+
+func (a *fakeAPI) serveTopics(w http.ResponseWriter, r *http.Request, p string) error {
+	parts := strings.Split(p, "/")
+	switch {
+	case r.Method == "GET" && p == "":
+		topics := client.Topics{}
+		for topic := range a.handlers {
+			topics.Topics = append(topics.Topics, client.Topic{ID: topic})
+		}
+		return json.NewEncoder(w).Encode(topics)
+	case r.Method == "GET" && len(parts) == 2:
+		handlers := client.TopicHandlers{Topic: parts[0]}
+		for _, h := range a.handlers[parts[0]] {
+			handlers.Handlers = append(handlers.Handlers, h)
+		}
+		return json.NewEncoder(w).Encode(handlers)
+	case (r.Method == "POST" && len(parts) == 2) || (r.Method == "PUT" && len(parts) == 3):
+		var opt client.TopicHandlerOptions
+		if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+			return err
+		}
+		h := client.TopicHandler{
+			Link:    client.Link{Relation: client.Self, Href: topicsPrefix + "/" + parts[0] + "/handlers/" + opt.ID},
+			ID:      opt.ID,
+			Kind:    opt.Kind,
+			Options: opt.Options,
+			Match:   opt.Match,
+		}
+		if a.handlers[parts[0]] == nil {
+			a.handlers[parts[0]] = make(map[string]client.TopicHandler)
+		}
+		a.handlers[parts[0]][opt.ID] = h
+		return json.NewEncoder(w).Encode(h)
+	case r.Method == "DELETE" && len(parts) == 3:
+		delete(a.handlers[parts[0]], parts[2])
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return fmt.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+}
+
+func newTestService(api http.Handler) *Service {
+	s := NewService(NewConfig(), log.New(os.Stderr, "[apply] ", log.LstdFlags))
+	s.APIHandler = api
+	return s
+}
+
+func doApply(t *testing.T, s *Service, opt client.ApplyOptions) (client.ApplyResult, int, string) {
+	data, err := json.Marshal(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/kapacitor/v1/apply", bytes.NewReader(data))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.handleApply(w, r)
+	result := client.ApplyResult{}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return result, w.Code, w.Body.String()
+}
+
+func TestService_Apply(t *testing.T) {
+	api := newFakeAPI()
+	api.templates["base"] = client.Template{
+		Link:       client.Link{Relation: client.Self, Href: templatePrefix + "/base"},
+		ID:         "base",
+		Type:       client.StreamTask,
+		TICKscript: "stream|from()\n",
+	}
+	api.setTask(client.Task{
+		Link:       client.Link{Relation: client.Self, Href: tasksPrefix + "/cpu"},
+		ID:         "cpu",
+		Type:       client.StreamTask,
+		TICKscript: "stream|from()\n",
+		Status:     client.Enabled,
+	})
+	api.setTask(client.Task{
+		Link:       client.Link{Relation: client.Self, Href: tasksPrefix + "/old"},
+		ID:         "old",
+		Type:       client.StreamTask,
+		TICKscript: "stream|from()\n",
+		Status:     client.Disabled,
+	})
+	api.handlers["cpu"] = map[string]client.TopicHandler{
+		"log": {
+			Link:    client.Link{Relation: client.Self, Href: topicsPrefix + "/cpu/handlers/log"},
+			ID:      "log",
+			Kind:    "log",
+			Options: map[string]interface{}{"path": "/tmp/cpu.log"},
+		},
+	}
+	s := newTestService(api)
+
+	opt := client.ApplyOptions{
+		Templates: []client.CreateTemplateOptions{{
+			ID:         "base",
+			Type:       client.StreamTask,
+			TICKscript: "stream|from()\n",
+		}},
+		Tasks: []client.CreateTaskOptions{
+			{
+				ID:         "cpu",
+				TICKscript: "stream|from().measurement('cpu')\n",
+				Status:     client.Enabled,
+			},
+			{
+				ID:         "mem",
+				TemplateID: "base",
+				Vars: client.Vars{
+					"warn": {Type: client.VarFloat, Value: 80.0},
+				},
+				Status: client.Enabled,
+			},
+		},
+		TopicHandlers: []client.ApplyTopicHandler{{
+			Topic: "cpu",
+			TopicHandlerOptions: client.TopicHandlerOptions{
+				ID:      "log",
+				Kind:    "log",
+				Options: map[string]interface{}{"path": "/var/log/cpu.log"},
+			},
+		}},
+		Prune:  true,
+		DryRun: true,
+	}
+	expChanges := []client.ApplyChange{
+		{
+			Action:     "update",
+			Kind:       "task",
+			ID:         "cpu",
+			Changes:    []string{},
+			ScriptDiff: "--- current\n+++ desired\n@@ -1 +1 @@\n-stream|from()\n+stream|from().measurement('cpu')\n",
+		},
+		{
+			Action:  "create",
+			Kind:    "task",
+			ID:      "mem",
+			Changes: []string{},
+		},
+		{
+			Action:  "update",
+			Kind:    "topic-handler",
+			ID:      "cpu/log",
+			Changes: []string{`options: {"path":"/tmp/cpu.log"} -> {"path":"/var/log/cpu.log"}`},
+		},
+		{
+			Action:     "delete",
+			Kind:       "task",
+			ID:         "old",
+			Changes:    []string{},
+			ScriptDiff: "--- current\n+++ desired\n@@ -1 +0,0 @@\n-stream|from()\n",
+		},
+	}
+
+	// Dry run
+	result, code, body := doApply(t, s, opt)
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status code got %d exp %d: %s", code, http.StatusOK, body)
+	}
+	if exp := (client.ApplyResult{Changes: expChanges, DryRun: true}); !reflect.DeepEqual(result, exp) {
+		t.Fatalf("unexpected dry run result:\ngot\n%+v\nexp\n%+v", result, exp)
+	}
+	if _, ok := api.tasks["mem"]; ok {
+		t.Fatal("dry run must not create tasks")
+	}
+
+	// Apply
+	opt.DryRun = false
+	result, code, body = doApply(t, s, opt)
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status code got %d exp %d: %s", code, http.StatusOK, body)
+	}
+	if exp := (client.ApplyResult{Changes: expChanges}); !reflect.DeepEqual(result, exp) {
+		t.Fatalf("unexpected result:\ngot\n%+v\nexp\n%+v", result, exp)
+	}
+	if got, exp := api.tasks["cpu"].TICKscript, "stream|from().measurement('cpu')\n"; got != exp {
+		t.Errorf("unexpected TICKscript got %q exp %q", got, exp)
+	}
+	if got, exp := api.tasks["mem"].TemplateID, "base"; got != exp {
+		t.Errorf("unexpected template ID got %q exp %q", got, exp)
+	}
+	if _, ok := api.tasks["old"]; ok {
+		t.Error("expected task old to be pruned")
+	}
+	if got, exp := api.handlers["cpu"]["log"].Options["path"], "/var/log/cpu.log"; got != exp {
+		t.Errorf("unexpected handler path got %v exp %v", got, exp)
+	}
+	for _, a := range api.authorization {
+		if a != "Bearer secret" {
+			t.Fatalf("unexpected authorization got %q exp %q", a, "Bearer secret")
+		}
+	}
+
+	// Applying again changes nothing
+	result, code, body = doApply(t, s, opt)
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status code got %d exp %d: %s", code, http.StatusOK, body)
+	}
+	if len(result.Changes) != 0 {
+		t.Errorf("unexpected changes %+v", result.Changes)
+	}
+}
+
+func TestService_Apply_Revert(t *testing.T) {
+	api := newFakeAPI()
+	api.setTask(client.Task{
+		Link:       client.Link{Relation: client.Self, Href: tasksPrefix + "/cpu"},
+		ID:         "cpu",
+		Type:       client.StreamTask,
+		TICKscript: "stream|from()\n",
+		Status:     client.Disabled,
+	})
+	api.failTask = "invalid"
+	s := newTestService(api)
+
+	_, code, body := doApply(t, s, client.ApplyOptions{
+		Templates: []client.CreateTemplateOptions{{
+			ID:         "base",
+			Type:       client.StreamTask,
+			TICKscript: "stream|from()\n",
+		}},
+		Tasks: []client.CreateTaskOptions{
+			{
+				ID:         "cpu",
+				TICKscript: "stream|from().measurement('cpu')\n",
+				Status:     client.Enabled,
+			},
+			{
+				ID:         "invalid",
+				TICKscript: "stream|from(\n",
+			},
+		},
+	})
+	if code != http.StatusBadRequest {
+		t.Fatalf("unexpected status code got %d exp %d: %s", code, http.StatusBadRequest, body)
+	}
+	if exp := "failed to create task invalid: invalid task invalid"; !strings.Contains(body, exp) {
+		t.Errorf("unexpected error got %s exp %s", body, exp)
+	}
+	if _, ok := api.templates["base"]; ok {
+		t.Error("expected the created template to be deleted")
+	}
+	cpu := api.tasks["cpu"]
+	if got, exp := cpu.TICKscript, "stream|from()\n"; got != exp {
+		t.Errorf("unexpected TICKscript got %q exp %q", got, exp)
+	}
+	if got, exp := cpu.Status, client.Disabled; got != exp {
+		t.Errorf("unexpected status got %v exp %v", got, exp)
+	}
+}
+
+func TestService_Apply_Redefine(t *testing.T) {
+	api := newFakeAPI()
+	api.templates["base"] = client.Template{
+		Link:       client.Link{Relation: client.Self, Href: templatePrefix + "/base"},
+		ID:         "base",
+		Type:       client.StreamTask,
+		TICKscript: "stream|from()\n",
+	}
+	api.setTask(client.Task{
+		Link:       client.Link{Relation: client.Self, Href: tasksPrefix + "/mem"},
+		ID:         "mem",
+		TemplateID: "base",
+		Type:       client.StreamTask,
+		TICKscript: "stream|from()\n",
+		Vars: client.Vars{
+			"warn": {Type: client.VarFloat, Value: 80.0},
+		},
+		Status: client.Disabled,
+	})
+	s := newTestService(api)
+
+	_, code, body := doApply(t, s, client.ApplyOptions{
+		Templates: []client.CreateTemplateOptions{{
+			ID:         "base",
+			Type:       client.StreamTask,
+			TICKscript: "stream|from()\n",
+		}},
+		Tasks: []client.CreateTaskOptions{{
+			ID:         "mem",
+			TICKscript: "stream|from().measurement('mem')\n",
+		}},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status code got %d exp %d: %s", code, http.StatusOK, body)
+	}
+	mem := api.tasks["mem"]
+	if mem.TemplateID != "" {
+		t.Errorf("expected the template to be unset got %q", mem.TemplateID)
+	}
+	if len(mem.Vars) != 0 {
+		t.Errorf("expected the vars to be removed got %v", mem.Vars)
+	}
+	// The task is updated in place, so it keeps its revisions.
+	if got, exp := len(api.revisions["mem"]), 2; got != exp {
+		t.Errorf("unexpected number of revisions got %d exp %d", got, exp)
+	}
+}
@@ -0,0 +1,138 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	hourLayout = "15:04"
+
+	DefaultTimezone      = "UTC"
+	DefaultBusinessHours = "09:00-17:00"
+)
+
+var DefaultBusinessDays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
+
+// calendarsFile is the structure of a calendar file.
+type calendarsFile struct {
+	Calendars []CalendarConfig `toml:"calendar"`
+}
+
+// CalendarConfig defines a calendar in a calendar file.
+type CalendarConfig struct {
+	Name string `toml:"name"`
+	// Timezone of the calendar, i.e. the zone of its holidays and business hours.
+	Timezone string `toml:"timezone"`
+	// Holidays as dates formatted YYYY-MM-DD.
+	Holidays []string `toml:"holidays"`
+	// Names of the week days that are business days.
+	BusinessDays []string `toml:"business-days"`
+	// Business hours of a business day formatted HH:MM-HH:MM.
+	BusinessHours string `toml:"business-hours"`
+}
+
+// Calendar answers whether a time is a holiday or within business hours.
+type Calendar struct {
+	location     *time.Location
+	holidays     map[string]bool
+	businessDays [7]bool
+	// Start and end of the business hours in minutes since midnight.
+	start, end int
+}
+
+// NewCalendar creates a calendar from its configuration.
+func NewCalendar(c CalendarConfig) (*Calendar, error) {
+	if c.Name == "" {
+		return nil, errors.New("must specify calendar name")
+	}
+	tz := c.Timezone
+	if tz == "" {
+		tz = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid timezone for calendar %q", c.Name)
+	}
+	cal := &Calendar{
+		location: loc,
+		holidays: make(map[string]bool, len(c.Holidays)),
+	}
+	for _, h := range c.Holidays {
+		d, err := time.Parse(dateLayout, h)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid holiday for calendar %q", c.Name)
+		}
+		cal.holidays[d.Format(dateLayout)] = true
+	}
+	days := c.BusinessDays
+	if days == nil {
+		days = DefaultBusinessDays
+	}
+	for _, day := range days {
+		wd, ok := weekday(day)
+		if !ok {
+			return nil, fmt.Errorf("invalid business day %q for calendar %q", day, c.Name)
+		}
+		cal.businessDays[wd] = true
+	}
+	hours := c.BusinessHours
+	if hours == "" {
+		hours = DefaultBusinessHours
+	}
+	cal.start, cal.end, err = parseHours(hours)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid business hours for calendar %q", c.Name)
+	}
+	return cal, nil
+}
+
+// IsHoliday reports whether t falls on a holiday of the calendar.
+func (c *Calendar) IsHoliday(t time.Time) bool {
+	return c.holidays[t.In(c.location).Format(dateLayout)]
+}
+
+// IsBusinessHours reports whether t is within the business hours of a business day
+// that is not a holiday.
+func (c *Calendar) IsBusinessHours(t time.Time) bool {
+	t = t.In(c.location)
+	if !c.businessDays[t.Weekday()] || c.holidays[t.Format(dateLayout)] {
+		return false
+	}
+	m := t.Hour()*60 + t.Minute()
+	return c.start <= m && m < c.end
+}
+
+func weekday(name string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseHours parses a range of hours formatted HH:MM-HH:MM
+// into minutes since midnight.
+func parseHours(hours string) (int, int, error) {
+	parts := strings.Split(hours, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected HH:MM-HH:MM got %q", hours)
+	}
+	var minutes [2]int
+	for i, p := range parts {
+		h, err := time.Parse(hourLayout, strings.TrimSpace(p))
+		if err != nil {
+			return 0, 0, err
+		}
+		minutes[i] = h.Hour()*60 + h.Minute()
+	}
+	if minutes[0] >= minutes[1] {
+		return 0, 0, fmt.Errorf("business hours must end after they start, got %q", hours)
+	}
+	return minutes[0], minutes[1], nil
+}
@@ -0,0 +1,87 @@
+package calendar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/kapacitor/services/calendar"
+)
+
+func TestCalendar(t *testing.T) {
+	var c calendar.CalendarConfig
+	if _, err := toml.Decode(`
+name = "office"
+timezone = "Europe/Berlin"
+holidays = ["2017-04-14"]
+business-hours = "08:30-18:00"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+	cal, err := calendar.NewCalendar(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		time          string
+		holiday       bool
+		businessHours bool
+	}{
+		// Thursday 08:29 in Berlin
+		{time: "2017-04-13T06:29:00Z"},
+		// Thursday 08:30 in Berlin
+		{time: "2017-04-13T06:30:00Z", businessHours: true},
+		// Thursday 18:00 in Berlin
+		{time: "2017-04-13T16:00:00Z"},
+		// Friday 12:00 in Berlin, a holiday
+		{time: "2017-04-14T10:00:00Z", holiday: true},
+		// Thursday 23:00 UTC is Friday in Berlin, a holiday
+		{time: "2017-04-13T23:00:00Z", holiday: true},
+		// Saturday 12:00 in Berlin
+		{time: "2017-04-15T10:00:00Z"},
+	}
+	for _, tc := range testCases {
+		tm, err := time.Parse(time.RFC3339, tc.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cal.IsHoliday(tm); got != tc.holiday {
+			t.Errorf("%s: unexpected IsHoliday got %t exp %t", tc.time, got, tc.holiday)
+		}
+		if got := cal.IsBusinessHours(tm); got != tc.businessHours {
+			t.Errorf("%s: unexpected IsBusinessHours got %t exp %t", tc.time, got, tc.businessHours)
+		}
+	}
+}
+
+func TestNewCalendar_Errors(t *testing.T) {
+	testCases := []struct {
+		c   calendar.CalendarConfig
+		err string
+	}{
+		{
+			c:   calendar.CalendarConfig{},
+			err: "must specify calendar name",
+		},
+		{
+			c:   calendar.CalendarConfig{Name: "a", Timezone: "Mars/Olympus"},
+			err: `invalid timezone for calendar "a": unknown time zone Mars/Olympus`,
+		},
+		{
+			c:   calendar.CalendarConfig{Name: "a", BusinessDays: []string{"Caturday"}},
+			err: `invalid business day "Caturday" for calendar "a"`,
+		},
+		{
+			c:   calendar.CalendarConfig{Name: "a", BusinessHours: "17:00-09:00"},
+			err: `invalid business hours for calendar "a": business hours must end after they start, got "17:00-09:00"`,
+		},
+	}
+	for _, tc := range testCases {
+		_, err := calendar.NewCalendar(tc.c)
+		if err == nil {
+			t.Errorf("expected error %q", tc.err)
+		} else if err.Error() != tc.err {
+			t.Errorf("unexpected error got %q exp %q", err.Error(), tc.err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package calendar
+
+import "github.com/pkg/errors"
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Path to the file defining the calendars.
+	File string `toml:"file"`
+}
+
+func NewConfig() Config {
+	return Config{}
+}
+
+func (c Config) Validate() error {
+	if c.Enabled && c.File == "" {
+		return errors.New("must specify calendar file")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package calendar
+
+import (
+	"log"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/kapacitor/tick/stateful"
+	"github.com/pkg/errors"
+)
+
+// Service loads the calendars used by the isHoliday and isBusinessHours lambda functions.
+type Service struct {
+	mu        sync.RWMutex
+	calendars map[string]*Calendar
+
+	c      Config
+	logger *log.Logger
+}
+
+func NewService(c Config, l *log.Logger) *Service {
+	return &Service{
+		c:      c,
+		logger: l,
+	}
+}
+
+func (s *Service) Open() error {
+	calendars, err := Load(s.c.File)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.calendars = calendars
+	s.mu.Unlock()
+	s.logger.Printf("I! loaded %d calendars from %s", len(calendars), s.c.File)
+	return nil
+}
+
+func (s *Service) Close() error {
+	s.mu.Lock()
+	s.calendars = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// Calendar returns the named calendar.
+func (s *Service) Calendar(name string) (stateful.Calendar, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.calendars[name]
+	if !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+// Load reads the calendars defined in the file.
+func Load(file string) (map[string]*Calendar, error) {
+	var f calendarsFile
+	if _, err := toml.DecodeFile(file, &f); err != nil {
+		return nil, errors.Wrapf(err, "failed to read calendar file %s", file)
+	}
+	calendars := make(map[string]*Calendar, len(f.Calendars))
+	for _, c := range f.Calendars {
+		if _, ok := calendars[c.Name]; ok {
+			return nil, errors.Errorf("duplicate calendar %q", c.Name)
+		}
+		cal, err := NewCalendar(c)
+		if err != nil {
+			return nil, err
+		}
+		calendars[c.Name] = cal
+	}
+	return calendars, nil
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -160,6 +161,34 @@ func (ua updateAction) Validate() error {
 	return nil
 }
 
+// auditChanges summarizes the update for the audit log.
+// Only the names of the options are recorded since their values may be secrets.
+func (ua updateAction) auditChanges() []string {
+	var changes []string
+	for _, k := range sortedKeys(ua.Set) {
+		changes = append(changes, "set "+k)
+	}
+	for _, k := range ua.Delete {
+		changes = append(changes, "delete "+k)
+	}
+	if len(ua.Add) > 0 {
+		changes = append(changes, "add element")
+	}
+	for _, id := range ua.Remove {
+		changes = append(changes, "remove "+id)
+	}
+	return changes
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 var validSectionOrElement = regexp.MustCompile(`^[-\w+]+$`)
 
 func sectionAndElementToID(section, element string) string {
@@ -224,6 +253,7 @@ func (s *Service) handleUpdateSection(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, fmt.Sprint("failed to decode JSON:", err), true, http.StatusBadRequest)
 		return
 	}
+	httpd.SetAuditChanges(r, ua.auditChanges())
 
 	// Apply sets/deletes to stored overrides
 	overrides, saveFunc, err := s.overridesForUpdateAction(ua)
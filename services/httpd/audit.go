@@ -0,0 +1,395 @@
+package httpd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+)
+
+const (
+	// Request bodies larger than this are not summarized in the audit log.
+	maxAuditBodySize = 1 << 20
+	// Default number of entries returned by the audit endpoint.
+	defaultAuditLimit = 100
+)
+
+// AuditEntry records a single mutating API call.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Resource  string    `json:"resource"`
+	Changes   []string  `json:"changes,omitempty"`
+	Status    int       `json:"status"`
+	RequestID string    `json:"request-id,omitempty"`
+	// Whether the request was a dry run that did not change any state.
+	DryRun bool `json:"dry-run,omitempty"`
+}
+
+// AuditQuery filters the entries of the audit log.
+type AuditQuery struct {
+	// Only entries of the user, if not empty.
+	User string
+	// Only entries whose resource matches the glob pattern or has the prefix, if not empty.
+	Resource string
+	// Only entries in the time range [Since, Until), if not zero.
+	Since time.Time
+	Until time.Time
+	// Maximum number of entries, the most recent entries are returned.
+	Limit int
+}
+
+func (q AuditQuery) matches(e AuditEntry) bool {
+	if q.User != "" && e.User != q.User {
+		return false
+	}
+	if q.Resource != "" && !strings.HasPrefix(e.Resource, q.Resource) {
+		if matched, _ := path.Match(q.Resource, e.Resource); !matched {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// AuditLog writes audit entries as JSON lines to a file,
+// the file is rotated once it reaches its maximum size.
+type AuditLog struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func NewAuditLog(path string, maxSize int64, maxBackups int) *AuditLog {
+	return &AuditLog{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+}
+
+func (l *AuditLog) Open() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.open()
+}
+
+func (l *AuditLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f = f
+	l.size = fi.Size()
+	return nil
+}
+
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// Record appends the entry to the log.
+func (l *AuditLog) Record(e AuditEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return fmt.Errorf("audit log %s is closed", l.path)
+	}
+	if l.size > 0 && l.size+int64(len(data)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.f.Write(data)
+	l.size += int64(n)
+	return err
+}
+
+func (l *AuditLog) backupPath(i int) string {
+	return l.path + "." + strconv.Itoa(i)
+}
+
+// rotate renames the current file to the first backup, shifting the existing backups,
+// and removes the backups beyond the maximum number.
+func (l *AuditLog) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+	if l.maxBackups == 0 {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return l.open()
+	}
+	if err := os.Remove(l.backupPath(l.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := l.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(l.backupPath(i), l.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(l.path, l.backupPath(1)); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Query returns the entries matching the query, oldest first.
+func (l *AuditLog) Query(q AuditQuery) ([]AuditEntry, error) {
+	readers, closeFiles, err := l.openFiles()
+	if err != nil {
+		return nil, err
+	}
+	defer closeFiles()
+
+	var entries []AuditEntry
+	for _, r := range readers {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxAuditBodySize)
+		for scanner.Scan() {
+			e := AuditEntry{}
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				// Skip partially written entries.
+				continue
+			}
+			if q.matches(e) {
+				entries = append(entries, e)
+				if q.Limit > 0 && len(entries) > q.Limit {
+					entries = entries[1:]
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// openFiles opens the backups, oldest first, and the current file and returns readers of their entries
+// and a function closing the files.
+// The lock is only held while opening the files so that the entries are scanned without blocking requests,
+// open files are still read in full if they are rotated in the meantime
+// and the current file is only read up to its size at the time it was opened.
+func (l *AuditLog) openFiles() ([]io.Reader, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	paths := make([]string, 0, l.maxBackups+1)
+	for i := l.maxBackups; i > 0; i-- {
+		paths = append(paths, l.backupPath(i))
+	}
+	paths = append(paths, l.path)
+
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	readers := make([]io.Reader, 0, len(paths))
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			closeFiles()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		if p == l.path && l.f != nil {
+			readers = append(readers, io.LimitReader(f, l.size))
+		} else {
+			readers = append(readers, f)
+		}
+	}
+	return readers, closeFiles, nil
+}
+
+type auditContextKey int
+
+const auditEntryKey auditContextKey = iota
+
+// SetAuditChanges replaces the summary of the changes of the request in its audit entry.
+// Handlers use it to record more detail than the summary of the request body.
+func SetAuditChanges(r *http.Request, changes []string) {
+	if e, ok := r.Context().Value(auditEntryKey).(*AuditEntry); ok {
+		e.Changes = changes
+	}
+}
+
+// isMutatingMethod reports whether requests with the method change state and must be audited.
+func isMutatingMethod(method string) bool {
+	switch method {
+	case "POST", "PATCH", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// audit wraps a handler and records the request and its result in the audit log.
+// The entry is recorded once the request has been handled, so a failure to record it
+// cannot reject the request, the audit log fails open and the failure is logged instead.
+func audit(inner AuthorizationHandler, h *Handler) AuthorizationHandler {
+	return func(w http.ResponseWriter, r *http.Request, user auth.User) {
+		if h.AuditLog == nil || !isMutatingMethod(r.Method) {
+			inner(w, r, user)
+			return
+		}
+		resource := strings.TrimPrefix(r.URL.Path, BasePath)
+		e := &AuditEntry{
+			Time:      time.Now().UTC(),
+			User:      user.Name(),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Resource:  resource,
+			RequestID: r.Header.Get("Request-Id"),
+		}
+		if dryRun, err := strconv.ParseBool(r.URL.Query().Get("dry-run")); err == nil {
+			e.DryRun = dryRun
+		}
+		if r.Body != nil {
+			// Read the beginning of the body to summarize it and restore it for the handler.
+			body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxAuditBodySize))
+			if err != nil {
+				HttpError(w, fmt.Sprint("failed to read request body: ", err), true, http.StatusBadRequest)
+				return
+			}
+			if len(body) < maxAuditBodySize {
+				e.Changes, e.Resource = summarizeBody(body, resource, r.Method == "POST")
+			}
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{
+				Reader: io.MultiReader(bytes.NewReader(body), r.Body),
+				Closer: r.Body,
+			}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), auditEntryKey, e))
+
+		l := &responseLogger{w: w}
+		inner(l, r, user)
+		e.Status = l.Status()
+		if err := h.AuditLog.Record(*e); err != nil {
+			h.logger.Println("E! failed to record audit entry:", err)
+		}
+	}
+}
+
+// summarizeBody returns the names of the top level fields of a JSON body,
+// field values are not recorded since they may contain secrets.
+// Requests that create an object identify it in the body,
+// so its id or name is appended to the resource.
+func summarizeBody(body []byte, resource string, create bool) ([]string, string) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, resource
+	}
+	changes := make([]string, 0, len(fields))
+	for k := range fields {
+		changes = append(changes, k)
+	}
+	sort.Strings(changes)
+	if !create {
+		return changes, resource
+	}
+	for _, k := range []string{"id", "name"} {
+		var id string
+		if raw, ok := fields[k]; ok && json.Unmarshal(raw, &id) == nil && id != "" {
+			return changes, path.Join(resource, id)
+		}
+	}
+	return changes, resource
+}
+
+func (h *Handler) serveAudit(w http.ResponseWriter, r *http.Request) {
+	if h.AuditLog == nil {
+		HttpError(w, "audit log is not enabled", true, http.StatusNotFound)
+		return
+	}
+	values := r.URL.Query()
+	q := AuditQuery{
+		User:     values.Get("user"),
+		Resource: values.Get("resource"),
+		Limit:    defaultAuditLimit,
+	}
+	for _, t := range []struct {
+		name string
+		t    *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if s := values.Get(t.name); s != "" {
+			v, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				HttpError(w, fmt.Sprintf("invalid %s parameter %q must be an RFC3339 time: %s", t.name, s, err), true, http.StatusBadRequest)
+				return
+			}
+			*t.t = v
+		}
+	}
+	if s := values.Get("limit"); s != "" {
+		limit, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			HttpError(w, fmt.Sprintf("invalid limit parameter %q must be an integer: %s", s, err), true, http.StatusBadRequest)
+			return
+		}
+		q.Limit = int(limit)
+	}
+	entries, err := h.AuditLog.Query(q)
+	if err != nil {
+		HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+
+	type response struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	w.Write(MarshalJSON(response{entries}, true))
+}
@@ -0,0 +1,255 @@
+package httpd
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+)
+
+func newTestAuditLog(t *testing.T, maxSize int64, maxBackups int) (*AuditLog, func()) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewAuditLog(filepath.Join(dir, "audit.log"), maxSize, maxBackups)
+	if err := l.Open(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return l, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAuditLog_Rotate(t *testing.T) {
+	l, cleanup := newTestAuditLog(t, 512, 2)
+	defer cleanup()
+
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		if err := l.Record(AuditEntry{
+			Time:     start.Add(time.Duration(i) * time.Second),
+			User:     "bob",
+			Method:   "PATCH",
+			Path:     "/kapacitor/v1/tasks/cpu",
+			Resource: "/tasks/cpu",
+			Status:   http.StatusOK,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{l.path, l.backupPath(1), l.backupPath(2)} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() > l.maxSize {
+			t.Errorf("unexpected size of %s got %d exp at most %d", p, fi.Size(), l.maxSize)
+		}
+	}
+	if _, err := os.Stat(l.backupPath(3)); !os.IsNotExist(err) {
+		t.Errorf("expected only %d backups to be kept", l.maxBackups)
+	}
+
+	entries, err := l.Query(AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 || len(entries) >= 30 {
+		t.Fatalf("unexpected number of entries got %d", len(entries))
+	}
+	// The entries of the removed backups are lost, the remaining ones are in order up to the last one.
+	for i := 1; i < len(entries); i++ {
+		if !entries[i].Time.After(entries[i-1].Time) {
+			t.Fatalf("entries out of order at %d: %v %v", i, entries[i-1].Time, entries[i].Time)
+		}
+	}
+	if got, exp := entries[len(entries)-1].Time, start.Add(29*time.Second); !got.Equal(exp) {
+		t.Errorf("unexpected last entry time got %v exp %v", got, exp)
+	}
+}
+
+func TestAuditLog_Query(t *testing.T) {
+	l, cleanup := newTestAuditLog(t, 1<<20, 1)
+	defer cleanup()
+
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []AuditEntry{
+		{Time: start, User: "bob", Method: "POST", Resource: "/tasks/cpu"},
+		{Time: start.Add(time.Minute), User: "alice", Method: "PATCH", Resource: "/tasks/cpu"},
+		{Time: start.Add(2 * time.Minute), User: "bob", Method: "DELETE", Resource: "/templates/base"},
+		{Time: start.Add(3 * time.Minute), User: "bob", Method: "PATCH", Resource: "/tasks/mem"},
+	}
+	for _, e := range entries {
+		if err := l.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testCases := []struct {
+		name string
+		q    AuditQuery
+		exp  []AuditEntry
+	}{
+		{
+			name: "all",
+			exp:  entries,
+		},
+		{
+			name: "user",
+			q:    AuditQuery{User: "alice"},
+			exp:  entries[1:2],
+		},
+		{
+			name: "resource prefix",
+			q:    AuditQuery{Resource: "/tasks/"},
+			exp:  []AuditEntry{entries[0], entries[1], entries[3]},
+		},
+		{
+			name: "resource pattern",
+			q:    AuditQuery{Resource: "/*/cpu"},
+			exp:  entries[:2],
+		},
+		{
+			name: "time range",
+			q:    AuditQuery{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)},
+			exp:  entries[1:3],
+		},
+		{
+			name: "limit",
+			q:    AuditQuery{User: "bob", Limit: 2},
+			exp:  entries[2:],
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := l.Query(tc.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.exp) {
+				t.Errorf("unexpected entries:\ngot\n%v\nexp\n%v", got, tc.exp)
+			}
+		})
+	}
+}
+
+func Test_Audit(t *testing.T) {
+	l, cleanup := newTestAuditLog(t, 1<<20, 1)
+	defer cleanup()
+	h := &Handler{
+		AuditLog: l,
+		logger:   log.New(os.Stderr, "[httpd] ", log.LstdFlags),
+	}
+	user := auth.NewUser("bob", nil, false, nil)
+
+	testCases := []struct {
+		method   string
+		path     string
+		body     string
+		status   int
+		changes  []string
+		resource string
+		dryRun   bool
+		recorded bool
+	}{
+		{
+			method:   "POST",
+			path:     BasePath + "/tasks",
+			body:     `{"id":"cpu","script":"stream","status":"enabled"}`,
+			status:   http.StatusOK,
+			changes:  []string{"id", "script", "status"},
+			resource: "/tasks/cpu",
+			recorded: true,
+		},
+		{
+			method:   "PATCH",
+			path:     BasePath + "/tasks/cpu",
+			body:     `{"status":"disabled"}`,
+			status:   http.StatusForbidden,
+			changes:  []string{"status"},
+			resource: "/tasks/cpu",
+			recorded: true,
+		},
+		{
+			method:   "PATCH",
+			path:     BasePath + "/templates/tmpl",
+			body:     `{"script":"stream"}`,
+			status:   http.StatusOK,
+			changes:  []string{"script"},
+			resource: "/templates/tmpl",
+			dryRun:   true,
+			recorded: true,
+		},
+		{
+			method:   "DELETE",
+			path:     BasePath + "/tasks/cpu",
+			status:   http.StatusNoContent,
+			resource: "/tasks/cpu",
+			recorded: true,
+		},
+		{
+			method: "GET",
+			path:   BasePath + "/tasks/cpu",
+			status: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		var body string
+		inner := func(w http.ResponseWriter, r *http.Request, _ auth.User) {
+			data, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = string(data)
+			w.WriteHeader(tc.status)
+		}
+		target := tc.path
+		if tc.dryRun {
+			target += "?dry-run=true"
+		}
+		r := httptest.NewRequest(tc.method, target, strings.NewReader(tc.body))
+		audit(inner, h)(httptest.NewRecorder(), r, user)
+		// The handler must still be able to read the whole body.
+		if body != tc.body {
+			t.Errorf("%s %s: unexpected body got %q exp %q", tc.method, tc.path, body, tc.body)
+		}
+	}
+
+	entries, err := l.Query(AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exp []AuditEntry
+	for _, tc := range testCases {
+		if tc.recorded {
+			exp = append(exp, AuditEntry{
+				User:     "bob",
+				Method:   tc.method,
+				Path:     tc.path,
+				Resource: tc.resource,
+				Changes:  tc.changes,
+				Status:   tc.status,
+				DryRun:   tc.dryRun,
+			})
+		}
+	}
+	for i := range entries {
+		if entries[i].Time.IsZero() {
+			t.Errorf("entry %d has no time", i)
+		}
+		entries[i].Time = time.Time{}
+	}
+	if !reflect.DeepEqual(entries, exp) {
+		t.Errorf("unexpected entries:\ngot\n%v\nexp\n%v", entries, exp)
+	}
+}
@@ -12,6 +12,9 @@ import (
 
 const (
 	DefaultShutdownTimeout = toml.Duration(time.Second * 10)
+	DefaultAuditFile       = "./audit.log"
+	DefaultAuditMaxSize    = toml.Size(100 << 20)
+	DefaultAuditMaxBackups = 5
 )
 
 type Config struct {
@@ -25,6 +28,12 @@ type Config struct {
 	ShutdownTimeout  toml.Duration `toml:"shutdown-timeout"`
 	SharedSecret     string        `toml:"shared-secret"`
 
+	// Audit log of all mutating API calls.
+	AuditEnabled    bool      `toml:"audit-enabled"`
+	AuditFile       string    `toml:"audit-file"`
+	AuditMaxSize    toml.Size `toml:"audit-max-size"`
+	AuditMaxBackups int       `toml:"audit-max-backups"`
+
 	// Enable gzipped encoding
 	// NOTE: this is ignored in toml since it is only consumed by the tests
 	GZIP bool `toml:"-"`
@@ -36,6 +45,9 @@ func NewConfig() Config {
 		LogEnabled:       true,
 		HttpsCertificate: "/etc/ssl/kapacitor.pem",
 		ShutdownTimeout:  DefaultShutdownTimeout,
+		AuditFile:        DefaultAuditFile,
+		AuditMaxSize:     DefaultAuditMaxSize,
+		AuditMaxBackups:  DefaultAuditMaxBackups,
 		GZIP:             true,
 	}
 }
@@ -53,6 +65,17 @@ func (c Config) Validate() error {
 	} else if pn > 65535 || pn < 0 {
 		return fmt.Errorf("invalid http bind address port %d: out of range", pn)
 	}
+	if c.AuditEnabled {
+		if c.AuditFile == "" {
+			return errors.New("must specify audit-file")
+		}
+		if c.AuditMaxSize <= 0 {
+			return errors.New("audit-max-size must be positive")
+		}
+		if c.AuditMaxBackups < 0 {
+			return errors.New("audit-max-backups must not be negative")
+		}
+	}
 
 	return nil
 }
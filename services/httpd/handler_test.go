@@ -103,3 +103,30 @@ func Test_RequiredPrilegeForHTTPMethod(t *testing.T) {
 		}
 	}
 }
+
+func Test_IsHMACToken(t *testing.T) {
+	testCases := []struct {
+		token string
+		hmac  bool
+	}{
+		{
+			// {"alg":"HS256","typ":"JWT"}
+			token: "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.e30.sig",
+			hmac:  true,
+		},
+		{
+			// {"alg":"RS256","typ":"JWT"}
+			token: "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCJ9.e30.sig",
+			hmac:  false,
+		},
+		{
+			token: "not a token",
+			hmac:  false,
+		},
+	}
+	for _, tc := range testCases {
+		if got, exp := isHMACToken(tc.token), tc.hmac; got != exp {
+			t.Errorf("unexpected result for %q: got %v exp %v", tc.token, got, exp)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/influxdata/kapacitor/services/logging"
+	"github.com/pkg/errors"
 )
 
 type Service struct {
@@ -72,6 +73,9 @@ func NewService(c Config, hostname string, l *log.Logger, li logging.Interface)
 		logger:           l,
 		httpServerLogger: li.NewStaticLevelLogger("[httpd]", log.LstdFlags, logging.ERROR),
 	}
+	if c.AuditEnabled {
+		s.Handler.AuditLog = NewAuditLog(c.AuditFile, int64(c.AuditMaxSize), c.AuditMaxBackups)
+	}
 	return s
 }
 
@@ -82,6 +86,13 @@ func (s *Service) Open() error {
 	s.logger.Println("I! Starting HTTP service")
 	s.logger.Println("I! Authentication enabled:", s.Handler.requireAuthentication)
 
+	if s.Handler.AuditLog != nil {
+		if err := s.Handler.AuditLog.Open(); err != nil {
+			return errors.Wrap(err, "failed to open audit log")
+		}
+		s.logger.Println("I! Audit log enabled:", s.Handler.AuditLog.path)
+	}
+
 	// Open listener.
 	if s.https {
 		cert, err := tls.LoadX509KeyPair(s.cert, s.cert)
@@ -153,6 +164,9 @@ func (s *Service) Close() error {
 	<-stopping
 	s.wg.Wait()
 	s.server = nil
+	if s.Handler.AuditLog != nil {
+		return s.Handler.AuditLog.Close()
+	}
 	return nil
 }
 
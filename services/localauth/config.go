@@ -0,0 +1,34 @@
+package localauth
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Name and password of an admin user to create when no users exist.
+	AdminUsername string `toml:"admin-username"`
+	AdminPassword string `toml:"admin-password"`
+	// Cost of the bcrypt hashes of the passwords.
+	BcryptCost int `toml:"bcrypt-cost"`
+}
+
+func NewConfig() Config {
+	return Config{
+		BcryptCost: bcrypt.DefaultCost,
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.AdminUsername != "" && c.AdminPassword == "" {
+		return errors.New("must specify admin-password with admin-username")
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		return errors.Errorf("bcrypt-cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
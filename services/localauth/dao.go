@@ -0,0 +1,433 @@
+package localauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUserExists                = errors.New("user already exists")
+	ErrNoUserExists              = errors.New("no user exists")
+	ErrNoSubscriptionTokenExists = errors.New("no subscription token exists")
+	ErrAPITokenExists            = errors.New("API token already exists")
+	ErrNoAPITokenExists          = errors.New("no API token exists")
+)
+
+// Data access object for User data.
+type UserDAO interface {
+	// Retrieve a user
+	Get(name string) (User, error)
+
+	// Create a user.
+	// ErrUserExists is returned if a user already exists with the same name.
+	Create(u User) error
+
+	// Replace an existing user.
+	// ErrNoUserExists is returned if the user does not exist.
+	Replace(u User) error
+
+	// Delete a user.
+	// It is not an error to delete an non-existent user.
+	Delete(name string) error
+
+	// List users matching a pattern.
+	// The pattern is shell/glob matching see https://golang.org/pkg/path/#Match
+	// Offset and limit are pagination bounds. Offset is inclusive starting at index 0.
+	// More results may exist while the number of returned items is equal to limit.
+	List(pattern string, offset, limit int) ([]User, error)
+
+	Rebuild() error
+}
+
+// Data access object for SubscriptionToken data.
+type SubscriptionTokenDAO interface {
+	// Retrieve a token
+	Get(token string) (SubscriptionToken, error)
+
+	// Put a token, replaces any existing token.
+	Put(t SubscriptionToken) error
+
+	// Delete a token.
+	// It is not an error to delete an non-existent token.
+	Delete(token string) error
+
+	// List all tokens.
+	List() ([]SubscriptionToken, error)
+
+	Rebuild() error
+}
+
+// Data access object for APIToken data.
+type APITokenDAO interface {
+	// Retrieve a token
+	Get(name string) (APIToken, error)
+
+	// Create a token.
+	// ErrAPITokenExists is returned if a token already exists with the same name.
+	Create(t APIToken) error
+
+	// Replace an existing token.
+	// ErrNoAPITokenExists is returned if the token does not exist.
+	Replace(t APIToken) error
+
+	// Delete a token.
+	// It is not an error to delete an non-existent token.
+	Delete(name string) error
+
+	// List tokens matching a pattern.
+	// The pattern is shell/glob matching see https://golang.org/pkg/path/#Match
+	// Offset and limit are pagination bounds. Offset is inclusive starting at index 0.
+	// More results may exist while the number of returned items is equal to limit.
+	List(pattern string, offset, limit int) ([]APIToken, error)
+
+	Rebuild() error
+}
+
+//--------------------------------------------------------------------
+// The following structures are stored in a database via JSON encoding.
+// Changes to the structures could break existing data.
+
+const (
+	userVersion              = 1
+	subscriptionTokenVersion = 1
+	apiTokenVersion          = 1
+)
+
+// User is a user with a bcrypt hash of its password
+// and the privileges it has on each resource.
+type User struct {
+	Name  string `json:"name"`
+	Hash  []byte `json:"hash"`
+	Admin bool   `json:"admin"`
+	// Map of resource to the names of its privileges.
+	Privileges map[string][]string `json:"privileges"`
+}
+
+var validUserName = regexp.MustCompile(`^[-\._@\p{L}0-9]+$`)
+
+func (u User) Validate() error {
+	if !validUserName.MatchString(u.Name) {
+		return fmt.Errorf("user name must contain only letters, numbers, '-', '.', '@' and '_'. %q", u.Name)
+	}
+	if len(u.Hash) == 0 {
+		return errors.New("user must have a password hash")
+	}
+	return nil
+}
+
+func (u User) ObjectID() string {
+	return u.Name
+}
+
+func (u User) MarshalBinary() ([]byte, error) {
+	if err := u.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid user")
+	}
+	return storage.VersionJSONEncode(userVersion, u)
+}
+
+func (u *User) UnmarshalBinary(data []byte) error {
+	return storage.VersionJSONDecode(data, func(version int, dec *json.Decoder) error {
+		switch version {
+		case userVersion:
+			return dec.Decode(u)
+		default:
+			return fmt.Errorf("unknown user version %d: cannot decode", version)
+		}
+	})
+}
+
+// SubscriptionToken is a token InfluxDB uses to write the data of its subscriptions.
+type SubscriptionToken struct {
+	Token string `json:"token"`
+	DBRPs []DBRP `json:"dbrps"`
+}
+
+type DBRP struct {
+	Database        string `json:"db"`
+	RetentionPolicy string `json:"rp"`
+}
+
+func (t SubscriptionToken) ObjectID() string {
+	return t.Token
+}
+
+func (t SubscriptionToken) MarshalBinary() ([]byte, error) {
+	return storage.VersionJSONEncode(subscriptionTokenVersion, t)
+}
+
+func (t *SubscriptionToken) UnmarshalBinary(data []byte) error {
+	return storage.VersionJSONDecode(data, func(version int, dec *json.Decoder) error {
+		switch version {
+		case subscriptionTokenVersion:
+			return dec.Decode(t)
+		default:
+			return fmt.Errorf("unknown subscription token version %d: cannot decode", version)
+		}
+	})
+}
+
+// APIToken is a named bearer token with a SHA-256 hash of its secret
+// and the privileges it has on each resource or resource pattern.
+type APIToken struct {
+	Name string `json:"name"`
+	Hash []byte `json:"hash"`
+	// Map of resource or resource pattern to the names of its privileges.
+	Privileges map[string][]string `json:"privileges"`
+	Created    time.Time           `json:"created"`
+	// Zero if the token never expires.
+	Expires  time.Time `json:"expires"`
+	LastUsed time.Time `json:"last-used"`
+}
+
+func (t APIToken) Validate() error {
+	if !validUserName.MatchString(t.Name) {
+		return fmt.Errorf("API token name must contain only letters, numbers, '-', '.', '@' and '_'. %q", t.Name)
+	}
+	if len(t.Hash) == 0 {
+		return errors.New("API token must have a hash")
+	}
+	return nil
+}
+
+// Expired reports whether the token has expired at the given time.
+func (t APIToken) Expired(now time.Time) bool {
+	return !t.Expires.IsZero() && !now.Before(t.Expires)
+}
+
+func (t APIToken) ObjectID() string {
+	return t.Name
+}
+
+func (t APIToken) MarshalBinary() ([]byte, error) {
+	if err := t.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid API token")
+	}
+	return storage.VersionJSONEncode(apiTokenVersion, t)
+}
+
+func (t *APIToken) UnmarshalBinary(data []byte) error {
+	return storage.VersionJSONDecode(data, func(version int, dec *json.Decoder) error {
+		switch version {
+		case apiTokenVersion:
+			return dec.Decode(t)
+		default:
+			return fmt.Errorf("unknown API token version %d: cannot decode", version)
+		}
+	})
+}
+
+// Key/Value store based implementation of the UserDAO
+type userKV struct {
+	store *storage.IndexedStore
+}
+
+const (
+	userPrefix              = "users"
+	subscriptionTokenPrefix = "subscription-tokens"
+	apiTokenPrefix          = "api-tokens"
+)
+
+func newUserKV(store storage.Interface) (*userKV, error) {
+	c := storage.DefaultIndexedStoreConfig(userPrefix, func() storage.BinaryObject {
+		return new(User)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &userKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *userKV) error(err error) error {
+	if err == storage.ErrObjectExists {
+		return ErrUserExists
+	} else if err == storage.ErrNoObjectExists {
+		return ErrNoUserExists
+	}
+	return err
+}
+
+func (kv *userKV) Get(name string) (User, error) {
+	o, err := kv.store.Get(name)
+	if err != nil {
+		return User{}, kv.error(err)
+	}
+	u, ok := o.(*User)
+	if !ok {
+		return User{}, storage.ImpossibleTypeErr(u, o)
+	}
+	return *u, nil
+}
+
+func (kv *userKV) Create(u User) error {
+	return kv.error(kv.store.Create(&u))
+}
+
+func (kv *userKV) Replace(u User) error {
+	return kv.error(kv.store.Replace(&u))
+}
+
+func (kv *userKV) Delete(name string) error {
+	return kv.store.Delete(name)
+}
+
+func (kv *userKV) List(pattern string, offset, limit int) ([]User, error) {
+	objects, err := kv.store.List(storage.DefaultIDIndex, pattern, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, len(objects))
+	for i, o := range objects {
+		u, ok := o.(*User)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(u, o)
+		}
+		users[i] = *u
+	}
+	return users, nil
+}
+
+func (kv *userKV) Rebuild() error {
+	return kv.store.Rebuild()
+}
+
+// Key/Value store based implementation of the SubscriptionTokenDAO
+type subscriptionTokenKV struct {
+	store *storage.IndexedStore
+}
+
+func newSubscriptionTokenKV(store storage.Interface) (*subscriptionTokenKV, error) {
+	c := storage.DefaultIndexedStoreConfig(subscriptionTokenPrefix, func() storage.BinaryObject {
+		return new(SubscriptionToken)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &subscriptionTokenKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *subscriptionTokenKV) Get(token string) (SubscriptionToken, error) {
+	o, err := kv.store.Get(token)
+	if err != nil {
+		if err == storage.ErrNoObjectExists {
+			return SubscriptionToken{}, ErrNoSubscriptionTokenExists
+		}
+		return SubscriptionToken{}, err
+	}
+	t, ok := o.(*SubscriptionToken)
+	if !ok {
+		return SubscriptionToken{}, storage.ImpossibleTypeErr(t, o)
+	}
+	return *t, nil
+}
+
+func (kv *subscriptionTokenKV) Put(t SubscriptionToken) error {
+	return kv.store.Put(&t)
+}
+
+func (kv *subscriptionTokenKV) Delete(token string) error {
+	return kv.store.Delete(token)
+}
+
+func (kv *subscriptionTokenKV) List() ([]SubscriptionToken, error) {
+	// List all tokens, there is one token per subscription.
+	objects, err := kv.store.List(storage.DefaultIDIndex, "", 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]SubscriptionToken, len(objects))
+	for i, o := range objects {
+		t, ok := o.(*SubscriptionToken)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(t, o)
+		}
+		tokens[i] = *t
+	}
+	return tokens, nil
+}
+
+func (kv *subscriptionTokenKV) Rebuild() error {
+	return kv.store.Rebuild()
+}
+
+// Key/Value store based implementation of the APITokenDAO
+type apiTokenKV struct {
+	store *storage.IndexedStore
+}
+
+func newAPITokenKV(store storage.Interface) (*apiTokenKV, error) {
+	c := storage.DefaultIndexedStoreConfig(apiTokenPrefix, func() storage.BinaryObject {
+		return new(APIToken)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &apiTokenKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *apiTokenKV) error(err error) error {
+	if err == storage.ErrObjectExists {
+		return ErrAPITokenExists
+	} else if err == storage.ErrNoObjectExists {
+		return ErrNoAPITokenExists
+	}
+	return err
+}
+
+func (kv *apiTokenKV) Get(name string) (APIToken, error) {
+	o, err := kv.store.Get(name)
+	if err != nil {
+		return APIToken{}, kv.error(err)
+	}
+	t, ok := o.(*APIToken)
+	if !ok {
+		return APIToken{}, storage.ImpossibleTypeErr(t, o)
+	}
+	return *t, nil
+}
+
+func (kv *apiTokenKV) Create(t APIToken) error {
+	return kv.error(kv.store.Create(&t))
+}
+
+func (kv *apiTokenKV) Replace(t APIToken) error {
+	return kv.error(kv.store.Replace(&t))
+}
+
+func (kv *apiTokenKV) Delete(name string) error {
+	return kv.store.Delete(name)
+}
+
+func (kv *apiTokenKV) List(pattern string, offset, limit int) ([]APIToken, error) {
+	objects, err := kv.store.List(storage.DefaultIDIndex, pattern, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]APIToken, len(objects))
+	for i, o := range objects {
+		t, ok := o.(*APIToken)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(t, o)
+		}
+		tokens[i] = *t
+	}
+	return tokens, nil
+}
+
+func (kv *apiTokenKV) Rebuild() error {
+	return kv.store.Rebuild()
+}
@@ -0,0 +1,761 @@
+package localauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/storage"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	usersPath         = "/users"
+	usersPathAnchored = "/users/"
+	usersBasePath     = httpd.BasePath + usersPath
+
+	apiTokensPath         = "/tokens"
+	apiTokensPathAnchored = "/tokens/"
+	apiTokensBasePath     = httpd.BasePath + apiTokensPath
+)
+
+const (
+	// Public name of the user storage layer
+	usersAPIName = "users"
+	// Public name of the subscription token storage layer
+	subscriptionTokensAPIName = "subscription-tokens"
+	// Public name of the API token storage layer
+	apiTokensAPIName = "api-tokens"
+	// The storage namespace for all local auth data.
+	localAuthNamespace = "localauth"
+)
+
+const (
+	// Prefix of the names of the users of API tokens.
+	apiTokenUserPrefix = "~token:"
+	// The last used time of API tokens is only stored with this resolution,
+	// so that the token is not written for each request.
+	lastUsedResolution = time.Minute
+)
+
+var ErrAuthenticationFailed = errors.New("authentication failed")
+
+// Service is an implementation of the auth.Interface that stores users
+// with bcrypt hashes of their passwords and their privileges.
+type Service struct {
+	config Config
+
+	users     UserDAO
+	tokens    SubscriptionTokenDAO
+	apiTokens APITokenDAO
+	routes    []httpd.Route
+
+	// Cache of the salted SHA-256 hashes of the last password each user authenticated with,
+	// so that bcrypt is not run for each request.
+	cacheMu   sync.Mutex
+	cacheSalt []byte
+	cache     map[string][sha256.Size]byte
+	// Hash compared against when the user does not exist,
+	// so that the time taken does not reveal whether it exists.
+	dummyHash []byte
+
+	StorageService interface {
+		Store(namespace string) storage.Interface
+		Register(name string, store storage.StoreActioner)
+	}
+	HTTPDService interface {
+		AddRoutes([]httpd.Route) error
+		DelRoutes([]httpd.Route)
+	}
+
+	logger *log.Logger
+}
+
+func NewService(c Config, l *log.Logger) *Service {
+	return &Service{
+		config: c,
+		cache:  make(map[string][sha256.Size]byte),
+		logger: l,
+	}
+}
+
+func (s *Service) Open() error {
+	s.cacheSalt = make([]byte, 32)
+	if _, err := rand.Read(s.cacheSalt); err != nil {
+		return errors.Wrap(err, "generating salt")
+	}
+	dummyHash, err := bcrypt.GenerateFromPassword(s.cacheSalt, s.config.BcryptCost)
+	if err != nil {
+		return errors.Wrap(err, "generating dummy hash")
+	}
+	s.dummyHash = dummyHash
+
+	store := s.StorageService.Store(localAuthNamespace)
+	users, err := newUserKV(store)
+	if err != nil {
+		return err
+	}
+	s.users = users
+	s.StorageService.Register(usersAPIName, users)
+
+	tokens, err := newSubscriptionTokenKV(store)
+	if err != nil {
+		return err
+	}
+	s.tokens = tokens
+	s.StorageService.Register(subscriptionTokensAPIName, tokens)
+
+	apiTokens, err := newAPITokenKV(store)
+	if err != nil {
+		return err
+	}
+	s.apiTokens = apiTokens
+	s.StorageService.Register(apiTokensAPIName, apiTokens)
+
+	if err := s.createAdminUser(); err != nil {
+		return errors.Wrap(err, "creating admin user")
+	}
+
+	// Define API routes
+	s.routes = []httpd.Route{
+		{
+			Method:      "GET",
+			Pattern:     usersPathAnchored,
+			HandlerFunc: s.handleUser,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     usersPathAnchored,
+			HandlerFunc: s.handleDeleteUser,
+		},
+		{
+			// Satisfy CORS checks.
+			Method:      "OPTIONS",
+			Pattern:     usersPathAnchored,
+			HandlerFunc: httpd.ServeOptions,
+		},
+		{
+			Method:      "PATCH",
+			Pattern:     usersPathAnchored,
+			HandlerFunc: s.handleUpdateUser,
+		},
+		{
+			Method:      "GET",
+			Pattern:     usersPath,
+			HandlerFunc: s.handleListUsers,
+		},
+		{
+			Method:      "POST",
+			Pattern:     usersPath,
+			HandlerFunc: s.handleCreateUser,
+		},
+		{
+			Method:      "GET",
+			Pattern:     apiTokensPathAnchored,
+			HandlerFunc: s.handleAPIToken,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     apiTokensPathAnchored,
+			HandlerFunc: s.handleDeleteAPIToken,
+		},
+		{
+			// Satisfy CORS checks.
+			Method:      "OPTIONS",
+			Pattern:     apiTokensPathAnchored,
+			HandlerFunc: httpd.ServeOptions,
+		},
+		{
+			Method:      "GET",
+			Pattern:     apiTokensPath,
+			HandlerFunc: s.handleListAPITokens,
+		},
+		{
+			Method:      "POST",
+			Pattern:     apiTokensPath,
+			HandlerFunc: s.handleCreateAPIToken,
+		},
+	}
+	return s.HTTPDService.AddRoutes(s.routes)
+}
+
+func (s *Service) Close() error {
+	if s.HTTPDService != nil {
+		s.HTTPDService.DelRoutes(s.routes)
+	}
+	return nil
+}
+
+// createAdminUser creates the configured admin user if no users exist.
+func (s *Service) createAdminUser() error {
+	if s.config.AdminUsername == "" {
+		return nil
+	}
+	users, err := s.users.List("", 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(s.config.AdminPassword), s.config.BcryptCost)
+	if err != nil {
+		return err
+	}
+	s.logger.Println("I! creating admin user", s.config.AdminUsername)
+	return s.users.Create(User{
+		Name:  s.config.AdminUsername,
+		Hash:  hash,
+		Admin: true,
+	})
+}
+
+// Authenticate the user with its password.
+func (s *Service) Authenticate(username, password string) (auth.User, error) {
+	u, err := s.users.Get(username)
+	if err != nil {
+		if err == ErrNoUserExists {
+			bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+			return auth.User{}, ErrAuthenticationFailed
+		}
+		return auth.User{}, err
+	}
+	sum := s.saltedSum(password)
+	s.cacheMu.Lock()
+	cached, ok := s.cache[username]
+	s.cacheMu.Unlock()
+	if !ok || subtle.ConstantTimeCompare(cached[:], sum[:]) != 1 {
+		if err := bcrypt.CompareHashAndPassword(u.Hash, []byte(password)); err != nil {
+			return auth.User{}, ErrAuthenticationFailed
+		}
+		s.cacheMu.Lock()
+		s.cache[username] = sum
+		s.cacheMu.Unlock()
+	}
+	return convertUser(u)
+}
+
+func (s *Service) saltedSum(password string) [sha256.Size]byte {
+	return sha256.Sum256(append(append([]byte{}, s.cacheSalt...), password...))
+}
+
+// invalidate removes the cached password of the user.
+func (s *Service) invalidate(username string) {
+	s.cacheMu.Lock()
+	delete(s.cache, username)
+	s.cacheMu.Unlock()
+}
+
+// User returns the user without authenticating it,
+// it is used for users authenticated by other means, i.e. bearer tokens.
+func (s *Service) User(username string) (auth.User, error) {
+	u, err := s.users.Get(username)
+	if err != nil {
+		return auth.User{}, err
+	}
+	return convertUser(u)
+}
+
+// convertUser converts a stored user to an auth.User.
+func convertUser(u User) (auth.User, error) {
+	privileges, err := auth.ParsePrivileges(u.Privileges)
+	if err != nil {
+		return auth.User{}, errors.Wrapf(err, "invalid privileges of user %s", u.Name)
+	}
+	return auth.NewUser(u.Name, u.Hash, u.Admin, privileges), nil
+}
+
+// SubscriptionUser returns a user that can write to the databases the token was granted access to.
+func (s *Service) SubscriptionUser(token string) (auth.User, error) {
+	t, err := s.tokens.Get(token)
+	if err != nil {
+		if err == ErrNoSubscriptionTokenExists {
+			return auth.User{}, ErrAuthenticationFailed
+		}
+		return auth.User{}, err
+	}
+	privileges := map[string][]auth.Privilege{
+		auth.APIResource("/write"): {auth.WritePrivilege},
+	}
+	for _, dbrp := range t.DBRPs {
+		privileges[auth.DatabaseResource(dbrp.Database)] = []auth.Privilege{auth.WritePrivilege}
+	}
+	return auth.NewUser(httpd.SubscriptionUser, nil, false, privileges), nil
+}
+
+// TokenUser returns a user with the privileges of the API token.
+// API tokens have the form <APITokenPrefix><name>_<secret>.
+func (s *Service) TokenUser(token string) (auth.User, error) {
+	name, secret, ok := splitAPIToken(token)
+	if !ok {
+		return auth.User{}, ErrAuthenticationFailed
+	}
+	t, err := s.apiTokens.Get(name)
+	if err != nil {
+		if err == ErrNoAPITokenExists {
+			return auth.User{}, ErrAuthenticationFailed
+		}
+		return auth.User{}, err
+	}
+	sum := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare(t.Hash, sum[:]) != 1 {
+		return auth.User{}, ErrAuthenticationFailed
+	}
+	now := time.Now().UTC()
+	if t.Expired(now) {
+		return auth.User{}, fmt.Errorf("API token %s expired at %v", t.Name, t.Expires)
+	}
+	if now.Sub(t.LastUsed) >= lastUsedResolution {
+		t.LastUsed = now
+		if err := s.apiTokens.Replace(t); err != nil && err != ErrNoAPITokenExists {
+			s.logger.Printf("E! failed to store last used time of API token %s: %v", t.Name, err)
+		}
+	}
+	privileges, err := auth.ParsePrivileges(t.Privileges)
+	if err != nil {
+		return auth.User{}, errors.Wrapf(err, "invalid privileges of API token %s", t.Name)
+	}
+	return auth.NewUser(apiTokenUserPrefix+t.Name, nil, false, privileges), nil
+}
+
+// splitAPIToken splits an API token into its name and secret.
+func splitAPIToken(token string) (name, secret string, ok bool) {
+	if !strings.HasPrefix(token, auth.APITokenPrefix) {
+		return "", "", false
+	}
+	token = token[len(auth.APITokenPrefix):]
+	// The secret is hex encoded so the last '_' separates the name from the secret.
+	i := strings.LastIndex(token, "_")
+	if i <= 0 || i == len(token)-1 {
+		return "", "", false
+	}
+	return token[:i], token[i+1:], true
+}
+
+func (s *Service) GrantSubscriptionAccess(token, db, rp string) error {
+	t, err := s.tokens.Get(token)
+	if err == ErrNoSubscriptionTokenExists {
+		t = SubscriptionToken{Token: token}
+	} else if err != nil {
+		return err
+	}
+	dbrp := DBRP{Database: db, RetentionPolicy: rp}
+	for _, existing := range t.DBRPs {
+		if existing == dbrp {
+			return nil
+		}
+	}
+	t.DBRPs = append(t.DBRPs, dbrp)
+	return s.tokens.Put(t)
+}
+
+func (s *Service) ListSubscriptionTokens() ([]string, error) {
+	tokens, err := s.tokens.List()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, len(tokens))
+	for i, t := range tokens {
+		list[i] = t.Token
+	}
+	return list, nil
+}
+
+func (s *Service) RevokeSubscriptionAccess(token string) error {
+	return s.tokens.Delete(token)
+}
+
+func (s *Service) userLink(name string) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(usersBasePath, name)}
+}
+
+func (s *Service) convertUser(u User) client.User {
+	privileges := make(map[string][]string, len(u.Privileges))
+	for resource, ps := range u.Privileges {
+		privileges[resource] = append([]string(nil), ps...)
+		sort.Strings(privileges[resource])
+	}
+	return client.User{
+		Link:       s.userLink(u.Name),
+		Name:       u.Name,
+		Admin:      u.Admin,
+		Privileges: privileges,
+	}
+}
+
+func (s *Service) userNameFromPath(p string) (string, error) {
+	if len(p) <= len(usersBasePath)+1 {
+		return "", errors.New("must specify user name on path")
+	}
+	return p[len(usersBasePath)+1:], nil
+}
+
+// requireAdmin writes an error and returns false if the user is not an admin.
+func requireAdmin(w http.ResponseWriter, user auth.User) bool {
+	if !user.IsAdmin() {
+		httpd.HttpError(w, fmt.Sprintf("user %s is not an admin, only admins can manage users", user.Name()), true, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (s *Service) handleUser(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	name, err := s.userNameFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	u, err := s.users.Get(name)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(s.convertUser(u), true))
+}
+
+func (s *Service) handleListUsers(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	pattern := r.URL.Query().Get("pattern")
+
+	var err error
+	offset := int64(0)
+	offsetStr := r.URL.Query().Get("offset")
+	if offsetStr != "" {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid offset parameter %q must be an integer: %s", offsetStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := int64(100)
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid limit parameter %q must be an integer: %s", limitStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rawUsers, err := s.users.List(pattern, int(offset), int(limit))
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list users with pattern %q: %s", pattern, err), true, http.StatusBadRequest)
+		return
+	}
+	users := make([]client.User, len(rawUsers))
+	for i, u := range rawUsers {
+		users[i] = s.convertUser(u)
+	}
+
+	type response struct {
+		Users []client.User `json:"users"`
+	}
+
+	w.Write(httpd.MarshalJSON(response{users}, true))
+}
+
+func (s *Service) handleCreateUser(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	opt := client.CreateUserOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	if opt.Password == "" {
+		httpd.HttpError(w, "must provide a password", true, http.StatusBadRequest)
+		return
+	}
+	if _, err := auth.ParsePrivileges(opt.Privileges); err != nil {
+		httpd.HttpError(w, "invalid privileges: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(opt.Password), s.config.BcryptCost)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	newUser := User{
+		Name:       opt.Name,
+		Hash:       hash,
+		Admin:      opt.Admin,
+		Privileges: opt.Privileges,
+	}
+	if err := newUser.Validate(); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := s.users.Create(newUser); err != nil {
+		if err == ErrUserExists {
+			httpd.HttpError(w, fmt.Sprintf("user %s already exists", opt.Name), true, http.StatusBadRequest)
+			return
+		}
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(s.convertUser(newUser), true))
+}
+
+func (s *Service) handleUpdateUser(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	name, err := s.userNameFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	opt := client.UpdateUserOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	existing, err := s.users.Get(name)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	if opt.Password != "" {
+		existing.Hash, err = bcrypt.GenerateFromPassword([]byte(opt.Password), s.config.BcryptCost)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+			return
+		}
+	}
+	if opt.Admin != nil {
+		if !*opt.Admin && name == user.Name() {
+			httpd.HttpError(w, "cannot remove admin from the current user", true, http.StatusBadRequest)
+			return
+		}
+		existing.Admin = *opt.Admin
+	}
+	if opt.Privileges != nil {
+		if _, err := auth.ParsePrivileges(opt.Privileges); err != nil {
+			httpd.HttpError(w, "invalid privileges: "+err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		existing.Privileges = opt.Privileges
+	}
+
+	if err := s.users.Replace(existing); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	s.invalidate(name)
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(s.convertUser(existing), true))
+}
+
+func (s *Service) handleDeleteUser(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	name, err := s.userNameFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if name == user.Name() {
+		httpd.HttpError(w, "cannot delete the current user", true, http.StatusBadRequest)
+		return
+	}
+	if _, err := s.users.Get(name); err != nil {
+		if err == ErrNoUserExists {
+			httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		} else {
+			httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		}
+		return
+	}
+	if err := s.users.Delete(name); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	s.invalidate(name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) apiTokenLink(name string) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(apiTokensBasePath, name)}
+}
+
+func (s *Service) convertAPIToken(t APIToken) client.APIToken {
+	privileges := make(map[string][]string, len(t.Privileges))
+	for resource, ps := range t.Privileges {
+		privileges[resource] = append([]string(nil), ps...)
+		sort.Strings(privileges[resource])
+	}
+	return client.APIToken{
+		Link:       s.apiTokenLink(t.Name),
+		Name:       t.Name,
+		Privileges: privileges,
+		Created:    t.Created,
+		Expires:    t.Expires,
+		LastUsed:   t.LastUsed,
+	}
+}
+
+func (s *Service) apiTokenNameFromPath(p string) (string, error) {
+	if len(p) <= len(apiTokensBasePath)+1 {
+		return "", errors.New("must specify API token name on path")
+	}
+	return p[len(apiTokensBasePath)+1:], nil
+}
+
+func (s *Service) handleAPIToken(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	name, err := s.apiTokenNameFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	t, err := s.apiTokens.Get(name)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(s.convertAPIToken(t), true))
+}
+
+func (s *Service) handleListAPITokens(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	pattern := r.URL.Query().Get("pattern")
+
+	var err error
+	offset := int64(0)
+	offsetStr := r.URL.Query().Get("offset")
+	if offsetStr != "" {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid offset parameter %q must be an integer: %s", offsetStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := int64(100)
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid limit parameter %q must be an integer: %s", limitStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rawTokens, err := s.apiTokens.List(pattern, int(offset), int(limit))
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list API tokens with pattern %q: %s", pattern, err), true, http.StatusBadRequest)
+		return
+	}
+	tokens := make([]client.APIToken, len(rawTokens))
+	for i, t := range rawTokens {
+		tokens[i] = s.convertAPIToken(t)
+	}
+
+	type response struct {
+		Tokens []client.APIToken `json:"tokens"`
+	}
+
+	w.Write(httpd.MarshalJSON(response{tokens}, true))
+}
+
+func (s *Service) handleCreateAPIToken(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	opt := client.CreateAPITokenOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	if _, err := auth.ParsePrivileges(opt.Privileges); err != nil {
+		httpd.HttpError(w, "invalid privileges: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UTC()
+	if !opt.Expires.IsZero() && !opt.Expires.After(now) {
+		httpd.HttpError(w, "expires must be in the future", true, http.StatusBadRequest)
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	encoded := hex.EncodeToString(secret)
+	sum := sha256.Sum256([]byte(encoded))
+	newToken := APIToken{
+		Name:       opt.Name,
+		Hash:       sum[:],
+		Privileges: opt.Privileges,
+		Created:    now,
+		Expires:    opt.Expires.UTC(),
+	}
+	if err := newToken.Validate(); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := s.apiTokens.Create(newToken); err != nil {
+		if err == ErrAPITokenExists {
+			httpd.HttpError(w, fmt.Sprintf("API token %s already exists", opt.Name), true, http.StatusBadRequest)
+			return
+		}
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	t := s.convertAPIToken(newToken)
+	// The token is only ever returned here, only its hash is stored.
+	t.Token = auth.APITokenPrefix + newToken.Name + "_" + encoded
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(t, true))
+}
+
+func (s *Service) handleDeleteAPIToken(w http.ResponseWriter, r *http.Request, user auth.User) {
+	if !requireAdmin(w, user) {
+		return
+	}
+	name, err := s.apiTokenNameFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := s.apiTokens.Delete(name); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
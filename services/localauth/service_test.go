@@ -0,0 +1,266 @@
+package localauth_test
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+	client "github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/services/httpd/httpdtest"
+	"github.com/influxdata/kapacitor/services/localauth"
+	"github.com/influxdata/kapacitor/services/storage/storagetest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func OpenNewService() (*localauth.Service, *httpdtest.Server) {
+	c := localauth.NewConfig()
+	c.Enabled = true
+	c.AdminUsername = "admin"
+	c.AdminPassword = "secret"
+	c.BcryptCost = bcrypt.MinCost
+	service := localauth.NewService(c, log.New(os.Stderr, "[localauth] ", log.LstdFlags))
+	service.StorageService = storagetest.New()
+	server := httpdtest.NewServer(testing.Verbose())
+	service.HTTPDService = server
+	if err := service.Open(); err != nil {
+		panic(err)
+	}
+	return service, server
+}
+
+func TestService_Authenticate(t *testing.T) {
+	service, server := OpenNewService()
+	defer server.Close()
+	defer service.Close()
+
+	// Authenticate twice to exercise the cached password.
+	for i := 0; i < 2; i++ {
+		u, err := service.Authenticate("admin", "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, exp := u.Name(), "admin"; got != exp {
+			t.Errorf("unexpected user name got %s exp %s", got, exp)
+		}
+		if !u.IsAdmin() {
+			t.Error("expected admin user")
+		}
+	}
+	if _, err := service.Authenticate("admin", "wrong"); err != localauth.ErrAuthenticationFailed {
+		t.Errorf("unexpected error got %v exp %v", err, localauth.ErrAuthenticationFailed)
+	}
+	if _, err := service.Authenticate("bob", "secret"); err != localauth.ErrAuthenticationFailed {
+		t.Errorf("unexpected error got %v exp %v", err, localauth.ErrAuthenticationFailed)
+	}
+}
+
+func TestService_Users(t *testing.T) {
+	service, server := OpenNewService()
+	defer server.Close()
+	defer service.Close()
+
+	cli, err := client.New(client.Config{
+		URL: server.Server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := cli.CreateUser(client.CreateUserOptions{
+		Name:     "bob",
+		Password: "bob's secret",
+		Privileges: map[string][]string{
+			"/api/tasks": {"write", "read"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := client.User{
+		Link: client.Link{Relation: client.Self, Href: "/kapacitor/v1/users/bob"},
+		Name: "bob",
+		Privileges: map[string][]string{
+			"/api/tasks": {"read", "write"},
+		},
+	}
+	if !reflect.DeepEqual(u, exp) {
+		t.Errorf("unexpected user:\ngot\n%#v\nexp\n%#v\n", u, exp)
+	}
+	if _, err := cli.CreateUser(client.CreateUserOptions{
+		Name:     "bob",
+		Password: "another secret",
+	}); err == nil {
+		t.Error("expected error creating existing user")
+	}
+	if _, err := cli.CreateUser(client.CreateUserOptions{
+		Name:     "alice",
+		Password: "alice's secret",
+		Privileges: map[string][]string{
+			"/api/tasks": {"execute"},
+		},
+	}); err == nil {
+		t.Error("expected error creating user with unknown privilege")
+	}
+
+	authUser, err := service.Authenticate("bob", "bob's secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authUser.AuthorizeAction(auth.Action{Resource: "/api/tasks/id", Privilege: auth.WritePrivilege}); err != nil {
+		t.Error(err)
+	}
+	if err := authUser.AuthorizeAction(auth.Action{Resource: "/api/config", Privilege: auth.ReadPrivilege}); err == nil {
+		t.Error("expected user to not be authorized to read config")
+	}
+
+	// Changing the password invalidates the old one.
+	if _, err := cli.UpdateUser(cli.UserLink("bob"), client.UpdateUserOptions{
+		Password: "bob's new secret",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Authenticate("bob", "bob's secret"); err != localauth.ErrAuthenticationFailed {
+		t.Errorf("unexpected error got %v exp %v", err, localauth.ErrAuthenticationFailed)
+	}
+	if _, err := service.Authenticate("bob", "bob's new secret"); err != nil {
+		t.Error(err)
+	}
+
+	if err := cli.DeleteUser(cli.UserLink("bob")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.DeleteUser(cli.UserLink("bob")); err == nil || !strings.Contains(err.Error(), "no user exists") {
+		t.Errorf("unexpected error deleting missing user got %v", err)
+	}
+	if _, err := service.Authenticate("bob", "bob's new secret"); err != localauth.ErrAuthenticationFailed {
+		t.Errorf("unexpected error got %v exp %v", err, localauth.ErrAuthenticationFailed)
+	}
+	users, err := cli.ListUsers(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(users), 1; got != exp {
+		t.Fatalf("unexpected number of users got %d exp %d", got, exp)
+	}
+}
+
+func TestService_SubscriptionUser(t *testing.T) {
+	service, server := OpenNewService()
+	defer server.Close()
+	defer service.Close()
+
+	if err := service.GrantSubscriptionAccess("token", "mydb", "myrp"); err != nil {
+		t.Fatal(err)
+	}
+	u, err := service.SubscriptionUser("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := u.Name(), httpd.SubscriptionUser; got != exp {
+		t.Errorf("unexpected user name got %s exp %s", got, exp)
+	}
+	if err := u.AuthorizeAction(auth.Action{Resource: auth.DatabaseResource("mydb"), Privilege: auth.WritePrivilege}); err != nil {
+		t.Error(err)
+	}
+	if err := u.AuthorizeAction(auth.Action{Resource: auth.DatabaseResource("otherdb"), Privilege: auth.WritePrivilege}); err == nil {
+		t.Error("expected subscription user to not be authorized to write to otherdb")
+	}
+
+	if err := service.RevokeSubscriptionAccess("token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.SubscriptionUser("token"); err != localauth.ErrAuthenticationFailed {
+		t.Errorf("unexpected error got %v exp %v", err, localauth.ErrAuthenticationFailed)
+	}
+}
+
+func TestService_APITokens(t *testing.T) {
+	service, server := OpenNewService()
+	defer server.Close()
+	defer service.Close()
+
+	cli, err := client.New(client.Config{
+		URL: server.Server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := cli.CreateAPIToken(client.CreateAPITokenOptions{
+		Name: "ci_pipeline",
+		Privileges: map[string][]string{
+			"/api/tasks/ci-*": {"all"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token.Token, auth.APITokenPrefix+"ci_pipeline_") {
+		t.Fatalf("unexpected token %q", token.Token)
+	}
+	if _, err := cli.CreateAPIToken(client.CreateAPITokenOptions{
+		Name: "bad",
+		Privileges: map[string][]string{
+			"/api/tasks/[": {"read"},
+		},
+	}); err == nil {
+		t.Error("expected error creating token with invalid resource pattern")
+	}
+
+	u, err := service.TokenUser(token.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.AuthorizeAction(auth.Action{Resource: "/api/tasks/ci-build", Privilege: auth.WritePrivilege}); err != nil {
+		t.Error(err)
+	}
+	if err := u.AuthorizeAction(auth.Action{Resource: "/api/tasks/prod", Privilege: auth.ReadPrivilege}); err == nil {
+		t.Error("expected token to not be authorized to read other tasks")
+	}
+	if _, err := service.TokenUser(token.Token + "0"); err != localauth.ErrAuthenticationFailed {
+		t.Errorf("unexpected error got %v exp %v", err, localauth.ErrAuthenticationFailed)
+	}
+
+	got, err := cli.APIToken(cli.APITokenLink("ci_pipeline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Token != "" {
+		t.Error("expected the token to not be returned after creation")
+	}
+	if got.LastUsed.IsZero() {
+		t.Error("expected the last used time to be set")
+	}
+
+	expiring, err := cli.CreateAPIToken(client.CreateAPITokenOptions{
+		Name:    "expiring",
+		Expires: time.Now().Add(50 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.TokenUser(expiring.Token); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := service.TokenUser(expiring.Token); err == nil {
+		t.Error("expected error using expired token")
+	}
+
+	if err := cli.DeleteAPIToken(cli.APITokenLink("ci_pipeline")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.TokenUser(token.Token); err != localauth.ErrAuthenticationFailed {
+		t.Errorf("unexpected error got %v exp %v", err, localauth.ErrAuthenticationFailed)
+	}
+	tokens, err := cli.ListAPITokens(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(tokens), 1; got != exp {
+		t.Fatalf("unexpected number of tokens got %d exp %d", got, exp)
+	}
+}
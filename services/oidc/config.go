@@ -0,0 +1,91 @@
+package oidc
+
+import (
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultUsernameClaim     = "sub"
+	DefaultGroupsClaim       = "groups"
+	DefaultJWKSCacheDuration = toml.Duration(time.Hour)
+)
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// URL of the JSON Web Key Set of the identity provider,
+	// either http(s):// or a path to a local file.
+	JWKSURL string `toml:"jwks-url"`
+	// How long the fetched keys are cached before they are fetched again.
+	JWKSCacheDuration toml.Duration `toml:"jwks-cache-duration"`
+	// Expected iss claim.
+	Issuer string `toml:"issuer"`
+	// Expected aud claim.
+	Audience string `toml:"audience"`
+	// Name of the claim containing the username.
+	UsernameClaim string `toml:"username-claim"`
+	// Name of the claim containing the groups or roles of the user.
+	GroupsClaim string `toml:"groups-claim"`
+	// Mappings from groups to privileges.
+	Mappings []Mapping `toml:"mapping"`
+}
+
+// Mapping grants privileges to the users that are members of a group.
+type Mapping struct {
+	Group string `toml:"group"`
+	// Admin users have all privileges on all resources.
+	Admin bool `toml:"admin"`
+	// Map of resource to the names of its privileges, i.e. "/api/tasks" = ["read", "write"].
+	// The resources may be glob patterns, i.e. "/api/tasks/ci-*",
+	// the same as the privileges of local users and API tokens.
+	Privileges map[string][]string `toml:"privileges"`
+}
+
+func NewConfig() Config {
+	return Config{
+		JWKSCacheDuration: DefaultJWKSCacheDuration,
+		UsernameClaim:     DefaultUsernameClaim,
+		GroupsClaim:       DefaultGroupsClaim,
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.JWKSURL == "" {
+		return errors.New("must specify jwks-url")
+	}
+	if c.Issuer == "" {
+		return errors.New("must specify issuer")
+	}
+	if c.Audience == "" {
+		return errors.New("must specify audience")
+	}
+	if c.JWKSCacheDuration <= 0 {
+		return errors.New("jwks-cache-duration must be positive")
+	}
+	if c.UsernameClaim == "" {
+		return errors.New("must specify username-claim")
+	}
+	if c.GroupsClaim == "" {
+		return errors.New("must specify groups-claim")
+	}
+	for _, m := range c.Mappings {
+		if err := m.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid mapping for group %q", m.Group)
+		}
+	}
+	return nil
+}
+
+func (m Mapping) Validate() error {
+	if m.Group == "" {
+		return errors.New("must specify group")
+	}
+	_, err := auth.ParsePrivileges(m.Privileges)
+	return err
+}
@@ -0,0 +1,245 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Minimum time between fetches of the keys.
+	minRefreshInterval = 10 * time.Second
+	fetchTimeout       = 10 * time.Second
+)
+
+// jwk is a JSON Web Key as defined in RFC 7517.
+// Only the fields of RSA and EC public keys are decoded.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// publicKey returns the *rsa.PublicKey or *ecdsa.PublicKey of the key.
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid modulus")
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid exponent")
+		}
+		if e.BitLen() > 31 {
+			return nil, errors.New("exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid x coordinate")
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid y coordinate")
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// parseJWKS returns the signing keys of the set by their key ID.
+// Keys that are not for signatures or are not supported are skipped.
+func parseJWKS(data []byte, l *log.Logger) (map[string]interface{}, error) {
+	set := jwkSet{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON Web Key Set")
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			l.Printf("W! skipping JSON Web Key %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JSON Web Key Set contains no supported signing keys")
+	}
+	return keys, nil
+}
+
+// keySet fetches and caches the keys of a JSON Web Key Set.
+type keySet struct {
+	url           string
+	cacheDuration time.Duration
+	client        *http.Client
+	logger        *log.Logger
+
+	mu   sync.Mutex
+	keys map[string]interface{}
+	// Time of the last successful fetch and of the last attempted fetch.
+	fetched   time.Time
+	attempted time.Time
+	// Closed once the fetch in progress completes, nil if no fetch is in progress.
+	refreshing chan struct{}
+}
+
+func newKeySet(url string, cacheDuration time.Duration, l *log.Logger) *keySet {
+	return &keySet{
+		url:           url,
+		cacheDuration: cacheDuration,
+		client:        &http.Client{Timeout: fetchTimeout},
+		logger:        l,
+	}
+}
+
+// Key returns the key with the ID.
+// The keys are fetched if they have expired or the ID is unknown.
+// If there is only a single key it is returned for tokens without a key ID.
+func (ks *keySet) Key(kid string) (interface{}, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	now := time.Now()
+	key, ok := ks.lookup(kid)
+	expired := now.Sub(ks.fetched) >= ks.cacheDuration
+	if !ok && ks.refreshing != nil {
+		// Wait for the fetch in progress, it may return the key.
+		done := ks.refreshing
+		ks.mu.Unlock()
+		<-done
+		ks.mu.Lock()
+		key, ok = ks.lookup(kid)
+	}
+	// Limit the rate of fetches so that unknown key IDs or an unreachable identity provider
+	// do not cause a fetch for each request.
+	// A fetch in progress was attempted less than minRefreshInterval ago so only one runs at a time.
+	if (expired || !ok) && now.Sub(ks.attempted) >= minRefreshInterval {
+		if err := ks.refresh(now); err != nil {
+			if ks.keys == nil {
+				return nil, err
+			}
+			// Keep using the cached keys until the identity provider is reachable again.
+			ks.logger.Println("E! failed to refresh JSON Web Key Set:", err)
+		}
+		key, ok = ks.lookup(kid)
+	}
+	if ks.keys == nil {
+		return nil, errors.New("no JSON Web Key Set has been fetched")
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	return key, nil
+}
+
+// Load fetches the keys.
+func (ks *keySet) Load() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.refresh(time.Now())
+}
+
+func (ks *keySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the keys, ks.mu must be held.
+// The lock is released while fetching so that requests with cached keys are not blocked.
+func (ks *keySet) refresh(now time.Time) error {
+	ks.attempted = now
+	done := make(chan struct{})
+	ks.refreshing = done
+	ks.mu.Unlock()
+	keys, err := ks.fetchKeys()
+	ks.mu.Lock()
+	ks.refreshing = nil
+	close(done)
+	if err != nil {
+		return err
+	}
+	ks.keys = keys
+	ks.fetched = now
+	return nil
+}
+
+func (ks *keySet) fetchKeys() (map[string]interface{}, error) {
+	data, err := ks.fetch()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch JSON Web Key Set from %s", ks.url)
+	}
+	return parseJWKS(data, ks.logger)
+}
+
+func (ks *keySet) fetch() ([]byte, error) {
+	if strings.HasPrefix(ks.url, "http://") || strings.HasPrefix(ks.url, "https://") {
+		resp, err := ks.client.Get(ks.url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected response code %d", resp.StatusCode)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+	return ioutil.ReadFile(strings.TrimPrefix(ks.url, "file://"))
+}
@@ -0,0 +1,172 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/pkg/errors"
+)
+
+// Service verifies JWTs issued by an OpenID Connect identity provider
+// and maps the groups of their users to privileges.
+type Service struct {
+	config Config
+	keys   *keySet
+
+	mappings []mapping
+	parser   *jwt.Parser
+
+	logger *log.Logger
+}
+
+type mapping struct {
+	group      string
+	admin      bool
+	privileges map[string][]auth.Privilege
+}
+
+func NewService(c Config, l *log.Logger) *Service {
+	return &Service{
+		config: c,
+		keys:   newKeySet(c.JWKSURL, time.Duration(c.JWKSCacheDuration), l),
+		parser: &jwt.Parser{
+			ValidMethods: []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()},
+		},
+		logger: l,
+	}
+}
+
+func (s *Service) Open() error {
+	s.mappings = make([]mapping, len(s.config.Mappings))
+	for i, m := range s.config.Mappings {
+		privileges, err := auth.ParsePrivileges(m.Privileges)
+		if err != nil {
+			return errors.Wrapf(err, "invalid mapping for group %q", m.Group)
+		}
+		s.mappings[i] = mapping{
+			group:      m.Group,
+			admin:      m.Admin,
+			privileges: privileges,
+		}
+	}
+	// Fetch the keys now so that configuration errors are reported early,
+	// the identity provider may not be reachable yet so it is not an error.
+	if err := s.keys.Load(); err != nil {
+		s.logger.Println("W! failed to load JSON Web Key Set:", err)
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	return nil
+}
+
+// Verify the token and return the user it identifies with the privileges mapped from its groups.
+func (s *Service) Verify(token string) (auth.User, error) {
+	t, err := s.parser.Parse(token, s.keyLookup)
+	if err != nil {
+		return auth.User{}, fmt.Errorf("invalid token: %s", err)
+	} else if !t.Valid {
+		return auth.User{}, errors.New("invalid token")
+	}
+	claims, ok := t.Claims.(jwt.MapClaims)
+	if !ok {
+		// This should not be possible, but just in case.
+		return auth.User{}, errors.New("invalid claims type")
+	}
+
+	// The exp claim is validated by the parser as long as it exists.
+	// Make sure it was set on the token.
+	if exp, ok := claims["exp"].(float64); !ok || exp <= 0.0 {
+		return auth.User{}, errors.New("token expiration required")
+	}
+	if !claims.VerifyIssuer(s.config.Issuer, true) {
+		return auth.User{}, errors.New("token has an unexpected issuer")
+	}
+	if !hasAudience(claims["aud"], s.config.Audience) {
+		return auth.User{}, errors.New("token has an unexpected audience")
+	}
+
+	username, ok := claims[s.config.UsernameClaim].(string)
+	if !ok || username == "" {
+		return auth.User{}, fmt.Errorf("token must contain a %s claim with the username", s.config.UsernameClaim)
+	}
+
+	groups := stringsClaim(claims[s.config.GroupsClaim])
+	admin := false
+	privileges := make(map[string][]auth.Privilege)
+	matched := false
+	for _, m := range s.mappings {
+		if !contains(groups, m.group) {
+			continue
+		}
+		matched = true
+		admin = admin || m.admin
+		for resource, ps := range m.privileges {
+			privileges[resource] = append(privileges[resource], ps...)
+		}
+	}
+	if !matched {
+		return auth.User{}, fmt.Errorf("user %s is not a member of any mapped group", username)
+	}
+	return auth.NewUser(username, nil, admin, privileges), nil
+}
+
+func (s *Service) keyLookup(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, err := s.keys.Key(kid)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure the key is of the type of the signing method,
+	// the parser has already restricted the methods.
+	switch t.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("key %q is not an RSA key", kid)
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("key %q is not an EC key", kid)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return key, nil
+}
+
+// hasAudience reports whether the aud claim, either a string or a list of strings, contains the audience.
+func hasAudience(aud interface{}, audience string) bool {
+	return contains(stringsClaim(aud), audience)
+}
+
+// stringsClaim returns the values of a claim that is either a string or a list of strings.
+func stringsClaim(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return []string{c}
+	case []interface{}:
+		values := make([]string, 0, len(c))
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,309 @@
+package oidc_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/influxdata/kapacitor/services/oidc"
+)
+
+type keys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newKeys(t *testing.T) keys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys{rsa: rsaKey, ec: ecKey}
+}
+
+func encode(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// jwks returns the JSON Web Key Set of the public keys.
+func (k keys) jwks() []byte {
+	set := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa",
+				"use": "sig",
+				"n":   encode(k.rsa.N),
+				"e":   encode(big.NewInt(int64(k.rsa.E))),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec",
+				"crv": "P-256",
+				"x":   encode(k.ec.X),
+				"y":   encode(k.ec.Y),
+			},
+		},
+	}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+func (k keys) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	var key interface{}
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		key = k.rsa
+	case *jwt.SigningMethodECDSA:
+		key = k.ec
+	default:
+		key = []byte("secret")
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newConfig(jwksURL string) oidc.Config {
+	c := oidc.NewConfig()
+	c.Enabled = true
+	c.JWKSURL = jwksURL
+	c.Issuer = "https://idp.example.com/"
+	c.Audience = "kapacitor"
+	c.Mappings = []oidc.Mapping{
+		{
+			Group: "admins",
+			Admin: true,
+		},
+		{
+			Group: "ci",
+			Privileges: map[string][]string{
+				"/api/tasks/ci-*": {"all"},
+				"/api/ping":       {"read"},
+			},
+		},
+	}
+	return c
+}
+
+func openService(t *testing.T, c oidc.Config) *oidc.Service {
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	s := oidc.NewService(c, log.New(os.Stderr, "[oidc] ", log.LstdFlags))
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":    "bob",
+		"iss":    "https://idp.example.com/",
+		"aud":    []string{"kapacitor", "other"},
+		"exp":    time.Now().Add(time.Minute).Unix(),
+		"groups": []string{"users", "ci"},
+	}
+}
+
+func TestService_Verify(t *testing.T) {
+	k := newKeys(t)
+	dir, err := ioutil.TempDir("", "oidc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jwksFile := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(jwksFile, k.jwks(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := openService(t, newConfig(jwksFile))
+	defer s.Close()
+
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	testCases := []struct {
+		name   string
+		token  string
+		err    string
+		admin  bool
+		action auth.Action
+	}{
+		{
+			name:   "RS256",
+			token:  k.sign(t, jwt.SigningMethodRS256, "rsa", validClaims()),
+			action: auth.Action{Resource: "/api/tasks/ci-build", Privilege: auth.WritePrivilege},
+		},
+		{
+			name:   "ES256",
+			token:  k.sign(t, jwt.SigningMethodES256, "ec", validClaims()),
+			action: auth.Action{Resource: "/api/ping", Privilege: auth.ReadPrivilege},
+		},
+		{
+			name:   "admin",
+			token:  k.sign(t, jwt.SigningMethodRS256, "rsa", withClaim("groups", "admins")),
+			admin:  true,
+			action: auth.Action{Resource: "/api/config", Privilege: auth.WritePrivilege},
+		},
+		{
+			name:  "HS256",
+			token: k.sign(t, jwt.SigningMethodHS256, "rsa", validClaims()),
+			err:   "invalid token: signing method HS256 is invalid",
+		},
+		{
+			name:  "unknown key",
+			token: k.sign(t, jwt.SigningMethodRS256, "other", validClaims()),
+			err:   `invalid token: unknown key ID "other"`,
+		},
+		{
+			name:  "wrong key type",
+			token: k.sign(t, jwt.SigningMethodES256, "rsa", validClaims()),
+			err:   `invalid token: key "rsa" is not an EC key`,
+		},
+		{
+			name:  "expired",
+			token: k.sign(t, jwt.SigningMethodRS256, "rsa", withClaim("exp", time.Now().Add(-time.Minute).Unix())),
+			err:   "invalid token: Token is expired",
+		},
+		{
+			name:  "no expiration",
+			token: k.sign(t, jwt.SigningMethodRS256, "rsa", withClaim("exp", nil)),
+			err:   "token expiration required",
+		},
+		{
+			name:  "wrong issuer",
+			token: k.sign(t, jwt.SigningMethodRS256, "rsa", withClaim("iss", "https://evil.example.com/")),
+			err:   "token has an unexpected issuer",
+		},
+		{
+			name:  "wrong audience",
+			token: k.sign(t, jwt.SigningMethodRS256, "rsa", withClaim("aud", "other")),
+			err:   "token has an unexpected audience",
+		},
+		{
+			name:  "no username",
+			token: k.sign(t, jwt.SigningMethodRS256, "rsa", withClaim("sub", nil)),
+			err:   "token must contain a sub claim with the username",
+		},
+		{
+			name:  "unmapped group",
+			token: k.sign(t, jwt.SigningMethodRS256, "rsa", withClaim("groups", []string{"users"})),
+			err:   "user bob is not a member of any mapped group",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := s.Verify(tc.token)
+			if tc.err != "" {
+				if err == nil {
+					t.Fatalf("expected error %q", tc.err)
+				} else if got := err.Error(); got != tc.err {
+					t.Fatalf("unexpected error got %q exp %q", got, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, exp := u.Name(), "bob"; got != exp {
+				t.Errorf("unexpected username got %s exp %s", got, exp)
+			}
+			if got, exp := u.IsAdmin(), tc.admin; got != exp {
+				t.Errorf("unexpected admin got %v exp %v", got, exp)
+			}
+			if err := u.AuthorizeAction(tc.action); err != nil {
+				t.Error(err)
+			}
+			if !tc.admin {
+				if err := u.AuthorizeAction(auth.Action{Resource: "/api/tasks/prod", Privilege: auth.ReadPrivilege}); err == nil {
+					t.Error("expected user to not be authorized to read other tasks")
+				}
+			}
+		})
+	}
+}
+
+func TestService_Verify_JWKSURL(t *testing.T) {
+	k := newKeys(t)
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(k.jwks())
+	}))
+	defer ts.Close()
+
+	s := openService(t, newConfig(ts.URL))
+	defer s.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := s.Verify(k.sign(t, jwt.SigningMethodRS256, "rsa", validClaims())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The keys are cached after they are loaded when the service is opened.
+	if got, exp := requests, 1; got != exp {
+		t.Errorf("unexpected number of JWKS requests got %d exp %d", got, exp)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := newConfig("jwks.json")
+	c.Mappings = append(c.Mappings, oidc.Mapping{
+		Group: "bad",
+		Privileges: map[string][]string{
+			"tasks": {"read"},
+		},
+	})
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if exp := `invalid mapping for group "bad"`; !strings.HasPrefix(err.Error(), exp) {
+		t.Errorf("unexpected error got %q exp prefix %q", err.Error(), exp)
+	}
+}
+
+func TestConfig_Validate_IssuerAudience(t *testing.T) {
+	c := newConfig("jwks.json")
+	c.Issuer = ""
+	if err := c.Validate(); err == nil || err.Error() != "must specify issuer" {
+		t.Errorf("unexpected error got %v exp %q", err, "must specify issuer")
+	}
+	c = newConfig("jwks.json")
+	c.Audience = ""
+	if err := c.Validate(); err == nil || err.Error() != "must specify audience" {
+		t.Errorf("unexpected error got %v exp %q", err, "must specify audience")
+	}
+}
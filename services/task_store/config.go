@@ -1,24 +1,61 @@
 package task_store
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/kapacitor"
 )
 
 type Config struct {
 	// Deprecated, only needed to find old db and migrate
 	Dir              string        `toml:"dir"`
 	SnapshotInterval toml.Duration `toml:"snapshot-interval"`
+
+	// Default limits of the resources used by each task, zero is unlimited.
+	MaxGroups           int64         `toml:"max-groups"`
+	MaxBufferedPoints   int64         `toml:"max-buffered-points"`
+	MaxEdgeQueue        int64         `toml:"max-edge-queue"`
+	MaxCPU              float64       `toml:"max-cpu"`
+	LimitSampleInterval toml.Duration `toml:"limit-sample-interval"`
+	// Action taken when a task exceeds a limit, one of drop-oldest, pause or stop.
+	LimitAction string `toml:"limit-action"`
 }
 
 func NewConfig() Config {
 	return Config{
-		Dir:              "./tasks",
-		SnapshotInterval: toml.Duration(time.Minute),
+		Dir:                 "./tasks",
+		SnapshotInterval:    toml.Duration(time.Minute),
+		LimitSampleInterval: toml.Duration(10 * time.Second),
+		LimitAction:         "stop",
 	}
 }
 
 func (c Config) Validate() error {
+	if c.MaxGroups < 0 || c.MaxBufferedPoints < 0 || c.MaxEdgeQueue < 0 || c.MaxCPU < 0 {
+		return fmt.Errorf("task limits must not be negative")
+	}
+	if c.LimitSampleInterval < 0 {
+		return fmt.Errorf("limit-sample-interval must not be negative")
+	}
+	var action kapacitor.LimitAction
+	if err := action.UnmarshalText([]byte(c.LimitAction)); err != nil {
+		return fmt.Errorf("invalid limit-action: %s", err)
+	}
 	return nil
 }
+
+// limits returns the default limits of the tasks.
+func (c Config) limits() kapacitor.TaskLimits {
+	l := kapacitor.TaskLimits{
+		MaxGroups:         c.MaxGroups,
+		MaxBufferedPoints: c.MaxBufferedPoints,
+		MaxEdgeQueue:      int(c.MaxEdgeQueue),
+		MaxCPU:            c.MaxCPU,
+		SampleInterval:    time.Duration(c.LimitSampleInterval),
+	}
+	// The action was validated with the configuration.
+	_ = l.Action.UnmarshalText([]byte(c.LimitAction))
+	return l
+}
@@ -16,7 +16,15 @@ var (
 	ErrNoTaskExists     = errors.New("no task exists")
 	ErrTemplateExists   = errors.New("template already exists")
 	ErrNoTemplateExists = errors.New("no template exists")
+	ErrModuleExists     = errors.New("module already exists")
+	ErrNoModuleExists   = errors.New("no module exists")
 	ErrNoSnapshotExists = errors.New("no snapshot exists")
+
+	ErrTaskRevisionExists   = errors.New("task revision already exists")
+	ErrNoTaskRevisionExists = errors.New("no task revision exists")
+
+	ErrTaskGroupExists   = errors.New("task group already exists")
+	ErrNoTaskGroupExists = errors.New("no task group exists")
 )
 
 // Data access object for Task data.
@@ -45,6 +53,50 @@ type TaskDAO interface {
 	Rebuild() error
 }
 
+// Data access object for TaskRevision data.
+type TaskRevisionDAO interface {
+	// Retrieve a revision of a task.
+	// ErrNoTaskRevisionExists is returned if the revision does not exist.
+	Get(taskID string, revision int64) (TaskRevision, error)
+
+	// Create a revision.
+	// ErrTaskRevisionExists is returned if the revision already exists.
+	Create(r TaskRevision) error
+
+	// Delete all revisions of a task.
+	// It is not an error to delete the revisions of a task without revisions.
+	Delete(taskID string) error
+
+	// List the revisions of a task, the most recent revision first.
+	// Offset and limit are pagination bounds. Offset is inclusive starting at index 0.
+	// More results may exist while the number of returned items is equal to limit.
+	List(taskID string, offset, limit int) ([]TaskRevision, error)
+}
+
+// Data access object for TaskGroup data.
+type TaskGroupDAO interface {
+	// Retrieve a task group
+	Get(id string) (TaskGroup, error)
+
+	// Create a task group.
+	// ErrTaskGroupExists is returned if a task group already exists with the same ID.
+	Create(g TaskGroup) error
+
+	// Replace an existing task group.
+	// ErrNoTaskGroupExists is returned if the task group does not exist.
+	Replace(g TaskGroup) error
+
+	// Delete a task group.
+	// It is not an error to delete an non-existent task group.
+	Delete(id string) error
+
+	// List task groups matching a pattern.
+	// The pattern is shell/glob matching see https://golang.org/pkg/path/#Match
+	// Offset and limit are pagination bounds. Offset is inclusive starting at index 0.
+	// More results may exist while the number of returned items is equal to limit.
+	List(pattern string, offset, limit int) ([]TaskGroup, error)
+}
+
 // Data access object for Template data.
 type TemplateDAO interface {
 	// Retrieve a template
@@ -77,6 +129,30 @@ type TemplateDAO interface {
 	ListAssociatedTasks(templateId string) ([]string, error)
 }
 
+// Data access object for Module data.
+type ModuleDAO interface {
+	// Retrieve a module
+	Get(id string) (Module, error)
+
+	// Create a module.
+	// ErrModuleExists is returned if a module already exists with the same ID.
+	Create(m Module) error
+
+	// Replace an existing module.
+	// ErrNoModuleExists is returned if the module does not exist.
+	Replace(m Module) error
+
+	// Delete a module.
+	// It is not an error to delete an non-existent module.
+	Delete(id string) error
+
+	// List modules matching a pattern.
+	// The pattern is shell/glob matching see https://golang.org/pkg/path/#Match
+	// Offset and limit are pagination bounds. Offset is inclusive starting at index 0.
+	// More results may exist while the number of returned items is equal to limit.
+	List(pattern string, offset, limit int) ([]Module, error)
+}
+
 // Data access object for Snapshot data.
 type SnapshotDAO interface {
 	// Load a saved snapshot.
@@ -104,6 +180,8 @@ type Status int
 const (
 	Disabled Status = iota
 	Enabled
+	// The task exceeded one of its limits and was stopped.
+	Quarantined
 )
 
 type TaskType int
@@ -114,6 +192,17 @@ const (
 	Undefined TaskType = -1
 )
 
+func (tt TaskType) String() string {
+	switch tt {
+	case StreamTask:
+		return "stream"
+	case BatchTask:
+		return "batch"
+	default:
+		return "undefined"
+	}
+}
+
 type Task struct {
 	// Unique identifier for the task
 	ID string
@@ -137,6 +226,28 @@ type Task struct {
 	Modified time.Time
 	// The time the task was last changed to status Enabled.
 	LastEnabled time.Time
+	// Versions of the modules imported by the TICKscript, keyed by module ID.
+	Modules map[string]int64
+	// Limits of the resources used by the task, zero values use the limits of the configuration.
+	Limits TaskLimits
+}
+
+type LimitAction int
+
+const (
+	DefaultLimitAction LimitAction = iota
+	LimitDropOldest
+	LimitPause
+	LimitStop
+)
+
+type TaskLimits struct {
+	MaxGroups         int64
+	MaxBufferedPoints int64
+	MaxEdgeQueue      int64
+	MaxCPU            float64
+	SampleInterval    time.Duration
+	Action            LimitAction
 }
 
 type rawTask Task
@@ -157,6 +268,85 @@ func (t *Task) UnmarshalBinary(data []byte) error {
 	return dec.Decode((*rawTask)(t))
 }
 
+// A TaskRevision is the definition of a task at the time it was changed.
+type TaskRevision struct {
+	// ID of the task
+	TaskID string
+	// Revision number, starting at 1 and incremented on each change of the task definition.
+	Revision int64
+	// The task type (stream|batch).
+	Type TaskType
+	// The DBs and RPs the task is allowed to access.
+	DBRPs []DBRP
+	// The TICKscript for the task.
+	TICKscript string
+	// ID of task template
+	TemplateID string
+	// Set of vars for a templated task
+	Vars map[string]Var
+	// Name of the user that made the change, empty if unknown.
+	Author string
+	// The time the revision was created.
+	Created time.Time
+}
+
+type rawTaskRevision TaskRevision
+
+// The revision number is zero padded so that the revisions of a task are sorted by their number.
+func (r TaskRevision) ObjectID() string {
+	return taskRevisionID(r.TaskID, r.Revision)
+}
+
+func taskRevisionID(taskID string, revision int64) string {
+	return fmt.Sprintf("%s/%010d", taskID, revision)
+}
+
+func (r TaskRevision) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(rawTaskRevision(r))
+	return buf.Bytes(), err
+}
+
+func (r *TaskRevision) UnmarshalBinary(data []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	return dec.Decode((*rawTaskRevision)(r))
+}
+
+// A TaskGroup is a set of tasks that are enabled and disabled together,
+// in the order of their dependencies.
+type TaskGroup struct {
+	// Unique identifier for the group
+	ID string
+	// IDs of the tasks of the group.
+	Tasks []string
+	// The declared dependencies of the tasks, keyed by task ID.
+	// A task is enabled after and disabled before the tasks it depends on.
+	Dependencies map[string][]string
+	// Created Date
+	Created time.Time
+	// The time the group was last modified
+	Modified time.Time
+}
+
+type rawTaskGroup TaskGroup
+
+func (g TaskGroup) ObjectID() string {
+	return g.ID
+}
+
+func (g TaskGroup) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(rawTaskGroup(g))
+	return buf.Bytes(), err
+}
+
+func (g *TaskGroup) UnmarshalBinary(data []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	return dec.Decode((*rawTaskGroup)(g))
+}
+
 type Template struct {
 	// Unique identifier for the task
 	ID string
@@ -170,6 +360,22 @@ type Template struct {
 	Created time.Time
 	// The time the task was last modified
 	Modified time.Time
+	// Versions of the modules imported by the TICKscript, keyed by module ID.
+	Modules map[string]int64
+}
+
+// A Module is a TICKscript that can be imported by tasks and templates.
+type Module struct {
+	// Unique identifier for the module, the path used to import it.
+	ID string
+	// The TICKscript for the module.
+	TICKscript string
+	// Version of the module, incremented on each update.
+	Version int64
+	// Created Date
+	Created time.Time
+	// The time the module was last modified
+	Modified time.Time
 }
 
 type DBRP struct {
@@ -179,6 +385,7 @@ type DBRP struct {
 
 type Snapshot struct {
 	NodeSnapshots map[string][]byte
+	NodeConfigs   map[string]string
 }
 
 // Key/Value store based implementation of the TaskDAO
@@ -252,6 +459,152 @@ func (kv *taskKV) Rebuild() error {
 	return kv.store.Rebuild()
 }
 
+// Key/Value store based implementation of the TaskRevisionDAO
+type taskRevisionKV struct {
+	store *storage.IndexedStore
+}
+
+func newTaskRevisionKV(store storage.Interface) (*taskRevisionKV, error) {
+	c := storage.DefaultIndexedStoreConfig("task-revisions", func() storage.BinaryObject {
+		return new(TaskRevision)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &taskRevisionKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *taskRevisionKV) error(err error) error {
+	if err == storage.ErrObjectExists {
+		return ErrTaskRevisionExists
+	} else if err == storage.ErrNoObjectExists {
+		return ErrNoTaskRevisionExists
+	}
+	return err
+}
+
+func (kv *taskRevisionKV) Get(taskID string, revision int64) (TaskRevision, error) {
+	o, err := kv.store.Get(taskRevisionID(taskID, revision))
+	if err != nil {
+		return TaskRevision{}, kv.error(err)
+	}
+	r, ok := o.(*TaskRevision)
+	if !ok {
+		return TaskRevision{}, storage.ImpossibleTypeErr(r, o)
+	}
+	return *r, nil
+}
+
+func (kv *taskRevisionKV) Create(r TaskRevision) error {
+	return kv.error(kv.store.Create(&r))
+}
+
+func (kv *taskRevisionKV) Delete(taskID string) error {
+	const limit = 100
+	for {
+		// The deleted revisions are no longer listed, so always list from the start.
+		revisions, err := kv.List(taskID, 0, limit)
+		if err != nil {
+			return err
+		}
+		for _, r := range revisions {
+			if err := kv.store.Delete(r.ObjectID()); err != nil {
+				return err
+			}
+		}
+		if len(revisions) != limit {
+			return nil
+		}
+	}
+}
+
+func (kv *taskRevisionKV) List(taskID string, offset, limit int) ([]TaskRevision, error) {
+	// Task IDs cannot contain '/' or pattern characters, so the pattern only matches the revisions of the task.
+	objects, err := kv.store.ReverseList(storage.DefaultIDIndex, taskID+"/*", offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]TaskRevision, len(objects))
+	for i, o := range objects {
+		r, ok := o.(*TaskRevision)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(r, o)
+		}
+		revisions[i] = *r
+	}
+	return revisions, nil
+}
+
+// Key/Value store based implementation of the TaskGroupDAO
+type taskGroupKV struct {
+	store *storage.IndexedStore
+}
+
+func newTaskGroupKV(store storage.Interface) (*taskGroupKV, error) {
+	c := storage.DefaultIndexedStoreConfig("task-groups", func() storage.BinaryObject {
+		return new(TaskGroup)
+	})
+	istore, err := storage.NewIndexedStore(store, c)
+	if err != nil {
+		return nil, err
+	}
+	return &taskGroupKV{
+		store: istore,
+	}, nil
+}
+
+func (kv *taskGroupKV) error(err error) error {
+	if err == storage.ErrObjectExists {
+		return ErrTaskGroupExists
+	} else if err == storage.ErrNoObjectExists {
+		return ErrNoTaskGroupExists
+	}
+	return err
+}
+
+func (kv *taskGroupKV) Get(id string) (TaskGroup, error) {
+	o, err := kv.store.Get(id)
+	if err != nil {
+		return TaskGroup{}, kv.error(err)
+	}
+	g, ok := o.(*TaskGroup)
+	if !ok {
+		return TaskGroup{}, storage.ImpossibleTypeErr(g, o)
+	}
+	return *g, nil
+}
+
+func (kv *taskGroupKV) Create(g TaskGroup) error {
+	return kv.error(kv.store.Create(&g))
+}
+
+func (kv *taskGroupKV) Replace(g TaskGroup) error {
+	return kv.error(kv.store.Replace(&g))
+}
+
+func (kv *taskGroupKV) Delete(id string) error {
+	return kv.error(kv.store.Delete(id))
+}
+
+func (kv *taskGroupKV) List(pattern string, offset, limit int) ([]TaskGroup, error) {
+	objects, err := kv.store.List(storage.DefaultIDIndex, pattern, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]TaskGroup, len(objects))
+	for i, o := range objects {
+		g, ok := o.(*TaskGroup)
+		if !ok {
+			return nil, storage.ImpossibleTypeErr(g, o)
+		}
+		groups[i] = *g
+	}
+	return groups, nil
+}
+
 const (
 	templateDataPrefix    = "/templates/data/"
 	templateIndexesPrefix = "/templates/indexes/"
@@ -473,6 +826,168 @@ func (d *templateKV) List(pattern string, offset, limit int) (templates []Templa
 	return
 }
 
+const (
+	moduleDataPrefix    = "/modules/data/"
+	moduleIndexesPrefix = "/modules/indexes/"
+)
+
+// Key/Value store based implementation of the ModuleDAO
+type moduleKV struct {
+	store storage.Interface
+}
+
+func newModuleKV(store storage.Interface) *moduleKV {
+	return &moduleKV{
+		store: store,
+	}
+}
+
+func (d *moduleKV) encodeModule(m Module) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(m)
+	return buf.Bytes(), err
+}
+
+func (d *moduleKV) decodeModule(data []byte) (Module, error) {
+	var module Module
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	err := dec.Decode(&module)
+	return module, err
+}
+
+// Create a key for the module data
+func (d *moduleKV) moduleDataKey(id string) string {
+	return moduleDataPrefix + id
+}
+
+// Create a key for a given index and value.
+//
+// Indexes are maintained via a 'directory' like system:
+//
+// /modules/data/ID -- contains encoded module data
+// /modules/index/id/ID -- contains the module ID
+//
+// As such to list all modules in ID sorted order use the /modules/index/id/ directory.
+func (d *moduleKV) moduleIndexKey(index, value string) string {
+	return moduleIndexesPrefix + index + value
+}
+
+func (d *moduleKV) Get(id string) (m Module, err error) {
+	err = d.store.View(func(tx storage.ReadOnlyTx) error {
+		key := d.moduleDataKey(id)
+		if exists, err := tx.Exists(key); err != nil {
+			return err
+		} else if !exists {
+			return ErrNoModuleExists
+		}
+		kv, err := tx.Get(key)
+		if err != nil {
+			return err
+		}
+		m, err = d.decodeModule(kv.Value)
+		return err
+	})
+	return
+}
+
+func (d *moduleKV) Create(m Module) error {
+	return d.store.Update(func(tx storage.Tx) error {
+		key := d.moduleDataKey(m.ID)
+
+		exists, err := tx.Exists(key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrModuleExists
+		}
+
+		data, err := d.encodeModule(m)
+		if err != nil {
+			return err
+		}
+		// Put data
+		err = tx.Put(key, data)
+		if err != nil {
+			return err
+		}
+		// Put ID index
+		indexKey := d.moduleIndexKey(idIndex, m.ID)
+		return tx.Put(indexKey, []byte(m.ID))
+	})
+}
+
+func (d *moduleKV) Replace(m Module) error {
+	return d.store.Update(func(tx storage.Tx) error {
+		key := d.moduleDataKey(m.ID)
+
+		exists, err := tx.Exists(key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNoModuleExists
+		}
+
+		data, err := d.encodeModule(m)
+		if err != nil {
+			return err
+		}
+		// Put data
+		return tx.Put(key, data)
+	})
+}
+
+func (d *moduleKV) Delete(id string) error {
+	return d.store.Update(func(tx storage.Tx) error {
+		key := d.moduleDataKey(id)
+		indexKey := d.moduleIndexKey(idIndex, id)
+
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+		return tx.Delete(indexKey)
+	})
+}
+
+func (d *moduleKV) List(pattern string, offset, limit int) (modules []Module, err error) {
+	err = d.store.View(func(tx storage.ReadOnlyTx) error {
+		// List all module ids sorted by ID
+		ids, err := tx.List(moduleIndexesPrefix + idIndex)
+		if err != nil {
+			return err
+		}
+
+		var match func([]byte) bool
+		if pattern != "" {
+			match = func(value []byte) bool {
+				id := string(value)
+				matched, _ := path.Match(pattern, id)
+				return matched
+			}
+		} else {
+			match = func([]byte) bool { return true }
+		}
+		matches := storage.DoListFunc(ids, match, offset, limit)
+
+		modules = make([]Module, len(matches))
+		for i, id := range matches {
+			data, err := tx.Get(d.moduleDataKey(string(id)))
+			if err != nil {
+				return err
+			}
+			m, err := d.decodeModule(data.Value)
+			if err != nil {
+				return err
+			}
+			modules[i] = m
+		}
+		return nil
+	})
+	return
+}
+
 const (
 	snapshotDataPrefix = "/snapshots/data/"
 )
@@ -0,0 +1,471 @@
+package task_store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	dbmodels "github.com/influxdata/influxdb/models"
+	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/alert"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/clock"
+	"github.com/influxdata/kapacitor/influxdb"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/influxdata/kapacitor/tick/stateful"
+	"github.com/influxdata/kapacitor/uuid"
+	"github.com/pkg/errors"
+)
+
+func (ts *Service) handleEvaluateTask(w http.ResponseWriter, r *http.Request) {
+	opt := client.EvaluateTaskOptions{}
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&opt)
+	if err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	if opt.TICKscript == "" && opt.Lambda == "" {
+		httpd.HttpError(w, "must provide TICKscript or lambda", true, http.StatusBadRequest)
+		return
+	}
+	if opt.TICKscript != "" && opt.Lambda != "" {
+		httpd.HttpError(w, "cannot provide both TICKscript and lambda", true, http.StatusBadRequest)
+		return
+	}
+	points, err := parseEvaluatePoints(opt.Points, opt.Precision)
+	if err != nil {
+		httpd.HttpError(w, "invalid points: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	var result client.EvaluateResult
+	if opt.Lambda != "" {
+		result, err = evaluateLambda(opt.Lambda, points, ts.TaskMasterLookup.Main().CalendarService)
+		if err != nil {
+			httpd.HttpError(w, "invalid lambda: "+err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(httpd.MarshalJSON(result, true))
+		return
+	}
+
+	task := Task{
+		ID:         "evaluate-" + uuid.New().String(),
+		TICKscript: opt.TICKscript,
+	}
+	switch opt.Type {
+	case client.StreamTask:
+		task.Type = StreamTask
+	case client.BatchTask:
+		task.Type = BatchTask
+	case 0:
+		task.Type = Undefined
+	default:
+		httpd.HttpError(w, fmt.Sprintf("unknown type %q", opt.Type), true, http.StatusBadRequest)
+		return
+	}
+	meta, err := parseScriptMetadata(task.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if meta.Type != Undefined {
+		if task.Type != Undefined && task.Type != meta.Type {
+			httpd.HttpError(w, fmt.Sprintf("task type %s conflicts with type %s of the TICKscript", task.Type, meta.Type), true, http.StatusBadRequest)
+			return
+		}
+		task.Type = meta.Type
+	}
+	if task.Type == Undefined {
+		httpd.HttpError(w, "must provide a task type or a TICKscript with a stream or batch node", true, http.StatusBadRequest)
+		return
+	}
+	for _, dbrp := range opt.DBRPs {
+		task.DBRPs = append(task.DBRPs, DBRP{
+			Database:        dbrp.Database,
+			RetentionPolicy: dbrp.RetentionPolicy,
+		})
+	}
+	if len(task.DBRPs) == 0 {
+		task.DBRPs = meta.DBRPs
+	}
+	if task.Type == StreamTask && len(task.DBRPs) == 0 {
+		httpd.HttpError(w, "must provide at least one database and retention policy", true, http.StatusBadRequest)
+		return
+	}
+	task.Vars, err = ts.convertToServiceVars(opt.Vars)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	kt, err := ts.newKapacitorTask(task)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	// The evaluation task must never save snapshots.
+	kt.SnapshotInterval = 0
+	err = kt.Pipeline.Walk(func(n pipeline.Node) error {
+		switch n := n.(type) {
+		case *pipeline.HTTPPostNode, *pipeline.K8sAutoscaleNode:
+			return fmt.Errorf("cannot evaluate node %s, it acts on external systems", n.Name())
+		case *pipeline.UDFNode:
+			return fmt.Errorf("cannot evaluate node %s, it runs a user defined function outside of Kapacitor", n.Name())
+		case *pipeline.HTTPOutNode:
+			return fmt.Errorf("cannot evaluate node %s, it serves its data on the HTTP API", n.Name())
+		case *pipeline.AlertNode:
+			// Alert nodes without a topic only send events to their handlers,
+			// use the task ID as their topic so all events are recorded.
+			if n.Topic == "" {
+				n.Topic = kt.ID
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	result, err = ts.evaluateTask(kt, points)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(result, true))
+}
+
+// parseEvaluatePoints parses line protocol points.
+// Points without a timestamp are given the current time.
+func parseEvaluatePoints(data, precision string) ([]models.Point, error) {
+	mps, err := dbmodels.ParsePointsWithPrecision([]byte(data), time.Now().UTC(), precision)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]models.Point, len(mps))
+	for i, mp := range mps {
+		points[i] = models.Point{
+			Name:   mp.Name(),
+			Group:  models.NilGroup,
+			Tags:   models.Tags(mp.Tags().Map()),
+			Fields: models.Fields(mp.Fields()),
+			Time:   mp.Time().UTC(),
+		}
+	}
+	return points, nil
+}
+
+// evaluateLambda evaluates a lambda expression against each point in order.
+// The state of stateful functions carries over from one point to the next.
+func evaluateLambda(text string, points []models.Point, calendars stateful.Calendars) (client.EvaluateResult, error) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "lambda:")
+	lambda, err := ast.ParseLambda(text)
+	if err != nil {
+		return client.EvaluateResult{}, err
+	}
+	expression, err := stateful.NewExpressionWithCalendars(lambda.Expression, calendars)
+	if err != nil {
+		return client.EvaluateResult{}, err
+	}
+	referenceVariables := ast.FindReferenceVariables(lambda.Expression)
+
+	result := client.EvaluateResult{
+		Values: make([]client.EvaluateValue, len(points)),
+	}
+	for i, p := range points {
+		scope := stateful.NewScope()
+		for _, name := range referenceVariables {
+			if name == "time" {
+				scope.Set("time", p.Time.Local())
+			} else if value, ok := p.Fields[name]; ok {
+				scope.Set(name, value)
+			} else if value, ok := p.Tags[name]; ok {
+				scope.Set(name, value)
+			} else {
+				scope.Set(name, ast.MissingValue)
+			}
+		}
+		value, err := expression.Eval(scope)
+		if err != nil {
+			result.Values[i].Error = err.Error()
+			continue
+		}
+		result.Values[i].Value = value
+	}
+	return result, nil
+}
+
+// evaluateTask runs the task against the points in a new task master.
+// The task master does not receive live data and its InfluxDB and alert services are replaced,
+// so that the points written and the alerts triggered are recorded instead.
+func (ts *Service) evaluateTask(task *kapacitor.Task, points []models.Point) (client.EvaluateResult, error) {
+	e := newEvaluation(task.ID)
+
+	tm := ts.TaskMasterLookup.Main().New(task.ID)
+	tm.InfluxDBService = e
+	tm.AlertService = e
+	tm.K8sService = nil
+	tm.EdgeTracer = e
+
+	if err := tm.Open(); err != nil {
+		return client.EvaluateResult{}, errors.Wrap(err, "open task master")
+	}
+	defer tm.Close()
+	et, err := tm.StartTask(task)
+	if err != nil {
+		return client.EvaluateResult{}, errors.Wrap(err, "task start")
+	}
+
+	// This will force the task to stop or do nothing if it already stopped.
+	defer func() {
+		for _, b := range tm.BatchCollectors(task.ID) {
+			b.Close()
+		}
+		tm.StopTasks()
+	}()
+
+	clk := clock.Fast()
+	var replayErrC <-chan error
+	switch task.Type {
+	case kapacitor.StreamTask:
+		stream, err := tm.Stream(task.ID)
+		if err != nil {
+			return client.EvaluateResult{}, errors.Wrap(err, "stream start")
+		}
+		dbrp := task.DBRPs[0]
+		source := make(chan models.Point, len(points))
+		for _, p := range points {
+			p.Database = dbrp.Database
+			p.RetentionPolicy = dbrp.RetentionPolicy
+			source <- p
+		}
+		close(source)
+		replayErrC = kapacitor.ReplayStreamFromChan(clk, source, stream, true)
+	case kapacitor.BatchTask:
+		collectors := tm.BatchCollectors(task.ID)
+		batches := evaluateBatches(points)
+		sources := make([]<-chan models.Batch, len(collectors))
+		for i := range collectors {
+			source := make(chan models.Batch, len(batches))
+			for _, b := range batches {
+				source <- b
+			}
+			close(source)
+			sources[i] = source
+		}
+		replayErrC = kapacitor.ReplayBatchFromChan(clk, sources, collectors, true)
+	}
+	if err := <-replayErrC; err != nil {
+		return client.EvaluateResult{}, errors.Wrap(err, "evaluating points")
+	}
+
+	// Drain tm so the task can finish
+	tm.Drain()
+
+	// Stop stats nodes
+	et.StopStats()
+
+	result := client.EvaluateResult{}
+	// Errors of the task are part of the result.
+	if err := et.Wait(); err != nil {
+		result.Error = err.Error()
+	}
+	order := make(map[string]int, task.Pipeline.Len())
+	task.Pipeline.Walk(func(n pipeline.Node) error {
+		order[n.Name()] = len(order)
+		return nil
+	})
+	e.result(&result, order)
+	return result, nil
+}
+
+// evaluateBatches groups the points into a batch per measurement.
+func evaluateBatches(points []models.Point) []models.Batch {
+	var batches []models.Batch
+	index := make(map[string]int)
+	for _, p := range points {
+		i, ok := index[p.Name]
+		if !ok {
+			i = len(batches)
+			index[p.Name] = i
+			batches = append(batches, models.Batch{
+				Name:  p.Name,
+				Group: models.NilGroup,
+			})
+		}
+		b := &batches[i]
+		b.Points = append(b.Points, models.BatchPointFromPoint(p))
+		if p.Time.After(b.TMax) {
+			b.TMax = p.Time
+		}
+	}
+	return batches
+}
+
+// evaluation records the output of a task evaluated against sample points.
+// It replaces the InfluxDB and alert services of the task master
+// and traces the edges of the task.
+type evaluation struct {
+	// ID of the evaluation task, the topic of its alert nodes without a topic.
+	id string
+
+	mu     sync.Mutex
+	points []client.EvaluatePoint
+	alerts []client.EvaluateAlert
+	traces map[evaluationEdge]*client.EvaluateTrace
+}
+
+type evaluationEdge struct {
+	parent, child string
+}
+
+func newEvaluation(id string) *evaluation {
+	return &evaluation{
+		id:     id,
+		traces: make(map[evaluationEdge]*client.EvaluateTrace),
+	}
+}
+
+// result sets the recorded output on the result, the traces are sorted by the order of the nodes.
+func (e *evaluation) result(r *client.EvaluateResult, order map[string]int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r.Points = e.points
+	r.Alerts = e.alerts
+	r.Traces = make([]client.EvaluateTrace, 0, len(e.traces))
+	for _, t := range e.traces {
+		r.Traces = append(r.Traces, *t)
+	}
+	sort.Sort(evaluateTraces{traces: r.Traces, order: order})
+}
+
+func (e *evaluation) Trace(task, parent, child string, p models.PointInterface) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	key := evaluationEdge{parent: parent, child: child}
+	t, ok := e.traces[key]
+	if !ok {
+		t = &client.EvaluateTrace{
+			Parent: parent,
+			Child:  child,
+			Points: []client.EvaluatePoint{},
+		}
+		e.traces[key] = t
+	}
+	switch p := p.(type) {
+	case models.Point:
+		t.Points = append(t.Points, client.EvaluatePoint{
+			Database:        p.Database,
+			RetentionPolicy: p.RetentionPolicy,
+			Name:            p.Name,
+			Tags:            p.Tags,
+			Fields:          p.Fields,
+			Time:            p.Time,
+		})
+	case models.Batch:
+		for _, bp := range p.Points {
+			t.Points = append(t.Points, client.EvaluatePoint{
+				Name:   p.Name,
+				Tags:   bp.Tags,
+				Fields: bp.Fields,
+				Time:   bp.Time,
+			})
+		}
+	}
+}
+
+func (e *evaluation) NewNamedClient(name string) (influxdb.Client, error) {
+	return evaluationClient{e: e}, nil
+}
+
+func (e *evaluation) Collect(event alert.Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	topic := event.Topic
+	if topic == e.id {
+		topic = ""
+	} else if strings.HasPrefix(topic, e.id+":") {
+		// The event is also sent to the topic of the alert node, ignore the copy for its handlers.
+		return nil
+	}
+	e.alerts = append(e.alerts, client.EvaluateAlert{
+		Topic: topic,
+		ID:    event.State.ID,
+		State: client.EventState{
+			Message:  event.State.Message,
+			Details:  event.State.Details,
+			Time:     event.State.Time,
+			Duration: client.Duration(event.State.Duration),
+			Level:    event.State.Level.String(),
+		},
+	})
+	return nil
+}
+
+func (e *evaluation) RegisterAnonHandler(topic string, h alert.Handler)   {}
+func (e *evaluation) DeregisterAnonHandler(topic string, h alert.Handler) {}
+func (e *evaluation) UpdateEvent(topic string, event alert.EventState) error {
+	return nil
+}
+func (e *evaluation) EventState(topic, event string) (alert.EventState, bool, error) {
+	return alert.EventState{}, false, nil
+}
+func (e *evaluation) CloseTopic(topic string) error   { return nil }
+func (e *evaluation) DeleteTopic(topic string) error  { return nil }
+func (e *evaluation) RestoreTopic(topic string) error { return nil }
+
+// evaluationClient records the points written to InfluxDB.
+type evaluationClient struct {
+	e *evaluation
+}
+
+func (c evaluationClient) Ping(ctx context.Context) (time.Duration, string, error) {
+	return 0, "", nil
+}
+
+func (c evaluationClient) Write(bp influxdb.BatchPoints) error {
+	c.e.mu.Lock()
+	defer c.e.mu.Unlock()
+	for _, p := range bp.Points() {
+		c.e.points = append(c.e.points, client.EvaluatePoint{
+			Database:        bp.Database(),
+			RetentionPolicy: bp.RetentionPolicy(),
+			Name:            p.Name,
+			Tags:            p.Tags,
+			Fields:          p.Fields,
+			Time:            p.Time,
+		})
+	}
+	return nil
+}
+
+func (c evaluationClient) Query(q influxdb.Query) (*influxdb.Response, error) {
+	return &influxdb.Response{}, nil
+}
+
+type evaluateTraces struct {
+	traces []client.EvaluateTrace
+	order  map[string]int
+}
+
+func (t evaluateTraces) Len() int      { return len(t.traces) }
+func (t evaluateTraces) Swap(i, j int) { t.traces[i], t.traces[j] = t.traces[j], t.traces[i] }
+func (t evaluateTraces) Less(i, j int) bool {
+	pi, pj := t.order[t.traces[i].Parent], t.order[t.traces[j].Parent]
+	if pi != pj {
+		return pi < pj
+	}
+	return t.order[t.traces[i].Child] < t.order[t.traces[j].Child]
+}
@@ -0,0 +1,707 @@
+package task_store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/server/vars"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pkg/errors"
+)
+
+const (
+	taskGroupsPath         = "/task-groups"
+	taskGroupsPathAnchored = "/task-groups/"
+	taskGraphPath          = "/tasks/graph"
+
+	taskGroupEnablePath  = "enable"
+	taskGroupDisablePath = "disable"
+	taskGroupReloadPath  = "reload"
+)
+
+const taskGroupsBasePathAnchored = httpd.BasePath + taskGroupsPathAnchored
+
+func (ts *Service) taskGroupIDFromPath(path string) (string, error) {
+	if len(path) <= len(taskGroupsBasePathAnchored) {
+		return "", errors.New("must specify task group id on path")
+	}
+	id := path[len(taskGroupsBasePathAnchored):]
+	return id, nil
+}
+
+func (ts *Service) taskGroupLink(id string) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, taskGroupsPath, id)}
+}
+
+// A taskEdge is a dependency between two tasks, the From task is enabled after and disabled before the To task.
+type taskEdge struct {
+	From string
+	To   string
+	// Inferred is true if the edge was inferred from a KapacitorLoopback node.
+	Inferred bool
+	// Label is the database, retention policy and measurement written via the KapacitorLoopback node.
+	Label string
+}
+
+// taskDataflow is the data a task writes via KapacitorLoopback nodes and the data it reads.
+type taskDataflow struct {
+	id        string
+	stream    bool
+	dbrps     []DBRP
+	loopbacks []*pipeline.KapacitorLoopbackNode
+	froms     []*pipeline.FromNode
+}
+
+// reads reports whether the task receives the data written by the loopback node.
+func (d taskDataflow) reads(l *pipeline.KapacitorLoopbackNode) bool {
+	if !d.stream {
+		return false
+	}
+	allowed := false
+	for _, dbrp := range d.dbrps {
+		if dbrp.Database == l.Database && dbrp.RetentionPolicy == l.RetentionPolicy {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+	for _, f := range d.froms {
+		if (f.Database == "" || f.Database == l.Database) &&
+			(f.RetentionPolicy == "" || f.RetentionPolicy == l.RetentionPolicy) &&
+			(f.Measurement == "" || f.Measurement == l.Measurement) {
+			return true
+		}
+	}
+	return false
+}
+
+// taskDataflows collects the loopback and from nodes of the tasks.
+// Tasks with invalid TICKscripts are skipped since they cannot run.
+func (ts *Service) taskDataflows(tasks []Task) []taskDataflow {
+	dataflows := make([]taskDataflow, 0, len(tasks))
+	for _, task := range tasks {
+		t, err := ts.newKapacitorTask(task)
+		if err != nil {
+			ts.logger.Printf("D! skipping task %s while inferring loopback dependencies: %s", task.ID, err)
+			continue
+		}
+		d := taskDataflow{
+			id:     task.ID,
+			stream: task.Type == StreamTask,
+			dbrps:  task.DBRPs,
+		}
+		t.Pipeline.Walk(func(n pipeline.Node) error {
+			switch n := n.(type) {
+			case *pipeline.KapacitorLoopbackNode:
+				d.loopbacks = append(d.loopbacks, n)
+			case *pipeline.FromNode:
+				d.froms = append(d.froms, n)
+			}
+			return nil
+		})
+		dataflows = append(dataflows, d)
+	}
+	return dataflows
+}
+
+// loopbackEdges infers the dependencies between the tasks that write data via KapacitorLoopback nodes
+// and the tasks that read that data.
+// The writing task depends on the reading task, so that no data is lost while the reading task is not running.
+func (ts *Service) loopbackEdges(tasks []Task) []taskEdge {
+	dataflows := ts.taskDataflows(tasks)
+	var edges []taskEdge
+	for _, producer := range dataflows {
+		for _, l := range producer.loopbacks {
+			for _, consumer := range dataflows {
+				if consumer.id == producer.id || !consumer.reads(l) {
+					continue
+				}
+				edges = append(edges, taskEdge{
+					From:     producer.id,
+					To:       consumer.id,
+					Inferred: true,
+					Label:    fmt.Sprintf("%s.%s.%s", l.Database, l.RetentionPolicy, l.Measurement),
+				})
+			}
+		}
+	}
+	return edges
+}
+
+// declaredEdges returns the dependencies declared by the group.
+func declaredEdges(g TaskGroup) []taskEdge {
+	var edges []taskEdge
+	for _, id := range sortedKeys(g.Dependencies) {
+		for _, dep := range g.Dependencies[id] {
+			edges = append(edges, taskEdge{
+				From: id,
+				To:   dep,
+			})
+		}
+	}
+	return edges
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// orderTasks sorts the tasks so that every task comes after the tasks it depends on.
+// Edges between tasks that are not in ids are ignored.
+// Tasks without dependencies between them are ordered by ID.
+func orderTasks(ids []string, edges []taskEdge) ([]string, error) {
+	pending := make(map[string]map[string]bool, len(ids))
+	for _, id := range ids {
+		pending[id] = make(map[string]bool)
+	}
+	dependents := make(map[string][]string)
+	for _, e := range edges {
+		deps, ok := pending[e.From]
+		if _, toOK := pending[e.To]; !ok || !toOK || e.From == e.To || deps[e.To] {
+			continue
+		}
+		deps[e.To] = true
+		dependents[e.To] = append(dependents[e.To], e.From)
+	}
+
+	var ready []string
+	for id, deps := range pending {
+		if len(deps) == 0 {
+			ready = append(ready, id)
+		}
+	}
+	order := make([]string, 0, len(ids))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		delete(pending, id)
+		for _, d := range dependents[id] {
+			deps := pending[d]
+			delete(deps, id)
+			if len(deps) == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if len(pending) > 0 {
+		cycle := make([]string, 0, len(pending))
+		for id := range pending {
+			cycle = append(cycle, id)
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("dependencies of tasks %s form a cycle", strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+// groupTasks returns the tasks of the group.
+func (ts *Service) groupTasks(g TaskGroup) ([]Task, error) {
+	tasks := make([]Task, len(g.Tasks))
+	for i, id := range g.Tasks {
+		task, err := ts.tasks.Get(id)
+		if err != nil {
+			return nil, fmt.Errorf("task %s of group %s: %s", id, g.ID, err)
+		}
+		tasks[i] = task
+	}
+	return tasks, nil
+}
+
+// groupOrder returns the order the tasks of the group are enabled in.
+func (ts *Service) groupOrder(g TaskGroup, tasks []Task) ([]string, error) {
+	edges := append(declaredEdges(g), ts.loopbackEdges(tasks)...)
+	return orderTasks(g.Tasks, edges)
+}
+
+// startupOrder returns the order to start the enabled tasks in on startup,
+// it honors the dependencies of all groups.
+func (ts *Service) startupOrder(tasks []Task) ([]string, error) {
+	edges := ts.loopbackEdges(tasks)
+	const limit = 100
+	for offset := 0; ; offset += limit {
+		groups, err := ts.groups.List("", offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			edges = append(edges, declaredEdges(g)...)
+		}
+		if len(groups) != limit {
+			break
+		}
+	}
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return orderTasks(ids, edges)
+}
+
+// setTaskStatus enables or disables a task, it does nothing if the task already has the status.
+func (ts *Service) setTaskStatus(task Task, status Status) (Task, error) {
+	if task.Status == status {
+		return task, nil
+	}
+	previous := task.Status
+	now := time.Now()
+	task.Status = status
+	task.Modified = now
+	if status == Enabled {
+		task.LastEnabled = now
+	}
+	if err := ts.tasks.Replace(task); err != nil {
+		return task, fmt.Errorf("failed to replace task definition: %s", err)
+	}
+	switch status {
+	case Enabled:
+		vars.NumEnabledTasksVar.Add(1)
+		if err := ts.startTask(task); err != nil {
+			return task, err
+		}
+	case Disabled:
+		if previous == Enabled {
+			vars.NumEnabledTasksVar.Add(-1)
+			ts.stopTask(task.ID)
+		}
+	}
+	return task, nil
+}
+
+func (ts *Service) convertTaskGroup(g TaskGroup, tasks []Task) client.TaskGroup {
+	deps := g.Dependencies
+	if deps == nil {
+		deps = make(map[string][]string)
+	}
+	cg := client.TaskGroup{
+		Link:         ts.taskGroupLink(g.ID),
+		ID:           g.ID,
+		Tasks:        g.Tasks,
+		Dependencies: deps,
+		Created:      g.Created,
+		Modified:     g.Modified,
+	}
+	if cg.Tasks == nil {
+		cg.Tasks = []string{}
+	}
+	order, err := ts.groupOrder(g, tasks)
+	if err != nil {
+		cg.Error = err.Error()
+	}
+	cg.Order = order
+	if cg.Order == nil {
+		cg.Order = []string{}
+	}
+	return cg
+}
+
+// validateTaskGroup checks that the tasks of the group exist and that their dependencies can be ordered.
+// It returns the tasks of the group.
+func (ts *Service) validateTaskGroup(g TaskGroup) ([]Task, error) {
+	if !validTaskID.MatchString(g.ID) {
+		return nil, fmt.Errorf("task group ID must contain only letters, numbers, '-', '.' and '_'. %q", g.ID)
+	}
+	members := make(map[string]bool, len(g.Tasks))
+	for _, id := range g.Tasks {
+		if members[id] {
+			return nil, fmt.Errorf("task %s is listed more than once", id)
+		}
+		members[id] = true
+	}
+	for id, deps := range g.Dependencies {
+		if !members[id] {
+			return nil, fmt.Errorf("task %s has dependencies but is not a task of the group", id)
+		}
+		for _, dep := range deps {
+			if !members[dep] {
+				return nil, fmt.Errorf("task %s depends on %s which is not a task of the group", id, dep)
+			}
+			if dep == id {
+				return nil, fmt.Errorf("task %s cannot depend on itself", id)
+			}
+		}
+	}
+	tasks, err := ts.groupTasks(g)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ts.groupOrder(g, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (ts *Service) handleTaskGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := ts.taskGroupIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	g, err := ts.groups.Get(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	tasks, err := ts.groupTasks(g)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(ts.convertTaskGroup(g, tasks), true))
+}
+
+func (ts *Service) handleListTaskGroups(w http.ResponseWriter, r *http.Request) {
+	pattern := r.URL.Query().Get("pattern")
+
+	var err error
+	offset := int64(0)
+	offsetStr := r.URL.Query().Get("offset")
+	if offsetStr != "" {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid offset parameter %q must be an integer: %s", offsetStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := int64(100)
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid limit parameter %q must be an integer: %s", limitStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rawGroups, err := ts.groups.List(pattern, int(offset), int(limit))
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list task groups with pattern %q: %s", pattern, err), true, http.StatusBadRequest)
+		return
+	}
+	groups := make([]client.TaskGroup, len(rawGroups))
+	for i, g := range rawGroups {
+		tasks, err := ts.groupTasks(g)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+			return
+		}
+		groups[i] = ts.convertTaskGroup(g, tasks)
+	}
+
+	type response struct {
+		TaskGroups []client.TaskGroup `json:"task-groups"`
+	}
+	w.Write(httpd.MarshalJSON(response{groups}, true))
+}
+
+func (ts *Service) handleCreateTaskGroup(w http.ResponseWriter, r *http.Request) {
+	opt := client.CreateTaskGroupOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	g := TaskGroup{
+		ID:           opt.ID,
+		Tasks:        opt.Tasks,
+		Dependencies: opt.Dependencies,
+		Created:      now,
+		Modified:     now,
+	}
+	tasks, err := ts.validateTaskGroup(g)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := ts.groups.Create(g); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(ts.convertTaskGroup(g, tasks), true))
+}
+
+func (ts *Service) handleUpdateTaskGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := ts.taskGroupIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	opt := client.UpdateTaskGroupOptions{}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&opt); err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+
+	original, err := ts.groups.Get(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	updated := original
+	if opt.ID != "" {
+		updated.ID = opt.ID
+	}
+	if opt.Tasks != nil {
+		updated.Tasks = opt.Tasks
+	}
+	if opt.Dependencies != nil {
+		updated.Dependencies = opt.Dependencies
+	}
+	tasks, err := ts.validateTaskGroup(updated)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	updated.Modified = time.Now()
+	if original.ID != updated.ID {
+		if err := ts.groups.Create(updated); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+		if err := ts.groups.Delete(original.ID); err != nil {
+			ts.logger.Printf("E! failed to delete old task group during ID change: old ID: %s new ID: %s, %s", original.ID, updated.ID, err)
+		}
+	} else if err := ts.groups.Replace(updated); err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to replace task group: %s", err), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(ts.convertTaskGroup(updated, tasks), true))
+}
+
+func (ts *Service) handleDeleteTaskGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := ts.taskGroupIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if err := ts.groups.Delete(id); err != nil && err != ErrNoTaskGroupExists {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTaskGroupAction serves the POST requests of the actions of a task group,
+// i.e. /task-groups/<id>/enable, /task-groups/<id>/disable and /task-groups/<id>/reload.
+func (ts *Service) handleTaskGroupAction(w http.ResponseWriter, r *http.Request, user auth.User) {
+	p, err := ts.taskGroupIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	id, action := path.Split(p)
+	id = strings.TrimSuffix(id, "/")
+	if id == "" || (action != taskGroupEnablePath && action != taskGroupDisablePath && action != taskGroupReloadPath) {
+		httpd.HttpError(w, fmt.Sprintf("unknown task group action %q", p), true, http.StatusNotFound)
+		return
+	}
+
+	g, err := ts.groups.Get(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	// The group changes the status of its tasks, so the user must be allowed to change each of them.
+	for _, taskID := range g.Tasks {
+		action := auth.Action{
+			Resource:  auth.APIResource(path.Join(tasksPath, taskID)),
+			Privilege: auth.WritePrivilege,
+		}
+		if err := user.AuthorizeAction(action); err != nil {
+			httpd.HttpError(w, fmt.Sprintf("not authorized to change task %s of group %s: %s", taskID, g.ID, err), true, http.StatusForbidden)
+			return
+		}
+	}
+	tasks, err := ts.groupTasks(g)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	order, err := ts.groupOrder(g, tasks)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	byID := make(map[string]Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	if action == taskGroupDisablePath || action == taskGroupReloadPath {
+		for i := len(order) - 1; i >= 0; i-- {
+			task, err := ts.setTaskStatus(byID[order[i]], Disabled)
+			if err != nil {
+				httpd.HttpError(w, fmt.Sprintf("failed to disable task %s: %s", order[i], err), true, http.StatusInternalServerError)
+				return
+			}
+			byID[task.ID] = task
+		}
+	}
+	if action == taskGroupEnablePath || action == taskGroupReloadPath {
+		for _, taskID := range order {
+			task, err := ts.setTaskStatus(byID[taskID], Enabled)
+			if err != nil {
+				httpd.HttpError(w, fmt.Sprintf("failed to enable task %s: %s", taskID, err), true, http.StatusInternalServerError)
+				return
+			}
+			byID[task.ID] = task
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(ts.convertTaskGroup(g, tasks), true))
+}
+
+// renameGroupTask renames a task in the groups and their dependencies.
+// If newID is empty the task was deleted and is removed from the groups instead.
+func (ts *Service) renameGroupTask(id, newID string) error {
+	const limit = 100
+	var changed []TaskGroup
+	for offset := 0; ; offset += limit {
+		groups, err := ts.groups.List("", offset, limit)
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			if g.renameTask(id, newID) {
+				changed = append(changed, g)
+			}
+		}
+		if len(groups) != limit {
+			break
+		}
+	}
+	for _, g := range changed {
+		g.Modified = time.Now()
+		if err := ts.groups.Replace(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameTask renames the task in the group, or removes it if newID is empty.
+// It reports whether the group contained the task.
+func (g *TaskGroup) renameTask(id, newID string) bool {
+	rename := func(ids []string) ([]string, bool) {
+		found := false
+		renamed := make([]string, 0, len(ids))
+		for _, i := range ids {
+			if i == id {
+				found = true
+				if newID == "" {
+					continue
+				}
+				i = newID
+			}
+			renamed = append(renamed, i)
+		}
+		return renamed, found
+	}
+
+	var found bool
+	g.Tasks, found = rename(g.Tasks)
+	if !found {
+		return false
+	}
+	deps := make(map[string][]string, len(g.Dependencies))
+	for task, d := range g.Dependencies {
+		d, _ = rename(d)
+		if task == id {
+			if newID == "" {
+				continue
+			}
+			task = newID
+		}
+		if len(d) > 0 {
+			deps[task] = d
+		}
+	}
+	g.Dependencies = deps
+	return true
+}
+
+// handleTaskGraph serves the graph of the dependencies between all tasks in DOT format.
+func (ts *Service) handleTaskGraph(w http.ResponseWriter, r *http.Request) {
+	var tasks []Task
+	const limit = 100
+	for offset := 0; ; offset += limit {
+		list, err := ts.tasks.List("", offset, limit)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("failed to list tasks: %s", err), true, http.StatusInternalServerError)
+			return
+		}
+		tasks = append(tasks, list...)
+		if len(list) != limit {
+			break
+		}
+	}
+	edges := ts.loopbackEdges(tasks)
+	for offset := 0; ; offset += limit {
+		groups, err := ts.groups.List("", offset, limit)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("failed to list task groups: %s", err), true, http.StatusInternalServerError)
+			return
+		}
+		for _, g := range groups {
+			edges = append(edges, declaredEdges(g)...)
+		}
+		if len(groups) != limit {
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph tasks {\n")
+	for _, task := range tasks {
+		fmt.Fprintf(&buf, "%q;\n", task.ID)
+	}
+	graph := client.TaskGraph{
+		Link:  client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, taskGraphPath)},
+		Edges: make([]client.TaskGraphEdge, len(edges)),
+	}
+	for i, e := range edges {
+		if e.Inferred {
+			fmt.Fprintf(&buf, "%q -> %q [label=%q];\n", e.From, e.To, e.Label)
+		} else {
+			fmt.Fprintf(&buf, "%q -> %q [style=dashed];\n", e.From, e.To)
+		}
+		graph.Edges[i] = client.TaskGraphEdge{
+			From:     e.From,
+			To:       e.To,
+			Inferred: e.Inferred,
+			Label:    e.Label,
+		}
+	}
+	buf.WriteString("}")
+	graph.Dot = buf.String()
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(graph, true))
+}
@@ -0,0 +1,100 @@
+package task_store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/server/vars"
+)
+
+// taskLimits returns the limits of a task, using the configured limits for the limits the task does not set.
+func (ts *Service) taskLimits(l TaskLimits) kapacitor.TaskLimits {
+	limits := ts.limits
+	if l.MaxGroups > 0 {
+		limits.MaxGroups = l.MaxGroups
+	}
+	if l.MaxBufferedPoints > 0 {
+		limits.MaxBufferedPoints = l.MaxBufferedPoints
+	}
+	if l.MaxEdgeQueue > 0 {
+		limits.MaxEdgeQueue = int(l.MaxEdgeQueue)
+	}
+	if l.MaxCPU > 0 {
+		limits.MaxCPU = l.MaxCPU
+	}
+	if l.SampleInterval > 0 {
+		limits.SampleInterval = l.SampleInterval
+	}
+	switch l.Action {
+	case LimitDropOldest:
+		limits.Action = kapacitor.LimitDropOldest
+	case LimitPause:
+		limits.Action = kapacitor.LimitPause
+	case LimitStop:
+		limits.Action = kapacitor.LimitStop
+	}
+	return limits
+}
+
+// quarantineTask changes the status of an enabled task that was stopped for exceeding its limits to quarantined.
+func (ts *Service) quarantineTask(id string, qerr *kapacitor.QuarantineError) error {
+	task, err := ts.tasks.Get(id)
+	if err != nil {
+		return err
+	}
+	task.Error = qerr.Error()
+	if task.Status == Enabled {
+		task.Status = Quarantined
+		vars.NumEnabledTasksVar.Add(-1)
+	}
+	return ts.tasks.Replace(task)
+}
+
+func convertToServiceLimits(l client.TaskLimits) (TaskLimits, error) {
+	if l.MaxGroups < 0 || l.MaxBufferedPoints < 0 || l.MaxEdgeQueue < 0 || l.MaxCPU < 0 || l.SampleInterval < 0 {
+		return TaskLimits{}, fmt.Errorf("invalid limits: limits must not be negative")
+	}
+	var action LimitAction
+	switch l.Action {
+	case client.DefaultLimitAction:
+		action = DefaultLimitAction
+	case client.LimitDropOldest:
+		action = LimitDropOldest
+	case client.LimitPause:
+		action = LimitPause
+	case client.LimitStop:
+		action = LimitStop
+	default:
+		return TaskLimits{}, fmt.Errorf("invalid limits: unknown action %v", l.Action)
+	}
+	return TaskLimits{
+		MaxGroups:         l.MaxGroups,
+		MaxBufferedPoints: l.MaxBufferedPoints,
+		MaxEdgeQueue:      l.MaxEdgeQueue,
+		MaxCPU:            l.MaxCPU,
+		SampleInterval:    time.Duration(l.SampleInterval),
+		Action:            action,
+	}, nil
+}
+
+func convertToClientLimits(l TaskLimits) client.TaskLimits {
+	var action client.LimitAction
+	switch l.Action {
+	case LimitDropOldest:
+		action = client.LimitDropOldest
+	case LimitPause:
+		action = client.LimitPause
+	case LimitStop:
+		action = client.LimitStop
+	}
+	return client.TaskLimits{
+		MaxGroups:         l.MaxGroups,
+		MaxBufferedPoints: l.MaxBufferedPoints,
+		MaxEdgeQueue:      l.MaxEdgeQueue,
+		MaxCPU:            l.MaxCPU,
+		SampleInterval:    client.Duration(l.SampleInterval),
+		Action:            action,
+	}
+}
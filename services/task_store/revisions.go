@@ -0,0 +1,497 @@
+package task_store
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/kapacitor/auth"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	taskRevisionsPath = "revisions"
+	taskDiffPath      = "diff"
+	taskRollbackPath  = "rollback"
+)
+
+func newTaskRevision(task Task) TaskRevision {
+	return TaskRevision{
+		TaskID:     task.ID,
+		Type:       task.Type,
+		DBRPs:      task.DBRPs,
+		TICKscript: task.TICKscript,
+		TemplateID: task.TemplateID,
+		Vars:       task.Vars,
+	}
+}
+
+// sameDefinition reports whether both revisions define the same task.
+func (r TaskRevision) sameDefinition(o TaskRevision) bool {
+	return r.Type == o.Type &&
+		r.TICKscript == o.TICKscript &&
+		r.TemplateID == o.TemplateID &&
+		dbrpsEqual(r.DBRPs, o.DBRPs) &&
+		varsEqual(r.Vars, o.Vars)
+}
+
+func varsEqual(a, b map[string]Var) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, v := range a {
+		if o, ok := b[name]; !ok || !reflect.DeepEqual(v, o) {
+			return false
+		}
+	}
+	return true
+}
+
+// maxRevisionAttempts is the number of times the next revision number of a task is computed
+// before giving up when concurrent updates of the task record the same revision number.
+const maxRevisionAttempts = 10
+
+// saveRevision records the definition of the task as its next revision,
+// unless the definition is unchanged since the latest revision.
+// Errors are logged since the task itself has already been saved.
+func (ts *Service) saveRevision(task Task, author string, created time.Time) {
+	r := newTaskRevision(task)
+	r.Author = author
+	r.Created = created
+	for attempt := 0; attempt < maxRevisionAttempts; attempt++ {
+		r.Revision = 1
+		latest, err := ts.revisions.List(task.ID, 0, 1)
+		if err != nil {
+			ts.logger.Printf("E! failed to record revision of task %s: %s", task.ID, err)
+			return
+		}
+		if len(latest) > 0 {
+			if latest[0].sameDefinition(r) {
+				return
+			}
+			r.Revision = latest[0].Revision + 1
+		}
+		err = ts.revisions.Create(r)
+		if err == ErrTaskRevisionExists {
+			// A concurrent update recorded the revision first, record this one after it.
+			continue
+		}
+		if err != nil {
+			ts.logger.Printf("E! failed to record revision %d of task %s: %s", r.Revision, task.ID, err)
+		}
+		return
+	}
+	ts.logger.Printf("E! failed to record revision of task %s: revision %d was recorded concurrently", task.ID, r.Revision)
+}
+
+// renameRevisions moves the revisions of a task to its new ID.
+func (ts *Service) renameRevisions(oldID, newID string) error {
+	const limit = 100
+	for offset := 0; ; offset += limit {
+		revisions, err := ts.revisions.List(oldID, offset, limit)
+		if err != nil {
+			return err
+		}
+		for _, r := range revisions {
+			r.TaskID = newID
+			if err := ts.revisions.Create(r); err != nil {
+				return err
+			}
+		}
+		if len(revisions) != limit {
+			break
+		}
+	}
+	return ts.revisions.Delete(oldID)
+}
+
+func (ts *Service) taskRevisionLink(id string, revision int64) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, tasksPath, id, taskRevisionsPath, strconv.FormatInt(revision, 10))}
+}
+
+func (ts *Service) convertTaskRevision(r TaskRevision) (client.TaskRevision, error) {
+	var typ client.TaskType
+	switch r.Type {
+	case StreamTask:
+		typ = client.StreamTask
+	case BatchTask:
+		typ = client.BatchTask
+	default:
+		return client.TaskRevision{}, fmt.Errorf("invalid task type %v", r.Type)
+	}
+
+	dbrps := make([]client.DBRP, len(r.DBRPs))
+	for i, dbrp := range r.DBRPs {
+		dbrps[i] = client.DBRP{
+			Database:        dbrp.Database,
+			RetentionPolicy: dbrp.RetentionPolicy,
+		}
+	}
+
+	vars, err := ts.convertToClientVars(r.Vars)
+	if err != nil {
+		return client.TaskRevision{}, err
+	}
+
+	return client.TaskRevision{
+		Link:       ts.taskRevisionLink(r.TaskID, r.Revision),
+		TaskID:     r.TaskID,
+		Revision:   r.Revision,
+		TemplateID: r.TemplateID,
+		Type:       typ,
+		DBRPs:      dbrps,
+		TICKscript: r.TICKscript,
+		Vars:       vars,
+		Author:     r.Author,
+		Created:    r.Created,
+	}, nil
+}
+
+// handleTaskResource serves the GET requests of the resources of a task,
+// i.e. /tasks/<id>/revisions.
+func (ts *Service) handleTaskResource(w http.ResponseWriter, r *http.Request, id, resource string) {
+	switch {
+	case resource == taskRevisionsPath:
+		ts.handleListTaskRevisions(w, r, id)
+	case strings.HasPrefix(resource, taskRevisionsPath+"/"):
+		revision, err := strconv.ParseInt(resource[len(taskRevisionsPath)+1:], 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid revision %q must be an integer", resource[len(taskRevisionsPath)+1:]), true, http.StatusBadRequest)
+			return
+		}
+		ts.handleTaskRevision(w, r, id, revision)
+	case resource == taskDiffPath:
+		ts.handleDiffTaskRevisions(w, r, id)
+	default:
+		httpd.HttpError(w, fmt.Sprintf("unknown resource %q of task %s", resource, id), true, http.StatusNotFound)
+	}
+}
+
+func (ts *Service) handleListTaskRevisions(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := ts.tasks.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	var err error
+	offset := int64(0)
+	offsetStr := r.URL.Query().Get("offset")
+	if offsetStr != "" {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid offset parameter %q must be an integer: %s", offsetStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := int64(100)
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid limit parameter %q must be an integer: %s", limitStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rawRevisions, err := ts.revisions.List(id, int(offset), int(limit))
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list revisions of task %s: %s", id, err), true, http.StatusInternalServerError)
+		return
+	}
+	revisions := make([]client.TaskRevision, len(rawRevisions))
+	for i, rev := range rawRevisions {
+		revisions[i], err = ts.convertTaskRevision(rev)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid task revision stored in db: %s", err), true, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type response struct {
+		Revisions []client.TaskRevision `json:"revisions"`
+	}
+	w.Write(httpd.MarshalJSON(response{revisions}, true))
+}
+
+func (ts *Service) handleTaskRevision(w http.ResponseWriter, r *http.Request, id string, revision int64) {
+	raw, err := ts.revisions.Get(id, revision)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("task %s has no revision %d", id, revision), true, http.StatusNotFound)
+		return
+	}
+	rev, err := ts.convertTaskRevision(raw)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("invalid task revision stored in db: %s", err), true, http.StatusInternalServerError)
+		return
+	}
+	w.Write(httpd.MarshalJSON(rev, true))
+}
+
+// handleDiffTaskRevisions compares two revisions of a task.
+// By default the latest revision is compared to the revision before it.
+func (ts *Service) handleDiffTaskRevisions(w http.ResponseWriter, r *http.Request, id string) {
+	var revisions [2]int64
+	for i, name := range []string{"from", "to"} {
+		if s := r.URL.Query().Get(name); s != "" {
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				httpd.HttpError(w, fmt.Sprintf("invalid %s parameter %q must be an integer: %s", name, s, err), true, http.StatusBadRequest)
+				return
+			}
+			revisions[i] = v
+		}
+	}
+	from, to := revisions[0], revisions[1]
+	if to == 0 {
+		latest, err := ts.revisions.List(id, 0, 1)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("failed to list revisions of task %s: %s", id, err), true, http.StatusInternalServerError)
+			return
+		}
+		if len(latest) == 0 {
+			httpd.HttpError(w, fmt.Sprintf("task %s has no revisions", id), true, http.StatusNotFound)
+			return
+		}
+		to = latest[0].Revision
+	}
+	if from == 0 {
+		from = to - 1
+	}
+
+	var old, new TaskRevision
+	for _, rev := range []struct {
+		n int64
+		r *TaskRevision
+	}{{from, &old}, {to, &new}} {
+		var err error
+		*rev.r, err = ts.revisions.Get(id, rev.n)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("task %s has no revision %d", id, rev.n), true, http.StatusNotFound)
+			return
+		}
+	}
+
+	scriptDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        scriptLines(old.TICKscript),
+		B:        scriptLines(new.TICKscript),
+		FromFile: fmt.Sprintf("revision %d", old.Revision),
+		ToFile:   fmt.Sprintf("revision %d", new.Revision),
+		Context:  3,
+	})
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to compare TICKscripts: %s", err), true, http.StatusInternalServerError)
+		return
+	}
+	diff := client.TaskRevisionDiff{
+		From:       old.Revision,
+		To:         new.Revision,
+		Changes:    revisionChanges(old, new),
+		ScriptDiff: scriptDiff,
+	}
+	w.Write(httpd.MarshalJSON(diff, true))
+}
+
+// scriptLines splits the script into lines that end with a newline.
+func scriptLines(script string) []string {
+	lines := strings.SplitAfter(script, "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n"
+	}
+	return lines
+}
+
+// revisionChanges describes the changes of the task definition other than the TICKscript.
+func revisionChanges(old, new TaskRevision) []string {
+	changes := []string{}
+	if old.Type != new.Type {
+		changes = append(changes, fmt.Sprintf("type: %v -> %v", old.Type, new.Type))
+	}
+	if old.TemplateID != new.TemplateID {
+		changes = append(changes, fmt.Sprintf("template-id: %q -> %q", old.TemplateID, new.TemplateID))
+	}
+	if !dbrpsEqual(old.DBRPs, new.DBRPs) {
+		changes = append(changes, fmt.Sprintf("dbrps: %s -> %s", formatDBRPs(old.DBRPs), formatDBRPs(new.DBRPs)))
+	}
+	names := make([]string, 0, len(old.Vars)+len(new.Vars))
+	for name := range old.Vars {
+		names = append(names, name)
+	}
+	for name := range new.Vars {
+		if _, ok := old.Vars[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o, inOld := old.Vars[name]
+		n, inNew := new.Vars[name]
+		switch {
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("var %s added: %s", name, formatVar(n)))
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("var %s deleted", name))
+		case !reflect.DeepEqual(o, n):
+			changes = append(changes, fmt.Sprintf("var %s: %s -> %s", name, formatVar(o), formatVar(n)))
+		}
+	}
+	return changes
+}
+
+func formatDBRPs(dbrps []DBRP) string {
+	s := make([]string, len(dbrps))
+	for i, dbrp := range dbrps {
+		s[i] = fmt.Sprintf("%q.%q", dbrp.Database, dbrp.RetentionPolicy)
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+func formatVar(v Var) string {
+	switch v.Type {
+	case VarBool:
+		return strconv.FormatBool(v.BoolValue)
+	case VarInt:
+		return strconv.FormatInt(v.IntValue, 10)
+	case VarFloat:
+		return strconv.FormatFloat(v.FloatValue, 'f', -1, 64)
+	case VarString:
+		return strconv.Quote(v.StringValue)
+	case VarRegex:
+		return "/" + v.RegexValue + "/"
+	case VarDuration:
+		return v.DurationValue.String()
+	case VarLambda:
+		return "lambda: " + v.LambdaValue
+	case VarStar:
+		return "*"
+	case VarList:
+		values := make([]string, len(v.ListValue))
+		for i, l := range v.ListValue {
+			values[i] = formatVar(l)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	default:
+		return v.Type.String()
+	}
+}
+
+// handleTaskAction serves the POST requests of the actions of a task,
+// i.e. /tasks/<id>/rollback.
+func (ts *Service) handleTaskAction(w http.ResponseWriter, r *http.Request, user auth.User) {
+	p, err := ts.taskIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	id, action := path.Split(p)
+	id = strings.TrimSuffix(id, "/")
+	switch {
+	case id != "" && action == taskRollbackPath:
+		ts.handleRollbackTask(w, r, user, id)
+	default:
+		httpd.HttpError(w, fmt.Sprintf("unknown task action %q", p), true, http.StatusNotFound)
+	}
+}
+
+// handleRollbackTask restores the definition of a task from one of its revisions.
+// The status of the task is unchanged, the task is reloaded if it is enabled.
+func (ts *Service) handleRollbackTask(w http.ResponseWriter, r *http.Request, user auth.User, id string) {
+	revisionStr := r.URL.Query().Get("revision")
+	if revisionStr == "" {
+		httpd.HttpError(w, "must provide revision parameter", true, http.StatusBadRequest)
+		return
+	}
+	revision, err := strconv.ParseInt(revisionStr, 10, 64)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("invalid revision parameter %q must be an integer: %s", revisionStr, err), true, http.StatusBadRequest)
+		return
+	}
+
+	original, err := ts.tasks.Get(id)
+	if err != nil {
+		httpd.HttpError(w, "task does not exist, cannot rollback", true, http.StatusNotFound)
+		return
+	}
+	rev, err := ts.revisions.Get(id, revision)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("task %s has no revision %d", id, revision), true, http.StatusNotFound)
+		return
+	}
+
+	updated := original
+	updated.Type = rev.Type
+	updated.DBRPs = rev.DBRPs
+	updated.TICKscript = rev.TICKscript
+	updated.TemplateID = rev.TemplateID
+	updated.Vars = rev.Vars
+
+	// Validate task
+	if _, err := ts.newKapacitorTask(updated); err != nil {
+		httpd.HttpError(w, fmt.Sprintf("invalid TICKscript of revision %d: %s", revision, err), true, http.StatusBadRequest)
+		return
+	}
+	updated.Modules, err = ts.moduleVersions(updated.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("invalid TICKscript of revision %d: %s", revision, err), true, http.StatusBadRequest)
+		return
+	}
+
+	if original.TemplateID != updated.TemplateID {
+		if updated.TemplateID != "" {
+			if _, err := ts.templates.Get(updated.TemplateID); err != nil {
+				httpd.HttpError(w, fmt.Sprintf("template %s of revision %d: %s", updated.TemplateID, revision, err), true, http.StatusBadRequest)
+				return
+			}
+			if err := ts.templates.AssociateTask(updated.TemplateID, id); err != nil {
+				httpd.HttpError(w, fmt.Sprintf("failed to associate task with template: %s", err), true, http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	// Make sure the current definition has a revision, tasks defined before revisions were recorded have none.
+	ts.saveRevision(original, "", original.Modified)
+
+	now := time.Now()
+	updated.Modified = now
+	if err := ts.tasks.Replace(updated); err != nil {
+		if original.TemplateID != updated.TemplateID && updated.TemplateID != "" {
+			if err := ts.templates.DisassociateTask(updated.TemplateID, id); err != nil {
+				ts.logger.Printf("E! failed to disassociate task %s from template %s", id, updated.TemplateID)
+			}
+		}
+		httpd.HttpError(w, fmt.Sprintf("failed to replace task definition: %s", err), true, http.StatusInternalServerError)
+		return
+	}
+	if original.TemplateID != updated.TemplateID && original.TemplateID != "" {
+		if err := ts.templates.DisassociateTask(original.TemplateID, id); err != nil {
+			ts.logger.Printf("E! failed to disassociate task %s from template %s", id, original.TemplateID)
+		}
+	}
+	ts.saveRevision(updated, user.Name(), now)
+
+	if updated.Status == Enabled {
+		ts.stopTask(id)
+		if err := ts.startTask(updated); err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	t, err := ts.convertTask(updated, "formatted", "attributes", ts.TaskMasterLookup.Main())
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(t, true))
+}
@@ -11,11 +11,15 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/influxdata/kapacitor"
+	"github.com/influxdata/kapacitor/auth"
 	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/influxdb"
+	"github.com/influxdata/kapacitor/lint"
 	"github.com/influxdata/kapacitor/server/vars"
 	"github.com/influxdata/kapacitor/services/httpd"
 	"github.com/influxdata/kapacitor/services/storage"
@@ -28,19 +32,29 @@ import (
 const (
 	tasksPath         = "/tasks"
 	tasksPathAnchored = "/tasks/"
+	lintTaskPath      = "/tasks/lint"
+	evaluateTaskPath  = "/tasks/evaluate"
 
 	templatesPath         = "/templates"
 	templatesPathAnchored = "/templates/"
+
+	modulesPath         = "/modules"
+	modulesPathAnchored = "/modules/"
 )
 
 type Service struct {
 	oldDBDir         string
 	tasks            TaskDAO
+	revisions        TaskRevisionDAO
 	templates        TemplateDAO
+	groups           TaskGroupDAO
+	modules          ModuleDAO
 	snapshots        SnapshotDAO
 	routes           []httpd.Route
 	snapshotInterval time.Duration
-	StorageService   interface {
+	// Default limits of the tasks
+	limits         kapacitor.TaskLimits
+	StorageService interface {
 		Store(namespace string) storage.Interface
 		Register(name string, store storage.StoreActioner)
 	}
@@ -54,6 +68,9 @@ type Service struct {
 		Set(*kapacitor.TaskMaster)
 		Delete(*kapacitor.TaskMaster)
 	}
+	InfluxDBService interface {
+		NewNamedClient(name string) (influxdb.Client, error)
+	}
 
 	logger *log.Logger
 }
@@ -67,6 +84,7 @@ type taskStore struct {
 func NewService(conf Config, l *log.Logger) *Service {
 	return &Service{
 		snapshotInterval: time.Duration(conf.SnapshotInterval),
+		limits:           conf.limits(),
 		logger:           l,
 		oldDBDir:         conf.Dir,
 	}
@@ -88,7 +106,16 @@ func (ts *Service) Open() error {
 	}
 	ts.tasks = tasksDAO
 	ts.StorageService.Register(tasksAPIName, ts.tasks)
+	ts.revisions, err = newTaskRevisionKV(store)
+	if err != nil {
+		return err
+	}
 	ts.templates = newTemplateKV(store)
+	ts.groups, err = newTaskGroupKV(store)
+	if err != nil {
+		return err
+	}
+	ts.modules = newModuleKV(store)
 	ts.snapshots = newSnapshotKV(store)
 
 	// Perform migration to new storage service.
@@ -119,6 +146,11 @@ func (ts *Service) Open() error {
 			Pattern:     tasksPathAnchored,
 			HandlerFunc: ts.handleUpdateTask,
 		},
+		{
+			Method:      "POST",
+			Pattern:     tasksPathAnchored,
+			HandlerFunc: ts.handleTaskAction,
+		},
 		{
 			Method:      "GET",
 			Pattern:     tasksPath,
@@ -128,6 +160,59 @@ func (ts *Service) Open() error {
 			Method:      "POST",
 			Pattern:     tasksPath,
 			HandlerFunc: ts.handleCreateTask,
+			// Authorized against the path of the new task.
+			NoAuthorize: true,
+		},
+		{
+			Method:      "POST",
+			Pattern:     lintTaskPath,
+			HandlerFunc: ts.handleLintTask,
+		},
+		{
+			Method:      "POST",
+			Pattern:     evaluateTaskPath,
+			HandlerFunc: ts.handleEvaluateTask,
+		},
+		{
+			Method:      "GET",
+			Pattern:     taskGraphPath,
+			HandlerFunc: ts.handleTaskGraph,
+		},
+		{
+			Method:      "GET",
+			Pattern:     taskGroupsPathAnchored,
+			HandlerFunc: ts.handleTaskGroup,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     taskGroupsPathAnchored,
+			HandlerFunc: ts.handleDeleteTaskGroup,
+		},
+		{
+			// Satisfy CORS checks.
+			Method:      "OPTIONS",
+			Pattern:     taskGroupsPathAnchored,
+			HandlerFunc: httpd.ServeOptions,
+		},
+		{
+			Method:      "PATCH",
+			Pattern:     taskGroupsPathAnchored,
+			HandlerFunc: ts.handleUpdateTaskGroup,
+		},
+		{
+			Method:      "POST",
+			Pattern:     taskGroupsPathAnchored,
+			HandlerFunc: ts.handleTaskGroupAction,
+		},
+		{
+			Method:      "GET",
+			Pattern:     taskGroupsPath,
+			HandlerFunc: ts.handleListTaskGroups,
+		},
+		{
+			Method:      "POST",
+			Pattern:     taskGroupsPath,
+			HandlerFunc: ts.handleCreateTaskGroup,
 		},
 		{
 			Method:      "GET",
@@ -160,6 +245,37 @@ func (ts *Service) Open() error {
 			Pattern:     templatesPath,
 			HandlerFunc: ts.handleCreateTemplate,
 		},
+		{
+			Method:      "GET",
+			Pattern:     modulesPathAnchored,
+			HandlerFunc: ts.handleModule,
+		},
+		{
+			Method:      "DELETE",
+			Pattern:     modulesPathAnchored,
+			HandlerFunc: ts.handleDeleteModule,
+		},
+		{
+			// Satisfy CORS checks.
+			Method:      "OPTIONS",
+			Pattern:     modulesPathAnchored,
+			HandlerFunc: httpd.ServeOptions,
+		},
+		{
+			Method:      "PATCH",
+			Pattern:     modulesPathAnchored,
+			HandlerFunc: ts.handleUpdateModule,
+		},
+		{
+			Method:      "GET",
+			Pattern:     modulesPath,
+			HandlerFunc: ts.handleListModules,
+		},
+		{
+			Method:      "POST",
+			Pattern:     modulesPath,
+			HandlerFunc: ts.handleCreateModule,
+		},
 	}
 
 	err = ts.HTTPDService.AddRoutes(ts.routes)
@@ -174,6 +290,7 @@ func (ts *Service) Open() error {
 	offset := 0
 	limit := 100
 	vars.NumEnabledTasksVar.Set(0)
+	var enabled []Task
 	for {
 		tasks, err := ts.tasks.List("*", offset, limit)
 		if err != nil {
@@ -183,13 +300,7 @@ func (ts *Service) Open() error {
 			numTasks++
 			if task.Status == Enabled {
 				numEnabledTasks++
-				ts.logger.Println("D! starting enabled task on startup", task.ID)
-				err = ts.startTask(task)
-				if err != nil {
-					ts.logger.Printf("E! error starting enabled task %s, err: %s\n", task.ID, err)
-				} else {
-					ts.logger.Println("D! started task during startup", task.ID)
-				}
+				enabled = append(enabled, task)
 			}
 		}
 		if len(tasks) != limit {
@@ -198,6 +309,29 @@ func (ts *Service) Open() error {
 		offset += limit
 	}
 
+	// Start enabled tasks in the order of their dependencies
+	order, err := ts.startupOrder(enabled)
+	if err != nil {
+		ts.logger.Println("E! failed to order enabled tasks on startup, starting them in ID order:", err)
+		order = make([]string, len(enabled))
+		for i, task := range enabled {
+			order[i] = task.ID
+		}
+	}
+	byID := make(map[string]Task, len(enabled))
+	for _, task := range enabled {
+		byID[task.ID] = task
+	}
+	for _, id := range order {
+		ts.logger.Println("D! starting enabled task on startup", id)
+		err = ts.startTask(byID[id])
+		if err != nil {
+			ts.logger.Printf("E! error starting enabled task %s, err: %s\n", id, err)
+		} else {
+			ts.logger.Println("D! started task during startup", id)
+		}
+	}
+
 	// Set expvars
 	vars.NumTasksVar.Set(numTasks)
 	vars.NumEnabledTasksVar.Set(numEnabledTasks)
@@ -376,6 +510,7 @@ func (ts *Service) Load(id string) (*kapacitor.Task, error) {
 func (ts *Service) SaveSnapshot(id string, snapshot *kapacitor.TaskSnapshot) error {
 	s := &Snapshot{
 		NodeSnapshots: snapshot.NodeSnapshots,
+		NodeConfigs:   snapshot.NodeConfigs,
 	}
 	return ts.snapshots.Put(id, s)
 }
@@ -396,10 +531,25 @@ func (ts *Service) LoadSnapshot(id string) (*kapacitor.TaskSnapshot, error) {
 	}
 	s := &kapacitor.TaskSnapshot{
 		NodeSnapshots: snapshot.NodeSnapshots,
+		NodeConfigs:   snapshot.NodeConfigs,
 	}
 	return s, nil
 }
 
+// renameSnapshot moves the snapshot of a task to the new ID of the task.
+func (ts *Service) renameSnapshot(oldID, newID string) error {
+	snapshot, err := ts.snapshots.Get(oldID)
+	if err == ErrNoSnapshotExists {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := ts.snapshots.Put(newID, snapshot); err != nil {
+		return err
+	}
+	return ts.snapshots.Delete(oldID)
+}
+
 type TaskInfo struct {
 	Name           string
 	Type           kapacitor.TaskType
@@ -418,6 +568,10 @@ func (ts *Service) handleTask(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
+	if i := strings.IndexByte(id, '/'); i > 0 {
+		ts.handleTaskResource(w, r, id[:i], id[i+1:])
+		return
+	}
 
 	raw, err := ts.tasks.Get(id)
 	if err != nil {
@@ -483,6 +637,8 @@ var allTaskFields = []string{
 	"modified",
 	"last-enabled",
 	"vars",
+	"modules",
+	"limits",
 }
 
 const tasksBasePathAnchored = httpd.BasePath + tasksPathAnchored
@@ -630,7 +786,11 @@ func (ts *Service) handleListTasks(w http.ResponseWriter, r *http.Request) {
 					value = client.Disabled
 				case Enabled:
 					value = client.Enabled
+				case Quarantined:
+					value = client.Quarantined
 				}
+			case "limits":
+				value = convertToClientLimits(task.Limits)
 			case "created":
 				value = task.Created
 			case "modified":
@@ -644,6 +804,8 @@ func (ts *Service) handleListTasks(w http.ResponseWriter, r *http.Request) {
 					break
 				}
 				value = vars
+			case "modules":
+				value = task.Modules
 			default:
 				httpd.HttpError(w, fmt.Sprintf("unsupported field %q", field), true, http.StatusBadRequest)
 				return
@@ -661,7 +823,7 @@ func (ts *Service) handleListTasks(w http.ResponseWriter, r *http.Request) {
 
 var validTaskID = regexp.MustCompile(`^[-\._\p{L}0-9]+$`)
 
-func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
+func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request, user auth.User) {
 	task := client.CreateTaskOptions{}
 	dec := json.NewDecoder(r.Body)
 	err := dec.Decode(&task)
@@ -676,6 +838,12 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, fmt.Sprintf("task ID must contain only letters, numbers, '-', '.' and '_'. %q", task.ID), true, http.StatusBadRequest)
 		return
 	}
+	// Authorize against the new task, so that users with privileges
+	// for a pattern of task IDs, i.e. /api/tasks/ci-*, can create matching tasks.
+	if err := httpd.AuthorizeRequestPath(r, user, tasksBasePathAnchored+task.ID); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusForbidden)
+		return
+	}
 
 	newTask := Task{
 		ID: task.ID,
@@ -716,6 +884,9 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 			newTask.Type = StreamTask
 		case client.BatchTask:
 			newTask.Type = BatchTask
+		case 0:
+			// Inferred from the TICKscript below
+			newTask.Type = Undefined
 		default:
 			httpd.HttpError(w, fmt.Sprintf("unknown type %q", task.Type), true, http.StatusBadRequest)
 			return
@@ -729,6 +900,24 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Read type and dbrps declared in the TICKscript
+	meta, err := parseScriptMetadata(newTask.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if meta.Type != Undefined {
+		if newTask.Type != Undefined && newTask.Type != meta.Type {
+			httpd.HttpError(w, fmt.Sprintf("task type %s conflicts with type %s of the TICKscript", newTask.Type, meta.Type), true, http.StatusBadRequest)
+			return
+		}
+		newTask.Type = meta.Type
+	}
+	if newTask.Type == Undefined {
+		httpd.HttpError(w, "must provide a task type or a TICKscript with a stream or batch node", true, http.StatusBadRequest)
+		return
+	}
+
 	// Set dbrps
 	newTask.DBRPs = make([]DBRP, len(task.DBRPs))
 	for i, dbrp := range task.DBRPs {
@@ -737,6 +926,14 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 			RetentionPolicy: dbrp.RetentionPolicy,
 		}
 	}
+	if len(meta.DBRPs) > 0 {
+		if len(newTask.DBRPs) == 0 {
+			newTask.DBRPs = meta.DBRPs
+		} else if !dbrpsEqual(newTask.DBRPs, meta.DBRPs) {
+			httpd.HttpError(w, "dbrps conflict with the dbrp statements of the TICKscript", true, http.StatusBadRequest)
+			return
+		}
+	}
 	if len(newTask.DBRPs) == 0 {
 		httpd.HttpError(w, fmt.Sprintf("must provide at least one database and retention policy."), true, http.StatusBadRequest)
 		return
@@ -748,10 +945,22 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		newTask.Status = Enabled
 	case client.Disabled:
 		newTask.Status = Disabled
+	case client.Quarantined:
+		httpd.HttpError(w, "cannot create a quarantined task", true, http.StatusBadRequest)
+		return
 	default:
 		newTask.Status = Disabled
 	}
 
+	// Set limits
+	if task.Limits != nil {
+		newTask.Limits, err = convertToServiceLimits(*task.Limits)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Set vars
 	newTask.Vars, err = ts.convertToServiceVars(task.Vars)
 	if err != nil {
@@ -766,6 +975,13 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record imported module versions
+	newTask.Modules, err = ts.moduleVersions(newTask.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
 	now := time.Now()
 	newTask.Created = now
 	newTask.Modified = now
@@ -779,6 +995,7 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
 		return
 	}
+	ts.saveRevision(newTask, user.Name(), now)
 
 	// Count new task
 	vars.NumTasksVar.Add(1)
@@ -803,12 +1020,149 @@ func (ts *Service) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 	w.Write(httpd.MarshalJSON(t, true))
 }
 
-func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
+func (ts *Service) handleLintTask(w http.ResponseWriter, r *http.Request) {
+	opt := client.LintTaskOptions{}
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&opt)
+	if err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	if opt.TICKscript == "" {
+		httpd.HttpError(w, "must provide TICKscript", true, http.StatusBadRequest)
+		return
+	}
+
+	task := Task{
+		ID:         "lint",
+		TICKscript: opt.TICKscript,
+	}
+	switch opt.Type {
+	case client.StreamTask:
+		task.Type = StreamTask
+	case client.BatchTask:
+		task.Type = BatchTask
+	case 0:
+		task.Type = Undefined
+	default:
+		httpd.HttpError(w, fmt.Sprintf("unknown type %q", opt.Type), true, http.StatusBadRequest)
+		return
+	}
+	meta, err := parseScriptMetadata(task.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if meta.Type != Undefined {
+		if task.Type != Undefined && task.Type != meta.Type {
+			httpd.HttpError(w, fmt.Sprintf("task type %s conflicts with type %s of the TICKscript", task.Type, meta.Type), true, http.StatusBadRequest)
+			return
+		}
+		task.Type = meta.Type
+	}
+	if task.Type == Undefined {
+		httpd.HttpError(w, "must provide a task type or a TICKscript with a stream or batch node", true, http.StatusBadRequest)
+		return
+	}
+	for _, dbrp := range opt.DBRPs {
+		task.DBRPs = append(task.DBRPs, DBRP{
+			Database:        dbrp.Database,
+			RetentionPolicy: dbrp.RetentionPolicy,
+		})
+	}
+	if len(task.DBRPs) == 0 {
+		task.DBRPs = meta.DBRPs
+	}
+	task.Vars, err = ts.convertToServiceVars(opt.Vars)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	kt, err := ts.newKapacitorTask(task)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	schema, err := ts.lintSchema(opt, task.DBRPs)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	problems, err := lint.Lint(task.TICKscript, kt.Pipeline, schema)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	result := client.LintResult{
+		Problems: make([]client.LintProblem, len(problems)),
+	}
+	for i, p := range problems {
+		result.Problems[i] = client.LintProblem{
+			Line:    p.Line,
+			Char:    p.Char,
+			Message: p.Message,
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(result, true))
+}
+
+// lintSchema returns the schema provided to lint a task.
+// If no schema is provided it is sampled from the databases of the task,
+// no schema is used if InfluxDB is not configured.
+func (ts *Service) lintSchema(opt client.LintTaskOptions, dbrps []DBRP) (*lint.Schema, error) {
+	if opt.Schema != nil {
+		schema := &lint.Schema{
+			Measurements: make(map[string]lint.Measurement, len(opt.Schema.Measurements)),
+		}
+		for name, m := range opt.Schema.Measurements {
+			schema.Measurements[name] = lint.Measurement{
+				Fields: m.Fields,
+				Tags:   m.Tags,
+			}
+		}
+		return schema, nil
+	}
+	if ts.InfluxDBService == nil || len(dbrps) == 0 {
+		return nil, nil
+	}
+	cli, err := ts.InfluxDBService.NewNamedClient(opt.Cluster)
+	if err != nil {
+		if opt.Cluster == "" {
+			// No default InfluxDB cluster, lint without a schema.
+			return nil, nil
+		}
+		return nil, err
+	}
+	databases := make([]string, 0, len(dbrps))
+	seen := make(map[string]bool, len(dbrps))
+	for _, dbrp := range dbrps {
+		if !seen[dbrp.Database] {
+			seen[dbrp.Database] = true
+			databases = append(databases, dbrp.Database)
+		}
+	}
+	return lint.QuerySchema(cli, databases...)
+}
+
+func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request, user auth.User) {
 	id, err := ts.taskIDFromPath(r.URL.Path)
 	if err != nil {
 		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
+	// With the replace parameter the template ID and vars replace those of the task even when they are empty,
+	// so that the task can be detached from its template and its vars can be removed.
+	replace := false
+	if replaceStr := r.URL.Query().Get("replace"); replaceStr != "" {
+		replace, err = strconv.ParseBool(replaceStr)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid replace parameter %q must be a boolean: %s", replaceStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
 	task := client.UpdateTaskOptions{}
 	dec := json.NewDecoder(r.Body)
 	err = dec.Decode(&task)
@@ -830,7 +1184,7 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		updated.ID = task.ID
 	}
 
-	if task.TemplateID != "" || updated.TemplateID != "" {
+	if task.TemplateID != "" || (updated.TemplateID != "" && !replace) {
 		templateID := task.TemplateID
 		if templateID == "" {
 			templateID = updated.TemplateID
@@ -856,6 +1210,14 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		updated.TICKscript = template.TICKscript
 		updated.TemplateID = templateID
 	} else {
+		if original.TemplateID != "" {
+			// Detach the task from its template, it keeps the TICKscript of the template unless a new one is set.
+			if err := ts.templates.DisassociateTask(original.TemplateID, original.ID); err != nil {
+				httpd.HttpError(w, fmt.Sprintf("failed to disassociate task with template: %s", err), true, http.StatusBadRequest)
+				return
+			}
+			updated.TemplateID = ""
+		}
 		// Only set type and script if not a templated task
 		// Set task type
 		switch task.Type {
@@ -871,6 +1233,20 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Read type and dbrps declared in the TICKscript
+	meta, err := parseScriptMetadata(updated.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	if meta.Type != Undefined {
+		if task.Type != 0 && updated.Type != meta.Type {
+			httpd.HttpError(w, fmt.Sprintf("task type %s conflicts with type %s of the TICKscript", updated.Type, meta.Type), true, http.StatusBadRequest)
+			return
+		}
+		updated.Type = meta.Type
+	}
+
 	// Set dbrps
 	if len(task.DBRPs) > 0 {
 		updated.DBRPs = make([]DBRP, len(task.DBRPs))
@@ -880,6 +1256,12 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 				RetentionPolicy: dbrp.RetentionPolicy,
 			}
 		}
+		if len(meta.DBRPs) > 0 && !dbrpsEqual(updated.DBRPs, meta.DBRPs) {
+			httpd.HttpError(w, "dbrps conflict with the dbrp statements of the TICKscript", true, http.StatusBadRequest)
+			return
+		}
+	} else if len(meta.DBRPs) > 0 {
+		updated.DBRPs = meta.DBRPs
 	}
 
 	// Set status
@@ -889,11 +1271,23 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		updated.Status = Enabled
 	case client.Disabled:
 		updated.Status = Disabled
+	case client.Quarantined:
+		httpd.HttpError(w, "cannot quarantine a task, only a task exceeding its limits is quarantined", true, http.StatusBadRequest)
+		return
 	}
 	statusChanged := previousStatus != updated.Status
 
+	// Set limits
+	if task.Limits != nil {
+		updated.Limits, err = convertToServiceLimits(*task.Limits)
+		if err != nil {
+			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Set vars
-	if len(task.Vars) > 0 {
+	if len(task.Vars) > 0 || replace {
 		updated.Vars, err = ts.convertToServiceVars(task.Vars)
 		if err != nil {
 			httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
@@ -908,6 +1302,16 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record imported module versions
+	updated.Modules, err = ts.moduleVersions(updated.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	// Make sure the current definition has a revision, tasks defined before revisions were recorded have none.
+	ts.saveRevision(original, "", original.Modified)
+
 	now := time.Now()
 	updated.Modified = now
 	if statusChanged && updated.Status == Enabled {
@@ -922,9 +1326,21 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		if err := ts.tasks.Delete(original.ID); err != nil {
 			ts.logger.Printf("E! failed to delete old task definition during ID change: old ID: %s new ID: %s, %s", original.ID, updated.ID, err.Error())
 		}
-		if original.Status == Enabled && updated.Status == Enabled {
-			// Stop task and start it under new name
+		if err := ts.renameRevisions(original.ID, updated.ID); err != nil {
+			ts.logger.Printf("E! failed to move task revisions during ID change: old ID: %s new ID: %s, %s", original.ID, updated.ID, err.Error())
+		}
+		if err := ts.renameGroupTask(original.ID, updated.ID); err != nil {
+			ts.logger.Printf("E! failed to rename task in task groups during ID change: old ID: %s new ID: %s, %s", original.ID, updated.ID, err.Error())
+		}
+		if original.Status == Enabled {
+			// Stop task, its snapshot is moved to the new name
 			ts.stopTask(original.ID)
+		}
+		if err := ts.renameSnapshot(original.ID, updated.ID); err != nil {
+			ts.logger.Printf("E! failed to move task snapshot during ID change: old ID: %s new ID: %s, %s", original.ID, updated.ID, err.Error())
+		}
+		if original.Status == Enabled && updated.Status == Enabled {
+			// Start task under new name
 			if err := ts.startTask(updated); err != nil {
 				httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
 				return
@@ -936,6 +1352,7 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	ts.saveRevision(updated, user.Name(), now)
 
 	if statusChanged {
 		// Enable/Disable task
@@ -948,8 +1365,10 @@ func (ts *Service) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		case Disabled:
-			vars.NumEnabledTasksVar.Add(-1)
-			ts.stopTask(original.ID)
+			if previousStatus == Enabled {
+				vars.NumEnabledTasksVar.Add(-1)
+				ts.stopTask(original.ID)
+			}
 		}
 	}
 
@@ -975,6 +1394,7 @@ func (ts *Service) convertTask(t Task, scriptFormat, dotView string, tm *kapacit
 	}
 
 	executing := tm.IsExecuting(t.ID)
+	paused := tm.IsPaused(t.ID)
 	errMsg := t.Error
 	dot := ""
 	stats := client.ExecutionStats{}
@@ -1002,6 +1422,8 @@ func (ts *Service) convertTask(t Task, scriptFormat, dotView string, tm *kapacit
 		status = client.Disabled
 	case Enabled:
 		status = client.Enabled
+	case Quarantined:
+		status = client.Quarantined
 	default:
 		return client.Task{}, fmt.Errorf("invalid task status %v", t.Status)
 	}
@@ -1037,9 +1459,12 @@ func (ts *Service) convertTask(t Task, scriptFormat, dotView string, tm *kapacit
 		DBRPs:          dbrps,
 		TICKscript:     script,
 		Vars:           vars,
+		Modules:        t.Modules,
 		Status:         status,
+		Limits:         convertToClientLimits(t.Limits),
 		Dot:            dot,
 		Executing:      executing,
+		Paused:         paused,
 		ExecutionStats: stats,
 		Created:        t.Created,
 		Modified:       t.Modified,
@@ -1313,16 +1738,31 @@ func (ts *Service) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ts *Service) deleteTask(id string) error {
+	task, err := ts.tasks.Get(id)
+	if err != nil && err != ErrNoTaskExists {
+		return err
+	}
+	if err == nil && task.Status == Enabled {
+		// Stop the task first, it saves snapshots until it has stopped.
+		ts.TaskMasterLookup.Main().DeleteTask(id)
+	}
+
 	// Delete associated snapshot
 	ts.snapshots.Delete(id)
 
+	// Delete revisions
+	if err := ts.revisions.Delete(id); err != nil {
+		ts.logger.Printf("E! failed to delete revisions of task %s: %s", id, err)
+	}
+
+	// Remove from task groups
+	if err := ts.renameGroupTask(id, ""); err != nil {
+		ts.logger.Printf("E! failed to remove task %s from task groups: %s", id, err)
+	}
+
 	// Delete task object
-	task, err := ts.tasks.Get(id)
-	if err != nil {
-		if err == ErrNoTaskExists {
-			return nil
-		}
-		return err
+	if err == ErrNoTaskExists {
+		return nil
 	}
 	if task.TemplateID != "" {
 		if err := ts.templates.DisassociateTask(task.TemplateID, task.ID); err != nil {
@@ -1332,7 +1772,6 @@ func (ts *Service) deleteTask(id string) error {
 	vars.NumTasksVar.Add(-1)
 	if task.Status == Enabled {
 		vars.NumEnabledTasksVar.Add(-1)
-		ts.TaskMasterLookup.Main().DeleteTask(id)
 	}
 	return ts.tasks.Delete(id)
 }
@@ -1380,6 +1819,7 @@ func (ts *Service) convertTemplate(t Template, scriptFormat string) (client.Temp
 		Created:    t.Created,
 		Modified:   t.Modified,
 		Vars:       vars,
+		Modules:    t.Modules,
 	}, nil
 }
 
@@ -1389,6 +1829,10 @@ func (ts *Service) handleTemplate(w http.ResponseWriter, r *http.Request) {
 		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
+	if i := strings.IndexByte(id, '/'); i > 0 {
+		ts.handleTemplateResource(w, r, id[:i], id[i+1:])
+		return
+	}
 
 	raw, err := ts.templates.Get(id)
 	if err != nil {
@@ -1604,6 +2048,13 @@ func (ts *Service) handleCreateTemplate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Record imported module versions
+	newTemplate.Modules, err = ts.moduleVersions(newTemplate.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
 	now := time.Now()
 	newTemplate.Created = now
 	newTemplate.Modified = now
@@ -1625,12 +2076,20 @@ func (ts *Service) handleCreateTemplate(w http.ResponseWriter, r *http.Request)
 	w.Write(httpd.MarshalJSON(t, true))
 }
 
-func (ts *Service) handleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+func (ts *Service) handleUpdateTemplate(w http.ResponseWriter, r *http.Request, user auth.User) {
 	id, err := ts.templateIDFromPath(r.URL.Path)
 	if err != nil {
 		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
 		return
 	}
+	dryRun := false
+	if dryRunStr := r.URL.Query().Get("dry-run"); dryRunStr != "" {
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid dry-run parameter %q must be a boolean: %s", dryRunStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
 	template := client.UpdateTemplateOptions{}
 	dec := json.NewDecoder(r.Body)
 	err = dec.Decode(&template)
@@ -1672,6 +2131,18 @@ func (ts *Service) handleUpdateTemplate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Record imported module versions
+	updated.Modules, err = ts.moduleVersions(updated.TICKscript)
+	if err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	if dryRun {
+		ts.previewTemplateUpdate(w, original, updated)
+		return
+	}
+
 	// Get associated tasks
 	taskIds, err := ts.templates.ListAssociatedTasks(original.ID)
 	if err != nil {
@@ -1699,7 +2170,7 @@ func (ts *Service) handleUpdateTemplate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Update all associated tasks
-	err = ts.updateAllAssociatedTasks(original, updated, taskIds)
+	err = ts.updateAllAssociatedTasks(original, updated, taskIds, user.Name())
 	if err != nil {
 		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
 		return
@@ -1718,7 +2189,8 @@ func (ts *Service) handleUpdateTemplate(w http.ResponseWriter, r *http.Request)
 
 // Update all associated tasks. Return the first error if any.
 // Rollsback all updated tasks if an error occurs.
-func (ts *Service) updateAllAssociatedTasks(old, new Template, taskIds []string) error {
+// The changes are recorded as revisions of the tasks made by the author.
+func (ts *Service) updateAllAssociatedTasks(old, new Template, taskIds []string, author string) error {
 	var i int
 	// Setup rollback function
 	defer func() {
@@ -1741,6 +2213,8 @@ func (ts *Service) updateAllAssociatedTasks(old, new Template, taskIds []string)
 			task.Type = old.Type
 			if err := ts.tasks.Replace(task); err != nil {
 				ts.logger.Printf("E! error rolling back associated task %s: %s", taskId, err)
+			} else {
+				ts.saveRevision(task, author, time.Now())
 			}
 			if task.Status == Enabled {
 				ts.stopTask(taskId)
@@ -1767,12 +2241,18 @@ func (ts *Service) updateAllAssociatedTasks(old, new Template, taskIds []string)
 				return fmt.Errorf("error updating task association %s: %s", taskId, err)
 			}
 		}
+		ts.saveRevision(task, "", task.Modified)
 		task.TemplateID = new.ID
 		task.TICKscript = new.TICKscript
 		task.Type = new.Type
+		task.Modules, err = ts.moduleVersions(task.TICKscript)
+		if err != nil {
+			return fmt.Errorf("error recording module versions of associated task %s: %s", taskId, err)
+		}
 		if err := ts.tasks.Replace(task); err != nil {
 			return fmt.Errorf("error updating associated task %s: %s", taskId, err)
 		}
+		ts.saveRevision(task, author, time.Now())
 		if task.Status == Enabled {
 			ts.stopTask(taskId)
 			err := ts.startTask(task)
@@ -1798,7 +2278,439 @@ func (ts *Service) handleDeleteTemplate(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (ts *Service) newKapacitorTask(task Task) (*kapacitor.Task, error) {
+// Import returns the TICKscript of the module with the given ID.
+// Import implements the tick.Importer interface.
+func (ts *Service) Import(id string) (string, error) {
+	m, err := ts.modules.Get(id)
+	if err != nil {
+		if err == ErrNoModuleExists {
+			return "", fmt.Errorf("unknown module %q", id)
+		}
+		return "", err
+	}
+	return m.TICKscript, nil
+}
+
+// moduleVersions returns the current versions of all modules imported by the script,
+// including modules imported by other modules.
+func (ts *Service) moduleVersions(script string) (map[string]int64, error) {
+	versions := make(map[string]int64)
+	if err := ts.collectModuleVersions(script, versions); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	return versions, nil
+}
+
+func (ts *Service) collectModuleVersions(script string, versions map[string]int64) error {
+	root, err := ast.Parse(script)
+	if err != nil {
+		return err
+	}
+	program, ok := root.(*ast.ProgramNode)
+	if !ok {
+		return nil
+	}
+	for _, n := range program.Nodes {
+		imp, ok := n.(*ast.ImportNode)
+		if !ok {
+			continue
+		}
+		id := imp.Path.Literal
+		if _, ok := versions[id]; ok {
+			continue
+		}
+		m, err := ts.modules.Get(id)
+		if err != nil {
+			if err == ErrNoModuleExists {
+				return fmt.Errorf("unknown module %q", id)
+			}
+			return err
+		}
+		versions[id] = m.Version
+		if err := ts.collectModuleVersions(m.TICKscript, versions); err != nil {
+			return errors.Wrapf(err, "module %s", id)
+		}
+	}
+	return nil
+}
+
+func (ts *Service) convertModule(m Module, scriptFormat string) client.Module {
+	script := m.TICKscript
+	if scriptFormat == "formatted" {
+		// Format TICKscript
+		formatted, err := tick.Format(script)
+		if err == nil {
+			// Only format if it succeeded.
+			// Otherwise a change in syntax may prevent module retrieval.
+			script = formatted
+		}
+	}
+	return client.Module{
+		Link:       ts.moduleLink(m.ID),
+		ID:         m.ID,
+		TICKscript: script,
+		Version:    m.Version,
+		Created:    m.Created,
+		Modified:   m.Modified,
+	}
+}
+
+const modulesBasePathAnchored = httpd.BasePath + modulesPathAnchored
+
+func (ts *Service) moduleIDFromPath(path string) (string, error) {
+	if len(path) <= len(modulesBasePathAnchored) {
+		return "", errors.New("must specify module id on path")
+	}
+	id := path[len(modulesBasePathAnchored):]
+	return id, nil
+}
+
+func (ts *Service) moduleLink(id string) client.Link {
+	return client.Link{Relation: client.Self, Href: path.Join(httpd.BasePath, modulesPath, id)}
+}
+
+func (ts *Service) handleModule(w http.ResponseWriter, r *http.Request) {
+	id, err := ts.moduleIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	raw, err := ts.modules.Get(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+
+	scriptFormat := r.URL.Query().Get("script-format")
+	switch scriptFormat {
+	case "":
+		scriptFormat = "formatted"
+	case "formatted", "raw":
+	default:
+		httpd.HttpError(w, fmt.Sprintf("invalid script-format parameter %q", scriptFormat), true, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(ts.convertModule(raw, scriptFormat), true))
+}
+
+func (ts *Service) handleListModules(w http.ResponseWriter, r *http.Request) {
+	pattern := r.URL.Query().Get("pattern")
+
+	scriptFormat := r.URL.Query().Get("script-format")
+	switch scriptFormat {
+	case "":
+		scriptFormat = "formatted"
+	case "formatted", "raw":
+	default:
+		httpd.HttpError(w, fmt.Sprintf("invalid script-format parameter %q", scriptFormat), true, http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	offset := int64(0)
+	offsetStr := r.URL.Query().Get("offset")
+	if offsetStr != "" {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid offset parameter %q must be an integer: %s", offsetStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := int64(100)
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid limit parameter %q must be an integer: %s", limitStr, err), true, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rawModules, err := ts.modules.List(pattern, int(offset), int(limit))
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list modules with pattern %q: %s", pattern, err), true, http.StatusBadRequest)
+		return
+	}
+	modules := make([]client.Module, len(rawModules))
+	for i, m := range rawModules {
+		modules[i] = ts.convertModule(m, scriptFormat)
+	}
+
+	type response struct {
+		Modules []client.Module `json:"modules"`
+	}
+
+	w.Write(httpd.MarshalJSON(response{modules}, true))
+}
+
+// Module IDs are paths, i.e. 'shared/alerting.tick'.
+var validModuleID = regexp.MustCompile(`^[-\._\p{L}0-9]+(/[-\._\p{L}0-9]+)*$`)
+
+// validateModule checks that all imports of the module exist and that the module TICKscript can be evaluated.
+func (ts *Service) validateModule(m Module) error {
+	versions := make(map[string]int64)
+	if err := ts.collectModuleVersions(m.TICKscript, versions); err != nil {
+		return err
+	}
+	if _, ok := versions[m.ID]; ok {
+		return fmt.Errorf("module %s imports itself", m.ID)
+	}
+	return ts.TaskMasterLookup.Main().ValidateModule(m.TICKscript)
+}
+
+func (ts *Service) handleCreateModule(w http.ResponseWriter, r *http.Request) {
+	module := client.CreateModuleOptions{}
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&module)
+	if err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+	if !validModuleID.MatchString(module.ID) {
+		httpd.HttpError(w, fmt.Sprintf("module ID must be a path of elements containing only letters, numbers, '-', '.' and '_'. %q", module.ID), true, http.StatusBadRequest)
+		return
+	}
+
+	// Check for existing module
+	_, err = ts.modules.Get(module.ID)
+	if err == nil {
+		httpd.HttpError(w, fmt.Sprintf("module %s already exists", module.ID), true, http.StatusBadRequest)
+		return
+	}
+
+	newModule := Module{
+		ID:         module.ID,
+		TICKscript: module.TICKscript,
+		Version:    1,
+	}
+	if newModule.TICKscript == "" {
+		httpd.HttpError(w, fmt.Sprintf("must provide TICKscript"), true, http.StatusBadRequest)
+		return
+	}
+
+	// Validate module
+	if err := ts.validateModule(newModule); err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	newModule.Created = now
+	newModule.Modified = now
+
+	// Save module
+	if err := ts.modules.Create(newModule); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(ts.convertModule(newModule, "formatted"), true))
+}
+
+func (ts *Service) handleUpdateModule(w http.ResponseWriter, r *http.Request) {
+	id, err := ts.moduleIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	module := client.UpdateModuleOptions{}
+	dec := json.NewDecoder(r.Body)
+	err = dec.Decode(&module)
+	if err != nil {
+		httpd.HttpError(w, "invalid JSON", true, http.StatusBadRequest)
+		return
+	}
+
+	// Check for existing module
+	original, err := ts.modules.Get(id)
+	if err != nil {
+		httpd.HttpError(w, "module does not exist, cannot update", true, http.StatusNotFound)
+		return
+	}
+	updated := original
+
+	// Set tick script
+	if module.TICKscript != "" {
+		updated.TICKscript = module.TICKscript
+	}
+
+	// Validate module
+	if err := ts.validateModule(updated); err != nil {
+		httpd.HttpError(w, "invalid TICKscript: "+err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	// Get dependent templates and tasks
+	templates, err := ts.dependentTemplates(original.ID)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("error getting dependent templates for module %s: %s", original.ID, err.Error()), true, http.StatusInternalServerError)
+		return
+	}
+	tasks, err := ts.dependentTasks(original.ID)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("error getting dependent tasks for module %s: %s", original.ID, err.Error()), true, http.StatusInternalServerError)
+		return
+	}
+
+	// Save updated module
+	updated.Version++
+	updated.Modified = time.Now()
+	if err := ts.modules.Replace(updated); err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to replace module definition: %s", err.Error()), true, http.StatusInternalServerError)
+		return
+	}
+
+	// Re-validate all dependent templates and tasks and reload the tasks
+	if err := ts.updateDependents(templates, tasks); err != nil {
+		if err := ts.modules.Replace(original); err != nil {
+			ts.logger.Printf("E! failed to roll back module %s: %s", original.ID, err)
+		}
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(ts.convertModule(updated, "formatted"), true))
+}
+
+// dependentTasks returns all tasks that import the module directly or via another module.
+func (ts *Service) dependentTasks(moduleID string) ([]Task, error) {
+	var dependents []Task
+	offset := 0
+	limit := 100
+	for {
+		tasks, err := ts.tasks.List("*", offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if _, ok := task.Modules[moduleID]; ok {
+				dependents = append(dependents, task)
+			}
+		}
+		if len(tasks) != limit {
+			break
+		}
+		offset += limit
+	}
+	return dependents, nil
+}
+
+// dependentTemplates returns all templates that import the module directly or via another module.
+func (ts *Service) dependentTemplates(moduleID string) ([]Template, error) {
+	var dependents []Template
+	offset := 0
+	limit := 100
+	for {
+		templates, err := ts.templates.List("*", offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, template := range templates {
+			if _, ok := template.Modules[moduleID]; ok {
+				dependents = append(dependents, template)
+			}
+		}
+		if len(templates) != limit {
+			break
+		}
+		offset += limit
+	}
+	return dependents, nil
+}
+
+// Validate all templates and tasks against the current modules, then record the new module versions
+// and reload the enabled tasks.
+// No template or task is modified if any of them is invalid.
+func (ts *Service) updateDependents(templates []Template, tasks []Task) error {
+	for _, template := range templates {
+		if _, err := ts.templateTask(template); err != nil {
+			return fmt.Errorf("module update breaks template %s: %s", template.ID, err)
+		}
+	}
+	for _, task := range tasks {
+		if _, err := ts.newKapacitorTask(task); err != nil {
+			return fmt.Errorf("module update breaks task %s: %s", task.ID, err)
+		}
+	}
+	for _, template := range templates {
+		modules, err := ts.moduleVersions(template.TICKscript)
+		if err != nil {
+			return fmt.Errorf("module update breaks template %s: %s", template.ID, err)
+		}
+		template.Modules = modules
+		if err := ts.templates.Replace(template); err != nil {
+			ts.logger.Printf("E! failed to record module versions of template %s: %s", template.ID, err)
+		}
+	}
+	for _, task := range tasks {
+		modules, err := ts.moduleVersions(task.TICKscript)
+		if err != nil {
+			return fmt.Errorf("module update breaks task %s: %s", task.ID, err)
+		}
+		task.Modules = modules
+		if err := ts.tasks.Replace(task); err != nil {
+			ts.logger.Printf("E! failed to record module versions of task %s: %s", task.ID, err)
+		}
+		if task.Status == Enabled {
+			ts.stopTask(task.ID)
+			if err := ts.startTask(task); err != nil {
+				ts.logger.Printf("E! error reloading task %s after module update: %s", task.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (ts *Service) handleDeleteModule(w http.ResponseWriter, r *http.Request) {
+	id, err := ts.moduleIDFromPath(r.URL.Path)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusBadRequest)
+		return
+	}
+	templates, err := ts.dependentTemplates(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	if len(templates) > 0 {
+		ids := make([]string, len(templates))
+		for i, template := range templates {
+			ids[i] = template.ID
+		}
+		httpd.HttpError(w, fmt.Sprintf("cannot delete module %s, it is imported by templates: %s", id, strings.Join(ids, ", ")), true, http.StatusBadRequest)
+		return
+	}
+	tasks, err := ts.dependentTasks(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	if len(tasks) > 0 {
+		ids := make([]string, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.ID
+		}
+		httpd.HttpError(w, fmt.Sprintf("cannot delete module %s, it is imported by tasks: %s", id, strings.Join(ids, ", ")), true, http.StatusBadRequest)
+		return
+	}
+	err = ts.modules.Delete(id)
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ts *Service) newKapacitorTask(task Task) (*kapacitor.Task, error) {
 	dbrps := make([]kapacitor.DBRP, len(task.DBRPs))
 	for i, dbrp := range task.DBRPs {
 		dbrps[i] = kapacitor.DBRP{
@@ -1817,13 +2729,94 @@ func (ts *Service) newKapacitorTask(task Task) (*kapacitor.Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	return ts.TaskMasterLookup.Main().NewTask(task.ID,
+	t, err := ts.TaskMasterLookup.Main().NewTask(task.ID,
 		task.TICKscript,
 		tt,
 		dbrps,
 		ts.snapshotInterval,
 		vars,
 	)
+	if err != nil {
+		return nil, err
+	}
+	t.Limits = ts.taskLimits(task.Limits)
+	return t, nil
+}
+
+// scriptMetadata is the task type and dbrps declared in a TICKscript.
+type scriptMetadata struct {
+	// Type inferred from the root nodes, Undefined if the script has no stream or batch node.
+	Type  TaskType
+	DBRPs []DBRP
+}
+
+// parseScriptMetadata reads the dbrp statements of a TICKscript
+// and infers the task type from the root nodes of its pipelines.
+func parseScriptMetadata(script string) (scriptMetadata, error) {
+	meta := scriptMetadata{Type: Undefined}
+	root, err := ast.Parse(script)
+	if err != nil {
+		return meta, err
+	}
+	program, ok := root.(*ast.ProgramNode)
+	if !ok {
+		return meta, nil
+	}
+	seen := make(map[DBRP]bool)
+	for _, n := range program.Nodes {
+		switch node := n.(type) {
+		case *ast.DBRPNode:
+			dbrp := DBRP{
+				Database:        node.DB.Reference,
+				RetentionPolicy: node.RP.Reference,
+			}
+			if !seen[dbrp] {
+				seen[dbrp] = true
+				meta.DBRPs = append(meta.DBRPs, dbrp)
+			}
+			continue
+		case *ast.DeclarationNode:
+			n = node.Right
+		}
+		// The root of a pipeline is the leftmost node of its chain
+		for c, ok := n.(*ast.ChainNode); ok; c, ok = n.(*ast.ChainNode) {
+			n = c.Left
+		}
+		ident, ok := n.(*ast.IdentifierNode)
+		if !ok {
+			continue
+		}
+		var tt TaskType
+		switch ident.Ident {
+		case "stream":
+			tt = StreamTask
+		case "batch":
+			tt = BatchTask
+		default:
+			continue
+		}
+		if meta.Type != Undefined && meta.Type != tt {
+			return meta, errors.New("TICKscript cannot contain both stream and batch nodes")
+		}
+		meta.Type = tt
+	}
+	return meta, nil
+}
+
+// dbrpsEqual reports whether both lists contain the same dbrps, ignoring order.
+func dbrpsEqual(a, b []DBRP) bool {
+	set := make(map[DBRP]bool, len(a))
+	for _, dbrp := range a {
+		set[dbrp] = true
+	}
+	other := make(map[DBRP]bool, len(b))
+	for _, dbrp := range b {
+		if !set[dbrp] {
+			return false
+		}
+		other[dbrp] = true
+	}
+	return len(set) == len(other)
 }
 
 func (ts *Service) templateTask(template Template) (*kapacitor.Template, error) {
@@ -1880,6 +2873,12 @@ func (ts *Service) startTask(task Task) error {
 			tm.StopTask(t.ID)
 
 			ts.logger.Printf("E! task %s finished with error: %s", et.Task.ID, err)
+			if qerr, ok := err.(*kapacitor.QuarantineError); ok {
+				if err := ts.quarantineTask(t.ID, qerr); err != nil {
+					ts.logger.Printf("E! failed to quarantine task %s: %s", et.Task.ID, err)
+				}
+				return
+			}
 			// Save last error from task.
 			err = ts.saveLastError(t.ID, err.Error())
 			if err != nil {
@@ -1890,8 +2889,19 @@ func (ts *Service) startTask(task Task) error {
 	return nil
 }
 
+// stopTask stops the task after saving a snapshot of the state of its nodes,
+// so that the state is restored when the task is started again.
 func (ts *Service) stopTask(id string) {
-	ts.TaskMasterLookup.Main().StopTask(id)
+	tm := ts.TaskMasterLookup.Main()
+	if tm.IsExecuting(id) {
+		snapshot, err := tm.SnapshotTask(id)
+		if err != nil {
+			ts.logger.Printf("E! failed to snapshot task %s before stopping it: %s", id, err)
+		} else if err := ts.SaveSnapshot(id, snapshot); err != nil {
+			ts.logger.Printf("E! failed to save snapshot of task %s: %s", id, err)
+		}
+	}
+	tm.StopTask(id)
 }
 
 // Save last error from task.
@@ -0,0 +1,276 @@
+package task_store
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/kapacitor/client/v1"
+	"github.com/influxdata/kapacitor/services/httpd"
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	templateTasksPath = "tasks"
+)
+
+// handleTemplateResource serves the GET requests of the resources of a template,
+// i.e. /templates/<id>/tasks.
+func (ts *Service) handleTemplateResource(w http.ResponseWriter, r *http.Request, id, resource string) {
+	switch resource {
+	case templateTasksPath:
+		ts.handleListTemplateTasks(w, r, id)
+	default:
+		httpd.HttpError(w, fmt.Sprintf("unknown resource %q of template %s", resource, id), true, http.StatusNotFound)
+	}
+}
+
+// templateTasks returns the tasks created from the template, sorted by ID.
+func (ts *Service) templateTasks(id string) ([]Task, error) {
+	taskIds, err := ts.templates.ListAssociatedTasks(id)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(taskIds)
+	tasks := make([]Task, 0, len(taskIds))
+	for _, taskId := range taskIds {
+		task, err := ts.tasks.Get(taskId)
+		if err == ErrNoTaskExists {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving associated task %s: %s", taskId, err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (ts *Service) convertTemplateTask(t Task) (client.TemplateTask, error) {
+	var status client.TaskStatus
+	switch t.Status {
+	case Disabled:
+		status = client.Disabled
+	case Enabled:
+		status = client.Enabled
+	case Quarantined:
+		status = client.Quarantined
+	default:
+		return client.TemplateTask{}, fmt.Errorf("invalid task status %v", t.Status)
+	}
+
+	dbrps := make([]client.DBRP, len(t.DBRPs))
+	for i, dbrp := range t.DBRPs {
+		dbrps[i] = client.DBRP{
+			Database:        dbrp.Database,
+			RetentionPolicy: dbrp.RetentionPolicy,
+		}
+	}
+
+	vars, err := ts.convertToClientVars(t.Vars)
+	if err != nil {
+		return client.TemplateTask{}, err
+	}
+
+	return client.TemplateTask{
+		Link:      ts.taskLink(t.ID),
+		ID:        t.ID,
+		Status:    status,
+		Executing: ts.TaskMasterLookup.Main().IsExecuting(t.ID),
+		DBRPs:     dbrps,
+		Vars:      vars,
+		Error:     t.Error,
+		Modified:  t.Modified,
+	}, nil
+}
+
+func (ts *Service) handleListTemplateTasks(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := ts.templates.Get(id); err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusNotFound)
+		return
+	}
+	rawTasks, err := ts.templateTasks(id)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to list tasks of template %s: %s", id, err), true, http.StatusInternalServerError)
+		return
+	}
+	tasks := make([]client.TemplateTask, len(rawTasks))
+	for i, task := range rawTasks {
+		tasks[i], err = ts.convertTemplateTask(task)
+		if err != nil {
+			httpd.HttpError(w, fmt.Sprintf("invalid task stored in db: %s", err), true, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type response struct {
+		Tasks []client.TemplateTask `json:"tasks"`
+	}
+	w.Write(httpd.MarshalJSON(response{tasks}, true))
+}
+
+// previewTemplateUpdate describes the changes an update of the template would make to the tasks created from it,
+// without changing anything.
+func (ts *Service) previewTemplateUpdate(w http.ResponseWriter, original, updated Template) {
+	t, err := ts.convertTemplate(updated, "formatted")
+	if err != nil {
+		httpd.HttpError(w, err.Error(), true, http.StatusInternalServerError)
+		return
+	}
+	scriptDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        scriptLines(original.TICKscript),
+		B:        scriptLines(updated.TICKscript),
+		FromFile: "current",
+		ToFile:   "updated",
+		Context:  3,
+	})
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("failed to compare TICKscripts: %s", err), true, http.StatusInternalServerError)
+		return
+	}
+
+	tasks, err := ts.templateTasks(original.ID)
+	if err != nil {
+		httpd.HttpError(w, fmt.Sprintf("error getting associated tasks for template %s: %s", original.ID, err), true, http.StatusInternalServerError)
+		return
+	}
+	preview := client.TemplateUpdatePreview{
+		Template:   t,
+		ScriptDiff: scriptDiff,
+		Tasks:      make([]client.TemplateTaskPreview, len(tasks)),
+	}
+	for i, task := range tasks {
+		preview.Tasks[i] = ts.previewTemplateTask(task, updated)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(httpd.MarshalJSON(preview, true))
+}
+
+// previewTemplateTask compares the pipeline of the task with the pipeline it would have with the updated template.
+func (ts *Service) previewTemplateTask(task Task, updated Template) client.TemplateTaskPreview {
+	p := client.TemplateTaskPreview{
+		Link:   ts.taskLink(task.ID),
+		ID:     task.ID,
+		Status: client.Disabled,
+	}
+	switch task.Status {
+	case Enabled:
+		p.Status = client.Enabled
+	case Quarantined:
+		p.Status = client.Quarantined
+	}
+
+	next := task
+	next.TemplateID = updated.ID
+	next.TICKscript = updated.TICKscript
+	next.Type = updated.Type
+	if _, err := ts.newKapacitorTask(next); err != nil {
+		p.Error = err.Error()
+		return p
+	}
+
+	current, err := renderTaskScript(task)
+	if err != nil {
+		// The current definition may be invalid, compare with the raw TICKscript instead.
+		current = task.TICKscript
+	}
+	rendered, err := renderTaskScript(next)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	p.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        scriptLines(current),
+		B:        scriptLines(rendered),
+		FromFile: "current",
+		ToFile:   "updated",
+		Context:  3,
+	})
+	if err != nil {
+		p.Error = fmt.Sprintf("failed to compare pipelines: %s", err)
+		return p
+	}
+	p.Changed = p.Diff != ""
+	return p
+}
+
+// renderTaskScript returns the formatted TICKscript of the task,
+// with the declarations of its vars replaced by the values of the task.
+// Comparing the rendered scripts shows the changes of the pipeline of the task, including the changes of properties.
+func renderTaskScript(task Task) (string, error) {
+	root, err := ast.Parse(task.TICKscript)
+	if err != nil {
+		return "", err
+	}
+	if program, ok := root.(*ast.ProgramNode); ok && len(task.Vars) > 0 {
+		for i, n := range program.Nodes {
+			var ident *ast.IdentifierNode
+			var comment *ast.CommentNode
+			switch decl := n.(type) {
+			case *ast.DeclarationNode:
+				ident, comment = decl.Left, decl.Comment
+			case *ast.TypeDeclarationNode:
+				// Required vars are declared with their type only.
+				ident, comment = decl.Node, decl.Comment
+			default:
+				continue
+			}
+			v, ok := task.Vars[ident.Ident]
+			if !ok {
+				continue
+			}
+			value, err := varNode(v)
+			if err != nil {
+				return "", fmt.Errorf("invalid var %s: %s", ident.Ident, err)
+			}
+			program.Nodes[i] = &ast.DeclarationNode{
+				Left:    ident,
+				Right:   value,
+				Comment: comment,
+			}
+		}
+	}
+	return ast.Format(root), nil
+}
+
+// varNode returns the literal node of the value of the var.
+func varNode(v Var) (ast.Node, error) {
+	switch v.Type {
+	case VarBool:
+		return &ast.BoolNode{Bool: v.BoolValue}, nil
+	case VarInt:
+		return &ast.NumberNode{IsInt: true, Int64: v.IntValue}, nil
+	case VarFloat:
+		return &ast.NumberNode{IsFloat: true, Float64: v.FloatValue}, nil
+	case VarDuration:
+		return &ast.DurationNode{Dur: v.DurationValue, Literal: influxql.FormatDuration(v.DurationValue)}, nil
+	case VarString:
+		return &ast.StringNode{Literal: v.StringValue}, nil
+	case VarRegex:
+		r, err := regexp.Compile(v.RegexValue)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.RegexNode{Regex: r, Literal: strings.Replace(v.RegexValue, "/", `\/`, -1)}, nil
+	case VarLambda:
+		return ast.ParseLambda(v.LambdaValue)
+	case VarStar:
+		return &ast.StarNode{}, nil
+	case VarList:
+		list := &ast.ListNode{Nodes: make([]ast.Node, len(v.ListValue))}
+		for i, l := range v.ListValue {
+			n, err := varNode(l)
+			if err != nil {
+				return nil, err
+			}
+			list.Nodes[i] = n
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("unsupported var type %v", v.Type)
+	}
+}
@@ -31,8 +31,12 @@ type StateTrackingNode struct {
 
 	newTracker func() stateTracker
 
+	// groupsMu guards the groups, it is held for reading while evaluating their expressions
+	// so their state is consistent in snapshots.
 	groupsMu sync.RWMutex
 	groups   map[models.GroupID]*stateTrackingGroup
+	// State of the expressions restored from a snapshot for groups not seen since.
+	restored map[models.GroupID][]byte
 }
 
 func (stn *StateTrackingNode) group(g models.GroupID) (*stateTrackingGroup, error) {
@@ -52,11 +56,18 @@ func (stn *StateTrackingNode) group(g models.GroupID) (*stateTrackingGroup, erro
 			stg = &stateTrackingGroup{}
 
 			var err error
-			stg.Expression, err = stateful.NewExpression(stn.lambda.Expression)
+			stg.Expression, err = stn.newExpression(stn.lambda.Expression)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to compile expression: %v", err)
 			}
 
+			if state, ok := stn.restored[g]; ok {
+				if err := stg.Expression.Restore(state); err != nil {
+					stn.logger.Printf("E! failed to restore state of group %s: %v", g, err)
+				}
+				delete(stn.restored, g)
+			}
+
 			stg.ScopePool = stateful.NewScopePool(ast.FindReferenceVariables(stn.lambda.Expression))
 
 			stg.tracker = stn.newTracker()
@@ -67,7 +78,12 @@ func (stn *StateTrackingNode) group(g models.GroupID) (*stateTrackingGroup, erro
 	return stg, nil
 }
 
-func (stn *StateTrackingNode) runStateTracking(_ []byte) error {
+func (stn *StateTrackingNode) runStateTracking(snapshot []byte) error {
+	if len(snapshot) > 0 {
+		if err := stn.restore(snapshot); err != nil {
+			return err
+		}
+	}
 	// Setup working_cardinality gauage.
 	valueF := func() int64 {
 		stn.groupsMu.RLock()
@@ -86,7 +102,9 @@ func (stn *StateTrackingNode) runStateTracking(_ []byte) error {
 				return err
 			}
 
+			stn.groupsMu.RLock()
 			pass, err := EvalPredicate(stg.Expression, stg.ScopePool, p.Time, p.Fields, p.Tags)
+			stn.groupsMu.RUnlock()
 			if err != nil {
 				stn.incrementErrorCount()
 				stn.logger.Println("E! error while evaluating expression:", err)
@@ -113,7 +131,11 @@ func (stn *StateTrackingNode) runStateTracking(_ []byte) error {
 			if err != nil {
 				return err
 			}
+			// Each batch is tracked from its first point,
+			// so the state of the stateful functions in the expression is reset as well.
+			stn.groupsMu.RLock()
 			stg.tracker.reset()
+			stg.Expression.Reset()
 
 			b.Points = b.ShallowCopyPoints()
 			for i := 0; i < len(b.Points); {
@@ -130,6 +152,7 @@ func (stn *StateTrackingNode) runStateTracking(_ []byte) error {
 				p.Fields = p.Fields.Copy()
 				p.Fields[stn.as] = stg.tracker.track(*p, pass)
 			}
+			stn.groupsMu.RUnlock()
 
 			stn.timer.Stop()
 			for _, child := range stn.outs {
@@ -143,6 +166,27 @@ func (stn *StateTrackingNode) runStateTracking(_ []byte) error {
 	return nil
 }
 
+func (stn *StateTrackingNode) snapshot() ([]byte, error) {
+	stn.groupsMu.Lock()
+	defer stn.groupsMu.Unlock()
+	expressions := make(map[models.GroupID]stateful.Expression, len(stn.groups))
+	for g, stg := range stn.groups {
+		expressions[g] = stg.Expression
+	}
+	return snapshotExpressions(expressions, stn.restored)
+}
+
+func (stn *StateTrackingNode) restore(data []byte) error {
+	restored, err := restoreExpressions(data)
+	if err != nil {
+		return err
+	}
+	stn.groupsMu.Lock()
+	stn.restored = restored
+	stn.groupsMu.Unlock()
+	return nil
+}
+
 type stateDurationTracker struct {
 	sd *pipeline.StateDurationNode
 
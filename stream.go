@@ -62,7 +62,7 @@ func newFromNode(et *ExecutingTask, n *pipeline.FromNode, l *log.Logger) (*FromN
 	sn.allDimensions, sn.dimensions = determineDimensions(n.Dimensions)
 
 	if n.Lambda != nil {
-		expr, err := stateful.NewExpression(n.Lambda.Expression)
+		expr, err := sn.newExpression(n.Lambda.Expression)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to compile from expression: %v", err)
 		}
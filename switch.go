@@ -0,0 +1,217 @@
+package kapacitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/kapacitor/expvar"
+	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+const statRoutePrefix = "route_"
+
+type SwitchNode struct {
+	node
+	s *pipeline.SwitchNode
+
+	// Output edges for each route name
+	routes map[string][]*Edge
+	// Number of points sent to each route
+	routeCounts map[string]*expvar.Int
+
+	expressions map[models.GroupID][]stateful.Expression
+	scopePools  []stateful.ScopePool
+}
+
+// Create a new SwitchNode which routes each point to a single route.
+func newSwitchNode(et *ExecutingTask, n *pipeline.SwitchNode, l *log.Logger) (*SwitchNode, error) {
+	if len(n.Cases) == 0 {
+		return nil, errors.New("switch node must have at least one case")
+	}
+	sn := &SwitchNode{
+		node:        node{Node: n, et: et, logger: l},
+		s:           n,
+		routes:      make(map[string][]*Edge),
+		routeCounts: make(map[string]*expvar.Int),
+		expressions: make(map[models.GroupID][]stateful.Expression),
+		scopePools:  make([]stateful.ScopePool, len(n.Cases)),
+	}
+	for i, c := range n.Cases {
+		sn.scopePools[i] = stateful.NewScopePool(ast.FindReferenceVariables(c.Lambda.Expression))
+	}
+	sn.runF = sn.runSwitch
+	return sn, nil
+}
+
+func (s *SwitchNode) runSwitch([]byte) error {
+	// Children and output edges are linked in the same order.
+	for i, child := range s.children {
+		r, ok := child.(*SwitchRouteNode)
+		if !ok {
+			return fmt.Errorf("unexpected child of switch node %s", child.Name())
+		}
+		s.routes[r.r.Case] = append(s.routes[r.r.Case], s.outs[i])
+	}
+	names := make([]string, 0, len(s.s.Cases)+1)
+	for _, c := range s.s.Cases {
+		names = append(names, c.Name)
+	}
+	if s.s.DefaultRoute != "" {
+		names = append(names, s.s.DefaultRoute)
+	}
+	for _, name := range names {
+		count := &expvar.Int{}
+		s.routeCounts[name] = count
+		s.statMap.Set(statRoutePrefix+name, count)
+	}
+
+	var mu sync.RWMutex
+	valueF := func() int64 {
+		mu.RLock()
+		l := len(s.expressions)
+		mu.RUnlock()
+		return int64(l)
+	}
+	s.statMap.Set(statCardinalityGauge, expvar.NewIntFuncGauge(valueF))
+
+	getExpressions := func(group models.GroupID) ([]stateful.Expression, error) {
+		mu.RLock()
+		exprs := s.expressions[group]
+		mu.RUnlock()
+		if exprs != nil {
+			return exprs, nil
+		}
+		exprs = make([]stateful.Expression, len(s.s.Cases))
+		for i, c := range s.s.Cases {
+			expr, err := s.newExpression(c.Lambda.Expression)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to compile expression of switch case %q: %v", c.Name, err)
+			}
+			exprs[i] = expr
+		}
+		mu.Lock()
+		s.expressions[group] = exprs
+		mu.Unlock()
+		return exprs, nil
+	}
+
+	switch s.Wants() {
+	case pipeline.StreamEdge:
+		for p, ok := s.ins[0].NextPoint(); ok; p, ok = s.ins[0].NextPoint() {
+			s.timer.Start()
+			exprs, err := getExpressions(p.Group)
+			if err != nil {
+				return err
+			}
+			route, ok := s.route(exprs, p.Time, p.Fields, p.Tags)
+			if ok {
+				s.routeCounts[route].Add(1)
+				s.timer.Pause()
+				for _, out := range s.routes[route] {
+					err := out.CollectPoint(p)
+					if err != nil {
+						return err
+					}
+				}
+				s.timer.Resume()
+			}
+			s.timer.Stop()
+		}
+	case pipeline.BatchEdge:
+		for b, ok := s.ins[0].NextBatch(); ok; b, ok = s.ins[0].NextBatch() {
+			s.timer.Start()
+			exprs, err := getExpressions(b.Group)
+			if err != nil {
+				return err
+			}
+			points := make(map[string][]models.BatchPoint, len(s.routes))
+			for _, p := range b.Points {
+				if route, ok := s.route(exprs, p.Time, p.Fields, p.Tags); ok {
+					s.routeCounts[route].Add(1)
+					points[route] = append(points[route], p)
+				}
+			}
+			s.timer.Stop()
+			for route, outs := range s.routes {
+				if len(points[route]) == 0 {
+					continue
+				}
+				rb := b
+				rb.Points = points[route]
+				for _, out := range outs {
+					err := out.CollectBatch(rb)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// route returns the name of the route for the point and whether the point should be forwarded.
+// Points for which a case fails to evaluate are dropped.
+func (s *SwitchNode) route(exprs []stateful.Expression, now time.Time, fields models.Fields, tags models.Tags) (string, bool) {
+	for i, expr := range exprs {
+		pass, err := EvalPredicate(expr, s.scopePools[i], now, fields, tags)
+		if err != nil {
+			s.incrementErrorCount()
+			s.logger.Printf("E! error while evaluating expression of switch case %q: %v", s.s.Cases[i].Name, err)
+			// Drop the point, it cannot be known which route it belongs to.
+			return "", false
+		}
+		if pass {
+			return s.s.Cases[i].Name, true
+		}
+	}
+	if s.s.DefaultRoute != "" {
+		return s.s.DefaultRoute, true
+	}
+	return "", false
+}
+
+type SwitchRouteNode struct {
+	node
+	r *pipeline.SwitchRouteNode
+}
+
+// Create a new SwitchRouteNode which passes on the points of a single route.
+func newSwitchRouteNode(et *ExecutingTask, n *pipeline.SwitchRouteNode, l *log.Logger) (*SwitchRouteNode, error) {
+	rn := &SwitchRouteNode{
+		node: node{Node: n, et: et, logger: l},
+		r:    n,
+	}
+	rn.node.runF = rn.runRoute
+	return rn, nil
+}
+
+func (r *SwitchRouteNode) runRoute([]byte) error {
+	switch r.Wants() {
+	case pipeline.StreamEdge:
+		for p, ok := r.ins[0].NextPoint(); ok; p, ok = r.ins[0].NextPoint() {
+			for _, child := range r.outs {
+				err := child.CollectPoint(p)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case pipeline.BatchEdge:
+		for b, ok := r.ins[0].NextBatch(); ok; b, ok = r.ins[0].NextBatch() {
+			for _, child := range r.outs {
+				err := child.CollectBatch(b)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -71,6 +71,7 @@ type Task struct {
 	Type             TaskType
 	DBRPs            []DBRP
 	SnapshotInterval time.Duration
+	Limits           TaskLimits
 }
 
 func (t *Task) Dot() []byte {
@@ -107,6 +108,7 @@ type ExecutingTask struct {
 	stopping chan struct{}
 	wg       sync.WaitGroup
 	logger   *log.Logger
+	limiter  *taskLimiter
 
 	// Mutex for throughput var
 	tmu        sync.RWMutex
@@ -123,6 +125,7 @@ func NewExecutingTask(tm *TaskMaster, t *Task) (*ExecutingTask, error) {
 		lookup:  make(map[pipeline.ID]Node),
 		logger:  l,
 	}
+	et.limiter = newTaskLimiter(et)
 	err := et.link()
 	if err != nil {
 		return nil, err
@@ -191,26 +194,14 @@ func (et *ExecutingTask) link() error {
 func (et *ExecutingTask) start(ins []*Edge, snapshot *TaskSnapshot) error {
 
 	for _, in := range ins {
+		if et.limiter.limited() {
+			in.limit(et.limiter, true)
+		}
 		et.source.addParentEdge(in)
 	}
-	validSnapshot := false
-	if snapshot != nil {
-		err := et.walk(func(n Node) error {
-			_, ok := snapshot.NodeSnapshots[n.Name()]
-			if !ok {
-				return fmt.Errorf("task pipeline changed not using snapshot")
-			}
-			return nil
-		})
-		validSnapshot = err == nil
-	}
-
+	states := et.restoredStates(snapshot)
 	err := et.walk(func(n Node) error {
-		if validSnapshot {
-			n.start(snapshot.NodeSnapshots[n.Name()])
-		} else {
-			n.start(nil)
-		}
+		n.start(states[n.Name()])
 		return nil
 	})
 	if err != nil {
@@ -224,6 +215,10 @@ func (et *ExecutingTask) start(ins []*Edge, snapshot *TaskSnapshot) error {
 	// Start calcThroughput
 	et.wg.Add(1)
 	go et.calcThroughput()
+	if et.limiter.limited() {
+		et.wg.Add(1)
+		go et.limiter.run()
+	}
 	return nil
 }
 
@@ -310,11 +305,16 @@ func (et *ExecutingTask) StopStats() {
 	})
 }
 
-// Wait till the task finishes and return any error
+// Wait till the task finishes and return any error.
+// If the task was quarantined for exceeding its limits the error is a *QuarantineError.
 func (et *ExecutingTask) Wait() error {
-	return et.rwalk(func(n Node) error {
+	err := et.rwalk(func(n Node) error {
 		return n.Wait()
 	})
+	if qerr := et.limiter.err(); qerr != nil {
+		return qerr
+	}
+	return err
 }
 
 // Get a named output.
@@ -344,6 +344,9 @@ func (et *ExecutingTask) ExecutionStats() (ExecutionStats, error) {
 
 	// Fill the task stats
 	executionStats.TaskStats["throughput"] = et.getThroughput()
+	if et.limiter.limited() {
+		executionStats.TaskStats["paused"] = et.limiter.paused()
+	}
 
 	// Fill the nodes stats
 	err := et.walk(func(node Node) error {
@@ -474,10 +477,22 @@ func (et *ExecutingTask) createNode(p pipeline.Node, l *log.Logger) (n Node, err
 		n, err = newEvalNode(et, t, l)
 	case *pipeline.WhereNode:
 		n, err = newWhereNode(et, t, l)
+	case *pipeline.SwitchNode:
+		n, err = newSwitchNode(et, t, l)
+	case *pipeline.SwitchRouteNode:
+		n, err = newSwitchRouteNode(et, t, l)
 	case *pipeline.SampleNode:
 		n, err = newSampleNode(et, t, l)
+	case *pipeline.FillNode:
+		n, err = newFillNode(et, t, l)
 	case *pipeline.DerivativeNode:
 		n, err = newDerivativeNode(et, t, l)
+	case *pipeline.RateNode:
+		n, err = newRateNode(et, t, l)
+	case *pipeline.HistogramNode:
+		n, err = newHistogramNode(et, t, l)
+	case *pipeline.AnomalyNode:
+		n, err = newAnomalyNode(et, t, l)
 	case *pipeline.UDFNode:
 		n, err = newUDFNode(et, t, l)
 	case *pipeline.StatsNode:
@@ -512,12 +527,17 @@ func (et *ExecutingTask) createNode(p pipeline.Node, l *log.Logger) (n Node, err
 }
 
 type TaskSnapshot struct {
+	// The state of each node, keyed by the name of the node which includes its ID.
 	NodeSnapshots map[string][]byte
+	// The fingerprint of the configuration of each node, keyed by the name of the node.
+	// The state of a node is only restored if its configuration is unchanged.
+	NodeConfigs map[string]string
 }
 
 func (et *ExecutingTask) Snapshot() (*TaskSnapshot, error) {
 	snapshot := &TaskSnapshot{
 		NodeSnapshots: make(map[string][]byte),
+		NodeConfigs:   make(map[string]string),
 	}
 	err := et.walk(func(n Node) error {
 		data, err := n.snapshot()
@@ -530,9 +550,58 @@ func (et *ExecutingTask) Snapshot() (*TaskSnapshot, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = et.Task.Pipeline.Walk(func(n pipeline.Node) error {
+		snapshot.NodeConfigs[n.Name()] = pipeline.Fingerprint(n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return snapshot, nil
 }
 
+// restoredStates returns the states of the nodes of the snapshot that are restored, keyed by node name.
+// The state of a node is restored if the snapshot has a node with the same ID and configuration.
+// Node IDs are assigned in the order the nodes are defined, so nodes defined after an inserted or removed node
+// do not match and lose their state.
+func (et *ExecutingTask) restoredStates(snapshot *TaskSnapshot) map[string][]byte {
+	if snapshot == nil {
+		return nil
+	}
+	if snapshot.NodeConfigs == nil {
+		// The snapshot does not record the configuration of the nodes,
+		// only use it if the task has the same nodes.
+		err := et.walk(func(n Node) error {
+			if _, ok := snapshot.NodeSnapshots[n.Name()]; !ok {
+				return fmt.Errorf("task pipeline changed not using snapshot")
+			}
+			return nil
+		})
+		if err != nil {
+			return nil
+		}
+		return snapshot.NodeSnapshots
+	}
+	states := make(map[string][]byte)
+	err := et.Task.Pipeline.Walk(func(n pipeline.Node) error {
+		data, ok := snapshot.NodeSnapshots[n.Name()]
+		if !ok {
+			return nil
+		}
+		if snapshot.NodeConfigs[n.Name()] != pipeline.Fingerprint(n) {
+			et.logger.Printf("D! configuration of node %s changed, not restoring its state", n.Name())
+			return nil
+		}
+		states[n.Name()] = data
+		return nil
+	})
+	if err != nil {
+		et.logger.Println("E! failed to match the snapshot with the nodes of the task, not restoring their state:", err)
+		return nil
+	}
+	return states
+}
+
 func (et *ExecutingTask) runSnapshotter() {
 	defer et.wg.Done()
 	// Wait random duration to splay snapshot events across interval
@@ -0,0 +1,297 @@
+package kapacitor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/kapacitor/timer"
+)
+
+const (
+	statDropped = "dropped"
+
+	defaultLimitSampleInterval = 10 * time.Second
+)
+
+// The action taken when a task exceeds one of its limits.
+type LimitAction int
+
+const (
+	// Discard the oldest data of the limited queue or buffer.
+	// Limits that cannot be enforced by discarding data pause the task instead.
+	LimitDropOldest LimitAction = iota
+	// Drop the incoming data of the task until it is back under its limits.
+	LimitPause
+	// Stop the task and quarantine it until it is enabled again.
+	LimitStop
+)
+
+func (a LimitAction) String() string {
+	switch a {
+	case LimitDropOldest:
+		return "drop-oldest"
+	case LimitPause:
+		return "pause"
+	case LimitStop:
+		return "stop"
+	default:
+		return "unknown"
+	}
+}
+
+func (a LimitAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *LimitAction) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "drop-oldest":
+		*a = LimitDropOldest
+	case "pause":
+		*a = LimitPause
+	case "stop":
+		*a = LimitStop
+	default:
+		return fmt.Errorf("unknown limit action %s", string(text))
+	}
+	return nil
+}
+
+// The limits of the resources used by an executing task.
+// A zero limit means unlimited.
+type TaskLimits struct {
+	// Maximum number of groups of any node of the task.
+	// The groups of a node are never removed, so a task exceeding it is always quarantined whatever the action.
+	MaxGroups int64
+	// Maximum number of points, or batches for batch tasks, buffered by any window or join node of the task.
+	MaxBufferedPoints int64
+	// Maximum number of points or batches queued on an edge between two nodes of the task.
+	MaxEdgeQueue int
+	// Maximum number of CPUs the nodes of the task keep busy, i.e. 0.5 is half of one CPU.
+	MaxCPU float64
+	// How often the groups, buffered points and CPU time of the task are sampled.
+	SampleInterval time.Duration
+	// The action taken when a limit is exceeded.
+	Action LimitAction
+}
+
+// Whether the task has any limit.
+func (l TaskLimits) Limited() bool {
+	return l.MaxGroups > 0 || l.MaxBufferedPoints > 0 || l.MaxEdgeQueue > 0 || l.MaxCPU > 0
+}
+
+// A QuarantineError is returned by ExecutingTask.Wait when the task was stopped for exceeding one of its limits.
+type QuarantineError struct {
+	Reason string
+}
+
+func (e *QuarantineError) Error() string {
+	return "task quarantined: " + e.Reason
+}
+
+// A node that buffers points, its buffer is limited by the MaxBufferedPoints limit of the task.
+type bufferingNode interface {
+	bufferedCount() int64
+}
+
+// taskLimiter enforces the limits of an executing task.
+type taskLimiter struct {
+	et     *ExecutingTask
+	limits TaskLimits
+
+	// Total time in nanoseconds the nodes of the task have been busy.
+	busy int64
+	// Set to 1 while the incoming data of the task is dropped.
+	pausedFlag int32
+
+	mu          sync.Mutex
+	quarantined *QuarantineError
+}
+
+func newTaskLimiter(et *ExecutingTask) *taskLimiter {
+	return &taskLimiter{
+		et:     et,
+		limits: et.Task.Limits,
+	}
+}
+
+func (l *taskLimiter) limited() bool {
+	return l.limits.Limited()
+}
+
+// Whether the limited queues and buffers of the task discard their oldest data.
+func (l *taskLimiter) dropOldest() bool {
+	return l.limits.Action == LimitDropOldest
+}
+
+func (l *taskLimiter) paused() bool {
+	return atomic.LoadInt32(&l.pausedFlag) == 1
+}
+
+// exceed applies the action of the limits for a limit that cannot be enforced by discarding data.
+func (l *taskLimiter) exceed(reason string) {
+	switch l.limits.Action {
+	case LimitStop:
+		l.quarantine(reason)
+	default:
+		l.pause(reason)
+	}
+}
+
+func (l *taskLimiter) pause(reason string) {
+	if atomic.CompareAndSwapInt32(&l.pausedFlag, 0, 1) {
+		l.et.logger.Printf("W! pausing task, dropping its incoming data until it is back under its limits: %s", reason)
+	}
+}
+
+func (l *taskLimiter) resume() {
+	if atomic.CompareAndSwapInt32(&l.pausedFlag, 1, 0) {
+		l.et.logger.Println("I! resuming task, it is back under its limits")
+	}
+}
+
+// quarantine aborts the task and stops it.
+// ExecutingTask.Wait returns a QuarantineError once the task has stopped.
+func (l *taskLimiter) quarantine(reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.quarantined != nil {
+		return
+	}
+	l.quarantined = &QuarantineError{Reason: reason}
+	l.et.logger.Println("E! quarantining task:", reason)
+	_ = l.et.walk(func(n Node) error {
+		n.abortParentEdges()
+		return nil
+	})
+	// The caller may be a node or the sampler of the task, which the task waits for when it stops.
+	go l.et.tm.stopExecutingTask(l.et)
+}
+
+func (l *taskLimiter) err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.quarantined == nil {
+		return nil
+	}
+	return l.quarantined
+}
+
+// timer returns a timer that also records the time the node is busy if the CPU time of the task is limited.
+func (l *taskLimiter) timer(t timer.Timer) timer.Timer {
+	if l.limits.MaxCPU <= 0 {
+		return t
+	}
+	return &busyTimer{Timer: t, busy: &l.busy}
+}
+
+// run samples the groups, buffered points and CPU time of the task until the task stops.
+func (l *taskLimiter) run() {
+	defer l.et.wg.Done()
+	interval := l.limits.SampleInterval
+	if interval <= 0 {
+		interval = defaultLimitSampleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := time.Now()
+	busy := atomic.LoadInt64(&l.busy)
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			current := atomic.LoadInt64(&l.busy)
+			cpu := float64(current-busy) / float64(now.Sub(last))
+			last, busy = now, current
+			if reason := l.checkGroups(); reason != "" {
+				// Pausing the task cannot bring it back under the limit, the groups are never removed.
+				l.quarantine(reason)
+			} else if reason := l.check(cpu); reason != "" {
+				l.exceed(reason)
+			} else {
+				l.resume()
+			}
+		case <-l.et.stopping:
+			return
+		}
+	}
+}
+
+// checkGroups returns why the task exceeds its MaxGroups limit, or an empty string if it is under it.
+func (l *taskLimiter) checkGroups() string {
+	max := l.limits.MaxGroups
+	if max <= 0 {
+		return ""
+	}
+	reason := ""
+	_ = l.et.walk(func(n Node) error {
+		if c := n.cardinality(); c > max {
+			reason = fmt.Sprintf("node %s has %d groups, the limit is %d", n.Name(), c, max)
+			return errLimitExceeded
+		}
+		return nil
+	})
+	return reason
+}
+
+// check returns why the task exceeds its limits other than MaxGroups, or an empty string if it is under them.
+func (l *taskLimiter) check(cpu float64) string {
+	if max := l.limits.MaxCPU; max > 0 && cpu > max {
+		return fmt.Sprintf("task keeps %0.2f CPUs busy, the limit is %0.2f", cpu, max)
+	}
+	reason := ""
+	_ = l.et.walk(func(n Node) error {
+		if max := l.limits.MaxBufferedPoints; max > 0 {
+			if b, ok := n.(bufferingNode); ok {
+				if c := b.bufferedCount(); c > max {
+					reason = fmt.Sprintf("node %s buffers %d points, the limit is %d", n.Name(), c, max)
+					return errLimitExceeded
+				}
+			}
+		}
+		return nil
+	})
+	return reason
+}
+
+var errLimitExceeded = errors.New("limit exceeded")
+
+// busyTimer accumulates the time between the start and the stop of the timer, excluding the time it is paused.
+type busyTimer struct {
+	timer.Timer
+	busy    *int64
+	start   time.Time
+	running bool
+}
+
+func (t *busyTimer) Start() {
+	t.start = time.Now()
+	t.running = true
+	t.Timer.Start()
+}
+
+func (t *busyTimer) Pause() {
+	t.record()
+	t.Timer.Pause()
+}
+
+func (t *busyTimer) Resume() {
+	t.start = time.Now()
+	t.running = true
+	t.Timer.Resume()
+}
+
+func (t *busyTimer) Stop() {
+	t.record()
+	t.Timer.Stop()
+}
+
+func (t *busyTimer) record() {
+	if t.running {
+		atomic.AddInt64(t.busy, int64(time.Since(t.start)))
+		t.running = false
+	}
+}
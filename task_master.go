@@ -31,6 +31,7 @@ import (
 	"github.com/influxdata/kapacitor/services/telegram"
 	"github.com/influxdata/kapacitor/services/victorops"
 	"github.com/influxdata/kapacitor/tick"
+	"github.com/influxdata/kapacitor/tick/ast"
 	"github.com/influxdata/kapacitor/tick/stateful"
 	"github.com/influxdata/kapacitor/timer"
 	"github.com/influxdata/kapacitor/udf"
@@ -74,6 +75,12 @@ type TaskMaster struct {
 		LoadSnapshot(id string) (*TaskSnapshot, error)
 	}
 	DeadmanService pipeline.DeadmanService
+	// Resolves modules imported by TICKscripts
+	ModuleImporter tick.Importer
+	// Looks up the calendars used by lambda expressions
+	CalendarService interface {
+		Calendar(name string) (stateful.Calendar, bool)
+	}
 
 	UDFService UDFService
 
@@ -149,6 +156,9 @@ type TaskMaster struct {
 
 	DefaultRetentionPolicy string
 
+	// EdgeTracer, if set, traces the data collected by the edges between the nodes of the tasks.
+	EdgeTracer EdgeTracer
+
 	// Incoming streams
 	writePointsIn StreamCollector
 	writesClosed  bool
@@ -215,6 +225,8 @@ func (tm *TaskMaster) New(id string) *TaskMaster {
 	n.HTTPDService = tm.HTTPDService
 	n.TaskStore = tm.TaskStore
 	n.DeadmanService = tm.DeadmanService
+	n.ModuleImporter = tm.ModuleImporter
+	n.CalendarService = tm.CalendarService
 	n.UDFService = tm.UDFService
 	n.AlertService = tm.AlertService
 	n.InfluxDBService = tm.InfluxDBService
@@ -315,7 +327,7 @@ func (tm *TaskMaster) NewTemplate(
 		srcEdge = pipeline.BatchEdge
 	}
 
-	tp, err := pipeline.CreateTemplatePipeline(script, srcEdge, scope, tm.DeadmanService)
+	tp, err := pipeline.CreateTemplatePipeline(script, srcEdge, scope, tm.DeadmanService, tm.ModuleImporter)
 	if err != nil {
 		return nil, err
 	}
@@ -323,6 +335,25 @@ func (tm *TaskMaster) NewTemplate(
 	return t, nil
 }
 
+// ValidateModule evaluates the TICKscript of a module.
+// Modules that use the batch source are evaluated as part of a batch task,
+// all other modules as part of a stream task.
+func (tm *TaskMaster) ValidateModule(script string) error {
+	root, err := ast.Parse(script)
+	if err != nil {
+		return err
+	}
+	tt := StreamTask
+	ast.Walk(root, func(n ast.Node) (ast.Node, error) {
+		if ident, ok := n.(*ast.IdentifierNode); ok && ident.Ident == "batch" {
+			tt = BatchTask
+		}
+		return n, nil
+	})
+	_, err = tm.NewTemplate("", script, tt)
+	return err
+}
+
 // Create a new task in the context of a TaskMaster
 func (tm *TaskMaster) NewTask(
 	id,
@@ -348,7 +379,7 @@ func (tm *TaskMaster) NewTask(
 		srcEdge = pipeline.BatchEdge
 	}
 
-	p, err := pipeline.CreatePipeline(script, srcEdge, scope, tm.DeadmanService, vars)
+	p, err := pipeline.CreatePipeline(script, srcEdge, scope, tm.DeadmanService, vars, tm.ModuleImporter)
 	if err != nil {
 		return nil, err
 	}
@@ -512,6 +543,16 @@ func (tm *TaskMaster) stopTask(id string) (err error) {
 	return
 }
 
+// stopExecutingTask stops the task unless it has already been stopped,
+// possibly to start it again under the same ID.
+func (tm *TaskMaster) stopExecutingTask(et *ExecutingTask) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.tasks[et.Task.ID] == et {
+		tm.stopTask(et.Task.ID)
+	}
+}
+
 // internal deleteTask function. The caller must have acquired
 // the lock in order to call this function
 func (tm *TaskMaster) deleteTask(id string) {
@@ -534,6 +575,15 @@ func (tm *TaskMaster) IsExecuting(id string) bool {
 	return executing
 }
 
+// IsPaused reports whether the task is executing and its incoming data is dropped
+// until it is back under its limits.
+func (tm *TaskMaster) IsPaused(id string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	task, executing := tm.tasks[id]
+	return executing && task.limiter.paused()
+}
+
 func (tm *TaskMaster) ExecutionStats(id string) (ExecutionStats, error) {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -38,6 +38,9 @@ const (
 	TokenRegex
 	TokenComment
 	TokenStar
+	TokenDBRP
+	TokenImport
+	TokenFunc
 
 	// begin operator tokens
 	begin_tok_operator
@@ -110,6 +113,9 @@ const (
 	KW_False  = "FALSE"
 	KW_Var    = "var"
 	KW_Lambda = "lambda"
+	KW_DBRP   = "dbrp"
+	KW_Import = "import"
+	KW_Func   = "func"
 )
 
 var keywords = map[string]TokenType{
@@ -119,6 +125,9 @@ var keywords = map[string]TokenType{
 	KW_False:  TokenFalse,
 	KW_Var:    TokenVar,
 	KW_Lambda: TokenLambda,
+	KW_DBRP:   TokenDBRP,
+	KW_Import: TokenImport,
+	KW_Func:   TokenFunc,
 }
 
 func init() {
@@ -155,6 +164,12 @@ func (t TokenType) String() string {
 		return "//"
 	case t == TokenStar:
 		return "*"
+	case t == TokenDBRP:
+		return "dbrp"
+	case t == TokenImport:
+		return "import"
+	case t == TokenFunc:
+		return "func"
 	case t == TokenDot:
 		return "."
 	case t == TokenPipe:
@@ -475,6 +490,15 @@ func lexIdentOrKeyword(l *lexer) stateFn {
 					// 'lambda' is a valid identifier.
 					l.backup()
 					l.emit(TokenIdent)
+				} else if t == TokenDBRP && !strings.HasPrefix(strings.TrimLeft(l.input[l.pos:], " \t"), `"`) {
+					// 'dbrp' is a valid identifier unless followed by a reference.
+					l.emit(TokenIdent)
+				} else if t == TokenImport && !strings.HasPrefix(strings.TrimLeft(l.input[l.pos:], " \t"), "'") {
+					// 'import' is a valid identifier unless followed by a string.
+					l.emit(TokenIdent)
+				} else if t == TokenFunc && !isFuncName(l.input[l.pos:]) {
+					// 'func' is a valid identifier unless followed by the name of a function.
+					l.emit(TokenIdent)
 				} else {
 					l.emit(t)
 				}
@@ -486,6 +510,16 @@ func lexIdentOrKeyword(l *lexer) stateFn {
 	}
 }
 
+// isFuncName reports whether the input starts with whitespace followed by an identifier.
+func isFuncName(input string) bool {
+	name := strings.TrimLeft(input, " \t")
+	if len(name) == len(input) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsLetter(r) || r == '_'
+}
+
 // isValidIdent reports whether r is either a letter or a digit
 func isValidIdent(r rune) bool {
 	return unicode.IsDigit(r) || unicode.IsLetter(r) || r == '_'
@@ -257,6 +257,62 @@ func TestLexer(t *testing.T) {
 				token{TokenEOF, 7, ""},
 			},
 		},
+		{
+			in: `dbrp "db"."rp"`,
+			tokens: []token{
+				token{TokenDBRP, 0, "dbrp"},
+				token{TokenReference, 5, `"db"`},
+				token{TokenDot, 9, "."},
+				token{TokenReference, 10, `"rp"`},
+				token{TokenEOF, 14, ""},
+			},
+		},
+		{
+			in: "dbrp ",
+			tokens: []token{
+				token{TokenIdent, 0, "dbrp"},
+				token{TokenEOF, 5, ""},
+			},
+		},
+		{
+			in: "import 'shared/alerting.tick'",
+			tokens: []token{
+				token{TokenImport, 0, "import"},
+				token{TokenString, 7, "'shared/alerting.tick'"},
+				token{TokenEOF, 29, ""},
+			},
+		},
+		{
+			in: "import()",
+			tokens: []token{
+				token{TokenIdent, 0, "import"},
+				token{TokenLParen, 6, "("},
+				token{TokenRParen, 7, ")"},
+				token{TokenEOF, 8, ""},
+			},
+		},
+		{
+			in: "func pct(a, b)",
+			tokens: []token{
+				token{TokenFunc, 0, "func"},
+				token{TokenIdent, 5, "pct"},
+				token{TokenLParen, 8, "("},
+				token{TokenIdent, 9, "a"},
+				token{TokenComma, 10, ","},
+				token{TokenIdent, 12, "b"},
+				token{TokenRParen, 13, ")"},
+				token{TokenEOF, 14, ""},
+			},
+		},
+		{
+			in: "func()",
+			tokens: []token{
+				token{TokenIdent, 0, "func"},
+				token{TokenLParen, 4, "("},
+				token{TokenRParen, 5, ")"},
+				token{TokenEOF, 6, ""},
+			},
+		},
 		//Numbers
 		{
 			in: "42",
@@ -446,6 +446,149 @@ func (n *TypeDeclarationNode) Equal(o interface{}) bool {
 	return false
 }
 
+// Represents a dbrp statement, declaring a database and retention policy the task uses.
+type DBRPNode struct {
+	position
+	DB      *ReferenceNode
+	RP      *ReferenceNode
+	Comment *CommentNode
+}
+
+func newDBRP(p position, db, rp *ReferenceNode, c *CommentNode) *DBRPNode {
+	return &DBRPNode{
+		position: p,
+		DB:       db,
+		RP:       rp,
+		Comment:  c,
+	}
+}
+
+func (n *DBRPNode) String() string {
+	return fmt.Sprintf("DBRPNode@%v{%v %v}%v", n.position, n.DB, n.RP, n.Comment)
+}
+
+func (n *DBRPNode) Format(buf *bytes.Buffer, indent string, onNewLine bool) {
+	if n.Comment != nil {
+		n.Comment.Format(buf, indent, onNewLine)
+	}
+	buf.WriteString(KW_DBRP)
+	buf.WriteByte(' ')
+	n.DB.Format(buf, indent, false)
+	buf.WriteByte('.')
+	n.RP.Format(buf, indent, false)
+}
+
+func (n *DBRPNode) SetComment(c *CommentNode) {
+	n.Comment = c
+}
+func (n *DBRPNode) Equal(o interface{}) bool {
+	if on, ok := o.(*DBRPNode); ok {
+		return n.DB.Equal(on.DB) &&
+			n.RP.Equal(on.RP)
+	}
+	return false
+}
+
+// Represents an import statement, importing the vars and fragments of a module.
+type ImportNode struct {
+	position
+	Path    *StringNode
+	Comment *CommentNode
+}
+
+func newImport(p position, path *StringNode, c *CommentNode) *ImportNode {
+	return &ImportNode{
+		position: p,
+		Path:     path,
+		Comment:  c,
+	}
+}
+
+func (n *ImportNode) String() string {
+	return fmt.Sprintf("ImportNode@%v{%v}%v", n.position, n.Path, n.Comment)
+}
+
+func (n *ImportNode) Format(buf *bytes.Buffer, indent string, onNewLine bool) {
+	if n.Comment != nil {
+		n.Comment.Format(buf, indent, onNewLine)
+	}
+	buf.WriteString(KW_Import)
+	buf.WriteByte(' ')
+	n.Path.Format(buf, indent, false)
+}
+
+func (n *ImportNode) SetComment(c *CommentNode) {
+	n.Comment = c
+}
+func (n *ImportNode) Equal(o interface{}) bool {
+	if on, ok := o.(*ImportNode); ok {
+		return n.Path.Equal(on.Path)
+	}
+	return false
+}
+
+// Represents a function declaration, defining a function that can be called from lambda expressions.
+type FuncDeclarationNode struct {
+	position
+	Name    *IdentifierNode
+	Params  []*IdentifierNode
+	Body    Node
+	Comment *CommentNode
+}
+
+func newFuncDecl(p position, name *IdentifierNode, params []*IdentifierNode, body Node, c *CommentNode) *FuncDeclarationNode {
+	return &FuncDeclarationNode{
+		position: p,
+		Name:     name,
+		Params:   params,
+		Body:     body,
+		Comment:  c,
+	}
+}
+
+func (n *FuncDeclarationNode) String() string {
+	return fmt.Sprintf("FuncDeclarationNode@%v{%v %v %v}%v", n.position, n.Name, n.Params, n.Body, n.Comment)
+}
+
+func (n *FuncDeclarationNode) Format(buf *bytes.Buffer, indent string, onNewLine bool) {
+	if n.Comment != nil {
+		n.Comment.Format(buf, indent, onNewLine)
+	}
+	buf.WriteString(KW_Func)
+	buf.WriteByte(' ')
+	n.Name.Format(buf, indent, false)
+	buf.WriteByte('(')
+	for i, param := range n.Params {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		param.Format(buf, indent, false)
+	}
+	buf.WriteByte(')')
+	buf.WriteByte(' ')
+	buf.WriteString(TokenAsgn.String())
+	buf.WriteByte(' ')
+	n.Body.Format(buf, indent, false)
+}
+
+func (n *FuncDeclarationNode) SetComment(c *CommentNode) {
+	n.Comment = c
+}
+func (n *FuncDeclarationNode) Equal(o interface{}) bool {
+	if on, ok := o.(*FuncDeclarationNode); ok {
+		if !n.Name.Equal(on.Name) || len(n.Params) != len(on.Params) {
+			return false
+		}
+		for i := range n.Params {
+			if !n.Params[i].Equal(on.Params[i]) {
+				return false
+			}
+		}
+		return n.Body.Equal(on.Body)
+	}
+	return false
+}
+
 type ChainNode struct {
 	position
 	Left     Node
@@ -859,6 +1002,10 @@ type FunctionNode struct {
 	Args      []Node
 	Comment   *CommentNode
 	MultiLine bool
+
+	// Definition is the declaration of a user defined function.
+	// It is set when the TICKscript is evaluated and is nil for builtin functions.
+	Definition *FuncDeclarationNode
 }
 
 func newFunc(p position, ft FuncType, ident string, args []Node, multi bool, c *CommentNode) *FunctionNode {
@@ -991,7 +1138,7 @@ func (n *ProgramNode) String() string {
 
 func (n *ProgramNode) Format(buf *bytes.Buffer, indent string, onNewLine bool) {
 	for i, node := range n.Nodes {
-		if i != 0 {
+		if i != 0 && !isStatementGroup(n.Nodes[i-1], node) {
 			buf.WriteByte('\n')
 		}
 		node.Format(buf, indent, true)
@@ -999,6 +1146,20 @@ func (n *ProgramNode) Format(buf *bytes.Buffer, indent string, onNewLine bool) {
 	}
 }
 
+// isStatementGroup reports whether both nodes are dbrp or both are import statements,
+// consecutive statements of a group are formatted without a blank line between them.
+func isStatementGroup(a, b Node) bool {
+	switch a.(type) {
+	case *DBRPNode:
+		_, ok := b.(*DBRPNode)
+		return ok
+	case *ImportNode:
+		_, ok := b.(*ImportNode)
+		return ok
+	}
+	return false
+}
+
 func (n *ProgramNode) Equal(o interface{}) bool {
 	if on, ok := o.(*ProgramNode); ok {
 		if len(n.Nodes) != len(on.Nodes) {
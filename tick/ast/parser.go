@@ -237,11 +237,55 @@ func (p *parser) statement() Node {
 	switch t := p.peek().typ; t {
 	case TokenVar:
 		return p.declaration()
+	case TokenDBRP:
+		return p.dbrp()
+	case TokenImport:
+		return p.importStatement()
+	case TokenFunc:
+		return p.funcDeclaration()
 	default:
 		return p.expression()
 	}
 }
 
+//parse a dbrp statement
+func (p *parser) dbrp() Node {
+	dbrpTok := p.expect(TokenDBRP)
+	dbrpC := p.consumeComment()
+	db := p.reference().(*ReferenceNode)
+	p.expect(TokenDot)
+	rp := p.reference().(*ReferenceNode)
+	return newDBRP(p.position(dbrpTok.pos), db, rp, dbrpC)
+}
+
+//parse an import statement
+func (p *parser) importStatement() Node {
+	importTok := p.expect(TokenImport)
+	importC := p.consumeComment()
+	path := p.string().(*StringNode)
+	return newImport(p.position(importTok.pos), path, importC)
+}
+
+//parse a function declaration statement
+func (p *parser) funcDeclaration() Node {
+	funcTok := p.expect(TokenFunc)
+	declC := p.consumeComment()
+	name := p.identifier()
+	p.expect(TokenLParen)
+	var params []*IdentifierNode
+	for p.peek().typ != TokenRParen {
+		params = append(params, p.identifier())
+		if p.next().typ != TokenComma {
+			p.backup()
+			break
+		}
+	}
+	p.expect(TokenRParen)
+	p.expect(TokenAsgn)
+	body := p.primaryExpr()
+	return newFuncDecl(p.position(funcTok.pos), name, params, body, declC)
+}
+
 //parse a declaration statement
 func (p *parser) declaration() Node {
 	varTok := p.expect(TokenVar)
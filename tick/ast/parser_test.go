@@ -127,6 +127,131 @@ func TestParseStatements(t *testing.T) {
 		Root   Node
 		err    error
 	}{
+		{
+			script: `dbrp "telegraf"."autogen"`,
+			Root: &ProgramNode{
+				position: position{
+					pos:  0,
+					line: 1,
+					char: 1,
+				},
+				Nodes: []Node{
+					&DBRPNode{
+						position: position{
+							pos:  0,
+							line: 1,
+							char: 1,
+						},
+						DB: &ReferenceNode{
+							position: position{
+								pos:  5,
+								line: 1,
+								char: 6,
+							},
+							Reference: "telegraf",
+						},
+						RP: &ReferenceNode{
+							position: position{
+								pos:  16,
+								line: 1,
+								char: 17,
+							},
+							Reference: "autogen",
+						},
+					},
+				},
+			},
+		},
+		{
+			script: `import 'shared/alerting.tick'`,
+			Root: &ProgramNode{
+				position: position{
+					pos:  0,
+					line: 1,
+					char: 1,
+				},
+				Nodes: []Node{
+					&ImportNode{
+						position: position{
+							pos:  0,
+							line: 1,
+							char: 1,
+						},
+						Path: &StringNode{
+							position: position{
+								pos:  7,
+								line: 1,
+								char: 8,
+							},
+							Literal: "shared/alerting.tick",
+						},
+					},
+				},
+			},
+		},
+		{
+			script: `func double(x) = x * 2`,
+			Root: &ProgramNode{
+				position: position{
+					pos:  0,
+					line: 1,
+					char: 1,
+				},
+				Nodes: []Node{
+					&FuncDeclarationNode{
+						position: position{
+							pos:  0,
+							line: 1,
+							char: 1,
+						},
+						Name: &IdentifierNode{
+							position: position{
+								pos:  5,
+								line: 1,
+								char: 6,
+							},
+							Ident: "double",
+						},
+						Params: []*IdentifierNode{
+							{
+								position: position{
+									pos:  12,
+									line: 1,
+									char: 13,
+								},
+								Ident: "x",
+							},
+						},
+						Body: &BinaryNode{
+							position: position{
+								pos:  19,
+								line: 1,
+								char: 20,
+							},
+							Operator: TokenMult,
+							Left: &IdentifierNode{
+								position: position{
+									pos:  17,
+									line: 1,
+									char: 18,
+								},
+								Ident: "x",
+							},
+							Right: &NumberNode{
+								position: position{
+									pos:  21,
+									line: 1,
+									char: 22,
+								},
+								IsInt: true,
+								Int64: 2,
+								Base:  10,
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			script: `var x int`,
 			Root: &ProgramNode{
@@ -22,6 +22,7 @@ const (
 	TList
 	TStar
 	TMissing
+	TMap
 )
 
 type Missing struct{}
@@ -52,6 +53,8 @@ func (v ValueType) String() string {
 		return "star"
 	case TMissing:
 		return "missing"
+	case TMap:
+		return "map"
 	}
 
 	return "invalid type"
@@ -81,6 +84,8 @@ func TypeOf(v interface{}) ValueType {
 		return TStar
 	case *Missing:
 		return TMissing
+	case map[string]interface{}:
+		return TMap
 	default:
 		return InvalidType
 	}
@@ -110,6 +115,8 @@ func ZeroValue(t ValueType) interface{} {
 		return (*StarNode)(nil)
 	case TMissing:
 		return (*Missing)(nil)
+	case TMap:
+		return map[string]interface{}(nil)
 	default:
 		return errors.New("invalid type")
 	}
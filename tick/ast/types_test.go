@@ -23,6 +23,7 @@ func Test_TypeOf(t *testing.T) {
 		{value: time.Duration(5), valueType: ast.TDuration},
 		{value: time.Time{}, valueType: ast.TTime},
 		{value: ast.MissingValue, valueType: ast.TMissing},
+		{value: map[string]interface{}{"a": 1.0}, valueType: ast.TMap},
 		{value: t, valueType: ast.InvalidType},
 	}
 
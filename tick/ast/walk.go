@@ -49,6 +49,51 @@ func Walk(root Node, f func(n Node) (Node, error)) (Node, error) {
 			return nil, err
 		}
 		node.Right = r
+	case *FuncDeclarationNode:
+		r, err := Walk(node.Name, f)
+		if err != nil {
+			return nil, err
+		}
+		ident, ok := r.(*IdentifierNode)
+		if !ok {
+			return nil, errors.New("function declaration node must always have an IdentifierNode name")
+		}
+		node.Name = ident
+		for i := range node.Params {
+			r, err := Walk(node.Params[i], f)
+			if err != nil {
+				return nil, err
+			}
+			ident, ok := r.(*IdentifierNode)
+			if !ok {
+				return nil, errors.New("function declaration node must always have IdentifierNode parameters")
+			}
+			node.Params[i] = ident
+		}
+		r, err = Walk(node.Body, f)
+		if err != nil {
+			return nil, err
+		}
+		node.Body = r
+	case *ChainNode:
+		r, err := Walk(node.Left, f)
+		if err != nil {
+			return nil, err
+		}
+		node.Left = r
+		r, err = Walk(node.Right, f)
+		if err != nil {
+			return nil, err
+		}
+		node.Right = r
+	case *ListNode:
+		for i := range node.Nodes {
+			r, err := Walk(node.Nodes[i], f)
+			if err != nil {
+				return nil, err
+			}
+			node.Nodes[i] = r
+		}
 	case *FunctionNode:
 		for i := range node.Args {
 			r, err := Walk(node.Args[i], f)
@@ -135,3 +135,41 @@ func TestWalk_Mutate(t *testing.T) {
 		t.Errorf("unexpected lambda str: got %s exp %s", str, expStr)
 	}
 }
+
+func TestWalk_Chain(t *testing.T) {
+	root, err := ast.Parse(`stream|from().groupBy('host', 'dc')|eval(lambda: "value" * 2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expList := []string{
+		"*ast.ProgramNode",
+		"*ast.ChainNode",
+		"*ast.ChainNode",
+		"*ast.ChainNode",
+		"*ast.IdentifierNode",
+		"*ast.FunctionNode",
+		"*ast.FunctionNode",
+		"*ast.StringNode",
+		"*ast.StringNode",
+		"*ast.FunctionNode",
+		"*ast.LambdaNode",
+		"*ast.BinaryNode",
+		"*ast.ReferenceNode",
+		"*ast.NumberNode",
+	}
+
+	list := make([]string, 0, len(expList))
+	f := func(n ast.Node) (ast.Node, error) {
+		list = append(list, reflect.TypeOf(n).String())
+		return n, nil
+	}
+
+	if _, err := ast.Walk(root, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expList, list) {
+		t.Errorf("unexpected walk list:\ngot\n%v\nexp\n%v\n", list, expList)
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick"
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+var keywords = []string{
+	"AND",
+	"FALSE",
+	"OR",
+	"TRUE",
+	"batch",
+	"dbrp",
+	"func",
+	"import",
+	"lambda",
+	"stream",
+	"var",
+}
+
+var varDeclaration = regexp.MustCompile(`(?m)^\s*var\s+(\w+)`)
+var funcDeclaration = regexp.MustCompile(`(?m)^\s*func\s+(\w+)`)
+
+var nodeType = reflect.TypeOf((*pipeline.Node)(nil)).Elem()
+
+// context describes the word at an offset in a TICKscript.
+type context struct {
+	// The word up to the offset.
+	prefix string
+	// The whole word.
+	word string
+	// The operator before the word, i.e. '|', '.' or '@', or zero.
+	op byte
+	// The offset of the operator.
+	opOffset int
+}
+
+// contextAt returns the context of the word at the offset.
+func contextAt(text string, o int) context {
+	start := o
+	for start > 0 && isIdentChar(text[start-1]) {
+		start--
+	}
+	end := o
+	for end < len(text) && isIdentChar(text[end]) {
+		end++
+	}
+	c := context{
+		prefix: text[start:o],
+		word:   text[start:end],
+	}
+	i := start
+	for i > 0 && (text[i-1] == ' ' || text[i-1] == '\t' || text[i-1] == '\r' || text[i-1] == '\n') {
+		i--
+	}
+	if i > 0 {
+		switch text[i-1] {
+		case '|', '.', '@':
+			c.op = text[i-1]
+			c.opOffset = i - 1
+		}
+	}
+	return c
+}
+
+// receiverType returns the type of the value the operator at the offset is applied to.
+// The script up to the operator is parsed and the type of its last statement inferred.
+func receiverType(text string, opOffset int) reflect.Type {
+	root, err := ast.Parse(text[:opOffset])
+	if err != nil {
+		return nil
+	}
+	program, ok := root.(*ast.ProgramNode)
+	if !ok || len(program.Nodes) == 0 {
+		return nil
+	}
+	vars := make(map[string]reflect.Type)
+	for _, n := range program.Nodes[:len(program.Nodes)-1] {
+		if decl, ok := n.(*ast.DeclarationNode); ok {
+			if t := inferType(decl.Right, vars); t != nil {
+				vars[decl.Left.Ident] = t
+			}
+		}
+	}
+	last := program.Nodes[len(program.Nodes)-1]
+	if decl, ok := last.(*ast.DeclarationNode); ok {
+		last = decl.Right
+	}
+	return inferType(last, vars)
+}
+
+// inferType returns the type of the value of a chain expression.
+func inferType(n ast.Node, vars map[string]reflect.Type) reflect.Type {
+	switch n := n.(type) {
+	case *ast.IdentifierNode:
+		switch n.Ident {
+		case "stream":
+			return reflect.TypeOf((*pipeline.StreamNode)(nil))
+		case "batch":
+			return reflect.TypeOf((*pipeline.BatchNode)(nil))
+		}
+		return vars[n.Ident]
+	case *ast.ChainNode:
+		left := inferType(n.Left, vars)
+		f, ok := n.Right.(*ast.FunctionNode)
+		if left == nil || !ok {
+			return nil
+		}
+		switch f.Type {
+		case ast.ChainFunc:
+			return chainMethodType(left, f.Func)
+		case ast.PropertyFunc:
+			if m, ok := left.MethodByName(capitalize(f.Func)); ok {
+				return resultType(m.Type)
+			}
+			// Properties set directly on a field return the object itself.
+			return left
+		case ast.DynamicFunc:
+			return reflect.TypeOf((*pipeline.UDFNode)(nil))
+		}
+	}
+	return nil
+}
+
+// chainMethodType returns the type returned by the chain method of t.
+func chainMethodType(t reflect.Type, name string) reflect.Type {
+	name = capitalize(name)
+	if pd, ok := zero(t).(tick.PartialDescriber); ok {
+		if m, ok := pd.ChainMethods()[name]; ok {
+			return resultType(m.Type())
+		}
+	}
+	if m, ok := t.MethodByName(name); ok {
+		return resultType(m.Type)
+	}
+	return nil
+}
+
+func resultType(t reflect.Type) reflect.Type {
+	if t.NumOut() == 0 {
+		return nil
+	}
+	return t.Out(0)
+}
+
+// zero returns a pointer to a zero value of the struct type t points to.
+// Embedded struct pointers are allocated as well, like they are
+// for objects created while evaluating a TICKscript.
+func zero(t reflect.Type) interface{} {
+	v := reflect.New(t.Elem())
+	allocEmbedded(v.Elem())
+	return v.Interface()
+}
+
+func allocEmbedded(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.Anonymous {
+			continue
+		}
+		switch {
+		case f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct:
+			if v.Field(i).CanSet() {
+				p := reflect.New(f.Type.Elem())
+				allocEmbedded(p.Elem())
+				v.Field(i).Set(p)
+			}
+		case f.Type.Kind() == reflect.Struct:
+			allocEmbedded(v.Field(i))
+		}
+	}
+}
+
+// describer returns the describer of a zero value of the type.
+func describer(t reflect.Type) *tick.ReflectionDescriber {
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	obj := zero(t)
+	var extraChainMethods map[string]reflect.Value
+	if pd, ok := obj.(tick.PartialDescriber); ok {
+		extraChainMethods = pd.ChainMethods()
+	}
+	d, err := tick.NewReflectionDescriber(obj, extraChainMethods)
+	if err != nil {
+		return nil
+	}
+	return d
+}
+
+// memberType returns the name of the documented type that declares the member of t.
+// Chain methods that a node hides behind a property of the same name
+// are documented on the chainnode.
+func memberType(t reflect.Type, op byte, name string) string {
+	if op == '|' {
+		if pd, ok := zero(t).(tick.PartialDescriber); ok {
+			if _, ok := pd.ChainMethods()[capitalize(name)]; ok {
+				return "chainnode"
+			}
+		}
+	}
+	return t.Elem().Name()
+}
+
+// complete returns the completion items for the word at the offset.
+func (s *Server) complete(text string, o int) []CompletionItem {
+	c := contextAt(text, o)
+	items := []CompletionItem{}
+	switch c.op {
+	case '|', '.':
+		t := receiverType(text, c.opOffset)
+		d := describer(t)
+		if d == nil {
+			return items
+		}
+		if c.op == '|' {
+			for _, name := range d.ChainMethodNames() {
+				if !strings.HasPrefix(name, c.prefix) {
+					continue
+				}
+				if rt := chainMethodType(t, name); rt == nil || !rt.Implements(nodeType) {
+					continue
+				}
+				m := s.docs.Member(memberType(t, c.op, name), capitalize(name))
+				if m != nil && m.Ignore {
+					continue
+				}
+				items = append(items, CompletionItem{
+					Label:  name,
+					Kind:   completionMethod,
+					Detail: signature(c.op, name, m),
+				})
+			}
+		} else {
+			for _, name := range d.PropertyNames() {
+				if !strings.HasPrefix(name, c.prefix) {
+					continue
+				}
+				m := s.docs.Member(memberType(t, c.op, name), capitalize(name))
+				if m != nil && m.Ignore {
+					continue
+				}
+				items = append(items, CompletionItem{
+					Label:  name,
+					Kind:   completionProperty,
+					Detail: signature(c.op, name, m),
+				})
+			}
+		}
+	case '@':
+		for _, name := range declared(varDeclaration, text) {
+			if strings.HasPrefix(name, c.prefix) {
+				items = append(items, CompletionItem{
+					Label: name,
+					Kind:  completionVariable,
+				})
+			}
+		}
+	default:
+		for _, name := range declared(varDeclaration, text) {
+			if strings.HasPrefix(name, c.prefix) && name != c.word {
+				items = append(items, CompletionItem{
+					Label: name,
+					Kind:  completionVariable,
+				})
+			}
+		}
+		functions := declared(funcDeclaration, text)
+		builtins := stateful.NewFunctions()
+		for name := range builtins {
+			functions = append(functions, name)
+		}
+		sort.Strings(functions)
+		for _, name := range functions {
+			if strings.HasPrefix(name, c.prefix) && name != c.word {
+				item := CompletionItem{
+					Label: name,
+					Kind:  completionFunction,
+				}
+				if f, ok := builtins[name]; ok {
+					item.Detail = funcSignature(name, f)
+				}
+				items = append(items, item)
+			}
+		}
+		for _, kw := range keywords {
+			if strings.HasPrefix(kw, c.prefix) && kw != c.word {
+				items = append(items, CompletionItem{
+					Label: kw,
+					Kind:  completionKeyword,
+				})
+			}
+		}
+	}
+	return items
+}
+
+// hover returns the documentation of the word at the offset, or nil if there is none.
+func (s *Server) hover(text string, o int) *Hover {
+	c := contextAt(text, o)
+	if c.word == "" {
+		return nil
+	}
+	var value string
+	switch c.op {
+	case '|', '.':
+		t := receiverType(text, c.opOffset)
+		d := describer(t)
+		if d == nil {
+			return nil
+		}
+		if c.op == '|' && !d.HasChainMethod(c.word) || c.op == '.' && !d.HasProperty(c.word) {
+			return nil
+		}
+		m := s.docs.Member(memberType(t, c.op, c.word), capitalize(c.word))
+		value = codeBlock(signature(c.op, c.word, m))
+		if m != nil {
+			if m.Doc != "" {
+				value += "\n\n" + markdown(m.Doc)
+			}
+			if c.op == '|' && m.Result != "" {
+				value += "\n\nReturns: " + m.Result
+			}
+		}
+	default:
+		switch c.word {
+		case "stream", "batch":
+			t := "StreamNode"
+			if c.word == "batch" {
+				t = "BatchNode"
+			}
+			value = codeBlock(c.word)
+			if td := s.docs.Type(t); td != nil && td.Doc != "" {
+				value += "\n\n" + markdown(td.Doc)
+			}
+		default:
+			f, ok := stateful.NewFunctions()[c.word]
+			if !ok {
+				return nil
+			}
+			value = codeBlock(funcSignature(c.word, f))
+		}
+	}
+	start := o - len(c.prefix)
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: value,
+		},
+		Range: &Range{
+			Start: position(text, start),
+			End:   position(text, start+len(c.word)),
+		},
+	}
+}
+
+// signature returns the signature of a chain method or property as it is called in a TICKscript.
+func signature(op byte, name string, m *memberDoc) string {
+	var params []string
+	if m != nil {
+		for _, p := range m.Params {
+			params = append(params, p.Text())
+		}
+	}
+	return fmt.Sprintf("node%c%s(%s)", op, name, strings.Join(params, ", "))
+}
+
+// funcSignature returns the signatures of a builtin function, one for each domain.
+func funcSignature(name string, f stateful.Func) string {
+	var signatures []string
+	for d, ret := range f.Signature() {
+		if ret == ast.InvalidType {
+			// The return type depends on the values of the args.
+			signatures = append(signatures, fmt.Sprintf("%s%v", name, d))
+			continue
+		}
+		signatures = append(signatures, fmt.Sprintf("%s%v %v", name, d, ret))
+	}
+	sort.Strings(signatures)
+	return strings.Join(signatures, "\n")
+}
+
+func codeBlock(code string) string {
+	return "```" + tickLang + "\n" + code + "\n```"
+}
+
+// declared returns the sorted names declared in the text matching the declaration regex.
+func declared(decl *regexp.Regexp, text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range decl.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// capitalize returns the Go name of the TICKscript name of a method or property.
+func capitalize(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
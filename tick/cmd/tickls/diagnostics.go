@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/kapacitor/lint"
+	"github.com/influxdata/kapacitor/pipeline"
+	"github.com/influxdata/kapacitor/tick/ast"
+	"github.com/influxdata/kapacitor/tick/stateful"
+)
+
+var errorPosition = regexp.MustCompile(`line (\d+) char (\d+)`)
+var errorPrefix = regexp.MustCompile(`^line \d+ char \d+: `)
+
+// diagnose parses and evaluates the TICKscript and lints the resulting pipeline.
+// Modules imported by the script are resolved relative to the directory of the document.
+func diagnose(uri, text string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	root, err := ast.Parse(text)
+	if err != nil {
+		return append(diagnostics, errorDiagnostic(text, err))
+	}
+	tp, err := pipeline.CreateTemplatePipeline(
+		text,
+		sourceEdge(root),
+		newScope(),
+		deadman{},
+		fileImporter{dir: documentDir(uri)},
+	)
+	if err != nil {
+		return append(diagnostics, errorDiagnostic(text, err))
+	}
+	problems, err := lint.Lint(text, tp.Pipeline(), nil)
+	if err != nil {
+		return append(diagnostics, errorDiagnostic(text, err))
+	}
+	for _, p := range problems {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    wordRange(text, p.Line, p.Char),
+			Severity: severityWarning,
+			Source:   "tickls",
+			Message:  p.Message,
+		})
+	}
+	return diagnostics
+}
+
+// errorDiagnostic creates a diagnostic from the position reported in the error message.
+// Errors without a position are reported at the start of the document.
+func errorDiagnostic(text string, err error) Diagnostic {
+	msg := err.Error()
+	line, char := 0, 0
+	if m := errorPosition.FindStringSubmatch(msg); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		char, _ = strconv.Atoi(m[2])
+	}
+	return Diagnostic{
+		Range:    wordRange(text, line, char),
+		Severity: severityError,
+		Source:   "tickls",
+		Message:  errorPrefix.ReplaceAllString(msg, ""),
+	}
+}
+
+// wordRange returns the range of the word at the one based line and char.
+func wordRange(text string, line, char int) Range {
+	if line == 0 {
+		return Range{}
+	}
+	// The char of TICKscript errors is a byte offset in the line.
+	lineStart, lineEnd := lineBounds(text, line-1)
+	o := lineStart + char - 1
+	if o < lineStart {
+		o = lineStart
+	} else if o > lineEnd {
+		o = lineEnd
+	}
+	start := position(text, o)
+	end := o
+	for end < len(text) && isIdentChar(text[end]) {
+		end++
+	}
+	return Range{
+		Start: start,
+		End:   position(text, end),
+	}
+}
+
+// sourceEdge returns the edge of the source node of the script.
+func sourceEdge(root ast.Node) pipeline.EdgeType {
+	var edge = pipeline.StreamEdge
+	ast.Walk(root, func(n ast.Node) (ast.Node, error) {
+		if ident, ok := n.(*ast.IdentifierNode); ok && ident.Ident == "batch" {
+			edge = pipeline.BatchEdge
+		}
+		return n, nil
+	})
+	return edge
+}
+
+// newScope returns a scope with the global functions available to a task.
+func newScope() *stateful.Scope {
+	scope := stateful.NewScope()
+	scope.Set("time", func(length time.Duration, offset ...time.Duration) time.Duration {
+		return length
+	})
+	return scope
+}
+
+// deadman provides the default deadman settings, which do not affect the diagnostics.
+type deadman struct{}
+
+func (deadman) Interval() time.Duration { return 0 }
+func (deadman) Threshold() float64      { return 0 }
+func (deadman) Id() string              { return "" }
+func (deadman) Message() string         { return "" }
+func (deadman) Global() bool            { return false }
+
+// fileImporter reads imported modules from the files next to the document.
+type fileImporter struct {
+	dir string
+}
+
+func (i fileImporter) Import(path string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(i.dir, filepath.FromSlash(path)+".tick"))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func documentDir(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "."
+	}
+	return filepath.Dir(filepath.FromSlash(u.Path))
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+)
+
+//go:generate go run gen_pipeline_source.go
+
+// The special comments understood by tickdoc, see tick/cmd/tickdoc.
+const tickIgnore = "tick:ignore"
+const tickProperty = "tick:property"
+const tickEmbedded = "tick:embedded:"
+const tickLang = "javascript"
+
+// docs is the documentation of the pipeline nodes,
+// read from the comments in the source of the pipeline package.
+type docs struct {
+	types map[string]*typeDoc
+}
+
+type typeDoc struct {
+	Name       string
+	Doc        string
+	AnonFields []string
+	Members    map[string]*memberDoc
+}
+
+// memberDoc documents a chain method or property of a node.
+type memberDoc struct {
+	Name     string
+	Doc      string
+	Params   []param
+	Result   string
+	Property bool
+	Ignore   bool
+}
+
+type param struct {
+	Name string
+	Type string
+}
+
+func (p param) Text() string {
+	return fmt.Sprintf("%s %s", p.Name, p.Type)
+}
+
+// loadDocs parses the Go source files of the pipeline package in dir.
+func loadDocs(dir string) (*docs, error) {
+	fset := token.NewFileSet()
+	skipTest := func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}
+	pkgs, err := parser.ParseDir(fset, dir, skipTest, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	d := newDocs()
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			d.handleFile(f)
+		}
+	}
+	return d, nil
+}
+
+// loadGeneratedDocs parses the source of the pipeline package generated into the tickls binary,
+// see gen_pipeline_source.go.
+func loadGeneratedDocs() (*docs, error) {
+	fset := token.NewFileSet()
+	d := newDocs()
+	for name, src := range pipelineSource {
+		f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		d.handleFile(f)
+	}
+	return d, nil
+}
+
+func newDocs() *docs {
+	return &docs{
+		types: make(map[string]*typeDoc),
+	}
+}
+
+func (d *docs) handleFile(f *ast.File) {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			d.handleGenDecl(decl)
+		case *ast.FuncDecl:
+			d.handleFuncDecl(decl)
+		}
+	}
+}
+
+func (d *docs) typ(name string) *typeDoc {
+	t := d.types[name]
+	if t == nil {
+		t = &typeDoc{
+			Name:    name,
+			Members: make(map[string]*memberDoc),
+		}
+		d.types[name] = t
+	}
+	return t
+}
+
+func (d *docs) handleGenDecl(decl *ast.GenDecl) {
+	if decl.Tok != token.TYPE {
+		return
+	}
+	for _, spec := range decl.Specs {
+		ts := spec.(*ast.TypeSpec)
+		s, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		cg := ts.Doc
+		if cg == nil && len(decl.Specs) == 1 {
+			cg = decl.Doc
+		}
+		t := d.typ(ts.Name.Name)
+		t.Doc = docText(cg)
+		for _, field := range s.Fields.List {
+			if field.Names == nil {
+				if name := typeName(field.Type); name != "" {
+					t.AnonFields = append(t.AnonFields, name)
+				}
+				continue
+			}
+			for _, name := range field.Names {
+				if !ast.IsExported(name.Name) {
+					continue
+				}
+				t.Members[name.Name] = &memberDoc{
+					Name:     name.Name,
+					Doc:      docText(field.Doc),
+					Params:   []param{{"value", typeExpr(field.Type)}},
+					Property: true,
+					Ignore:   hasComment(field.Doc, tickIgnore),
+				}
+			}
+		}
+	}
+}
+
+func (d *docs) handleFuncDecl(decl *ast.FuncDecl) {
+	if decl.Recv == nil || !ast.IsExported(decl.Name.Name) {
+		return
+	}
+	recv := typeName(decl.Recv.List[0].Type)
+	if recv == "" {
+		return
+	}
+	m := &memberDoc{
+		Name:     decl.Name.Name,
+		Doc:      docText(decl.Doc),
+		Property: hasComment(decl.Doc, tickProperty),
+		Ignore:   hasComment(decl.Doc, tickIgnore),
+	}
+	for _, p := range decl.Type.Params.List {
+		ptype := typeExpr(p.Type)
+		for _, name := range p.Names {
+			m.Params = append(m.Params, param{name.Name, ptype})
+		}
+	}
+	if decl.Type.Results != nil && len(decl.Type.Results.List) > 0 {
+		m.Result = typeExpr(decl.Type.Results.List[0].Type)
+	}
+	d.typ(recv).Members[decl.Name.Name] = m
+}
+
+// Type returns the documentation of the named type.
+func (d *docs) Type(name string) *typeDoc {
+	if d == nil {
+		return nil
+	}
+	return d.types[name]
+}
+
+// Member returns the documentation of the member of the named type.
+// Members of anonymous fields are found recursively.
+func (d *docs) Member(typ, name string) *memberDoc {
+	if d == nil {
+		return nil
+	}
+	t := d.types[typ]
+	if t == nil {
+		return nil
+	}
+	if m, ok := t.Members[name]; ok {
+		return m
+	}
+	for _, anon := range t.AnonFields {
+		if m := d.Member(anon, name); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// docText returns the text of the comment without the special tickdoc comments.
+func docText(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	for _, line := range strings.Split(cg.Text(), "\n") {
+		s := strings.TrimSpace(line)
+		if s == tickIgnore || s == tickProperty || strings.HasPrefix(s, tickEmbedded) {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// markdown renders the documentation as markdown.
+// Indented blocks of lines, i.e. examples, are rendered as TICKscript code blocks.
+func markdown(doc string) string {
+	lines := strings.Split(doc, "\n")
+	var buf bytes.Buffer
+	for i := 0; i < len(lines); i++ {
+		if !isIndented(lines[i]) {
+			buf.WriteString(lines[i])
+			buf.WriteByte('\n')
+			continue
+		}
+		// Find the end of the block, blank lines are part of the block
+		// if they are followed by an indented line.
+		end := i
+		for j := i; j < len(lines); j++ {
+			if isIndented(lines[j]) {
+				end = j + 1
+			} else if strings.TrimSpace(lines[j]) != "" {
+				break
+			}
+		}
+		block := lines[i:end]
+		indent := len(block[0]) - len(strings.TrimLeft(block[0], " \t"))
+		for _, l := range block {
+			if isIndented(l) {
+				if n := len(l) - len(strings.TrimLeft(l, " \t")); n < indent {
+					indent = n
+				}
+			}
+		}
+		buf.WriteString("```" + tickLang + "\n")
+		for _, l := range block {
+			if len(l) >= indent {
+				l = l[indent:]
+			}
+			buf.WriteString(strings.TrimRight(l, " \t"))
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("```\n")
+		i = end - 1
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+func isIndented(line string) bool {
+	return strings.TrimSpace(line) != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+func hasComment(cg *ast.CommentGroup, comment string) bool {
+	if cg == nil {
+		return false
+	}
+	for _, l := range cg.List {
+		if strings.TrimSpace(strings.TrimLeft(l.Text, "/")) == comment {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(n ast.Expr) string {
+	switch t := n.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return typeName(t.X)
+	}
+	return ""
+}
+
+func typeExpr(n ast.Expr) string {
+	switch t := n.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return typeExpr(t.X)
+	case *ast.Ellipsis:
+		return "..." + typeExpr(t.Elt)
+	case *ast.SelectorExpr:
+		return typeExpr(t.X) + "." + t.Sel.Name
+	case *ast.MapType:
+		return fmt.Sprintf("map[%s]%s", typeExpr(t.Key), typeExpr(t.Value))
+	case *ast.InterfaceType:
+		return "interface{}"
+	case *ast.ArrayType:
+		return "[]" + typeExpr(t.Elt)
+	case *ast.FuncType:
+		return "func"
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+// +build ignore
+
+// Generates pipeline_source.gen.go, the source of the pipeline package
+// from which tickls reads the documentation of the nodes.
+//
+// Run with go generate from the directory of tickls.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+const pipelineDir = "../../../pipeline"
+const output = "pipeline_source.gen.go"
+
+func main() {
+	files, err := filepath.Glob(filepath.Join(pipelineDir, "*.go"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("// Generated by gen_pipeline_source.go\n//\n// DO NOT EDIT!\n\n")
+	buf.WriteString("package main\n\n")
+	buf.WriteString("// pipelineSource maps the names of the Go files of the pipeline package to their source.\n")
+	buf.WriteString("var pipelineSource = map[string]string{\n")
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(&buf, "%q: %q,\n", filepath.Base(file), src)
+	}
+	buf.WriteString("}\n")
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(output, out, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+// Tickls is a Language Server Protocol server for TICKscripts.
+//
+// It communicates with the editor over STDIN and STDOUT and provides
+// diagnostics, completion, hover documentation and formatting.
+//
+// The documentation shown by tickls is read from the comments in the source of the pipeline package,
+// the same comments used by tickdoc to generate the node reference.
+// The source is generated into the binary, run go generate after changing the pipeline package.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+var pipelineFlag = flag.String("pipeline", "", "path to the source of the pipeline package used for documentation. Defaults to the source generated into tickls.")
+var logFlag = flag.String("log", "", "path to a log file, logs are discarded by default.")
+
+func usage() {
+	message := `Usage: %s [options]
+
+    Serves the Language Server Protocol over STDIN and STDOUT.
+
+Options:
+`
+	fmt.Fprintf(os.Stderr, message, os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	logger := log.New(ioutil.Discard, "", 0)
+	if *logFlag != "" {
+		f, err := os.OpenFile(*logFlag, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		logger = log.New(f, "[tickls] ", log.LstdFlags)
+	}
+
+	var d *docs
+	var err error
+	if *pipelineFlag != "" {
+		d, err = loadDocs(*pipelineFlag)
+	} else {
+		d, err = loadGeneratedDocs()
+	}
+	if err != nil {
+		logger.Println("W! no documentation available:", err)
+	}
+
+	s := NewServer(os.Stdin, os.Stdout, d, logger)
+	if err := s.Serve(); err != nil {
+		logger.Println("E!", err)
+		os.Exit(1)
+	}
+	if !s.shutdown {
+		// The editor exited without asking the server to shutdown.
+		os.Exit(1)
+	}
+}
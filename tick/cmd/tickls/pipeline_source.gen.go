@@ -0,0 +1,46 @@
+// Generated by gen_pipeline_source.go
+//
+// DO NOT EDIT!
+
+package main
+
+// pipelineSource maps the names of the Go files of the pipeline package to their source.
+var pipelineSource = map[string]string{
+	"alert.go":              "package pipeline\n\nimport (\n\t\"fmt\"\n\t\"reflect\"\n\t\"strings\"\n\t\"time\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n\t\"github.com/pkg/errors\"\n)\n\n// Number of previous states to remember when computing flapping percentage.\nconst defaultFlapHistory = 21\n\n// Default template for constructing an ID\nconst defaultIDTmpl = \"{{ .Name }}:{{ .Group }}\"\n\n// Default template for constructing a message.\nconst defaultMessageTmpl = \"{{ .ID }} is {{ .Level }}\"\n\n// Default template for constructing a details message.\nconst defaultDetailsTmpl = \"{{ json . }}\"\n\n// An AlertNode can trigger an event of varying severity levels,\n// and pass the event to alert handlers. The criteria for triggering\n// an alert is specified via a [lambda expression](/kapacitor/latest/tick/expr/).\n// See AlertNode.Info, AlertNode.Warn, and AlertNode.Crit below.\n//\n// Different event handlers can be configured for each AlertNode.\n// Some handlers like Email, HipChat, Sensu, Slack, OpsGenie, VictorOps, PagerDuty, Telegram and Talk have a configuration\n// option 'global' that indicates that all alerts implicitly use the handler.\n//\n// Available event handlers:\n//\n//    * log -- log alert data to file.\n//    * post -- HTTP POST data to a specified URL.\n//    * tcp -- Send data to a specified address via raw TCP.\n//    * email -- Send and email with alert data.\n//    * exec -- Execute a command passing alert data over STDIN.\n//    * HipChat -- Post alert message to HipChat room.\n//    * Alerta -- Post alert message to Alerta.\n//    * Sensu -- Post alert message to Sensu client.\n//    * Slack -- Post alert message to Slack channel.\n//    * SNMPTraps -- Trigger SNMP traps.\n//    * OpsGenie -- Send alert to OpsGenie.\n//    * VictorOps -- Send alert to VictorOps.\n//    * PagerDuty -- Send alert to PagerDuty.\n//    * Pushover -- Send alert to Pushover.\n//    * Talk -- Post alert message to Talk client.\n//    * Telegram -- Post alert message to Telegram client.\n//\n// See below for more details on configuring each handler.\n//\n// Each event that gets sent to a handler contains the following alert data:\n//\n//    * ID -- the ID of the alert, user defined.\n//    * Message -- the alert message, user defined.\n//    * Details -- the alert details, user defined HTML content.\n//    * Time -- the time the alert occurred.\n//    * Duration -- the duration of the alert in nanoseconds.\n//    * Level -- one of OK, INFO, WARNING or CRITICAL.\n//    * Data -- influxql.Result containing the data that triggered the alert.\n//\n// Events are sent to handlers if the alert is in a state other than 'OK'\n// or the alert just changed to the 'OK' state from a non 'OK' state (a.k.a. the alert recovered).\n// Using the AlertNode.StateChangesOnly property events will only be sent to handlers\n// if the alert changed state.\n//\n// It is valid to configure multiple alert handlers, even with the same type.\n//\n// Example:\n//   stream\n//           .groupBy('service')\n//       |alert()\n//           .id('kapacitor/{{ index .Tags \"service\" }}')\n//           .message('{{ .ID }} is {{ .Level }} value:{{ index .Fields \"value\" }}')\n//           .info(lambda: \"value\" > 10)\n//           .warn(lambda: \"value\" > 20)\n//           .crit(lambda: \"value\" > 30)\n//           .post(\"http://example.com/api/alert\")\n//           .post(\"http://another.example.com/api/alert\")\n//           .tcp(\"exampleendpoint.com:5678\")\n//           .email('oncall@example.com')\n//\n//\n// Each expression maintains its own state.\n// The order of execution for the expressions is not considered to be deterministic.\n// For each point an expression may or may not be evaluated.\n// If no expression is true then the alert is considered to be in the OK state.\n//\n// Kapacitor supports alert reset expressions.\n// This way when an alert enters a state, it can only be lowered in severity if its reset expression evaluates to true.\n//\n// Example:\n//   stream\n//       |from()\n//           .measurement('cpu')\n//           .where(lambda: \"host\" == 'serverA')\n//           .groupBy('host')\n//       |alert()\n//           .info(lambda: \"value\" > 60)\n//           .infoReset(lambda: \"value\" < 50)\n//           .warn(lambda: \"value\" > 70)\n//           .warnReset(lambda: \"value\" < 60)\n//           .crit(lambda: \"value\" > 80)\n//           .critReset(lambda: \"value\" < 70)\n//\n// For example given the following values:\n//     61 73 64 85 62 56 47\n// The corresponding alert states are:\n//     INFO WARNING WARNING CRITICAL INFO INFO OK\n//\n// Available Statistics:\n//\n//    * alerts_triggered -- Total number of alerts triggered\n//    * oks_triggered -- Number of OK alerts triggered\n//    * infos_triggered -- Number of Info alerts triggered\n//    * warns_triggered -- Number of Warn alerts triggered\n//    * crits_triggered -- Number of Crit alerts triggered\n//\ntype AlertNode struct {\n\tchainnode\n\n\t// Topic specifies the name of an alert topic to which,\n\t// alerts will be published.\n\t// Alert handlers can be configured per topic, see the API documentation.\n\tTopic string\n\n\t// Template for constructing a unique ID for a given alert.\n\t//\n\t// Available template data:\n\t//\n\t//    * Name -- Measurement name.\n\t//    * TaskName -- The name of the task\n\t//    * Group -- Concatenation of all group-by tags of the form [key=value,]+.\n\t//        If no groupBy is performed equal to literal 'nil'.\n\t//    * Tags -- Map of tags. Use '{{ index .Tags \"key\" }}' to get a specific tag value.\n\t//    * ServerInfo -- Information about the running server. Available nested fields are:\n\t//        Hostname, ClusterID and ServerID.\n\t//\n\t// Example:\n\t//   stream\n\t//       |from()\n\t//           .measurement('cpu')\n\t//           .groupBy('cpu')\n\t//       |alert()\n\t//           .id('kapacitor/{{ .Name }}/{{ .Group }}')\n\t//\n\t// ID: kapacitor/cpu/cpu=cpu0,\n\t//\n\t// Example:\n\t//   stream\n\t//       |from()\n\t//           .measurement('cpu')\n\t//           .groupBy('service')\n\t//       |alert()\n\t//           .id('kapacitor/{{ index .Tags \"service\" }}')\n\t//\n\t// ID: kapacitor/authentication\n\t//\n\t// Example:\n\t//   stream\n\t//       |from()\n\t//           .measurement('cpu')\n\t//           .groupBy('service', 'host')\n\t//       |alert()\n\t//           .id('kapacitor/{{ index .Tags \"service\" }}/{{ index .Tags \"host\" }}')\n\t//\n\t// ID: kapacitor/authentication/auth001.example.com\n\t//\n\t// Default: {{ .Name }}:{{ .Group }}\n\tId string\n\n\t// Template for constructing a meaningful message for the alert.\n\t//\n\t// Available template data:\n\t//\n\t//    * ID -- The ID of the alert.\n\t//    * Name -- Measurement name.\n\t//    * TaskName -- The name of the task\n\t//    * Group -- Concatenation of all group-by tags of the form [key=value,]+.\n\t//        If no groupBy is performed equal to literal 'nil'.\n\t//    * Tags -- Map of tags. Use '{{ index .Tags \"key\" }}' to get a specific tag value.\n\t//    * Level -- Alert Level, one of: INFO, WARNING, CRITICAL.\n\t//    * Fields -- Map of fields. Use '{{ index .Fields \"key\" }}' to get a specific field value.\n\t//    * Time -- The time of the point that triggered the event.\n\t//\n\t// Example:\n\t//   stream\n\t//       |from()\n\t//           .measurement('cpu')\n\t//           .groupBy('service', 'host')\n\t//       |alert()\n\t//           .id('{{ index .Tags \"service\" }}/{{ index .Tags \"host\" }}')\n\t//           .message('{{ .ID }} is {{ .Level}} value: {{ index .Fields \"value\" }}')\n\t//\n\t// Message: authentication/auth001.example.com is CRITICAL value:42\n\t//\n\t// Default: {{ .ID }} is {{ .Level }}\n\tMessage string\n\n\t// Template for constructing a detailed HTML message for the alert.\n\t// The same template data is available as the AlertNode.Message property,\n\t// in addition to a Message field that contains the rendered Message value.\n\t//\n\t// The intent is that the Message property be a single line summary while the\n\t// Details property is a more detailed message possibly spanning multiple lines,\n\t// and containing HTML formatting.\n\t//\n\t// This template is rendered using the html/template package in Go so that\n\t// safe and valid HTML can be generated.\n\t//\n\t// The `json` method is available within the template to convert any variable to a valid\n\t// JSON string.\n\t//\n\t// Example:\n\t//    |alert()\n\t//       .id('{{ .Name }}')\n\t//       .details('''\n\t//<h1>{{ .ID }}</h1>\n\t//<b>{{ .Message }}</b>\n\t//Value: {{ index .Fields \"value\" }}\n\t//''')\n\t//       .email()\n\t//\n\t// Default: {{ json . }}\n\tDetails string\n\n\t// Filter expression for the INFO alert level.\n\t// An empty value indicates the level is invalid and is skipped.\n\tInfo *ast.LambdaNode\n\t// Filter expression for the WARNING alert level.\n\t// An empty value indicates the level is invalid and is skipped.\n\tWarn *ast.LambdaNode\n\t// Filter expression for the CRITICAL alert level.\n\t// An empty value indicates the level is invalid and is skipped.\n\tCrit *ast.LambdaNode\n\n\t// Filter expression for reseting the INFO alert level to lower level.\n\tInfoReset *ast.LambdaNode\n\t// Filter expression for reseting the WARNING alert level to lower level.\n\tWarnReset *ast.LambdaNode\n\t// Filter expression for reseting the CRITICAL alert level to lower level.\n\tCritReset *ast.LambdaNode\n\n\t//tick:ignore\n\tUseFlapping bool `tick:\"Flapping\"`\n\t//tick:ignore\n\tFlapLow float64\n\t//tick:ignore\n\tFlapHigh float64\n\n\t// Number of previous states to remember when computing flapping levels and\n\t// checking for state changes.\n\t// Minimum value is 2 in order to keep track of current and previous states.\n\t//\n\t// Default: 21\n\tHistory int64\n\n\t// Optional tag key to use when tagging the data with the alert level.\n\tLevelTag string\n\t// Optional field key to add to the data, containing the alert level as a string.\n\tLevelField string\n\n\t// Optional field key to add to the data, containing the alert message.\n\tMessageField string\n\n\t// Optional field key to add the alert duration to the data.\n\t// The duration is always in units of nanoseconds.\n\tDurationField string\n\n\t// Optional tag key to use when tagging the data with the alert ID.\n\tIdTag string\n\t// Optional field key to add to the data, containing the alert ID as a string.\n\tIdField string\n\n\t// Indicates an alert should trigger only if all points in a batch match the criteria\n\t// tick:ignore\n\tAllFlag bool `tick:\"All\"`\n\n\t// Do not send recovery events.\n\t// tick:ignore\n\tNoRecoveriesFlag bool `tick:\"NoRecoveries\"`\n\n\t// Send alerts only on state changes.\n\t// tick:ignore\n\tIsStateChangesOnly bool `tick:\"StateChangesOnly\"`\n\n\t// Maximum interval to ignore non state changed events\n\t// tick:ignore\n\tStateChangesOnlyDuration time.Duration\n\n\t// Post the JSON alert data to the specified URL.\n\t// tick:ignore\n\tHTTPPostHandlers []*AlertHTTPPostHandler `tick:\"Post\"`\n\n\t// Send the JSON alert data to the specified endpoint via TCP.\n\t// tick:ignore\n\tTcpHandlers []*TcpHandler `tick:\"Tcp\"`\n\n\t// Email handlers\n\t// tick:ignore\n\tEmailHandlers []*EmailHandler `tick:\"Email\"`\n\n\t// A commands to run when an alert triggers\n\t// tick:ignore\n\tExecHandlers []*ExecHandler `tick:\"Exec\"`\n\n\t// Log JSON alert data to file. One event per line.\n\t// tick:ignore\n\tLogHandlers []*LogHandler `tick:\"Log\"`\n\n\t// Send alert to VictorOps.\n\t// tick:ignore\n\tVictorOpsHandlers []*VictorOpsHandler `tick:\"VictorOps\"`\n\n\t// Send alert to PagerDuty.\n\t// tick:ignore\n\tPagerDutyHandlers []*PagerDutyHandler `tick:\"PagerDuty\"`\n\n\t// Send alert to Pushover.\n\t// tick:ignore\n\tPushoverHandlers []*PushoverHandler `tick:\"Pushover\"`\n\n\t// Send alert to Sensu.\n\t// tick:ignore\n\tSensuHandlers []*SensuHandler `tick:\"Sensu\"`\n\n\t// Send alert to Slack.\n\t// tick:ignore\n\tSlackHandlers []*SlackHandler `tick:\"Slack\"`\n\n\t// Send alert to Telegram.\n\t// tick:ignore\n\tTelegramHandlers []*TelegramHandler `tick:\"Telegram\"`\n\n\t// Send alert to HipChat.\n\t// tick:ignore\n\tHipChatHandlers []*HipChatHandler `tick:\"HipChat\"`\n\n\t// Send alert to Alerta.\n\t// tick:ignore\n\tAlertaHandlers []*AlertaHandler `tick:\"Alerta\"`\n\n\t// Send alert to OpsGenie\n\t// tick:ignore\n\tOpsGenieHandlers []*OpsGenieHandler `tick:\"OpsGenie\"`\n\n\t// Send alert to Talk.\n\t// tick:ignore\n\tTalkHandlers []*TalkHandler `tick:\"Talk\"`\n\n\t// Send alert using SNMPtraps.\n\t// tick:ignore\n\tSNMPTrapHandlers []*SNMPTrapHandler `tick:\"SnmpTrap\"`\n}\n\nfunc newAlertNode(wants EdgeType) *AlertNode {\n\ta := &AlertNode{\n\t\tchainnode: newBasicChainNode(\"alert\", wants, wants),\n\t\tHistory:   defaultFlapHistory,\n\t\tId:        defaultIDTmpl,\n\t\tMessage:   defaultMessageTmpl,\n\t\tDetails:   defaultDetailsTmpl,\n\t}\n\treturn a\n}\n\n//tick:ignore\nfunc (n *AlertNode) ChainMethods() map[string]reflect.Value {\n\treturn map[string]reflect.Value{\n\t\t\"Log\": reflect.ValueOf(n.chainnode.Log),\n\t}\n}\n\nfunc (n *AlertNode) validate() error {\n\tfor _, snmp := range n.SNMPTrapHandlers {\n\t\tif err := snmp.validate(); err != nil {\n\t\t\treturn errors.Wrapf(err, \"invalid SNMP trap %q\", snmp.TrapOid)\n\t\t}\n\t}\n\n\tfor _, post := range n.HTTPPostHandlers {\n\t\tif err := post.validate(); err != nil {\n\t\t\treturn errors.Wrap(err, \"invalid post\")\n\t\t}\n\t}\n\treturn nil\n}\n\n// Indicates an alert should trigger only if all points in a batch match the criteria.\n// Does not apply to stream alerts.\n// tick:property\nfunc (n *AlertNode) All() *AlertNode {\n\tn.AllFlag = true\n\treturn n\n}\n\n// Do not send recovery alerts.\n// tick:property\nfunc (n *AlertNode) NoRecoveries() *AlertNode {\n\tn.NoRecoveriesFlag = true\n\treturn n\n}\n\n// Only sends events where the state changed.\n// Each different alert level OK, INFO, WARNING, and CRITICAL\n// are considered different states.\n//\n// Example:\n//   stream\n//       |from()\n//           .measurement('cpu')\n//       |window()\n//            .period(10s)\n//            .every(10s)\n//       |alert()\n//           .crit(lambda: \"value\" > 10)\n//           .stateChangesOnly()\n//           .slack()\n//\n// If the \"value\" is greater than 10 for a total of 60s, then\n// only two events will be sent. First, when the value crosses\n// the threshold, and second, when it falls back into an OK state.\n// Without stateChangesOnly, the alert would have triggered 7 times:\n// 6 times for each 10s period where the condition was met and once more\n// for the recovery.\n//\n// An optional maximum interval duration can be provided.\n// An event will not be ignore (aka trigger an alert) if more than the maximum interval has elapsed\n// since the last alert.\n//\n// Example:\n//   stream\n//       |from()\n//           .measurement('cpu')\n//       |window()\n//            .period(10s)\n//            .every(10s)\n//       |alert()\n//           .crit(lambda: \"value\" > 10)\n//           .stateChangesOnly(10m)\n//           .slack()\n//\n// The above usage will only trigger alerts to slack on state changes or at least every 10 minutes.\n//\n// tick:property\nfunc (a *AlertNode) StateChangesOnly(maxInterval ...time.Duration) *AlertNode {\n\ta.IsStateChangesOnly = true\n\tif len(maxInterval) == 1 {\n\t\ta.StateChangesOnlyDuration = maxInterval[0]\n\t}\n\treturn a\n}\n\n// Perform flap detection on the alerts.\n// The method used is similar method to Nagios:\n// https://assets.nagios.com/downloads/nagioscore/docs/nagioscore/3/en/flapping.html\n//\n// Each different alerting level is considered a different state.\n// The low and high thresholds are inverted thresholds of a percentage of state changes.\n// Meaning that if the percentage of state changes goes above the `high`\n// threshold, the alert enters a flapping state. The alert remains in the flapping state\n// until the percentage of state changes goes below the `low` threshold.\n// Typical values are low: 0.25 and high: 0.5. The percentage values represent the number state changes\n// over the total possible number of state changes. A percentage change of 0.5 means that the alert changed\n// state in half of the recorded history, and remained the same in the other half of the history.\n// tick:property\nfunc (a *AlertNode) Flapping(low, high float64) *AlertNode {\n\ta.UseFlapping = true\n\ta.FlapLow = low\n\ta.FlapHigh = high\n\treturn a\n}\n\n// HTTP POST JSON alert data to a specified URL.\n//\n// Example:\n//    stream\n//         |alert()\n//             .post()\n//                 .endpoint('example')\n//\n// Example:\n//    stream\n//         |alert()\n//             .post('http://example.com')\n//\n// tick:property\nfunc (a *AlertNode) Post(urls ...string) *AlertHTTPPostHandler {\n\tpost := &AlertHTTPPostHandler{\n\t\tAlertNode: a,\n\t}\n\ta.HTTPPostHandlers = append(a.HTTPPostHandlers, post)\n\n\tif len(urls) == 0 {\n\t\treturn post\n\t}\n\n\tpost.URL = urls[0]\n\treturn post\n}\n\n// Set a header key and value on the post request.\n// Setting the Authenticate header is not allowed from within TICKscript,\n// please use the configuration file to specify sensitive headers.\n//\n// Example:\n//    stream\n//         |alert()\n//             .post()\n//                 .endpoint('example')\n//                 .header('a','b')\n// tick:property\nfunc (a *AlertHTTPPostHandler) Header(k, v string) *AlertHTTPPostHandler {\n\tif a.Headers == nil {\n\t\ta.Headers = map[string]string{}\n\t}\n\n\ta.Headers[k] = v\n\treturn a\n}\n\n// tick:embedded:AlertNode.Post\ntype AlertHTTPPostHandler struct {\n\t*AlertNode\n\n\t// The POST URL.\n\t// tick:ignore\n\tURL string\n\n\t// Name of the endpoint to be used, as is defined in the configuration file\n\tEndpoint string\n\n\t// tick:ignore\n\tHeaders map[string]string `tick:\"Header\"`\n}\n\nfunc (a *AlertHTTPPostHandler) validate() error {\n\tfor k := range a.Headers {\n\t\tif strings.ToUpper(k) == \"AUTHENTICATE\" {\n\t\t\treturn errors.New(\"cannot set 'authenticate' header\")\n\t\t}\n\t}\n\treturn nil\n}\n\n// Send JSON alert data to a specified address over TCP.\n// tick:property\nfunc (a *AlertNode) Tcp(address string) *TcpHandler {\n\ttcp := &TcpHandler{\n\t\tAlertNode: a,\n\t\tAddress:   address,\n\t}\n\ta.TcpHandlers = append(a.TcpHandlers, tcp)\n\treturn tcp\n}\n\n// tick:embedded:AlertNode.Tcp\ntype TcpHandler struct {\n\t*AlertNode\n\n\t// The endpoint address.\n\tAddress string\n}\n\n// Email the alert data.\n//\n// If the To list is empty, the To addresses from the configuration are used.\n// The email subject is the AlertNode.Message property.\n// The email body is the AlertNode.Details property.\n// The emails are sent as HTML emails and so the body can contain html markup.\n//\n// If the 'smtp' section in the configuration has the option: global = true\n// then all alerts are sent via email without the need to explicitly state it\n// in the TICKscript.\n//\n// Example:\n//    |alert()\n//       .id('{{ .Name }}')\n//       // Email subject\n//       .message('{{ .ID }}:{{ .Level }}')\n//       //Email body as HTML\n//       .details('''\n//<h1>{{ .ID }}</h1>\n//<b>{{ .Message }}</b>\n//Value: {{ index .Fields \"value\" }}\n//''')\n//       .email()\n//\n// Send an email with custom subject and body.\n//\n// Example:\n//     [smtp]\n//       enabled = true\n//       host = \"localhost\"\n//       port = 25\n//       username = \"\"\n//       password = \"\"\n//       from = \"kapacitor@example.com\"\n//       to = [\"oncall@example.com\"]\n//       # Set global to true so all alert trigger emails.\n//       global = true\n//       state-changes-only =  true\n//\n// Example:\n//    stream\n//         |alert()\n//\n// Send email to 'oncall@example.com' from 'kapacitor@example.com'\n//\n// tick:property\nfunc (a *AlertNode) Email(to ...string) *EmailHandler {\n\tem := &EmailHandler{\n\t\tAlertNode: a,\n\t\tToList:    to,\n\t}\n\ta.EmailHandlers = append(a.EmailHandlers, em)\n\treturn em\n}\n\n// Email AlertHandler\n// tick:embedded:AlertNode.Email\ntype EmailHandler struct {\n\t*AlertNode\n\n\t// List of email recipients.\n\t// tick:ignore\n\tToList []string `tick:\"To\"`\n}\n\n// Define the To addresses for the email alert.\n// Multiple calls append to the existing list of addresses.\n// If empty uses the addresses from the configuration.\n//\n// Example:\n//    |alert()\n//       .id('{{ .Name }}')\n//       // Email subject\n//       .message('{{ .ID }}:{{ .Level }}')\n//       //Email body as HTML\n//       .details('''\n//<h1>{{ .ID }}</h1>\n//<b>{{ .Message }}</b>\n//Value: {{ index .Fields \"value\" }}\n//''')\n//       .email('admin@example.com')\n//         .to('oncall@example.com')\n//         .to('support@example.com')\n//\n// All three email addresses will receive the alert message.\n//\n// Passing addresses to the `email` property directly or using the `email.to` property is the same.\n// tick:property\nfunc (h *EmailHandler) To(to ...string) *EmailHandler {\n\th.ToList = append(h.ToList, to...)\n\treturn h\n}\n\n// Execute a command whenever an alert is triggered and pass the alert data over STDIN in JSON format.\n// tick:property\nfunc (a *AlertNode) Exec(executable string, args ...string) *ExecHandler {\n\texec := &ExecHandler{\n\t\tAlertNode: a,\n\t\tCommand:   append([]string{executable}, args...),\n\t}\n\ta.ExecHandlers = append(a.ExecHandlers, exec)\n\treturn exec\n}\n\n// tick:embedded:AlertNode.Exec\ntype ExecHandler struct {\n\t*AlertNode\n\n\t// The command to execute\n\t// tick:ignore\n\tCommand []string\n}\n\n// Log JSON alert data to file. One event per line.\n// Must specify the absolute path to the log file.\n// It will be created if it does not exist.\n// Example:\n//    stream\n//         |alert()\n//             .log('/tmp/alert')\n//\n// Example:\n//    stream\n//         |alert()\n//             .log('/tmp/alert')\n//             .mode(0644)\n// tick:property\nfunc (a *AlertNode) Log(filepath string) *LogHandler {\n\tlog := &LogHandler{\n\t\tAlertNode: a,\n\t\tFilePath:  filepath,\n\t}\n\ta.LogHandlers = append(a.LogHandlers, log)\n\treturn log\n}\n\n// tick:embedded:AlertNode.Log\ntype LogHandler struct {\n\t*AlertNode\n\n\t// Absolute path the the log file.\n\t// It will be created if it does not exist.\n\t// tick:ignore\n\tFilePath string\n\n\t// File's mode and permissions, default is 0600\n\t// NOTE: The leading 0 is required to interpret the value as an octal integer.\n\tMode int64\n}\n\n// Send alert to VictorOps.\n// To use VictorOps alerting you must first enable the 'Alert Ingestion API'\n// in the 'Integrations' section of VictorOps.\n// Then place the API key from the URL into the 'victorops' section of the Kapacitor configuration.\n//\n// Example:\n//    [victorops]\n//      enabled = true\n//      api-key = \"xxxxx\"\n//      routing-key = \"everyone\"\n//\n// With the correct configuration you can now use VictorOps in TICKscripts.\n//\n// Example:\n//    stream\n//         |alert()\n//             .victorOps()\n//\n// Send alerts to VictorOps using the routing key in the configuration file.\n//\n// Example:\n//    stream\n//         |alert()\n//             .victorOps()\n//             .routingKey('team_rocket')\n//\n// Send alerts to VictorOps with routing key 'team_rocket'\n//\n// If the 'victorops' section in the configuration has the option: global = true\n// then all alerts are sent to VictorOps without the need to explicitly state it\n// in the TICKscript.\n//\n// Example:\n//    [victorops]\n//      enabled = true\n//      api-key = \"xxxxx\"\n//      routing-key = \"everyone\"\n//      global = true\n//\n// Example:\n//    stream\n//         |alert()\n//\n// Send alert to VictorOps using the default routing key, found in the configuration.\n// tick:property\nfunc (a *AlertNode) VictorOps() *VictorOpsHandler {\n\tvo := &VictorOpsHandler{\n\t\tAlertNode: a,\n\t}\n\ta.VictorOpsHandlers = append(a.VictorOpsHandlers, vo)\n\treturn vo\n}\n\n// tick:embedded:AlertNode.VictorOps\ntype VictorOpsHandler struct {\n\t*AlertNode\n\n\t// The routing key to use for the alert.\n\t// Defaults to the value in the configuration if empty.\n\tRoutingKey string\n}\n\n// Send the alert to PagerDuty.\n// To use PagerDuty alerting you must first follow the steps to enable a new 'Generic API' service.\n//\n// From https://developer.pagerduty.com/documentation/integration/events\n//\n//    1. In your account, under the Services tab, click \"Add New Service\".\n//    2. Enter a name for the service and select an escalation policy. Then, select \"Generic API\" for the Service Type.\n//    3. Click the \"Add Service\" button.\n//    4. Once the service is created, you'll be taken to the service page. On this page, you'll see the \"Service key\", which is needed to access the API\n//\n// Place the 'service key' into the 'pagerduty' section of the Kapacitor configuration as the option 'service-key'.\n//\n// Example:\n//    [pagerduty]\n//      enabled = true\n//      service-key = \"xxxxxxxxx\"\n//\n// With the correct configuration you can now use PagerDuty in TICKscripts.\n//\n// Example:\n//    stream\n//         |alert()\n//             .pagerDuty()\n//\n// If the 'pagerduty' section in the configuration has the option: global = true\n// then all alerts are sent to PagerDuty without the need to explicitly state it\n// in the TICKscript.\n//\n// Example:\n//    [pagerduty]\n//      enabled = true\n//      service-key = \"xxxxxxxxx\"\n//      global = true\n//\n// Example:\n//    stream\n//         |alert()\n//\n// Send alert to PagerDuty.\n// tick:property\nfunc (a *AlertNode) PagerDuty() *PagerDutyHandler {\n\tpd := &PagerDutyHandler{\n\t\tAlertNode: a,\n\t}\n\ta.PagerDutyHandlers = append(a.PagerDutyHandlers, pd)\n\treturn pd\n}\n\n// tick:embedded:AlertNode.PagerDuty\ntype PagerDutyHandler struct {\n\t*AlertNode\n\n\t// The service key to use for the alert.\n\t// Defaults to the value in the configuration if empty.\n\tServiceKey string\n}\n\n// Send the alert to HipChat.\n// For step-by-step instructions on setting up Kapacitor with HipChat, see the Event Handler Setup Guide (https://docs.influxdata.com//kapacitor/latest/guides/event-handler-setup/#hipchat-setup).\n// To allow Kapacitor to post to HipChat,\n// go to the URL https://www.hipchat.com/docs/apiv2 for\n// information on how to get your room id and tokens.\n//\n// Example:\n//    [hipchat]\n//      enabled = true\n//      url = \"https://orgname.hipchat.com/v2/room\"\n//      room = \"4189212\"\n//      token = \"9hiWoDOZ9IbmHsOTeST123ABciWTIqXQVFDo63h9\"\n//\n// In order to not post a message every alert interval\n// use AlertNode.StateChangesOnly so that only events\n// where the alert changed state are posted to the room.\n//\n// Example:\n//    stream\n//         |alert()\n//             .hipChat()\n//\n// Send alerts to HipChat room in the configuration file.\n//\n// Example:\n//    stream\n//         |alert()\n//             .hipChat()\n//             .room('Kapacitor')\n//\n// Send alerts to HipChat room 'Kapacitor'\n\n//\n// If the 'hipchat' section in the configuration has the option: global = true\n// then all alerts are sent to HipChat without the need to explicitly state it\n// in the TICKscript.\n//\n// Example:\n//    [hipchat]\n//      enabled = true\n//      url = \"https://orgname.hipchat.com/v2/room\"\n//      room = \"Test Room\"\n//      token = \"9hiWoDOZ9IbmHsOTeST123ABciWTIqXQVFDo63h9\"\n//      global = true\n//      state-changes-only = true\n//\n// Example:\n//    stream\n//         |alert()\n//\n// Send alert to HipChat using default room 'Test Room'.\n// tick:property\nfunc (a *AlertNode) HipChat() *HipChatHandler {\n\thipchat := &HipChatHandler{\n\t\tAlertNode: a,\n\t}\n\ta.HipChatHandlers = append(a.HipChatHandlers, hipchat)\n\treturn hipchat\n}\n\n// tick:embedded:AlertNode.HipChat\ntype HipChatHandler struct {\n\t*AlertNode\n\n\t// HipChat room in which to post messages.\n\t// If empty uses the channel from the configuration.\n\tRoom string\n\n\t// HipChat authentication token.\n\t// If empty uses the token from the configuration.\n\tToken string\n}\n\n// Send the alert to Alerta.\n//\n// Example:\n//    [alerta]\n//      enabled = true\n//      url = \"https://alerta.yourdomain\"\n//      token = \"9hiWoDOZ9IbmHsOTeST123ABciWTIqXQVFDo63h9\"\n//      environment = \"Production\"\n//      origin = \"Kapacitor\"\n//\n// In order to not post a message every alert interval\n// use AlertNode.StateChangesOnly so that only events\n// where the alert changed state are sent to Alerta.\n//\n// Send alerts to Alerta. The resource and event properties are required.\n//\n// Example:\n//    stream\n//         |alert()\n//             .alerta()\n//                 .resource('Hostname or service')\n//                 .event('Something went wrong')\n//\n// Alerta also accepts optional alert information.\n//\n// Example:\n//    stream\n//         |alert()\n//             .alerta()\n//                 .resource('Hostname or service')\n//                 .event('Something went wrong')\n//                 .environment('Development')\n//                 .group('Dev. Servers')\n//\n// NOTE: Alerta cannot be configured globally because of its required properties.\n// tick:property\nfunc (a *AlertNode) Alerta() *AlertaHandler {\n\talerta := &AlertaHandler{\n\t\tAlertNode: a,\n\t}\n\ta.AlertaHandlers = append(a.AlertaHandlers, alerta)\n\treturn alerta\n}\n\n// tick:embedded:AlertNode.Alerta\ntype AlertaHandler struct {\n\t*AlertNode\n\n\t// Alerta authentication token.\n\t// If empty uses the token from the configuration.\n\tToken string\n\n\t// Alerta resource.\n\t// Can be a template and has access to the same data as the AlertNode.Details property.\n\t// Default: {{ .Name }}\n\tResource string\n\n\t// Alerta event.\n\t// Can be a template and has access to the same data as the idInfo property.\n\t// Default: {{ .ID }}\n\tEvent string\n\n\t// Alerta environment.\n\t// Can be a template and has access to the same data as the AlertNode.Details property.\n\t// Defaut is set from the configuration.\n\tEnvironment string\n\n\t// Alerta group.\n\t// Can be a template and has access to the same data as the AlertNode.Details property.\n\t// Default: {{ .Group }}\n\tGroup string\n\n\t// Alerta value.\n\t// Can be a template and has access to the same data as the AlertNode.Details property.\n\t// Default is an empty string.\n\tValue string\n\n\t// Alerta origin.\n\t// If empty uses the origin from the configuration.\n\tOrigin string\n\n\t// List of effected Services\n\t// tick:ignore\n\tService []string `tick:\"Services\"`\n}\n\n// List of effected services.\n// If not specified defaults to the Name of the stream.\n// tick:property\nfunc (a *AlertaHandler) Services(service ...string) *AlertaHandler {\n\ta.Service = service\n\treturn a\n}\n\n// Send the alert to Sensu.\n//\n// Example:\n//    [sensu]\n//      enabled = true\n//      url = \"http://sensu:3030\"\n//      source = \"Kapacitor\"\n//      handlers = [\"sns\",\"slack\"]\n//\n// Example:\n//    stream\n//         |alert()\n//             .sensu()\n//\n// Send alerts to Sensu client.\n//\n// Example:\n//    stream\n//         |alert()\n//             .sensu()\n//             .handlers('sns','slack')\n//\n// Send alerts to Sensu specifying the handlers\n//\n// tick:property\nfunc (a *AlertNode) Sensu() *SensuHandler {\n\tsensu := &SensuHandler{\n\t\tAlertNode: a,\n\t}\n\ta.SensuHandlers = append(a.SensuHandlers, sensu)\n\treturn sensu\n}\n\n// tick:embedded:AlertNode.Sensu\ntype SensuHandler struct {\n\t*AlertNode\n\n\t// Sensu source in which to post messages.\n\t// If empty uses the Source from the configuration.\n\tSource string\n\n\t// Sensu handler list\n\t// If empty uses the handler list from the configuration\n\t// tick:ignore\n\tHandlersList []string `tick:\"Handlers\"`\n}\n\n// List of effected services.\n// If not specified defaults to the Name of the stream.\n// tick:property\nfunc (s *SensuHandler) Handlers(handlers ...string) *SensuHandler {\n\ts.HandlersList = handlers\n\treturn s\n}\n\n// Send the alert to Pushover.\n// Register your application with Pushover at\n// https://pushover.net/apps/build to get a\n// Pushover token.\n//\n// Alert Level Mapping:\n// OK - Sends a -2 priority level.\n// Info - Sends a -1 priority level.\n// Warning - Sends a 0 priority level.\n// Critical - Sends a 1 priority level.\n//\n// Example:\n//    [pushover]\n//      enabled = true\n//      token = \"9hiWoDOZ9IbmHsOTeST123ABciWTIqXQVFDo63h9\"\n//      user_key = \"Pushover\"\n//\n// Example:\n//    stream\n//         |alert()\n//             .pushover()\n//              .sound('siren')\n//              .user_key('other user')\n//              .device('mydev')\n//              .title('mytitle')\n//              .URL('myurl')\n//              .URLTitle('mytitle')\n//\n// Send alerts to Pushover.\n//\n// tick:property\nfunc (a *AlertNode) Pushover() *PushoverHandler {\n\tpushover := &PushoverHandler{\n\t\tAlertNode: a,\n\t}\n\ta.PushoverHandlers = append(a.PushoverHandlers, pushover)\n\treturn pushover\n}\n\n// tick:embedded:AlertNode.Pushover\ntype PushoverHandler struct {\n\t*AlertNode\n\n\t// User/Group key of your user (or you), viewable when logged\n\t// into the Pushover dashboard. Often referred to as USER_KEY\n\t// in the Pushover documentation.\n\t// If empty uses the user from the configuration.\n\tUserKey string\n\n\t// Users device name to send message directly to that device,\n\t// rather than all of a user's devices (multiple device names may\n\t// be separated by a comma)\n\tDevice string\n\n\t// Your message's title, otherwise your apps name is used\n\tTitle string\n\n\t// A supplementary URL to show with your message\n\tURL string\n\n\t// A title for your supplementary URL, otherwise just URL is shown\n\tURLTitle string\n\n\t// The name of one of the sounds supported by the device clients to override\n\t// the user's default sound choice\n\tSound string\n}\n\n// Send the alert to Slack.\n// To allow Kapacitor to post to Slack,\n// go to the URL https://slack.com/services/new/incoming-webhook\n// and create a new incoming webhook and place the generated URL\n// in the 'slack' configuration section.\n//\n// Example:\n//    [slack]\n//      enabled = true\n//      url = \"https://hooks.slack.com/services/xxxxxxxxx/xxxxxxxxx/xxxxxxxxxxxxxxxxxxxxxxxx\"\n//      channel = \"#general\"\n//\n// In order to not post a message every alert interval\n// use AlertNode.StateChangesOnly so that only events\n// where the alert changed state are posted to the channel.\n//\n// Example:\n//    stream\n//         |alert()\n//             .slack()\n//\n// Send alerts to Slack channel in the configuration file.\n//\n// Example:\n//    stream\n//         |alert()\n//             .slack()\n//             .channel('#alerts')\n//\n// Send alerts to Slack channel '#alerts'\n//\n// Example:\n//    stream\n//         |alert()\n//             .slack()\n//             .channel('@jsmith')\n//\n// Send alert to user '@jsmith'\n//\n// If the 'slack' section in the configuration has the option: global = true\n// then all alerts are sent to Slack without the need to explicitly state it\n// in the TICKscript.\n//\n// Example:\n//    [slack]\n//      enabled = true\n//      url = \"https://hooks.slack.com/services/xxxxxxxxx/xxxxxxxxx/xxxxxxxxxxxxxxxxxxxxxxxx\"\n//      channel = \"#general\"\n//      global = true\n//      state-changes-only = true\n//\n// Example:\n//    stream\n//         |alert()\n//\n// Send alert to Slack using default channel '#general'.\n// tick:property\nfunc (a *AlertNode) Slack() *SlackHandler {\n\tslack := &SlackHandler{\n\t\tAlertNode: a,\n\t}\n\ta.SlackHandlers = append(a.SlackHandlers, slack)\n\treturn slack\n}\n\n// tick:embedded:AlertNode.Slack\ntype SlackHandler struct {\n\t*AlertNode\n\n\t// Slack channel in which to post messages.\n\t// If empty uses the channel from the configuration.\n\tChannel string\n\n\t// Username of the Slack bot.\n\t// If empty uses the username from the configuration.\n\tUsername string\n\n\t// IconEmoji is an emoji name surrounded in ':' characters.\n\t// The emoji image will replace the normal user icon for the slack bot.\n\tIconEmoji string\n}\n\n// Send the alert to Telegram.\n// For step-by-step instructions on setting up Kapacitor with Telegram, see the Event Handler Setup Guide (https://docs.influxdata.com//kapacitor/latest/guides/event-handler-setup/#telegram-setup).\n// To allow Kapacitor to post to Telegram,\n//\n// Example:\n//    [telegram]\n//      enabled = true\n//      token = \"123456789:xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx\"\n//      chat-id = \"xxxxxxxxx\"\n//      parse-mode = \"Markdown\"\n//\tdisable-web-page-preview = true\n//\tdisable-notification = false\n//\n// In order to not post a message every alert interval\n// use AlertNode.StateChangesOnly so that only events\n// where the alert changed state are posted to the chat-id.\n//\n// Example:\n//    stream\n//         |alert()\n//             .telegram()\n//\n// Send alerts to Telegram chat-id in the configuration file.\n//\n// Example:\n//    stream\n//         |alert()\n//             .telegram()\n//             .chatId('xxxxxxx')\n//\n// Send alerts to Telegram user/group 'xxxxxx'\n//\n// If the 'telegram' section in the configuration has the option: global = true\n// then all alerts are sent to Telegram without the need to explicitly state it\n// in the TICKscript.\n//\n// Example:\n//    [telegram]\n//      enabled = true\n//      token = \"123456789:xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx\"\n//      chat-id = \"xxxxxxxxx\"\n//      global = true\n//      state-changes-only = true\n//\n// Example:\n//    stream\n//         |alert()\n//\n// Send alert to Telegram using default chat-id 'xxxxxxxx'.\n// tick:property\nfunc (a *AlertNode) Telegram() *TelegramHandler {\n\ttelegram := &TelegramHandler{\n\t\tAlertNode: a,\n\t}\n\ta.TelegramHandlers = append(a.TelegramHandlers, telegram)\n\treturn telegram\n}\n\n// tick:embedded:AlertNode.Telegram\ntype TelegramHandler struct {\n\t*AlertNode\n\n\t// Telegram user/group ID to post messages to.\n\t// If empty uses the chati-d from the configuration.\n\tChatId string\n\t// Parse node, defaults to Mardown\n\t// If empty uses the parse-mode from the configuration.\n\tParseMode string\n\t// Web Page preview\n\t// If empty uses the disable-web-page-preview from the configuration.\n\t// tick:ignore\n\tIsDisableWebPagePreview bool `tick:\"DisableWebPagePreview\"`\n\t// Disables Notification\n\t// If empty uses the disable-notification from the configuration.\n\t// tick:ignore\n\tIsDisableNotification bool `tick:\"DisableNotification\"`\n}\n\n// Disables the Notification. If empty defaults to the configuration.\n// tick:property\nfunc (tel *TelegramHandler) DisableNotification() *TelegramHandler {\n\ttel.IsDisableNotification = true\n\treturn tel\n}\n\n// Disables the WebPagePreview. If empty defaults to the configuration.\n// tick:property\nfunc (tel *TelegramHandler) DisableWebPagePreview() *TelegramHandler {\n\ttel.IsDisableWebPagePreview = true\n\treturn tel\n}\n\n// Send alert to OpsGenie.\n// To use OpsGenie alerting you must first enable the 'Alert Ingestion API'\n// in the 'Integrations' section of OpsGenie.\n// Then place the API key from the URL into the 'opsgenie' section of the Kapacitor configuration.\n//\n// Example:\n//    [opsgenie]\n//      enabled = true\n//      api-key = \"xxxxx\"\n//      teams = [\"everyone\"]\n//      recipients = [\"jim\", \"bob\"]\n//\n// With the correct configuration you can now use OpsGenie in TICKscripts.\n//\n// Example:\n//    stream\n//         |alert()\n//             .opsGenie()\n//\n// Send alerts to OpsGenie using the teams and recipients in the configuration file.\n//\n// Example:\n//    stream\n//         |alert()\n//             .opsGenie()\n//             .teams('team_rocket','team_test')\n//\n// Send alerts to OpsGenie with team set to 'team_rocket' and 'team_test'\n//\n// If the 'opsgenie' section in the configuration has the option: global = true\n// then all alerts are sent to OpsGenie without the need to explicitly state it\n// in the TICKscript.\n//\n// Example:\n//    [opsgenie]\n//      enabled = true\n//      api-key = \"xxxxx\"\n//      recipients = [\"johndoe\"]\n//      global = true\n//\n// Example:\n//    stream\n//         |alert()\n//\n// Send alert to OpsGenie using the default recipients, found in the configuration.\n// tick:property\nfunc (a *AlertNode) OpsGenie() *OpsGenieHandler {\n\tog := &OpsGenieHandler{\n\t\tAlertNode: a,\n\t}\n\ta.OpsGenieHandlers = append(a.OpsGenieHandlers, og)\n\treturn og\n}\n\n// tick:embedded:AlertNode.OpsGenie\ntype OpsGenieHandler struct {\n\t*AlertNode\n\n\t// OpsGenie Teams.\n\t// tick:ignore\n\tTeamsList []string `tick:\"Teams\"`\n\n\t// OpsGenie Recipients.\n\t// tick:ignore\n\tRecipientsList []string `tick:\"Recipients\"`\n}\n\n// The list of teams to be alerted. If empty defaults to the teams from the configuration.\n// tick:property\nfunc (og *OpsGenieHandler) Teams(teams ...string) *OpsGenieHandler {\n\tog.TeamsList = teams\n\treturn og\n}\n\n// The list of recipients to be alerted. If empty defaults to the recipients from the configuration.\n// tick:property\nfunc (og *OpsGenieHandler) Recipients(recipients ...string) *OpsGenieHandler {\n\tog.RecipientsList = recipients\n\treturn og\n}\n\n// Send the alert to Talk.\n// To use Talk alerting you must first follow the steps to create a new incoming webhook.\n//\n//    1. Go to the URL https:/account.jianliao.com/signin.\n//    2. Sign in with you account. under the Team tab, click \"Integrations\".\n//    3. Select \"Customize service\", click incoming Webhook \"Add\" button.\n//    4. After choose the topic to connect with \"xxx\", click \"Confirm Add\" button.\n//    5. Once the service is created, you'll see the \"Generate Webhook url\".\n//\n// Place the 'Generate Webhook url' into the 'Talk' section of the Kapacitor configuration as the option 'url'.\n//\n// Example:\n//    [talk]\n//      enabled = true\n//      url = \"https://jianliao.com/v2/services/webhook/uuid\"\n//      author_name = \"Kapacitor\"\n//\n// Example:\n//    stream\n//         |alert()\n//             .talk()\n//\n// Send alerts to Talk client.\n//\n// tick:property\nfunc (a *AlertNode) Talk() *TalkHandler {\n\ttalk := &TalkHandler{\n\t\tAlertNode: a,\n\t}\n\ta.TalkHandlers = append(a.TalkHandlers, talk)\n\treturn talk\n}\n\n// tick:embedded:AlertNode.Talk\ntype TalkHandler struct {\n\t*AlertNode\n}\n\n// Send the alert using SNMP traps.\n// To allow Kapacitor to post SNMP traps,\n//\n// Example:\n//    [snmptrap]\n//      enabled = true\n//      addr = \"127.0.0.1:9162\"\n//      community = \"public\"\n//\n// Example:\n//    stream\n//         |alert()\n//             .snmpTrap('1.1.1.1')\n//                 .data('1.3.6.1.2.1.1.7', 'i', '{{ index .Field \"value\" }}')\n//\n// Send alerts to `target-ip:target-port` on OID '1.3.6.1.2.1.1.7'\n//\n// tick:property\nfunc (a *AlertNode) SnmpTrap(trapOid string) *SNMPTrapHandler {\n\tsnmpTrap := &SNMPTrapHandler{\n\t\tAlertNode: a,\n\t\tTrapOid:   trapOid,\n\t}\n\ta.SNMPTrapHandlers = append(a.SNMPTrapHandlers, snmpTrap)\n\treturn snmpTrap\n}\n\n// SNMPTrap AlertHandler\n// tick:embedded:AlertNode.SnmpTrap\ntype SNMPTrapHandler struct {\n\t*AlertNode\n\n\t// TrapOid\n\t// tick:ignore\n\tTrapOid string\n\n\t// List of trap data.\n\t// tick:ignore\n\tDataList []SNMPData `tick:\"Data\"`\n}\n\n// tick:ignore\ntype SNMPData struct {\n\tOid   string\n\tType  string\n\tValue string\n}\n\n// Define Data for SNMP Trap alert.\n// Multiple calls append to the existing list of data.\n//\n// Available types:\n//\n// | Abbreviation | Datatype   |\n// | ------------ | --------   |\n// | c            | Counter    |\n// | i            | Integer    |\n// | n            | Null       |\n// | s            | String     |\n// | t            | Time ticks |\n//\n// Example:\n//    |alert()\n//       .message('{{ .ID }}:{{ .Level }}')\n//       .snmpTrap('1.3.6.1.4.1.1')\n//          .data('1.3.6.1.4.1.1.5', 's', '{{ .Level }}' )\n//          .data('1.3.6.1.4.1.1.6', 'i', '50' )\n//          .data('1.3.6.1.4.1.1.7', 'c', '{{ index .Fields \"num_requests\" }}' )\n//          .data('1.3.6.1.4.1.1.8', 's', '{{ .Message }}' )\n//\n// tick:property\nfunc (h *SNMPTrapHandler) Data(oid, typ, value string) *SNMPTrapHandler {\n\tdata := SNMPData{\n\t\tOid:   oid,\n\t\tType:  typ,\n\t\tValue: value,\n\t}\n\th.DataList = append(h.DataList, data)\n\treturn h\n}\n\nfunc (h *SNMPTrapHandler) validate() error {\n\tif h.TrapOid == \"\" {\n\t\treturn fmt.Errorf(\"you must supply a trap Oid\")\n\t}\n\tfor _, d := range h.DataList {\n\t\tswitch d.Type {\n\t\tcase \"c\", \"i\", \"n\", \"s\", \"t\":\n\t\t\t// OK\n\t\tdefault:\n\t\t\treturn fmt.Errorf(\"unsupported data type %q for data entry %q\", d.Type, d.Oid)\n\t\t}\n\t}\n\treturn nil\n}\n",
+	"anomaly.go":            "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n)\n\n// Seasons supported by the AnomalyNode.\nconst (\n\t// A single baseline for all points.\n\tAnomalySeasonNone = \"none\"\n\t// A baseline for each hour of the day.\n\tAnomalySeasonHour = \"hour\"\n\t// A baseline for each day of the week.\n\tAnomalySeasonWeekday = \"weekday\"\n\t// A baseline for each hour of each day of the week.\n\tAnomalySeasonHourOfWeek = \"hourOfWeek\"\n)\n\n// An AnomalyNode detects anomalies in a field by comparing each value\n// to a learned seasonal baseline.\n//\n// A baseline is maintained per group and per season bucket, i.e. when using\n// the 'hour' season each group has 24 independent baselines, one for each hour of the day.\n// Each baseline is an exponentially weighted moving mean and variance of the values\n// that fell into the bucket.\n//\n// For each point the following fields are added:\n//\n//    * expected     -- The mean of the baseline.\n//    * upper        -- The upper band, expected + sigmas * stddev.\n//    * lower        -- The lower band, expected - sigmas * stddev.\n//    * anomalyScore -- The number of standard deviations the value is away from expected.\n//\n// Points that fall into a bucket which has not yet seen `warmup` values are used\n// to train the baseline and are not emitted.\n//\n// The learned baselines are included in task snapshots so restarting\n// the task does not lose them. See the `snapshot-interval` option of the task store.\n//\n// Example:\n//    stream\n//        |from()\n//            .measurement('requests')\n//        |groupBy('host')\n//        |anomaly('count')\n//            .season('hourOfWeek')\n//            .sigmas(3.0)\n//        |alert()\n//            .crit(lambda: \"anomalyScore\" > 3.0)\n//\n// Times are bucketed using UTC.\ntype AnomalyNode struct {\n\tchainnode\n\n\t// The field to use when detecting anomalies.\n\t// tick:ignore\n\tField string\n\n\t// The season of the baselines.\n\t// One of 'none', 'hour', 'weekday' or 'hourOfWeek'.\n\t// Default: 'hour'\n\tSeason string\n\n\t// The smoothing factor of the moving mean and variance, between 0 and 1.\n\t// Larger values give more weight to recent values.\n\t// Default: 0.3\n\tAlpha float64\n\n\t// The number of standard deviations of the upper and lower bands.\n\t// Default: 3.0\n\tSigmas float64\n\n\t// The number of values a bucket must see before anomalies are reported for it.\n\t// Default: 2\n\tWarmup int64\n}\n\nfunc newAnomalyNode(wants EdgeType, field string) *AnomalyNode {\n\treturn &AnomalyNode{\n\t\tchainnode: newBasicChainNode(\"anomaly\", wants, wants),\n\t\tField:     field,\n\t\tSeason:    AnomalySeasonHour,\n\t\tAlpha:     0.3,\n\t\tSigmas:    3.0,\n\t\tWarmup:    2,\n\t}\n}\n\nfunc (n *AnomalyNode) validate() error {\n\tswitch n.Season {\n\tcase AnomalySeasonNone, AnomalySeasonHour, AnomalySeasonWeekday, AnomalySeasonHourOfWeek:\n\tdefault:\n\t\treturn fmt.Errorf(\"invalid season %q, must be one of 'none', 'hour', 'weekday' or 'hourOfWeek'\", n.Season)\n\t}\n\tif n.Alpha <= 0 || n.Alpha > 1 {\n\t\treturn errors.New(\"alpha must be greater than 0 and less than or equal to 1\")\n\t}\n\tif n.Sigmas <= 0 {\n\t\treturn errors.New(\"sigmas must be greater than 0\")\n\t}\n\tif n.Warmup < 1 {\n\t\treturn errors.New(\"warmup must be at least 1\")\n\t}\n\treturn nil\n}\n",
+	"batch.go":              "package pipeline\n\nimport (\n\t\"bytes\"\n\t\"reflect\"\n\t\"time\"\n)\n\n// A node that handles creating several child QueryNodes.\n// Each call to `query` creates a child batch node that\n// can further be configured. See QueryNode\n// The `batch` variable in batch tasks is an instance of\n// a BatchNode.\n//\n// Example:\n//     var errors = batch\n//                      |query('SELECT value from errors')\n//                      ...\n//     var views = batch\n//                      |query('SELECT value from views')\n//                      ...\n//\n// Available Statistics:\n//\n//    * query_errors -- number of errors when querying\n//    * batches_queried -- number of batches returned from queries\n//    * points_queried -- total number of points in batches\n//\ntype BatchNode struct {\n\tnode\n}\n\nfunc newBatchNode() *BatchNode {\n\treturn &BatchNode{\n\t\tnode: node{\n\t\t\tdesc:     \"batch\",\n\t\t\twants:    NoEdge,\n\t\t\tprovides: BatchEdge,\n\t\t},\n\t}\n}\n\n// The query to execute. Must not contain a time condition\n// in the `WHERE` clause or contain a `GROUP BY` clause.\n// The time conditions are added dynamically according to the period, offset and schedule.\n// The `GROUP BY` clause is added dynamically according to the dimensions\n// passed to the `groupBy` method.\nfunc (b *BatchNode) Query(q string) *QueryNode {\n\tn := newQueryNode()\n\tn.QueryStr = q\n\tb.linkChild(n)\n\treturn n\n}\n\n// Do not add the source batch node to the dot output\n// since its not really an edge.\n// tick:ignore\nfunc (b *BatchNode) dot(buf *bytes.Buffer) {\n}\n\n// A QueryNode defines a source and a schedule for\n// processing batch data. The data is queried from\n// an InfluxDB database and then passed into the data pipeline.\n//\n// Example:\n// batch\n//     |query('''\n//         SELECT mean(\"value\")\n//         FROM \"telegraf\".\"default\".cpu_usage_idle\n//         WHERE \"host\" = 'serverA'\n//     ''')\n//         .period(1m)\n//         .every(20s)\n//         .groupBy(time(10s), 'cpu')\n//     ...\n//\n// In the above example InfluxDB is queried every 20 seconds; the window of time returned\n// spans 1 minute and is grouped into 10 second buckets.\ntype QueryNode struct {\n\tchainnode\n\n\t// The query text\n\t//tick:ignore\n\tQueryStr string\n\n\t// The period or length of time that will be queried from InfluxDB\n\tPeriod time.Duration\n\n\t// How often to query InfluxDB.\n\t//\n\t// The Every property is mutually exclusive with the Cron property.\n\tEvery time.Duration\n\n\t// Align start and end times with the Every value\n\t// Does not apply if Cron is used.\n\t// tick:ignore\n\tAlignFlag bool `tick:\"Align\"`\n\n\t// Define a schedule using a cron syntax.\n\t//\n\t// The specific cron implementation is documented here:\n\t// https://github.com/gorhill/cronexpr#implementation\n\t//\n\t// The Cron property is mutually exclusive with the Every property.\n\tCron string\n\n\t// How far back in time to query from the current time\n\t//\n\t// For example an Offest of 2 hours and an Every of 5m,\n\t// Kapacitor will query InfluxDB every 5 minutes for the window of data 2 hours ago.\n\t//\n\t// This applies to Cron schedules as well. If the cron specifies to run every Sunday at\n\t// 1 AM and the Offset is 1 hour. Then at 1 AM on Sunday the data from 12 AM will be queried.\n\tOffset time.Duration\n\n\t// Align the group by time intervals with the start time of the query\n\t// tick:ignore\n\tAlignGroupFlag bool `tick:\"AlignGroup\"`\n\n\t// The list of dimensions for the group-by clause.\n\t//tick:ignore\n\tDimensions []interface{} `tick:\"GroupBy\"`\n\n\t// Whether to include the measurement in the group ID.\n\t// tick:ignore\n\tGroupByMeasurementFlag bool `tick:\"GroupByMeasurement\"`\n\n\t// Fill the data.\n\t// Options are:\n\t//\n\t//   - Any numerical value\n\t//   - null - exhibits the same behavior as the default\n\t//   - previous - reports the value of the previous window\n\t//   - none - suppresses timestamps and values where the value is null\n\t//   - linear - reports the results of linear interpolation\n\tFill interface{}\n\n\t// The name of a configured InfluxDB cluster.\n\t// If empty the default cluster will be used.\n\tCluster string\n}\n\nfunc newQueryNode() *QueryNode {\n\tb := &QueryNode{\n\t\tchainnode: newBasicChainNode(\"query\", BatchEdge, BatchEdge),\n\t}\n\treturn b\n}\n\n//tick:ignore\nfunc (n *QueryNode) ChainMethods() map[string]reflect.Value {\n\treturn map[string]reflect.Value{\n\t\t\"GroupBy\": reflect.ValueOf(n.chainnode.GroupBy),\n\t\t\"Fill\":    reflect.ValueOf(n.chainnode.Fill),\n\t}\n}\n\n// Group the data by a set of dimensions.\n// Can specify one time dimension.\n//\n// This property adds a `GROUP BY` clause to the query\n// so all the normal behaviors when quering InfluxDB with a `GROUP BY` apply.\n//\n// Use group by time when your period is longer than your group by time interval.\n//\n// Example:\n//    batch\n//        |query(...)\n//            .period(1m)\n//            .every(1m)\n//            .groupBy(time(10s), 'tag1', 'tag2'))\n//            .align()\n//\n// A group by time offset is also possible.\n//\n// Example:\n//    batch\n//        |query(...)\n//            .period(1m)\n//            .every(1m)\n//            .groupBy(time(10s, -5s), 'tag1', 'tag2'))\n//            .align()\n//            .offset(5s)\n//\n// It is recommended to use QueryNode.Align and QueryNode.Offset in conjunction with\n// group by time dimensions so that the time bounds match up with the group by intervals.\n// To automatically align the group by intervals to the start of the query time,\n// use QueryNode.AlignGroup. This is useful in more complex situations, such as when\n// the groupBy time period is longer than the query frequency.\n//\n// Example:\n//    batch\n//        |query(...)\n//            .period(5m)\n//            .every(30s)\n//            .groupBy(time(1m), 'tag1', 'tag2')\n//            .align()\n//            .alignGroup()\n//\n// For the above example, without QueryNode.AlignGroup, every other query issued by Kapacitor\n// (at :30 past the minute) will align to :00 seconds instead of the desired :30 seconds,\n// which would create 6 group by intervals instead of 5, the first and last of which\n// would only have 30 seconds of data instead of a full minute.\n// If the group by time offset (i.e. time(t, offset)) is used in conjunction with\n// QueryNode.AlignGroup, the alignment will occur first, and will be offset\n// the specified amount after.\n//\n// NOTE: Since QueryNode.Offset is inherently a negative property the second \"offset\" argument to the \"time\" function is negative to match.\n//\n// tick:property\nfunc (b *QueryNode) GroupBy(d ...interface{}) *QueryNode {\n\tb.Dimensions = d\n\treturn b\n}\n\n// If set will include the measurement name in the group ID.\n// Along with any other group by dimensions.\n//\n// Example:\n// batch\n//      |query('SELECT sum(\"value\") FROM \"telegraf\".\"autogen\"./process_.*/')\n//          .groupByMeasurement()\n//          .groupBy('host')\n//\n// The above example selects data from several measurements matching `/process_.*/ and\n// then each point is grouped by the host tag and measurement name.\n// Thus keeping measurements in their own groups.\n// tick:property\nfunc (n *QueryNode) GroupByMeasurement() *QueryNode {\n\tn.GroupByMeasurementFlag = true\n\treturn n\n}\n\n// Align start and stop times for quiries with even boundaries of the QueryNode.Every property.\n// Does not apply if using the QueryNode.Cron property.\n// tick:property\nfunc (b *QueryNode) Align() *QueryNode {\n\tb.AlignFlag = true\n\treturn b\n}\n\n// Align the group by time intervals with the start time of the query\n// tick:property\nfunc (b *QueryNode) AlignGroup() *QueryNode {\n\tb.AlignGroupFlag = true\n\treturn b\n}\n",
+	"combine.go":            "package pipeline\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n\t\"time\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\nconst (\n\tdefaultCombineDelimiter = \".\"\n\tdefaultMaxCombinations  = 1e6\n)\n\n// Combine the data from a single node with itself.\n// Points with the same time are grouped and then combinations are created.\n// The size of the combinations is defined by how many expressions are given.\n// Combinations are order independent and will not ever include the same point multiple times.\n//\n// Example:\n//    stream\n//        |from()\n//            .measurement('request_latency')\n//        |combine(lambda: \"service\" == 'login', lambda: TRUE)\n//            .as('login', 'other')\n//            // points that are within 1 second are considered the same time.\n//            .tolerance(1s)\n//            // delimiter for new field and tag names\n//            .delimiter('.')\n//        // Change group by to be new other.service tag\n//        |groupBy('other.service')\n//        // Both the \"value\" fields from each data point have been prefixed\n//        // with the respective names 'login' and 'other'.\n//        |eval(lambda: \"login.value\" / \"other.value\")\n//           .as('ratio')\n//        ...\n//\n// In the above example the data points for the `login` service are combined with the data points from all other services.\n//\n// Example:\n//        |combine(lambda: TRUE, lambda: TRUE)\n//            .as('login', 'other')\n//\n// In the above example all combination pairs are created.\n//\n// Example:\n//        |combine(lambda: TRUE, lambda: TRUE, lambda: TRUE)\n//            .as('login', 'other', 'another')\n//\n// In the above example all combinations triples are created.\ntype CombineNode struct {\n\tchainnode\n\n\t// The list of expressions for matching pairs\n\t// tick:ignore\n\tLambdas []*ast.LambdaNode\n\n\t// The alias names of the two parents.\n\t// Note:\n\t//       Names[1] corresponds to the left  parent\n\t//       Names[0] corresponds to the right parent\n\t// tick:ignore\n\tNames []string `tick:\"As\"`\n\n\t// The delimiter between the As names and existing field an tag keys.\n\t// Can be the empty string, but you are responsible for ensuring conflicts are not possible if you use the empty string.\n\tDelimiter string\n\n\t// The maximum duration of time that two incoming points\n\t// can be apart and still be considered to be equal in time.\n\t// The joined data point's time will be rounded to the nearest\n\t// multiple of the tolerance duration.\n\tTolerance time.Duration\n\n\t// Maximum number of possible combinations.\n\t// Since the number of possible combinations can grow very rapidly\n\t// you can set a maximum number of combinations allowed.\n\t// If the max is crossed, an error is logged and the combinations are not calculated.\n\t// Default: 10,000\n\tMax int64\n}\n\nfunc newCombineNode(e EdgeType, lambdas []*ast.LambdaNode) *CombineNode {\n\tc := &CombineNode{\n\t\tchainnode: newBasicChainNode(\"combine\", e, StreamEdge),\n\t\tLambdas:   lambdas,\n\t\tDelimiter: defaultCombineDelimiter,\n\t\tMax:       defaultMaxCombinations,\n\t}\n\treturn c\n}\n\n// Prefix names for all fields from the respective nodes.\n// Each field from the parent nodes will be prefixed with the provided name and a '.'.\n// See the example above.\n//\n// The names cannot have a dot '.' character.\n//\n// tick:property\nfunc (n *CombineNode) As(names ...string) *CombineNode {\n\tn.Names = names\n\treturn n\n}\n\n// Validate that the as() specification is consistent with the number of combine expressions.\nfunc (n *CombineNode) validate() error {\n\tif len(n.Names) == 0 {\n\t\treturn fmt.Errorf(\"a call to combine.as() is required to specify the output stream prefixes.\")\n\t}\n\n\tif len(n.Names) != len(n.Lambdas) {\n\t\treturn fmt.Errorf(\"number of prefixes specified by combine.as() must match the number of combine expressions\")\n\t}\n\n\tfor _, name := range n.Names {\n\t\tif len(name) == 0 {\n\t\t\treturn fmt.Errorf(\"must provide a prefix name for the combine node, see .as() property method\")\n\t\t}\n\t\tif strings.Contains(name, n.Delimiter) {\n\t\t\treturn fmt.Errorf(\"cannot use name %s as field prefix, it contains the delimiter character %s\", name, n.Delimiter)\n\t\t}\n\t}\n\tnames := make(map[string]bool, len(n.Names))\n\tfor _, name := range n.Names {\n\t\tif names[name] {\n\t\t\treturn fmt.Errorf(\"cannot use the same prefix name see .as() property method\")\n\t\t}\n\t\tnames[name] = true\n\t}\n\n\treturn nil\n}\n",
+	"default.go":            "package pipeline\n\nimport \"fmt\"\n\n// Defaults fields and tags on data points.\n//\n// Example:\n//    stream\n//        |default()\n//            .field('value', 0.0)\n//            .tag('host', '')\n//\n// The above example will set the field `value` to float64(0) if it does not already exist\n// It will also set the tag `host` to string(\"\") if it does not already exist.\n//\n// Available Statistics:\n//\n//    * fields_defaulted -- number of fields that were missing\n//    * tags_defaulted -- number of tags that were missing\n//\ntype DefaultNode struct {\n\tchainnode\n\n\t// Set of fields to default\n\t// tick:ignore\n\tFields map[string]interface{} `tick:\"Field\"`\n\n\t// Set of tags to default\n\t// tick:ignore\n\tTags map[string]string `tick:\"Tag\"`\n}\n\nfunc newDefaultNode(e EdgeType) *DefaultNode {\n\tn := &DefaultNode{\n\t\tchainnode: newBasicChainNode(\"default\", e, e),\n\t\tFields:    make(map[string]interface{}),\n\t\tTags:      make(map[string]string),\n\t}\n\treturn n\n}\n\n// Define a field default.\n// tick:property\nfunc (n *DefaultNode) Field(name string, value interface{}) *DefaultNode {\n\tn.Fields[name] = value\n\treturn n\n}\n\n// Define a tag default.\n// tick:property\nfunc (n *DefaultNode) Tag(name string, value string) *DefaultNode {\n\tn.Tags[name] = value\n\treturn n\n}\n\nfunc (n *DefaultNode) validate() error {\n\tfor field, value := range n.Fields {\n\t\tswitch value.(type) {\n\t\tcase float64:\n\t\tcase int64:\n\t\tcase bool:\n\t\tcase string:\n\t\tdefault:\n\t\t\treturn fmt.Errorf(\"unsupported type %T for field %q, field default values must be float,int,string or bool\", value, field)\n\t\t}\n\t}\n\treturn nil\n}\n",
+	"delete.go":             "package pipeline\n\n// Deletes fields and tags from data points.\n//\n// Example:\n//    stream\n//        |delete()\n//            .field('value')\n//            .tag('host')\n//\n// The above example will remove the field `value` and the tag `host`, from each point.\n//\n// Available Statistics:\n//\n//    * fields_deleted -- number of fields that were deleted. Only counts if the field already existed.\n//    * tags_deleted -- number of tags that were deleted. Only counts if the tag already existed.\n//\ntype DeleteNode struct {\n\tchainnode\n\n\t// Set of fields to delete\n\t// tick:ignore\n\tFields []string `tick:\"Field\"`\n\n\t// Set of tags to delete\n\t// tick:ignore\n\tTags []string `tick:\"Tag\"`\n}\n\nfunc newDeleteNode(e EdgeType) *DeleteNode {\n\tn := &DeleteNode{\n\t\tchainnode: newBasicChainNode(\"delete\", e, e),\n\t}\n\treturn n\n}\n\n// Delete a field.\n// tick:property\nfunc (n *DeleteNode) Field(name string) *DeleteNode {\n\tn.Fields = append(n.Fields, name)\n\treturn n\n}\n\n// Delete a tag.\n// tick:property\nfunc (n *DeleteNode) Tag(name string) *DeleteNode {\n\tn.Tags = append(n.Tags, name)\n\treturn n\n}\n",
+	"derivative.go":         "package pipeline\n\nimport (\n\t\"time\"\n)\n\n// Compute the derivative of a stream or batch.\n// The derivative is computed on a single field\n// and behaves similarly to the InfluxQL derivative\n// function. Kapacitor has its own implementation\n// of the derivative function, and, as a result, is\n// not part of the normal InfluxQL functions.\n//\n// Example:\n//     stream\n//         |from()\n//             .measurement('net_rx_packets')\n//         |derivative('value')\n//            .unit(1s) // default\n//            .nonNegative()\n//         ...\n//\n// Computes the derivative via:\n//    (current - previous ) / ( time_difference / unit)\n//\n// The derivative is computed for each point, and\n// because of boundary conditions the first point is\n// dropped.\ntype DerivativeNode struct {\n\tchainnode\n\n\t// The field to use when calculating the derivative\n\t// tick:ignore\n\tField string\n\n\t// The new name of the derivative field.\n\t// Default is the name of the field used\n\t// when calculating the derivative.\n\tAs string\n\n\t// The time unit of the resulting derivative value.\n\t// Default: 1s\n\tUnit time.Duration\n\n\t// Where negative values are acceptable.\n\t// tick:ignore\n\tNonNegativeFlag bool `tick:\"NonNegative\"`\n}\n\nfunc newDerivativeNode(wants EdgeType, field string) *DerivativeNode {\n\treturn &DerivativeNode{\n\t\tchainnode: newBasicChainNode(\"derivative\", wants, wants),\n\t\tUnit:      time.Second,\n\t\tField:     field,\n\t\tAs:        field,\n\t}\n}\n\n// If called the derivative will skip negative results.\n// tick:property\nfunc (d *DerivativeNode) NonNegative() *DerivativeNode {\n\td.NonNegativeFlag = true\n\treturn d\n}\n",
+	"doc.go":                "/*\n\tProvides an API for constructing data processing pipelines.\n\n\tThe nodes defined in this package just define how nodes can be linked together not the actual implementation of the transformation functions.\n*/\npackage pipeline\n",
+	"eval.go":               "package pipeline\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\n// Evaluates expressions on each data point it receives.\n// A list of expressions may be provided and will be evaluated in the order they are given.\n// The results of expressions are available to later expressions in the list.\n// See the property EvalNode.As for details on how to reference the results.\n//\n// Example:\n//    stream\n//        |eval(lambda: \"error_count\" / \"total_count\")\n//          .as('error_percent')\n//\n// The above example will add a new field `error_percent` to each\n// data point with the result of `error_count / total_count` where\n// `error_count` and `total_count` are existing fields on the data point.\n//\n// An expression that results in a map or list, i.e. `jsonParse(\"message\")`, is flattened into\n// fields prefixed with its name, with nested keys and list indexes separated by a `.`.\n//\n// Example:\n//    stream\n//        |eval(lambda: jsonParse(\"message\"))\n//          .as('msg')\n//\n// The above example will add the fields `msg.level` and `msg.request.latency`\n// for the message `{\"level\": \"error\", \"request\": {\"latency\": 1.5}}`.\n//\n// Available Statistics:\n//\n//    * eval_errors -- number of errors evaluating any expressions.\n//\ntype EvalNode struct {\n\tchainnode\n\n\t// The name of the field that results from applying the expression.\n\t// tick:ignore\n\tAsList []string `tick:\"As\"`\n\n\t// The names of the expressions that should be converted to tags.\n\t// tick:ignore\n\tTagsList []string `tick:\"Tags\"`\n\n\t// tick:ignore\n\tLambdas []*ast.LambdaNode\n\n\t// tick:ignore\n\tKeepFlag bool `tick:\"Keep\"`\n\t// List of fields to keep\n\t// if empty and KeepFlag is true\n\t// keep all fields.\n\t// tick:ignore\n\tKeepList []string\n\n\t// tick:ignore\n\tQuietFlag bool `tick:\"Quiet\"`\n}\n\nfunc newEvalNode(e EdgeType, exprs []*ast.LambdaNode) *EvalNode {\n\tn := &EvalNode{\n\t\tchainnode: newBasicChainNode(\"eval\", e, e),\n\t\tLambdas:   exprs,\n\t}\n\treturn n\n}\n\nfunc (e *EvalNode) validate() error {\n\tif asLen, lambdaLen := len(e.AsList), len(e.Lambdas); asLen != lambdaLen {\n\t\treturn fmt.Errorf(\"must specify same number of expressions and .as() names: got %d as names, and %d expressions.\", asLen, lambdaLen)\n\t}\n\t// Validate tag names exist in As names list.\n\tfor _, tag := range e.TagsList {\n\t\tfound := false\n\t\tfor _, as := range e.AsList {\n\t\t\tif tag == as {\n\t\t\t\tfound = true\n\t\t\t\tbreak\n\t\t\t}\n\t\t}\n\t\tif !found {\n\t\t\treturn fmt.Errorf(\"invalid tag name %q, name is not present is .as() names\", tag)\n\t\t}\n\t}\n\treturn nil\n}\n\n// List of names for each expression.\n// The expressions are evaluated in order. The result\n// of an expression may be referenced by later expressions\n// via the name provided.\n//\n// Example:\n//    stream\n//        |eval(lambda: \"value\" * \"value\", lambda: 1.0 / \"value2\")\n//            .as('value2', 'inv_value2')\n//\n// The above example calculates two fields from the value and names them\n// `value2` and `inv_value2` respectively.\n//\n// tick:property\nfunc (e *EvalNode) As(names ...string) *EvalNode {\n\te.AsList = names\n\treturn e\n}\n\n// Convert the result of an expression into a tag.\n// The result must be a string.\n// Use the `string()` expression function to convert types.\n//\n//\n// Example:\n//    stream\n//        |eval(lambda: string(floor(\"value\" / 10.0)))\n//            .as('value_bucket')\n//            .tags('value_bucket')\n//\n// The above example calculates an expression from the field `value`, casts it as a string, and names it `value_bucket`.\n// The `value_bucket` expression is then converted from a field on the point to a tag `value_bucket` on the point.\n//\n// Example:\n//    stream\n//        |eval(lambda: string(floor(\"value\" / 10.0)))\n//            .as('value_bucket')\n//            .tags('value_bucket')\n//            .keep('value') // keep the original field `value` as well\n//\n// The above example calculates an expression from the field `value`, casts it as a string, and names it `value_bucket`.\n// The `value_bucket` expression is then converted from a field on the point to a tag `value_bucket` on the point.\n// The `keep` property preserves the original field `value`.\n// Tags are always kept since creating a tag implies you want to keep it.\n//\n// tick:property\nfunc (e *EvalNode) Tags(names ...string) *EvalNode {\n\te.TagsList = names\n\treturn e\n}\n\n// If called the existing fields will be preserved in addition\n// to the new fields being set.\n// If not called then only new fields are preserved. (Tags are\n// always preserved regardless how `keep` is used.)\n//\n// Optionally, intermediate values can be discarded\n// by passing a list of field names to be kept.\n// Only fields in the list will be retained, the rest will be discarded.\n// If no list is given then all fields are retained.\n//\n// Example:\n//    stream\n//        |eval(lambda: \"value\" * \"value\", lambda: 1.0 / \"value2\")\n//            .as('value2', 'inv_value2')\n//            .keep('value', 'inv_value2')\n//\n// In the above example the original field `value` is preserved.\n// The new field `value2` is calculated and used in evaluating\n// `inv_value2` but is discarded before the point is sent on to child nodes.\n// The resulting point has only two fields: `value` and `inv_value2`.\n//\n// tick:property\nfunc (e *EvalNode) Keep(fields ...string) *EvalNode {\n\te.KeepFlag = true\n\te.KeepList = fields\n\treturn e\n}\n\n// Suppress errors during evaluation.\n// tick:property\nfunc (e *EvalNode) Quiet() *EvalNode {\n\te.QuietFlag = true\n\treturn e\n}\n",
+	"fill.go":               "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\t\"time\"\n)\n\n// Fill methods supported by the FillNode.\nconst (\n\t// Linearly interpolate between the previous and next point.\n\tFillLinear = \"linear\"\n\t// Carry forward the fields of the previous point.\n\tFillPrevious = \"previous\"\n\t// Set the fields to null.\n\tFillNull = \"null\"\n\t// Set the fields to a given value.\n\tFillValue = \"value\"\n)\n\n// A FillNode resamples the data of each group onto a regular time grid.\n//\n// Points whose time is on the grid are passed through unchanged.\n// For each grid time without a point a synthetic point is created from\n// the surrounding points using the fill method.\n// Points whose time is not on the grid are only used to compute synthetic points.\n// The grid is aligned with the zero time, i.e. when every is 10s grid times are\n// 00:00:00, 00:00:10, 00:00:20 etc.\n//\n// The available fill methods are:\n//\n//    * linear   -- Linearly interpolate numeric fields between the previous and next point.\n//                  Non numeric fields are carried forward from the previous point.\n//    * previous -- Carry forward the fields of the previous point.\n//    * null     -- Set all fields to null. Null fields are not written by influxDBOut.\n//    * value    -- Set all fields to the given value.\n//\n// Example:\n//    var fast = stream\n//        |from()\n//            .measurement('cpu')\n//            .where(lambda: \"interval\" == '10s')\n//    var slow = stream\n//        |from()\n//            .measurement('cpu')\n//            .where(lambda: \"interval\" == '60s')\n//        |fill()\n//            .every(10s)\n//            .method('linear')\n//            .tag('synthetic')\n//    fast\n//        |join(slow)\n//            .as('fast', 'slow')\n//\n// Synthetic points are tagged with synthetic=true.\n//\n// On a batch edge each batch is resampled independently, starting at the first\n// grid time of the batch.\n//\n// Gaps that would need more than limit synthetic points are not filled,\n// the grid continues at the next point after the gap.\n//\n// NOTE: On a stream edge synthetic points can only be created once the\n// next point of the group has arrived.\ntype FillNode struct {\n\tchainnode\n\n\t// The interval of the time grid.\n\tEvery time.Duration\n\n\t// The fill method, one of 'linear', 'previous', 'null' or 'value'.\n\t// Default: 'linear'\n\t// tick:ignore\n\tFillMethod string `tick:\"Method\"`\n\n\t// The value used by the 'value' fill method.\n\t// tick:ignore\n\tFillValue interface{}\n\n\t// The name of a tag that is set to 'true' on synthetic points.\n\t// If empty synthetic points are not tagged.\n\tTag string\n\n\t// The maximum number of synthetic points created for a single gap.\n\t// Larger gaps are not filled.\n\t// Default: 1000\n\tLimit int64\n}\n\nfunc newFillNode(wants EdgeType) *FillNode {\n\treturn &FillNode{\n\t\tchainnode:  newBasicChainNode(\"fill\", wants, wants),\n\t\tFillMethod: FillLinear,\n\t\tLimit:      1000,\n\t}\n}\n\n// Set the fill method.\n// The 'value' method requires the fill value as its second argument.\n//\n// Example:\n//    |fill()\n//        .every(10s)\n//        .method('value', 0.0)\n//\n// tick:property\nfunc (n *FillNode) Method(method string, value ...interface{}) *FillNode {\n\tn.FillMethod = method\n\tn.FillValue = nil\n\tif len(value) > 0 {\n\t\tn.FillValue = value[0]\n\t}\n\tif len(value) > 1 {\n\t\tpanic(fmt.Sprintf(\"method accepts at most one fill value, got %d\", len(value)))\n\t}\n\treturn n\n}\n\nfunc (n *FillNode) validate() error {\n\tif n.Every <= 0 {\n\t\treturn errors.New(\"fill every must be greater than zero\")\n\t}\n\tif n.Limit <= 0 {\n\t\treturn errors.New(\"fill limit must be greater than zero\")\n\t}\n\tswitch n.FillMethod {\n\tcase FillLinear, FillPrevious, FillNull:\n\t\tif n.FillValue != nil {\n\t\t\treturn fmt.Errorf(\"fill method %q does not accept a value\", n.FillMethod)\n\t\t}\n\tcase FillValue:\n\t\tif n.FillValue == nil {\n\t\t\treturn errors.New(\"fill method 'value' requires a value\")\n\t\t}\n\tdefault:\n\t\treturn fmt.Errorf(\"invalid fill method %q, must be one of 'linear', 'previous', 'null' or 'value'\", n.FillMethod)\n\t}\n\treturn nil\n}\n",
+	"fingerprint.go":        "package pipeline\n\nimport (\n\t\"bytes\"\n\t\"crypto/sha256\"\n\t\"encoding/hex\"\n\t\"fmt\"\n\t\"reflect\"\n\t\"sort\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\nvar (\n\tnodeType     = reflect.TypeOf((*Node)(nil)).Elem()\n\tastNodeType  = reflect.TypeOf((*ast.Node)(nil)).Elem()\n\tstringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()\n)\n\n// Fingerprint returns a digest of the properties of the node.\n// Nodes of the same kind with the same properties have the same fingerprint,\n// the parents and children of the node and the position of its definition do not change it.\nfunc Fingerprint(n Node) string {\n\tvar buf bytes.Buffer\n\tbuf.WriteString(n.Desc())\n\twriteFingerprint(&buf, reflect.ValueOf(n), true)\n\tsum := sha256.Sum256(buf.Bytes())\n\treturn hex.EncodeToString(sum[:])\n}\n\n// writeFingerprint writes the exported properties of v to buf.\n// Other nodes referenced by v are only written by name, which breaks the cycles of the pipeline.\nfunc writeFingerprint(buf *bytes.Buffer, v reflect.Value, root bool) {\n\tif !v.IsValid() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {\n\t\tbuf.WriteString(\"nil;\")\n\t\treturn\n\t}\n\tif v.CanInterface() {\n\t\tt := v.Type()\n\t\tswitch {\n\t\tcase !root && t.Implements(nodeType):\n\t\t\tfmt.Fprintf(buf, \"%s;\", v.Interface().(Node).Name())\n\t\t\treturn\n\t\tcase t.Implements(astNodeType):\n\t\t\tn := v.Interface().(ast.Node)\n\t\t\tif l, ok := n.(*ast.LambdaNode); ok {\n\t\t\t\t// Comments do not change the lambda.\n\t\t\t\tn = l.Expression\n\t\t\t}\n\t\t\tfmt.Fprintf(buf, \"%s;\", ast.Format(n))\n\t\t\treturn\n\t\tcase !root && t.Implements(stringerType):\n\t\t\tfmt.Fprintf(buf, \"%s;\", v.Interface().(fmt.Stringer).String())\n\t\t\treturn\n\t\t}\n\t}\n\tswitch v.Kind() {\n\tcase reflect.Ptr, reflect.Interface:\n\t\twriteFingerprint(buf, v.Elem(), root)\n\tcase reflect.Struct:\n\t\tt := v.Type()\n\t\tbuf.WriteString(\"{\")\n\t\tfor i := 0; i < t.NumField(); i++ {\n\t\t\tf := t.Field(i)\n\t\t\tif f.PkgPath != \"\" {\n\t\t\t\t// Unexported fields, including the embedded node, are not properties.\n\t\t\t\tcontinue\n\t\t\t}\n\t\t\tbuf.WriteString(f.Name)\n\t\t\tbuf.WriteString(\":\")\n\t\t\twriteFingerprint(buf, v.Field(i), false)\n\t\t}\n\t\tbuf.WriteString(\"}\")\n\tcase reflect.Slice, reflect.Array:\n\t\tbuf.WriteString(\"[\")\n\t\tfor i := 0; i < v.Len(); i++ {\n\t\t\twriteFingerprint(buf, v.Index(i), false)\n\t\t}\n\t\tbuf.WriteString(\"]\")\n\tcase reflect.Map:\n\t\tkeys := make([]string, 0, v.Len())\n\t\tvalues := make(map[string]reflect.Value, v.Len())\n\t\tfor _, k := range v.MapKeys() {\n\t\t\tkey := fmt.Sprint(k.Interface())\n\t\t\tkeys = append(keys, key)\n\t\t\tvalues[key] = v.MapIndex(k)\n\t\t}\n\t\tsort.Strings(keys)\n\t\tbuf.WriteString(\"{\")\n\t\tfor _, k := range keys {\n\t\t\tfmt.Fprintf(buf, \"%q:\", k)\n\t\t\twriteFingerprint(buf, values[k], false)\n\t\t}\n\t\tbuf.WriteString(\"}\")\n\tcase reflect.Func, reflect.Chan, reflect.UnsafePointer:\n\t\t// Not a property.\n\t\tbuf.WriteString(\"-;\")\n\tdefault:\n\t\tfmt.Fprintf(buf, \"%#v;\", v.Interface())\n\t}\n}\n",
+	"flatten.go":            "package pipeline\n\nimport \"time\"\n\nconst (\n\tdefaultFlattenDelimiter = \".\"\n)\n\n// Flatten a set of points on specific dimensions.\n// For example given two points:\n//\n// m,host=A,port=80 bytes=3512\n// m,host=A,port=443 bytes=6723\n//\n// Flattening the points on `port` would result in a single point:\n//\n// m,host=A 80.bytes=3512,443.bytes=6723\n//\n// Example:\n//        |flatten()\n//            .on('port')\n//\n// If flattening on multiple dimensions the order is preserved:\n//\n// m,host=A,port=80 bytes=3512\n// m,host=A,port=443 bytes=6723\n// m,host=B,port=443 bytes=7243\n//\n// Flattening the points on `host` and `port` would result in a single point:\n//\n// m A.80.bytes=3512,A.443.bytes=6723,B.443.bytes=7243\n//\n// Example:\n//        |flatten()\n//            .on('host', 'port')\n//\n//\n// Since flattening points creates dynamically named fields in general it is expected\n// that the resultant data is passed to a UDF or similar for custom processing.\ntype FlattenNode struct {\n\tchainnode\n\n\t// The dimensions on which to join\n\t// tick:ignore\n\tDimensions []string `tick:\"On\"`\n\n\t// The delimiter between field name parts\n\tDelimiter string\n\n\t// The maximum duration of time that two incoming points\n\t// can be apart and still be considered to be equal in time.\n\t// The joined data point's time will be rounded to the nearest\n\t// multiple of the tolerance duration.\n\tTolerance time.Duration\n\n\t// DropOriginalFieldNameFlag indicates whether the original field name should\n\t// be included in the final field name.\n\t//tick:ignore\n\tDropOriginalFieldNameFlag bool `tick:\"DropOriginalFieldName\"`\n}\n\nfunc newFlattenNode(e EdgeType) *FlattenNode {\n\tf := &FlattenNode{\n\t\tchainnode: newBasicChainNode(\"flatten\", e, e),\n\t\tDelimiter: defaultFlattenDelimiter,\n\t}\n\treturn f\n}\n\n// Specify the dimensions on which to flatten the points.\n// tick:property\nfunc (f *FlattenNode) On(dims ...string) *FlattenNode {\n\tf.Dimensions = dims\n\treturn f\n}\n\n// DropOriginalFieldName indicates whether the original field name should\n// be dropped when constructing the final field name.\n// tick:property\nfunc (f *FlattenNode) DropOriginalFieldName(drop ...bool) *FlattenNode {\n\tif len(drop) == 1 {\n\t\tf.DropOriginalFieldNameFlag = drop[0]\n\t} else {\n\t\tf.DropOriginalFieldNameFlag = true\n\t}\n\treturn f\n}\n",
+	"group_by.go":           "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\n// A GroupByNode will group the incoming data.\n// Each group is then processed independently for the rest of the pipeline.\n// Only tags that are dimensions in the grouping will be preserved;\n// all other tags are dropped.\n//\n// Example:\n//    stream\n//        |groupBy('service', 'datacenter')\n//        ...\n//\n// The above example groups the data along two dimensions `service` and `datacenter`.\n// Groups are dynamically created as new data arrives and each group is processed\n// independently.\ntype GroupByNode struct {\n\tchainnode\n\t//The dimensions by which to group to the data.\n\t// tick:ignore\n\tDimensions []interface{}\n\n\t// The dimensions to exclude.\n\t// Useful for substractive tags from using *.\n\t// tick:ignore\n\tExcludedDimensions []string `tick:\"Exclude\"`\n\n\t// Whether to include the measurement in the group ID.\n\t// tick:ignore\n\tByMeasurementFlag bool `tick:\"ByMeasurement\"`\n}\n\nfunc newGroupByNode(wants EdgeType, dims []interface{}) *GroupByNode {\n\treturn &GroupByNode{\n\t\tchainnode:  newBasicChainNode(\"groupby\", wants, wants),\n\t\tDimensions: dims,\n\t}\n}\n\nfunc (n *GroupByNode) validate() error {\n\treturn validateDimensions(n.Dimensions, n.ExcludedDimensions)\n}\n\nfunc validateDimensions(dimensions []interface{}, excludedDimensions []string) error {\n\thasStar := false\n\tfor _, d := range dimensions {\n\t\tswitch dim := d.(type) {\n\t\tcase string:\n\t\t\tif len(dim) == 0 {\n\t\t\t\treturn errors.New(\"dimensions cannot not be the empty string\")\n\t\t\t}\n\t\tcase *ast.StarNode:\n\t\t\thasStar = true\n\t\tdefault:\n\t\t\treturn fmt.Errorf(\"invalid dimension object of type %T\", d)\n\t\t}\n\t}\n\tif hasStar && len(dimensions) > 1 {\n\t\treturn errors.New(\"cannot group by both '*' and named dimensions.\")\n\t}\n\tif !hasStar && len(excludedDimensions) > 0 {\n\t\treturn errors.New(\"exclude requires '*'\")\n\t}\n\treturn nil\n}\n\n// If set will include the measurement name in the group ID.\n// Along with any other group by dimensions.\n//\n// Example:\n//     ...\n//     |groupBy('host')\n//         .byMeasurement()\n//\n// The above example groups points by their host tag and measurement name.\n//\n// If you want to remove the measurement name from the group ID,\n// then groupBy all existing dimensions but without specifying 'byMeasurement'.\n//\n// Example:\n//    |groupBy(*)\n//\n// The above removes the group by measurement name if any.\n// tick:property\nfunc (n *GroupByNode) ByMeasurement() *GroupByNode {\n\tn.ByMeasurementFlag = true\n\treturn n\n}\n\n// Exclude removes any tags from the group.\nfunc (n *GroupByNode) Exclude(dims ...string) *GroupByNode {\n\tn.ExcludedDimensions = append(n.ExcludedDimensions, dims...)\n\treturn n\n}\n",
+	"histogram.go":          "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\t\"math\"\n\t\"sort\"\n)\n\n// A HistogramNode computes the distribution of a field's values over a set of buckets.\n// The histogram is computed over each batch, producing a single point per group.\n//\n// Each bucket is identified by its upper bound and counts the number of values\n// less than or equal to that bound, i.e. the buckets are cumulative.\n// An additional `le_inf` bucket counts all values.\n// The `sum` and `count` fields contain the sum and number of values respectively.\n//\n// Example:\n//    stream\n//        |from()\n//            .measurement('requests')\n//        |window()\n//            .period(1m)\n//            .every(1m)\n//        |histogram('latency')\n//            .buckets(0.1, 0.5, 1, 5)\n//        |influxDBOut()\n//            .database('mydb')\n//            .measurement('latency_histogram')\n//\n// Each emitted point has the fields `le_0.1`, `le_0.5`, `le_1`, `le_5`, `le_inf`, `sum` and `count`.\n//\n// Buckets can also be defined as an exponential series using the exponentialBuckets property.\n//\n// Example:\n//    batch\n//        |query('SELECT latency FROM \"mydb\".\"autogen\".\"requests\"')\n//            .period(1m)\n//            .every(1m)\n//        |histogram('latency')\n//            .exponentialBuckets(0.01, 2.0, 10)\n//\n// NOTE: Histogram can only be applied to batch edges, use a window node on stream edges.\ntype HistogramNode struct {\n\tchainnode\n\n\t// The field to use when calculating the histogram\n\t// tick:ignore\n\tField string\n\n\t// The upper bounds of the buckets, sorted in increasing order.\n\t// tick:ignore\n\tBounds []float64 `tick:\"Buckets\"`\n\n\t// The parameters used to generate exponential buckets, if any.\n\t// tick:ignore\n\tExponential *ExponentialBuckets `tick:\"ExponentialBuckets\"`\n\n\t// Prefix of the bucket field names.\n\t// Default: le_\n\tPrefix string\n}\n\nfunc newHistogramNode(field string) *HistogramNode {\n\treturn &HistogramNode{\n\t\tchainnode: newBasicChainNode(\"histogram\", BatchEdge, StreamEdge),\n\t\tField:     field,\n\t\tPrefix:    \"le_\",\n\t}\n}\n\n// Set the upper bounds of the buckets.\n// Bounds can be any mix of float and integer values.\n// tick:property\nfunc (n *HistogramNode) Buckets(bounds ...interface{}) *HistogramNode {\n\tn.Exponential = nil\n\tn.Bounds = make([]float64, len(bounds))\n\tfor i, b := range bounds {\n\t\tswitch v := b.(type) {\n\t\tcase float64:\n\t\t\tn.Bounds[i] = v\n\t\tcase int64:\n\t\t\tn.Bounds[i] = float64(v)\n\t\tdefault:\n\t\t\tpanic(fmt.Sprintf(\"bucket bounds must be float or int values, got %T\", b))\n\t\t}\n\t}\n\tsort.Float64s(n.Bounds)\n\treturn n\n}\n\n// Set count bucket bounds where the first bound is start\n// and each following bound is the previous bound multiplied by factor.\n// tick:property\nfunc (n *HistogramNode) ExponentialBuckets(start, factor interface{}, count int64) *HistogramNode {\n\ts, ok := histogramFloat(start)\n\tif !ok {\n\t\tpanic(fmt.Sprintf(\"start must be a float or int value, got %T\", start))\n\t}\n\tf, ok := histogramFloat(factor)\n\tif !ok {\n\t\tpanic(fmt.Sprintf(\"factor must be a float or int value, got %T\", factor))\n\t}\n\tif count < 1 {\n\t\tpanic(\"count must be greater than zero\")\n\t}\n\tn.Exponential = &ExponentialBuckets{\n\t\tStart:  s,\n\t\tFactor: f,\n\t\tCount:  count,\n\t}\n\tn.Bounds = make([]float64, count)\n\tfor i := range n.Bounds {\n\t\tn.Bounds[i] = s\n\t\ts *= f\n\t}\n\treturn n\n}\n\n// ExponentialBuckets describes a series of bucket bounds\n// where each bound is the previous bound multiplied by Factor.\ntype ExponentialBuckets struct {\n\tStart  float64\n\tFactor float64\n\tCount  int64\n}\n\nfunc histogramFloat(v interface{}) (float64, bool) {\n\tswitch n := v.(type) {\n\tcase float64:\n\t\treturn n, true\n\tcase int64:\n\t\treturn float64(n), true\n\tdefault:\n\t\treturn 0, false\n\t}\n}\n\nfunc (n *HistogramNode) validate() error {\n\tif len(n.Bounds) == 0 {\n\t\treturn errors.New(\"must specify buckets or exponentialBuckets for histogram\")\n\t}\n\tif n.Exponential != nil && (n.Exponential.Start <= 0 || n.Exponential.Factor <= 1) {\n\t\treturn errors.New(\"exponential buckets must have a start greater than zero and a factor greater than one\")\n\t}\n\tfor i, b := range n.Bounds {\n\t\tif math.IsNaN(b) || math.IsInf(b, 0) {\n\t\t\treturn fmt.Errorf(\"invalid bucket bound %v\", b)\n\t\t}\n\t\tif i > 0 && b <= n.Bounds[i-1] {\n\t\t\treturn fmt.Errorf(\"bucket bounds must be strictly increasing, got %v after %v\", b, n.Bounds[i-1])\n\t\t}\n\t}\n\treturn nil\n}\n",
+	"http_out.go":           "package pipeline\n\n// An HTTPOutNode caches the most recent data for each group it has received.\n//\n// The cached data is available at the given endpoint.\n// The endpoint is the relative path from the API endpoint of the running task.\n// For example if the task endpoint is at `/kapacitor/v1/tasks/<task_id>` and endpoint is\n// `top10`, then the data can be requested from `/kapacitor/v1/tasks/<task_id>/top10`.\n//\n// Example:\n//    stream\n//        |window()\n//            .period(10s)\n//            .every(5s)\n//        |top('value', 10)\n//        //Publish the top 10 results over the last 10s updated every 5s.\n//        |httpOut('top10')\n//\ntype HTTPOutNode struct {\n\tchainnode\n\n\t// The relative path where the cached data is exposed\n\t// tick:ignore\n\tEndpoint string\n}\n\nfunc newHTTPOutNode(wants EdgeType, endpoint string) *HTTPOutNode {\n\treturn &HTTPOutNode{\n\t\tchainnode: newBasicChainNode(\"http_out\", wants, wants),\n\t\tEndpoint:  endpoint,\n\t}\n}\n",
+	"http_post.go":          "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\t\"strings\"\n)\n\n// An HTTPPostNode will take the incoming data stream and POST it to an HTTP endpoint.\n// That endpoint may be specified as a positional argument, or as an endpoint property\n// method on httpPost. Multiple endpoint property methods may be specified.\n//\n// Example:\n//    stream\n//        |window()\n//            .period(10s)\n//            .every(5s)\n//        |top('value', 10)\n//        //Post the top 10 results over the last 10s updated every 5s.\n//        |httpPost('http://example.com/api/top10')\n//\n// Example:\n//    stream\n//        |window()\n//            .period(10s)\n//            .every(5s)\n//        |top('value', 10)\n//        //Post the top 10 results over the last 10s updated every 5s.\n//        |httpPost()\n//            .endpoint('example')\n//\ntype HTTPPostNode struct {\n\tchainnode\n\n\t// tick:ignore\n\tEndpoints []string `tick:\"Endpoint\"`\n\n\t// Headers\n\tHeaders map[string]string `tick:\"Header\"`\n\n\t// tick:ignore\n\tURLs []string\n}\n\nfunc newHTTPPostNode(wants EdgeType, urls ...string) *HTTPPostNode {\n\treturn &HTTPPostNode{\n\t\tchainnode: newBasicChainNode(\"http_post\", wants, wants),\n\t\tURLs:      urls,\n\t}\n}\n\n// tick:ignore\nfunc (p *HTTPPostNode) validate() error {\n\tif len(p.URLs) >= 2 {\n\t\treturn fmt.Errorf(\"httpPost expects 0 or 1 arguments, got %v\", len(p.URLs))\n\t}\n\n\tif len(p.Endpoints) > 1 {\n\t\treturn fmt.Errorf(\"httpPost expects 0 or 1 endpoints, got %v\", len(p.Endpoints))\n\t}\n\n\tif len(p.URLs) == 0 && len(p.Endpoints) == 0 {\n\t\treturn errors.New(\"must provide url or endpoint\")\n\t}\n\n\tif len(p.URLs) > 0 && len(p.Endpoints) > 0 {\n\t\treturn errors.New(\"only one endpoint and url may be specified\")\n\t}\n\n\tfor k := range p.Headers {\n\t\tif strings.ToUpper(k) == \"AUTHENTICATE\" {\n\t\t\treturn errors.New(\"cannot set 'authenticate' header\")\n\t\t}\n\t}\n\n\treturn nil\n}\n\n// Name of the endpoint to be used, as is defined in the configuration file.\n//\n// Example:\n//    stream\n//         |httpPost()\n//            .endpoint('example')\n//\n// tick:property\nfunc (p *HTTPPostNode) Endpoint(endpoint string) *HTTPPostNode {\n\tp.Endpoints = append(p.Endpoints, endpoint)\n\treturn p\n}\n\n// Example:\n//    stream\n//         |httpPost()\n//            .endpoint('example')\n//              .header('my', 'header')\n//\n// tick:property\nfunc (p *HTTPPostNode) Header(k, v string) *HTTPPostNode {\n\tif p.Headers == nil {\n\t\tp.Headers = map[string]string{}\n\t}\n\tp.Headers[k] = v\n\n\treturn p\n}\n",
+	"influxdb_out.go":       "package pipeline\n\nimport \"time\"\n\nconst DefaultBufferSize = 1000\nconst DefaultFlushInterval = time.Second * 10\n\n// Writes the data to InfluxDB as it is received.\n//\n// Example:\n//    stream\n//        |from()\n//            .measurement('requests')\n//        |eval(lambda: \"errors\" / \"total\")\n//            .as('error_percent')\n//        // Write the transformed data to InfluxDB\n//        |influxDBOut()\n//            .database('mydb')\n//            .retentionPolicy('myrp')\n//            .measurement('errors')\n//            .tag('kapacitor', 'true')\n//            .tag('version', '0.2')\n//\n// Available Statistics:\n//\n//    * points_written -- number of points written to InfluxDB\n//    * write_errors -- number of errors attempting to write to InfluxDB\n//\ntype InfluxDBOutNode struct {\n\tnode\n\n\t// The name of the InfluxDB instance to connect to.\n\t// If empty the configured default will be used.\n\tCluster string\n\t// The name of the database.\n\tDatabase string\n\t// The name of the retention policy.\n\tRetentionPolicy string\n\t// The name of the measurement.\n\tMeasurement string\n\t// The write consistency to use when writing the data.\n\tWriteConsistency string\n\t// The precision to use when writing the data.\n\tPrecision string\n\t// Number of points to buffer when writing to InfluxDB.\n\t// Default: 1000\n\tBuffer int64\n\t// Write points to InfluxDB after interval even if buffer is not full.\n\t// Default: 10s\n\tFlushInterval time.Duration\n\t// Static set of tags to add to all data points before writing them.\n\t// tick:ignore\n\tTags map[string]string `tick:\"Tag\"`\n\t// Create the specified database and retention policy\n\t// tick:ignore\n\tCreateFlag bool `tick:\"Create\"`\n}\n\nfunc newInfluxDBOutNode(wants EdgeType) *InfluxDBOutNode {\n\treturn &InfluxDBOutNode{\n\t\tnode: node{\n\t\t\tdesc:     \"influxdb_out\",\n\t\t\twants:    wants,\n\t\t\tprovides: NoEdge,\n\t\t},\n\t\tTags:          make(map[string]string),\n\t\tBuffer:        DefaultBufferSize,\n\t\tFlushInterval: DefaultFlushInterval,\n\t}\n}\n\n// Add a static tag to all data points.\n// Tag can be called more than once.\n//\n// tick:property\nfunc (i *InfluxDBOutNode) Tag(key, value string) *InfluxDBOutNode {\n\ti.Tags[key] = value\n\treturn i\n}\n\n// Create indicates that both the database and retention policy\n// will be created, when the task is started.\n// If the retention policy name is empty than no\n// retention policy will be specified and\n// the default retention policy name will be created.\n//\n// If the database already exists nothing happens.\n//\n// tick:property\nfunc (i *InfluxDBOutNode) Create() *InfluxDBOutNode {\n\ti.CreateFlag = true\n\treturn i\n}\n",
+	"influxql.gen.go":       "// Generated by tmpl\n// https://github.com/benbjohnson/tmpl\n//\n// DO NOT EDIT!\n// Source: influxql.gen.go.tmpl\n\npackage pipeline\n\nimport \"github.com/influxdata/influxdb/influxql\"\n\n//tick:ignore\ntype ReduceCreater struct {\n\tCreateFloatReducer     func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter)\n\tCreateFloatBulkReducer func() (FloatBulkPointAggregator, influxql.FloatPointEmitter)\n\n\tCreateFloatIntegerReducer     func() (influxql.FloatPointAggregator, influxql.IntegerPointEmitter)\n\tCreateFloatBulkIntegerReducer func() (FloatBulkPointAggregator, influxql.IntegerPointEmitter)\n\n\tCreateFloatStringReducer     func() (influxql.FloatPointAggregator, influxql.StringPointEmitter)\n\tCreateFloatBulkStringReducer func() (FloatBulkPointAggregator, influxql.StringPointEmitter)\n\n\tCreateFloatBooleanReducer     func() (influxql.FloatPointAggregator, influxql.BooleanPointEmitter)\n\tCreateFloatBulkBooleanReducer func() (FloatBulkPointAggregator, influxql.BooleanPointEmitter)\n\n\tCreateIntegerFloatReducer     func() (influxql.IntegerPointAggregator, influxql.FloatPointEmitter)\n\tCreateIntegerBulkFloatReducer func() (IntegerBulkPointAggregator, influxql.FloatPointEmitter)\n\n\tCreateIntegerReducer     func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter)\n\tCreateIntegerBulkReducer func() (IntegerBulkPointAggregator, influxql.IntegerPointEmitter)\n\n\tCreateIntegerStringReducer     func() (influxql.IntegerPointAggregator, influxql.StringPointEmitter)\n\tCreateIntegerBulkStringReducer func() (IntegerBulkPointAggregator, influxql.StringPointEmitter)\n\n\tCreateIntegerBooleanReducer     func() (influxql.IntegerPointAggregator, influxql.BooleanPointEmitter)\n\tCreateIntegerBulkBooleanReducer func() (IntegerBulkPointAggregator, influxql.BooleanPointEmitter)\n\n\tCreateStringFloatReducer     func() (influxql.StringPointAggregator, influxql.FloatPointEmitter)\n\tCreateStringBulkFloatReducer func() (StringBulkPointAggregator, influxql.FloatPointEmitter)\n\n\tCreateStringIntegerReducer     func() (influxql.StringPointAggregator, influxql.IntegerPointEmitter)\n\tCreateStringBulkIntegerReducer func() (StringBulkPointAggregator, influxql.IntegerPointEmitter)\n\n\tCreateStringReducer     func() (influxql.StringPointAggregator, influxql.StringPointEmitter)\n\tCreateStringBulkReducer func() (StringBulkPointAggregator, influxql.StringPointEmitter)\n\n\tCreateStringBooleanReducer     func() (influxql.StringPointAggregator, influxql.BooleanPointEmitter)\n\tCreateStringBulkBooleanReducer func() (StringBulkPointAggregator, influxql.BooleanPointEmitter)\n\n\tCreateBooleanFloatReducer     func() (influxql.BooleanPointAggregator, influxql.FloatPointEmitter)\n\tCreateBooleanBulkFloatReducer func() (BooleanBulkPointAggregator, influxql.FloatPointEmitter)\n\n\tCreateBooleanIntegerReducer     func() (influxql.BooleanPointAggregator, influxql.IntegerPointEmitter)\n\tCreateBooleanBulkIntegerReducer func() (BooleanBulkPointAggregator, influxql.IntegerPointEmitter)\n\n\tCreateBooleanStringReducer     func() (influxql.BooleanPointAggregator, influxql.StringPointEmitter)\n\tCreateBooleanBulkStringReducer func() (BooleanBulkPointAggregator, influxql.StringPointEmitter)\n\n\tCreateBooleanReducer     func() (influxql.BooleanPointAggregator, influxql.BooleanPointEmitter)\n\tCreateBooleanBulkReducer func() (BooleanBulkPointAggregator, influxql.BooleanPointEmitter)\n\n\tTopBottomCallInfo      *TopBottomCallInfo\n\tIsSimpleSelector       bool\n\tIsStreamTransformation bool\n\tIsEmptyOK              bool\n}\n\ntype FloatBulkPointAggregator interface {\n\tinfluxql.FloatPointAggregator\n\tinfluxql.FloatBulkPointAggregator\n}\n\ntype IntegerBulkPointAggregator interface {\n\tinfluxql.IntegerPointAggregator\n\tinfluxql.IntegerBulkPointAggregator\n}\n\ntype StringBulkPointAggregator interface {\n\tinfluxql.StringPointAggregator\n\tinfluxql.StringBulkPointAggregator\n}\n\ntype BooleanBulkPointAggregator interface {\n\tinfluxql.BooleanPointAggregator\n\tinfluxql.BooleanBulkPointAggregator\n}\n",
+	"influxql.go":           "package pipeline\n\nimport (\n\t\"time\"\n\n\t\"github.com/influxdata/influxdb/influxql\"\n)\n\n// tmpl -- go get github.com/benbjohnson/tmpl\n//go:generate tmpl -data=@../tmpldata.json influxql.gen.go.tmpl\n\n// An InfluxQLNode performs the available function from the InfluxQL language.\n// These function can be performed on a stream or batch edge.\n// The resulting edge is dependent on the function.\n// For a stream edge, all points with the same time are accumulated into the function.\n// For a batch edge, all points in the batch are accumulated into the function.\n//\n//\n// Example:\n//    stream\n//        |window()\n//            .period(10s)\n//            .every(10s)\n//        // Sum the values for each 10s window of data.\n//        |sum('value')\n//\n//\n// Note: Derivative has its own implementation as a DerivativeNode instead of as part of the\n// InfluxQL functions.\ntype InfluxQLNode struct {\n\tchainnode\n\n\t// tick:ignore\n\tMethod string\n\t// tick:ignore\n\tField string\n\n\t// The name of the field, defaults to the name of\n\t// function used (i.e. .mean -> 'mean')\n\tAs string\n\n\t// tick:ignore\n\tReduceCreater ReduceCreater\n\n\t// tick:ignore\n\tPointTimes bool `tick:\"UsePointTimes\"`\n}\n\nfunc newInfluxQLNode(method, field string, wants, provides EdgeType, reducer ReduceCreater) *InfluxQLNode {\n\treturn &InfluxQLNode{\n\t\tchainnode:     newBasicChainNode(method, wants, provides),\n\t\tMethod:        method,\n\t\tField:         field,\n\t\tAs:            method,\n\t\tReduceCreater: reducer,\n\t}\n}\n\n// Use the time of the selected point instead of the time of the batch.\n//\n// Only applies to selector functions like first, last, top, bottom, etc.\n// Aggregation functions always use the batch time.\n// tick:property\nfunc (n *InfluxQLNode) UsePointTimes() *InfluxQLNode {\n\tn.PointTimes = true\n\treturn n\n}\n\n//------------------------------------\n// Aggregation Functions\n//\n\n// Count the number of points.\nfunc (n *chainnode) Count(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"count\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatIntegerReducer: func() (influxql.FloatPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewFloatFuncIntegerReducer(influxql.FloatCountReduce, &influxql.IntegerPoint{Value: 0})\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerFuncReducer(influxql.IntegerCountReduce, &influxql.IntegerPoint{Value: 0})\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateStringIntegerReducer: func() (influxql.StringPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewStringFuncIntegerReducer(influxql.StringCountReduce, &influxql.IntegerPoint{Value: 0})\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateBooleanIntegerReducer: func() (influxql.BooleanPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewBooleanFuncIntegerReducer(influxql.BooleanCountReduce, &influxql.IntegerPoint{Value: 0})\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsEmptyOK: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Produce batch of only the distinct points.\nfunc (n *chainnode) Distinct(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"distinct\", field, n.Provides(), BatchEdge, ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatDistinctReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerDistinctReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateStringReducer: func() (influxql.StringPointAggregator, influxql.StringPointEmitter) {\n\t\t\tfn := influxql.NewStringDistinctReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateBooleanReducer: func() (influxql.BooleanPointAggregator, influxql.BooleanPointEmitter) {\n\t\t\tfn := influxql.NewBooleanDistinctReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute the mean of the data.\nfunc (n *chainnode) Mean(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"mean\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatMeanReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerFloatReducer: func() (influxql.IntegerPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewIntegerMeanReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute the median of the data. Note, this method is not a selector,\n// if you want the median point use `.percentile(field, 50.0)`.\nfunc (n *chainnode) Median(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"median\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatBulkReducer: func() (FloatBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatSliceFuncReducer(influxql.FloatMedianReduceSlice)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerBulkFloatReducer: func() (IntegerBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewIntegerSliceFuncFloatReducer(influxql.IntegerMedianReduceSlice)\n\t\t\treturn fn, fn\n\t\t},\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute the mode of the data.\nfunc (n *chainnode) Mode(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"mode\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatBulkReducer: func() (FloatBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatSliceFuncReducer(influxql.FloatModeReduceSlice)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerBulkReducer: func() (IntegerBulkPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerSliceFuncReducer(influxql.IntegerModeReduceSlice)\n\t\t\treturn fn, fn\n\t\t},\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute the difference between `min` and `max` points.\nfunc (n *chainnode) Spread(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"spread\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatBulkReducer: func() (FloatBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatSliceFuncReducer(influxql.FloatSpreadReduceSlice)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerBulkReducer: func() (IntegerBulkPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerSliceFuncReducer(influxql.IntegerSpreadReduceSlice)\n\t\t\treturn fn, fn\n\t\t},\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute the sum of all values.\nfunc (n *chainnode) Sum(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"sum\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatFuncReducer(influxql.FloatSumReduce, &influxql.FloatPoint{Value: 0})\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerFuncReducer(influxql.IntegerSumReduce, &influxql.IntegerPoint{Value: 0})\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsEmptyOK: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n//------------------------------------\n// Selection Functions\n//\n\n// Select the first point.\nfunc (n *chainnode) First(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"first\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatFuncReducer(influxql.FloatFirstReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerFuncReducer(influxql.IntegerFirstReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateStringReducer: func() (influxql.StringPointAggregator, influxql.StringPointEmitter) {\n\t\t\tfn := influxql.NewStringFuncReducer(influxql.StringFirstReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateBooleanReducer: func() (influxql.BooleanPointAggregator, influxql.BooleanPointEmitter) {\n\t\t\tfn := influxql.NewBooleanFuncReducer(influxql.BooleanFirstReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsSimpleSelector: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Select the last point.\nfunc (n *chainnode) Last(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"last\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatFuncReducer(influxql.FloatLastReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerFuncReducer(influxql.IntegerLastReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateStringReducer: func() (influxql.StringPointAggregator, influxql.StringPointEmitter) {\n\t\t\tfn := influxql.NewStringFuncReducer(influxql.StringLastReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateBooleanReducer: func() (influxql.BooleanPointAggregator, influxql.BooleanPointEmitter) {\n\t\t\tfn := influxql.NewBooleanFuncReducer(influxql.BooleanLastReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsSimpleSelector: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Select the minimum point.\nfunc (n *chainnode) Min(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"min\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatFuncReducer(influxql.FloatMinReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerFuncReducer(influxql.IntegerMinReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsSimpleSelector: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Select the maximum point.\nfunc (n *chainnode) Max(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"max\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatFuncReducer(influxql.FloatMaxReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerFuncReducer(influxql.IntegerMaxReduce, nil)\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsSimpleSelector: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Select a point at the given percentile. This is a selector function, no interpolation between points is performed.\nfunc (n *chainnode) Percentile(field string, percentile float64) *InfluxQLNode {\n\ti := newInfluxQLNode(\"percentile\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatBulkReducer: func() (FloatBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatSliceFuncReducer(influxql.NewFloatPercentileReduceSliceFunc(percentile))\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerBulkReducer: func() (IntegerBulkPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerSliceFuncReducer(influxql.NewIntegerPercentileReduceSliceFunc(percentile))\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsSimpleSelector: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n//tick:ignore\ntype TopBottomCallInfo struct {\n\tFieldsAndTags []string\n}\n\n// Select the top `num` points for `field` and sort by any extra tags or fields.\nfunc (n *chainnode) Top(num int64, field string, fieldsAndTags ...string) *InfluxQLNode {\n\ttags := make([]int, len(fieldsAndTags))\n\tfor i := range fieldsAndTags {\n\t\ttags[i] = i\n\t}\n\ti := newInfluxQLNode(\"top\", field, n.Provides(), BatchEdge, ReduceCreater{\n\t\tCreateFloatBulkReducer: func() (FloatBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatSliceFuncReducer(influxql.NewFloatTopReduceSliceFunc(\n\t\t\t\tint(num),\n\t\t\t\ttags,\n\t\t\t\tinfluxql.Interval{},\n\t\t\t))\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerBulkReducer: func() (IntegerBulkPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerSliceFuncReducer(influxql.NewIntegerTopReduceSliceFunc(\n\t\t\t\tint(num),\n\t\t\t\ttags,\n\t\t\t\tinfluxql.Interval{},\n\t\t\t))\n\t\t\treturn fn, fn\n\t\t},\n\t\tTopBottomCallInfo: &TopBottomCallInfo{\n\t\t\tFieldsAndTags: fieldsAndTags,\n\t\t},\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Select the bottom `num` points for `field` and sort by any extra tags or fields.\nfunc (n *chainnode) Bottom(num int64, field string, fieldsAndTags ...string) *InfluxQLNode {\n\ttags := make([]int, len(fieldsAndTags))\n\tfor i := range fieldsAndTags {\n\t\ttags[i] = i\n\t}\n\ti := newInfluxQLNode(\"bottom\", field, n.Provides(), BatchEdge, ReduceCreater{\n\t\tCreateFloatBulkReducer: func() (FloatBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatSliceFuncReducer(influxql.NewFloatBottomReduceSliceFunc(\n\t\t\t\tint(num),\n\t\t\t\ttags,\n\t\t\t\tinfluxql.Interval{},\n\t\t\t))\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerBulkReducer: func() (IntegerBulkPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerSliceFuncReducer(influxql.NewIntegerBottomReduceSliceFunc(\n\t\t\t\tint(num),\n\t\t\t\ttags,\n\t\t\t\tinfluxql.Interval{},\n\t\t\t))\n\t\t\treturn fn, fn\n\t\t},\n\t\tTopBottomCallInfo: &TopBottomCallInfo{\n\t\t\tFieldsAndTags: fieldsAndTags,\n\t\t},\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n//------------------------------------\n// Transformation Functions\n//\n\n// Compute the standard deviation.\nfunc (n *chainnode) Stddev(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"stddev\", field, n.Provides(), StreamEdge, ReduceCreater{\n\t\tCreateFloatBulkReducer: func() (FloatBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatSliceFuncReducer(influxql.FloatStddevReduceSlice)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerBulkFloatReducer: func() (IntegerBulkPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewIntegerSliceFuncFloatReducer(influxql.IntegerStddevReduceSlice)\n\t\t\treturn fn, fn\n\t\t},\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute the elapsed time between points\nfunc (n *chainnode) Elapsed(field string, unit time.Duration) *InfluxQLNode {\n\ti := newInfluxQLNode(\"elapsed\", field, n.Provides(), n.Provides(), ReduceCreater{\n\t\tCreateFloatIntegerReducer: func() (influxql.FloatPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewFloatElapsedReducer(influxql.Interval{Duration: unit})\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerElapsedReducer(influxql.Interval{Duration: unit})\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateStringIntegerReducer: func() (influxql.StringPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewStringElapsedReducer(influxql.Interval{Duration: unit})\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateBooleanIntegerReducer: func() (influxql.BooleanPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewBooleanElapsedReducer(influxql.Interval{Duration: unit})\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsStreamTransformation: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute the difference between points independent of elapsed time.\nfunc (n *chainnode) Difference(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"difference\", field, n.Provides(), n.Provides(), ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatDifferenceReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerDifferenceReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsStreamTransformation: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute a moving average of the last window points.\n// No points are emitted until the window is full.\nfunc (n *chainnode) MovingAverage(field string, window int64) *InfluxQLNode {\n\ti := newInfluxQLNode(\"movingAverage\", field, n.Provides(), n.Provides(), ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatMovingAverageReducer(int(window))\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerFloatReducer: func() (influxql.IntegerPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewIntegerMovingAverageReducer(int(window))\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsStreamTransformation: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute the holt-winters (https://docs.influxdata.com/influxdb/latest/query_language/functions/#holt-winters) forecast of a data set.\nfunc (n *chainnode) HoltWinters(field string, h, m int64, interval time.Duration) *InfluxQLNode {\n\treturn n.holtWinters(field, h, m, interval, false)\n}\n\n// Compute the holt-winters (https://docs.influxdata.com/influxdb/latest/query_language/functions/#holt-winters) forecast of a data set.\n// This method also outputs all the points used to fit the data in addition to the forecasted data.\nfunc (n *chainnode) HoltWintersWithFit(field string, h, m int64, interval time.Duration) *InfluxQLNode {\n\treturn n.holtWinters(field, h, m, interval, true)\n}\n\nfunc (n *chainnode) holtWinters(field string, h, m int64, interval time.Duration, includeFitData bool) *InfluxQLNode {\n\ti := newInfluxQLNode(\"holtWinters\", field, n.Provides(), BatchEdge, ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatHoltWintersReducer(int(h), int(m), includeFitData, interval)\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerFloatReducer: func() (influxql.IntegerPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatHoltWintersReducer(int(h), int(m), includeFitData, interval)\n\t\t\treturn fn, fn\n\t\t},\n\t})\n\t// Always use point times for Holt Winters\n\ti.PointTimes = true\n\tn.linkChild(i)\n\treturn i\n}\n\n// Compute a cumulative sum of each point that is received.\n// A point is emitted for every point collected.\nfunc (n *chainnode) CumulativeSum(field string) *InfluxQLNode {\n\ti := newInfluxQLNode(\"cumulativeSum\", field, n.Provides(), n.Provides(), ReduceCreater{\n\t\tCreateFloatReducer: func() (influxql.FloatPointAggregator, influxql.FloatPointEmitter) {\n\t\t\tfn := influxql.NewFloatCumulativeSumReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t\tCreateIntegerReducer: func() (influxql.IntegerPointAggregator, influxql.IntegerPointEmitter) {\n\t\t\tfn := influxql.NewIntegerCumulativeSumReducer()\n\t\t\treturn fn, fn\n\t\t},\n\t\tIsStreamTransformation: true,\n\t})\n\tn.linkChild(i)\n\treturn i\n}\n",
+	"join.go":               "package pipeline\n\nimport (\n\t\"fmt\"\n\t\"reflect\"\n\t\"strings\"\n\t\"time\"\n)\n\nconst (\n\tdefaultJoinDelimiter = \".\"\n)\n\n// Joins the data from any number of nodes.\n// As each data point is received from a parent node it is paired\n// with the next data points from the other parent nodes with a\n// matching timestamp. Each parent node contributes at most one point\n// to each joined point. A tolerance can be supplied to join points\n// that do not have perfectly aligned timestamps.\n// Any points that fall within the tolerance are joined on the timestamp.\n// If multiple points fall within the same tolerance window than they are joined in the order\n// they arrive.\n//\n// Aliases are used to prefix all fields from the respective nodes.\n//\n// The join can be an inner or outer join, see the JoinNode.Fill property.\n//\n// Example:\n//    var errors = stream\n//        |from()\n//            .measurement('errors')\n//    var requests = stream\n//        |from()\n//            .measurement('requests')\n//    // Join the errors and requests streams\n//    errors\n//        |join(requests)\n//            // Provide prefix names for the fields of the data points.\n//            .as('errors', 'requests')\n//            // points that are within 1 second are considered the same time.\n//            .tolerance(1s)\n//            // fill missing values with 0, implies outer join.\n//            .fill(0.0)\n//            // name the resulting stream\n//            .streamName('error_rate')\n//        // Both the \"value\" fields from each parent have been prefixed\n//        // with the respective names 'errors' and 'requests'.\n//        |eval(lambda: \"errors.value\" / \"requests.value\")\n//           .as('rate')\n//        ...\n//\n// In the above example the `errors` and `requests` streams are joined\n// and then transformed to calculate a combined field.\ntype JoinNode struct {\n\tchainnode\n\t// The alias names of the two parents.\n\t// Note:\n\t//       Names[1] corresponds to the left  parent\n\t//       Names[0] corresponds to the right parent\n\t// tick:ignore\n\tNames []string `tick:\"As\"`\n\n\t// The dimensions on which to join\n\t// tick:ignore\n\tDimensions []string `tick:\"On\"`\n\n\t// The delimiter for the field name prefixes.\n\t// Can be the empty string.\n\tDelimiter string\n\n\t// The name of this new joined data stream.\n\t// If empty the name of the left parent is used.\n\tStreamName string\n\n\t// The maximum duration of time that two incoming points\n\t// can be apart and still be considered to be equal in time.\n\t// The joined data point's time will be rounded to the nearest\n\t// multiple of the tolerance duration.\n\tTolerance time.Duration\n\n\t// Fill the data.\n\t// The fill option implies the type of join: inner or full outer\n\t// Options are:\n\t//\n\t//   - none - (default) skip rows where a point is missing, inner join.\n\t//   - null - fill missing points with null, full outer join.\n\t//   - Any numerical value - fill fields with given value, full outer join.\n\t//\n\t// When using a numerical or null fill, the fields names are determined by copying\n\t// the field names from another point.\n\t// This doesn't work well when different sources have different field names.\n\t// Use the DefaultNode and DeleteNode to finalize the fill operation if necessary.\n\t//\n\t// Example:\n\t//    var maintlock = stream\n\t//        |from()\n\t//            .measurement('maintlock')\n\t//            .groupBy('service')\n\t//    var requests = stream\n\t//        |from()\n\t//            .measurement('requests')\n\t//            .groupBy('service')\n\t//    // Join the maintlock and requests streams\n\t//    // The intent it to drop any points in maintenance mode.\n\t//    maintlock\n\t//        |join(requests)\n\t//            // Provide prefix names for the fields of the data points.\n\t//            .as('maintlock', 'requests')\n\t//            // points that are within 1 second are considered the same time.\n\t//            .tolerance(1s)\n\t//            // fill missing fields with null, implies outer join.\n\t//            // a better default per field will be set later.\n\t//            .fill('null')\n\t//            // name the resulting stream.\n\t//            .streamName('requests')\n\t//        |default()\n\t//            // default maintenance mode to false, overwriting the null value if present.\n\t//            .field('maintlock.mode', false)\n\t//            // default the requests to 0, again overwriting the null value if present.\n\t//            .field('requests.value', 0.0)\n\t//        // drop any points that are in maintenance mode.\n\t//        |where(lambda: \"maintlock.mode\")\n\t//        |...\n\tFill interface{}\n}\n\nfunc newJoinNode(e EdgeType, parents []Node) *JoinNode {\n\tj := &JoinNode{\n\t\tchainnode: newBasicChainNode(\"join\", e, e),\n\t\tDelimiter: defaultJoinDelimiter,\n\t}\n\tfor _, n := range parents {\n\t\tn.linkChild(j)\n\t}\n\treturn j\n}\n\n//tick:ignore\nfunc (j *JoinNode) ChainMethods() map[string]reflect.Value {\n\treturn map[string]reflect.Value{\n\t\t\"Fill\": reflect.ValueOf(j.chainnode.Fill),\n\t}\n}\n\n// Prefix names for all fields from the respective nodes.\n// Each field from the parent nodes will be prefixed with the provided name and a '.'.\n// See the example above.\n//\n// The names cannot have a dot '.' character.\n//\n// tick:property\nfunc (j *JoinNode) As(names ...string) *JoinNode {\n\tj.Names = names\n\treturn j\n}\n\n// Join on a subset of the group by dimensions.\n// This is a special case where you want a single point from one parent to join with multiple\n// points from a different parent.\n//\n// For example given two measurements:\n//\n// 1. building_power (a single value) -- tagged by building, value is the total power consumed by the building.\n// 2. floor_power (multiple values) -- tagged by building and floor, values are the total power consumed by each floor.\n//\n// You want to calculate the percentage of the total building power consumed by each floor.\n// Since you only have one point per building you need it to join multiple times with\n// the points from each floor. By defining the `on` dimensions as `building` we are saying\n// that we want points that only have the building tag to be joined with more specifc points that\n// more tags, in this case the `floor` tag. In other words while we have points with tags building and floor\n// we only want to join on the building tag.\n//\n// Example:\n//    var building = stream\n//        |from()\n//            .measurement('building_power')\n//            .groupBy('building')\n//    var floor = stream\n//        |from()\n//            .measurement('floor_power')\n//            .groupBy('building', 'floor')\n//    building\n//        |join(floor)\n//            .as('building', 'floor')\n//            .on('building')\n//        |eval(lambda: \"floor.value\" / \"building.value\")\n//            ... // Values here are grouped by 'building' and 'floor'\n//\n// tick:property\nfunc (j *JoinNode) On(dims ...string) *JoinNode {\n\tj.Dimensions = dims\n\treturn j\n}\n\n// Validate that the as() specification is consistent with the number of join arms.\nfunc (j *JoinNode) validate() error {\n\tif len(j.Names) == 0 {\n\t\treturn fmt.Errorf(\"a call to join.as() is required to specify the output stream prefixes.\")\n\t}\n\n\tif len(j.Names) != len(j.Parents()) {\n\t\treturn fmt.Errorf(\"number of prefixes specified by join.as() must match the number of joined streams\")\n\t}\n\n\tfor _, name := range j.Names {\n\t\tif len(name) == 0 {\n\t\t\treturn fmt.Errorf(\"must provide a prefix name for the join node, see .as() property method\")\n\t\t}\n\t\tif j.Delimiter != \"\" && strings.Contains(name, j.Delimiter) {\n\t\t\treturn fmt.Errorf(\"cannot use name %s as field prefix, it contains the delimiter %q\t\", name, j.Delimiter)\n\t\t}\n\t}\n\tnames := make(map[string]bool, len(j.Names))\n\tfor _, name := range j.Names {\n\t\tif names[name] {\n\t\t\treturn fmt.Errorf(\"cannot use the same prefix name see .as() property method\")\n\t\t}\n\t\tnames[name] = true\n\t}\n\n\treturn nil\n}\n",
+	"k8s_autoscale.go":      "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\t\"time\"\n\n\t\"github.com/influxdata/kapacitor/services/k8s/client\"\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\nconst (\n\tDefaultNamespaceTag = \"namespace\"\n\tDefaultKindTag      = \"kind\"\n\tDefaultResourceTag  = \"resource\"\n)\n\n// K8sAutoscaleNode triggers autoscale events for a resource on a Kubernetes cluster.\n// The node also outputs points for the triggered events.\n//\n// Example:\n//     // Target 100 requests per second per host\n//     var target = 100.0\n//     var min = 1\n//     var max = 100\n//     var period = 5m\n//     var every = period\n//     stream\n//         |from()\n//             .measurement('requests')\n//             .groupBy('host', 'deployment')\n//             .truncate(1s)\n//         |derivative('value')\n//             .as('requests_per_second')\n//             .unit(1s)\n//             .nonNegative()\n//         |groupBy('deployment')\n//         |sum('requests_per_second')\n//             .as('total_requests')\n//         |window()\n//             .period(period)\n//             .every(every)\n//         |mean('total_requests')\n//             .as('total_requests')\n//         |k8sAutoscale()\n//             // Get the name of the deployment from the 'deployment' tag.\n//             .resourceNameTag('deployment')\n//             .min(min)\n//             .max(max)\n//             // Set the desired number of replicas based on target.\n//             .replicas(lambda: int(ceil(\"total_requests\" / target)))\n//         |influxDBOut()\n//             .database('deployments')\n//             .measurement('scale_events')\n//             .precision('s')\n//\n//\n// The above example computes the requests per second by deployment and host.\n// Then the total_requests per second across all hosts is computed per deployment.\n// Using the mean of the total_requests over the last time period a desired number of replicas is computed\n// based on the target number of request per second per host.\n//\n// If the desired number of replicas has changed, Kapacitor makes the appropriate API call to Kubernetes\n// to update the replicas spec.\n//\n// Any time the k8sAutoscale node changes a replica count, it emits a point.\n// The point is tagged with the namespace, kind and resource name,\n// using the NamespaceTag, KindTag, and ResourceTag properties respectively.\n// In addition the group by tags will be preserved on the emitted point.\n// The point contains two fields: `old`, and `new` representing change in the replicas.\n//\n// Available Statistics:\n//\n//    * increase_events -- number of times the replica count was increased.\n//    * decrease_events -- number of times the replica count was decreased.\n//    * cooldown_drops  -- number of times an event was dropped because of a cooldown timer.\n//    * errors          -- number of errors encountered, typically related to communicating with the Kubernetes API.\n//\ntype K8sAutoscaleNode struct {\n\tchainnode\n\n\t// Cluster is the name of the Kubernetes cluster to use.\n\tCluster string\n\n\t// Namespace is the namespace of the resource, if empty the default namespace will be used.\n\tNamespace string\n\n\t// Kind is the type of resources to autoscale.\n\t// Currently only \"deployments\", \"replicasets\" and \"replicationcontrollers\" are supported.\n\t// Default: \"deployments\"\n\tKind string\n\n\t// ResourceName is the name of the resource to autoscale.\n\tResourceName string\n\n\t// ResourceNameTag is the name of a tag that names the resource to autoscale.\n\tResourceNameTag string\n\n\t// CurrentField is the name of a field into which the current replica count will be set as an int.\n\t// If empty no field will be set.\n\t// Useful for computing deltas on the current state.\n\t//\n\t// Example:\n\t//    |k8sAutoscale()\n\t//        .currentField('replicas')\n\t//        // Increase the replicas by 1 if the qps is over the threshold\n\t//        .replicas(lambda: if(\"qps\" > threshold, \"replicas\" + 1, \"replicas\"))\n\t//\n\tCurrentField string\n\n\t// The maximum scale factor to set.\n\t// If 0 then there is no upper limit.\n\t// Default: 0, a.k.a no limit.\n\tMax int64\n\n\t// The minimum scale factor to set.\n\t// Default: 1\n\tMin int64\n\n\t// Replicas is a lambda expression that should evaluate to the desired number of replicas for the resource.\n\tReplicas *ast.LambdaNode\n\n\t// Only one increase event can be triggered per resource every IncreaseCooldown interval.\n\tIncreaseCooldown time.Duration\n\t// Only one decrease event can be triggered per resource every DecreaseCooldown interval.\n\tDecreaseCooldown time.Duration\n\n\t// NamespaceTag is the name of a tag to use when tagging emitted points with the namespace.\n\t// If empty the point will not be tagged with the resource.\n\t// Default: namespace\n\tNamespaceTag string\n\n\t// KindTag is the name of a tag to use when tagging emitted points with the kind.\n\t// If empty the point will not be tagged with the resource.\n\t// Default: kind\n\tKindTag string\n\n\t// ResourceTag is the name of a tag to use when tagging emitted points the resource.\n\t// If empty the point will not be tagged with the resource.\n\t// Default: resource\n\tResourceTag string\n}\n\nfunc newK8sAutoscaleNode(e EdgeType) *K8sAutoscaleNode {\n\tk := &K8sAutoscaleNode{\n\t\tchainnode:    newBasicChainNode(\"k8s_autoscale\", e, StreamEdge),\n\t\tMin:          1,\n\t\tKind:         client.DeploymentsKind,\n\t\tNamespaceTag: DefaultNamespaceTag,\n\t\tKindTag:      DefaultKindTag,\n\t\tResourceTag:  DefaultResourceTag,\n\t}\n\treturn k\n}\n\nfunc (n *K8sAutoscaleNode) validate() error {\n\tif (n.ResourceName != \"\" && n.ResourceNameTag != \"\") ||\n\t\t(n.ResourceNameTag == \"\" && n.ResourceName == \"\") {\n\t\treturn fmt.Errorf(\"must specify exactly one of ResourceName or ResourceNameTag\")\n\t}\n\tif n.Kind != client.DeploymentsKind && n.Kind != client.ReplicationControllerKind && n.Kind != client.ReplicaSetsKind {\n\t\treturn fmt.Errorf(\"invalid Kind, must be 'deployments', 'replicasets' or 'replicationcontrollers', got %s\", n.Kind)\n\t}\n\tif n.Min < 1 {\n\t\treturn fmt.Errorf(\"min must be >= 1, got %d\", n.Min)\n\t}\n\tif n.Replicas == nil {\n\t\treturn errors.New(\"must provide a replicas lambda expression\")\n\t}\n\treturn nil\n}\n",
+	"kapacitor_loopback.go": "package pipeline\n\nimport (\n\t\"errors\"\n)\n\n// Writes the data back into the Kapacitor stream.\n// To write data to a remote Kapacitor instance use the InfluxDBOut node.\n//\n// Example:\n//        |kapacitorLoopback()\n//            .database('mydb')\n//            .retentionPolicy('myrp')\n//            .measurement('errors')\n//            .tag('kapacitor', 'true')\n//            .tag('version', '0.2')\n//\n//\n// NOTE: It is possible to create infinite loops using this node.\n// Take care to ensure you do not chain tasks together creating a loop.\n//\n// Available Statistics:\n//\n//    * points_written -- number of points written back to Kapacitor\n//\ntype KapacitorLoopbackNode struct {\n\tnode\n\n\t// The name of the database.\n\tDatabase string\n\t// The name of the retention policy.\n\tRetentionPolicy string\n\t// The name of the measurement.\n\tMeasurement string\n\t// Static set of tags to add to all data points before writing them.\n\t// tick:ignore\n\tTags map[string]string `tick:\"Tag\"`\n}\n\nfunc newKapacitorLoopbackNode(wants EdgeType) *KapacitorLoopbackNode {\n\treturn &KapacitorLoopbackNode{\n\t\tnode: node{\n\t\t\tdesc:     \"kapacitor_loopback\",\n\t\t\twants:    wants,\n\t\t\tprovides: NoEdge,\n\t\t},\n\t\tTags: make(map[string]string),\n\t}\n}\n\n// Add a static tag to all data points.\n// Tag can be called more than once.\n//\n// tick:property\nfunc (k *KapacitorLoopbackNode) Tag(key, value string) *KapacitorLoopbackNode {\n\tk.Tags[key] = value\n\treturn k\n}\n\nfunc (k *KapacitorLoopbackNode) validate() error {\n\tif k.Database == \"\" {\n\t\treturn errors.New(\"must specify a database\")\n\t}\n\tif k.RetentionPolicy == \"\" {\n\t\treturn errors.New(\"must specify a retention policy\")\n\t}\n\treturn nil\n}\n",
+	"log.go":                "package pipeline\n\n// A node that logs all data that passes through the node.\n//\n// Example:\n//    stream.from()...\n//      |window()\n//          .period(10s)\n//          .every(10s)\n//      |log()\n//      |count('value')\n//\ntype LogNode struct {\n\tchainnode\n\n\t// The level at which to log the data.\n\t// One of: DEBUG, INFO, WARN, ERROR\n\t// Default: INFO\n\tLevel string\n\t// Optional prefix to add to all log messages\n\tPrefix string\n}\n\nfunc newLogNode(wants EdgeType) *LogNode {\n\treturn &LogNode{\n\t\tchainnode: newBasicChainNode(\"log\", wants, wants),\n\t\tLevel:     \"INFO\",\n\t}\n}\n",
+	"node.go":               "package pipeline\n\nimport (\n\t\"bytes\"\n\t\"fmt\"\n\t\"strings\"\n\t\"time\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\n// The type of data that travels along an edge connecting two nodes in a Pipeline.\ntype EdgeType int\n\nconst (\n\t// No data are transferred.\n\tNoEdge EdgeType = iota\n\t// Data are transferred immediately and one point at a time.\n\tStreamEdge\n\t// Data are transferred in batches as soon as the data are ready.\n\tBatchEdge\n)\n\ntype ID int\n\nfunc (e EdgeType) String() string {\n\tswitch e {\n\tcase NoEdge:\n\t\treturn \"noedge\"\n\tcase StreamEdge:\n\t\treturn \"stream\"\n\tcase BatchEdge:\n\t\treturn \"batch\"\n\tdefault:\n\t\treturn \"unknown EdgeType\"\n\t}\n}\n\n//Generic node in a pipeline\ntype Node interface {\n\t// List of parents of this node.\n\tParents() []Node\n\t// List of children of this node.\n\tChildren() []Node\n\t// Add a parent node only, does not add the child relation.\n\taddParent(p Node)\n\t// Links a child node by adding both the parent and child relation.\n\tlinkChild(c Node)\n\n\t// Short description of the node does not need to be unique\n\tDesc() string\n\n\t// Friendly readable unique name of the node\n\tName() string\n\tSetName(string)\n\n\t// Position in the TICKscript where the node was created\n\tPosition() ast.Position\n\n\t// Unique id for the node\n\tID() ID\n\tsetID(ID)\n\n\t// The type of input the node wants.\n\tWants() EdgeType\n\t// The type of output the node provides.\n\tProvides() EdgeType\n\n\t// Check that the definition of the node is consistent\n\tvalidate() error\n\n\t// Helper methods for walking DAG\n\ttMark() bool\n\tsetTMark(b bool)\n\tpMark() bool\n\tsetPMark(b bool)\n\tsetPipeline(*Pipeline)\n\tpipeline() *Pipeline\n\n\t// Return .dot string to graph DAG\n\tdot(buf *bytes.Buffer)\n}\n\ntype node struct {\n\tp        *Pipeline\n\tdesc     string\n\tname     string\n\tid       ID\n\tparents  []Node\n\tchildren []Node\n\twants    EdgeType\n\tprovides EdgeType\n\ttm       bool\n\tpm       bool\n\tpos      ast.Position\n}\n\n// tick:ignore\nfunc (n *node) Desc() string {\n\treturn n.desc\n}\n\n// tick:ignore\nfunc (n *node) ID() ID {\n\treturn n.id\n}\n\nfunc (n *node) setID(id ID) {\n\tn.id = id\n}\n\n// tick:ignore\nfunc (n *node) Name() string {\n\tif n.name == \"\" {\n\t\tn.name = fmt.Sprintf(\"%s%d\", n.Desc(), n.ID())\n\t}\n\treturn n.name\n}\n\n// tick:ignore\nfunc (n *node) SetName(name string) {\n\tn.name = name\n}\n\n// The position in the TICKscript where the node was created.\n// It is nil if the node was not created by a TICKscript.\n// tick:ignore\nfunc (n *node) Position() ast.Position {\n\treturn n.pos\n}\n\n// tick:ignore\nfunc (n *node) SetPosition(p ast.Position) {\n\tn.pos = p\n}\n\n// tick:ignore\nfunc (n *node) Parents() []Node {\n\treturn n.parents\n}\n\n// tick:ignore\nfunc (n *node) Children() []Node {\n\treturn n.children\n}\n\nfunc (n *node) addParent(c Node) {\n\tn.parents = append(n.parents, c)\n}\n\nfunc (n *node) linkChild(c Node) {\n\tc.setPipeline(n.p)\n\t_ = n.p.assignID(c)\n\tn.children = append(n.children, c)\n\tc.addParent(n)\n}\n\nfunc (n *node) tMark() bool {\n\treturn n.tm\n}\n\nfunc (n *node) setTMark(b bool) {\n\tn.tm = b\n}\n\nfunc (n *node) pMark() bool {\n\treturn n.pm\n}\n\nfunc (n *node) setPMark(b bool) {\n\tn.pm = b\n}\n\nfunc (n *node) setPipeline(p *Pipeline) {\n\tn.p = p\n}\nfunc (n *node) pipeline() *Pipeline {\n\treturn n.p\n}\n\n// tick:ignore\nfunc (n *node) Wants() EdgeType {\n\treturn n.wants\n}\n\n// tick:ignore\nfunc (n *node) Provides() EdgeType {\n\treturn n.provides\n}\n\nfunc (n *node) validate() error {\n\treturn nil\n}\n\nfunc (n *node) dot(buf *bytes.Buffer) {\n\tfor _, c := range n.children {\n\t\tbuf.Write([]byte(fmt.Sprintf(\"%s -> %s;\\n\", n.Name(), c.Name())))\n\t}\n}\n\n// Create a new stream of data that contains the internal statistics of the node.\n// The interval represents how often to emit the statistics based on real time.\n// This means the interval time is independent of the times of the data points the source node is receiving.\nfunc (n *node) Stats(interval time.Duration) *StatsNode {\n\tstats := newStatsNode(n, interval)\n\tn.pipeline().addSource(stats)\n\t// If the source node does not have any children add a NoOpNode.\n\t// This is a work around to make it so that the source node has somewhere to send its data.\n\t// That way we can get stats on its behavior.\n\tif len(n.Children()) == 0 {\n\t\tnoop := newNoOpNode(n.Provides())\n\t\tn.linkChild(noop)\n\t}\n\treturn stats\n}\n\nconst nodeNameMarker = \"NODE_NAME\"\nconst intervalMarker = \"INTERVAL\"\n\n// Helper function for creating an alert on low throughput, a.k.a. deadman's switch.\n//\n// - Threshold -- trigger alert if throughput drops below threshold in points/interval.\n// - Interval -- how often to check the throughput.\n// - Expressions -- optional list of expressions to also evaluate. Useful for time of day alerting.\n//\n// Example:\n//    var data = stream\n//        |from()...\n//    // Trigger critical alert if the throughput drops below 100 points per 10s and checked every 10s.\n//    data\n//        |deadman(100.0, 10s)\n//    //Do normal processing of data\n//    data...\n//\n// The above is equivalent to this\n// Example:\n//    var data = stream\n//        |from()...\n//    // Trigger critical alert if the throughput drops below 100 points per 10s and checked every 10s.\n//    data\n//        |stats(10s)\n//            .align()\n//        |derivative('emitted')\n//            .unit(10s)\n//            .nonNegative()\n//        |alert()\n//            .id('node \\'stream0\\' in task \\'{{ .TaskName }}\\'')\n//            .message('{{ .ID }} is {{ if eq .Level \"OK\" }}alive{{ else }}dead{{ end }}: {{ index .Fields \"emitted\" | printf \"%0.3f\" }} points/10s.')\n//            .crit(lambda: \"emitted\" <= 100.0)\n//    //Do normal processing of data\n//    data...\n//\n// The `id` and `message` alert properties can be configured globally via the 'deadman' configuration section.\n//\n// Since the AlertNode is the last piece it can be further modified as usual.\n// Example:\n//    var data = stream\n//        |from()...\n//    // Trigger critical alert if the throughput drops below 100 points per 10s and checked every 10s.\n//    data\n//        |deadman(100.0, 10s)\n//            .slack()\n//            .channel('#dead_tasks')\n//    //Do normal processing of data\n//    data...\n//\n// You can specify additional lambda expressions to further constrain when the deadman's switch is triggered.\n// Example:\n//    var data = stream\n//        |from()...\n//    // Trigger critical alert if the throughput drops below 100 points per 10s and checked every 10s.\n//    // Only trigger the alert if the time of day is between 8am-5pm.\n//    data\n//        |deadman(100.0, 10s, lambda: hour(\"time\") >= 8 AND hour(\"time\") <= 17)\n//    //Do normal processing of data\n//    data...\n//\nfunc (n *node) Deadman(threshold float64, interval time.Duration, expr ...*ast.LambdaNode) *AlertNode {\n\tdn := n.Stats(interval).Align().\n\t\tDerivative(\"emitted\").NonNegative()\n\tdn.Unit = interval\n\n\tan := dn.Alert()\n\tcritExpr := &ast.BinaryNode{\n\t\tOperator: ast.TokenLessEqual,\n\t\tLeft: &ast.ReferenceNode{\n\t\t\tReference: \"emitted\",\n\t\t},\n\t\tRight: &ast.NumberNode{\n\t\t\tIsFloat: true,\n\t\t\tFloat64: threshold,\n\t\t},\n\t}\n\t// Add any additional expressions\n\tfor _, e := range expr {\n\t\tcritExpr = &ast.BinaryNode{\n\t\t\tOperator: ast.TokenAnd,\n\t\t\tLeft:     critExpr,\n\t\t\tRight:    e.Expression,\n\t\t}\n\t}\n\tan.Crit = &ast.LambdaNode{Expression: critExpr}\n\t// Replace NODE_NAME with actual name of the node in the Id.\n\tan.Id = strings.Replace(n.pipeline().deadman.Id(), nodeNameMarker, n.Name(), 1)\n\t// Set the message on the alert node.\n\tan.Message = strings.Replace(n.pipeline().deadman.Message(), intervalMarker, interval.String(), 1)\n\treturn an\n}\n\n// ---------------------------------\n// Chaining methods\n//\n\n// basic implementation of node + chaining methods\ntype chainnode struct {\n\tnode\n}\n\nfunc newBasicChainNode(desc string, wants, provides EdgeType) chainnode {\n\treturn chainnode{node{\n\t\tdesc:     desc,\n\t\twants:    wants,\n\t\tprovides: provides,\n\t}}\n}\n\n// Create a new node that filters the data stream by a given expression.\nfunc (n *chainnode) Where(expression *ast.LambdaNode) *WhereNode {\n\tw := newWhereNode(n.provides, expression)\n\tn.linkChild(w)\n\treturn w\n}\n\n// Create a new node that routes each point to one of several named routes.\nfunc (n *chainnode) Switch() *SwitchNode {\n\ts := newSwitchNode(n.provides)\n\tn.linkChild(s)\n\treturn s\n}\n\n// Create an HTTP output node that caches the most recent data it has received.\n// The cached data are available at the given endpoint.\n// The endpoint is the relative path from the API endpoint of the running task.\n// For example, if the task endpoint is at `/kapacitor/v1/tasks/<task_id>` and endpoint is\n// `top10`, then the data can be requested from `/kapacitor/v1/tasks/<task_id>/top10`.\nfunc (n *chainnode) HttpOut(endpoint string) *HTTPOutNode {\n\th := newHTTPOutNode(n.provides, endpoint)\n\tn.linkChild(h)\n\treturn h\n}\n\n// Creates an HTTP Post node that POSTS received data to the provided HTTP endpoint.\n// HttpPost expects 0 or 1 arguments. If 0 arguments are provided, you must specify an\n// endpoint property method.\nfunc (n *chainnode) HttpPost(url ...string) *HTTPPostNode {\n\th := newHTTPPostNode(n.provides, url...)\n\tn.linkChild(h)\n\treturn h\n}\n\n// Create an influxdb output node that will store the incoming data into InfluxDB.\nfunc (n *chainnode) InfluxDBOut() *InfluxDBOutNode {\n\ti := newInfluxDBOutNode(n.provides)\n\tn.linkChild(i)\n\treturn i\n}\n\n// Create an kapacitor loopback node that will send data back into Kapacitor as a stream.\nfunc (n *chainnode) KapacitorLoopback() *KapacitorLoopbackNode {\n\tk := newKapacitorLoopbackNode(n.provides)\n\tn.linkChild(k)\n\treturn k\n}\n\n// Create an alert node, which can trigger alerts.\nfunc (n *chainnode) Alert() *AlertNode {\n\ta := newAlertNode(n.provides)\n\tn.linkChild(a)\n\treturn a\n}\n\n// Perform the union of this node and all other given nodes.\nfunc (n *chainnode) Union(node ...Node) *UnionNode {\n\tu := newUnionNode(n.provides, node)\n\tn.linkChild(u)\n\treturn u\n}\n\n// Join this node with other nodes. The data are joined on timestamp.\nfunc (n *chainnode) Join(others ...Node) *JoinNode {\n\tothers = append([]Node{n}, others...)\n\tj := newJoinNode(n.provides, others)\n\treturn j\n}\n\n// Combine this node with itself. The data are combined on timestamp.\nfunc (n *chainnode) Combine(expressions ...*ast.LambdaNode) *CombineNode {\n\tc := newCombineNode(n.provides, expressions)\n\tn.linkChild(c)\n\treturn c\n}\n\n// Flatten points with similar times into a single point.\nfunc (n *chainnode) Flatten() *FlattenNode {\n\tf := newFlattenNode(n.provides)\n\tn.linkChild(f)\n\treturn f\n}\n\n// Create an eval node that will evaluate the given transformation function to each data point.\n// A list of expressions may be provided and will be evaluated in the order they are given.\n// The results are available to later expressions.\nfunc (n *chainnode) Eval(expressions ...*ast.LambdaNode) *EvalNode {\n\te := newEvalNode(n.provides, expressions)\n\tn.linkChild(e)\n\treturn e\n}\n\n// Group the data by a set of tags.\n//\n// Can pass literal * to group by all dimensions.\n// Example:\n//    |groupBy(*)\n//\nfunc (n *chainnode) GroupBy(tag ...interface{}) *GroupByNode {\n\tg := newGroupByNode(n.provides, tag)\n\tn.linkChild(g)\n\treturn g\n}\n\n// Create a new node that windows the stream by time.\n//\n// NOTE: Window can only be applied to stream edges.\nfunc (n *chainnode) Window() *WindowNode {\n\tif n.Provides() != StreamEdge {\n\t\tpanic(\"cannot Window batch edge\")\n\t}\n\tw := newWindowNode()\n\tn.linkChild(w)\n\treturn w\n}\n\n// Create a new node that detects anomalies of a field against seasonal baselines.\nfunc (n *chainnode) Anomaly(field string) *AnomalyNode {\n\ta := newAnomalyNode(n.Provides(), field)\n\tn.linkChild(a)\n\treturn a\n}\n\n// Create a new node that computes a histogram of a field's values for each batch.\n//\n// NOTE: Histogram can only be applied to batch edges.\nfunc (n *chainnode) Histogram(field string) *HistogramNode {\n\tif n.Provides() != BatchEdge {\n\t\tpanic(\"cannot compute histogram of stream edge, use window first\")\n\t}\n\th := newHistogramNode(field)\n\tn.linkChild(h)\n\treturn h\n}\n\n// Create a new node that resamples the data onto a regular time grid, filling gaps.\nfunc (n *chainnode) Fill() *FillNode {\n\tf := newFillNode(n.Provides())\n\tn.linkChild(f)\n\treturn f\n}\n\n// Create a new node that samples the incoming points or batches.\n//\n// One point will be emitted every count or duration specified.\nfunc (n *chainnode) Sample(rate interface{}) *SampleNode {\n\ts := newSampleNode(n.Provides(), rate)\n\tn.linkChild(s)\n\treturn s\n}\n\n// Create a new node that computes the derivative of adjacent points.\nfunc (n *chainnode) Derivative(field string) *DerivativeNode {\n\ts := newDerivativeNode(n.Provides(), field)\n\tn.linkChild(s)\n\treturn s\n}\n\n// Create a new node that computes the rate and increase of a field.\nfunc (n *chainnode) Rate(field string) *RateNode {\n\tr := newRateNode(n.Provides(), field)\n\tn.linkChild(r)\n\treturn r\n}\n\n// Create a new node that shifts the incoming points or batches in time.\nfunc (n *chainnode) Shift(shift time.Duration) *ShiftNode {\n\ts := newShiftNode(n.Provides(), shift)\n\tn.linkChild(s)\n\treturn s\n}\n\n// Create a node that logs all data it receives.\nfunc (n *chainnode) Log() *LogNode {\n\ts := newLogNode(n.Provides())\n\tn.linkChild(s)\n\treturn s\n}\n\n// Create a node that can set defaults for missing tags or fields.\nfunc (n *chainnode) Default() *DefaultNode {\n\ts := newDefaultNode(n.Provides())\n\tn.linkChild(s)\n\treturn s\n}\n\n// Create a node that can delete tags or fields.\nfunc (n *chainnode) Delete() *DeleteNode {\n\ts := newDeleteNode(n.Provides())\n\tn.linkChild(s)\n\treturn s\n}\n\n// Create a node that can trigger autoscale events for a kubernetes cluster.\nfunc (n *chainnode) K8sAutoscale() *K8sAutoscaleNode {\n\tk := newK8sAutoscaleNode(n.Provides())\n\tn.linkChild(k)\n\treturn k\n}\n\n// Create a node that tracks duration in a given state.\nfunc (n *chainnode) StateDuration(expression *ast.LambdaNode) *StateDurationNode {\n\tsd := newStateDurationNode(n.provides, expression)\n\tn.linkChild(sd)\n\treturn sd\n}\n\n// Create a node that tracks number of consecutive points in a given state.\nfunc (n *chainnode) StateCount(expression *ast.LambdaNode) *StateCountNode {\n\tsc := newStateCountNode(n.provides, expression)\n\tn.linkChild(sc)\n\treturn sc\n}\n",
+	"noop.go":               "package pipeline\n\n// A node that does not perform any operation.\n//\n// *Do not use this node in a TICKscript there should be no need for it.*\n//\n// If a node does not have any children, then its emitted count remains zero.\n// Using a NoOpNode is a work around so that statistics are accurately reported\n// for nodes with no real children.\n// A NoOpNode is automatically appended to any node that is a source for a StatsNode\n// and does not have any children.\ntype NoOpNode struct {\n\tchainnode\n}\n\nfunc newNoOpNode(wants EdgeType) *NoOpNode {\n\treturn &NoOpNode{\n\t\tchainnode: newBasicChainNode(\"noop\", wants, wants),\n\t}\n}\n",
+	"pipeline.go":           "package pipeline\n\nimport (\n\t\"bytes\"\n\t\"fmt\"\n\t\"time\"\n\n\t\"github.com/influxdata/kapacitor/tick\"\n\t\"github.com/influxdata/kapacitor/tick/stateful\"\n)\n\n// Information relavant to configuring a deadman's swith\ntype DeadmanService interface {\n\tInterval() time.Duration\n\tThreshold() float64\n\tId() string\n\tMessage() string\n\tGlobal() bool\n}\n\n// Create a template pipeline\n// tick:ignore\nfunc CreateTemplatePipeline(\n\tscript string,\n\tsourceEdge EdgeType,\n\tscope *stateful.Scope,\n\tdeadman DeadmanService,\n\timporter tick.Importer,\n) (*TemplatePipeline, error) {\n\tp, vars, err := createPipelineAndVars(script, sourceEdge, scope, deadman, nil, true, importer)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\ttp := &TemplatePipeline{\n\t\tp:    p,\n\t\tvars: vars,\n\t}\n\treturn tp, nil\n}\n\n// Create a pipeline from a given script.\n// tick:ignore\nfunc CreatePipeline(\n\tscript string,\n\tsourceEdge EdgeType,\n\tscope *stateful.Scope,\n\tdeadman DeadmanService,\n\tpredefinedVars map[string]tick.Var,\n\timporter tick.Importer,\n) (*Pipeline, error) {\n\tp, _, err := createPipelineAndVars(script, sourceEdge, scope, deadman, predefinedVars, false, importer)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\treturn p, nil\n}\n\nfunc createPipelineAndVars(\n\tscript string,\n\tsourceEdge EdgeType,\n\tscope *stateful.Scope,\n\tdeadman DeadmanService,\n\tpredefinedVars map[string]tick.Var,\n\tignoreMissingVars bool,\n\timporter tick.Importer,\n) (*Pipeline, map[string]tick.Var, error) {\n\tp := &Pipeline{\n\t\tdeadman: deadman,\n\t}\n\tvar src Node\n\tswitch sourceEdge {\n\tcase StreamEdge:\n\t\tsrc = newStreamNode()\n\t\tscope.Set(\"stream\", src)\n\tcase BatchEdge:\n\t\tsrc = newBatchNode()\n\t\tscope.Set(\"batch\", src)\n\tdefault:\n\t\treturn nil, nil, fmt.Errorf(\"source edge type must be either Stream or Batch not %s\", sourceEdge)\n\t}\n\tp.addSource(src)\n\n\tvars, err := tick.EvaluateWithImporter(script, scope, predefinedVars, ignoreMissingVars, importer)\n\tif err != nil {\n\t\treturn nil, nil, err\n\t}\n\tif deadman.Global() {\n\t\tswitch s := src.(type) {\n\t\tcase *StreamNode:\n\t\t\ts.Deadman(deadman.Threshold(), deadman.Interval())\n\t\tcase *BatchNode:\n\t\t\ts.Deadman(deadman.Threshold(), deadman.Interval())\n\t\tdefault:\n\t\t\treturn nil, nil, fmt.Errorf(\"source edge type must be either Stream or Batch not %s\", sourceEdge)\n\t\t}\n\t}\n\tif err = p.Walk(\n\t\tfunc(n Node) error {\n\t\t\treturn n.validate()\n\t\t}); err != nil {\n\t\treturn nil, nil, err\n\t}\n\treturn p, vars, nil\n}\n\n// A complete data processing pipeline. Starts with a single source.\n// tick:ignore\ntype Pipeline struct {\n\tsources []Node\n\tid      ID\n\tsorted  []Node\n\n\tdeadman DeadmanService\n}\n\nfunc (p *Pipeline) addSource(src Node) {\n\tsrc.setPipeline(p)\n\t_ = p.assignID(src)\n\tp.sources = append(p.sources, src)\n}\n\nfunc (p *Pipeline) assignID(n Node) error {\n\tn.setID(p.id)\n\tp.id++\n\treturn nil\n}\n\n// The number of nodes in the pipeline.\n// tick:ignore\nfunc (p *Pipeline) Len() int {\n\tif p.sorted == nil {\n\t\tp.sort()\n\t}\n\treturn len(p.sorted)\n}\n\n// Walks the entire pipeline and calls func f on each node exactly once.\n// f will be called on a node n only after all of its parents have already had f called.\n// tick:ignore\nfunc (p *Pipeline) Walk(f func(n Node) error) error {\n\tif p.sorted == nil {\n\t\tp.sort()\n\t}\n\tfor _, n := range p.sorted {\n\t\terr := f(n)\n\t\tif err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n\treturn nil\n}\n\nfunc (p *Pipeline) sort() {\n\t// Iterate the sources in reverse order\n\tfor i := len(p.sources) - 1; i >= 0; i-- {\n\t\tp.visit(p.sources[i])\n\t}\n\t//reverse p.sorted\n\ts := p.sorted\n\tfor i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {\n\t\ts[i], s[j] = s[j], s[i]\n\t}\n}\n\n// Depth first search topological sorting of a DAG.\n// https://en.wikipedia.org/wiki/Topological_sorting#Algorithms\nfunc (p *Pipeline) visit(n Node) {\n\tif n.tMark() {\n\t\tpanic(\"pipeline contains a cycle\")\n\t}\n\tif !n.pMark() {\n\t\tn.setTMark(true)\n\t\tfor _, c := range n.Children() {\n\t\t\tp.visit(c)\n\t\t}\n\t\tn.setPMark(true)\n\t\tn.setTMark(false)\n\t\tp.sorted = append(p.sorted, n)\n\t}\n}\n\n// Return a graphviz .dot formatted byte array.\n// tick:ignore\nfunc (p *Pipeline) Dot(name string) []byte {\n\n\tvar buf bytes.Buffer\n\n\tbuf.Write([]byte(\"digraph \"))\n\tbuf.Write([]byte(name))\n\tbuf.Write([]byte(\" {\\n\"))\n\t_ = p.Walk(func(n Node) error {\n\t\tn.dot(&buf)\n\t\treturn nil\n\t})\n\tbuf.Write([]byte(\"}\"))\n\n\treturn buf.Bytes()\n}\n\n//tick:ignore\ntype TemplatePipeline struct {\n\tp    *Pipeline\n\tvars map[string]tick.Var\n}\n\n// Return the set of vars defined by the TICKscript with their defaults\n// tick:ignore\nfunc (t *TemplatePipeline) Vars() map[string]tick.Var {\n\treturn t.vars\n}\n\n// Return the pipeline of the template, vars without a default value are not set.\n// tick:ignore\nfunc (t *TemplatePipeline) Pipeline() *Pipeline {\n\treturn t.p\n}\n\n// Return a graphviz .dot formatted byte array.\n// tick:ignore\nfunc (t *TemplatePipeline) Dot(name string) []byte {\n\treturn t.p.Dot(name)\n}\n",
+	"rate.go":               "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\t\"time\"\n)\n\n// Compute the rate and increase of a field.\n//\n// On a stream edge the rate and increase are computed between each point and\n// the previous point of the same group, and the first point is dropped.\n// On a batch edge the rate and increase are computed over all points of the batch\n// and a single point is emitted per batch.\n//\n// When the counter property is set the field is treated as a monotonic counter.\n// A decrease in value is considered a counter reset and the value after the\n// reset is counted as the increase, similar to the Prometheus rate and increase functions.\n// If a wrap value is set, a decrease from a value above half the wrap value is\n// instead considered an overflow of the counter at that value.\n// Decreases from lower values are still considered counter resets.\n//\n// Example:\n//     stream\n//         |from()\n//             .measurement('net')\n//         |window()\n//             .period(1m)\n//             .every(1m)\n//         |rate('bytes_recv')\n//             .counter()\n//             .unit(1s)\n//         |influxDBOut()\n//             .database('mydb')\n//             .measurement('net_rate')\n//\n// Computes the per second rate of bytes received over each minute,\n// along with the total number of bytes received.\n//\n// The rate is computed via:\n//    increase / ( time_difference / unit)\ntype RateNode struct {\n\tchainnode\n\n\t// The field to use when calculating the rate\n\t// tick:ignore\n\tField string\n\n\t// The name of the rate field.\n\t// Default: 'rate'\n\tAs string\n\n\t// The name of the increase field.\n\t// Default: 'increase'\n\tIncreaseAs string\n\n\t// The time unit of the resulting rate value.\n\t// Default: 1s\n\tUnit time.Duration\n\n\t// Whether the field is a monotonic counter.\n\t// tick:ignore\n\tCounterFlag bool `tick:\"Counter\"`\n\n\t// The value at which the counter wraps around to zero.\n\t// tick:ignore\n\tWrapValue float64 `tick:\"Wrap\"`\n}\n\nfunc newRateNode(wants EdgeType, field string) *RateNode {\n\treturn &RateNode{\n\t\tchainnode:  newBasicChainNode(\"rate\", wants, StreamEdge),\n\t\tField:      field,\n\t\tAs:         \"rate\",\n\t\tIncreaseAs: \"increase\",\n\t\tUnit:       time.Second,\n\t}\n}\n\n// If called the field is treated as a monotonic counter and\n// decreases in value are handled as counter resets.\n// tick:property\nfunc (n *RateNode) Counter() *RateNode {\n\tn.CounterFlag = true\n\treturn n\n}\n\n// Set the value at which the counter overflows and wraps around to zero.\n// For example an unsigned 32 bit counter wraps at 4294967296.\n// Only a decrease from a value above half the wrap value is considered an overflow,\n// other decreases are considered counter resets.\n// Implies counter.\n// tick:property\nfunc (n *RateNode) Wrap(max interface{}) *RateNode {\n\tswitch v := max.(type) {\n\tcase float64:\n\t\tn.WrapValue = v\n\tcase int64:\n\t\tn.WrapValue = float64(v)\n\tdefault:\n\t\tpanic(fmt.Sprintf(\"wrap value must be a float or int value, got %T\", max))\n\t}\n\tn.CounterFlag = true\n\treturn n\n}\n\nfunc (n *RateNode) validate() error {\n\tif n.Unit <= 0 {\n\t\treturn errors.New(\"unit must be greater than zero\")\n\t}\n\tif n.WrapValue < 0 {\n\t\treturn errors.New(\"wrap value must not be negative\")\n\t}\n\tif n.As == n.IncreaseAs {\n\t\treturn fmt.Errorf(\"rate and increase fields must have different names, got %q\", n.As)\n\t}\n\treturn nil\n}\n",
+	"sample.go":             "package pipeline\n\nimport (\n\t\"time\"\n)\n\n// Sample points or batches.\n// One point will be emitted every count or duration specified.\n//\n// Example:\n//    stream\n//        |sample(3)\n//\n// Keep every third data point or batch.\n//\n// Example:\n//    stream\n//        |sample(10s)\n//\n// Keep only samples that land on the 10s boundary.\n// See FromNode.Truncate, QueryNode.GroupBy time or WindowNode.Align\n// for ensuring data is aligned with a boundary.\ntype SampleNode struct {\n\tchainnode\n\n\t// Keep every N point or batch\n\t// tick:ignore\n\tN int64\n\n\t// Keep one point or batch every Duration\n\t// tick:ignore\n\tDuration time.Duration\n}\n\nfunc newSampleNode(wants EdgeType, rate interface{}) *SampleNode {\n\tvar n int64\n\tvar d time.Duration\n\tswitch r := rate.(type) {\n\tcase int64:\n\t\tn = r\n\tcase time.Duration:\n\t\td = r\n\tdefault:\n\t\tpanic(\"must pass int64 or duration to new sample node\")\n\t}\n\n\treturn &SampleNode{\n\t\tchainnode: newBasicChainNode(\"sample\", wants, wants),\n\t\tN:         n,\n\t\tDuration:  d,\n\t}\n}\n",
+	"shift.go":              "package pipeline\n\nimport (\n\t\"time\"\n)\n\n// Shift points and batches in time, this is useful for comparing\n// batches or points from different times.\n//\n// Example:\n//    stream\n//        |shift(5m)\n//\n// Shift all data points 5m forward in time.\n//\n// Example:\n//    stream\n//        |shift(-10s)\n//\n// Shift all data points 10s backward in time.\ntype ShiftNode struct {\n\tchainnode\n\n\t// Keep one point or batch every Duration\n\t// tick:ignore\n\tShift time.Duration\n}\n\nfunc newShiftNode(wants EdgeType, shift time.Duration) *ShiftNode {\n\treturn &ShiftNode{\n\t\tchainnode: newBasicChainNode(\"shift\", wants, wants),\n\t\tShift:     shift,\n\t}\n}\n",
+	"state_tracking.go":     "package pipeline\n\nimport (\n\t\"time\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\n// Compute the duration of a given state.\n// The state is defined via a lambda expression. For each consecutive point for\n// which the expression evaluates as true, the state duration will be\n// incremented by the duration between points. When a point evaluates as false,\n// the state duration is reset.\n//\n// The state duration will be added as an additional field to each point. If the\n// expression evaluates as false, the value will be -1. If the expression\n// generates an error during evaluation, the point is discarded, and does not\n// affect the state duration.\n//\n// Example:\n//     stream\n//         |from()\n//             .measurement('cpu')\n//         |where(lambda: \"cpu\" == 'cpu-total')\n//         |groupBy('host')\n//         |stateDuration(lambda: \"usage_idle\" <= 10)\n//             .unit(1m)\n//         |alert()\n//             // Warn after 1 minute\n//             .warn(lambda: \"state_duration\" >= 1)\n//             // Critical after 5 minutes\n//             .crit(lambda: \"state_duration\" >= 5)\n//\n// Note that as the first point in the given state has no previous point, its\n// state duration will be 0.\n//\n// Stateful functions in the expression, i.e. `previous` or `delta`, keep their state per group\n// regardless of the state. For batches the state duration and the state of the\n// stateful functions are reset at the start of each batch.\ntype StateDurationNode struct {\n\tchainnode\n\n\t// Expression to determine whether state is active.\n\t// tick:ignore\n\tLambda *ast.LambdaNode\n\n\t// The new name of the resulting duration field.\n\t// Default: 'state_duration'\n\tAs string\n\n\t// The time unit of the resulting duration value.\n\t// Default: 1s.\n\tUnit time.Duration\n}\n\nfunc newStateDurationNode(wants EdgeType, predicate *ast.LambdaNode) *StateDurationNode {\n\treturn &StateDurationNode{\n\t\tchainnode: newBasicChainNode(\"state_duration\", wants, wants),\n\t\tLambda:    predicate,\n\t\tAs:        \"state_duration\",\n\t\tUnit:      time.Second,\n\t}\n}\n\n// Compute the number of consecutive points in a given state.\n// The state is defined via a lambda expression. For each consecutive point for\n// which the expression evaluates as true, the state count will be incremented\n// When a point evaluates as false, the state count is reset.\n//\n// The state count will be added as an additional field to each point. If the\n// expression evaluates as false, the value will be -1. If the expression\n// generates an error during evaluation, the point is discarded, and does not\n// affect the state count.\n//\n// Example:\n//     stream\n//         |from()\n//             .measurement('cpu')\n//         |where(lambda: \"cpu\" == 'cpu-total')\n//         |groupBy('host')\n//         |stateCount(lambda: \"usage_idle\" <= 10)\n//         |alert()\n//             // Warn after 1 point\n//             .warn(lambda: \"state_count\" >= 1)\n//             // Critical after 5 points\n//             .crit(lambda: \"state_count\" >= 5)\n//\n// Stateful functions in the expression, i.e. `previous` or `delta`, keep their state per group\n// regardless of the state. For batches the state count and the state of the\n// stateful functions are reset at the start of each batch.\ntype StateCountNode struct {\n\tchainnode\n\n\t// Expression to determine whether state is active.\n\t// tick:ignore\n\tLambda *ast.LambdaNode\n\n\t// The new name of the resulting duration field.\n\t// Default: 'state_count'\n\tAs string\n}\n\nfunc newStateCountNode(wants EdgeType, predicate *ast.LambdaNode) *StateCountNode {\n\treturn &StateCountNode{\n\t\tchainnode: newBasicChainNode(\"state_count\", wants, wants),\n\t\tLambda:    predicate,\n\t\tAs:        \"state_count\",\n\t}\n}\n",
+	"stats.go":              "package pipeline\n\nimport \"time\"\n\n// A StatsNode emits internal statistics about the another node at a given interval.\n//\n// The interval represents how often to emit the statistics based on real time.\n// This means the interval time is independent of the times of the data points the other node is receiving.\n// As a result the StatsNode is a root node in the task pipeline.\n//\n//\n// The currently available internal statistics:\n//\n//    * emitted -- the number of points or batches this node has sent to its children.\n//\n// Each stat is available as a field in the data stream.\n//\n// The stats are in groups according to the original data.\n// Meaning that if the source node is grouped by the tag 'host' as an example,\n// then the counts are output per host with the appropriate 'host' tag.\n// Since its possible for groups to change when crossing a node only the emitted groups\n// are considered.\n//\n// Example:\n//     var data = stream\n//         |from()...\n//     // Emit statistics every 1 minute and cache them via the HTTP API.\n//     data\n//         |stats(1m)\n//         |httpOut('stats')\n//     // Continue normal processing of the data stream\n//     data...\n//\n// WARNING: It is not recommended to join the stats stream with the original data stream.\n// Since they operate on different clocks you could potentially create a deadlock.\n// This is a limitation of the current implementation and may be removed in the future.\ntype StatsNode struct {\n\tchainnode\n\t// tick:ignore\n\tSourceNode Node\n\t// tick:ignore\n\tInterval time.Duration\n\n\t// tick:ignore\n\tAlignFlag bool `tick:\"Align\"`\n}\n\nfunc newStatsNode(n Node, interval time.Duration) *StatsNode {\n\treturn &StatsNode{\n\t\tchainnode:  newBasicChainNode(\"stats\", StreamEdge, StreamEdge),\n\t\tSourceNode: n,\n\t\tInterval:   interval,\n\t}\n}\n\n// Round times to the StatsNode.Interval value.\n// tick:property\nfunc (n *StatsNode) Align() *StatsNode {\n\tn.AlignFlag = true\n\treturn n\n}\n",
+	"stream.go":             "package pipeline\n\nimport (\n\t\"reflect\"\n\t\"time\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\n// A StreamNode represents the source of data being\n// streamed to Kapacitor via any of its inputs.\n// The `stream` variable in stream tasks is an instance of\n// a StreamNode.\n// StreamNode.From is the method/property of this node.\ntype StreamNode struct {\n\tnode\n}\n\nfunc newStreamNode() *StreamNode {\n\treturn &StreamNode{\n\t\tnode: node{\n\t\t\tdesc:     \"stream\",\n\t\t\twants:    StreamEdge,\n\t\t\tprovides: StreamEdge,\n\t\t},\n\t}\n}\n\n// Creates a new FromNode that can be further\n// filtered using the Database, RetentionPolicy, Measurement and Where properties.\n// From can be called multiple times to create multiple\n// independent forks of the data stream.\n//\n// Example:\n//    // Select the 'cpu' measurement from just the database 'mydb'\n//    // and retention policy 'myrp'.\n//    var cpu = stream\n//        |from()\n//            .database('mydb')\n//            .retentionPolicy('myrp')\n//            .measurement('cpu')\n//    // Select the 'load' measurement from any database and retention policy.\n//    var load = stream\n//        |from()\n//            .measurement('load')\n//    // Join cpu and load streams and do further processing.\n//    cpu\n//        |join(load)\n//            .as('cpu', 'load')\n//        ...\n//\nfunc (s *StreamNode) From() *FromNode {\n\tf := newFromNode()\n\ts.linkChild(f)\n\treturn f\n}\n\n// A FromNode selects a subset of the data flowing through a StreamNode.\n// The stream node allows you to select which portion of the stream you want to process.\n//\n// Example:\n//    stream\n//        |from()\n//           .database('mydb')\n//           .retentionPolicy('myrp')\n//           .measurement('mymeasurement')\n//           .where(lambda: \"host\" =~ /logger\\d+/)\n//        |window()\n//        ...\n//\n// The above example selects only data points from the database `mydb`\n// and retention policy `myrp` and measurement `mymeasurement` where\n// the tag `host` matches the regex `logger\\d+`\ntype FromNode struct {\n\tchainnode\n\n\t// An expression to filter the data stream.\n\t// tick:ignore\n\tLambda *ast.LambdaNode `tick:\"Where\"`\n\n\t// The dimensions by which to group to the data.\n\t// tick:ignore\n\tDimensions []interface{} `tick:\"GroupBy\"`\n\n\t// Whether to include the measurement in the group ID.\n\t// tick:ignore\n\tGroupByMeasurementFlag bool `tick:\"GroupByMeasurement\"`\n\n\t// The database name.\n\t// If empty any database will be used.\n\tDatabase string\n\n\t// The retention policy name\n\t// If empty any retention policy will be used.\n\tRetentionPolicy string\n\n\t// The measurement name\n\t// If empty any measurement will be used.\n\tMeasurement string\n\n\t// Optional duration for truncating timestamps.\n\t// Helpful to ensure data points land on specific boundaries\n\t// Example:\n\t//    stream\n\t//       |from()\n\t//           .measurement('mydata')\n\t//           .truncate(1s)\n\t//\n\t// All incoming data will be truncated to 1 second resolution.\n\tTruncate time.Duration\n\n\t// Optional duration for rounding timestamps.\n\t// Helpful to ensure data points land on specific boundaries\n\t// Example:\n\t//    stream\n\t//       |from()\n\t//           .measurement('mydata')\n\t//           .round(1s)\n\t//\n\t// All incoming data will be rounded to the nearest 1 second boundary.\n\tRound time.Duration\n}\n\nfunc newFromNode() *FromNode {\n\treturn &FromNode{\n\t\tchainnode: newBasicChainNode(\"from\", StreamEdge, StreamEdge),\n\t}\n}\n\n//tick:ignore\nfunc (n *FromNode) ChainMethods() map[string]reflect.Value {\n\treturn map[string]reflect.Value{\n\t\t\"GroupBy\": reflect.ValueOf(n.chainnode.GroupBy),\n\t\t\"Where\":   reflect.ValueOf(n.chainnode.Where),\n\t}\n}\n\n// Creates a new stream node that can be further\n// filtered using the Database, RetentionPolicy, Measurement and Where properties.\n// From can be called multiple times to create multiple\n// independent forks of the data stream.\n//\n// Example:\n//    // Select the 'cpu' measurement from just the database 'mydb'\n//    // and retention policy 'myrp'.\n//    var cpu = stream\n//        |from()\n//            .database('mydb')\n//            .retentionPolicy('myrp')\n//            .measurement('cpu')\n//    // Select the 'load' measurement from any database and retention policy.\n//    var load = stream\n//        |from()\n//            .measurement('load')\n//    // Join cpu and load streams and do further processing.\n//    cpu\n//        |join(load)\n//            .as('cpu', 'load')\n//        ...\n//\nfunc (s *FromNode) From() *FromNode {\n\tf := newFromNode()\n\ts.linkChild(f)\n\treturn f\n}\n\n// Filter the current stream using the given expression.\n// This expression is a Kapacitor expression. Kapacitor\n// expressions are a superset of InfluxQL WHERE expressions.\n// See the [expression](https://docs.influxdata.com/kapacitor/latest/tick/expr/) docs for more information.\n//\n// Multiple calls to the Where method will `AND` together each expression.\n//\n// Example:\n//    stream\n//       |from()\n//          .where(lambda: condition1)\n//          .where(lambda: condition2)\n//\n// The above is equivalent to this\n// Example:\n//    stream\n//       |from()\n//          .where(lambda: condition1 AND condition2)\n//\n//\n// NOTE: Becareful to always use `|from` if you want multiple different streams.\n//\n// Example:\n//  var data = stream\n//      |from()\n//          .measurement('cpu')\n//  var total = data\n//      .where(lambda: \"cpu\" == 'cpu-total')\n//  var others = data\n//      .where(lambda: \"cpu\" != 'cpu-total')\n//\n// The example above is equivalent to the example below,\n// which is obviously not what was intended.\n//\n// Example:\n//  var data = stream\n//      |from()\n//          .measurement('cpu')\n//          .where(lambda: \"cpu\" == 'cpu-total' AND \"cpu\" != 'cpu-total')\n//  var total = data\n//  var others = total\n//\n// The example below will create two different streams each selecting\n// a different subset of the original stream.\n//\n// Example:\n//  var data = stream\n//      |from()\n//          .measurement('cpu')\n//  var total = stream\n//      |from()\n//          .measurement('cpu')\n//          .where(lambda: \"cpu\" == 'cpu-total')\n//  var others = stream\n//      |from()\n//          .measurement('cpu')\n//          .where(lambda: \"cpu\" != 'cpu-total')\n//\n//\n// If empty then all data points are considered to match.\n// tick:property\nfunc (s *FromNode) Where(lambda *ast.LambdaNode) *FromNode {\n\tif s.Lambda != nil {\n\t\ts.Lambda.Expression = &ast.BinaryNode{\n\t\t\tOperator: ast.TokenAnd,\n\t\t\tLeft:     s.Lambda.Expression,\n\t\t\tRight:    lambda.Expression,\n\t\t}\n\t} else {\n\t\ts.Lambda = lambda\n\t}\n\treturn s\n}\n\n// Group the data by a set of tags.\n//\n// Can pass literal * to group by all dimensions.\n// Example:\n//  stream\n//      |from()\n//          .groupBy(*)\n// tick:property\nfunc (s *FromNode) GroupBy(tag ...interface{}) *FromNode {\n\ts.Dimensions = tag\n\treturn s\n}\n\n// If set will include the measurement name in the group ID.\n// Along with any other group by dimensions.\n//\n// Example:\n// stream\n//      |from()\n//          .database('mydb')\n//          .groupByMeasurement()\n//          .groupBy('host')\n//\n// The above example selects all measurements from the database 'mydb' and\n// then each point is grouped by the host tag and measurement name.\n// Thus keeping measurements in their own groups.\n// tick:property\nfunc (n *FromNode) GroupByMeasurement() *FromNode {\n\tn.GroupByMeasurementFlag = true\n\treturn n\n}\n\nfunc (s *FromNode) validate() error {\n\treturn validateDimensions(s.Dimensions, nil)\n}\n",
+	"switch.go":             "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\n\t\"github.com/influxdata/kapacitor/tick/ast\"\n)\n\n// A SwitchNode routes each point to exactly one of several named routes.\n//\n// Cases are evaluated in the order they are defined and each point is forwarded\n// to the route of the first case whose expression evaluates to true.\n// Points that match no case are forwarded to the default route, or dropped\n// if no default route is defined.\n// Points for which the expression of a case fails to evaluate are dropped.\n//\n// Children are attached to a route using the route property of the switch node.\n//\n// Example:\n//    var sw = stream\n//        |from()\n//            .measurement('cpu')\n//        |switch()\n//            .case('critical', lambda: \"usage_idle\" < 10)\n//            .case('warning', lambda: \"usage_idle\" < 30)\n//            .default('ok')\n//\n//    sw.route('critical')\n//        |alert()\n//            .crit(lambda: TRUE)\n//\n//    sw.route('warning')\n//        |influxDBOut()\n//            .database('mydb')\n//            .measurement('cpu_warnings')\n//\n// Points matching neither case are forwarded to the 'ok' route, which has no children and\n// so they are dropped.\n//\n// On a batch edge the points of each batch are split into one batch per route.\n// Routes that receive no points of a batch are not sent an empty batch.\n//\n// The number of points sent to each route is available in the node stats\n// as `route_<name>`.\ntype SwitchNode struct {\n\tchainnode\n\n\t// The ordered cases of the switch.\n\t// tick:ignore\n\tCases []*SwitchCase `tick:\"Case\"`\n\n\t// The name of the route for points matching no case.\n\t// tick:ignore\n\tDefaultRoute string `tick:\"Default\"`\n\n\t// The routes attached to the switch.\n\t// tick:ignore\n\tRoutes []*SwitchRouteNode `tick:\"Route\"`\n}\n\n// A named case of a SwitchNode.\ntype SwitchCase struct {\n\tName   string\n\tLambda *ast.LambdaNode\n}\n\nfunc newSwitchNode(wants EdgeType) *SwitchNode {\n\treturn &SwitchNode{\n\t\tchainnode: newBasicChainNode(\"switch\", wants, wants),\n\t}\n}\n\n// Add a named case to the switch.\n// Points for which the expression evaluates to true\n// and that matched no previous case are forwarded to the route of the same name.\n// tick:property\nfunc (n *SwitchNode) Case(name string, expression *ast.LambdaNode) *SwitchNode {\n\tn.Cases = append(n.Cases, &SwitchCase{\n\t\tName:   name,\n\t\tLambda: expression,\n\t})\n\treturn n\n}\n\n// Set the name of the route for points that match no case.\n// tick:property\nfunc (n *SwitchNode) Default(name string) *SwitchNode {\n\tn.DefaultRoute = name\n\treturn n\n}\n\n// Create a child of the switch that receives the points of the named route.\n// tick:property\nfunc (n *SwitchNode) Route(name string) *SwitchRouteNode {\n\tr := newSwitchRouteNode(n.provides, name)\n\tn.linkChild(r)\n\tn.Routes = append(n.Routes, r)\n\treturn r\n}\n\nfunc (n *SwitchNode) validate() error {\n\tif len(n.Cases) == 0 {\n\t\treturn errors.New(\"switch must have at least one case\")\n\t}\n\tnames := make(map[string]bool, len(n.Cases)+1)\n\tfor _, c := range n.Cases {\n\t\tif c.Name == \"\" {\n\t\t\treturn errors.New(\"switch case name must not be empty\")\n\t\t}\n\t\tif c.Lambda == nil {\n\t\t\treturn fmt.Errorf(\"switch case %q must have an expression\", c.Name)\n\t\t}\n\t\tif names[c.Name] {\n\t\t\treturn fmt.Errorf(\"duplicate switch case %q\", c.Name)\n\t\t}\n\t\tnames[c.Name] = true\n\t}\n\tif n.DefaultRoute != \"\" {\n\t\tif names[n.DefaultRoute] {\n\t\t\treturn fmt.Errorf(\"default route %q has the same name as a case\", n.DefaultRoute)\n\t\t}\n\t\tnames[n.DefaultRoute] = true\n\t}\n\tfor _, c := range n.Children() {\n\t\tr, ok := c.(*SwitchRouteNode)\n\t\tif !ok {\n\t\t\treturn fmt.Errorf(\"children of a switch must be attached using route, got %s\", c.Name())\n\t\t}\n\t\tif !names[r.Case] {\n\t\t\treturn fmt.Errorf(\"unknown switch route %q\", r.Case)\n\t\t}\n\t}\n\treturn nil\n}\n\n// A SwitchRouteNode passes on the points of a single route of a SwitchNode.\n// Use the SwitchNode.Route property to create a SwitchRouteNode.\ntype SwitchRouteNode struct {\n\tchainnode\n\n\t// The name of the case or default route.\n\t// tick:ignore\n\tCase string\n}\n\nfunc newSwitchRouteNode(wants EdgeType, name string) *SwitchRouteNode {\n\treturn &SwitchRouteNode{\n\t\tchainnode: newBasicChainNode(\"route\", wants, wants),\n\t\tCase:      name,\n\t}\n}\n",
+	"udf.go":                "package pipeline\n\nimport (\n\t\"fmt\"\n\t\"time\"\n\n\t\"github.com/influxdata/kapacitor/tick\"\n\t\"github.com/influxdata/kapacitor/udf/agent\"\n)\n\n// A UDFNode is a node that can run a User Defined Function (UDF) in a separate process.\n//\n// A UDF is a custom script or binary that can communicate via Kapacitor's UDF RPC protocol.\n// The path and arguments to the UDF program are specified in Kapacitor's configuration.\n// Using TICKscripts you can invoke and configure your UDF for each task.\n//\n// See the [README.md](https://github.com/influxdata/kapacitor/tree/master/udf/agent/)\n// for details on how to write your own UDF.\n//\n// UDFs are configured via Kapacitor's main configuration file.\n//\n// Example:\n//    [udf]\n//    [udf.functions]\n//        # Example moving average UDF.\n//        [udf.functions.movingAverage]\n//            prog = \"/path/to/executable/moving_avg\"\n//            args = []\n//            timeout = \"10s\"\n//\n// UDFs are first class objects in TICKscripts and are referenced via their configuration name.\n//\n// Example:\n//     // Given you have a UDF that computes a moving average\n//     // The UDF can define what its options are and then can be\n//     // invoked via a TICKscript like so:\n//     stream\n//         |from()...\n//         @movingAverage()\n//             .field('value')\n//             .size(100)\n//             .as('mavg')\n//         |httpOut('movingaverage')\n//\n// NOTE: The UDF process runs as the same user as the Kapacitor daemon.\n// As a result make the user is properly secured as well as the configuration file.\ntype UDFNode struct {\n\tchainnode\n\n\tUDFName string\n\toptions map[string]*agent.OptionInfo\n\n\t// Options that were set on the node\n\t// tick:ignore\n\tOptions []*agent.Option\n\n\tdescriber *tick.ReflectionDescriber\n}\n\nfunc NewUDF(\n\tparent Node,\n\tname string,\n\twants,\n\tprovides agent.EdgeType,\n\toptions map[string]*agent.OptionInfo,\n) *UDFNode {\n\tvar pwants, pprovides EdgeType\n\tswitch wants {\n\tcase agent.EdgeType_STREAM:\n\t\tpwants = StreamEdge\n\tcase agent.EdgeType_BATCH:\n\t\tpwants = BatchEdge\n\t}\n\tswitch provides {\n\tcase agent.EdgeType_STREAM:\n\t\tpprovides = StreamEdge\n\tcase agent.EdgeType_BATCH:\n\t\tpprovides = BatchEdge\n\t}\n\tudf := &UDFNode{\n\t\tchainnode: newBasicChainNode(name, pwants, pprovides),\n\t\tUDFName:   name,\n\t\toptions:   options,\n\t}\n\tudf.describer, _ = tick.NewReflectionDescriber(udf, nil)\n\tparent.linkChild(udf)\n\treturn udf\n}\n\n// tick:ignore\nfunc (u *UDFNode) Desc() string {\n\treturn u.UDFName\n}\n\n// tick:ignore\nfunc (u *UDFNode) HasChainMethod(name string) bool {\n\treturn u.describer.HasChainMethod(name)\n}\n\n// tick:ignore\nfunc (u *UDFNode) CallChainMethod(name string, args ...interface{}) (interface{}, error) {\n\treturn u.describer.CallChainMethod(name, args...)\n}\n\n// tick:ignore\nfunc (u *UDFNode) HasProperty(name string) bool {\n\t_, ok := u.options[name]\n\tif ok {\n\t\treturn ok\n\t}\n\treturn u.describer.HasProperty(name)\n}\n\n// tick:ignore\nfunc (u *UDFNode) Property(name string) interface{} {\n\treturn u.describer.Property(name)\n}\n\n// tick:ignore\nfunc (u *UDFNode) SetProperty(name string, args ...interface{}) (interface{}, error) {\n\topt, ok := u.options[name]\n\tif ok {\n\t\tif got, exp := len(args), len(opt.ValueTypes); got != exp {\n\t\t\treturn nil, fmt.Errorf(\"unexpected number of args to %s, got %d expected %d\", name, got, exp)\n\t\t}\n\t\tvalues := make([]*agent.OptionValue, len(args))\n\t\tfor i, arg := range args {\n\t\t\tvalues[i] = &agent.OptionValue{}\n\t\t\tswitch v := arg.(type) {\n\t\t\tcase bool:\n\t\t\t\tvalues[i].Type = agent.ValueType_BOOL\n\t\t\t\tvalues[i].Value = &agent.OptionValue_BoolValue{v}\n\t\t\tcase int64:\n\t\t\t\tvalues[i].Type = agent.ValueType_INT\n\t\t\t\tvalues[i].Value = &agent.OptionValue_IntValue{v}\n\t\t\tcase float64:\n\t\t\t\tvalues[i].Type = agent.ValueType_DOUBLE\n\t\t\t\tvalues[i].Value = &agent.OptionValue_DoubleValue{v}\n\t\t\tcase string:\n\t\t\t\tvalues[i].Type = agent.ValueType_STRING\n\t\t\t\tvalues[i].Value = &agent.OptionValue_StringValue{v}\n\t\t\tcase time.Duration:\n\t\t\t\tvalues[i].Type = agent.ValueType_DURATION\n\t\t\t\tvalues[i].Value = &agent.OptionValue_DurationValue{int64(v)}\n\t\t\t}\n\t\t\tif values[i].Type != opt.ValueTypes[i] {\n\t\t\t\treturn nil, fmt.Errorf(\"unexpected arg to %s, got %v expected %v\", name, values[i].Type, opt.ValueTypes[i])\n\t\t\t}\n\t\t}\n\t\tu.Options = append(u.Options, &agent.Option{\n\t\t\tName:   name,\n\t\t\tValues: values,\n\t\t})\n\t\treturn u, nil\n\t}\n\treturn u.describer.SetProperty(name, args...)\n}\n",
+	"union.go":              "package pipeline\n\n// Takes the union of all of its parents.\n// The union is just a simple pass through.\n// Each data points received from each parent is passed onto children nodes\n// without modification.\n//\n// Example:\n//    var logins = stream\n//        |from()\n//            .measurement('logins')\n//    var logouts = stream\n//        |from()\n//            .measurement('logouts')\n//    var frontpage = stream\n//        |from()\n//            .measurement('frontpage')\n//    // Union all user actions into a single stream\n//    logins\n//        |union(logouts, frontpage)\n//            .rename('user_actions')\n//        ...\n//\ntype UnionNode struct {\n\tchainnode\n\t// The new name of the stream.\n\t// If empty the name of the left node\n\t// (i.e. `leftNode.union(otherNode1, otherNode2)`) is used.\n\tRename string\n}\n\nfunc newUnionNode(e EdgeType, nodes []Node) *UnionNode {\n\tu := &UnionNode{\n\t\tchainnode: newBasicChainNode(\"union\", e, e),\n\t}\n\tfor _, n := range nodes {\n\t\tn.linkChild(u)\n\t}\n\treturn u\n}\n",
+	"where.go":              "package pipeline\n\nimport \"github.com/influxdata/kapacitor/tick/ast\"\n\n// The WhereNode filters the data stream by a given expression.\n//\n// Example:\n// var sums = stream\n//     |from()\n//         .groupBy('service', 'host')\n//     |sum('value')\n// //Watch particular host for issues.\n// sums\n//    |where(lambda: \"host\" == 'h001.example.com')\n//    |alert()\n//        .crit(lambda: TRUE)\n//        .email().to('user@example.com')\n//\ntype WhereNode struct {\n\tchainnode\n\t// The expression predicate.\n\t// tick:ignore\n\tLambda *ast.LambdaNode\n}\n\nfunc newWhereNode(wants EdgeType, predicate *ast.LambdaNode) *WhereNode {\n\treturn &WhereNode{\n\t\tchainnode: newBasicChainNode(\"where\", wants, wants),\n\t\tLambda:    predicate,\n\t}\n}\n",
+	"window.go":             "package pipeline\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n\t\"time\"\n)\n\n// A `window` node caches data within a moving time range.\n// The `period` property of `window` defines the time range covered by `window`.\n//\n// The `every` property of `window` defines the frequency at which the window\n// is emitted to the next node in the pipeline.\n//\n//The `align` property of `window` defines how to align the window edges.\n//(By default, the edges are defined relative to the first data point the `window`\n//node receives.)\n//\n// Example:\n//    stream\n//        |window()\n//            .period(10m)\n//            .every(5m)\n//        |httpOut('recent')\n//\n// his example emits the last `10 minute` period  every `5 minutes` to the pipeline's `httpOut` node.\n// Because `every` is less than `period`, each time the window is emitted it contains `5 minutes` of\n// new data and `5 minutes` of the previous period's data.\n//\n// NOTE: Because no `align` property is defined, the `window` edge is defined relative to the first data point.\ntype WindowNode struct {\n\tchainnode\n\t// The period, or length in time, of the window.\n\tPeriod time.Duration\n\t// How often the current window is emitted into the pipeline.\n\t// If equal to zero, then every new point will emit the current window.\n\tEvery time.Duration\n\t// Whether to align the window edges with the zero time\n\t// tick:ignore\n\tAlignFlag bool `tick:\"Align\"`\n\t// Whether to wait till the period is full before the first emit.\n\t// tick:ignore\n\tFillPeriodFlag bool `tick:\"FillPeriod\"`\n\n\t// PeriodCount is the number of points per window.\n\tPeriodCount int64\n\t// EveryCount determines how often the window is emitted based on the count of points.\n\t// A value of 1 means that every new point will emit the window.\n\tEveryCount int64\n}\n\nfunc newWindowNode() *WindowNode {\n\treturn &WindowNode{\n\t\tchainnode: newBasicChainNode(\"window\", StreamEdge, BatchEdge),\n\t}\n}\n\n// If the `align` property is not used to modify the `window` node, then the\n// window alignment is assumed to start at the time of the first data point it receives.\n// If `align` property is set, the window time edges\n// will be truncated to the `every` property (For example, if a data point's time\n// is 12:06 and the `every` property is `5m` then the data point's window will range\n// from 12:05 to 12:10).\n// tick:property\nfunc (w *WindowNode) Align() *WindowNode {\n\tw.AlignFlag = true\n\treturn w\n}\n\n// FillPeriod instructs the WindowNode to wait till the period has elapsed before emitting the first batch.\n// This only applies if the period is greater than the every value.\n// tick:property\nfunc (w *WindowNode) FillPeriod() *WindowNode {\n\tw.FillPeriodFlag = true\n\treturn w\n}\n\nfunc (w *WindowNode) validate() error {\n\tif w.PeriodCount != 0 && w.Period != 0 {\n\t\treturn errors.New(\"cannot specify both period and periodCount\")\n\t}\n\tif w.PeriodCount != 0 && w.AlignFlag {\n\t\treturn errors.New(\"can only align windows based off time, not count\")\n\t}\n\tif w.PeriodCount != 0 && w.EveryCount <= 0 {\n\t\treturn fmt.Errorf(\"everyCount must be greater than zero\")\n\t}\n\treturn nil\n}\n",
+}
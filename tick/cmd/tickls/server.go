@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/influxdata/kapacitor/tick"
+)
+
+// Server is a language server for TICKscripts,
+// communicating with the editor over a pair of streams.
+type Server struct {
+	in   *bufio.Reader
+	docs *docs
+
+	// Guards writes to out.
+	mu  sync.Mutex
+	out io.Writer
+
+	documents map[string]string
+	shutdown  bool
+
+	logger *log.Logger
+}
+
+// NewServer creates a server that reads messages from r and writes messages to w.
+// The documentation of the pipeline nodes is optional.
+func NewServer(r io.Reader, w io.Writer, d *docs, l *log.Logger) *Server {
+	if l == nil {
+		l = log.New(ioutil.Discard, "", 0)
+	}
+	return &Server{
+		in:        bufio.NewReader(r),
+		out:       w,
+		docs:      d,
+		documents: make(map[string]string),
+		logger:    l,
+	}
+}
+
+// Serve handles messages until the editor sends the exit notification or closes the stream.
+func (s *Server) Serve() error {
+	for {
+		data, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			s.logger.Println("E! invalid message:", err)
+			if err := s.respond(nil, nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		s.logger.Println("D! received", req.Method)
+		if req.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(req)
+		if req.ID == nil {
+			// Notifications do not get a response
+			if err != nil {
+				s.logger.Printf("E! failed to handle %s: %v", req.Method, err)
+			}
+			continue
+		}
+		var rerr *responseError
+		if err != nil {
+			if e, ok := err.(*responseError); ok {
+				rerr = e
+			} else {
+				rerr = &responseError{Code: codeRequestFailed, Message: err.Error()}
+			}
+		}
+		if err := s.respond(req.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) respond(id *json.RawMessage, result interface{}, rerr *responseError) error {
+	resp := response{
+		JSONRPC: jsonrpcVersion,
+		ID:      id,
+		Error:   rerr,
+	}
+	if rerr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = data
+	}
+	return s.write(resp)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(notification{
+		JSONRPC: jsonrpcVersion,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (s *Server) write(v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeMessage(s.out, v)
+}
+
+func (s *Server) handle(req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync: syncFull,
+				CompletionProvider: &CompletionOptions{
+					TriggerCharacters: []string{"|", ".", "@"},
+				},
+				HoverProvider:              true,
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "tickls"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		s.documents[params.TextDocument.URI] = params.TextDocument.Text
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if l := len(params.ContentChanges); l > 0 {
+			// Only full document sync is supported so the last change is the content of the document.
+			s.documents[params.TextDocument.URI] = params.ContentChanges[l-1].Text
+		}
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		text, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return CompletionList{
+			Items: s.complete(text, offset(text, params.Position)),
+		}, nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		text, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		h := s.hover(text, offset(text, params.Position))
+		if h == nil {
+			// Marshals as null, meaning there is nothing to show.
+			return nil, nil
+		}
+		return h, nil
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		text, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		formatted, err := tick.Format(text)
+		if err != nil {
+			return nil, err
+		}
+		if formatted == text {
+			return []TextEdit{}, nil
+		}
+		return []TextEdit{{
+			Range: Range{
+				Start: Position{},
+				End:   position(text, len(text)),
+			},
+			NewText: formatted,
+		}}, nil
+	}
+	if req.ID == nil {
+		// Notifications the server does not understand are ignored
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found %q", req.Method)}
+}
+
+func (s *Server) document(uri string) (string, error) {
+	text, ok := s.documents[uri]
+	if !ok {
+		return "", fmt.Errorf("unknown document %q", uri)
+	}
+	return text, nil
+}
+
+func (s *Server) publishDiagnostics(uri string) error {
+	text, err := s.document(uri)
+	if err != nil {
+		return err
+	}
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnose(uri, text),
+	})
+}
+
+func unmarshalParams(data json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return &responseError{Code: codeParseError, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return nil
+}
+
+// lineBounds returns the byte offsets of the start and end of the zero based line,
+// excluding the newline. Lines past the end of the text are clamped to the end of the text.
+func lineBounds(text string, line int) (start, end int) {
+	for ; line > 0; line-- {
+		i := strings.IndexByte(text[start:], '\n')
+		if i == -1 {
+			return len(text), len(text)
+		}
+		start += i + 1
+	}
+	end = strings.IndexByte(text[start:], '\n')
+	if end == -1 {
+		return start, len(text)
+	}
+	return start, start + end
+}
+
+// offset returns the byte offset in the text of the position.
+// The character of the position is in UTF-16 code units, as defined by the protocol.
+// Positions before the start or past the end of a line are clamped to the line.
+func offset(text string, p Position) int {
+	o, end := lineBounds(text, p.Line)
+	for units := 0; units < p.Character && o < end; {
+		r, size := utf8.DecodeRuneInString(text[o:end])
+		units += utf16Len(r)
+		o += size
+	}
+	return o
+}
+
+// position returns the position of the byte offset in the text.
+// The character of the position is in UTF-16 code units.
+func position(text string, o int) Position {
+	if o > len(text) {
+		o = len(text)
+	}
+	var p Position
+	for _, r := range text[:o] {
+		if r == '\n' {
+			p.Line++
+			p.Character = 0
+		} else {
+			p.Character += utf16Len(r)
+		}
+	}
+	return p
+}
+
+// utf16Len returns the number of UTF-16 code units needed to encode the rune.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
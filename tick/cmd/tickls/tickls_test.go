@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// session is a recorded exchange of messages between an editor and the server.
+//
+// Lines starting with '-->' are messages sent by the editor
+// and lines starting with '<--' are the messages expected from the server, in order.
+// Other lines are ignored.
+type session struct {
+	sent     []string
+	received []string
+}
+
+func readSession(path string) (session, error) {
+	var s session
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "--> "):
+			s.sent = append(s.sent, line[4:])
+		case strings.HasPrefix(line, "<-- "):
+			s.received = append(s.received, line[4:])
+		}
+	}
+	return s, nil
+}
+
+func TestSessions(t *testing.T) {
+	d, err := loadDocs("../../../pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob("testdata/*.session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no recorded sessions found")
+	}
+	for _, file := range files {
+		s, err := readSession(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var in, out bytes.Buffer
+		for _, msg := range s.sent {
+			if err := writeMessage(&in, json.RawMessage(msg)); err != nil {
+				t.Fatalf("%s: invalid message %s: %v", file, msg, err)
+			}
+		}
+		if err := NewServer(&in, &out, d, nil).Serve(); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+
+		r := bufio.NewReader(&out)
+		for i, exp := range s.received {
+			data, err := readMessage(r)
+			if err != nil {
+				t.Fatalf("%s: missing message %d: %v", file, i, err)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("%s: invalid message %d: %v", file, i, err)
+			}
+			if err := json.Unmarshal([]byte(exp), &want); err != nil {
+				t.Fatalf("%s: invalid expected message %d: %v", file, i, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: unexpected message %d:\ngot\n%s\nexp\n%s", file, i, data, exp)
+			}
+		}
+		if data, err := readMessage(r); err == nil {
+			t.Errorf("%s: unexpected extra message:\n%s", file, data)
+		}
+	}
+}
+
+func TestOffsetPosition(t *testing.T) {
+	// The emoji is two UTF-16 code units and four bytes,
+	// the accented letter is one UTF-16 code unit and two bytes.
+	text := "var x = '😀é'\n|log()"
+	testCases := []struct {
+		p   Position
+		exp int
+		// The position of the offset, if different from p.
+		back *Position
+	}{
+		{p: Position{Line: 0, Character: 9}, exp: 9},
+		{p: Position{Line: 0, Character: 11}, exp: 13},
+		{p: Position{Line: 0, Character: 12}, exp: 15},
+		{p: Position{Line: 0, Character: 13}, exp: 16},
+		{p: Position{Line: 0, Character: 20}, exp: 16, back: &Position{Line: 0, Character: 13}},
+		{p: Position{Line: 0, Character: -3}, exp: 0, back: &Position{}},
+		{p: Position{Line: 1, Character: 1}, exp: 18},
+		{p: Position{Line: 5, Character: 1}, exp: len(text), back: &Position{Line: 1, Character: 6}},
+	}
+	for _, tc := range testCases {
+		o := offset(text, tc.p)
+		if o != tc.exp {
+			t.Errorf("unexpected offset of %+v got: %d exp: %d", tc.p, o, tc.exp)
+			continue
+		}
+		exp := tc.p
+		if tc.back != nil {
+			exp = *tc.back
+		}
+		if got := position(text, o); got != exp {
+			t.Errorf("unexpected position of %d got: %+v exp: %+v", o, got, exp)
+		}
+	}
+}
+
+func TestPipelineSource(t *testing.T) {
+	files, err := filepath.Glob("../../../pipeline/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := make(map[string]string)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp[filepath.Base(file)] = string(src)
+	}
+	if !reflect.DeepEqual(pipelineSource, exp) {
+		t.Error("the generated source of the pipeline package is out of date, run go generate")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -61,10 +62,38 @@ type PartialDescriber interface {
 	ChainMethods() map[string]reflect.Value
 }
 
+// Positioner is implemented by objects returned from chaining methods
+// that record where in the TICKscript they were created.
+type Positioner interface {
+	SetPosition(p ast.Position)
+}
+
+// Importer resolves the TICKscript of a module imported via an import statement.
+type Importer interface {
+	Import(path string) (string, error)
+}
+
+// The identifier used as the root of a chain fragment.
+//
+// Example:
+//    var alertTail = fragment
+//        |alert()
+//            .crit(lambda: "value" > 10)
+//
+// A fragment is applied to a node as a dynamic method, i.e. 'stream|from()@alertTail()'.
+const FragmentRoot = "fragment"
+
 // Parse and evaluate a given script for the scope.
 // Returns a set of default vars.
 // If a set of predefined vars is provided, they may effect the default var values.
-func Evaluate(script string, scope *stateful.Scope, predefinedVars map[string]Var, ignoreMissingVars bool) (_ map[string]Var, err error) {
+func Evaluate(script string, scope *stateful.Scope, predefinedVars map[string]Var, ignoreMissingVars bool) (map[string]Var, error) {
+	return EvaluateWithImporter(script, scope, predefinedVars, ignoreMissingVars, nil)
+}
+
+// EvaluateWithImporter is the same as Evaluate,
+// except modules imported by the script are resolved using the importer.
+// The importer may be nil if the script has no imports.
+func EvaluateWithImporter(script string, scope *stateful.Scope, predefinedVars map[string]Var, ignoreMissingVars bool, importer Importer) (_ map[string]Var, err error) {
 	defer func(errP *error) {
 		r := recover()
 		if r == ErrEmptyStack {
@@ -81,6 +110,12 @@ func Evaluate(script string, scope *stateful.Scope, predefinedVars map[string]Va
 	if err != nil {
 		return nil, err
 	}
+	if program, ok := root.(*ast.ProgramNode); ok {
+		err = resolveImports(program, importer, nil, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Use a stack machine to evaluate the AST
 	stck := &stack{}
@@ -93,6 +128,56 @@ func Evaluate(script string, scope *stateful.Scope, predefinedVars map[string]Va
 	return defaultVars, nil
 }
 
+// resolveImports replaces the import statements of the program with the statements of the imported modules.
+// Each module is imported only once, importing is the list of modules currently being imported and is used to detect cycles.
+func resolveImports(program *ast.ProgramNode, importer Importer, importing []string, imported map[string]bool) error {
+	nodes := make([]ast.Node, 0, len(program.Nodes))
+	for _, n := range program.Nodes {
+		imp, ok := n.(*ast.ImportNode)
+		if !ok {
+			nodes = append(nodes, n)
+			continue
+		}
+		path := imp.Path.Literal
+		for _, p := range importing {
+			if p == path {
+				return errorf(imp, "import cycle: %s -> %s", strings.Join(importing, " -> "), path)
+			}
+		}
+		if imported[path] {
+			continue
+		}
+		imported[path] = true
+		if importer == nil {
+			return errorf(imp, "cannot import %q, imports are not supported", path)
+		}
+		script, err := importer.Import(path)
+		if err != nil {
+			return wrapError(imp, err)
+		}
+		root, err := ast.Parse(script)
+		if err != nil {
+			return errorf(imp, "failed to parse module %q: %v", path, err)
+		}
+		module, ok := root.(*ast.ProgramNode)
+		if !ok {
+			continue
+		}
+		if err := resolveImports(module, importer, append(importing, path), imported); err != nil {
+			return err
+		}
+		for _, n := range module.Nodes {
+			if _, ok := n.(*ast.DBRPNode); ok {
+				// dbrps are declared by the task, not its modules.
+				continue
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	program.Nodes = nodes
+	return nil
+}
+
 func errorf(p ast.Position, fmtStr string, args ...interface{}) error {
 	lineStr := fmt.Sprintf("line %d char %d: %s", p.Line(), p.Char(), fmtStr)
 	return fmt.Errorf(lineStr, args...)
@@ -177,7 +262,20 @@ func eval(n ast.Node, scope *stateful.Scope, stck *stack, predefinedVars, defaul
 		if err != nil {
 			return
 		}
+	case *ast.DBRPNode:
+		// dbrp statements are task metadata and have no effect on evaluation.
+	case *ast.ImportNode:
+		return errorf(node, "unresolved import %q", node.Path.Literal)
+	case *ast.FuncDeclarationNode:
+		err = evalFuncDeclaration(node, scope)
+		if err != nil {
+			return
+		}
 	case *ast.DeclarationNode:
+		if isFragment(node.Right) {
+			err = evalFragment(node, scope, predefinedVars, defaultVars, ignoreMissingVars)
+			return
+		}
 		err = eval(node.Right, scope, stck, predefinedVars, defaultVars, ignoreMissingVars)
 		if err != nil {
 			return
@@ -429,6 +527,88 @@ func evalDeclaration(node *ast.DeclarationNode, scope *stateful.Scope, stck *sta
 	return nil
 }
 
+// isFragment reports whether the node is a chain with the fragment root.
+func isFragment(n ast.Node) bool {
+	c, ok := n.(*ast.ChainNode)
+	if !ok {
+		return false
+	}
+	for ; ok; c, ok = n.(*ast.ChainNode) {
+		n = c.Left
+	}
+	ident, ok := n.(*ast.IdentifierNode)
+	return ok && ident.Ident == FragmentRoot
+}
+
+// evalFragment defines a dynamic method that applies the chain of the fragment declaration to a node.
+func evalFragment(node *ast.DeclarationNode, scope *stateful.Scope, predefinedVars, defaultVars map[string]Var, ignoreMissingVars bool) error {
+	name := node.Left.Ident
+	if scope.DynamicMethod(name) != nil {
+		return errorf(node, "attempted to redefine %s, a dynamic method with the same name already exists", name)
+	}
+	// Collect the links of the chain from the root outwards.
+	var links []*ast.ChainNode
+	for c, ok := node.Right.(*ast.ChainNode); ok; c, ok = c.Left.(*ast.ChainNode) {
+		links = append([]*ast.ChainNode{c}, links...)
+	}
+	scope.SetDynamicMethod(name, func(self interface{}, args ...interface{}) (interface{}, error) {
+		if len(args) != 0 {
+			return nil, errorf(node, "fragment %s does not take any arguments", name)
+		}
+		stck := &stack{}
+		stck.Push(self)
+		for _, c := range links {
+			if err := eval(c.Right, scope, stck, predefinedVars, defaultVars, ignoreMissingVars); err != nil {
+				return nil, err
+			}
+			if err := evalChain(c, scope, stck); err != nil {
+				return nil, err
+			}
+		}
+		return stck.Pop(), nil
+	})
+	return nil
+}
+
+// evalFuncDeclaration defines a function that can be called from lambda expressions.
+// The body may only call functions declared before it, so functions cannot be recursive.
+func evalFuncDeclaration(node *ast.FuncDeclarationNode, scope *stateful.Scope) error {
+	name := node.Name.Ident
+	if v, _ := scope.Get(name); v != nil {
+		return errorf(node, "attempted to redefine %s, vars are immutable", name)
+	}
+	params := make(map[string]bool, len(node.Params))
+	for _, p := range node.Params {
+		params[p.Ident] = true
+	}
+	body, err := ast.Walk(node.Body, func(n ast.Node) (ast.Node, error) {
+		switch n := n.(type) {
+		case *ast.IdentifierNode:
+			if params[n.Ident] {
+				// Parameters are references to the args of the call.
+				return &ast.ReferenceNode{Reference: n.Ident}, nil
+			}
+		case *ast.FunctionNode:
+			if n.Func == name {
+				return nil, errorf(n, "function %s cannot call itself, recursion is not allowed", name)
+			}
+		}
+		return n, nil
+	})
+	if err != nil {
+		return err
+	}
+	node.Body, err = resolveIdents(body, scope)
+	if err != nil {
+		return wrapError(node, err)
+	}
+	if err := stateful.ValidateFuncDeclaration(node); err != nil {
+		return wrapError(node, err)
+	}
+	scope.Set(name, node)
+	return nil
+}
+
 func evalChain(p ast.Position, scope *stateful.Scope, stck *stack) error {
 	r := stck.Pop()
 	l := stck.Pop()
@@ -533,7 +713,13 @@ func evalFunc(f *ast.FunctionNode, scope *stateful.Scope, stck *stack, args []in
 		case ast.ChainFunc:
 			if describer.HasChainMethod(name) {
 				o, err := describer.CallChainMethod(name, args...)
-				return o, wrapError(f, err)
+				if err != nil {
+					return nil, wrapError(f, err)
+				}
+				if p, ok := o.(Positioner); ok {
+					p.SetPosition(f)
+				}
+				return o, nil
 			}
 			if describer.HasProperty(name) {
 				return nil, errorf(f, "no chaining method %q on %T, but property does exist. Use '.' operator instead: 'node.%s(..)'.", name, obj, name)
@@ -809,6 +995,31 @@ func (r *ReflectionDescriber) CallChainMethod(name string, args ...interface{})
 	return nil, fmt.Errorf("unknown method %s on %T", name, r.obj)
 }
 
+// ChainMethodNames returns the sorted names of the chain methods of the object as written in a TICKscript.
+func (r *ReflectionDescriber) ChainMethodNames() []string {
+	names := make([]string, 0, len(r.chainMethods))
+	for name := range r.chainMethods {
+		names = append(names, lowerFirst(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PropertyNames returns the sorted names of the properties of the object as written in a TICKscript.
+func (r *ReflectionDescriber) PropertyNames() []string {
+	names := make([]string, 0, len(r.propertyMethods)+len(r.properties))
+	for name := range r.propertyMethods {
+		names = append(names, lowerFirst(name))
+	}
+	for name := range r.properties {
+		if _, ok := r.propertyMethods[name]; !ok {
+			names = append(names, lowerFirst(name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Using reflection check if the object has a field with the property name.
 func (r *ReflectionDescriber) HasProperty(name string) bool {
 	name = capitalizeFirst(name)
@@ -876,6 +1087,12 @@ func capitalizeFirst(s string) string {
 	return s
 }
 
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	s = string(unicode.ToLower(r)) + s[n:]
+	return s
+}
+
 // Resolve all identifiers immediately in the tree with their value from the scope.
 // This operation is performed in place.
 // Panics if the scope value does not exist or if the value cannot be expressed as a literal.
@@ -912,6 +1129,15 @@ func resolveIdents(n ast.Node, scope *stateful.Scope) (_ ast.Node, err error) {
 				return nil, err
 			}
 		}
+		if scope.Has(node.Func) {
+			v, _ := scope.Get(node.Func)
+			if def, ok := v.(*ast.FuncDeclarationNode); ok {
+				if got, exp := len(node.Args), len(def.Params); got != exp {
+					return nil, errorf(node, "function %s takes %d arguments, got %d", node.Func, exp, got)
+				}
+				node.Definition = def
+			}
+		}
 	case *ast.ProgramNode:
 		for i, n := range node.Nodes {
 			node.Nodes[i], err = resolveIdents(n, scope)
@@ -135,6 +135,220 @@ s2|structC()
 	}
 }
 
+type mapImporter map[string]string
+
+func (m mapImporter) Import(path string) (string, error) {
+	script, ok := m[path]
+	if !ok {
+		return "", fmt.Errorf("unknown module %q", path)
+	}
+	return script, nil
+}
+
+func TestEvaluate_Import(t *testing.T) {
+	importer := mapImporter{
+		"shared/fields.tick": `var f2 = 42`,
+		"shared/tail.tick": `
+import 'shared/fields.tick'
+
+var tail = fragment
+    |structC()
+        .options('c', 21.5, 7h)
+`,
+	}
+	script := `
+import 'shared/tail.tick'
+import 'shared/fields.tick'
+
+var s2 = a|structB()
+			.field2(f2)
+
+var s3 = s2@tail()
+`
+
+	scope := stateful.NewScope()
+	a := &structA{}
+	scope.Set("a", a)
+
+	vars, err := tick.EvaluateWithImporter(script, scope, nil, false, importer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := (tick.Var{Type: ast.TInt, Value: int64(42)}), vars["f2"]; !reflect.DeepEqual(exp, got) {
+		t.Errorf("unexpected module var exp: %v got: %v", exp, got)
+	}
+
+	s2I, err := scope.Get("s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2 := s2I.(*structB)
+	if s2.Field2 != 42 {
+		t.Errorf("unexpected field2 exp: 42 got: %d", s2.Field2)
+	}
+	s3I, err := scope.Get("s3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s3I != s2.c {
+		t.Fatalf("fragment was not applied to s2, got %v", s3I)
+	}
+	exp := structC{
+		field1: "c",
+		field2: 21.5,
+		field3: time.Hour * 7,
+	}
+	if got := *s2.c; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected s3 exp: %v got: %v", exp, got)
+	}
+}
+
+func TestEvaluate_ImportErrors(t *testing.T) {
+	testCases := []struct {
+		script   string
+		importer tick.Importer
+		err      string
+	}{
+		{
+			script: `import 'a.tick'`,
+			err:    `line 1 char 1: cannot import "a.tick", imports are not supported`,
+		},
+		{
+			script:   `import 'a.tick'`,
+			importer: mapImporter{},
+			err:      `line 1 char 1: unknown module "a.tick"`,
+		},
+		{
+			script: `import 'a.tick'`,
+			importer: mapImporter{
+				"a.tick": `import 'b.tick'`,
+				"b.tick": `import 'a.tick'`,
+			},
+			err: `line 1 char 1: import cycle: a.tick -> b.tick -> a.tick`,
+		},
+		{
+			script: `import 'a.tick'
+var s = a@tail(1)`,
+			importer: mapImporter{
+				"a.tick": `var tail = fragment|structB()`,
+			},
+			err: `line 1 char 1: fragment tail does not take any arguments`,
+		},
+	}
+	for _, tc := range testCases {
+		scope := stateful.NewScope()
+		scope.Set("a", &structA{})
+		_, err := tick.EvaluateWithImporter(tc.script, scope, nil, false, tc.importer)
+		if err == nil {
+			t.Errorf("expected error %q for script %q", tc.err, tc.script)
+			continue
+		}
+		if got := err.Error(); got != tc.err {
+			t.Errorf("unexpected error for script %q\nexp: %s\ngot: %s", tc.script, tc.err, got)
+		}
+	}
+}
+
+func TestEvaluate_FuncDeclaration(t *testing.T) {
+	importer := mapImporter{
+		"shared/funcs.tick": `func pct(a, b) = if(b == 0, 0.0, a / b * 100.0)`,
+	}
+	script := `
+import 'shared/funcs.tick'
+
+var threshold = 90.0
+
+func over(a, b) = pct(a, b) > threshold
+
+var l = lambda: over("used", "total")
+`
+
+	scope := stateful.NewScope()
+	if _, err := tick.EvaluateWithImporter(script, scope, nil, false, importer); err != nil {
+		t.Fatal(err)
+	}
+	lI, err := scope.Get("l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := stateful.NewExpression(lI.(*ast.LambdaNode).Expression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		used, total float64
+		exp         bool
+	}{
+		{used: 95, total: 100, exp: true},
+		{used: 50, total: 100, exp: false},
+		{used: 50, total: 0, exp: false},
+	}
+	for _, tc := range testCases {
+		s := stateful.NewScope()
+		s.Set("used", tc.used)
+		s.Set("total", tc.total)
+		got, err := expr.EvalBool(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.exp {
+			t.Errorf("unexpected result for used: %v total: %v exp: %v got: %v", tc.used, tc.total, tc.exp, got)
+		}
+	}
+
+	// The body is type checked against the types of the args.
+	s := stateful.NewScope()
+	s.Set("used", "a lot")
+	s.Set("total", 100.0)
+	if _, err := expr.EvalBool(s); err == nil {
+		t.Error("expected type error calling function with a string arg")
+	}
+}
+
+func TestEvaluate_FuncDeclarationErrors(t *testing.T) {
+	testCases := []struct {
+		script string
+		err    string
+	}{
+		{
+			script: `func f(x) = f(x) + 1`,
+			err:    `line 1 char 13: function f cannot call itself, recursion is not allowed`,
+		},
+		{
+			script: `func abs(x) = x`,
+			err:    `line 1 char 1: cannot redefine builtin function "abs"`,
+		},
+		{
+			script: `func f(x, x) = x`,
+			err:    `line 1 char 1: function "f" declares parameter "x" more than once`,
+		},
+		{
+			script: `func f(a, b, c, d, e) = a`,
+			err:    `line 1 char 1: function "f" declares 5 parameters, at most 4 are allowed`,
+		},
+		{
+			script: `var f = 1
+func f(x) = x`,
+			err: `line 2 char 1: attempted to redefine f, vars are immutable`,
+		},
+		{
+			script: `func f(x) = x * 2
+var l = lambda: f("a", "b")`,
+			err: `line 2 char 17: function f takes 1 arguments, got 2`,
+		},
+	}
+	for _, tc := range testCases {
+		_, err := tick.Evaluate(tc.script, stateful.NewScope(), nil, false)
+		if err == nil {
+			t.Errorf("expected error %q for script %q", tc.err, tc.script)
+			continue
+		}
+		if got := err.Error(); got != tc.err {
+			t.Errorf("unexpected error for script %q\nexp: %s\ngot: %s", tc.script, tc.err, got)
+		}
+	}
+}
+
 func TestEvaluate_DynamicMethod(t *testing.T) {
 	script := `var x = a@dynamicMethod(1,'str', 10s).sad(FALSE)`
 
@@ -1450,6 +1664,19 @@ func TestReflectionDescriber(t *testing.T) {
 	}
 }
 
+func TestReflectionDescriber_Names(t *testing.T) {
+	rd, err := tick.NewReflectionDescriber(new(A), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := []string{"chainMethodA", "hiddenChainMethod"}, rd.ChainMethodNames(); !reflect.DeepEqual(exp, got) {
+		t.Errorf("unexpected ChainMethodNames got: %v exp: %v", got, exp)
+	}
+	if exp, got := []string{"aProperty", "hiddenPropertyMethod", "propertyMethodA"}, rd.PropertyNames(); !reflect.DeepEqual(exp, got) {
+		t.Errorf("unexpected PropertyNames got: %v exp: %v", got, exp)
+	}
+}
+
 func TestReflectionDescriberErrors(t *testing.T) {
 	_, err := tick.NewReflectionDescriber(nil, nil)
 	if err == nil {
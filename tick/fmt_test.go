@@ -7,6 +7,43 @@ func TestFormat(t *testing.T) {
 		script string
 		exp    string
 	}{
+		{
+			script: `dbrp  "telegraf" . "autogen"
+  dbrp "telegraf"."not_autogen"
+stream|from().measurement('cpu')`,
+			exp: `dbrp "telegraf"."autogen"
+dbrp "telegraf"."not_autogen"
+
+stream
+    |from()
+        .measurement('cpu')
+`,
+		},
+		{
+			script: `import   'shared/a.tick'
+import 'shared/b.tick'
+var tail = fragment|alert().crit(lambda: TRUE)
+stream|from()@tail()`,
+			exp: `import 'shared/a.tick'
+import 'shared/b.tick'
+
+var tail = fragment
+    |alert()
+        .crit(lambda: TRUE)
+
+stream
+    |from()
+    @tail()
+`,
+		},
+		{
+			script: `func pct(a,b)=if(b==0,0.0,a/b*100.0)
+var usage = lambda: pct("used","total")`,
+			exp: `func pct(a, b) = if(b == 0, 0.0, a / b * 100.0)
+
+var usage = lambda: pct("used", "total")
+`,
+		},
 		{
 			script: `var x = 1`,
 			exp:    "var x = 1\n",
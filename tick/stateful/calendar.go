@@ -0,0 +1,37 @@
+package stateful
+
+import (
+	"fmt"
+	"time"
+)
+
+// Calendar defines the holidays and business hours
+// used by the isHoliday and isBusinessHours functions.
+type Calendar interface {
+	// IsHoliday reports whether the time is on a holiday.
+	IsHoliday(t time.Time) bool
+	// IsBusinessHours reports whether the time is within business hours.
+	IsBusinessHours(t time.Time) bool
+}
+
+// Calendars looks up the calendars available to an expression by name.
+type Calendars interface {
+	Calendar(name string) (Calendar, bool)
+}
+
+// CalendarMap is a fixed set of calendars by name.
+type CalendarMap map[string]Calendar
+
+func (m CalendarMap) Calendar(name string) (Calendar, bool) {
+	c, ok := m[name]
+	return c, ok
+}
+
+func lookupCalendar(calendars Calendars, name string) (Calendar, error) {
+	if calendars != nil {
+		if c, ok := calendars.Calendar(name); ok {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown calendar %q", name)
+}
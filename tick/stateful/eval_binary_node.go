@@ -26,6 +26,9 @@ type resultContainer struct {
 
 	IsDurationValue bool
 	DurationValue   time.Duration
+
+	IsTimeValue bool
+	TimeValue   time.Time
 }
 
 // this function shouldn't be used! only for throwing details error messages!
@@ -139,6 +142,43 @@ func (n *EvalBinaryNode) Type(scope ReadOnlyScope) (ast.ValueType, error) {
 	return n.constReturnType, nil
 }
 
+// CheckOperandTypes checks that every binary operator of the expression supports the types of its operands.
+// Unlike Type it also checks the operands of operators whose return type is constant, i.e. comparisons.
+func CheckOperandTypes(node ast.Node, scope ReadOnlyScope) error {
+	_, err := ast.Walk(node, func(n ast.Node) (ast.Node, error) {
+		b, ok := n.(*ast.BinaryNode)
+		if !ok {
+			return n, nil
+		}
+		evaluator, err := NewEvalBinaryNode(b)
+		if err != nil {
+			return nil, err
+		}
+		return n, evaluator.checkOperandTypes(scope)
+	})
+	return err
+}
+
+func (n *EvalBinaryNode) checkOperandTypes(scope ReadOnlyScope) error {
+	leftType, err := n.leftEvaluator.Type(scope)
+	if err != nil {
+		return err
+	}
+	rightType, err := n.rightEvaluator.Type(scope)
+	if err != nil {
+		return err
+	}
+	if leftType == ast.InvalidType || rightType == ast.InvalidType {
+		return nil
+	}
+	n.leftType = leftType
+	n.rightType = rightType
+	if n.lookupEvaluationFn() == nil {
+		return n.determineError(nil, ExecutionState{})
+	}
+	return nil
+}
+
 func (n *EvalBinaryNode) IsDynamic() bool {
 	if n.constReturnType != ast.InvalidType {
 		return false
@@ -150,14 +190,31 @@ func (n *EvalBinaryNode) EvalRegex(scope *Scope, executionState ExecutionState)
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TRegex, ActualType: n.constReturnType}
 }
 
-func (n *EvalBinaryNode) EvalTime(scope *Scope, executionState ExecutionState) (time.Time, error) {
-	return time.Time{}, ErrTypeGuardFailed{RequestedType: ast.TTime, ActualType: n.constReturnType}
+func (e *EvalBinaryNode) EvalTime(scope *Scope, executionState ExecutionState) (time.Time, error) {
+	result, err := e.eval(scope, executionState)
+	if err != nil {
+		return time.Time{}, err.error
+	}
+
+	if result.IsTimeValue {
+		return result.TimeValue, nil
+	}
+
+	return time.Time{}, fmt.Errorf("expression returned unexpected type %T", result.value())
 }
 
 func (n *EvalBinaryNode) EvalMissing(scope *Scope, executionState ExecutionState) (*ast.Missing, error) {
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: n.constReturnType}
 }
 
+func (n *EvalBinaryNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: n.constReturnType}
+}
+
+func (n *EvalBinaryNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: n.constReturnType}
+}
+
 func (e *EvalBinaryNode) EvalDuration(scope *Scope, executionState ExecutionState) (time.Duration, error) {
 	result, err := e.eval(scope, executionState)
 	if err != nil {
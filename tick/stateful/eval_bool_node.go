@@ -47,6 +47,14 @@ func (n *EvalBoolNode) EvalMissing(scope *Scope, executionState ExecutionState)
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: ast.TBool}
 }
 
+func (n *EvalBoolNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: ast.TBool}
+}
+
+func (n *EvalBoolNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: ast.TBool}
+}
+
 func (n *EvalBoolNode) IsDynamic() bool {
 	return false
 }
@@ -47,6 +47,14 @@ func (n *EvalDurationNode) EvalMissing(scope *Scope, executionState ExecutionSta
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: ast.TDuration}
 }
 
+func (n *EvalDurationNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: ast.TDuration}
+}
+
+func (n *EvalDurationNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: ast.TDuration}
+}
+
 func (n *EvalDurationNode) IsDynamic() bool {
 	return false
 }
@@ -47,6 +47,14 @@ func (n *EvalFloatNode) EvalMissing(scope *Scope, executionState ExecutionState)
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: ast.TFloat}
 }
 
+func (n *EvalFloatNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: ast.TFloat}
+}
+
+func (n *EvalFloatNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: ast.TFloat}
+}
+
 func (n *EvalFloatNode) IsDynamic() bool {
 	return false
 }
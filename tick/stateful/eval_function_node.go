@@ -12,6 +12,7 @@ import (
 type EvalFunctionNode struct {
 	funcName       string
 	argsEvaluators []NodeEvaluator
+	userFunc       *userFunc
 }
 
 func NewEvalFunctionNode(funcNode *ast.FunctionNode) (*EvalFunctionNode, error) {
@@ -29,10 +30,24 @@ func NewEvalFunctionNode(funcNode *ast.FunctionNode) (*EvalFunctionNode, error)
 		evalFuncNode.argsEvaluators = append(evalFuncNode.argsEvaluators, argEvaluator)
 	}
 
+	if funcNode.Definition != nil {
+		f, err := newUserFunc(funcNode.Definition)
+		if err != nil {
+			return nil, err
+		}
+		if got, exp := len(funcNode.Args), len(f.params); got != exp {
+			return nil, fmt.Errorf("function %q takes %d arguments, got %d", funcNode.Func, exp, got)
+		}
+		evalFuncNode.userFunc = f
+	}
+
 	return evalFuncNode, nil
 }
 
 func (n *EvalFunctionNode) Type(scope ReadOnlyScope) (ast.ValueType, error) {
+	if n.userFunc != nil {
+		return n.userFuncType(scope)
+	}
 	f := lookupFunc(n.funcName, builtinFuncs, scope)
 	if f == nil {
 		return ast.InvalidType, fmt.Errorf("undefined function: %q", n.funcName)
@@ -60,6 +75,25 @@ func (n *EvalFunctionNode) Type(scope ReadOnlyScope) (ast.ValueType, error) {
 	return retType, nil
 }
 
+// userFuncType type checks the body of the user defined function against the types of the args.
+func (n *EvalFunctionNode) userFuncType(scope ReadOnlyScope) (ast.ValueType, error) {
+	domain := Domain{}
+	args := make([]interface{}, len(n.argsEvaluators))
+	for i, argEvaluator := range n.argsEvaluators {
+		t, err := argEvaluator.Type(scope)
+		if err != nil {
+			return ast.InvalidType, fmt.Errorf("Failed to handle %v argument: %v", i+1, err)
+		}
+		domain[i] = t
+		args[i] = ast.ZeroValue(t)
+	}
+	retType, err := n.userFunc.Type(args...)
+	if err != nil {
+		return ast.InvalidType, fmt.Errorf("Cannot call function \"%s\" with args signature %s: %v", n.funcName, domain, err)
+	}
+	return retType, nil
+}
+
 func (n *EvalFunctionNode) IsDynamic() bool {
 	return true
 }
@@ -76,12 +110,29 @@ func (n *EvalFunctionNode) callFunction(scope *Scope, executionState ExecutionSt
 		args = append(args, value)
 	}
 
+	if n.userFunc != nil {
+		ret, err := n.userFunc.Call(executionState, args...)
+		if err != nil {
+			return nil, fmt.Errorf("error calling %q: %s", n.funcName, err)
+		}
+		return ret, nil
+	}
+
 	f := lookupFunc(n.funcName, executionState.Funcs, scope)
 	if f == nil {
 		return ast.InvalidType, fmt.Errorf("undefined function: %q", n.funcName)
 	}
+	if cs, ok := f.(*callSites); ok {
+		f = cs.site(n)
+	}
 
-	ret, err := f.Call(args...)
+	var ret interface{}
+	var err error
+	if cf, ok := f.(calendarFunc); ok {
+		ret, err = cf.callCalendar(executionState.Calendars, args...)
+	} else {
+		ret, err = f.Call(args...)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error calling %q: %s", n.funcName, err)
 	}
@@ -192,6 +243,30 @@ func (n *EvalFunctionNode) EvalMissing(scope *Scope, executionState ExecutionSta
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: ast.TypeOf(refValue)}
 }
 
+func (n *EvalFunctionNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	refValue, err := n.callFunction(scope, executionState)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapValue, isMap := refValue.(map[string]interface{}); isMap {
+		return mapValue, nil
+	}
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: ast.TypeOf(refValue)}
+}
+
+func (n *EvalFunctionNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	refValue, err := n.callFunction(scope, executionState)
+	if err != nil {
+		return nil, err
+	}
+
+	if listValue, isList := refValue.([]interface{}); isList {
+		return listValue, nil
+	}
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: ast.TypeOf(refValue)}
+}
+
 // eval - generic evaluation until we have reflection/introspection capabillities so we can know the type of args
 // and return type, we can remove this entirely
 func eval(n NodeEvaluator, scope *Scope, executionState ExecutionState) (interface{}, error) {
@@ -221,12 +296,44 @@ func eval(n NodeEvaluator, scope *Scope, executionState ExecutionState) (interfa
 			return v, err
 		}
 		return v, nil
+	case ast.TMap:
+		return n.EvalMap(scope, executionState)
+	case ast.TList:
+		return n.EvalList(scope, executionState)
 	default:
 		return nil, fmt.Errorf("function arg expression returned unexpected type %s", retType)
 	}
 
 }
 
+// statefulCallSites returns the calls of stateful functions made by the evaluator,
+// in the order they appear, including the calls made by the user defined functions it calls.
+// Calls within lambdas are not included since lambdas keep their own state.
+func statefulCallSites(evaluator NodeEvaluator) []*EvalFunctionNode {
+	var sites []*EvalFunctionNode
+	var walk func(e NodeEvaluator)
+	walk = func(e NodeEvaluator) {
+		switch n := e.(type) {
+		case *EvalBinaryNode:
+			walk(n.leftEvaluator)
+			walk(n.rightEvaluator)
+		case *EvalUnaryNode:
+			walk(n.nodeEvaluator)
+		case *EvalFunctionNode:
+			for _, arg := range n.argsEvaluators {
+				walk(arg)
+			}
+			if n.userFunc != nil {
+				walk(n.userFunc.body)
+			} else if _, ok := builtinFuncs[n.funcName].(*callSites); ok {
+				sites = append(sites, n)
+			}
+		}
+	}
+	walk(evaluator)
+	return sites
+}
+
 func lookupFunc(name string, funcs Funcs, scope ReadOnlyScope) Func {
 	f := funcs[name]
 	if f != nil {
@@ -47,6 +47,14 @@ func (n *EvalIntNode) EvalMissing(scope *Scope, executionState ExecutionState) (
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: ast.TInt}
 }
 
+func (n *EvalIntNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: ast.TInt}
+}
+
+func (n *EvalIntNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: ast.TInt}
+}
+
 func (n *EvalIntNode) IsDynamic() bool {
 	return false
 }
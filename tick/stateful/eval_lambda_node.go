@@ -58,6 +58,10 @@ func (n *EvalLambdaNode) EvalTime(scope *Scope, _ ExecutionState) (time.Time, er
 	if err != nil {
 		return time.Time{}, err
 	}
+	if typ == ast.TTime {
+		return n.nodeEvaluator.EvalTime(scope, n.state)
+	}
+
 	return time.Time{}, ErrTypeGuardFailed{RequestedType: ast.TTime, ActualType: typ}
 }
 
@@ -132,3 +136,27 @@ func (n *EvalLambdaNode) EvalMissing(scope *Scope, _ ExecutionState) (*ast.Missi
 
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TBool, ActualType: typ}
 }
+
+func (n *EvalLambdaNode) EvalMap(scope *Scope, _ ExecutionState) (map[string]interface{}, error) {
+	typ, err := n.Type(scope)
+	if err != nil {
+		return nil, err
+	}
+	if typ == ast.TMap {
+		return n.nodeEvaluator.EvalMap(scope, n.state)
+	}
+
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: typ}
+}
+
+func (n *EvalLambdaNode) EvalList(scope *Scope, _ ExecutionState) ([]interface{}, error) {
+	typ, err := n.Type(scope)
+	if err != nil {
+		return nil, err
+	}
+	if typ == ast.TList {
+		return n.nodeEvaluator.EvalList(scope, n.state)
+	}
+
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: typ}
+}
@@ -177,3 +177,39 @@ func (n *EvalReferenceNode) EvalMissing(scope *Scope, executionState ExecutionSt
 
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: ast.TypeOf(refValue)}
 }
+
+func (n *EvalReferenceNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	refValue, err := n.getReferenceValue(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapValue, isMap := refValue.(map[string]interface{}); isMap {
+		return mapValue, nil
+	}
+
+	refType := ast.TypeOf(refValue)
+	if refType == ast.TMissing {
+		return nil, fmt.Errorf("reference \"%s\" is missing value", n.Node.Reference)
+	}
+
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: ast.TypeOf(refValue)}
+}
+
+func (n *EvalReferenceNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	refValue, err := n.getReferenceValue(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if listValue, isList := refValue.([]interface{}); isList {
+		return listValue, nil
+	}
+
+	refType := ast.TypeOf(refValue)
+	if refType == ast.TMissing {
+		return nil, fmt.Errorf("reference \"%s\" is missing value", n.Node.Reference)
+	}
+
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: ast.TypeOf(refValue)}
+}
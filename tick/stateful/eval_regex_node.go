@@ -47,6 +47,14 @@ func (n *EvalRegexNode) EvalMissing(scope *Scope, executionState ExecutionState)
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: ast.TRegex}
 }
 
+func (n *EvalRegexNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: ast.TRegex}
+}
+
+func (n *EvalRegexNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: ast.TRegex}
+}
+
 func (n *EvalRegexNode) IsDynamic() bool {
 	return false
 }
@@ -47,6 +47,14 @@ func (n *EvalStringNode) EvalMissing(scope *Scope, executionState ExecutionState
 	return nil, ErrTypeGuardFailed{RequestedType: ast.TMissing, ActualType: ast.TString}
 }
 
+func (n *EvalStringNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: ast.TString}
+}
+
+func (n *EvalStringNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: ast.TString}
+}
+
 func (n *EvalStringNode) IsDynamic() bool {
 	return false
 }
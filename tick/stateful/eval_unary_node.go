@@ -65,6 +65,14 @@ func (n *EvalUnaryNode) EvalMissing(scope *Scope, executionState ExecutionState)
 	return nil, fmt.Errorf("reference \"%s\" is missing value", ref.Node.Reference)
 }
 
+func (n *EvalUnaryNode) EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TMap, ActualType: n.constReturnType}
+}
+
+func (n *EvalUnaryNode) EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error) {
+	return nil, ErrTypeGuardFailed{RequestedType: ast.TList, ActualType: n.constReturnType}
+}
+
 func (n *EvalUnaryNode) EvalDuration(scope *Scope, executionState ExecutionState) (time.Duration, error) {
 	typ, err := n.Type(scope)
 	if err != nil {
@@ -884,6 +884,126 @@ var evaluationFuncs = map[operationKey]*evaluationFnInfo{
 		returnType: ast.TBool,
 	},
 
+	operationKey{operator: ast.TokenEqual, leftType: ast.TTime, rightType: ast.TTime}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Time
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{BoolValue: left.Equal(right), IsBoolValue: true}, nil
+
+		},
+		returnType: ast.TBool,
+	},
+
+	operationKey{operator: ast.TokenNotEqual, leftType: ast.TTime, rightType: ast.TTime}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Time
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{BoolValue: !left.Equal(right), IsBoolValue: true}, nil
+
+		},
+		returnType: ast.TBool,
+	},
+
+	operationKey{operator: ast.TokenGreater, leftType: ast.TTime, rightType: ast.TTime}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Time
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{BoolValue: left.After(right), IsBoolValue: true}, nil
+
+		},
+		returnType: ast.TBool,
+	},
+
+	operationKey{operator: ast.TokenGreaterEqual, leftType: ast.TTime, rightType: ast.TTime}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Time
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{BoolValue: !left.Before(right), IsBoolValue: true}, nil
+
+		},
+		returnType: ast.TBool,
+	},
+
+	operationKey{operator: ast.TokenLess, leftType: ast.TTime, rightType: ast.TTime}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Time
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{BoolValue: left.Before(right), IsBoolValue: true}, nil
+
+		},
+		returnType: ast.TBool,
+	},
+
+	operationKey{operator: ast.TokenLessEqual, leftType: ast.TTime, rightType: ast.TTime}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Time
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalTime(scope, executionState); err != nil {
+				return boolFalseResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{BoolValue: !left.After(right), IsBoolValue: true}, nil
+
+		},
+		returnType: ast.TBool,
+	},
+
 	// -----------------------------------------
 	//	Math evaluation funcs
 
@@ -1227,6 +1347,82 @@ var evaluationFuncs = map[operationKey]*evaluationFnInfo{
 		returnType: ast.TInt,
 	},
 
+	// -----------------------------------------
+	//	Time arithmetic funcs
+
+	operationKey{operator: ast.TokenPlus, leftType: ast.TTime, rightType: ast.TDuration}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Duration
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return emptyResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalDuration(scope, executionState); err != nil {
+				return emptyResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{TimeValue: left.Add(right), IsTimeValue: true}, nil
+		},
+		returnType: ast.TTime,
+	},
+	operationKey{operator: ast.TokenPlus, leftType: ast.TDuration, rightType: ast.TTime}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Duration
+			var right time.Time
+			var err error
+
+			if left, err = leftNode.EvalDuration(scope, executionState); err != nil {
+				return emptyResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalTime(scope, executionState); err != nil {
+				return emptyResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{TimeValue: right.Add(left), IsTimeValue: true}, nil
+		},
+		returnType: ast.TTime,
+	},
+	operationKey{operator: ast.TokenMinus, leftType: ast.TTime, rightType: ast.TDuration}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Duration
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return emptyResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalDuration(scope, executionState); err != nil {
+				return emptyResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{TimeValue: left.Add(-right), IsTimeValue: true}, nil
+		},
+		returnType: ast.TTime,
+	},
+	operationKey{operator: ast.TokenMinus, leftType: ast.TTime, rightType: ast.TTime}: {
+		f: func(scope *Scope, executionState ExecutionState, leftNode, rightNode NodeEvaluator) (resultContainer, *ErrSide) {
+			var left time.Time
+			var right time.Time
+			var err error
+
+			if left, err = leftNode.EvalTime(scope, executionState); err != nil {
+				return emptyResultContainer, &ErrSide{error: err, IsLeft: true}
+			}
+
+			if right, err = rightNode.EvalTime(scope, executionState); err != nil {
+				return emptyResultContainer, &ErrSide{error: err, IsRight: true}
+			}
+
+			return resultContainer{DurationValue: left.Sub(right), IsDurationValue: true}, nil
+		},
+		returnType: ast.TDuration,
+	},
+
 	// -----------------------------------------
 	//	String concatenation func
 
@@ -1,9 +1,32 @@
 package stateful
 
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"time"
+)
+
+func init() {
+	// Register the types of values kept by stateful functions that gob does not know.
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+}
+
 // ExecutionState is auxiliary struct for data/context that needs to be passed
 // to evaluation functions
 type ExecutionState struct {
 	Funcs Funcs
+	// Calendars used by the isHoliday and isBusinessHours functions, may be nil.
+	Calendars Calendars
+}
+
+// A Func with state that is saved in task snapshots.
+type snapshotFunc interface {
+	Func
+	// snapshot returns a pointer to the state of the function,
+	// used to both encode and decode the state.
+	snapshot() interface{}
 }
 
 func CreateExecutionState() ExecutionState {
@@ -18,3 +41,68 @@ func (ea ExecutionState) ResetAll() {
 		f.Reset()
 	}
 }
+
+// callSiteState is the encoded state of a stateful function at one call site.
+type callSiteState struct {
+	Func  string
+	State []byte
+}
+
+// snapshot encodes the state of the stateful functions at the call sites.
+// Nil is returned if there are no call sites.
+func (ea ExecutionState) snapshot(sites []*EvalFunctionNode) ([]byte, error) {
+	if len(sites) == 0 {
+		return nil, nil
+	}
+	states := make([]callSiteState, len(sites))
+	for i, n := range sites {
+		states[i].Func = n.funcName
+		cs, ok := ea.Funcs[n.funcName].(*callSites)
+		if !ok {
+			continue
+		}
+		sf, ok := cs.site(n).(snapshotFunc)
+		if !ok {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(sf.snapshot()); err != nil {
+			return nil, fmt.Errorf("failed to snapshot state of %q: %v", n.funcName, err)
+		}
+		states[i].State = buf.Bytes()
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(states); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// restore decodes the state of the stateful functions at the call sites from a snapshot.
+// Call sites missing from the snapshot, or calling a different function than when
+// the snapshot was taken, are reset.
+func (ea ExecutionState) restore(sites []*EvalFunctionNode, data []byte) error {
+	var states []callSiteState
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&states); err != nil {
+		return err
+	}
+	for i, n := range sites {
+		cs, ok := ea.Funcs[n.funcName].(*callSites)
+		if !ok {
+			continue
+		}
+		f := cs.site(n)
+		f.Reset()
+		if i >= len(states) || states[i].Func != n.funcName || len(states[i].State) == 0 {
+			continue
+		}
+		sf, ok := f.(snapshotFunc)
+		if !ok {
+			continue
+		}
+		if err := gob.NewDecoder(bytes.NewReader(states[i].State)).Decode(sf.snapshot()); err != nil {
+			return fmt.Errorf("failed to restore state of %q: %v", n.funcName, err)
+		}
+	}
+	return nil
+}
@@ -24,11 +24,19 @@ type Expression interface {
 
 	// Return a copy of the expression but with a Reset state.
 	CopyReset() Expression
+
+	// Snapshot returns the encoded state of the stateful functions of the expression,
+	// or nil if the expression does not call any stateful functions.
+	Snapshot() ([]byte, error)
+	// Restore the state of the stateful functions of the expression from a snapshot.
+	Restore(snapshot []byte) error
 }
 
 type expression struct {
 	nodeEvaluator  NodeEvaluator
 	executionState ExecutionState
+	// Calls of stateful functions made by the expression, in the order they appear.
+	sites []*EvalFunctionNode
 }
 
 // NewExpression accept a node and try to "compile"/ "specialise" it
@@ -38,21 +46,33 @@ type expression struct {
 // 	Given a BinaryNode{ReferNode("value"), NumberNode{Float64:10}} during runtime
 // 	we can find the type of "value" and find the most matching comparison function - (float64,float64) or (int64,float64)
 func NewExpression(node ast.Node) (Expression, error) {
+	return NewExpressionWithCalendars(node, nil)
+}
+
+// NewExpressionWithCalendars is the same as NewExpression,
+// except the isHoliday and isBusinessHours functions look up calendars in the given calendars.
+func NewExpressionWithCalendars(node ast.Node, calendars Calendars) (Expression, error) {
 	nodeEvaluator, err := createNodeEvaluator(node)
 	if err != nil {
 		return nil, err
 	}
 
+	executionState := CreateExecutionState()
+	executionState.Calendars = calendars
 	return &expression{
 		nodeEvaluator:  nodeEvaluator,
-		executionState: CreateExecutionState(),
+		executionState: executionState,
+		sites:          statefulCallSites(nodeEvaluator),
 	}, nil
 }
 
 func (se *expression) CopyReset() Expression {
+	executionState := CreateExecutionState()
+	executionState.Calendars = se.executionState.Calendars
 	return &expression{
 		nodeEvaluator:  se.nodeEvaluator,
-		executionState: CreateExecutionState(),
+		executionState: executionState,
+		sites:          se.sites,
 	}
 }
 
@@ -60,6 +80,14 @@ func (se *expression) Reset() {
 	se.executionState.ResetAll()
 }
 
+func (se *expression) Snapshot() ([]byte, error) {
+	return se.executionState.snapshot(se.sites)
+}
+
+func (se *expression) Restore(snapshot []byte) error {
+	return se.executionState.restore(se.sites, snapshot)
+}
+
 func (se *expression) Type(scope ReadOnlyScope) (ast.ValueType, error) {
 	return se.nodeEvaluator.Type(scope)
 }
@@ -88,6 +116,14 @@ func (se *expression) EvalMissing(scope *Scope) (*ast.Missing, error) {
 	return se.nodeEvaluator.EvalMissing(scope, se.executionState)
 }
 
+func (se *expression) EvalMap(scope *Scope) (map[string]interface{}, error) {
+	return se.nodeEvaluator.EvalMap(scope, se.executionState)
+}
+
+func (se *expression) EvalList(scope *Scope) ([]interface{}, error) {
+	return se.nodeEvaluator.EvalList(scope, se.executionState)
+}
+
 func (se *expression) Eval(scope *Scope) (interface{}, error) {
 	typ, err := se.nodeEvaluator.Type(scope)
 	if err != nil {
@@ -131,6 +167,18 @@ func (se *expression) Eval(scope *Scope) (interface{}, error) {
 			return nil, err
 		}
 		return result, err
+	case ast.TMap:
+		result, err := se.EvalMap(scope)
+		if err != nil {
+			return nil, err
+		}
+		return result, err
+	case ast.TList:
+		result, err := se.EvalList(scope)
+		if err != nil {
+			return nil, err
+		}
+		return result, err
 	default:
 		return nil, fmt.Errorf("expression returned unexpected type %s", typ)
 	}
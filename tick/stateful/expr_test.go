@@ -3,6 +3,7 @@ package stateful_test
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
 	"testing"
 	"time"
@@ -48,6 +49,424 @@ func TestExpression_EvalNum_KeepsFunctionsState(t *testing.T) {
 
 }
 
+func TestExpression_Eval_JSONFunctions(t *testing.T) {
+	testCases := []struct {
+		lambda string
+		exp    interface{}
+	}{
+		{
+			lambda: `float(jsonPath("msg", 'request.latency')) > 1.0`,
+			exp:    true,
+		},
+		{
+			lambda: `int(jsonPath("msg", 'request.codes[1]')) + 1`,
+			exp:    int64(501),
+		},
+		{
+			lambda: `mapGet(jsonParse("msg"), 'level') == 'error'`,
+			exp:    true,
+		},
+		{
+			lambda: `mapGet(jsonParse("msg"), 'host', 'unknown')`,
+			exp:    "unknown",
+		},
+		{
+			lambda: `strJoin(mapKeys(jsonParse("msg")), ',')`,
+			exp:    "level,request",
+		},
+	}
+	for _, tc := range testCases {
+		l, err := ast.ParseLambda(tc.lambda)
+		if err != nil {
+			t.Fatal(err)
+		}
+		se := mustCompileExpression(l.Expression)
+
+		// The type is known without the value of the JSON.
+		typeScope := stateful.NewScope()
+		typeScope.Set("msg", ast.ZeroValue(ast.TString))
+		if typ, err := se.Type(typeScope); err != nil {
+			t.Errorf("%s: unexpected type error: %v", tc.lambda, err)
+		} else if exp := ast.TypeOf(tc.exp); typ != exp {
+			t.Errorf("%s: unexpected type got: %v exp: %v", tc.lambda, typ, exp)
+		}
+
+		scope := stateful.NewScope()
+		scope.Set("msg", `{"level":"error","request":{"latency":1.5,"codes":[200,500]}}`)
+		result, err := se.Eval(scope)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.lambda, err)
+			continue
+		}
+		if result != tc.exp {
+			t.Errorf("%s: unexpected result got: %v exp: %v", tc.lambda, result, tc.exp)
+		}
+	}
+}
+
+type officeCalendar struct{}
+
+func (officeCalendar) IsHoliday(t time.Time) bool {
+	return t.Month() == time.December && t.Day() == 25
+}
+
+func (c officeCalendar) IsBusinessHours(t time.Time) bool {
+	return !c.IsHoliday(t) && t.Weekday() != time.Sunday && t.Hour() >= 9 && t.Hour() < 17
+}
+
+func TestExpression_Eval_TimeFunctions(t *testing.T) {
+	calendars := stateful.CalendarMap{
+		"office": officeCalendar{},
+	}
+
+	testCases := []struct {
+		lambda string
+		exp    interface{}
+	}{
+		{
+			lambda: `hour("time")`,
+			exp:    int64(0),
+		},
+		{
+			lambda: `hour("time", 'Europe/Berlin')`,
+			exp:    int64(1),
+		},
+		{
+			// Daylight saving time starts at 01:00 UTC
+			lambda: `hour("time" + 1h, 'Europe/Berlin')`,
+			exp:    int64(3),
+		},
+		{
+			lambda: `weekday("time", 'America/New_York')`,
+			exp:    int64(6),
+		},
+		{
+			lambda: `unixNano(truncate("time", 1h))`,
+			exp:    int64(1490486400000000000),
+		},
+		{
+			lambda: `unixNano(truncate("time", 24h, 'Europe/Berlin'))`,
+			exp:    int64(1490482800000000000),
+		},
+		{
+			// Truncating across the start of daylight saving time returns the start of the local day.
+			lambda: `unixNano(truncate("time" + 10h, 24h, 'Europe/Berlin'))`,
+			exp:    int64(1490482800000000000),
+		},
+		{
+			// 04:30 CEST is truncated to 04:00 CEST
+			lambda: `unixNano(truncate("time" + 2h, 1h, 'Europe/Berlin'))`,
+			exp:    int64(1490493600000000000),
+		},
+		{
+			// Truncating across the end of daylight saving time returns the start of the local day.
+			lambda: `unixNano(truncate("time" + 5218h, 24h, 'Europe/Berlin'))`,
+			exp:    int64(1509228000000000000),
+		},
+		{
+			lambda: `"time" - 30m == truncate("time", 1h)`,
+			exp:    true,
+		},
+		{
+			lambda: `("time" + 1h) - "time"`,
+			exp:    time.Hour,
+		},
+		{
+			lambda: `"time" < now() AND now() - "time" > 24h`,
+			exp:    true,
+		},
+		{
+			lambda: `strftime("time", '%Y-%m-%d %H:%M %Z', 'Europe/Berlin')`,
+			exp:    "2017-03-26 01:30 CET",
+		},
+		{
+			lambda: `strftime("time", '%a %j %u %s %%')`,
+			exp:    "Sun 085 7 1490488200 %",
+		},
+		{
+			lambda: `isHoliday("time", 'office') OR isBusinessHours("time", 'office')`,
+			exp:    false,
+		},
+		{
+			lambda: `isBusinessHours("time" + 33h, 'office')`,
+			exp:    true,
+		},
+	}
+	for _, tc := range testCases {
+		l, err := ast.ParseLambda(tc.lambda)
+		if err != nil {
+			t.Fatal(err)
+		}
+		se, err := stateful.NewExpressionWithCalendars(l.Expression, calendars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scope := stateful.NewScope()
+		scope.Set("time", time.Date(2017, time.March, 26, 0, 30, 0, 0, time.UTC))
+		result, err := se.Eval(scope)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.lambda, err)
+			continue
+		}
+		if result != tc.exp {
+			t.Errorf("%s: unexpected result got: %v exp: %v", tc.lambda, result, tc.exp)
+		}
+	}
+}
+
+func TestExpression_Eval_StatefulFunctions(t *testing.T) {
+	values := []float64{1, 3, 3, 6}
+	testCases := []struct {
+		lambda string
+		exp    []interface{}
+	}{
+		{
+			lambda: `previous("value")`,
+			exp:    []interface{}{1.0, 1.0, 3.0, 3.0},
+		},
+		{
+			lambda: `delta("value")`,
+			exp:    []interface{}{0.0, 2.0, 0.0, 3.0},
+		},
+		{
+			lambda: `delta(int("value"))`,
+			exp:    []interface{}{int64(0), int64(2), int64(0), int64(3)},
+		},
+		{
+			lambda: `changed("value")`,
+			exp:    []interface{}{false, true, false, true},
+		},
+		{
+			lambda: `ewma("value", 0.5)`,
+			exp:    []interface{}{1.0, 2.0, 2.5, 4.25},
+		},
+		{
+			lambda: `rate("value", "time")`,
+			exp:    []interface{}{0.0, 0.2, 0.0, 0.3},
+		},
+		{
+			lambda: `rate("value", "time", 1m)`,
+			exp:    []interface{}{0.0, 12.0, 0.0, 18.0},
+		},
+		{
+			lambda: `changed("value") AND delta("value") > 2.5`,
+			exp:    []interface{}{false, false, false, true},
+		},
+	}
+	start := time.Date(2017, time.March, 26, 0, 0, 0, 0, time.UTC)
+	for _, tc := range testCases {
+		l, err := ast.ParseLambda(tc.lambda)
+		if err != nil {
+			t.Fatal(err)
+		}
+		se := mustCompileExpression(l.Expression)
+		scope := stateful.NewScope()
+		for i, v := range values {
+			scope.Set("value", v)
+			scope.Set("time", start.Add(time.Duration(i)*10*time.Second))
+			result, err := se.Eval(scope)
+			if err != nil {
+				t.Errorf("%s: unexpected error at %d: %v", tc.lambda, i, err)
+				break
+			}
+			if result != tc.exp[i] {
+				t.Errorf("%s: unexpected result at %d got: %v exp: %v", tc.lambda, i, result, tc.exp[i])
+			}
+		}
+	}
+}
+
+func TestExpression_Eval_StatefulFunctionsCallSites(t *testing.T) {
+	a := []float64{0, 1, 2}
+	b := []float64{100, 110, 120}
+	testCases := []struct {
+		lambda string
+		exp    []float64
+	}{
+		{
+			lambda: `delta("a") + delta("b") * 1000.0`,
+			exp:    []float64{0, 10001, 10001},
+		},
+		{
+			lambda: `previous("a") + previous("b") * 1000.0`,
+			exp:    []float64{100000, 100000, 110001},
+		},
+	}
+	for _, tc := range testCases {
+		l, err := ast.ParseLambda(tc.lambda)
+		if err != nil {
+			t.Fatal(err)
+		}
+		se := mustCompileExpression(l.Expression)
+		scope := stateful.NewScope()
+		for i := range a {
+			if i == 2 {
+				// Each call site must be restored with its own state.
+				snapshot, err := se.Snapshot()
+				if err != nil {
+					t.Fatal(err)
+				}
+				se = mustCompileExpression(l.Expression)
+				if err := se.Restore(snapshot); err != nil {
+					t.Fatal(err)
+				}
+			}
+			scope.Set("a", a[i])
+			scope.Set("b", b[i])
+			result, err := se.EvalFloat(scope)
+			if err != nil {
+				t.Errorf("%s: unexpected error at %d: %v", tc.lambda, i, err)
+				break
+			}
+			if result != tc.exp[i] {
+				t.Errorf("%s: unexpected result at %d got: %v exp: %v", tc.lambda, i, result, tc.exp[i])
+			}
+		}
+	}
+}
+
+func TestExpression_SnapshotRestore(t *testing.T) {
+	l, err := ast.ParseLambda(`ewma("value", 0.5) + delta("value") + sigma("value") + spread("value") + float(count())`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []float64{1, 3, 3, 6, 2, 8}
+
+	// Evaluate all values with the same expression.
+	se := mustCompileExpression(l.Expression)
+	scope := stateful.NewScope()
+	var exp []interface{}
+	for _, v := range values {
+		scope.Set("value", v)
+		result, err := se.Eval(scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp = append(exp, result)
+	}
+
+	// Evaluate half of the values, then the rest with an expression restored from a snapshot.
+	se = mustCompileExpression(l.Expression)
+	var got []interface{}
+	for i, v := range values {
+		if i == len(values)/2 {
+			snapshot, err := se.Snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			se = mustCompileExpression(l.Expression)
+			if err := se.Restore(snapshot); err != nil {
+				t.Fatal(err)
+			}
+		}
+		scope.Set("value", v)
+		result, err := se.Eval(scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, result)
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected results after restore got: %v exp: %v", got, exp)
+	}
+
+	// Expressions without stateful functions have nothing to snapshot.
+	se = mustCompileExpression(&ast.ReferenceNode{Reference: "value"})
+	if snapshot, err := se.Snapshot(); err != nil {
+		t.Fatal(err)
+	} else if snapshot != nil {
+		t.Errorf("unexpected snapshot %v", snapshot)
+	}
+}
+
+func TestExpression_Eval_StringFunctions(t *testing.T) {
+	testCases := []struct {
+		lambda string
+		exp    interface{}
+	}{
+		{
+			lambda: `regexExtract(/user=(\w+)/, "msg", 1)`,
+			exp:    "alice",
+		},
+		{
+			lambda: `regexExtract(/status=(?P<status>\d+)/, "msg", 'status')`,
+			exp:    "503",
+		},
+		{
+			lambda: `regexExtract(/missing=(\w+)/, "msg", 1)`,
+			exp:    "",
+		},
+		{
+			lambda: `regexMatchNamed(/user=(?P<user>\w+) (\w+)=(?P<status>\d+)/, "msg")`,
+			exp:    map[string]interface{}{"user": "alice", "status": "503"},
+		},
+		{
+			lambda: `strFormat('%s-%d-%.1f', "host", "count", 0.25)`,
+			exp:    "serverA-3-0.2",
+		},
+		{
+			lambda: `strFormat('%s/%s', "at", 90s)`,
+			exp:    "2017-03-26 01:00:00 +0000 UTC/1m30s",
+		},
+		{
+			lambda: `strJoin(strSplit("path", '/'), '.')`,
+			exp:    ".var.log.syslog",
+		},
+		{
+			lambda: `urlHost("url")`,
+			exp:    "example.com",
+		},
+		{
+			lambda: `urlPath("url") + '?' + urlDecode(urlEncode('a b'))`,
+			exp:    "/api/v1?a b",
+		},
+		{
+			lambda: `base64Decode(base64Encode("host"))`,
+			exp:    "serverA",
+		},
+		{
+			lambda: `sha256("host")`,
+			exp:    "3974a23efc7d6c5726917f9ded1111f15d463047f8ae61bca6b7220705b83a7c",
+		},
+	}
+	for _, tc := range testCases {
+		l, err := ast.ParseLambda(tc.lambda)
+		if err != nil {
+			t.Fatal(err)
+		}
+		se := mustCompileExpression(l.Expression)
+		scope := stateful.NewScope()
+		scope.Set("msg", "level=error user=alice status=503")
+		scope.Set("host", "serverA")
+		scope.Set("count", int64(3))
+		scope.Set("at", time.Date(2017, 3, 26, 1, 0, 0, 0, time.UTC))
+		scope.Set("path", "/var/log/syslog")
+		scope.Set("url", "https://example.com:8443/api/v1?q=1")
+
+		// The type is known from the zero values of the types of the references.
+		typeScope := stateful.NewScope()
+		for _, name := range []string{"msg", "host", "path", "url"} {
+			typeScope.Set(name, ast.ZeroValue(ast.TString))
+		}
+		typeScope.Set("count", ast.ZeroValue(ast.TInt))
+		typeScope.Set("at", ast.ZeroValue(ast.TTime))
+		if typ, err := se.Type(typeScope); err != nil {
+			t.Errorf("%s: unexpected type error: %v", tc.lambda, err)
+		} else if exp := ast.TypeOf(tc.exp); typ != exp {
+			t.Errorf("%s: unexpected type got: %v exp: %v", tc.lambda, typ, exp)
+		}
+
+		result, err := se.Eval(scope)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.lambda, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tc.exp) {
+			t.Errorf("%s: unexpected result got: %v exp: %v", tc.lambda, result, tc.exp)
+		}
+	}
+}
+
 func TestExpression_EvalBool_BinaryNodeWithDurationNode(t *testing.T) {
 	leftValues := []interface{}{time.Duration(5), time.Duration(10)}
 	rightValues := []interface{}{time.Duration(5), time.Duration(10), int64(5)}
@@ -1,13 +1,25 @@
 package stateful
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"math"
+	"net"
+	"net/url"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
@@ -20,9 +32,13 @@ import (
 // Increment this value if you create a builtin function with more than
 // the current value of maxArgs.
 const (
-	maxArgs = 4
+	maxArgs = 5
 )
 
+// maxUserFuncParams is the largest number of parameters that a
+// user-defined function can declare.
+const maxUserFuncParams = 4
+
 type ErrWrongFuncSignature struct {
 	Name           string
 	DomainProvided Domain
@@ -160,13 +176,39 @@ func init() {
 	statelessFuncs["strTrimRight"] = newString2String("strTrimRight", strings.TrimRight)
 	statelessFuncs["strTrimSpace"] = newString1String("strTrimSpace", strings.TrimSpace)
 	statelessFuncs["strTrimSuffix"] = newString2String("strTrimSuffix", strings.TrimSuffix)
+	statelessFuncs["strFormat"] = strFormat{}
+	statelessFuncs["strSplit"] = strSplit{}
+	statelessFuncs["strJoin"] = strJoin{}
+
+	// URL functions
+	statelessFuncs["urlHost"] = newString1StringErr("urlHost", urlHost)
+	statelessFuncs["urlPath"] = newString1StringErr("urlPath", urlPath)
+	statelessFuncs["urlEncode"] = newString1String("urlEncode", url.QueryEscape)
+	statelessFuncs["urlDecode"] = newString1StringErr("urlDecode", url.QueryUnescape)
+
+	// Encoding functions
+	statelessFuncs["base64Encode"] = newString1String("base64Encode", base64Encode)
+	statelessFuncs["base64Decode"] = newString1StringErr("base64Decode", base64Decode)
+
+	// Hash functions
+	statelessFuncs["md5"] = newString1String("md5", hexDigest(md5.New))
+	statelessFuncs["sha1"] = newString1String("sha1", hexDigest(sha1.New))
+	statelessFuncs["sha256"] = newString1String("sha256", hexDigest(sha256.New))
 
 	// Regex functions
 	statelessFuncs["regexReplace"] = regexReplace{}
+	statelessFuncs["regexExtract"] = regexExtract{}
+	statelessFuncs["regexMatchNamed"] = regexMatchNamed{}
 
 	// Missing functions
 	statelessFuncs["isPresent"] = isPresent{}
 
+	// Map functions
+	statelessFuncs["jsonParse"] = jsonParse{}
+	statelessFuncs["jsonPath"] = jsonPath{}
+	statelessFuncs["mapGet"] = mapGet{}
+	statelessFuncs["mapKeys"] = mapKeys{}
+
 	// Time functions
 	statelessFuncs["minute"] = minute{}
 	statelessFuncs["hour"] = hour{}
@@ -174,6 +216,14 @@ func init() {
 	statelessFuncs["day"] = day{}
 	statelessFuncs["month"] = month{}
 	statelessFuncs["year"] = year{}
+	statelessFuncs["unixNano"] = unixNano{}
+	statelessFuncs["now"] = now{}
+	statelessFuncs["truncate"] = truncate{}
+	statelessFuncs["strftime"] = strftime{}
+
+	// Calendar functions
+	statelessFuncs["isHoliday"] = isHoliday{}
+	statelessFuncs["isBusinessHours"] = isBusinessHours{}
 
 	// Humanize functions
 	statelessFuncs["humanBytes"] = humanBytes{}
@@ -187,19 +237,58 @@ func init() {
 
 // Return set of built-in Funcs
 func NewFunctions() Funcs {
-	funcs := make(Funcs, len(statelessFuncs)+3)
+	funcs := make(Funcs, len(statelessFuncs)+8)
 	for n, f := range statelessFuncs {
 		funcs[n] = f
 	}
 
-	// Statefull functions -- need new instance
-	funcs["sigma"] = &sigma{}
-	funcs["count"] = &count{}
-	funcs["spread"] = &spread{min: math.Inf(+1), max: math.Inf(-1)}
+	// Statefull functions -- need new instance for each call site
+	funcs["sigma"] = newCallSites(func() Func { return &sigma{} })
+	funcs["count"] = newCallSites(func() Func { return &count{} })
+	funcs["spread"] = newCallSites(func() Func { return &spread{Min: math.Inf(+1), Max: math.Inf(-1)} })
+	funcs["previous"] = newCallSites(func() Func { return &previous{} })
+	funcs["delta"] = newCallSites(func() Func { return &delta{} })
+	funcs["changed"] = newCallSites(func() Func { return &changed{} })
+	funcs["ewma"] = newCallSites(func() Func { return &ewma{} })
+	funcs["rate"] = newCallSites(func() Func { return &rate{} })
 
 	return funcs
 }
 
+// callSites keeps a separate instance of a stateful function for each place it is called,
+// so that calling the same function twice in an expression does not mix their states.
+type callSites struct {
+	newFunc func() Func
+	// Used for the signature and for calls without a call site.
+	Func
+	sites map[*EvalFunctionNode]Func
+}
+
+func newCallSites(newFunc func() Func) *callSites {
+	return &callSites{
+		newFunc: newFunc,
+		Func:    newFunc(),
+		sites:   make(map[*EvalFunctionNode]Func),
+	}
+}
+
+// site returns the instance of the function for the call site, creating it if needed.
+func (c *callSites) site(n *EvalFunctionNode) Func {
+	f, ok := c.sites[n]
+	if !ok {
+		f = c.newFunc()
+		c.sites[n] = f
+	}
+	return f
+}
+
+func (c *callSites) Reset() {
+	c.Func.Reset()
+	for _, f := range c.sites {
+		f.Reset()
+	}
+}
+
 type math1Func func(float64) float64
 type math1 struct {
 	name string
@@ -558,6 +647,222 @@ func (m string1String) Signature() map[Domain]ast.ValueType {
 
 func (m string1String) Reset() {}
 
+type string1StringErrFunc func(string) (string, error)
+type string1StringErr struct {
+	name string
+	f    string1StringErrFunc
+}
+
+func newString1StringErr(name string, f string1StringErrFunc) string1StringErr {
+	return string1StringErr{
+		name: name,
+		f:    f,
+	}
+}
+
+func (m string1StringErr) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 1 {
+		return 0, errors.New(m.name + " expects exactly one argument")
+	}
+	a0, ok := args[0].(string)
+	if !ok {
+		err = fmt.Errorf("cannot pass %T as first arg to %s, must be string", args[0], m.name)
+		return
+	}
+	v, err = m.f(a0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", m.name, err)
+	}
+	return
+}
+
+func (m string1StringErr) Signature() map[Domain]ast.ValueType {
+	return string1StringFuncSignature
+}
+
+func (m string1StringErr) Reset() {}
+
+// The types of the values that can be formatted by strFormat.
+// Times and durations are formatted with their String method when used with the %s or %v verbs.
+var strFormatTypes = []ast.ValueType{
+	ast.TFloat,
+	ast.TInt,
+	ast.TString,
+	ast.TBool,
+	ast.TTime,
+	ast.TDuration,
+}
+
+type strFormat struct {
+}
+
+// Formats the values according to the format, see the Go fmt package for the format verbs.
+func (strFormat) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) < 1 {
+		return nil, errors.New("strFormat expects at least one argument strFormat(format, values...)")
+	}
+	format, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as first arg to strFormat, must be string", args[0])
+	}
+	return fmt.Sprintf(format, args[1:]...), nil
+}
+
+var strFormatFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize String Format Function Signature
+// for every combination of the types of the values.
+func init() {
+	var add func(d Domain, i int)
+	add = func(d Domain, i int) {
+		strFormatFuncSignature[d] = ast.TString
+		if i == maxArgs {
+			return
+		}
+		for _, t := range strFormatTypes {
+			d[i] = t
+			add(d, i+1)
+		}
+	}
+	d := Domain{}
+	d[0] = ast.TString
+	add(d, 1)
+}
+
+func (strFormat) Signature() map[Domain]ast.ValueType {
+	return strFormatFuncSignature
+}
+
+func (strFormat) Reset() {}
+
+type strSplit struct {
+}
+
+// Splits the value into the list of the substrings separated by sep.
+func (strSplit) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 2 {
+		return nil, errors.New("strSplit expects exactly two arguments")
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as first arg to strSplit, must be string", args[0])
+	}
+	sep, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as second arg to strSplit, must be string", args[1])
+	}
+	parts := strings.Split(s, sep)
+	list := make([]interface{}, len(parts))
+	for i, p := range parts {
+		list[i] = p
+	}
+	return list, nil
+}
+
+var strSplitFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize String Split Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TString
+	d[1] = ast.TString
+	strSplitFuncSignature[d] = ast.TList
+}
+
+func (strSplit) Signature() map[Domain]ast.ValueType {
+	return strSplitFuncSignature
+}
+
+func (strSplit) Reset() {}
+
+type strJoin struct {
+}
+
+// Joins the elements of the list separated by sep.
+// Elements that are not strings are converted like the string function converts them.
+func (strJoin) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 2 {
+		return nil, errors.New("strJoin expects exactly two arguments")
+	}
+	list, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as first arg to strJoin, must be list", args[0])
+	}
+	sep, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as second arg to strJoin, must be string", args[1])
+	}
+	parts := make([]string, len(list))
+	for i, e := range list {
+		s, err := str{}.Call(e)
+		if err != nil {
+			return nil, fmt.Errorf("strJoin: element %d: %v", i, err)
+		}
+		parts[i] = s.(string)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+var strJoinFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize String Join Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TList
+	d[1] = ast.TString
+	strJoinFuncSignature[d] = ast.TString
+}
+
+func (strJoin) Signature() map[Domain]ast.ValueType {
+	return strJoinFuncSignature
+}
+
+func (strJoin) Reset() {}
+
+// urlHost returns the host of the URL without the port.
+func urlHost(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h, nil
+	}
+	// The host has no port, remove the brackets of IPv6 addresses.
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), nil
+}
+
+// urlPath returns the path of the URL.
+func urlPath(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return u.Path, nil
+}
+
+func base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func base64Decode(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// hexDigest returns a function returning the hex encoded hash of a string.
+func hexDigest(newHash func() hash.Hash) string1StringFunc {
+	return func(s string) string {
+		h := newHash()
+		h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
 type strLength struct {
 }
 
@@ -734,6 +1039,115 @@ func (regexReplace) Signature() map[Domain]ast.ValueType {
 
 func (m regexReplace) Reset() {}
 
+type regexExtract struct {
+}
+
+// Returns the text of the capture group of the first match of the pattern in the value,
+// or an empty string if the pattern does not match.
+// The group is either the index of the group, where 0 is the whole match, or the name of the group.
+func (regexExtract) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 3 {
+		return nil, errors.New("regexExtract expects exactly three arguments regexExtract(pattern, value, group)")
+	}
+	pattern, ok := args[0].(*regexp.Regexp)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as first arg to regexExtract, must be regex", args[0])
+	}
+	src, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as second arg to regexExtract, must be string", args[1])
+	}
+	group := -1
+	switch g := args[2].(type) {
+	case int64:
+		if g >= 0 && g <= int64(pattern.NumSubexp()) {
+			group = int(g)
+		}
+	case string:
+		for i, name := range pattern.SubexpNames() {
+			if name != "" && name == g {
+				group = i
+			}
+		}
+	default:
+		return nil, fmt.Errorf("cannot pass %T as third arg to regexExtract, must be int or string", args[2])
+	}
+	if group < 0 {
+		return nil, fmt.Errorf("regexExtract: pattern %s has no group %v", pattern, args[2])
+	}
+	match := pattern.FindStringSubmatch(src)
+	if match == nil {
+		return "", nil
+	}
+	return match[group], nil
+}
+
+var regexExtractFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize Regex Extract Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TRegex
+	d[1] = ast.TString
+	d[2] = ast.TInt
+	regexExtractFuncSignature[d] = ast.TString
+	d[2] = ast.TString
+	regexExtractFuncSignature[d] = ast.TString
+}
+
+func (regexExtract) Signature() map[Domain]ast.ValueType {
+	return regexExtractFuncSignature
+}
+
+func (regexExtract) Reset() {}
+
+type regexMatchNamed struct {
+}
+
+// Returns a map of the names of the named capture groups to their text
+// in the first match of the pattern in the value.
+// The map is empty if the pattern does not match.
+func (regexMatchNamed) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 2 {
+		return nil, errors.New("regexMatchNamed expects exactly two arguments")
+	}
+	pattern, ok := args[0].(*regexp.Regexp)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as first arg to regexMatchNamed, must be regex", args[0])
+	}
+	src, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as second arg to regexMatchNamed, must be string", args[1])
+	}
+	groups := make(map[string]interface{})
+	match := pattern.FindStringSubmatch(src)
+	if match == nil {
+		return groups, nil
+	}
+	for i, name := range pattern.SubexpNames() {
+		if name != "" {
+			groups[name] = match[i]
+		}
+	}
+	return groups, nil
+}
+
+var regexMatchNamedFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize Regex Match Named Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TRegex
+	d[1] = ast.TString
+	regexMatchNamedFuncSignature[d] = ast.TMap
+}
+
+func (regexMatchNamed) Signature() map[Domain]ast.ValueType {
+	return regexMatchNamedFuncSignature
+}
+
+func (regexMatchNamed) Reset() {}
+
 type boolean struct {
 }
 
@@ -1007,18 +1421,19 @@ func (duration) Signature() map[Domain]ast.ValueType {
 	return durationFuncSignature
 }
 
+// The state of the stateful functions is exported to be saved in task snapshots.
 type count struct {
-	n int64
+	N int64
 }
 
 func (c *count) Reset() {
-	c.n = 0
+	c.N = 0
 }
 
 // Counts the number of values processed.
 func (c *count) Call(args ...interface{}) (v interface{}, err error) {
-	c.n++
-	return c.n, nil
+	c.N++
+	return c.N, nil
 }
 
 var countFuncSignature = map[Domain]ast.ValueType{}
@@ -1033,18 +1448,22 @@ func (c *count) Signature() map[Domain]ast.ValueType {
 	return countFuncSignature
 }
 
+func (c *count) snapshot() interface{} {
+	return c
+}
+
 type sigma struct {
-	mean     float64
-	variance float64
-	m2       float64
-	n        float64
+	Mean     float64
+	Variance float64
+	M2       float64
+	N        float64
 }
 
 func (s *sigma) Reset() {
-	s.mean = 0
-	s.variance = 0
-	s.m2 = 0
-	s.n = 0
+	s.Mean = 0
+	s.Variance = 0
+	s.M2 = 0
+	s.N = 0
 }
 
 // Computes the number of standard devaitions a given value is from the running mean.
@@ -1056,16 +1475,16 @@ func (s *sigma) Call(args ...interface{}) (interface{}, error) {
 	if !ok {
 		return nil, ErrNotFloat
 	}
-	s.n++
-	delta := x - s.mean
-	s.mean = s.mean + delta/s.n
-	s.m2 = s.m2 + delta*(x-s.mean)
-	s.variance = s.m2 / (s.n - 1)
+	s.N++
+	delta := x - s.Mean
+	s.Mean = s.Mean + delta/s.N
+	s.M2 = s.M2 + delta*(x-s.Mean)
+	s.Variance = s.M2 / (s.N - 1)
 
-	if s.n < 2 || s.variance == 0 {
+	if s.N < 2 || s.Variance == 0 {
 		return float64(0), nil
 	}
-	return math.Abs(x-s.mean) / math.Sqrt(s.variance), nil
+	return math.Abs(x-s.Mean) / math.Sqrt(s.Variance), nil
 }
 
 var sigmaFuncSignature = map[Domain]ast.ValueType{}
@@ -1081,14 +1500,18 @@ func (s *sigma) Signature() map[Domain]ast.ValueType {
 	return sigmaFuncSignature
 }
 
+func (s *sigma) snapshot() interface{} {
+	return s
+}
+
 type spread struct {
-	min float64
-	max float64
+	Min float64
+	Max float64
 }
 
 func (s *spread) Reset() {
-	s.min = math.Inf(+1)
-	s.max = math.Inf(-1)
+	s.Min = math.Inf(+1)
+	s.Max = math.Inf(-1)
 }
 
 // Computes the running range of all values
@@ -1101,15 +1524,15 @@ func (s *spread) Call(args ...interface{}) (interface{}, error) {
 		return nil, ErrNotFloat
 	}
 
-	if x < s.min {
-		s.min = x
+	if x < s.Min {
+		s.Min = x
 	}
 
-	if x > s.max {
-		s.max = x
+	if x > s.Max {
+		s.Max = x
 	}
 
-	return s.max - s.min, nil
+	return s.Max - s.Min, nil
 }
 
 var spreadFuncSignature = map[Domain]ast.ValueType{}
@@ -1125,129 +1548,472 @@ func (s *spread) Signature() map[Domain]ast.ValueType {
 	return spreadFuncSignature
 }
 
-// Time function signatures
-var timeFuncSignature = map[Domain]ast.ValueType{}
-
-// Initialize Time Function Signature
-func init() {
-	d := Domain{}
-	d[0] = ast.TTime
-	timeFuncSignature[d] = ast.TInt
+func (s *spread) snapshot() interface{} {
+	return s
 }
 
-type minute struct {
+// The types of values whose previous value can be kept by stateful functions.
+var previousTypes = []ast.ValueType{
+	ast.TFloat,
+	ast.TInt,
+	ast.TString,
+	ast.TBool,
+	ast.TTime,
+	ast.TDuration,
 }
 
-func (minute) Reset() {
+// lastValue is the value of the previous call of a stateful function.
+// The value is forgotten when the type of the values changes.
+type lastValue struct {
+	Value interface{}
+	Set   bool
 }
 
-// Return the minute within the hour for the given time, within the range [0,59].
-func (minute) Call(args ...interface{}) (v interface{}, err error) {
-	if len(args) != 1 {
-		return 0, errors.New("minute expects exactly one argument")
-	}
-	switch a := args[0].(type) {
-	case time.Time:
-		v = int64(a.Minute())
-	default:
-		err = fmt.Errorf("cannot convert %T to time.Time", a)
+// update sets the value and returns the previous value and whether it was set.
+func (l *lastValue) update(v interface{}) (interface{}, bool) {
+	prev, ok := l.Value, l.Set
+	if ok && reflect.TypeOf(prev) != reflect.TypeOf(v) {
+		ok = false
 	}
-	return
+	l.Value = v
+	l.Set = true
+	return prev, ok
 }
 
-func (minute) Signature() map[Domain]ast.ValueType {
-	return timeFuncSignature
+func (l *lastValue) reset() {
+	l.Value = nil
+	l.Set = false
 }
 
-type hour struct {
+type previous struct {
+	last lastValue
 }
 
-func (hour) Reset() {
+func (p *previous) Reset() {
+	p.last.reset()
 }
 
-// Return the hour within the day for the given time, within the range [0,23].
-func (hour) Call(args ...interface{}) (v interface{}, err error) {
+// Returns the value of the previous call, or the value itself on the first call.
+func (p *previous) Call(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
-		return 0, errors.New("hour expects exactly one argument")
+		return nil, errors.New("previous expects exactly one argument")
 	}
-	switch a := args[0].(type) {
-	case time.Time:
-		v = int64(a.Hour())
-	default:
-		err = fmt.Errorf("cannot convert %T to time.Time", a)
+	prev, ok := p.last.update(args[0])
+	if !ok {
+		return args[0], nil
 	}
-	return
-}
-
-func (hour) Signature() map[Domain]ast.ValueType {
-	return timeFuncSignature
+	return prev, nil
 }
 
-type weekday struct {
-}
+var previousFuncSignature = map[Domain]ast.ValueType{}
 
-func (weekday) Reset() {
+// Initialize Previous Function Signature
+func init() {
+	for _, t := range previousTypes {
+		d := Domain{}
+		d[0] = t
+		previousFuncSignature[d] = t
+	}
 }
 
-// Return the weekday within the week for the given time, within the range [0,6] where 0 is Sunday.
-func (weekday) Call(args ...interface{}) (v interface{}, err error) {
-	if len(args) != 1 {
-		return 0, errors.New("weekday expects exactly one argument")
-	}
-	switch a := args[0].(type) {
-	case time.Time:
-		v = int64(a.Weekday())
-	default:
-		err = fmt.Errorf("cannot convert %T to time.Time", a)
-	}
-	return
+func (p *previous) Signature() map[Domain]ast.ValueType {
+	return previousFuncSignature
 }
 
-func (weekday) Signature() map[Domain]ast.ValueType {
-	return timeFuncSignature
+func (p *previous) snapshot() interface{} {
+	return &p.last
 }
 
-type day struct {
+type delta struct {
+	last lastValue
 }
 
-func (day) Reset() {
+func (d *delta) Reset() {
+	d.last.reset()
 }
 
-// Return the day within the month for the given time, within the range [1,31] depending on the month.
-func (day) Call(args ...interface{}) (v interface{}, err error) {
+// Computes the difference between the value and the value of the previous call, zero on the first call.
+func (d *delta) Call(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
-		return 0, errors.New("day expects exactly one argument")
+		return nil, errors.New("delta expects exactly one argument")
 	}
-	switch a := args[0].(type) {
-	case time.Time:
-		v = int64(a.Day())
+	prev, ok := d.last.update(args[0])
+	switch x := args[0].(type) {
+	case float64:
+		if !ok {
+			return float64(0), nil
+		}
+		return x - prev.(float64), nil
+	case int64:
+		if !ok {
+			return int64(0), nil
+		}
+		return x - prev.(int64), nil
 	default:
-		err = fmt.Errorf("cannot convert %T to time.Time", a)
+		d.last.reset()
+		return nil, fmt.Errorf("cannot compute delta of type %T", x)
 	}
-	return
 }
 
-func (day) Signature() map[Domain]ast.ValueType {
-	return timeFuncSignature
+var deltaFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize Delta Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TFloat
+	deltaFuncSignature[d] = ast.TFloat
+	d[0] = ast.TInt
+	deltaFuncSignature[d] = ast.TInt
 }
 
-type month struct {
+func (d *delta) Signature() map[Domain]ast.ValueType {
+	return deltaFuncSignature
 }
 
-func (month) Reset() {
+func (d *delta) snapshot() interface{} {
+	return &d.last
 }
 
-// Return the month within the year for the given time, within the range [1,12].
-func (month) Call(args ...interface{}) (v interface{}, err error) {
+type changed struct {
+	last lastValue
+}
+
+func (c *changed) Reset() {
+	c.last.reset()
+}
+
+// Reports whether the value differs from the value of the previous call, false on the first call.
+func (c *changed) Call(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
-		return 0, errors.New("month expects exactly one argument")
+		return nil, errors.New("changed expects exactly one argument")
 	}
-	switch a := args[0].(type) {
-	case time.Time:
-		v = int64(a.Month())
+	prev, ok := c.last.update(args[0])
+	if !ok {
+		return false, nil
+	}
+	if t, isTime := args[0].(time.Time); isTime {
+		return !t.Equal(prev.(time.Time)), nil
+	}
+	return args[0] != prev, nil
+}
+
+var changedFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize Changed Function Signature
+func init() {
+	for _, t := range previousTypes {
+		d := Domain{}
+		d[0] = t
+		changedFuncSignature[d] = ast.TBool
+	}
+}
+
+func (c *changed) Signature() map[Domain]ast.ValueType {
+	return changedFuncSignature
+}
+
+func (c *changed) snapshot() interface{} {
+	return &c.last
+}
+
+type ewma struct {
+	state struct {
+		Average float64
+		Set     bool
+	}
+}
+
+func (e *ewma) Reset() {
+	e.state.Average = 0
+	e.state.Set = false
+}
+
+// Computes the exponentially weighted moving average of the values,
+// where alpha is the weight of the value, 0 < alpha <= 1.
+func (e *ewma) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.New("ewma expects exactly two arguments ewma(value, alpha)")
+	}
+	var x float64
+	switch v := args[0].(type) {
+	case float64:
+		x = v
+	case int64:
+		x = float64(v)
 	default:
-		err = fmt.Errorf("cannot convert %T to time.Time", a)
+		return nil, fmt.Errorf("cannot compute ewma of type %T", v)
+	}
+	alpha, ok := args[1].(float64)
+	if !ok {
+		return nil, ErrNotFloat
+	}
+	if alpha <= 0 || alpha > 1 {
+		return nil, fmt.Errorf("ewma alpha must be in the range (0, 1], got %v", alpha)
+	}
+	if !e.state.Set {
+		e.state.Average = x
+		e.state.Set = true
+	} else {
+		e.state.Average = alpha*x + (1-alpha)*e.state.Average
 	}
+	return e.state.Average, nil
+}
+
+var ewmaFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize EWMA Function Signature
+func init() {
+	d := Domain{}
+	d[1] = ast.TFloat
+	d[0] = ast.TFloat
+	ewmaFuncSignature[d] = ast.TFloat
+	d[0] = ast.TInt
+	ewmaFuncSignature[d] = ast.TFloat
+}
+
+func (e *ewma) Signature() map[Domain]ast.ValueType {
+	return ewmaFuncSignature
+}
+
+func (e *ewma) snapshot() interface{} {
+	return &e.state
+}
+
+type rate struct {
+	state struct {
+		Value float64
+		Time  time.Time
+		Set   bool
+	}
+}
+
+func (r *rate) Reset() {
+	r.state.Value = 0
+	r.state.Time = time.Time{}
+	r.state.Set = false
+}
+
+// Computes the rate of change of the value per unit between the time of the value
+// and the time of the value of the previous call, zero on the first call.
+// The unit defaults to one second.
+func (r *rate) Call(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, errors.New("rate expects two or three arguments rate(value, time, unit) where unit is optional")
+	}
+	var x float64
+	switch v := args[0].(type) {
+	case float64:
+		x = v
+	case int64:
+		x = float64(v)
+	default:
+		return nil, fmt.Errorf("cannot compute rate of type %T", v)
+	}
+	t, ok := args[1].(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("rate expects the second argument to be a time, got %T", args[1])
+	}
+	unit := time.Second
+	if len(args) == 3 {
+		unit, ok = args[2].(time.Duration)
+		if !ok {
+			return nil, fmt.Errorf("rate expects the third argument to be a duration, got %T", args[2])
+		}
+	}
+	prev := r.state
+	if prev.Set && !t.After(prev.Time) {
+		return nil, fmt.Errorf("rate expects increasing times, got %v after %v", t, prev.Time)
+	}
+	r.state.Value = x
+	r.state.Time = t
+	r.state.Set = true
+	if !prev.Set {
+		return float64(0), nil
+	}
+	return (x - prev.Value) / float64(t.Sub(prev.Time)) * float64(unit), nil
+}
+
+var rateFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize Rate Function Signature
+func init() {
+	for _, t := range []ast.ValueType{ast.TFloat, ast.TInt} {
+		d := Domain{}
+		d[0] = t
+		d[1] = ast.TTime
+		rateFuncSignature[d] = ast.TFloat
+		d[2] = ast.TDuration
+		rateFuncSignature[d] = ast.TFloat
+	}
+}
+
+func (r *rate) Signature() map[Domain]ast.ValueType {
+	return rateFuncSignature
+}
+
+func (r *rate) snapshot() interface{} {
+	return &r.state
+}
+
+// Time function signatures
+var timeFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize Time Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TTime
+	timeFuncSignature[d] = ast.TInt
+	d[1] = ast.TString
+	timeFuncSignature[d] = ast.TInt
+}
+
+// Cache of the time zones loaded by name.
+var locations = struct {
+	sync.RWMutex
+	m map[string]*time.Location
+}{
+	m: make(map[string]*time.Location),
+}
+
+// loadLocation returns the time zone with the IANA name, i.e. 'Europe/Berlin'.
+func loadLocation(name string) (*time.Location, error) {
+	locations.RLock()
+	loc, ok := locations.m[name]
+	locations.RUnlock()
+	if ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	locations.Lock()
+	locations.m[name] = loc
+	locations.Unlock()
+	return loc, nil
+}
+
+// timeInZone returns the time argument of a time function in the time zone of the optional second argument.
+// Times are in UTC if no time zone is given.
+func timeInZone(name string, args []interface{}) (time.Time, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return time.Time{}, fmt.Errorf("%s expects one or two arguments %s(time, timezone) where timezone is optional", name, name)
+	}
+	t, ok := args[0].(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot convert %T to time.Time", args[0])
+	}
+	if len(args) == 1 {
+		return t, nil
+	}
+	return inZone(t, args[1])
+}
+
+func inZone(t time.Time, tz interface{}) (time.Time, error) {
+	name, ok := tz.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot convert %T to string", tz)
+	}
+	loc, err := loadLocation(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
+
+type minute struct {
+}
+
+func (minute) Reset() {
+}
+
+// Return the minute within the hour for the given time, within the range [0,59].
+func (minute) Call(args ...interface{}) (v interface{}, err error) {
+	t, err := timeInZone("minute", args)
+	if err != nil {
+		return 0, err
+	}
+	v = int64(t.Minute())
+	return
+}
+
+func (minute) Signature() map[Domain]ast.ValueType {
+	return timeFuncSignature
+}
+
+type hour struct {
+}
+
+func (hour) Reset() {
+}
+
+// Return the hour within the day for the given time, within the range [0,23].
+func (hour) Call(args ...interface{}) (v interface{}, err error) {
+	t, err := timeInZone("hour", args)
+	if err != nil {
+		return 0, err
+	}
+	v = int64(t.Hour())
+	return
+}
+
+func (hour) Signature() map[Domain]ast.ValueType {
+	return timeFuncSignature
+}
+
+type weekday struct {
+}
+
+func (weekday) Reset() {
+}
+
+// Return the weekday within the week for the given time, within the range [0,6] where 0 is Sunday.
+func (weekday) Call(args ...interface{}) (v interface{}, err error) {
+	t, err := timeInZone("weekday", args)
+	if err != nil {
+		return 0, err
+	}
+	v = int64(t.Weekday())
+	return
+}
+
+func (weekday) Signature() map[Domain]ast.ValueType {
+	return timeFuncSignature
+}
+
+type day struct {
+}
+
+func (day) Reset() {
+}
+
+// Return the day within the month for the given time, within the range [1,31] depending on the month.
+func (day) Call(args ...interface{}) (v interface{}, err error) {
+	t, err := timeInZone("day", args)
+	if err != nil {
+		return 0, err
+	}
+	v = int64(t.Day())
+	return
+}
+
+func (day) Signature() map[Domain]ast.ValueType {
+	return timeFuncSignature
+}
+
+type month struct {
+}
+
+func (month) Reset() {
+}
+
+// Return the month within the year for the given time, within the range [1,12].
+func (month) Call(args ...interface{}) (v interface{}, err error) {
+	t, err := timeInZone("month", args)
+	if err != nil {
+		return 0, err
+	}
+	v = int64(t.Month())
 	return
 }
 
@@ -1263,20 +2029,350 @@ func (year) Reset() {
 
 // Return the year for the given time.
 func (year) Call(args ...interface{}) (v interface{}, err error) {
+	t, err := timeInZone("year", args)
+	if err != nil {
+		return 0, err
+	}
+	v = int64(t.Year())
+	return
+}
+
+func (year) Signature() map[Domain]ast.ValueType {
+	return timeFuncSignature
+}
+
+var unixNanoFuncSignature = map[Domain]ast.ValueType{}
+
+func init() {
+	d := Domain{}
+	d[0] = ast.TTime
+	unixNanoFuncSignature[d] = ast.TInt
+}
+
+type unixNano struct {
+}
+
+func (unixNano) Reset() {
+}
+
+// Return the number of nanoseconds elapsed since January 1, 1970 UTC for the given time.
+func (unixNano) Call(args ...interface{}) (v interface{}, err error) {
 	if len(args) != 1 {
-		return 0, errors.New("year expects exactly one argument")
+		return 0, errors.New("unixNano expects exactly one argument")
 	}
 	switch a := args[0].(type) {
 	case time.Time:
-		v = int64(a.Year())
+		v = a.UnixNano()
 	default:
 		err = fmt.Errorf("cannot convert %T to time.Time", a)
 	}
 	return
 }
 
-func (year) Signature() map[Domain]ast.ValueType {
-	return timeFuncSignature
+func (unixNano) Signature() map[Domain]ast.ValueType {
+	return unixNanoFuncSignature
+}
+
+var nowFuncSignature = map[Domain]ast.ValueType{}
+
+func init() {
+	nowFuncSignature[Domain{}] = ast.TTime
+}
+
+type now struct {
+}
+
+func (now) Reset() {
+}
+
+// Return the current local time of the server in UTC.
+// Unlike the time of a point it is not replayed.
+func (now) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 0 {
+		return time.Time{}, errors.New("now expects no arguments")
+	}
+	return time.Now().UTC(), nil
+}
+
+func (now) Signature() map[Domain]ast.ValueType {
+	return nowFuncSignature
+}
+
+var truncateFuncSignature = map[Domain]ast.ValueType{}
+
+func init() {
+	d := Domain{}
+	d[0] = ast.TTime
+	d[1] = ast.TDuration
+	truncateFuncSignature[d] = ast.TTime
+	d[2] = ast.TString
+	truncateFuncSignature[d] = ast.TTime
+}
+
+type truncate struct {
+}
+
+func (truncate) Reset() {
+}
+
+// Return the time rounded down to a multiple of the duration.
+// If a time zone is given the multiples are relative to the local time in the zone,
+// so truncating to 24h returns the start of the local day.
+func (truncate) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 2 && len(args) != 3 {
+		return time.Time{}, errors.New("truncate expects two or three arguments truncate(time, duration, timezone) where timezone is optional")
+	}
+	t, ok := args[0].(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot convert %T to time.Time", args[0])
+	}
+	d, ok := args[1].(time.Duration)
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot convert %T to time.Duration", args[1])
+	}
+	if len(args) == 2 {
+		return t.Truncate(d), nil
+	}
+	local, err := inZone(t, args[2])
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Truncate the wall clock time and find the offset of the zone at the result,
+	// the offset at t is different if a daylight saving time change is between both.
+	y, mo, day := local.Date()
+	h, mi, sec := local.Clock()
+	wall := time.Date(y, mo, day, h, mi, sec, local.Nanosecond(), time.UTC).Truncate(d)
+	y, mo, day = wall.Date()
+	h, mi, sec = wall.Clock()
+	return time.Date(y, mo, day, h, mi, sec, wall.Nanosecond(), local.Location()).In(t.Location()), nil
+}
+
+func (truncate) Signature() map[Domain]ast.ValueType {
+	return truncateFuncSignature
+}
+
+var strftimeFuncSignature = map[Domain]ast.ValueType{}
+
+func init() {
+	d := Domain{}
+	d[0] = ast.TTime
+	d[1] = ast.TString
+	strftimeFuncSignature[d] = ast.TString
+	d[2] = ast.TString
+	strftimeFuncSignature[d] = ast.TString
+}
+
+type strftime struct {
+}
+
+func (strftime) Reset() {
+}
+
+// Format the time according to the strftime style format, optionally in a time zone.
+// The supported directives are:
+//
+//	%a abbreviated weekday name, i.e. Mon
+//	%A full weekday name, i.e. Monday
+//	%b abbreviated month name, i.e. Jan
+//	%B full month name, i.e. January
+//	%d day of the month, 01-31
+//	%e day of the month padded with a space, 1-31
+//	%F the date, equivalent to %Y-%m-%d
+//	%f microseconds, 000000-999999
+//	%H hour, 00-23
+//	%I hour, 01-12
+//	%j day of the year, 001-366
+//	%m month, 01-12
+//	%M minute, 00-59
+//	%p AM or PM
+//	%s seconds since January 1, 1970 UTC
+//	%S second, 00-59
+//	%T the time, equivalent to %H:%M:%S
+//	%u weekday, 1-7 where 1 is Monday
+//	%w weekday, 0-6 where 0 is Sunday
+//	%y year without the century, 00-99
+//	%Y year
+//	%z offset from UTC, i.e. +0100
+//	%Z abbreviated time zone name, i.e. CET
+//	%% a literal %
+func (strftime) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", errors.New("strftime expects two or three arguments strftime(time, format, timezone) where timezone is optional")
+	}
+	t, ok := args[0].(time.Time)
+	if !ok {
+		return "", fmt.Errorf("cannot convert %T to time.Time", args[0])
+	}
+	format, ok := args[1].(string)
+	if !ok {
+		return "", fmt.Errorf("cannot convert %T to string", args[1])
+	}
+	if len(args) == 3 {
+		t, err = inZone(t, args[2])
+		if err != nil {
+			return "", err
+		}
+	}
+	return formatTime(t, format)
+}
+
+func (strftime) Signature() map[Domain]ast.ValueType {
+	return strftimeFuncSignature
+}
+
+func formatTime(t time.Time, format string) (string, error) {
+	var buf bytes.Buffer
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(format) {
+			return "", errors.New("strftime format ends with an incomplete directive")
+		}
+		switch format[i] {
+		case 'a':
+			buf.WriteString(t.Format("Mon"))
+		case 'A':
+			buf.WriteString(t.Format("Monday"))
+		case 'b':
+			buf.WriteString(t.Format("Jan"))
+		case 'B':
+			buf.WriteString(t.Format("January"))
+		case 'd':
+			buf.WriteString(t.Format("02"))
+		case 'e':
+			buf.WriteString(t.Format("_2"))
+		case 'F':
+			buf.WriteString(t.Format("2006-01-02"))
+		case 'f':
+			fmt.Fprintf(&buf, "%06d", t.Nanosecond()/1000)
+		case 'H':
+			buf.WriteString(t.Format("15"))
+		case 'I':
+			buf.WriteString(t.Format("03"))
+		case 'j':
+			fmt.Fprintf(&buf, "%03d", t.YearDay())
+		case 'm':
+			buf.WriteString(t.Format("01"))
+		case 'M':
+			buf.WriteString(t.Format("04"))
+		case 'p':
+			buf.WriteString(t.Format("PM"))
+		case 's':
+			buf.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'S':
+			buf.WriteString(t.Format("05"))
+		case 'T':
+			buf.WriteString(t.Format("15:04:05"))
+		case 'u':
+			wd := int(t.Weekday())
+			if wd == 0 {
+				wd = 7
+			}
+			buf.WriteString(strconv.Itoa(wd))
+		case 'w':
+			buf.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'y':
+			buf.WriteString(t.Format("06"))
+		case 'Y':
+			buf.WriteString(t.Format("2006"))
+		case 'z':
+			buf.WriteString(t.Format("-0700"))
+		case 'Z':
+			buf.WriteString(t.Format("MST"))
+		case '%':
+			buf.WriteByte('%')
+		default:
+			return "", fmt.Errorf("unsupported strftime directive %%%c", format[i])
+		}
+	}
+	return buf.String(), nil
+}
+
+var calendarFuncSignature = map[Domain]ast.ValueType{}
+
+func init() {
+	d := Domain{}
+	d[0] = ast.TTime
+	d[1] = ast.TString
+	calendarFuncSignature[d] = ast.TBool
+}
+
+// A Func that looks up calendars in the calendars of the execution state.
+type calendarFunc interface {
+	Func
+	callCalendar(calendars Calendars, args ...interface{}) (interface{}, error)
+}
+
+type isHoliday struct {
+}
+
+func (isHoliday) Reset() {
+}
+
+// Call without calendars, any calendar is unknown.
+func (f isHoliday) Call(args ...interface{}) (v interface{}, err error) {
+	return f.callCalendar(nil, args...)
+}
+
+// Return whether the time is on a holiday of the named calendar.
+func (isHoliday) callCalendar(calendars Calendars, args ...interface{}) (v interface{}, err error) {
+	t, c, err := calendarArgs("isHoliday", calendars, args)
+	if err != nil {
+		return false, err
+	}
+	return c.IsHoliday(t), nil
+}
+
+func (isHoliday) Signature() map[Domain]ast.ValueType {
+	return calendarFuncSignature
+}
+
+type isBusinessHours struct {
+}
+
+func (isBusinessHours) Reset() {
+}
+
+// Call without calendars, any calendar is unknown.
+func (f isBusinessHours) Call(args ...interface{}) (v interface{}, err error) {
+	return f.callCalendar(nil, args...)
+}
+
+// Return whether the time is within the business hours of the named calendar.
+// Holidays are never within business hours.
+func (isBusinessHours) callCalendar(calendars Calendars, args ...interface{}) (v interface{}, err error) {
+	t, c, err := calendarArgs("isBusinessHours", calendars, args)
+	if err != nil {
+		return false, err
+	}
+	return c.IsBusinessHours(t), nil
+}
+
+func (isBusinessHours) Signature() map[Domain]ast.ValueType {
+	return calendarFuncSignature
+}
+
+func calendarArgs(name string, calendars Calendars, args []interface{}) (time.Time, Calendar, error) {
+	if len(args) != 2 {
+		return time.Time{}, nil, fmt.Errorf("%s expects exactly two arguments %s(time, calendar)", name, name)
+	}
+	t, ok := args[0].(time.Time)
+	if !ok {
+		return time.Time{}, nil, fmt.Errorf("cannot convert %T to time.Time", args[0])
+	}
+	calendar, ok := args[1].(string)
+	if !ok {
+		return time.Time{}, nil, fmt.Errorf("cannot convert %T to string", args[1])
+	}
+	c, err := lookupCalendar(calendars, calendar)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return t, c, nil
 }
 
 type humanBytes struct {
@@ -1360,6 +2456,7 @@ func init() {
 		ast.TRegex,
 		ast.TTime,
 		ast.TDuration,
+		ast.TMap,
 	}
 
 	for _, t := range types {
@@ -1409,3 +2506,312 @@ func init() {
 func (isPresent) Signature() map[Domain]ast.ValueType {
 	return isPresentFuncSignature
 }
+
+type jsonParse struct {
+}
+
+func (jsonParse) Reset() {
+}
+
+// Parses a JSON object into a map
+func (jsonParse) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 1 {
+		return nil, errors.New("jsonParse expects exactly one argument")
+	}
+	str, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as first arg to jsonParse, must be string", args[0])
+	}
+	o, err := parseJSON(str)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := o.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("jsonParse expects a JSON object, got %s", ast.TypeOf(o))
+	}
+	return m, nil
+}
+
+var jsonParseFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize jsonParse Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TString
+	jsonParseFuncSignature[d] = ast.TMap
+}
+
+func (jsonParse) Signature() map[Domain]ast.ValueType {
+	return jsonParseFuncSignature
+}
+
+// parseJSON decodes the JSON text, numbers are decoded as ints when they are integers
+// and null values are decoded as missing values.
+func parseJSON(str string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(str))
+	dec.UseNumber()
+	var o interface{}
+	if err := dec.Decode(&o); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return convertJSONValue(o), nil
+}
+
+func convertJSONValue(o interface{}) interface{} {
+	switch o := o.(type) {
+	case nil:
+		return ast.MissingValue
+	case json.Number:
+		if i, err := o.Int64(); err == nil {
+			return i
+		}
+		f, _ := o.Float64()
+		return f
+	case map[string]interface{}:
+		for k, v := range o {
+			o[k] = convertJSONValue(v)
+		}
+		return o
+	case []interface{}:
+		for i, v := range o {
+			o[i] = convertJSONValue(v)
+		}
+		return o
+	default:
+		return o
+	}
+}
+
+type jsonPath struct {
+}
+
+func (jsonPath) Reset() {
+}
+
+// Returns the value at the path within a map or JSON object, i.e. 'a.b[0]'.
+// The value is returned as a string, use the float, int or bool functions to convert it.
+// Objects and lists are returned as JSON text.
+func (jsonPath) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 2 {
+		return nil, errors.New("jsonPath expects exactly two arguments")
+	}
+	path, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as second arg to jsonPath, must be string", args[1])
+	}
+	o := args[0]
+	if str, ok := o.(string); ok {
+		o, err = parseJSON(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+	elements, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range elements {
+		switch e := e.(type) {
+		case string:
+			m, ok := o.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot get key %q of %s value in path %q", e, ast.TypeOf(o), path)
+			}
+			o, ok = m[e]
+			if !ok {
+				return nil, fmt.Errorf("key %q does not exist in path %q", e, path)
+			}
+		case int:
+			l, ok := o.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot get index %d of %s value in path %q", e, ast.TypeOf(o), path)
+			}
+			if e >= len(l) {
+				return nil, fmt.Errorf("index %d out of range in path %q", e, path)
+			}
+			o = l[e]
+		}
+	}
+	if _, ok := o.(*ast.Missing); ok {
+		return nil, fmt.Errorf("value at path %q is null", path)
+	}
+	return jsonValueString(o)
+}
+
+// jsonValueString returns the string form of a value of a map or JSON object.
+func jsonValueString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		b, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+// jsonValue reverts the conversion of convertJSONValue, missing values are encoded as null.
+func jsonValue(o interface{}) interface{} {
+	switch o := o.(type) {
+	case *ast.Missing:
+		return nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(o))
+		for k, v := range o {
+			m[k] = jsonValue(v)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(o))
+		for i, v := range o {
+			l[i] = jsonValue(v)
+		}
+		return l
+	default:
+		return o
+	}
+}
+
+// parseJSONPath splits a path into its keys and indexes, i.e. 'a.b[0]' is "a", "b", 0.
+func parseJSONPath(path string) ([]interface{}, error) {
+	var elements []interface{}
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key = part[:i]
+		}
+		if key != "" {
+			elements = append(elements, key)
+		} else if len(part) == 0 || len(elements) == 0 && part[0] != '[' {
+			return nil, fmt.Errorf("invalid path %q, empty key", path)
+		}
+		for rest := part[len(key):]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid path %q, malformed index", path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid path %q, index must be a non negative integer", path)
+			}
+			elements = append(elements, i)
+			rest = rest[end+1:]
+		}
+	}
+	return elements, nil
+}
+
+var jsonPathFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize jsonPath Function Signature
+func init() {
+	d := Domain{}
+	d[1] = ast.TString
+	d[0] = ast.TString
+	jsonPathFuncSignature[d] = ast.TString
+	d[0] = ast.TMap
+	jsonPathFuncSignature[d] = ast.TString
+}
+
+func (jsonPath) Signature() map[Domain]ast.ValueType {
+	return jsonPathFuncSignature
+}
+
+type mapGet struct {
+}
+
+func (mapGet) Reset() {
+}
+
+// Returns the value of a key of the map, or the default if one is provided and the key does not exist or is null.
+// The value is returned as a string, use the float, int or bool functions to convert it.
+// Objects and lists are returned as JSON text.
+func (mapGet) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, errors.New("mapGet expects two or three arguments")
+	}
+	m, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as first arg to mapGet, must be map", args[0])
+	}
+	key, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as second arg to mapGet, must be string", args[1])
+	}
+	v, ok = m[key]
+	if _, isMissing := v.(*ast.Missing); !ok || isMissing {
+		if len(args) == 3 {
+			return args[2], nil
+		}
+		if isMissing {
+			return nil, fmt.Errorf("value of key %q is null", key)
+		}
+		return nil, fmt.Errorf("key %q does not exist", key)
+	}
+	return jsonValueString(v)
+}
+
+var mapGetFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize mapGet Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TMap
+	d[1] = ast.TString
+	mapGetFuncSignature[d] = ast.TString
+	d[2] = ast.TString
+	mapGetFuncSignature[d] = ast.TString
+}
+
+func (mapGet) Signature() map[Domain]ast.ValueType {
+	return mapGetFuncSignature
+}
+
+type mapKeys struct {
+}
+
+func (mapKeys) Reset() {
+}
+
+// Returns the sorted list of the keys of the map
+func (mapKeys) Call(args ...interface{}) (v interface{}, err error) {
+	if len(args) != 1 {
+		return nil, errors.New("mapKeys expects exactly one argument")
+	}
+	m, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot pass %T as first arg to mapKeys, must be map", args[0])
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]interface{}, len(keys))
+	for i, k := range keys {
+		list[i] = k
+	}
+	return list, nil
+}
+
+var mapKeysFuncSignature = map[Domain]ast.ValueType{}
+
+// Initialize mapKeys Function Signature
+func init() {
+	d := Domain{}
+	d[0] = ast.TMap
+	mapKeysFuncSignature[d] = ast.TList
+}
+
+func (mapKeys) Signature() map[Domain]ast.ValueType {
+	return mapKeysFuncSignature
+}
@@ -2,9 +2,13 @@ package stateful
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 	"regexp"
 	"testing"
 	"time"
+
+	"github.com/influxdata/kapacitor/tick/ast"
 )
 
 func Test_Bool(t *testing.T) {
@@ -886,6 +890,176 @@ func Test_StatelessFuncs(t *testing.T) {
 			args: []interface{}{""},
 			err:  errors.New("regexReplace expects exactly three arguments"),
 		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":{"b":[1.5,2]}}`, "a.b[1]"},
+			exp:  "2",
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":{"b":[1.5,2]}}`, "a.b[0]"},
+			exp:  "1.5",
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{map[string]interface{}{"level": "error"}, "level"},
+			exp:  "error",
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":true}`, "a"},
+			exp:  "true",
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":null}`, "a"},
+			err:  errors.New(`value at path "a" is null`),
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":{"b":[1.5,2,null]}}`, "a.b"},
+			exp:  `[1.5,2,null]`,
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":{"b":{"c":"d"}}}`, "a"},
+			exp:  `{"b":{"c":"d"}}`,
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":{"b":[1.5,2]}}`, "a.b[2]"},
+			err:  errors.New(`index 2 out of range in path "a.b[2]"`),
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":1}`, "a.c"},
+			err:  errors.New(`cannot get key "c" of int value in path "a.c"`),
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":1}`, "a..c"},
+			err:  errors.New(`invalid path "a..c", empty key`),
+		},
+		{
+			name: "jsonPath",
+			args: []interface{}{`{"a":1`, "a"},
+			err:  errors.New(`invalid JSON: unexpected EOF`),
+		},
+		{
+			name: "mapGet",
+			args: []interface{}{map[string]interface{}{"level": "error"}, "level"},
+			exp:  "error",
+		},
+		{
+			name: "mapGet",
+			args: []interface{}{map[string]interface{}{"level": "error"}, "host"},
+			err:  errors.New(`key "host" does not exist`),
+		},
+		{
+			name: "mapGet",
+			args: []interface{}{map[string]interface{}{"level": "error"}, "host", "unknown"},
+			exp:  "unknown",
+		},
+		{
+			name: "mapGet",
+			args: []interface{}{map[string]interface{}{"code": int64(500)}, "code"},
+			exp:  "500",
+		},
+		{
+			name: "mapGet",
+			args: []interface{}{map[string]interface{}{"host": ast.MissingValue}, "host"},
+			err:  errors.New(`value of key "host" is null`),
+		},
+		{
+			name: "mapGet",
+			args: []interface{}{map[string]interface{}{"host": ast.MissingValue}, "host", "unknown"},
+			exp:  "unknown",
+		},
+		{
+			name: "hour",
+			args: []interface{}{time.Date(2017, time.March, 26, 0, 30, 0, 0, time.UTC), "Mars/Olympus_Mons"},
+			err:  errors.New(`unknown time zone "Mars/Olympus_Mons"`),
+		},
+		{
+			name: "hour",
+			args: []interface{}{},
+			err:  errors.New("hour expects one or two arguments hour(time, timezone) where timezone is optional"),
+		},
+		{
+			name: "unixNano",
+			args: []interface{}{time.Date(1970, time.January, 1, 0, 0, 1, 0, time.UTC)},
+			exp:  int64(time.Second),
+		},
+		{
+			name: "strftime",
+			args: []interface{}{time.Date(2017, time.March, 6, 15, 4, 5, 123456789, time.UTC), "%F %T.%f %e %I%p %y %z"},
+			exp:  "2017-03-06 15:04:05.123456  6 03PM 17 +0000",
+		},
+		{
+			name: "strftime",
+			args: []interface{}{time.Date(2017, time.March, 26, 0, 30, 0, 0, time.UTC), "%Q"},
+			err:  errors.New("unsupported strftime directive %Q"),
+		},
+		{
+			name: "isHoliday",
+			args: []interface{}{time.Date(2017, time.March, 26, 0, 30, 0, 0, time.UTC), "unknown"},
+			err:  errors.New(`unknown calendar "unknown"`),
+		},
+		{
+			name: "regexExtract",
+			args: []interface{}{regexp.MustCompile(`a(b)`), "ab", int64(2)},
+			err:  errors.New("regexExtract: pattern a(b) has no group 2"),
+		},
+		{
+			name: "regexExtract",
+			args: []interface{}{regexp.MustCompile(`a(?P<x>b)`), "ab", "y"},
+			err:  errors.New("regexExtract: pattern a(?P<x>b) has no group y"),
+		},
+		{
+			name: "regexExtract",
+			args: []interface{}{regexp.MustCompile(`a(b)`), "xab", int64(0)},
+			exp:  "ab",
+		},
+		{
+			name: "strFormat",
+			args: []interface{}{"%s=%v %t", "a", 1.5, true},
+			exp:  "a=1.5 true",
+		},
+		{
+			name: "strFormat",
+			args: []interface{}{},
+			err:  errors.New("strFormat expects at least one argument strFormat(format, values...)"),
+		},
+		{
+			name: "strJoin",
+			args: []interface{}{[]interface{}{"a", int64(1), 1.5, true}, ","},
+			exp:  "a,1,1.5,true",
+		},
+		{
+			name: "strJoin",
+			args: []interface{}{[]interface{}{"a", map[string]interface{}{}}, ","},
+			err:  errors.New("strJoin: element 1: cannot convert map[string]interface {} to string"),
+		},
+		{
+			name: "urlHost",
+			args: []interface{}{"http://[::1]/path"},
+			exp:  "::1",
+		},
+		{
+			name: "base64Decode",
+			args: []interface{}{"!"},
+			err:  errors.New("base64Decode: illegal base64 data at input byte 0"),
+		},
+		{
+			name: "md5",
+			args: []interface{}{""},
+			exp:  "d41d8cd98f00b204e9800998ecf8427e",
+		},
+		{
+			name: "sha1",
+			args: []interface{}{""},
+			exp:  "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -913,3 +1087,76 @@ func Test_StatelessFuncs(t *testing.T) {
 	}
 
 }
+
+func Test_JSONParse(t *testing.T) {
+	f := jsonParse{}
+	result, err := f.Call(`{"level":"error","code":500,"latency":1.5,"tags":["a","b"],"user":null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]interface{}{
+		"level":   "error",
+		"code":    int64(500),
+		"latency": 1.5,
+		"tags":    []interface{}{"a", "b"},
+		"user":    ast.MissingValue,
+	}
+	if !reflect.DeepEqual(result, exp) {
+		t.Errorf("unexpected result\ngot: %+v\nexp: %+v", result, exp)
+	}
+
+	if _, err := f.Call(`[1, 2]`); err == nil {
+		t.Error("expected error parsing JSON array")
+	} else if got, exp := err.Error(), "jsonParse expects a JSON object, got list"; got != exp {
+		t.Errorf("unexpected error\ngot: %s\nexp: %s", got, exp)
+	}
+}
+
+func Test_MapKeys(t *testing.T) {
+	result, err := mapKeys{}.Call(map[string]interface{}{"b": 1.0, "a": 2.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []interface{}{"a", "b"}; !reflect.DeepEqual(result, exp) {
+		t.Errorf("unexpected result\ngot: %+v\nexp: %+v", result, exp)
+	}
+}
+
+func Test_StatefulFuncs_Errors(t *testing.T) {
+	start := time.Date(2017, time.March, 26, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name  string
+		calls [][]interface{}
+		err   error
+	}{
+		{
+			name:  "ewma",
+			calls: [][]interface{}{{1.0, 1.5}},
+			err:   errors.New("ewma alpha must be in the range (0, 1], got 1.5"),
+		},
+		{
+			name:  "previous",
+			calls: [][]interface{}{{}},
+			err:   errors.New("previous expects exactly one argument"),
+		},
+		{
+			name:  "rate",
+			calls: [][]interface{}{{1.0, start}, {2.0, start}},
+			err:   fmt.Errorf("rate expects increasing times, got %v after %v", start, start),
+		},
+	}
+	for _, tc := range testCases {
+		f := NewFunctions()[tc.name]
+		var err error
+		for _, args := range tc.calls {
+			if _, err = f.Call(args...); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.Errorf("%s: expected error got: nil exp: %s", tc.name, tc.err)
+		} else if got, exp := err.Error(), tc.err.Error(); got != exp {
+			t.Errorf("%s: unexpected error\ngot:\n%s\nexp:\n%s", tc.name, got, exp)
+		}
+	}
+}
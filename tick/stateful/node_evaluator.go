@@ -36,6 +36,8 @@ type NodeEvaluator interface {
 	EvalTime(scope *Scope, executionState ExecutionState) (time.Time, error)
 	EvalDuration(scope *Scope, executionState ExecutionState) (time.Duration, error)
 	EvalMissing(scope *Scope, executionState ExecutionState) (*ast.Missing, error)
+	EvalMap(scope *Scope, executionState ExecutionState) (map[string]interface{}, error)
+	EvalList(scope *Scope, executionState ExecutionState) ([]interface{}, error)
 
 	// Type returns the type of ast.ValueType
 	Type(scope ReadOnlyScope) (ast.ValueType, error)
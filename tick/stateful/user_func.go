@@ -0,0 +1,67 @@
+package stateful
+
+import (
+	"fmt"
+
+	"github.com/influxdata/kapacitor/tick/ast"
+)
+
+// userFunc evaluates the body of a function declared in a TICKscript.
+// The parameters of the function are references within its body.
+type userFunc struct {
+	name   string
+	params []string
+	body   NodeEvaluator
+}
+
+func newUserFunc(def *ast.FuncDeclarationNode) (*userFunc, error) {
+	name := def.Name.Ident
+	if _, ok := builtinFuncs[name]; ok {
+		return nil, fmt.Errorf("cannot redefine builtin function %q", name)
+	}
+	if len(def.Params) > maxUserFuncParams {
+		return nil, fmt.Errorf("function %q declares %d parameters, at most %d are allowed", name, len(def.Params), maxUserFuncParams)
+	}
+	params := make([]string, len(def.Params))
+	for i, p := range def.Params {
+		for _, prev := range params[:i] {
+			if prev == p.Ident {
+				return nil, fmt.Errorf("function %q declares parameter %q more than once", name, p.Ident)
+			}
+		}
+		params[i] = p.Ident
+	}
+	body, err := createNodeEvaluator(def.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body of function %q: %v", name, err)
+	}
+	return &userFunc{
+		name:   name,
+		params: params,
+		body:   body,
+	}, nil
+}
+
+// ValidateFuncDeclaration reports whether the declared function can be called from an expression.
+// The parameters of the function must already be references within its body.
+func ValidateFuncDeclaration(def *ast.FuncDeclarationNode) error {
+	_, err := newUserFunc(def)
+	return err
+}
+
+// Type returns the type of the body when the function is called with the args.
+func (f *userFunc) Type(args ...interface{}) (ast.ValueType, error) {
+	scope := NewScope()
+	for i, p := range f.params {
+		scope.Set(p, args[i])
+	}
+	return f.body.Type(scope)
+}
+
+func (f *userFunc) Call(executionState ExecutionState, args ...interface{}) (interface{}, error) {
+	scope := NewScope()
+	for i, p := range f.params {
+		scope.Set(p, args[i])
+	}
+	return eval(f.body, scope, executionState)
+}
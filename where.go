@@ -18,8 +18,13 @@ type WhereNode struct {
 	w        *pipeline.WhereNode
 	endpoint string
 
+	// mu guards the expressions, it is held for reading while evaluating them
+	// so their state is consistent in snapshots.
+	mu          sync.RWMutex
 	expressions map[models.GroupID]stateful.Expression
 	scopePools  map[models.GroupID]stateful.ScopePool
+	// State of the expressions restored from a snapshot for groups not seen since.
+	restored map[models.GroupID][]byte
 }
 
 // Create a new WhereNode which filters down the batch or stream by a condition
@@ -38,11 +43,15 @@ func newWhereNode(et *ExecutingTask, n *pipeline.WhereNode, l *log.Logger) (wn *
 }
 
 func (w *WhereNode) runWhere(snapshot []byte) error {
-	var mu sync.RWMutex
+	if len(snapshot) > 0 {
+		if err := w.restore(snapshot); err != nil {
+			return err
+		}
+	}
 	valueF := func() int64 {
-		mu.RLock()
+		w.mu.RLock()
 		l := len(w.expressions)
-		mu.RUnlock()
+		w.mu.RUnlock()
 		return int64(l)
 	}
 	w.statMap.Set(statCardinalityGauge, expvar.NewIntFuncGauge(valueF))
@@ -51,26 +60,14 @@ func (w *WhereNode) runWhere(snapshot []byte) error {
 	case pipeline.StreamEdge:
 		for p, ok := w.ins[0].NextPoint(); ok; p, ok = w.ins[0].NextPoint() {
 			w.timer.Start()
-			mu.RLock()
-			expr := w.expressions[p.Group]
-			mu.RUnlock()
-			scopePool := w.scopePools[p.Group]
-
-			if expr == nil {
-				compiledExpr, err := stateful.NewExpression(w.w.Lambda.Expression)
-				if err != nil {
-					return fmt.Errorf("Failed to compile expression in where clause: %v", err)
-				}
-
-				expr = compiledExpr
-				mu.Lock()
-				w.expressions[p.Group] = expr
-				mu.Unlock()
-
-				scopePool = stateful.NewScopePool(ast.FindReferenceVariables(w.w.Lambda.Expression))
-				w.scopePools[p.Group] = scopePool
+			expr, scopePool, err := w.expression(p.Group)
+			if err != nil {
+				return err
 			}
-			if pass, err := EvalPredicate(expr, scopePool, p.Time, p.Fields, p.Tags); pass {
+			w.mu.RLock()
+			pass, err := EvalPredicate(expr, scopePool, p.Time, p.Fields, p.Tags)
+			w.mu.RUnlock()
+			if pass {
 				w.timer.Pause()
 				for _, child := range w.outs {
 					err := child.CollectPoint(p)
@@ -88,27 +85,13 @@ func (w *WhereNode) runWhere(snapshot []byte) error {
 	case pipeline.BatchEdge:
 		for b, ok := w.ins[0].NextBatch(); ok; b, ok = w.ins[0].NextBatch() {
 			w.timer.Start()
-			mu.RLock()
-			expr := w.expressions[b.Group]
-			mu.RUnlock()
-			scopePool := w.scopePools[b.Group]
-
-			if expr == nil {
-				compiledExpr, err := stateful.NewExpression(w.w.Lambda.Expression)
-				if err != nil {
-					return fmt.Errorf("Failed to compile expression in where clause: %v", err)
-				}
-
-				expr = compiledExpr
-				mu.Lock()
-				w.expressions[b.Group] = expr
-				mu.Unlock()
-
-				scopePool = stateful.NewScopePool(ast.FindReferenceVariables(w.w.Lambda.Expression))
-				w.scopePools[b.Group] = scopePool
+			expr, scopePool, err := w.expression(b.Group)
+			if err != nil {
+				return err
 			}
 			points := b.Points
 			b.Points = make([]models.BatchPoint, 0, len(b.Points))
+			w.mu.RLock()
 			for _, p := range points {
 				if pass, err := EvalPredicate(expr, scopePool, p.Time, p.Fields, p.Tags); pass {
 					if err != nil {
@@ -118,6 +101,7 @@ func (w *WhereNode) runWhere(snapshot []byte) error {
 					b.Points = append(b.Points, p)
 				}
 			}
+			w.mu.RUnlock()
 			w.timer.Stop()
 			for _, child := range w.outs {
 				err := child.CollectBatch(b)
@@ -129,3 +113,49 @@ func (w *WhereNode) runWhere(snapshot []byte) error {
 	}
 	return nil
 }
+
+// expression returns the expression of the group, compiling it the first time the group is seen.
+func (w *WhereNode) expression(group models.GroupID) (stateful.Expression, stateful.ScopePool, error) {
+	w.mu.RLock()
+	expr := w.expressions[group]
+	scopePool := w.scopePools[group]
+	w.mu.RUnlock()
+	if expr != nil {
+		return expr, scopePool, nil
+	}
+
+	expr, err := w.newExpression(w.w.Lambda.Expression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to compile expression in where clause: %v", err)
+	}
+	scopePool = stateful.NewScopePool(ast.FindReferenceVariables(w.w.Lambda.Expression))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if state, ok := w.restored[group]; ok {
+		if err := expr.Restore(state); err != nil {
+			w.logger.Printf("E! failed to restore state of group %s: %v", group, err)
+		}
+		delete(w.restored, group)
+	}
+	w.expressions[group] = expr
+	w.scopePools[group] = scopePool
+	return expr, scopePool, nil
+}
+
+func (w *WhereNode) snapshot() ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return snapshotExpressions(w.expressions, w.restored)
+}
+
+func (w *WhereNode) restore(data []byte) error {
+	restored, err := restoreExpressions(data)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.restored = restored
+	w.mu.Unlock()
+	return nil
+}
@@ -1,10 +1,13 @@
 package kapacitor
 
 import (
+	"bytes"
+	"encoding/gob"
 	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/kapacitor/expvar"
@@ -15,13 +18,25 @@ import (
 type WindowNode struct {
 	node
 	w *pipeline.WindowNode
+
+	// mu guards the windows, it is held while inserting points.
+	mu      sync.RWMutex
+	windows map[models.GroupID]window
+	// Groups in the order their windows were created, the oldest window is dropped first.
+	// Only kept if the windows are limited.
+	order      []models.GroupID
+	dropOldest bool
+
+	// Number of points buffered by the windows of all groups.
+	buffered int64
 }
 
 // Create a new  WindowNode, which windows data for a period of time and emits the window.
 func newWindowNode(et *ExecutingTask, n *pipeline.WindowNode, l *log.Logger) (*WindowNode, error) {
 	wn := &WindowNode{
-		w:    n,
-		node: node{Node: n, et: et, logger: l},
+		w:       n,
+		node:    node{Node: n, et: et, logger: l},
+		windows: make(map[models.GroupID]window),
 	}
 	wn.node.runF = wn.runWindow
 	return wn, nil
@@ -29,15 +44,42 @@ func newWindowNode(et *ExecutingTask, n *pipeline.WindowNode, l *log.Logger) (*W
 
 type window interface {
 	Insert(p models.Point) (models.Batch, bool)
+	// Number of buffered points
+	Len() int
+	// Drop the n oldest buffered points, returns the number of dropped points.
+	DropOldest(n int) int
+	// The buffered points and emit state of the window.
+	Snapshot() windowSnapshot
+	// Restore the buffered points and emit state of the window.
+	Restore(s windowSnapshot)
 }
 
-func (w *WindowNode) runWindow([]byte) error {
-	var mu sync.RWMutex
-	windows := make(map[models.GroupID]window)
+// windowSnapshot is the state of the window of a group.
+type windowSnapshot struct {
+	Group  models.GroupID
+	Name   string
+	Tags   models.Tags
+	ByName bool
+	// The buffered points, oldest first.
+	Points []models.BatchPoint
+	// The next emit time of a window by time.
+	NextEmit time.Time
+	// The number of inserted points and the count of the next emit of a window by count.
+	Count         int
+	NextEmitCount int
+}
+
+func (w *WindowNode) runWindow(snapshot []byte) error {
+	w.dropOldest = w.et.limiter.limited() && w.et.limiter.dropOldest()
+	if len(snapshot) > 0 {
+		if err := w.restore(snapshot); err != nil {
+			return err
+		}
+	}
 	valueF := func() int64 {
-		mu.RLock()
-		l := len(windows)
-		mu.RUnlock()
+		w.mu.RLock()
+		l := len(w.windows)
+		w.mu.RUnlock()
 		return int64(l)
 	}
 	w.statMap.Set(statCardinalityGauge, expvar.NewIntFuncGauge(valueF))
@@ -45,49 +87,12 @@ func (w *WindowNode) runWindow([]byte) error {
 	// Loops through points windowing by group
 	for p, ok := w.ins[0].NextPoint(); ok; p, ok = w.ins[0].NextPoint() {
 		w.timer.Start()
-		mu.RLock()
-		wnd := windows[p.Group]
-		mu.RUnlock()
-		if wnd == nil {
-			tags := make(map[string]string, len(p.Dimensions.TagNames))
-			for _, dim := range p.Dimensions.TagNames {
-				tags[dim] = p.Tags[dim]
-			}
-			switch {
-			case w.w.Period != 0:
-				// Window by time
-				wnd = newWindowByTime(
-					p.Time,
-					w.w.Period,
-					w.w.Every,
-					p.Name,
-					p.Group,
-					w.w.AlignFlag,
-					p.Dimensions.ByName,
-					w.w.FillPeriodFlag,
-					tags,
-					w.logger,
-				)
-			case w.w.PeriodCount != 0:
-				wnd = newWindowByCount(
-					p.Name,
-					p.Group,
-					tags,
-					p.Dimensions.ByName,
-					int(w.w.PeriodCount),
-					int(w.w.EveryCount),
-					w.w.FillPeriodFlag,
-					w.logger,
-				)
-			default:
-				// This should not be possible, but just in case.
-				return errors.New("invalid window, no period specified for either time or count")
-			}
-			mu.Lock()
-			windows[p.Group] = wnd
-			mu.Unlock()
+		w.mu.Lock()
+		batch, ok, err := w.insert(p)
+		w.mu.Unlock()
+		if err != nil {
+			return err
 		}
-		batch, ok := wnd.Insert(p)
 		if ok {
 			// Send window to all children
 			w.timer.Pause()
@@ -104,6 +109,132 @@ func (w *WindowNode) runWindow([]byte) error {
 	return nil
 }
 
+// insert the point into the window of its group, returns the batch of the window if it is emitted.
+// The caller must hold the lock of the node.
+func (w *WindowNode) insert(p models.Point) (models.Batch, bool, error) {
+	limits := w.et.Task.Limits
+	wnd := w.windows[p.Group]
+	if wnd == nil {
+		if w.dropOldest && limits.MaxGroups > 0 && int64(len(w.order)) >= limits.MaxGroups {
+			atomic.AddInt64(&w.buffered, -int64(w.windows[w.order[0]].Len()))
+			delete(w.windows, w.order[0])
+			w.order = w.order[1:]
+		}
+		tags := make(map[string]string, len(p.Dimensions.TagNames))
+		for _, dim := range p.Dimensions.TagNames {
+			tags[dim] = p.Tags[dim]
+		}
+		var err error
+		wnd, err = w.newWindow(p.Time, p.Name, p.Group, tags, p.Dimensions.ByName)
+		if err != nil {
+			return models.Batch{}, false, err
+		}
+		w.windows[p.Group] = wnd
+		if w.dropOldest {
+			w.order = append(w.order, p.Group)
+		}
+	}
+	l := wnd.Len()
+	batch, ok := wnd.Insert(p)
+	buffered := atomic.AddInt64(&w.buffered, int64(wnd.Len()-l))
+	if w.dropOldest && limits.MaxBufferedPoints > 0 && buffered > limits.MaxBufferedPoints {
+		// Drop the oldest points of the group first, then of the oldest groups.
+		excess := int(buffered - limits.MaxBufferedPoints)
+		excess -= wnd.DropOldest(excess)
+		for i := 0; excess > 0 && i < len(w.order); i++ {
+			excess -= w.windows[w.order[i]].DropOldest(excess)
+		}
+		atomic.StoreInt64(&w.buffered, limits.MaxBufferedPoints+int64(excess))
+	}
+	return batch, ok, nil
+}
+
+// newWindow creates the window of a group, now is the time of the first point of the window.
+func (w *WindowNode) newWindow(now time.Time, name string, group models.GroupID, tags models.Tags, byName bool) (window, error) {
+	switch {
+	case w.w.Period != 0:
+		// Window by time
+		return newWindowByTime(
+			now,
+			w.w.Period,
+			w.w.Every,
+			name,
+			group,
+			w.w.AlignFlag,
+			byName,
+			w.w.FillPeriodFlag,
+			tags,
+			w.logger,
+		), nil
+	case w.w.PeriodCount != 0:
+		return newWindowByCount(
+			name,
+			group,
+			tags,
+			byName,
+			int(w.w.PeriodCount),
+			int(w.w.EveryCount),
+			w.w.FillPeriodFlag,
+			w.logger,
+		), nil
+	default:
+		// This should not be possible, but just in case.
+		return nil, errors.New("invalid window, no period specified for either time or count")
+	}
+}
+
+func (w *WindowNode) bufferedCount() int64 {
+	return atomic.LoadInt64(&w.buffered)
+}
+
+func (w *WindowNode) snapshot() ([]byte, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if len(w.windows) == 0 {
+		// Nothing to snapshot
+		return nil, nil
+	}
+	windows := make([]windowSnapshot, 0, len(w.windows))
+	if w.dropOldest {
+		// Keep the order the windows are dropped in.
+		for _, group := range w.order {
+			windows = append(windows, w.windows[group].Snapshot())
+		}
+	} else {
+		for _, wnd := range w.windows {
+			windows = append(windows, wnd.Snapshot())
+		}
+	}
+	// Encode with gob, which keeps the types of the field values unlike JSON.
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(windows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (w *WindowNode) restore(data []byte) error {
+	var windows []windowSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&windows); err != nil {
+		return fmt.Errorf("failed to restore window state: %v", err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, s := range windows {
+		wnd, err := w.newWindow(time.Time{}, s.Name, s.Group, s.Tags, s.ByName)
+		if err != nil {
+			return err
+		}
+		wnd.Restore(s)
+		w.windows[s.Group] = wnd
+		if w.dropOldest {
+			w.order = append(w.order, s.Group)
+		}
+		atomic.AddInt64(&w.buffered, int64(wnd.Len()))
+	}
+	return nil
+}
+
 type windowByTime struct {
 	buf      *windowTimeBuffer
 	align    bool
@@ -204,6 +335,38 @@ func (w *windowByTime) Insert(p models.Point) (b models.Batch, ok bool) {
 	return
 }
 
+func (w *windowByTime) Len() int {
+	return w.buf.size
+}
+
+func (w *windowByTime) DropOldest(n int) int {
+	return w.buf.drop(n)
+}
+
+func (w *windowByTime) Snapshot() windowSnapshot {
+	return windowSnapshot{
+		Group:    w.group,
+		Name:     w.name,
+		Tags:     w.tags,
+		ByName:   w.byName,
+		Points:   w.buf.points(),
+		NextEmit: w.nextEmit,
+	}
+}
+
+func (w *windowByTime) Restore(s windowSnapshot) {
+	for _, bp := range s.Points {
+		w.buf.insert(models.Point{
+			Name:   w.name,
+			Group:  w.group,
+			Tags:   bp.Tags,
+			Fields: bp.Fields,
+			Time:   bp.Time,
+		})
+	}
+	w.nextEmit = s.NextEmit
+}
+
 func (w *windowByTime) batch(tmax time.Time) models.Batch {
 	return models.Batch{
 		Name:   w.name,
@@ -303,6 +466,21 @@ func (b *windowTimeBuffer) purge(oldest time.Time, inclusive bool) {
 	}
 }
 
+// Drop the n oldest points from the buffer, returns the number of dropped points.
+func (b *windowTimeBuffer) drop(n int) int {
+	if n >= b.size {
+		n = b.size
+		b.window = b.window[:0]
+		b.start = 0
+		b.stop = 0
+		b.size = 0
+		return n
+	}
+	b.start = (b.start + n) % len(b.window)
+	b.size -= n
+	return n
+}
+
 // Returns a copy of the current buffer.
 func (b *windowTimeBuffer) points() []models.BatchPoint {
 	if b.size == 0 {
@@ -376,24 +554,62 @@ func newWindowByCount(
 }
 
 func (w *windowByCount) Insert(p models.Point) (b models.Batch, ok bool) {
-	w.buf[w.stop] = models.BatchPoint{
+	w.push(models.BatchPoint{
 		Time:   p.Time,
 		Fields: p.Fields,
 		Tags:   p.Tags,
+	})
+	w.count++
+	//Check if its time to emit
+	if w.count == w.nextEmit {
+		b = w.batch()
+		ok = true
 	}
+	return
+}
+
+// push a point into the buffer, overwriting the oldest point if the buffer is full.
+func (w *windowByCount) push(bp models.BatchPoint) {
+	w.buf[w.stop] = bp
 	w.stop = (w.stop + 1) % w.period
 	if w.size == w.period {
 		w.start = (w.start + 1) % w.period
 	} else {
 		w.size++
 	}
-	w.count++
-	//Check if its time to emit
-	if w.count == w.nextEmit {
-		b = w.batch()
-		ok = true
+}
+
+func (w *windowByCount) Snapshot() windowSnapshot {
+	return windowSnapshot{
+		Group:         w.group,
+		Name:          w.name,
+		Tags:          w.tags,
+		ByName:        w.byName,
+		Points:        w.points(),
+		Count:         w.count,
+		NextEmitCount: w.nextEmit,
 	}
-	return
+}
+
+func (w *windowByCount) Restore(s windowSnapshot) {
+	for _, bp := range s.Points {
+		w.push(bp)
+	}
+	w.count = s.Count
+	w.nextEmit = s.NextEmitCount
+}
+
+func (w *windowByCount) Len() int {
+	return w.size
+}
+
+func (w *windowByCount) DropOldest(n int) int {
+	if n > w.size {
+		n = w.size
+	}
+	w.start = (w.start + n) % w.period
+	w.size -= n
+	return n
 }
 
 func (w *windowByCount) batch() models.Batch {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/influxdata/kapacitor/models"
+	"github.com/influxdata/kapacitor/pipeline"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -76,6 +77,42 @@ func TestWindowBufferByTime(t *testing.T) {
 	}
 }
 
+func TestWindowBufferByTimeDrop(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := &windowTimeBuffer{logger: logger}
+
+	// fill the buffer to its capacity and wrap it around
+	for i := 1; i <= 14; i++ {
+		buf.insert(models.Point{Time: time.Unix(int64(i), 0)})
+	}
+	buf.purge(time.Unix(6, 0), true)
+	for i := 15; i <= 17; i++ {
+		buf.insert(models.Point{Time: time.Unix(int64(i), 0)})
+	}
+	assert.Equal(12, buf.size)
+	assert.True(buf.start > buf.stop, "buffer did not wrap around")
+
+	assert.Equal(3, buf.drop(3))
+	points := buf.points()
+	if assert.Equal(9, len(points)) {
+		for i, p := range points {
+			assert.Equal(time.Unix(int64(i+9), 0), p.Time)
+		}
+	}
+
+	// dropping more points than buffered empties the buffer
+	assert.Equal(9, buf.drop(10))
+	assert.Equal(0, buf.size)
+	assert.Nil(buf.points())
+
+	buf.insert(models.Point{Time: time.Unix(18, 0)})
+	points = buf.points()
+	if assert.Equal(1, len(points)) {
+		assert.Equal(time.Unix(18, 0), points[0].Time)
+	}
+}
+
 func TestWindowBufferByCount(t *testing.T) {
 	testCases := []struct {
 		size       int
@@ -178,3 +215,90 @@ func TestWindowBufferByCount(t *testing.T) {
 		}
 	}
 }
+
+func newTestWindowNode(w *pipeline.WindowNode) *WindowNode {
+	return &WindowNode{
+		node:    node{et: &ExecutingTask{Task: &Task{}}, logger: logger},
+		w:       w,
+		windows: make(map[models.GroupID]window),
+	}
+}
+
+func TestWindowNode_SnapshotRestore(t *testing.T) {
+	testCases := []struct {
+		name   string
+		window *pipeline.WindowNode
+		// Number of points of each group inserted before and after the snapshot.
+		before, after int
+	}{
+		{
+			name:   "by time",
+			window: &pipeline.WindowNode{Period: 10 * time.Second, Every: 10 * time.Second},
+			before: 8,
+			after:  3,
+		},
+		{
+			name:   "by count",
+			window: &pipeline.WindowNode{PeriodCount: 5, EveryCount: 5},
+			before: 3,
+			after:  2,
+		},
+	}
+	point := func(host string, i int) models.Point {
+		tags := models.Tags{"host": host}
+		dims := models.Dimensions{TagNames: []string{"host"}}
+		return models.Point{
+			Name:       "cpu",
+			Group:      models.ToGroupID("cpu", tags, dims),
+			Dimensions: dims,
+			Tags:       tags,
+			Fields:     models.Fields{"count": int64(i), "value": float64(i) / 2},
+			Time:       time.Unix(int64(i), 0).UTC(),
+		}
+	}
+	for _, tc := range testCases {
+		orig := newTestWindowNode(tc.window)
+		for i := 0; i < tc.before; i++ {
+			for _, host := range []string{"serverA", "serverB"} {
+				if _, _, err := orig.insert(point(host, i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		data, err := orig.snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := newTestWindowNode(tc.window)
+		if err := restored.restore(data); err != nil {
+			t.Fatal(err)
+		}
+		if got, exp := restored.bufferedCount(), orig.bufferedCount(); got != exp {
+			t.Errorf("%s: unexpected buffered points got %d exp %d", tc.name, got, exp)
+		}
+
+		emitted := 0
+		for i := tc.before; i < tc.before+tc.after; i++ {
+			for _, host := range []string{"serverA", "serverB"} {
+				expBatch, expOK, err := orig.insert(point(host, i))
+				if err != nil {
+					t.Fatal(err)
+				}
+				batch, ok, err := restored.insert(point(host, i))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != expOK {
+					t.Fatalf("%s: unexpected emit of point %d of %s got %t exp %t", tc.name, i, host, ok, expOK)
+				}
+				if ok {
+					emitted++
+					assert.Equal(t, expBatch, batch, tc.name)
+				}
+			}
+		}
+		if emitted != 2 {
+			t.Errorf("%s: expected a window of each group to be emitted, got %d", tc.name, emitted)
+		}
+	}
+}